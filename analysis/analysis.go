@@ -0,0 +1,651 @@
+// Package analysis answers static questions about filters: does one filter
+// imply another, can two filters match the same row, can a filter match
+// anything at all given the types of its table.
+//
+// Filters are rewritten into a disjunction of conjunctions of predicates
+// on identities (comparisons, IN, BETWEEN, EXISTS, resolved includes) and
+// each conjunction is checked for a contradiction.  Expressions the
+// analysis doesn't understand (functions, LIKE, ...) are kept as opaque
+// predicates, only an expression and its own negation contradict.
+//
+// Answers are conservative, Implies and Disjoint are only true when proven
+// and Satisfiable is only false when proven.  The semantics are those of
+// the vm with default null logic, a comparison on a missing field is false
+// and its negation true, so NOT (x > 5) matches rows without x.
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// ErrTooComplex the filter expands to too many conjunctions to analyze
+	ErrTooComplex = fmt.Errorf("filter too complex to analyze")
+
+	// MaxConjunctions the most conjunctions a filter may expand to
+	MaxConjunctions = 1024
+
+	// maxIncludeDepth guards against include cycles
+	maxIncludeDepth = 100
+)
+
+// Analyzer analyzes filters of one table.
+type Analyzer struct {
+	// Table optional, if set identities are checked against its fields:
+	// predicates requiring a field it doesn't have, or a value its type
+	// can't hold, never match.
+	Table *schema.Table
+	// Includer optional, resolves INCLUDE's; without it each include is
+	// an opaque predicate.
+	Includer expr.Includer
+}
+
+// NewAnalyzer create an analyzer, both the table and includer may be nil.
+func NewAnalyzer(tbl *schema.Table, inc expr.Includer) *Analyzer {
+	return &Analyzer{Table: tbl, Includer: inc}
+}
+
+// Satisfiable may n match some row.  It is false only if n can be proven
+// to never match, on error it is true.
+func (m *Analyzer) Satisfiable(n expr.Node) (bool, error) {
+	d, err := m.dnf(n, false, 0)
+	if err != nil {
+		return true, err
+	}
+	return m.satisfiable(d), nil
+}
+
+// Implies does every row matching a also match b.
+func (m *Analyzer) Implies(a, b expr.Node) (bool, error) {
+	da, err := m.dnf(a, false, 0)
+	if err != nil {
+		return false, err
+	}
+	db, err := m.dnf(b, true, 0)
+	if err != nil {
+		return false, err
+	}
+	d, err := and(da, db)
+	if err != nil {
+		return false, err
+	}
+	return !m.satisfiable(d), nil
+}
+
+// Disjoint is there no row matching both a and b.
+func (m *Analyzer) Disjoint(a, b expr.Node) (bool, error) {
+	da, err := m.dnf(a, false, 0)
+	if err != nil {
+		return false, err
+	}
+	db, err := m.dnf(b, false, 0)
+	if err != nil {
+		return false, err
+	}
+	d, err := and(da, db)
+	if err != nil {
+		return false, err
+	}
+	return !m.satisfiable(d), nil
+}
+
+// Equivalent do a and b match the same rows.
+func (m *Analyzer) Equivalent(a, b expr.Node) (bool, error) {
+	if ok, err := m.Implies(a, b); !ok || err != nil {
+		return false, err
+	}
+	return m.Implies(b, a)
+}
+
+// FilterSatisfiable see Satisfiable, for the FILTER (or WHERE) of fs.
+func (m *Analyzer) FilterSatisfiable(fs *rel.FilterStatement) (bool, error) {
+	return m.Satisfiable(filterNode(fs))
+}
+
+// FilterImplies see Implies, only the FILTER (or WHERE) of the statements
+// are compared, not their FROM.
+func (m *Analyzer) FilterImplies(a, b *rel.FilterStatement) (bool, error) {
+	return m.Implies(filterNode(a), filterNode(b))
+}
+
+// FilterDisjoint see Disjoint, for the FILTER (or WHERE) of the statements.
+func (m *Analyzer) FilterDisjoint(a, b *rel.FilterStatement) (bool, error) {
+	return m.Disjoint(filterNode(a), filterNode(b))
+}
+
+// FilterEquivalent see Equivalent, for the FILTER (or WHERE) of the statements.
+func (m *Analyzer) FilterEquivalent(a, b *rel.FilterStatement) (bool, error) {
+	return m.Equivalent(filterNode(a), filterNode(b))
+}
+
+// filterNode the expression of fs, nil matches all
+func filterNode(fs *rel.FilterStatement) expr.Node {
+	if fs == nil {
+		return nil
+	}
+	if fs.Filter != nil {
+		return fs.Filter
+	}
+	return fs.Where
+}
+
+type predKind uint8
+
+const (
+	predExists predKind = iota // EXISTS id
+	predCmp                    // id op literal
+	predIn                     // id IN (literals)
+	predOpaque                 // any other expression, keyed by its string
+)
+
+// pred a predicate, or its negation
+type pred struct {
+	kind predKind
+	neg  bool
+	id   string
+	op   lex.TokenType // predCmp: =, <, <=, >, >=
+	vals []literal
+	key  string // predOpaque
+}
+
+// conj a conjunction of predicates, dnf a disjunction of them.  The
+// empty dnf is false, a dnf with an empty conj true.
+type (
+	conj []pred
+	dnf  []conj
+)
+
+var (
+	dnfTrue  = dnf{conj{}}
+	dnfFalse = dnf{}
+)
+
+func single(p pred) dnf { return dnf{conj{p}} }
+
+func or(a, b dnf) (dnf, error) {
+	if len(a)+len(b) > MaxConjunctions {
+		return nil, ErrTooComplex
+	}
+	return append(append(make(dnf, 0, len(a)+len(b)), a...), b...), nil
+}
+
+func and(a, b dnf) (dnf, error) {
+	if len(a)*len(b) > MaxConjunctions {
+		return nil, ErrTooComplex
+	}
+	d := make(dnf, 0, len(a)*len(b))
+	for _, ca := range a {
+		for _, cb := range b {
+			c := make(conj, 0, len(ca)+len(cb))
+			d = append(d, append(append(c, ca...), cb...))
+		}
+	}
+	return d, nil
+}
+
+// dnf rewrite n (negated if neg) as a disjunction of conjunctions
+func (m *Analyzer) dnf(n expr.Node, neg bool, depth int) (dnf, error) {
+	switch n := n.(type) {
+	case nil:
+		return constant(!neg), nil
+	case *expr.BooleanNode:
+		neg = neg != n.Negated()
+		return m.list(n.Operator.T == lex.TokenLogicAnd, n.Args, neg, depth)
+	case *expr.UnaryNode:
+		switch n.Operator.T {
+		case lex.TokenNegate:
+			return m.dnf(n.Arg, !neg, depth)
+		case lex.TokenExists:
+			if id, ok := n.Arg.(*expr.IdentityNode); ok {
+				return single(pred{kind: predExists, neg: neg, id: id.Text}), nil
+			}
+		}
+	case *expr.IdentityNode:
+		if n.IsBooleanIdentity() {
+			return constant(n.Bool() != neg), nil
+		}
+		if n.Text == "*" || strings.ToLower(n.Text) == "match_all" {
+			return constant(!neg), nil
+		}
+	case *expr.IncludeNode:
+		return m.include(n, neg, depth)
+	case *expr.TriNode:
+		if d, ok := between(n, neg != n.Negated()); ok {
+			return d, nil
+		}
+	case *expr.BinaryNode:
+		switch n.Operator.T {
+		case lex.TokenLogicAnd, lex.TokenLogicOr:
+			return m.list(n.Operator.T == lex.TokenLogicAnd, n.Args, neg, depth)
+		}
+		if d, ok := binary(n, neg); ok {
+			return d, nil
+		}
+	}
+	return single(pred{kind: predOpaque, neg: neg, key: opaqueKey(n)}), nil
+}
+
+// opaqueKey the key of an expression, (x LIKE "a%") is x LIKE "a%"
+func opaqueKey(n expr.Node) string {
+	if bn, ok := n.(*expr.BinaryNode); ok && bn.Paren {
+		nb := *bn
+		nb.Paren = false
+		return nb.String()
+	}
+	return n.String()
+}
+
+func constant(v bool) dnf {
+	if v {
+		return dnfTrue
+	}
+	return dnfFalse
+}
+
+// list an AND/OR list, by De Morgan a negated AND is an OR of negations
+func (m *Analyzer) list(isAnd bool, args []expr.Node, neg bool, depth int) (dnf, error) {
+	if neg {
+		isAnd = !isAnd
+	}
+	d := constant(isAnd)
+	for _, arg := range args {
+		ad, err := m.dnf(arg, neg, depth)
+		if err != nil {
+			return nil, err
+		}
+		if isAnd {
+			d, err = and(d, ad)
+		} else {
+			d, err = or(d, ad)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (m *Analyzer) include(n *expr.IncludeNode, neg bool, depth int) (dnf, error) {
+	neg = neg != n.Negated()
+	inc := n.ExprNode
+	if inc == nil && m.Includer != nil {
+		var err error
+		if inc, err = m.Includer.Include(n.Identity.Text); err != nil {
+			return nil, err
+		}
+	}
+	if inc == nil {
+		return single(pred{kind: predOpaque, neg: neg, key: "INCLUDE " + n.Identity.Text}), nil
+	}
+	if depth >= maxIncludeDepth {
+		return nil, fmt.Errorf("include depth exceeded at %q, is there a cycle?", n.Identity.Text)
+	}
+	return m.dnf(inc, neg, depth+1)
+}
+
+// between x BETWEEN lo AND hi is x > lo AND x < hi, the vm's BETWEEN is
+// exclusive
+func between(n *expr.TriNode, neg bool) (dnf, bool) {
+	if n.Operator.T != lex.TokenBetween || len(n.Args) != 3 {
+		return nil, false
+	}
+	id, ok := n.Args[0].(*expr.IdentityNode)
+	if !ok {
+		return nil, false
+	}
+	lo, ok1 := literalOf(n.Args[1])
+	hi, ok2 := literalOf(n.Args[2])
+	if !ok1 || !ok2 || !lo.isNum || !hi.isNum {
+		return nil, false
+	}
+	gt := pred{kind: predCmp, id: id.Text, op: lex.TokenGT, vals: []literal{lo}}
+	lt := pred{kind: predCmp, id: id.Text, op: lex.TokenLT, vals: []literal{hi}}
+	if !neg {
+		return dnf{conj{gt, lt}}, true
+	}
+	// missing, below or above
+	return append(negate(gt), negate(lt)[1:]...), true
+}
+
+// binary comparisons and IN of an identity with literals
+func binary(n *expr.BinaryNode, neg bool) (dnf, bool) {
+	if len(n.Args) != 2 {
+		return nil, false
+	}
+	op := n.Operator.T
+	id, ok := n.Args[0].(*expr.IdentityNode)
+	right := n.Args[1]
+	if !ok || id.IsBooleanIdentity() {
+		// literal on the left, 5 < x is x > 5
+		if id, ok = n.Args[1].(*expr.IdentityNode); !ok || id.IsBooleanIdentity() {
+			return nil, false
+		}
+		if op, ok = flipped[op]; !ok {
+			return nil, false
+		}
+		right = n.Args[0]
+	}
+
+	switch op {
+	case lex.TokenIN:
+		arr, ok := right.(*expr.ArrayNode)
+		if !ok {
+			return nil, false
+		}
+		vals := make([]literal, 0, len(arr.Args))
+		for _, a := range arr.Args {
+			lit, ok := literalOf(a)
+			if !ok {
+				return nil, false
+			}
+			vals = append(vals, lit)
+		}
+		return single(pred{kind: predIn, neg: neg, id: id.Text, vals: vals}), true
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE:
+		if op == lex.TokenNE {
+			neg = !neg
+		}
+		if _, isNull := right.(*expr.NullNode); isNull {
+			// x = NULL is x doesn't exist
+			return single(pred{kind: predExists, neg: !neg, id: id.Text}), true
+		}
+		lit, ok := literalOf(right)
+		if !ok {
+			return nil, false
+		}
+		return single(pred{kind: predCmp, neg: neg, id: id.Text, op: lex.TokenEqual, vals: []literal{lit}}), true
+	case lex.TokenLT, lex.TokenLE, lex.TokenGT, lex.TokenGE:
+		lit, ok := literalOf(right)
+		if !ok || !lit.isNum {
+			return nil, false
+		}
+		p := pred{kind: predCmp, id: id.Text, op: op, vals: []literal{lit}}
+		if neg {
+			return negate(p), true
+		}
+		return single(p), true
+	}
+	return nil, false
+}
+
+var flipped = map[lex.TokenType]lex.TokenType{
+	lex.TokenEqual:      lex.TokenEqual,
+	lex.TokenEqualEqual: lex.TokenEqual,
+	lex.TokenNE:         lex.TokenNE,
+	lex.TokenLT:         lex.TokenGT,
+	lex.TokenLE:         lex.TokenGE,
+	lex.TokenGT:         lex.TokenLT,
+	lex.TokenGE:         lex.TokenLE,
+}
+
+var negatedOps = map[lex.TokenType]lex.TokenType{
+	lex.TokenLT: lex.TokenGE,
+	lex.TokenLE: lex.TokenGT,
+	lex.TokenGT: lex.TokenLE,
+	lex.TokenGE: lex.TokenLT,
+}
+
+// negate an ordered comparison, NOT (x > 5) is x missing or x <= 5
+func negate(p pred) dnf {
+	np := p
+	np.op = negatedOps[p.op]
+	return dnf{
+		conj{{kind: predExists, neg: true, id: p.id}},
+		conj{np},
+	}
+}
+
+// literal a value compared to, numbers (and numeric strings) compare
+// by value, others by text
+type literal struct {
+	isNum bool
+	num   float64
+	text  string
+}
+
+func (l literal) key() string {
+	if l.isNum {
+		return strconv.FormatFloat(l.num, 'g', -1, 64)
+	}
+	return "s:" + l.text
+}
+
+func literalOf(n expr.Node) (literal, bool) {
+	switch n := n.(type) {
+	case *expr.NumberNode:
+		if n.IsInt {
+			return literal{isNum: true, num: float64(n.Int64), text: n.Text}, true
+		}
+		return literal{isNum: true, num: n.Float64, text: n.Text}, true
+	case *expr.StringNode:
+		if f, err := strconv.ParseFloat(n.Text, 64); err == nil {
+			return literal{isNum: true, num: f, text: n.Text}, true
+		}
+		return literal{text: n.Text}, true
+	case *expr.IdentityNode:
+		if n.IsBooleanIdentity() {
+			return literal{text: strconv.FormatBool(n.Bool())}, true
+		}
+	}
+	return literal{}, false
+}
+
+func (m *Analyzer) satisfiable(d dnf) bool {
+	for _, c := range d {
+		if m.satisfiableConj(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// domain the constraints of a conjunction on one identity
+type domain struct {
+	exists    bool
+	notExists bool
+	lo, hi    *bound
+	allowed   map[string]literal // nil is any value
+	excluded  map[string]literal
+}
+
+type bound struct {
+	v         float64
+	inclusive bool
+}
+
+func (m *Analyzer) satisfiableConj(c conj) bool {
+	domains := make(map[string]*domain)
+	opaque := make(map[string]bool)
+	for _, p := range c {
+		if p.kind == predOpaque {
+			if neg, ok := opaque[p.key]; ok && neg != p.neg {
+				return false
+			}
+			opaque[p.key] = p.neg
+			continue
+		}
+		d := domains[p.id]
+		if d == nil {
+			d = &domain{}
+			domains[p.id] = d
+		}
+		d.add(p)
+	}
+	for id, d := range domains {
+		if !m.satisfiableDomain(id, d) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *domain) add(p pred) {
+	switch {
+	case p.kind == predExists:
+		if p.neg {
+			d.notExists = true
+		} else {
+			d.exists = true
+		}
+	case p.neg:
+		// x != v, x NOT IN (..) hold for missing x
+		if d.excluded == nil {
+			d.excluded = make(map[string]literal)
+		}
+		for _, v := range p.vals {
+			d.excluded[v.key()] = v
+		}
+	case p.kind == predIn || p.op == lex.TokenEqual:
+		d.exists = true
+		vals := make(map[string]literal, len(p.vals))
+		for _, v := range p.vals {
+			if _, ok := d.allowed[v.key()]; ok || d.allowed == nil {
+				vals[v.key()] = v
+			}
+		}
+		d.allowed = vals
+	default:
+		d.exists = true
+		b := &bound{v: p.vals[0].num, inclusive: p.op == lex.TokenLE || p.op == lex.TokenGE}
+		if p.op == lex.TokenGT || p.op == lex.TokenGE {
+			if d.lo == nil || b.v > d.lo.v || (b.v == d.lo.v && !b.inclusive) {
+				d.lo = b
+			}
+		} else if d.hi == nil || b.v < d.hi.v || (b.v == d.hi.v && !b.inclusive) {
+			d.hi = b
+		}
+	}
+}
+
+func (m *Analyzer) satisfiableDomain(id string, d *domain) bool {
+	if d.exists && d.notExists {
+		return false
+	}
+	if !d.exists {
+		// a missing value satisfies the negations
+		return true
+	}
+	vt := value.UnknownType
+	if m.Table != nil && len(m.Table.Fields) > 0 {
+		var ok bool
+		if vt, ok = m.Table.Column(fieldName(id)); !ok {
+			return false
+		}
+	}
+	isInt := vt == value.IntType
+
+	if d.allowed != nil {
+		for _, v := range d.allowed {
+			if _, ok := d.excluded[v.key()]; ok {
+				continue
+			}
+			if (d.lo != nil || d.hi != nil) && (!v.isNum || !d.within(v.num)) {
+				continue
+			}
+			if holds(vt, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch vt {
+	case value.IntType, value.NumberType, value.UnknownType, value.TimeType:
+	default:
+		// ranges on strings, bools etc, we don't know their conversions
+		return true
+	}
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if d.lo != nil {
+		lo = d.lo.v
+	}
+	if d.hi != nil {
+		hi = d.hi.v
+	}
+	if isInt {
+		lo, hi = intBounds(d.lo, lo, true), intBounds(d.hi, hi, false)
+		if lo > hi {
+			return false
+		}
+		if hi-lo >= float64(len(d.excluded)) {
+			return true
+		}
+		// few enough integers to check each
+		for v := lo; v <= hi; v++ {
+			if _, ok := d.excluded[strconv.FormatFloat(v, 'g', -1, 64)]; !ok {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case lo < hi:
+		return true
+	case lo > hi:
+		return false
+	}
+	// a single point
+	if !d.lo.inclusive || !d.hi.inclusive {
+		return false
+	}
+	_, excluded := d.excluded[strconv.FormatFloat(lo, 'g', -1, 64)]
+	return !excluded
+}
+
+func (d *domain) within(v float64) bool {
+	if d.lo != nil && (v < d.lo.v || (v == d.lo.v && !d.lo.inclusive)) {
+		return false
+	}
+	if d.hi != nil && (v > d.hi.v || (v == d.hi.v && !d.hi.inclusive)) {
+		return false
+	}
+	return true
+}
+
+// intBounds the integer bound of b, x > 5.5 is x >= 6, x > 5 is x >= 6
+func intBounds(b *bound, v float64, lower bool) float64 {
+	if b == nil {
+		return v
+	}
+	if lower {
+		if c := math.Ceil(v); c != v || b.inclusive {
+			return c
+		}
+		return v + 1
+	}
+	if f := math.Floor(v); f != v || b.inclusive {
+		return f
+	}
+	return v - 1
+}
+
+// holds can a field of type vt have value v
+func holds(vt value.ValueType, v literal) bool {
+	switch vt {
+	case value.IntType:
+		return v.isNum && v.num == math.Trunc(v.num)
+	case value.NumberType:
+		return v.isNum
+	case value.BoolType:
+		_, err := strconv.ParseBool(v.text)
+		return err == nil
+	}
+	return true
+}
+
+// fieldName the field of a possibly qualified identity, t.x is x
+func fieldName(id string) string {
+	if _, right, ok := expr.NewIdentityNodeVal(id).LeftRight(); ok {
+		return right
+	}
+	return id
+}
@@ -0,0 +1,215 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/analysis"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+type includer map[string]string
+
+func (m includer) Include(name string) (expr.Node, error) {
+	fs, err := rel.ParseFilterQL(m[name])
+	if err != nil {
+		return nil, err
+	}
+	return fs.Filter, nil
+}
+
+var inc = includer{
+	"adults": `FILTER age >= 18`,
+	"west":   `FILTER state IN ("CA", "NV")`,
+	"cycle":  `FILTER INCLUDE cycle`,
+}
+
+func filter(t *testing.T, f string) *rel.FilterStatement {
+	fs, err := rel.ParseFilterQL("FILTER " + f)
+	assert.Equal(t, nil, err, f)
+	return fs
+}
+
+func TestImplies(t *testing.T) {
+	a := analysis.NewAnalyzer(nil, inc)
+	tests := []struct {
+		a, b    string
+		implies bool
+	}{
+		{`x > 10`, `x > 5`, true},
+		{`x > 5`, `x > 10`, false},
+		{`x >= 5`, `x > 5`, false},
+		{`x = 7`, `x BETWEEN 5 AND 10`, true},
+		// the vm's BETWEEN is exclusive
+		{`x = 5`, `x BETWEEN 5 AND 10`, false},
+		{`x = 10`, `x BETWEEN 5 AND 10`, false},
+		{`x BETWEEN 5 AND 10`, `x > 5`, true},
+		{`x BETWEEN 5 AND 10`, `x >= 6`, false},
+		{`AND ( x > 5, x < 10 )`, `x BETWEEN 5 AND 10`, true},
+		{`AND ( x >= 5, x < 10 )`, `x BETWEEN 5 AND 10`, false},
+		{`x BETWEEN 6 AND 8`, `x BETWEEN 5 AND 10`, true},
+		{`x BETWEEN 5 AND 11`, `x BETWEEN 5 AND 10`, false},
+		{`x IN (1, 2)`, `x IN (1, 2, 3)`, true},
+		{`x IN (1, 2, 4)`, `x IN (1, 2, 3)`, false},
+		{`x = "a"`, `x != "b"`, true},
+		{`x = "a"`, `EXISTS x`, true},
+		{`x != "a"`, `EXISTS x`, false},
+		{`x = 1`, `x NOT IN (2, 3)`, true},
+		{`AND ( x > 1, y = "a" )`, `y = "a"`, true},
+		{`y = "a"`, `OR ( y = "a", z = 1 )`, true},
+		{`OR ( x = 1, x = 2 )`, `x IN (1, 2)`, true},
+		{`x IN (1, 2)`, `OR ( x = 1, x = 2 )`, true},
+		{`AND ( x > 1, NOT x >= 3 )`, `x BETWEEN 1 AND 3`, true},
+		{`AND ( x > 1, NOT x > 3 )`, `x BETWEEN 1 AND 3`, false},
+		// missing x matches NOT x > 5 but not x <= 5
+		{`NOT x > 5`, `x <= 5`, false},
+		{`NOT EXISTS x`, `x <= 5`, false},
+		{`NOT EXISTS x`, `NOT x > 5`, true},
+		{`NOT x BETWEEN 1 AND 5`, `x != 3`, true},
+		{`NOT x BETWEEN 1 AND 5`, `x != 5`, false},
+		{`INCLUDE adults`, `age > 10`, true},
+		{`age > 30`, `INCLUDE adults`, true},
+		{`state = "CA"`, `INCLUDE west`, true},
+		{`state = "WA"`, `NOT INCLUDE west`, true},
+		{`name LIKE "a%"`, `OR ( name LIKE "a%", x = 1 )`, true},
+		{`name LIKE "a%"`, `name LIKE "b%"`, false},
+		{`x > 1`, `*`, true},
+		{`*`, `x > 1`, false},
+		{`false`, `x > 1`, true},
+	}
+	for _, tt := range tests {
+		ok, err := a.FilterImplies(filter(t, tt.a), filter(t, tt.b))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.implies, ok, "%s => %s", tt.a, tt.b)
+	}
+
+	// without the includer, includes are only equal to themselves
+	ok, err := analysis.NewAnalyzer(nil, nil).FilterImplies(filter(t, `AND ( INCLUDE adults, x = 1 )`), filter(t, `INCLUDE adults`))
+	assert.Equal(t, nil, err)
+	assert.True(t, ok)
+	ok, _ = analysis.NewAnalyzer(nil, nil).FilterImplies(filter(t, `age > 30`), filter(t, `INCLUDE adults`))
+	assert.False(t, ok)
+
+	_, err = a.FilterImplies(filter(t, `INCLUDE cycle`), filter(t, `x = 1`))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestDisjoint(t *testing.T) {
+	a := analysis.NewAnalyzer(nil, inc)
+	tests := []struct {
+		a, b     string
+		disjoint bool
+	}{
+		{`x > 10`, `x < 5`, true},
+		{`x > 5`, `x < 10`, false},
+		{`x >= 5`, `x <= 5`, false},
+		{`x > 5`, `x <= 5`, true},
+		{`x = "a"`, `x = "b"`, true},
+		{`x IN ("a", "b")`, `x IN ("b", "c")`, false},
+		{`x IN ("a", "b")`, `x NOT IN ("a", "b")`, true},
+		{`x = 1`, `x = "1"`, false},
+		{`EXISTS x`, `NOT EXISTS x`, true},
+		{`x != 1`, `NOT EXISTS x`, false},
+		{`x BETWEEN 1 AND 5`, `x BETWEEN 6 AND 10`, true},
+		{`x BETWEEN 1 AND 5`, `x BETWEEN 5 AND 10`, true},
+		{`x BETWEEN 1 AND 5`, `x BETWEEN 4 AND 10`, false},
+		{`x = 5`, `x BETWEEN 5 AND 10`, true},
+		{`INCLUDE adults`, `age < 18`, true},
+		{`INCLUDE west`, `NOT INCLUDE west`, true},
+		{`name LIKE "a%"`, `NOT name LIKE "a%"`, true},
+		{`OR ( x = 1, y = 1 )`, `AND ( x = 2, y = 2 )`, true},
+		{`OR ( x = 1, y = 1 )`, `AND ( x = 2, z = 2 )`, false},
+	}
+	for _, tt := range tests {
+		ok, err := a.FilterDisjoint(filter(t, tt.a), filter(t, tt.b))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.disjoint, ok, "%s & %s", tt.a, tt.b)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	a := analysis.NewAnalyzer(nil, inc)
+	ok, err := a.FilterEquivalent(filter(t, `AND ( x > 1, x < 5 )`), filter(t, `x BETWEEN 1 AND 5`))
+	assert.Equal(t, nil, err)
+	assert.True(t, ok)
+	ok, _ = a.FilterEquivalent(filter(t, `AND ( x >= 1, x <= 5 )`), filter(t, `x BETWEEN 1 AND 5`))
+	assert.False(t, ok)
+	ok, _ = a.FilterEquivalent(filter(t, `NOT AND ( x = 1, y = 2 )`), filter(t, `OR ( x != 1, y != 2 )`))
+	assert.True(t, ok)
+	ok, _ = a.FilterEquivalent(filter(t, `x > 1`), filter(t, `x >= 1`))
+	assert.False(t, ok)
+}
+
+func TestSatisfiable(t *testing.T) {
+	tbl := schema.NewTable("users")
+	tbl.AddFieldType("age", value.IntType)
+	tbl.AddFieldType("score", value.NumberType)
+	tbl.AddFieldType("name", value.StringType)
+	tbl.AddFieldType("active", value.BoolType)
+
+	a := analysis.NewAnalyzer(tbl, inc)
+	tests := []struct {
+		f   string
+		sat bool
+	}{
+		{`age > 5`, true},
+		{`AND ( age > 5, age < 3 )`, false},
+		// no int between
+		{`AND ( age > 5, age < 6 )`, false},
+		{`AND ( score > 5, score < 6 )`, true},
+		{`age BETWEEN 5 AND 6`, false},
+		{`age BETWEEN 5 AND 7`, true},
+		{`AND ( age >= 5, age <= 6, age NOT IN (5, 6) )`, false},
+		{`AND ( age >= 5, age <= 7, age NOT IN (5, 6) )`, true},
+		{`age = 5.5`, false},
+		{`age = "abc"`, false},
+		{`age IN ("abc", 3)`, true},
+		{`score = 5.5`, true},
+		{`active = true`, true},
+		{`active = "maybe"`, false},
+		{`name = "bob"`, true},
+		{`email = "bob@example.com"`, false},
+		{`EXISTS email`, false},
+		{`NOT EXISTS email`, true},
+		{`email != "bob@example.com"`, true},
+		{`users.age > 5`, true},
+		{`AND ( INCLUDE adults, age < 10 )`, false},
+		{`AND ( name = "a", name != "a" )`, false},
+		{`OR ( AND ( age > 5, age < 3 ), name = "a" )`, true},
+		{`false`, false},
+		{`*`, true},
+	}
+	for _, tt := range tests {
+		ok, err := a.FilterSatisfiable(filter(t, tt.f))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.sat, ok, tt.f)
+	}
+
+	// without a table any field may exist
+	ok, err := analysis.NewAnalyzer(nil, nil).FilterSatisfiable(filter(t, `AND ( EXISTS email, age = "abc" )`))
+	assert.Equal(t, nil, err)
+	assert.True(t, ok)
+}
+
+func TestTooComplex(t *testing.T) {
+	// 2^11 conjunctions
+	f := `AND (`
+	for i := 0; i < 11; i++ {
+		if i > 0 {
+			f += ","
+		}
+		f += ` OR ( a = 1, b = 2 )`
+	}
+	f += ` )`
+	a := analysis.NewAnalyzer(nil, nil)
+	ok, err := a.FilterSatisfiable(filter(t, f))
+	assert.Equal(t, analysis.ErrTooComplex, err)
+	assert.True(t, ok)
+	ok, err = a.FilterImplies(filter(t, f), filter(t, f))
+	assert.Equal(t, analysis.ErrTooComplex, err)
+	assert.False(t, ok)
+}
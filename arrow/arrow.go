@@ -0,0 +1,197 @@
+// Package arrow is a minimal implementation of the Apache Arrow columnar
+// format: typed columns, record batches of them and the IPC streaming
+// format (schema message, record batch messages, end of stream), enough
+// to hand qlbridge results to arrow consumers (pyarrow, arrow-js, duckdb)
+// without depending on the arrow libraries.
+//
+// Only the types qlbridge values map to are supported, see FromValueType.
+// Times are Timestamp(microsecond, "UTC") and other values (maps, slices,
+// json) are written as their String form.
+package arrow
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/araddon/qlbridge/value"
+)
+
+// Type of a column
+type Type uint8
+
+const (
+	Bool Type = iota
+	Int64
+	Float64
+	String
+	Binary
+	Timestamp // microseconds since epoch, UTC
+)
+
+func (t Type) String() string {
+	switch t {
+	case Bool:
+		return "bool"
+	case Int64:
+		return "int64"
+	case Float64:
+		return "float64"
+	case String:
+		return "utf8"
+	case Binary:
+		return "binary"
+	case Timestamp:
+		return "timestamp[us, tz=UTC]"
+	}
+	return fmt.Sprintf("type(%d)", uint8(t))
+}
+
+// FromValueType the column type for values of a value.ValueType
+func FromValueType(vt value.ValueType) Type {
+	switch vt {
+	case value.BoolType:
+		return Bool
+	case value.IntType:
+		return Int64
+	case value.NumberType:
+		return Float64
+	case value.TimeType:
+		return Timestamp
+	case value.ByteSliceType:
+		return Binary
+	}
+	return String
+}
+
+// ValueType the value.ValueType of values of a column type
+func (t Type) ValueType() value.ValueType {
+	switch t {
+	case Bool:
+		return value.BoolType
+	case Int64:
+		return value.IntType
+	case Float64:
+		return value.NumberType
+	case Timestamp:
+		return value.TimeType
+	case Binary:
+		return value.ByteSliceType
+	}
+	return value.StringType
+}
+
+// Field a named column of a schema
+type Field struct {
+	Name     string
+	Type     Type
+	Nullable bool
+}
+
+// Schema the fields of the columns of record batches
+type Schema struct {
+	Fields []Field
+}
+
+// NewSchema a schema of fields
+func NewSchema(fields ...Field) *Schema {
+	return &Schema{Fields: fields}
+}
+
+// FieldIndex the position of the named field, -1 if there is none.
+func (m *Schema) FieldIndex(name string) int {
+	for i, f := range m.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Names of the fields
+func (m *Schema) Names() []string {
+	names := make([]string, len(m.Fields))
+	for i, f := range m.Fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Record a record batch, equal length columns of a schema
+type Record struct {
+	Schema  *Schema
+	Columns []*Column
+	Len     int
+}
+
+// NewRecord a record of columns, which must have a column per field of s
+// all of the same length.
+func NewRecord(s *Schema, cols []*Column) *Record {
+	r := &Record{Schema: s, Columns: cols}
+	if len(cols) > 0 {
+		r.Len = cols[0].Len
+	}
+	return r
+}
+
+// Row the values of row i
+func (m *Record) Row(i int) []driver.Value {
+	row := make([]driver.Value, len(m.Columns))
+	for j, c := range m.Columns {
+		row[j] = c.Value(i)
+	}
+	return row
+}
+
+// Take a record of the rows at positions idx
+func (m *Record) Take(idx []int) *Record {
+	cols := make([]*Column, len(m.Columns))
+	for i, c := range m.Columns {
+		cols[i] = c.Take(idx)
+	}
+	r := NewRecord(m.Schema, cols)
+	r.Len = len(idx)
+	return r
+}
+
+// RecordBuilder builds records a row at a time
+type RecordBuilder struct {
+	schema   *Schema
+	builders []*Builder
+}
+
+// NewRecordBuilder a record builder for schema s
+func NewRecordBuilder(s *Schema) *RecordBuilder {
+	m := &RecordBuilder{schema: s, builders: make([]*Builder, len(s.Fields))}
+	for i, f := range s.Fields {
+		m.builders[i] = NewBuilder(f.Type)
+	}
+	return m
+}
+
+// Append a row, missing values are null.
+func (m *RecordBuilder) Append(row []driver.Value) {
+	for i, b := range m.builders {
+		if i < len(row) {
+			b.Append(row[i])
+		} else {
+			b.AppendNull()
+		}
+	}
+}
+
+// Len the number of rows appended since the last NewRecord
+func (m *RecordBuilder) Len() int {
+	if len(m.builders) == 0 {
+		return 0
+	}
+	return m.builders[0].Len()
+}
+
+// NewRecord a record of the rows appended, the builder is reset.
+func (m *RecordBuilder) NewRecord() *Record {
+	cols := make([]*Column, len(m.builders))
+	for i, b := range m.builders {
+		cols[i] = b.Finish()
+	}
+	return NewRecord(m.schema, cols)
+}
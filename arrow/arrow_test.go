@@ -0,0 +1,177 @@
+package arrow
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/value"
+)
+
+var testSchema = NewSchema(
+	Field{Name: "name", Type: String, Nullable: true},
+	Field{Name: "ct", Type: Int64, Nullable: true},
+	Field{Name: "score", Type: Float64, Nullable: true},
+	Field{Name: "ok", Type: Bool, Nullable: true},
+	Field{Name: "created", Type: Timestamp, Nullable: true},
+	Field{Name: "raw", Type: Binary, Nullable: true},
+)
+
+var (
+	t1       = time.Date(2012, 10, 17, 17, 29, 39, 738000000, time.UTC)
+	testRows = [][]driver.Value{
+		{"aaron", int64(82), 1.5, true, t1, []byte{1, 2}},
+		{nil, nil, nil, nil, nil, nil},
+		{"bob", "12", int64(3), false, "2009-12-11T19:53:31Z", "x"},
+		{value.NewStringValue("ç"), value.NewIntValue(-1), value.NewNumberValue(2), value.NewBoolValue(true),
+			value.NewTimeValue(t1), value.NewNilValue()},
+	}
+)
+
+func TestColumnBuilder(t *testing.T) {
+	rb := NewRecordBuilder(testSchema)
+	for _, row := range testRows {
+		rb.Append(row)
+	}
+	assert.Equal(t, 4, rb.Len())
+	rec := rb.NewRecord()
+	assert.Equal(t, 0, rb.Len())
+	assert.Equal(t, 4, rec.Len)
+
+	assert.Equal(t, []driver.Value{"aaron", int64(82), 1.5, true, t1, []byte{1, 2}}, rec.Row(0))
+	assert.Equal(t, []driver.Value{nil, nil, nil, nil, nil, nil}, rec.Row(1))
+	t2 := time.Date(2009, 12, 11, 19, 53, 31, 0, time.UTC)
+	assert.Equal(t, []driver.Value{"bob", int64(12), float64(3), false, t2, []byte("x")}, rec.Row(2))
+	assert.Equal(t, []driver.Value{"ç", int64(-1), float64(2), true, t1, nil}, rec.Row(3))
+
+	name := rec.Columns[0]
+	assert.Equal(t, 1, name.Nulls)
+	assert.True(t, name.IsNull(1))
+	assert.Equal(t, []int32{0, 5, 5, 8, 10}, name.Offsets)
+	raw := rec.Columns[5]
+	assert.Equal(t, 2, raw.Nulls)
+
+	// unconvertible values are null
+	b := NewBuilder(Int64)
+	b.Append("not a number")
+	b.Append(int64(1))
+	c := b.Finish()
+	assert.Equal(t, 2, c.Len)
+	assert.True(t, c.IsNull(0))
+	assert.Equal(t, int64(1), c.Int64(1))
+
+	// maps and slices are json
+	b = NewBuilder(String)
+	b.Append(map[string]interface{}{"a": 1})
+	b.Append([]string{"x", "y"})
+	c = b.Finish()
+	assert.Equal(t, `{"a":1}`, c.String(0))
+	assert.Equal(t, `["x","y"]`, c.String(1))
+}
+
+func TestRecordTake(t *testing.T) {
+	rb := NewRecordBuilder(testSchema)
+	for _, row := range testRows {
+		rb.Append(row)
+	}
+	rec := rb.NewRecord()
+
+	taken := rec.Take([]int{3, 1, 0})
+	assert.Equal(t, 3, taken.Len)
+	assert.Equal(t, rec.Row(3), taken.Row(0))
+	assert.Equal(t, rec.Row(1), taken.Row(1))
+	assert.Equal(t, rec.Row(0), taken.Row(2))
+	assert.Equal(t, 1, taken.Columns[1].Nulls)
+	assert.Equal(t, 0, rec.Take(nil).Len)
+
+	c := NewColumn(Bool, 10)
+	c.SetBool(9, true)
+	c.SetNull(2)
+	c.SetNull(2)
+	assert.Equal(t, 1, c.Nulls)
+	assert.Equal(t, nil, c.Value(2))
+	assert.Equal(t, true, c.Value(9))
+	assert.Equal(t, false, c.Value(8))
+
+	ct := rec.Columns[1].Cast(String)
+	assert.Equal(t, String, ct.Type)
+	assert.Equal(t, "82", ct.Value(0))
+	assert.Equal(t, nil, ct.Value(1))
+	assert.True(t, rec.Columns[1] == rec.Columns[1].Cast(Int64))
+}
+
+func TestIPCRoundTrip(t *testing.T) {
+	rb := NewRecordBuilder(testSchema)
+	var buf bytes.Buffer
+	w := NewWriter(&buf, testSchema)
+	for _, row := range testRows {
+		rb.Append(row)
+		if rb.Len() == 3 {
+			assert.Equal(t, nil, w.Write(rb.NewRecord()))
+		}
+	}
+	assert.Equal(t, nil, w.Write(rb.NewRecord()))
+	assert.Equal(t, nil, w.Close())
+	assert.NotEqual(t, nil, w.Write(rb.NewRecord()))
+
+	// every message is 8 byte aligned, the stream ends with a zero length
+	b := buf.Bytes()
+	assert.Equal(t, 0, len(b)%8)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}, b[len(b)-8:])
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff}, b[:4])
+
+	r, err := NewReader(bytes.NewReader(b))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, testSchema, r.Schema())
+	var rows [][]driver.Value
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, nil, err)
+		if err != nil {
+			return
+		}
+		for i := 0; i < rec.Len; i++ {
+			rows = append(rows, rec.Row(i))
+		}
+	}
+	assert.Equal(t, 4, len(rows))
+	assert.Equal(t, []driver.Value{"aaron", int64(82), 1.5, true, t1, []byte{1, 2}}, rows[0])
+	assert.Equal(t, []driver.Value{nil, nil, nil, nil, nil, nil}, rows[1])
+	assert.Equal(t, []driver.Value{"ç", int64(-1), float64(2), true, t1, nil}, rows[3])
+
+	// a schema only stream
+	buf.Reset()
+	w = NewWriter(&buf, NewSchema())
+	assert.Equal(t, nil, w.Close())
+	r, err = NewReader(&buf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(r.Schema().Fields))
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// truncated
+	_, err = NewReader(bytes.NewReader(b[:20]))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestFromValueType(t *testing.T) {
+	for vt, at := range map[value.ValueType]Type{
+		value.IntType:       Int64,
+		value.NumberType:    Float64,
+		value.BoolType:      Bool,
+		value.TimeType:      Timestamp,
+		value.StringType:    String,
+		value.ByteSliceType: Binary,
+		value.MapValueType:  String,
+		value.UnknownType:   String,
+	} {
+		assert.Equal(t, at, FromValueType(vt), vt.String())
+	}
+}
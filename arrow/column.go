@@ -0,0 +1,351 @@
+package arrow
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/araddon/qlbridge/value"
+)
+
+// Column a column (arrow array) of a single type, in the arrow memory
+// layout: a validity bitmap and the values of the type.
+type Column struct {
+	Type  Type
+	Len   int
+	Nulls int
+	// Valid the validity bitmap, bit i (lsb first) set if row i is not
+	// null, nil if there are no nulls.
+	Valid []byte
+	// Int64s values of Int64 and Timestamp (microseconds) columns
+	Int64s []int64
+	// Float64s values of Float64 columns
+	Float64s []float64
+	// Bools the bitmap of Bool columns
+	Bools []byte
+	// Offsets of String and Binary values in Data, Len+1 of them
+	Offsets []int32
+	Data    []byte
+}
+
+// IsNull is row i null
+func (m *Column) IsNull(i int) bool {
+	return m.Valid != nil && !bitSet(m.Valid, i)
+}
+
+// Int64 value of row i of an Int64 or Timestamp column
+func (m *Column) Int64(i int) int64 { return m.Int64s[i] }
+
+// Float64 value of row i of a Float64 column
+func (m *Column) Float64(i int) float64 { return m.Float64s[i] }
+
+// Bool value of row i of a Bool column
+func (m *Column) Bool(i int) bool { return bitSet(m.Bools, i) }
+
+// Bytes value of row i of a String or Binary column, not copied
+func (m *Column) Bytes(i int) []byte { return m.Data[m.Offsets[i]:m.Offsets[i+1]] }
+
+// String value of row i of a String or Binary column
+func (m *Column) String(i int) string { return string(m.Bytes(i)) }
+
+// Time value of row i of a Timestamp column
+func (m *Column) Time(i int) time.Time { return microsTime(m.Int64s[i]) }
+
+// Value of row i, nil if null
+func (m *Column) Value(i int) driver.Value {
+	if m.IsNull(i) {
+		return nil
+	}
+	switch m.Type {
+	case Bool:
+		return m.Bool(i)
+	case Int64:
+		return m.Int64(i)
+	case Float64:
+		return m.Float64(i)
+	case Binary:
+		return append([]byte{}, m.Bytes(i)...)
+	case Timestamp:
+		return m.Time(i)
+	}
+	return m.String(i)
+}
+
+// Builder builds a column a value at a time
+type Builder struct {
+	col Column
+}
+
+// NewBuilder a column builder of type t
+func NewBuilder(t Type) *Builder {
+	b := &Builder{}
+	b.reset(t)
+	return b
+}
+
+func (m *Builder) reset(t Type) {
+	m.col = Column{Type: t}
+	if t == String || t == Binary {
+		m.col.Offsets = []int32{0}
+	}
+}
+
+// Len the number of values appended
+func (m *Builder) Len() int { return m.col.Len }
+
+// AppendNull append a null
+func (m *Builder) AppendNull() {
+	c := &m.col
+	if c.Valid == nil {
+		// all previous rows were valid
+		c.Valid = make([]byte, 0, c.Len/8+1)
+		for i := 0; i < c.Len; i++ {
+			c.Valid = appendBit(c.Valid, i, true)
+		}
+	}
+	c.Valid = appendBit(c.Valid, c.Len, false)
+	c.Nulls++
+	m.appendZero()
+}
+
+func (m *Builder) appendZero() {
+	c := &m.col
+	switch c.Type {
+	case Bool:
+		c.Bools = appendBit(c.Bools, c.Len, false)
+	case Int64, Timestamp:
+		c.Int64s = append(c.Int64s, 0)
+	case Float64:
+		c.Float64s = append(c.Float64s, 0)
+	default:
+		c.Offsets = append(c.Offsets, int32(len(c.Data)))
+	}
+	c.Len++
+}
+
+// Append a value converted to the column type, nil and values that can't
+// be converted are null.
+func (m *Builder) Append(v driver.Value) {
+	v = Coerce(m.col.Type, v)
+	if v == nil {
+		m.AppendNull()
+		return
+	}
+	c := &m.col
+	switch c.Type {
+	case Bool:
+		c.Bools = appendBit(c.Bools, c.Len, v.(bool))
+	case Int64:
+		c.Int64s = append(c.Int64s, v.(int64))
+	case Float64:
+		c.Float64s = append(c.Float64s, v.(float64))
+	case Timestamp:
+		c.Int64s = append(c.Int64s, timeMicros(v.(time.Time)))
+	case Binary:
+		c.Data = append(c.Data, v.([]byte)...)
+		c.Offsets = append(c.Offsets, int32(len(c.Data)))
+	default:
+		c.Data = append(c.Data, v.(string)...)
+		c.Offsets = append(c.Offsets, int32(len(c.Data)))
+	}
+	if c.Valid != nil {
+		c.Valid = appendBit(c.Valid, c.Len, true)
+	}
+	c.Len++
+}
+
+// Coerce a value to the go type of values of column type t (bool, int64,
+// float64, time.Time, []byte or string), nil if it is nil or can't be
+// converted.
+func Coerce(t Type, v driver.Value) driver.Value {
+	if vv, ok := v.(value.Value); ok {
+		if vv.Nil() {
+			return nil
+		}
+		v = vv.Value()
+	}
+	if v == nil {
+		return nil
+	}
+	var ok bool
+	switch t {
+	case Bool:
+		if _, ok = v.(bool); !ok {
+			v, ok = value.ValueToBool(value.NewValue(v))
+		}
+	case Int64:
+		if _, ok = v.(int64); !ok {
+			v, ok = value.ValueToInt64(value.NewValue(v))
+		}
+	case Float64:
+		if _, ok = v.(float64); !ok {
+			v, ok = value.ValueToFloat64(value.NewValue(v))
+		}
+	case Timestamp:
+		if _, ok = v.(time.Time); !ok {
+			v, ok = value.ValueToTime(value.NewValue(v))
+		}
+	case Binary:
+		if _, ok = v.([]byte); !ok {
+			v, ok = toBytes(v), true
+		}
+	default:
+		if _, ok = v.(string); !ok {
+			v, ok = string(toBytes(v)), true
+		}
+	}
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// Finish the column of the values appended, the builder is reset.
+func (m *Builder) Finish() *Column {
+	c := m.col
+	m.reset(c.Type)
+	return &c
+}
+
+func toBytes(v driver.Value) []byte {
+	switch vt := v.(type) {
+	case string:
+		return []byte(vt)
+	case []byte:
+		return vt
+	case time.Time:
+		return []byte(vt.UTC().Format(time.RFC3339Nano))
+	case json.RawMessage:
+		return vt
+	}
+	val := value.NewValue(v)
+	switch val.(type) {
+	case value.StringsValue, value.MapValue, value.SliceValue, value.MapStringValue, value.MapIntValue,
+		value.MapNumberValue, value.MapBoolValue, value.MapTimeValue:
+		if b, err := json.Marshal(v); err == nil {
+			return b
+		}
+	}
+	return []byte(val.ToString())
+}
+
+func timeMicros(t time.Time) int64 {
+	return t.Unix()*1e6 + int64(t.Nanosecond()/1e3)
+}
+
+func microsTime(us int64) time.Time {
+	return time.Unix(us/1e6, (us%1e6)*1e3).UTC()
+}
+
+func bitSet(bits []byte, i int) bool {
+	return bits[i>>3]&(1<<uint(i&7)) != 0
+}
+
+// appendBit set bit i of a bitmap of i bits
+func appendBit(bits []byte, i int, set bool) []byte {
+	if i&7 == 0 {
+		bits = append(bits, 0)
+	}
+	if set {
+		bits[i>>3] |= 1 << uint(i&7)
+	}
+	return bits
+}
+
+// NewColumn a column of n valid zero values of type t, for setting values
+// by row: Int64s, Float64s directly, SetBool and SetNull.
+func NewColumn(t Type, n int) *Column {
+	c := &Column{Type: t, Len: n}
+	switch t {
+	case Bool:
+		c.Bools = make([]byte, (n+7)/8)
+	case Int64, Timestamp:
+		c.Int64s = make([]int64, n)
+	case Float64:
+		c.Float64s = make([]float64, n)
+	default:
+		c.Offsets = make([]int32, n+1)
+	}
+	return c
+}
+
+// SetBool set row i of a Bool column
+func (m *Column) SetBool(i int, v bool) {
+	if v {
+		m.Bools[i>>3] |= 1 << uint(i&7)
+	} else {
+		m.Bools[i>>3] &^= 1 << uint(i&7)
+	}
+}
+
+// SetNull set row i null
+func (m *Column) SetNull(i int) {
+	if m.IsNull(i) {
+		return
+	}
+	if m.Valid == nil {
+		m.Valid = make([]byte, (m.Len+7)/8)
+		for j := range m.Valid {
+			m.Valid[j] = 0xff
+		}
+	}
+	m.Valid[i>>3] &^= 1 << uint(i&7)
+	m.Nulls++
+}
+
+// Take a column of the rows at positions idx
+func (m *Column) Take(idx []int) *Column {
+	if m.Type == String || m.Type == Binary {
+		b := NewBuilder(m.Type)
+		c := &b.col
+		for _, i := range idx {
+			if m.IsNull(i) {
+				b.AppendNull()
+				continue
+			}
+			c.Data = append(c.Data, m.Bytes(i)...)
+			c.Offsets = append(c.Offsets, int32(len(c.Data)))
+			if c.Valid != nil {
+				c.Valid = appendBit(c.Valid, c.Len, true)
+			}
+			c.Len++
+		}
+		return b.Finish()
+	}
+	c := NewColumn(m.Type, len(idx))
+	for j, i := range idx {
+		switch m.Type {
+		case Bool:
+			c.SetBool(j, m.Bool(i))
+		case Int64, Timestamp:
+			c.Int64s[j] = m.Int64s[i]
+		case Float64:
+			c.Float64s[j] = m.Float64s[i]
+		}
+		if m.IsNull(i) {
+			c.SetNull(j)
+		}
+	}
+	return c
+}
+
+// Cast a column to type t, values that can't be converted are null.  The
+// column itself if it is already of type t.
+func (m *Column) Cast(t Type) *Column {
+	if m.Type == t {
+		return m
+	}
+	b := NewBuilder(t)
+	for i := 0; i < m.Len; i++ {
+		b.Append(m.Value(i))
+	}
+	return b.Finish()
+}
+
+// TypeOf the column type of a go value, String for nil.
+func TypeOf(v driver.Value) Type {
+	if v == nil {
+		return String
+	}
+	return FromValueType(value.NewValue(v).Type())
+}
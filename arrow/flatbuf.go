@@ -0,0 +1,267 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// The IPC message metadata is flatbuffers encoded, these are a minimal
+// encoder and decoder for the arrow Message, Schema and RecordBatch
+// tables rather than generated code.
+
+var errInvalidFlatbuf = errors.New("arrow: invalid message metadata")
+
+var le = binary.LittleEndian
+
+// fbTable a table to encode, the value of each field by slot, an untyped
+// nil for absent fields.  Values are scalars (bool, uint8, int16, int32,
+// int64) or references: string, fbTable, []fbTable or fbStructs.
+type fbTable []interface{}
+
+// fbStructs a vector of structs, the encoded struct bytes
+type fbStructs struct {
+	n int
+	b []byte
+}
+
+// fbEncode the flatbuffer of root table t
+func fbEncode(t fbTable) []byte {
+	e := &fbEncoder{b: make([]byte, 4)}
+	pos := e.table(t)
+	le.PutUint32(e.b, uint32(pos))
+	e.pad(8)
+	return e.b
+}
+
+// fbEncoder writes objects front to back, references are to objects
+// written after them so offsets are forward (unsigned) as required.
+type fbEncoder struct {
+	b []byte
+}
+
+func (e *fbEncoder) pad(align int) {
+	for len(e.b)%align != 0 {
+		e.b = append(e.b, 0)
+	}
+}
+
+func fbSize(v interface{}) int {
+	switch v.(type) {
+	case bool, uint8:
+		return 1
+	case int16:
+		return 2
+	case int64:
+		return 8
+	}
+	return 4 // int32 and references
+}
+
+func (e *fbEncoder) table(t fbTable) int {
+	// inline layout, the soffset to the vtable then fields largest first
+	slots := make([]int, 0, len(t))
+	for i, v := range t {
+		if v != nil {
+			slots = append(slots, i)
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return fbSize(t[slots[i]]) > fbSize(t[slots[j]]) })
+	offsets := make([]int, len(t))
+	size, align := 4, 4
+	for _, slot := range slots {
+		n := fbSize(t[slot])
+		for size%n != 0 {
+			size++
+		}
+		offsets[slot] = size
+		size += n
+		if n > align {
+			align = n
+		}
+	}
+
+	e.pad(2)
+	vtable := len(e.b)
+	e.b = appendU16(e.b, uint16(4+2*len(t)))
+	e.b = appendU16(e.b, uint16(size))
+	for _, off := range offsets {
+		e.b = appendU16(e.b, uint16(off))
+	}
+
+	e.pad(align)
+	start := len(e.b)
+	e.b = append(e.b, make([]byte, size)...)
+	le.PutUint32(e.b[start:], uint32(int32(start-vtable)))
+	for _, slot := range slots {
+		p := start + offsets[slot]
+		switch v := t[slot].(type) {
+		case bool:
+			if v {
+				e.b[p] = 1
+			}
+		case uint8:
+			e.b[p] = v
+		case int16:
+			le.PutUint16(e.b[p:], uint16(v))
+		case int32:
+			le.PutUint32(e.b[p:], uint32(v))
+		case int64:
+			le.PutUint64(e.b[p:], uint64(v))
+		}
+	}
+	for _, slot := range slots {
+		p := start + offsets[slot]
+		switch v := t[slot].(type) {
+		case string, fbTable, []fbTable, fbStructs:
+			child := e.ref(v)
+			le.PutUint32(e.b[p:], uint32(child-p))
+		}
+	}
+	return start
+}
+
+func (e *fbEncoder) ref(v interface{}) int {
+	switch v := v.(type) {
+	case fbTable:
+		return e.table(v)
+	case string:
+		e.pad(4)
+		pos := len(e.b)
+		e.b = appendU32(e.b, uint32(len(v)))
+		e.b = append(append(e.b, v...), 0)
+		return pos
+	case []fbTable:
+		e.pad(4)
+		pos := len(e.b)
+		e.b = appendU32(e.b, uint32(len(v)))
+		e.b = append(e.b, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			p := pos + 4 + 4*i
+			child := e.table(t)
+			le.PutUint32(e.b[p:], uint32(child-p))
+		}
+		return pos
+	case fbStructs:
+		// the structs are 8 byte aligned, after the length
+		for (len(e.b)+4)%8 != 0 {
+			e.b = append(e.b, 0)
+		}
+		pos := len(e.b)
+		e.b = appendU32(e.b, uint32(v.n))
+		e.b = append(e.b, v.b...)
+		return pos
+	}
+	panic("unsupported flatbuffer value")
+}
+
+func appendU16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendU32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendU64(b []byte, v uint64) []byte {
+	return appendU32(appendU32(b, uint32(v)), uint32(v>>32))
+}
+
+// fbRef a table of a flatbuffer being decoded.  Decoding methods panic
+// on out of range offsets, callers recover to errInvalidFlatbuf.
+type fbRef struct {
+	b   []byte
+	pos int
+}
+
+func fbRoot(b []byte) fbRef {
+	return fbRef{b: b, pos: int(le.Uint32(b))}
+}
+
+// field the position of field slot, 0 if absent
+func (t fbRef) field(slot int) int {
+	vtable := t.pos - int(int32(le.Uint32(t.b[t.pos:])))
+	vsize := int(le.Uint16(t.b[vtable:]))
+	if 4+2*slot+2 > vsize {
+		return 0
+	}
+	off := int(le.Uint16(t.b[vtable+4+2*slot:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbRef) uint8(slot int, def uint8) uint8 {
+	if p := t.field(slot); p > 0 {
+		return t.b[p]
+	}
+	return def
+}
+
+func (t fbRef) bool(slot int) bool {
+	return t.uint8(slot, 0) != 0
+}
+
+func (t fbRef) int16(slot int, def int16) int16 {
+	if p := t.field(slot); p > 0 {
+		return int16(le.Uint16(t.b[p:]))
+	}
+	return def
+}
+
+func (t fbRef) int32(slot int, def int32) int32 {
+	if p := t.field(slot); p > 0 {
+		return int32(le.Uint32(t.b[p:]))
+	}
+	return def
+}
+
+func (t fbRef) int64(slot int, def int64) int64 {
+	if p := t.field(slot); p > 0 {
+		return int64(le.Uint64(t.b[p:]))
+	}
+	return def
+}
+
+func (t fbRef) deref(p int) int {
+	return p + int(le.Uint32(t.b[p:]))
+}
+
+func (t fbRef) table(slot int) (fbRef, bool) {
+	p := t.field(slot)
+	if p == 0 {
+		return fbRef{}, false
+	}
+	return fbRef{b: t.b, pos: t.deref(p)}, true
+}
+
+func (t fbRef) string(slot int) string {
+	p := t.field(slot)
+	if p == 0 {
+		return ""
+	}
+	p = t.deref(p)
+	n := int(le.Uint32(t.b[p:]))
+	return string(t.b[p+4 : p+4+n])
+}
+
+// vector the position of the first element and length of a vector
+func (t fbRef) vector(slot int) (int, int) {
+	p := t.field(slot)
+	if p == 0 {
+		return 0, 0
+	}
+	p = t.deref(p)
+	return p + 4, int(le.Uint32(t.b[p:]))
+}
+
+// tables the tables of a vector of tables
+func (t fbRef) tables(slot int) []fbRef {
+	start, n := t.vector(slot)
+	refs := make([]fbRef, n)
+	for i := range refs {
+		refs[i] = fbRef{b: t.b, pos: t.deref(start + 4*i)}
+	}
+	return refs
+}
@@ -0,0 +1,332 @@
+package arrow
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ContentType the media type of the IPC streaming format
+const ContentType = "application/vnd.apache.arrow.stream"
+
+// flatbuffer enums of the arrow format (Schema.fbs, Message.fbs)
+const (
+	metadataV5 = 4
+
+	headerSchema      = 1
+	headerRecordBatch = 3
+
+	fbTypeInt           = 2
+	fbTypeFloatingPoint = 3
+	fbTypeBinary        = 4
+	fbTypeUtf8          = 5
+	fbTypeBool          = 6
+	fbTypeTimestamp     = 10
+
+	precisionDouble = 2
+	unitMicrosecond = 2
+
+	continuation = 0xFFFFFFFF
+)
+
+// Writer writes record batches in the IPC streaming format, the schema
+// message is written before the first record.
+type Writer struct {
+	w       io.Writer
+	schema  *Schema
+	started bool
+	closed  bool
+}
+
+// NewWriter a stream writer of records of schema s
+func NewWriter(w io.Writer, s *Schema) *Writer {
+	return &Writer{w: w, schema: s}
+}
+
+// Write a record batch
+func (m *Writer) Write(rec *Record) error {
+	if err := m.start(); err != nil {
+		return err
+	}
+	if len(rec.Columns) != len(m.schema.Fields) {
+		return fmt.Errorf("arrow: record has %d columns, schema %d", len(rec.Columns), len(m.schema.Fields))
+	}
+	var body []byte
+	var nodes, buffers []byte
+	addBuffer := func(b []byte) {
+		buffers = appendU64(appendU64(buffers, uint64(len(body))), uint64(len(b)))
+		body = append(body, b...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+	for i, c := range rec.Columns {
+		if c.Type != m.schema.Fields[i].Type {
+			return fmt.Errorf("arrow: column %d is %s, schema %s", i, c.Type, m.schema.Fields[i].Type)
+		}
+		nodes = appendU64(appendU64(nodes, uint64(c.Len)), uint64(c.Nulls))
+		if c.Nulls > 0 {
+			addBuffer(c.Valid)
+		} else {
+			addBuffer(nil)
+		}
+		switch c.Type {
+		case Bool:
+			addBuffer(c.Bools)
+		case Int64, Timestamp:
+			b := make([]byte, 0, 8*len(c.Int64s))
+			for _, v := range c.Int64s {
+				b = appendU64(b, uint64(v))
+			}
+			addBuffer(b)
+		case Float64:
+			b := make([]byte, 0, 8*len(c.Float64s))
+			for _, v := range c.Float64s {
+				b = appendU64(b, math.Float64bits(v))
+			}
+			addBuffer(b)
+		default:
+			b := make([]byte, 0, 4*len(c.Offsets))
+			for _, v := range c.Offsets {
+				b = appendU32(b, uint32(v))
+			}
+			addBuffer(b)
+			addBuffer(c.Data)
+		}
+	}
+	batch := fbTable{
+		int64(rec.Len),
+		fbStructs{n: len(rec.Columns), b: nodes},
+		fbStructs{n: len(buffers) / 16, b: buffers},
+	}
+	return m.writeMessage(headerRecordBatch, batch, body)
+}
+
+// Close write the end of stream marker, the underlying writer is not
+// closed.
+func (m *Writer) Close() error {
+	if m.closed {
+		return nil
+	}
+	if err := m.start(); err != nil {
+		return err
+	}
+	m.closed = true
+	_, err := m.w.Write(appendU32(appendU32(nil, continuation), 0))
+	return err
+}
+
+func (m *Writer) start() error {
+	if m.closed {
+		return fmt.Errorf("arrow: writer closed")
+	}
+	if m.started {
+		return nil
+	}
+	m.started = true
+	fields := make([]fbTable, len(m.schema.Fields))
+	for i, f := range m.schema.Fields {
+		typ, typeTable := fbType(f.Type)
+		fields[i] = fbTable{f.Name, f.Nullable, typ, typeTable, nil, []fbTable{}}
+	}
+	return m.writeMessage(headerSchema, fbTable{int16(0), fields}, nil)
+}
+
+func fbType(t Type) (uint8, fbTable) {
+	switch t {
+	case Bool:
+		return fbTypeBool, fbTable{}
+	case Int64:
+		return fbTypeInt, fbTable{int32(64), true}
+	case Float64:
+		return fbTypeFloatingPoint, fbTable{int16(precisionDouble)}
+	case Binary:
+		return fbTypeBinary, fbTable{}
+	case Timestamp:
+		return fbTypeTimestamp, fbTable{int16(unitMicrosecond), "UTC"}
+	}
+	return fbTypeUtf8, fbTable{}
+}
+
+// writeMessage an encapsulated message, continuation marker, metadata
+// length, the flatbuffer Message (padded to 8 bytes) and body.
+func (m *Writer) writeMessage(headerType uint8, header fbTable, body []byte) error {
+	meta := fbEncode(fbTable{int16(metadataV5), headerType, header, int64(len(body))})
+	b := make([]byte, 0, 8+len(meta)+len(body))
+	b = appendU32(appendU32(b, continuation), uint32(len(meta)))
+	b = append(append(b, meta...), body...)
+	_, err := m.w.Write(b)
+	return err
+}
+
+// Reader reads record batches of the IPC streaming format
+type Reader struct {
+	r      *bufio.Reader
+	schema *Schema
+}
+
+// NewReader a stream reader, the schema message is read.
+func NewReader(r io.Reader) (*Reader, error) {
+	m := &Reader{r: bufio.NewReader(r)}
+	typ, meta, _, err := m.readMessage()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	if typ != headerSchema {
+		return nil, fmt.Errorf("arrow: expected schema message got %d", typ)
+	}
+	if m.schema, err = decodeSchema(meta); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Schema of the records
+func (m *Reader) Schema() *Schema { return m.schema }
+
+// Next record batch, io.EOF at the end of the stream.
+func (m *Reader) Next() (*Record, error) {
+	typ, meta, body, err := m.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if typ != headerRecordBatch {
+		return nil, fmt.Errorf("arrow: unsupported message type %d", typ)
+	}
+	return decodeRecord(m.schema, meta, body)
+}
+
+// readMessage the header type and table and body of a message
+func (m *Reader) readMessage() (typ uint8, header fbRef, body []byte, err error) {
+	var n uint32
+	if err = binary.Read(m.r, le, &n); err != nil {
+		return
+	}
+	if n == continuation {
+		if err = binary.Read(m.r, le, &n); err != nil {
+			return
+		}
+	}
+	if n == 0 {
+		err = io.EOF
+		return
+	}
+	meta := make([]byte, n)
+	if _, err = io.ReadFull(m.r, meta); err != nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = errInvalidFlatbuf
+		}
+	}()
+	msg := fbRoot(meta)
+	typ = msg.uint8(1, 0)
+	var ok bool
+	if header, ok = msg.table(2); !ok {
+		return 0, header, nil, errInvalidFlatbuf
+	}
+	body = make([]byte, msg.int64(3, 0))
+	_, err = io.ReadFull(m.r, body)
+	return
+}
+
+func decodeSchema(t fbRef) (s *Schema, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errInvalidFlatbuf
+		}
+	}()
+	s = &Schema{}
+	for _, f := range t.tables(1) {
+		field := Field{Name: f.string(0), Nullable: f.bool(1)}
+		tt, _ := f.table(3)
+		switch f.uint8(2, 0) {
+		case fbTypeBool:
+			field.Type = Bool
+		case fbTypeInt:
+			if tt.int32(0, 0) != 64 || !tt.bool(1) {
+				return nil, fmt.Errorf("arrow: unsupported int type of field %q", field.Name)
+			}
+			field.Type = Int64
+		case fbTypeFloatingPoint:
+			if tt.int16(0, 0) != precisionDouble {
+				return nil, fmt.Errorf("arrow: unsupported float type of field %q", field.Name)
+			}
+			field.Type = Float64
+		case fbTypeUtf8:
+			field.Type = String
+		case fbTypeBinary:
+			field.Type = Binary
+		case fbTypeTimestamp:
+			if tt.int16(0, 0) != unitMicrosecond {
+				return nil, fmt.Errorf("arrow: unsupported timestamp unit of field %q", field.Name)
+			}
+			field.Type = Timestamp
+		default:
+			return nil, fmt.Errorf("arrow: unsupported type %d of field %q", f.uint8(2, 0), field.Name)
+		}
+		s.Fields = append(s.Fields, field)
+	}
+	return s, nil
+}
+
+func decodeRecord(s *Schema, t fbRef, body []byte) (rec *Record, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errInvalidFlatbuf
+		}
+	}()
+	rec = &Record{Schema: s, Len: int(t.int64(0, 0))}
+	nodes, nn := t.vector(1)
+	bufs, nb := t.vector(2)
+	if nn != len(s.Fields) {
+		return nil, fmt.Errorf("arrow: record has %d columns, schema %d", nn, len(s.Fields))
+	}
+	bi := 0
+	buffer := func() []byte {
+		if bi >= nb {
+			panic("missing buffer")
+		}
+		p := bufs + 16*bi
+		bi++
+		off, n := le.Uint64(t.b[p:]), le.Uint64(t.b[p+8:])
+		return append([]byte{}, body[off:off+n]...)
+	}
+	for i, f := range s.Fields {
+		p := nodes + 16*i
+		c := &Column{Type: f.Type, Len: int(le.Uint64(t.b[p:])), Nulls: int(le.Uint64(t.b[p+8:]))}
+		if valid := buffer(); c.Nulls > 0 {
+			c.Valid = valid
+		}
+		switch f.Type {
+		case Bool:
+			c.Bools = buffer()
+		case Int64, Timestamp:
+			b := buffer()
+			c.Int64s = make([]int64, c.Len)
+			for j := range c.Int64s {
+				c.Int64s[j] = int64(le.Uint64(b[8*j:]))
+			}
+		case Float64:
+			b := buffer()
+			c.Float64s = make([]float64, c.Len)
+			for j := range c.Float64s {
+				c.Float64s[j] = math.Float64frombits(le.Uint64(b[8*j:]))
+			}
+		default:
+			b := buffer()
+			c.Offsets = make([]int32, c.Len+1)
+			for j := range c.Offsets {
+				c.Offsets[j] = int32(le.Uint32(b[4*j:]))
+			}
+			c.Data = buffer()
+		}
+		rec.Columns = append(rec.Columns, c)
+	}
+	return rec, nil
+}
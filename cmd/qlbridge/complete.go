@@ -0,0 +1,122 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// statement keywords at start of line
+	statementWords = []string{"SELECT", "SHOW", "DESCRIBE", "EXPLAIN", "INSERT", "UPSERT",
+		"UPDATE", "DELETE", "CREATE", "DROP", "ALTER", "SET"}
+	// words that may follow SHOW
+	showWords = []string{"TABLES", "FULL", "DATABASES", "COLUMNS", "INDEXES", "KEYS",
+		"VARIABLES", "GLOBAL", "SESSION", "STATUS", "ENGINES", "PROCEDURE", "FUNCTION", "CREATE"}
+	// words that may follow the previous word, table means a table name
+	nextWords = map[string][]string{
+		"full":      {"TABLES", "COLUMNS"},
+		"columns":   {"FROM"},
+		"indexes":   {"FROM"},
+		"keys":      {"FROM"},
+		"global":    {"VARIABLES", "STATUS"},
+		"session":   {"VARIABLES", "STATUS"},
+		"procedure": {"STATUS"},
+		"function":  {"STATUS"},
+		"create":    {"TABLE", "VIEW", "DATABASE", "SCHEMA", "SOURCE", "FUNCTION"},
+	}
+	// words followed by a table name
+	tableWords = map[string]bool{
+		"from": true, "join": true, "into": true, "update": true, "table": true,
+		"describe": true, "desc": true, "view": true,
+	}
+	// keywords within a select statement
+	selectWords = []string{"FROM", "WHERE", "GROUP BY", "ORDER BY", "HAVING", "LIMIT",
+		"AND", "OR", "NOT", "AS", "IN", "LIKE", "BETWEEN", "IS NULL", "IS NOT NULL", "DISTINCT"}
+)
+
+// Complete the word being typed at end of line, returns the position the
+// word starts at and the candidate replacements for it.  Completes
+// keywords of SHOW and DESCRIBE statements, table names and the column
+// names of tables named in the statement.
+func (m *Shell) Complete(line string) (int, []string) {
+	start := strings.LastIndexFunc(line, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == '(' || r == '='
+	}) + 1
+	word := line[start:]
+	prior := strings.Fields(strings.ToLower(m.buf + line[:start]))
+
+	var words []string
+	switch {
+	case len(prior) == 0:
+		words = statementWords
+	case prior[0] == "show" && len(prior) == 1:
+		words = showWords
+	case tableWords[prior[len(prior)-1]]:
+		words = m.tables()
+	case nextWords[prior[len(prior)-1]] != nil:
+		words = nextWords[prior[len(prior)-1]]
+	case prior[0] == "show":
+		return start, nil
+	default:
+		words = append(m.columns(prior), selectWords...)
+	}
+	return start, matchWords(word, words)
+}
+
+func (m *Shell) tables() []string {
+	s, ok := m.reg.Schema(m.source)
+	if !ok {
+		return nil
+	}
+	return s.Tables()
+}
+
+// columns of the tables named in statement
+func (m *Shell) columns(stmt []string) []string {
+	s, ok := m.reg.Schema(m.source)
+	if !ok {
+		return nil
+	}
+	named := make(map[string]bool, len(stmt))
+	for _, w := range stmt {
+		named[strings.Trim(w, "`\";")] = true
+	}
+	seen := make(map[string]bool)
+	var cols []string
+	for _, name := range s.Tables() {
+		if !named[strings.ToLower(name)] {
+			continue
+		}
+		t, err := s.Table(name)
+		if err != nil || t == nil {
+			continue
+		}
+		for _, col := range t.Columns() {
+			if !seen[col] {
+				seen[col] = true
+				cols = append(cols, col)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// matchWords words with prefix (case-insensitive), keywords are in the
+// case of the prefix.
+func matchWords(prefix string, words []string) []string {
+	lower := strings.ToLower(prefix)
+	useLower := prefix != "" && prefix == lower
+	var matches []string
+	for _, w := range words {
+		if !strings.HasPrefix(strings.ToLower(w), lower) {
+			continue
+		}
+		if useLower && w == strings.ToUpper(w) {
+			w = strings.ToLower(w)
+		}
+		matches = append(matches, w)
+	}
+	return matches
+}
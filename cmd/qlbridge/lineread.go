@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const maxHistory = 1000
+
+// ErrInterrupt Ctrl-C while reading a line
+var ErrInterrupt = errors.New("interrupt")
+
+// completeFunc candidate completions of the word at end of line, and the
+// position it starts
+type completeFunc func(line string) (int, []string)
+
+// lineReader reads lines from a terminal with editing, history (up/down)
+// and tab completion.  If input isn't a terminal lines are read as is.
+type lineReader struct {
+	in       *os.File
+	r        *bufio.Reader
+	out      io.Writer
+	complete completeFunc
+	history  []string
+}
+
+func newLineReader(in *os.File, out io.Writer, complete completeFunc) *lineReader {
+	return &lineReader{
+		in:       in,
+		r:        bufio.NewReader(in),
+		out:      out,
+		complete: complete,
+	}
+}
+
+// ReadLine read a line, the prompt is only written to terminals.
+func (m *lineReader) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(int(m.in.Fd()))
+	if err != nil {
+		// not a terminal
+		line, err := m.r.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	defer restore()
+	return m.edit(prompt)
+}
+
+// AddHistory add a line to history, consecutive duplicates are ignored.
+func (m *lineReader) AddHistory(line string) {
+	if n := len(m.history); n > 0 && m.history[n-1] == line {
+		return
+	}
+	m.history = append(m.history, line)
+	if len(m.history) > maxHistory {
+		m.history = m.history[len(m.history)-maxHistory:]
+	}
+}
+
+// LoadHistory read history lines from file, a missing file is ignored.
+func (m *lineReader) LoadHistory(path string) {
+	by, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(by), "\n") {
+		if line != "" {
+			m.AddHistory(line)
+		}
+	}
+}
+
+// SaveHistory write history lines to file.
+func (m *lineReader) SaveHistory(path string) error {
+	if len(m.history) == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(path, []byte(strings.Join(m.history, "\n")+"\n"), 0600)
+}
+
+// edit a line in raw mode
+func (m *lineReader) edit(prompt string) (string, error) {
+	var line []rune
+	pos := 0
+	hist := len(m.history) // index into history, len is the new line
+	var pending []rune     // the new line while browsing history
+
+	redraw := func() {
+		fmt.Fprintf(m.out, "\r%s%s\x1b[K", prompt, string(line))
+		if back := len(line) - pos; back > 0 {
+			fmt.Fprintf(m.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(s []rune) {
+		line = append([]rune(nil), s...)
+		pos = len(line)
+	}
+	fmt.Fprint(m.out, prompt)
+
+	for {
+		r, _, err := m.r.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(m.out, "\r\n")
+			return string(line), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(m.out, "^C\r\n")
+			return "", ErrInterrupt
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case 127, 8: // backspace
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(line)
+		case 11: // Ctrl-K
+			line = line[:pos]
+		case 21: // Ctrl-U
+			line = line[pos:]
+			pos = 0
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && unicode.IsSpace(line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(line[start-1]) {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+		case '\t':
+			line, pos = m.tab(prompt, line, pos)
+		case 27: // escape sequence
+			switch m.escape() {
+			case "[A": // up
+				if hist > 0 {
+					if hist == len(m.history) {
+						pending = line
+					}
+					hist--
+					setLine([]rune(m.history[hist]))
+				}
+			case "[B": // down
+				if hist < len(m.history) {
+					hist++
+					if hist == len(m.history) {
+						setLine(pending)
+					} else {
+						setLine([]rune(m.history[hist]))
+					}
+				}
+			case "[C": // right
+				if pos < len(line) {
+					pos++
+				}
+			case "[D": // left
+				if pos > 0 {
+					pos--
+				}
+			case "[H", "[1~", "OH":
+				pos = 0
+			case "[F", "[4~", "OF":
+				pos = len(line)
+			case "[3~": // delete
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if !unicode.IsPrint(r) {
+				continue
+			}
+			line = append(line, 0)
+			copy(line[pos+1:], line[pos:])
+			line[pos] = r
+			pos++
+		}
+		redraw()
+	}
+}
+
+// escape read the rest of an escape sequence after ESC
+func (m *lineReader) escape() string {
+	b, err := m.r.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return ""
+	}
+	seq := []byte{b}
+	for {
+		b, err := m.r.ReadByte()
+		if err != nil {
+			return string(seq)
+		}
+		seq = append(seq, b)
+		if b >= 0x40 && b <= 0x7e {
+			return string(seq)
+		}
+	}
+}
+
+// tab complete the word before the cursor, a single candidate replaces
+// the word, multiple extend it to their common prefix and are listed.
+func (m *lineReader) tab(prompt string, line []rune, pos int) ([]rune, int) {
+	if m.complete == nil {
+		return line, pos
+	}
+	head := string(line[:pos])
+	start, words := m.complete(head)
+	if len(words) == 0 {
+		return line, pos
+	}
+	word := words[0]
+	if len(words) == 1 {
+		word += " "
+	} else {
+		word = commonPrefix(words)
+		fmt.Fprintf(m.out, "\r\n%s\r\n", strings.Join(words, "  "))
+	}
+	if len(word) < len(head)-start {
+		// case-insensitive prefix shorter than what is typed
+		word = head[start:]
+	}
+	tail := line[pos:]
+	newLine := []rune(head[:start] + word)
+	newPos := len(newLine)
+	return append(newLine, tail...), newPos
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(strings.ToLower(w), strings.ToLower(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
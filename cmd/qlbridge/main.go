@@ -0,0 +1,100 @@
+// Command qlbridge is an interactive sql shell for qlbridge sources.
+//
+//    qlbridge --schema=sources.json --source=mydb
+//
+// The schema file is a json schema.ConfigSource, or a list of them, each
+// source is added to the default registry:
+//
+//    [
+//      {"name": "mydb", "type": "sqlite", "settings": {"file": "my.db"}}
+//    ]
+//
+// Statements end with a semi-colon and may span lines, backslash commands
+// control the shell, use \? for help.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	u "github.com/araddon/gou"
+
+	// Side-Effect Import the qlbridge sql driver and sources
+	_ "github.com/araddon/qlbridge/datasource/files"
+	_ "github.com/araddon/qlbridge/datasource/sqldb"
+	_ "github.com/araddon/qlbridge/datasource/sqlite"
+	"github.com/araddon/qlbridge/expr/builtins"
+	_ "github.com/araddon/qlbridge/qlbdriver"
+	"github.com/araddon/qlbridge/schema"
+)
+
+var (
+	schemaFile  string
+	sourceName  string
+	format      = "table"
+	sqlText     string
+	historyFile string
+	logging     = "error"
+)
+
+func init() {
+	home, _ := os.UserHomeDir()
+	flag.StringVar(&schemaFile, "schema", "", "json file of schema.ConfigSource (or list of them) to load")
+	flag.StringVar(&sourceName, "source", "", "schema to connect to, defaults to first in schema file")
+	flag.StringVar(&format, "format", "table", "output format [table,csv,json]")
+	flag.StringVar(&sqlText, "e", "", "execute statement(s) and exit")
+	flag.StringVar(&historyFile, "history", filepath.Join(home, ".qlbridge_history"), "history file, empty for none")
+	flag.StringVar(&logging, "logging", "error", "logging [debug,info,warn,error]")
+}
+
+func main() {
+	flag.Parse()
+	u.SetupLogging(logging)
+
+	builtins.LoadAllBuiltins()
+
+	if schemaFile == "" {
+		fmt.Fprintln(os.Stderr, "You must provide a schema file:    --schema=sources.json")
+		os.Exit(1)
+	}
+	names, err := loadSchemaFile(schema.DefaultRegistry(), schemaFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not load schema %s: %v\n", schemaFile, err)
+		os.Exit(1)
+	}
+	if sourceName == "" {
+		sourceName = names[0]
+	}
+
+	db, err := sql.Open("qlbridge", sourceName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not open %s: %v\n", sourceName, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	sh := NewShell(db, schema.DefaultRegistry(), sourceName, os.Stdout)
+	if err := sh.SetFormat(format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if sqlText != "" {
+		sh.Errors = os.Stderr
+		if !sh.RunScript(sqlText) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	sh.Errors = os.Stdout
+	in := newLineReader(os.Stdin, os.Stdout, sh.Complete)
+	if historyFile != "" {
+		in.LoadHistory(historyFile)
+		defer in.SaveHistory(historyFile)
+	}
+	sh.Run(in)
+}
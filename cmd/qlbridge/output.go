@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// resultWriter write a result set of columns and rows
+type resultWriter func(w io.Writer, cols []string, rows [][]interface{}) error
+
+var resultWriters = map[string]resultWriter{
+	"table": writeTable,
+	"csv":   writeCsv,
+	"json":  writeJson,
+}
+
+// writeTable aligned table, numbers right aligned
+//
+//    +---------+-------+
+//    | user_id | ct    |
+//    +---------+-------+
+//    | abc     |    10 |
+//    +---------+-------+
+//    1 row
+//
+func writeTable(w io.Writer, cols []string, rows [][]interface{}) error {
+	widths := make([]int, len(cols))
+	for i, col := range cols {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	cells := make([][]string, len(rows))
+	for ri, row := range rows {
+		cells[ri] = make([]string, len(row))
+		for i, v := range row {
+			s := formatValue(v)
+			cells[ri][i] = s
+			if n := utf8.RuneCountInString(s); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	sep := &strings.Builder{}
+	sep.WriteByte('+')
+	for _, width := range widths {
+		sep.WriteString(strings.Repeat("-", width+2))
+		sep.WriteByte('+')
+	}
+	sep.WriteByte('\n')
+
+	line := func(vals []string, right func(i int) bool) string {
+		b := &strings.Builder{}
+		b.WriteByte('|')
+		for i, s := range vals {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s))
+			b.WriteByte(' ')
+			if right(i) {
+				b.WriteString(pad + s)
+			} else {
+				b.WriteString(s + pad)
+			}
+			b.WriteString(" |")
+		}
+		b.WriteByte('\n')
+		return b.String()
+	}
+
+	out := &strings.Builder{}
+	out.WriteString(sep.String())
+	out.WriteString(line(cols, func(int) bool { return false }))
+	out.WriteString(sep.String())
+	for ri, row := range cells {
+		out.WriteString(line(row, func(i int) bool { return isNumber(rows[ri][i]) }))
+	}
+	if len(rows) > 0 {
+		out.WriteString(sep.String())
+	}
+	if len(rows) == 1 {
+		out.WriteString("1 row\n")
+	} else {
+		fmt.Fprintf(out, "%d rows\n", len(rows))
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// writeCsv header line then rows, NULL is empty
+func writeCsv(w io.Writer, cols []string, rows [][]interface{}) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+	rec := make([]string, len(cols))
+	for _, row := range rows {
+		for i, v := range row {
+			if v == nil {
+				rec[i] = ""
+				continue
+			}
+			rec[i] = formatValue(v)
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJson one json object per row, keyed by column name
+func writeJson(w io.Writer, cols []string, rows [][]interface{}) error {
+	enc := json.NewEncoder(w)
+	for _, row := range rows {
+		// preserve column order rather than use a map
+		b := &strings.Builder{}
+		b.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(cols[i])
+			b.Write(key)
+			b.WriteByte(':')
+			if by, ok := v.([]byte); ok {
+				v = string(by)
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Write(val)
+		}
+		b.WriteByte('}')
+		if err := enc.Encode(json.RawMessage(b.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatValue(v interface{}) string {
+	switch vt := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(vt)
+	case string:
+		return vt
+	case time.Time:
+		return vt.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
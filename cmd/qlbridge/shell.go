@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/schema"
+)
+
+const shellHelp = `Statements end with ; and may span multiple lines.
+
+  \timing [on|off]          toggle display of statement execution time
+  \format [table|csv|json]  set (or show) output format
+  \c                        clear the current statement
+  \?                        this help
+  \q                        quit
+`
+
+// LineReader reads input lines for the shell, with a prompt.
+type LineReader interface {
+	ReadLine(prompt string) (string, error)
+	AddHistory(line string)
+}
+
+// Shell an interactive sql shell over a qlbridge database/sql connection.
+type Shell struct {
+	db     *sql.DB
+	reg    *schema.Registry
+	source string
+	out    io.Writer
+	// Errors where statement errors are written
+	Errors io.Writer
+	format string
+	timing bool
+	buf    string // current partial (multi-line) statement
+}
+
+// NewShell create a shell writing results to out.
+func NewShell(db *sql.DB, reg *schema.Registry, source string, out io.Writer) *Shell {
+	return &Shell{
+		db:     db,
+		reg:    reg,
+		source: source,
+		out:    out,
+		Errors: out,
+		format: "table",
+	}
+}
+
+// SetFormat set output format [table,csv,json]
+func (m *Shell) SetFormat(format string) error {
+	format = strings.ToLower(format)
+	if _, ok := resultWriters[format]; !ok {
+		return fmt.Errorf("unknown format %q, expected table, csv or json", format)
+	}
+	m.format = format
+	return nil
+}
+
+// Run read and execute statements until \q or end of input.
+func (m *Shell) Run(in LineReader) {
+	for {
+		prompt := m.source + "> "
+		if strings.TrimSpace(m.buf) != "" && len(m.source) > 0 {
+			prompt = strings.Repeat(" ", len(m.source)-1) + "-> "
+		}
+		line, err := in.ReadLine(prompt)
+		if err == ErrInterrupt {
+			m.buf = ""
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(m.Errors, "error: %v\n", err)
+			}
+			fmt.Fprintln(m.out)
+			return
+		}
+		if strings.TrimSpace(line) != "" {
+			in.AddHistory(line)
+		}
+		if !m.Input(line) {
+			return
+		}
+	}
+}
+
+// RunScript execute all statements in text, a trailing statement need
+// not end in a semi-colon.  Returns false if any statement failed.
+func (m *Shell) RunScript(text string) bool {
+	stmts, rest := splitStatements(text)
+	if strings.TrimSpace(rest) != "" {
+		stmts = append(stmts, rest)
+	}
+	ok := true
+	for _, stmt := range stmts {
+		if err := m.Exec(stmt); err != nil {
+			fmt.Fprintf(m.Errors, "error: %v\n", err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Input process a single line of input, executing any statements it
+// completes.  Returns false when the shell should exit.
+func (m *Shell) Input(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, `\`) {
+		return m.command(trimmed)
+	}
+	if strings.TrimSpace(m.buf) == "" {
+		switch strings.ToLower(strings.TrimRight(trimmed, ";")) {
+		case "exit", "quit":
+			return false
+		}
+	}
+	m.buf += line + "\n"
+	stmts, rest := splitStatements(m.buf)
+	m.buf = rest
+	for _, stmt := range stmts {
+		if err := m.Exec(stmt); err != nil {
+			fmt.Fprintf(m.Errors, "error: %v\n", err)
+		}
+	}
+	return true
+}
+
+// command run a backslash shell command
+func (m *Shell) command(line string) bool {
+	args := strings.Fields(line)
+	switch args[0] {
+	case `\q`, `\quit`:
+		return false
+	case `\?`, `\h`, `\help`:
+		fmt.Fprint(m.out, shellHelp)
+	case `\c`:
+		m.buf = ""
+	case `\timing`:
+		switch {
+		case len(args) == 1:
+			m.timing = !m.timing
+		case strings.ToLower(args[1]) == "on":
+			m.timing = true
+		case strings.ToLower(args[1]) == "off":
+			m.timing = false
+		default:
+			fmt.Fprintf(m.Errors, "expected \\timing [on|off] got %q\n", line)
+			return true
+		}
+		onOff := "off"
+		if m.timing {
+			onOff = "on"
+		}
+		fmt.Fprintf(m.out, "Timing is %s.\n", onOff)
+	case `\format`:
+		if len(args) > 1 {
+			if err := m.SetFormat(args[1]); err != nil {
+				fmt.Fprintln(m.Errors, err)
+				return true
+			}
+		}
+		fmt.Fprintf(m.out, "Output format is %s.\n", m.format)
+	default:
+		fmt.Fprintf(m.Errors, "unknown command %s, use \\? for help\n", args[0])
+	}
+	return true
+}
+
+// Exec a single statement writing its results.
+func (m *Shell) Exec(stmt string) error {
+	stmt = strings.TrimSpace(stmt)
+	if stmt == "" {
+		return nil
+	}
+	start := time.Now()
+	if !returnsRows(stmt) {
+		result, err := m.db.Exec(stmt)
+		if err != nil {
+			return err
+		}
+		if ct, err := result.RowsAffected(); err == nil && ct > 0 {
+			fmt.Fprintf(m.out, "OK, %d rows affected\n", ct)
+		} else {
+			fmt.Fprintln(m.out, "OK")
+		}
+		m.writeTiming(start)
+		return nil
+	}
+
+	rows, err := m.db.Query(stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var vals [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		vals = append(vals, row)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := resultWriters[m.format](m.out, cols, vals); err != nil {
+		return err
+	}
+	m.writeTiming(start)
+	return nil
+}
+
+func (m *Shell) writeTiming(start time.Time) {
+	if m.timing {
+		fmt.Fprintf(m.out, "Time: %.3f ms\n", float64(time.Since(start))/float64(time.Millisecond))
+	}
+}
+
+// returnsRows does statement return a result set, or only rows affected
+func returnsRows(stmt string) bool {
+	kw := strings.ToLower(strings.Fields(stmt)[0])
+	switch kw {
+	case "select", "show", "describe", "desc", "explain", "with":
+		return true
+	}
+	return false
+}
+
+// splitStatements split text into complete semi-colon terminated
+// statements (without the semi-colon) and the remaining partial
+// statement.  Semi-colons in quotes or -- comments don't end statements.
+func splitStatements(text string) ([]string, string) {
+	var stmts []string
+	var quote rune
+	start := 0
+	inComment := false
+	for i, r := range text {
+		switch {
+		case inComment:
+			if r == '\n' {
+				inComment = false
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && strings.HasPrefix(text[i:], "--"):
+			inComment = true
+		case r == ';':
+			if stmt := strings.TrimSpace(text[start:i]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = i + 1
+		}
+	}
+	rest := text[start:]
+	if strings.TrimSpace(rest) == "" {
+		rest = ""
+	}
+	return stmts, rest
+}
+
+// loadSchemaFile add the sources in a json file of schema.ConfigSource,
+// or list of them, to the registry returning their names.
+func loadSchemaFile(reg *schema.Registry, path string) ([]string, error) {
+	by, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var confs []*schema.ConfigSource
+	if trimmed := strings.TrimSpace(string(by)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(by, &confs)
+	} else {
+		conf := &schema.ConfigSource{}
+		err = json.Unmarshal(by, conf)
+		confs = append(confs, conf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(confs) == 0 {
+		return nil, fmt.Errorf("no sources in %s", path)
+	}
+	names := make([]string, 0, len(confs))
+	for _, conf := range confs {
+		if conf.Name == "" {
+			return nil, fmt.Errorf("source in %s must have a name", path)
+		}
+		if err := reg.SchemaAddFromConfig(conf); err != nil {
+			return nil, err
+		}
+		names = append(names, conf.Name)
+	}
+	return names, nil
+}
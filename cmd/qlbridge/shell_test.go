@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource/mockcsv"
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/schema"
+)
+
+func newTestShell(t *testing.T) (*Shell, *bytes.Buffer) {
+	td.LoadTestDataOnce()
+	db, err := sql.Open("qlbridge", mockcsv.SchemaName)
+	assert.Equal(t, nil, err)
+	out := &bytes.Buffer{}
+	return NewShell(db, schema.DefaultRegistry(), mockcsv.SchemaName, out), out
+}
+
+// lines fake LineReader
+type lines []string
+
+func (m *lines) ReadLine(prompt string) (string, error) {
+	if len(*m) == 0 {
+		return "", io.EOF
+	}
+	line := (*m)[0]
+	*m = (*m)[1:]
+	return line, nil
+}
+func (m *lines) AddHistory(line string) {}
+
+func TestSplitStatements(t *testing.T) {
+	stmts, rest := splitStatements("select 1; select 'a;b' from x;\n-- a ; comment\nselect")
+	assert.Equal(t, []string{"select 1", "select 'a;b' from x"}, stmts)
+	assert.Equal(t, "\n-- a ; comment\nselect", rest)
+
+	stmts, rest = splitStatements("select `a;` from x;  \n")
+	assert.Equal(t, []string{"select `a;` from x"}, stmts)
+	assert.Equal(t, "", rest)
+}
+
+func TestShellRun(t *testing.T) {
+	sh, out := newTestShell(t)
+	in := &lines{
+		`SELECT user_id, referral_count`,
+		`FROM users WHERE user_id = "9Ip1aKbeZe2njCDM";`,
+		`\format csv`,
+		`SELECT user_id FROM users WHERE user_id = "9Ip1aKbeZe2njCDM"; SELECT`,
+		`\c`,
+		`\format json`,
+		`SELECT user_id, referral_count FROM users WHERE user_id = "9Ip1aKbeZe2njCDM";`,
+		`SELECT not_a_func( FROM users;`,
+		`\timing on`,
+		`\q`,
+		`SELECT 1;`,
+	}
+	sh.Run(in)
+	assert.Equal(t, 1, len(*in), "should stop at \\q")
+	expected := `+------------------+----------------+
+| user_id          | referral_count |
++------------------+----------------+
+| 9Ip1aKbeZe2njCDM | 82             |
++------------------+----------------+
+1 row
+Output format is csv.
+user_id
+9Ip1aKbeZe2njCDM
+Output format is json.
+{"user_id":"9Ip1aKbeZe2njCDM","referral_count":"82"}
+error: `
+	assert.True(t, strings.HasPrefix(out.String(), expected), "got:\n%s", out.String())
+	assert.True(t, strings.HasSuffix(out.String(), "Timing is on.\n"), out.String())
+
+	assert.NotEqual(t, nil, sh.SetFormat("xml"))
+	out.Reset()
+	assert.Equal(t, true, sh.RunScript(`SELECT user_id FROM users WHERE user_id = "9Ip1aKbeZe2njCDM"`))
+	assert.Equal(t, false, sh.RunScript(`SELECT user_id FROM not_a_table`))
+}
+
+func TestShellComplete(t *testing.T) {
+	sh, _ := newTestShell(t)
+	complete := func(line string) []string {
+		_, words := sh.Complete(line)
+		return words
+	}
+	assert.Equal(t, []string{"SELECT", "SHOW", "SET"}, complete("S"))
+	assert.Equal(t, []string{"tables"}, complete("show t"))
+	assert.Equal(t, []string{"COLUMNS", "CREATE"}, complete("SHOW C"))
+	assert.Equal(t, []string{"FROM"}, complete("SHOW FULL COLUMNS "))
+	assert.Equal(t, []string{"users"}, complete("show columns from us"))
+	assert.Equal(t, []string{"users"}, complete("describe u"))
+	assert.Equal(t, []string{"orders"}, complete("SELECT * FROM ord"))
+	assert.Equal(t, 0, len(complete("SELECT ref")), "no table named yet")
+	assert.Equal(t, []string{"referral_count"}, complete("SELECT user_id FROM users WHERE ref"))
+
+	start, words := sh.Complete("SELECT email,inter")
+	assert.Equal(t, 13, start)
+	assert.Equal(t, 0, len(words))
+	start, words = sh.Complete("select user_id FROM users WHERE w")
+	assert.Equal(t, 32, start)
+	assert.Equal(t, []string{"where"}, words)
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package main
+
+import "errors"
+
+// makeRaw line editing is not supported, lines are read as is
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("line editing not supported")
+}
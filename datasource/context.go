@@ -111,10 +111,11 @@ func (m *SqlDriverMessageMap) SetKeyHashed(key string) {
 	hasher64.Write([]byte(key))
 	m.IdVal = hasher64.Sum64()
 }
-func (m *SqlDriverMessageMap) Body() interface{}         { return m }
-func (m *SqlDriverMessageMap) Values() []driver.Value    { return m.Vals }
-func (m *SqlDriverMessageMap) SetRow(row []driver.Value) { m.Vals = row }
-func (m *SqlDriverMessageMap) Ts() time.Time             { return time.Time{} }
+func (m *SqlDriverMessageMap) Body() interface{}           { return m }
+func (m *SqlDriverMessageMap) Values() []driver.Value      { return m.Vals }
+func (m *SqlDriverMessageMap) ColumnIndex() map[string]int { return m.ColIndex }
+func (m *SqlDriverMessageMap) SetRow(row []driver.Value)   { m.Vals = row }
+func (m *SqlDriverMessageMap) Ts() time.Time               { return time.Time{} }
 func (m *SqlDriverMessageMap) Get(key string) (value.Value, bool) {
 	if idx, ok := m.ColIndex[key]; ok {
 		return value.NewValue(m.Vals[idx]), true
@@ -257,6 +258,28 @@ func (n *NestedContextReader) Ts() time.Time {
 	return n.ts
 }
 
+// Values the row values of the first reader, if it is a row with column
+// positions (SqlDriverMessageMap), so a compiled expression can read by
+// position the keys the first reader would have answered.
+func (n *NestedContextReader) Values() []driver.Value {
+	if len(n.readers) > 0 {
+		if rr, ok := n.readers[0].(*SqlDriverMessageMap); ok {
+			return rr.Vals
+		}
+	}
+	return nil
+}
+
+// ColumnIndex the column positions of the first reader, see Values().
+func (n *NestedContextReader) ColumnIndex() map[string]int {
+	if len(n.readers) > 0 {
+		if rr, ok := n.readers[0].(*SqlDriverMessageMap); ok {
+			return rr.ColIndex
+		}
+	}
+	return nil
+}
+
 func (n *NestedContextReader) Put(col expr.SchemaInfo, readCtx expr.ContextReader, v value.Value) error {
 	if n.writer != nil {
 		return n.writer.Put(col, readCtx, v)
@@ -274,8 +297,7 @@ func (n *NestedContextReader) Delete(delRow map[string]value.Value) error {
 // all keys with a name space.  This is useful if you have overlapping
 // field names between ContextReaders within a NestedContextReader.
 //
-//      msg.Get("foo.key")
-//
+//	msg.Get("foo.key")
 func NewNamespacedContextReader(basereader expr.ContextReader, namespace string) expr.ContextReader {
 	if namespace == "" {
 		return basereader
@@ -5,6 +5,8 @@
 package files
 
 import (
+	"database/sql/driver"
+	"io"
 	"strings"
 	"sync"
 
@@ -55,6 +57,26 @@ type FileHandlerTables interface {
 	Tables() []*FileTable
 }
 
+// FileHandlerWriter - file handlers may optionally provide the ability to
+// write rows in their format, which allows INSERT and SELECT INTO against
+// the tables of a FileSource.  New files are appended to the folder of the
+// table, rolled over once they reach the sources max file size.
+type FileHandlerWriter interface {
+	FileHandler
+	// FileExtension is the file-name extension (without dot) of written files.
+	FileExtension() string
+	// Writer create a row writer for given table, writing onto @w.
+	Writer(w io.Writer, tbl *schema.Table) (FileRowWriter, error)
+}
+
+// FileRowWriter writes rows of a single file, created by FileHandlerWriter.
+type FileRowWriter interface {
+	// Write a single row, values are in the same order as table columns.
+	Write(row []driver.Value) error
+	// Flush any buffered rows, does not close the underlying writer.
+	Flush() error
+}
+
 // FileHandlerSchema - file handlers may optionally provide info about
 // tables contained
 type FileHandlerSchema interface {
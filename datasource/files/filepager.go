@@ -19,6 +19,7 @@ var (
 	_ FileReaderIterator  = (*FilePager)(nil)
 	_ schema.ConnScanner  = (*FilePager)(nil)
 	_ exec.ExecutorSource = (*FilePager)(nil)
+	_ schema.ConnMutation = (*FilePager)(nil)
 
 	// Default file queue size to buffer by pager
 	FileBufferSize = 5
@@ -71,6 +72,13 @@ func (m *FilePager) WalkExecSource(p *plan.Source) (exec.Task, error) {
 	return exec.NewSource(p.Context(), p)
 }
 
+// CreateMutator part of Mutator interface to allow INSERT and SELECT INTO
+// this table, by appending new files.  See FileWriter.
+func (m *FilePager) CreateMutator(pc interface{}) (schema.ConnMutator, error) {
+	ctx, _ := pc.(*plan.Context)
+	return NewFileWriter(m.fs, m.table, ctx)
+}
+
 // Columns part of Conn interface for providing columns for this table/conn
 func (m *FilePager) Columns() []string {
 	if m.tbl == nil {
@@ -96,7 +104,7 @@ func (m *FilePager) NextScanner() (schema.ConnScanner, error) {
 		return nil, err
 	}
 
-	scanner, err := m.fs.fh.Scanner(m.fs.store, fr)
+	scanner, err := m.fs.scanner(fr)
 	if err != nil {
 		u.Errorf("Could not open file scanner %v err=%v", m.fs.fileType, err)
 		return nil, err
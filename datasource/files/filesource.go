@@ -78,6 +78,9 @@ type FileSource struct {
 	Partitioner    string // random, ??  (date, keyed?)
 	partitionFunc  Partitioner
 	partitionCt    uint64
+	introspectCt   int  // rows per file to introspect, -1 = full-scan
+	flatten        bool // flatten nested json into dotted columns
+	schemaCache    bool // persist introspected schema into store
 }
 
 // NewFileSource provides a singleton manager for a particular
@@ -89,6 +92,7 @@ func NewFileSource() *FileSource {
 		tables:        make(map[string]*FileTable),
 		tablenames:    make([]string, 0),
 		partitionFunc: SipPartitioner,
+		introspectCt:  datasource.IntrospectCount,
 	}
 	return &m
 }
@@ -146,6 +150,13 @@ func (m *FileSource) init() error {
 		if partitioner := conf.String("partitioner"); partitioner != "" {
 			m.Partitioner = partitioner
 		}
+		// "introspect" = "full" reads every row of every file to build
+		// schema, otherwise the first rows of each file.
+		if conf.String("introspect") == "full" {
+			m.introspectCt = -1
+		}
+		m.flatten = conf.Bool("flatten")
+		m.schemaCache = conf.Bool("schemacache")
 
 		store, err := FileStoreLoader(m.ss)
 		if err != nil {
@@ -191,6 +202,9 @@ func (m *FileSource) init() error {
 }
 
 func (m *FileSource) File(o cloudstorage.Object) *FileInfo {
+	if isSchemaCacheFile(o.Name()) {
+		return nil
+	}
 	fi := m.fh.File(m.path, o)
 	if fi == nil {
 		// u.Debugf("ignoring file, path:%v  %q  is nil", m.path, o.Name())
@@ -266,7 +280,7 @@ func (m *FileSource) findTablesFromFileNames() error {
 				return err
 			}
 
-			fi := m.fh.File(m.path, o)
+			fi := m.File(o)
 			if fi == nil || fi.Name == "" {
 				u.Warnf("no file?? %#v", o)
 				continue
@@ -329,37 +343,100 @@ func (m *FileSource) Table(tableName string) (*schema.Table, error) {
 	return t, nil
 }
 
+// buildTable introspects the files of a table to create a schema.  Each
+// file is introspected (first n rows, or all rows if "introspect"="full")
+// and the schema is extended/widened to fit each one.  If "schemacache"
+// is enabled the schema is persisted into the store and only files not
+// yet seen are introspected.
 func (m *FileSource) buildTable(tableName string) (*schema.Table, error) {
 
-	// Since we don't have a table schema, lets create one via introspection
 	//u.Debugf("introspecting file-table %q for schema type=%q path=%s", tableName, m.fileType, m.path)
-	pager, err := m.createPager(tableName, 0, 1)
+	t := schema.NewTable(tableName)
+	seen := make(map[string]bool)
+	var files []string
+	if m.schemaCache {
+		if c := m.loadSchemaCache(tableName); c != nil {
+			t = c.Table(tableName)
+			for _, f := range c.Files {
+				seen[f] = true
+			}
+			files = c.Files
+		}
+	}
+
+	pager, err := m.createPager(tableName, 0, 0)
 	if err != nil {
 		u.Errorf("could not find scanner for table %q table err:%v", tableName, err)
 		return nil, err
 	}
+	defer pager.Close()
 
-	scanner, err := pager.NextScanner()
-	if err != nil {
-		u.Errorf("what, no scanner? table=%q  err=%v", tableName, err)
-		return nil, err
+	newFiles := 0
+	for {
+		fr, err := pager.NextFile()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if seen[fr.Name] {
+			fr.F.Close()
+			continue
+		}
+		if err = m.introspectFile(t, fr); err != nil {
+			return nil, err
+		}
+		seen[fr.Name] = true
+		files = append(files, fr.Name)
+		newFiles++
 	}
 
-	colScanner, hasColumns := scanner.(schema.ConnColumns)
-	if !hasColumns {
-		return nil, fmt.Errorf("Must have Columns to Introspect Tables")
+	if len(t.Columns()) == 0 {
+		return nil, fmt.Errorf("Could not introspect columns for table %q", tableName)
+	}
+	if m.schemaCache && newFiles > 0 {
+		if err = m.saveSchemaCache(tableName, newTableSchemaCache(t, files)); err != nil {
+			u.Warnf("could not save schema cache for %q err=%v", tableName, err)
+		}
 	}
+	//u.Infof("built table %v %v", tableName, t.Columns())
+	return t, nil
+}
 
-	t := schema.NewTable(tableName)
-	t.SetColumns(colScanner.Columns())
+func (m *FileSource) introspectFile(t *schema.Table, fr *FileReader) error {
+	defer fr.F.Close()
+
+	scanner, err := m.scanner(fr)
+	if err != nil {
+		u.Errorf("what, no scanner? table=%q  err=%v", t.Name, err)
+		return err
+	}
+	if len(t.Columns()) == 0 {
+		colScanner, hasColumns := scanner.(schema.ConnColumns)
+		if !hasColumns {
+			return fmt.Errorf("Must have Columns to Introspect Tables")
+		}
+		t.SetColumns(colScanner.Columns())
+	}
 
-	// we are going to look at ~10 rows to create schema for it
-	if err = datasource.IntrospectTable(t, scanner); err != nil {
+	if err = datasource.IntrospectTableCount(t, scanner, m.introspectCt); err != nil {
 		u.Errorf("Could not introspect schema %v", err)
+		return err
+	}
+	return nil
+}
+
+// scanner creates the file-handler scanner for a file, flattening
+// nested documents if enabled.
+func (m *FileSource) scanner(fr *FileReader) (schema.ConnScanner, error) {
+	scanner, err := m.fh.Scanner(m.store, fr)
+	if err != nil {
 		return nil, err
 	}
-	//u.Infof("built table %v %v", tableName, t.Columns())
-	return t, nil
+	if m.flatten {
+		return &flattenScanner{scanner}, nil
+	}
+	return scanner, nil
 }
 
 func (m *FileSource) createPager(tableName string, partition, limit int) (*FilePager, error) {
@@ -0,0 +1,248 @@
+package files
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+	"github.com/lytics/cloudstorage"
+	"golang.org/x/net/context"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+)
+
+var (
+	// Ensure our file writer is a full mutator connection
+	_ schema.ConnMutator = (*FileWriter)(nil)
+	_ schema.ConnFlusher = (*FileWriter)(nil)
+
+	// FileSizeMax is the default size in bytes at which a written file
+	// is closed and rolled over to a new one.  Over-ridden per source
+	// with the "maxfilesize" setting.
+	FileSizeMax = 64 * 1024 * 1024
+)
+
+// FileWriter is a mutator connection that appends rows to a FileSource
+// table by writing new files into the folder of that table.  The format
+// is determined by the sources FileHandler which must implement FileHandlerWriter.
+//
+//	path/tables/appearances/appearances.csv                      // original
+//	path/tables/appearances/appearances_1500000000000000000_0.csv  // written
+//
+// Files are rolled over to a new file once they reach max size, and are
+// finalized on Flush() which is called at end of each mutation statement.
+type FileWriter struct {
+	fs      *FileSource
+	fh      FileHandlerWriter
+	store   cloudstorage.Store
+	table   string
+	tbl     *schema.Table
+	folder  string
+	colPos  []int // position in table of each column of insert, select into
+	maxSize int
+	seq     int
+	wc      io.WriteCloser
+	cw      *countingWriter
+	rw      FileRowWriter
+	rowct   int64
+}
+
+// countingWriter counts bytes written to underlying file.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (m *countingWriter) Write(p []byte) (int, error) {
+	n, err := m.w.Write(p)
+	m.n += n
+	return n, err
+}
+
+// NewFileWriter create a FileWriter for given table of the file-source.  The plan
+// context is optional, if it is an INSERT with a list of columns or a SELECT INTO
+// then rows are mapped by name from those columns to the table columns.
+func NewFileWriter(fs *FileSource, table string, ctx *plan.Context) (*FileWriter, error) {
+
+	fh, ok := fs.fh.(FileHandlerWriter)
+	if !ok {
+		return nil, fmt.Errorf("FileHandler for format %q does not support writes", fs.fileType)
+	}
+	store, ok := fs.store.(cloudstorage.Store)
+	if !ok {
+		return nil, fmt.Errorf("FileStore %T is not writeable", fs.store)
+	}
+	tbl, err := fs.Table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tables that were found from file-names (rootpath/users.csv) don't have a
+	// folder, new files are written into a folder of the table name.
+	folder := path.Join(fs.path, table)
+	if ft, exists := fs.tables[table]; exists && ft.PartialPath != "" {
+		folder = path.Join(fs.path, ft.PartialPath)
+	}
+
+	m := &FileWriter{
+		fs:      fs,
+		fh:      fh,
+		store:   store,
+		table:   table,
+		tbl:     tbl,
+		folder:  folder,
+		maxSize: FileSizeMax,
+	}
+	if fs.ss != nil && fs.ss.Conf != nil {
+		if maxSize, ok := fs.ss.Conf.Settings.IntSafe("maxfilesize"); ok && maxSize > 0 {
+			m.maxSize = maxSize
+		}
+	}
+
+	if ctx != nil {
+		var names []string
+		switch stmt := ctx.Stmt.(type) {
+		case *rel.SqlInsert:
+			for _, col := range stmt.Columns {
+				names = append(names, col.Key())
+			}
+		case *rel.SqlSelect:
+			if stmt.Into != nil {
+				for _, col := range stmt.Columns {
+					if col.Star {
+						// positional, the table columns of the source
+						names = nil
+						break
+					}
+					names = append(names, col.As)
+				}
+			}
+		}
+		if len(names) > 0 {
+			m.colPos = make([]int, len(names))
+			for i, name := range names {
+				pos, exists := tbl.FieldPositions[strings.ToLower(name)]
+				if !exists {
+					return nil, fmt.Errorf("Column %q not found in table %q", name, table)
+				}
+				m.colPos[i] = pos
+			}
+		}
+	}
+	return m, nil
+}
+
+// Put a single row, expects []driver.Value
+func (m *FileWriter) Put(ctx context.Context, key schema.Key, row interface{}) (schema.Key, error) {
+	switch rowVals := row.(type) {
+	case []driver.Value:
+		if err := m.writeRow(rowVals); err != nil {
+			return nil, err
+		}
+		return schema.NewKeyUint(uint64(m.rowct)), nil
+	default:
+		return nil, fmt.Errorf("Expected []driver.Value but got %T", row)
+	}
+}
+
+// PutMulti many rows, expects [][]driver.Value
+func (m *FileWriter) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
+	switch rows := src.(type) {
+	case [][]driver.Value:
+		keys := make([]schema.Key, 0, len(rows))
+		for _, row := range rows {
+			if err := m.writeRow(row); err != nil {
+				return nil, err
+			}
+			keys = append(keys, schema.NewKeyUint(uint64(m.rowct)))
+		}
+		return keys, nil
+	}
+	return nil, fmt.Errorf("unrecognized put object type: %T", src)
+}
+
+// Delete is not supported for files.
+func (m *FileWriter) Delete(driver.Value) (int, error) { return 0, schema.ErrNotImplemented }
+
+// DeleteExpression is not supported for files.
+func (m *FileWriter) DeleteExpression(p interface{}, n expr.Node) (int, error) {
+	return 0, schema.ErrNotImplemented
+}
+
+// Flush finalizes the file currently being written, next row written
+// will start a new file.
+func (m *FileWriter) Flush() error {
+	if m.wc == nil {
+		return nil
+	}
+	wc, rw := m.wc, m.rw
+	m.wc, m.rw, m.cw = nil, nil, nil
+	if err := rw.Flush(); err != nil {
+		wc.Close()
+		return err
+	}
+	return wc.Close()
+}
+
+// Close flushes current file.
+func (m *FileWriter) Close() error { return m.Flush() }
+
+func (m *FileWriter) writeRow(row []driver.Value) error {
+
+	cols := m.tbl.Columns()
+	vals := row
+	if len(m.colPos) > 0 {
+		if len(row) != len(m.colPos) {
+			return fmt.Errorf("Wrong number of columns, expected %v got %v", len(m.colPos), len(row))
+		}
+		vals = make([]driver.Value, len(cols))
+		for i, pos := range m.colPos {
+			vals[pos] = row[i]
+		}
+	} else if len(row) != len(cols) {
+		return fmt.Errorf("Wrong number of columns, expected %v got %v", len(cols), len(row))
+	}
+
+	if m.rw == nil || m.cw.n >= m.maxSize {
+		if err := m.Flush(); err != nil {
+			return err
+		}
+		if err := m.nextFile(); err != nil {
+			return err
+		}
+	}
+	if err := m.rw.Write(vals); err != nil {
+		return err
+	}
+	// flush the row writer so the byte count of file is accurate
+	if err := m.rw.Flush(); err != nil {
+		return err
+	}
+	m.rowct++
+	return nil
+}
+
+func (m *FileWriter) nextFile() error {
+	name := path.Join(m.folder, fmt.Sprintf("%s_%d_%d.%s", m.table, time.Now().UnixNano(), m.seq, m.fh.FileExtension()))
+	m.seq++
+	wc, err := m.store.NewWriter(name, nil)
+	if err != nil {
+		u.Errorf("could not open %q for writing %v", name, err)
+		return err
+	}
+	cw := &countingWriter{w: wc}
+	rw, err := m.fh.Writer(cw, m.tbl)
+	if err != nil {
+		wc.Close()
+		return err
+	}
+	m.wc, m.cw, m.rw = wc, cw, rw
+	return nil
+}
@@ -0,0 +1,146 @@
+package files_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource/files"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/testutil"
+)
+
+type writeTestSource struct {
+	*files.FileSource
+	name     string
+	settings u.JsonHelper
+}
+
+func (m *writeTestSource) Setup(ss *schema.Schema) error {
+	ss.Conf = &schema.ConfigSource{
+		Name:       m.name,
+		SourceType: m.name,
+		Settings:   m.settings,
+	}
+	return m.FileSource.Setup(ss)
+}
+
+func writeTestFile(t *testing.T, name, data string) {
+	assert.Equal(t, nil, os.MkdirAll(filepath.Dir(name), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(name, []byte(data), 0644))
+}
+
+func countFiles(t *testing.T, dir string) int {
+	ct := 0
+	fl, err := ioutil.ReadDir(dir)
+	assert.Equal(t, nil, err)
+	for _, f := range fl {
+		if filepath.Ext(f.Name()) != ".metadata" {
+			ct++
+		}
+	}
+	return ct
+}
+
+func TestFileWriterCsv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbridge_writecsv")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "writecsv/users/users.csv"), "user_id,name,ct\n1,aaron,5\n2,bob,3\n")
+	writeTestFile(t, filepath.Join(dir, "writecsv/archive/archive.csv"), "user_id,name,ct\n0,zed,1\n")
+
+	schema.RegisterSourceAsSchema("testwritecsv", &writeTestSource{
+		FileSource: files.NewFileSource(),
+		name:       "testwritecsv",
+		settings: u.JsonHelper(map[string]interface{}{
+			"path":        "writecsv",
+			"format":      "csv",
+			"type":        "localfs",
+			"localpath":   dir,
+			"maxfilesize": 10,
+		}),
+	})
+
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "testwritecsv",
+		Exec:        `INSERT INTO users (user_id, name, ct) VALUES ("3", "carl", 7), ("4", "dee", 9);`,
+		ExpectRowCt: 2,
+	})
+	// Partial list of columns, in different order than table
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "testwritecsv",
+		Exec:        `INSERT INTO users (name, user_id) VALUES ("eve", "5");`,
+		ExpectRowCt: 1,
+	})
+	// small maxfilesize means each row rolls over into a new file
+	assert.Equal(t, 4, countFiles(t, filepath.Join(dir, "writecsv/users")))
+
+	testutil.TestSqlSelect(t, "testwritecsv", `SELECT name FROM users WHERE user_id = "5";`,
+		[][]driver.Value{{"eve"}},
+	)
+	testutil.TestSqlSelect(t, "testwritecsv", `SELECT count(*) AS ct FROM users;`,
+		[][]driver.Value{{int64(5)}},
+	)
+
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "testwritecsv",
+		Exec:        `SELECT user_id, name, ct INTO archive FROM users WHERE user_id = "1" OR user_id = "3";`,
+		ExpectRowCt: 2,
+	})
+	testutil.TestSqlSelect(t, "testwritecsv", `SELECT count(*) AS ct FROM archive;`,
+		[][]driver.Value{{int64(3)}},
+	)
+
+	// select columns are written to the table columns of the same name
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "testwritecsv",
+		Exec:        `SELECT name, user_id INTO archive FROM users WHERE user_id = "2";`,
+		ExpectRowCt: 1,
+	})
+	testutil.TestSqlSelect(t, "testwritecsv", `SELECT name FROM archive WHERE user_id = "2";`,
+		[][]driver.Value{{"bob"}},
+	)
+	db, err := sql.Open("qlbridge", "testwritecsv")
+	assert.Equal(t, nil, err)
+	defer db.Close()
+	_, err = db.Exec(`SELECT user_id, name AS nickname INTO archive FROM users;`)
+	assert.NotEqual(t, nil, err, "nickname is not a column of archive")
+}
+
+func TestFileWriterJson(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbridge_writejson")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "writejson/events/events.json"), `{"user_id":"1","event":"login"}`+"\n")
+
+	schema.RegisterSourceAsSchema("testwritejson", &writeTestSource{
+		FileSource: files.NewFileSource(),
+		name:       "testwritejson",
+		settings: u.JsonHelper(map[string]interface{}{
+			"path":      "writejson",
+			"format":    "json",
+			"type":      "localfs",
+			"localpath": dir,
+		}),
+	})
+
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "testwritejson",
+		Exec:        `INSERT INTO events (user_id, event) VALUES ("2", "logout"), ("3", "login");`,
+		ExpectRowCt: 2,
+	})
+	// default max size, both rows in a single new file
+	assert.Equal(t, 2, countFiles(t, filepath.Join(dir, "writejson/events")))
+
+	testutil.TestSqlSelect(t, "testwritejson", `SELECT count(*) AS ct FROM events WHERE event = "login";`,
+		[][]driver.Value{{int64(2)}},
+	)
+}
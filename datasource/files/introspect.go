@@ -0,0 +1,144 @@
+package files
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+
+	u "github.com/araddon/gou"
+	"github.com/lytics/cloudstorage"
+	"golang.org/x/net/context"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Ensure our flattening scanner wrapper is a full scanner
+	_ schema.ConnScanner = (*flattenScanner)(nil)
+)
+
+const (
+	// SchemaCacheFile is the name of persisted schema file written into
+	// the folder of each table when the "schemacache" setting is enabled.
+	SchemaCacheFile = "_schema.json"
+)
+
+// tableSchemaCache is the persisted schema of a file table, along with
+// list of files that have already been introspected into it so that only
+// new files need be introspected to extend the schema.
+type tableSchemaCache struct {
+	Columns []*cachedField `json:"columns"`
+	Files   []string       `json:"files"`
+}
+
+type cachedField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// flattenScanner wraps a file scanner, flattening nested json objects
+// into dotted column names.  See datasource.FlattenMessage
+type flattenScanner struct {
+	schema.ConnScanner
+}
+
+func (m *flattenScanner) Next() schema.Message {
+	msg := m.ConnScanner.Next()
+	if msg == nil {
+		return nil
+	}
+	return datasource.FlattenMessage(msg)
+}
+func (m *flattenScanner) Columns() []string {
+	if cols, ok := m.ConnScanner.(schema.ConnColumns); ok {
+		return cols.Columns()
+	}
+	return nil
+}
+
+// isSchemaCacheFile the persisted schema file of a table is not one of
+// its data files.
+func isSchemaCacheFile(name string) bool {
+	return path.Base(name) == SchemaCacheFile
+}
+
+func newTableSchemaCache(tbl *schema.Table, files []string) *tableSchemaCache {
+	c := &tableSchemaCache{Files: files}
+	for _, col := range tbl.Columns() {
+		f, ok := tbl.FieldMap[col]
+		if !ok {
+			continue
+		}
+		c.Columns = append(c.Columns, &cachedField{
+			Name:     f.Name,
+			Type:     f.ValueType().String(),
+			Nullable: !f.NoNulls,
+		})
+	}
+	return c
+}
+
+// Table create a table schema from the cache.
+func (m *tableSchemaCache) Table(name string) *schema.Table {
+	tbl := schema.NewTable(name)
+	cols := make([]string, 0, len(m.Columns))
+	for _, c := range m.Columns {
+		tbl.AddFieldType(c.Name, value.ValueFromString(c.Type))
+		tbl.FieldMap[c.Name].NoNulls = !c.Nullable
+		cols = append(cols, c.Name)
+	}
+	tbl.SetColumns(cols)
+	return tbl
+}
+
+// schemaCachePath the path in store of the persisted schema for table.
+func (m *FileSource) schemaCachePath(table string) string {
+	if ft, exists := m.tables[table]; exists && ft.PartialPath != "" {
+		return path.Join(m.path, ft.PartialPath, SchemaCacheFile)
+	}
+	return path.Join(m.path, "_"+table+SchemaCacheFile)
+}
+
+func (m *FileSource) loadSchemaCache(table string) *tableSchemaCache {
+	rc, err := m.store.NewReaderWithContext(context.Background(), m.schemaCachePath(table))
+	if err != nil {
+		// not yet cached
+		return nil
+	}
+	defer rc.Close()
+	by, err := ioutil.ReadAll(rc)
+	if err != nil {
+		u.Warnf("could not read schema cache for %q err=%v", table, err)
+		return nil
+	}
+	c := &tableSchemaCache{}
+	if err = json.Unmarshal(by, c); err != nil {
+		u.Warnf("could not read schema cache for %q err=%v", table, err)
+		return nil
+	}
+	return c
+}
+
+func (m *FileSource) saveSchemaCache(table string, c *tableSchemaCache) error {
+	store, ok := m.store.(cloudstorage.Store)
+	if !ok {
+		u.Warnf("FileStore %T is not writeable, can't cache schema", m.store)
+		return nil
+	}
+	by, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	wc, err := store.NewWriter(m.schemaCachePath(table), nil)
+	if err != nil {
+		return err
+	}
+	if _, err = wc.Write(by); err != nil {
+		wc.Close()
+		return err
+	}
+	return wc.Close()
+}
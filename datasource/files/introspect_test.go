@@ -0,0 +1,77 @@
+package files_test
+
+import (
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource/files"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/testutil"
+	"github.com/araddon/qlbridge/value"
+)
+
+func TestFileIntrospectSchemaCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qlbridge_introspect")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "introspect/visits/visits_1.json"),
+		`{"user_id":"1","ct":5,"geo":{"city":"Portland","zip":"97201"}}`+"\n")
+	// underscore files are data files too, only the schema cache isn't
+	writeTestFile(t, filepath.Join(dir, "introspect/visits/_visits_2.json"),
+		`{"user_id":"2","ct":2.5,"geo":{"city":"Denver"},"referrer":"google"}`+"\n")
+
+	settings := u.JsonHelper(map[string]interface{}{
+		"path":        "introspect",
+		"format":      "json",
+		"type":        "localfs",
+		"localpath":   dir,
+		"introspect":  "full",
+		"flatten":     true,
+		"schemacache": true,
+	})
+	fs := files.NewFileSource()
+	schema.RegisterSourceAsSchema("testintrospect", &writeTestSource{
+		FileSource: fs,
+		name:       "testintrospect",
+		settings:   settings,
+	})
+
+	tbl, err := fs.Table("visits")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"ct", "geo.city", "referrer", "user_id", "geo.zip"}, tbl.Columns())
+	// float in _visits_2, int in visits_1
+	assert.Equal(t, value.NumberType, tbl.FieldMap["ct"].ValueType())
+	assert.Equal(t, false, tbl.FieldMap["geo.zip"].NoNulls)
+	assert.Equal(t, false, tbl.FieldMap["referrer"].NoNulls)
+	assert.Equal(t, true, tbl.FieldMap["user_id"].NoNulls)
+
+	_, err = os.Stat(filepath.Join(dir, "introspect/visits", files.SchemaCacheFile))
+	assert.Equal(t, nil, err)
+
+	testutil.TestSqlSelect(t, "testintrospect", `SELECT user_id FROM visits WHERE geo.city = "Denver";`,
+		[][]driver.Value{{"2"}},
+	)
+
+	// A new file with a new column, a new source loads the cached schema
+	// and extends it with only the new file.
+	writeTestFile(t, filepath.Join(dir, "introspect/visits/visits_3.json"),
+		`{"user_id":"3","ct":1,"device":"mobile"}`+"\n")
+	fs2 := files.NewFileSource()
+	schema.RegisterSourceAsSchema("testintrospect2", &writeTestSource{
+		FileSource: fs2,
+		name:       "testintrospect2",
+		settings:   settings,
+	})
+	tbl, err = fs2.Table("visits")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"ct", "geo.city", "referrer", "user_id", "geo.zip", "device"}, tbl.Columns())
+	assert.Equal(t, value.NumberType, tbl.FieldMap["ct"].ValueType())
+	assert.Equal(t, false, tbl.FieldMap["geo.zip"].NoNulls)
+}
@@ -1,16 +1,23 @@
 package files
 
 import (
+	"database/sql/driver"
+	"encoding/csv"
+	"io"
+	"time"
+
 	u "github.com/araddon/gou"
 	"github.com/lytics/cloudstorage"
 
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
 )
 
 var (
 	// ensuure our csv handler implements FileHandler interface
-	_ FileHandler = (*csvFiles)(nil)
+	_ FileHandler       = (*csvFiles)(nil)
+	_ FileHandlerWriter = (*csvFiles)(nil)
 )
 
 func init() {
@@ -22,8 +29,17 @@ type csvFiles struct {
 	appendcols []string
 }
 
+// csvWriter writes rows of a single csv file, the header
+// row of table columns is written before first row.
+type csvWriter struct {
+	w           *csv.Writer
+	cols        []string
+	wroteHeader bool
+}
+
 func (m *csvFiles) Init(store FileStore, ss *schema.Schema) error { return nil }
 func (m *csvFiles) FileAppendColumns() []string                   { return m.appendcols }
+func (m *csvFiles) FileExtension() string                         { return "csv" }
 func (m *csvFiles) File(path string, obj cloudstorage.Object) *FileInfo {
 	return FileInfoFromCloudObject(path, obj)
 }
@@ -35,3 +51,30 @@ func (m *csvFiles) Scanner(store cloudstorage.StoreReader, fr *FileReader) (sche
 	}
 	return csv, nil
 }
+func (m *csvFiles) Writer(w io.Writer, tbl *schema.Table) (FileRowWriter, error) {
+	return &csvWriter{w: csv.NewWriter(w), cols: tbl.Columns()}, nil
+}
+
+func (m *csvWriter) Write(row []driver.Value) error {
+	if !m.wroteHeader {
+		m.wroteHeader = true
+		if err := m.w.Write(m.cols); err != nil {
+			return err
+		}
+	}
+	rec := make([]string, len(row))
+	for i, v := range row {
+		switch vt := v.(type) {
+		case nil:
+		case time.Time:
+			rec[i] = vt.Format(time.RFC3339Nano)
+		default:
+			rec[i] = value.NewValue(v).ToString()
+		}
+	}
+	return m.w.Write(rec)
+}
+func (m *csvWriter) Flush() error {
+	m.w.Flush()
+	return m.w.Error()
+}
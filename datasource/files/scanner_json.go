@@ -1,6 +1,10 @@
 package files
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+
 	u "github.com/araddon/gou"
 	"github.com/lytics/cloudstorage"
 
@@ -10,7 +14,8 @@ import (
 
 var (
 	// ensuure our json handler implements FileHandler interface
-	_ FileHandler = (*jsonHandler)(nil)
+	_ FileHandler       = (*jsonHandler)(nil)
+	_ FileHandlerWriter = (*jsonHandler)(nil)
 )
 
 func init() {
@@ -28,6 +33,13 @@ type jsonHandlerTables struct {
 	FileHandler
 }
 
+// jsonWriter writes new-line delimited json rows, one object per row
+// keyed by table column name.
+type jsonWriter struct {
+	enc  *json.Encoder
+	cols []string
+}
+
 // NewJsonHandler creates a json file handler for paging new-line
 // delimited rows of json file
 func NewJsonHandler(lh datasource.FileLineHandler) FileHandler {
@@ -45,6 +57,7 @@ func NewJsonHandlerTables(lh datasource.FileLineHandler, tables []string) FileHa
 
 func (m *jsonHandler) Init(store FileStore, ss *schema.Schema) error { return nil }
 func (m *jsonHandler) FileAppendColumns() []string                   { return nil }
+func (m *jsonHandler) FileExtension() string                         { return "json" }
 func (m *jsonHandler) File(path string, obj cloudstorage.Object) *FileInfo {
 	return FileInfoFromCloudObject(path, obj)
 }
@@ -56,6 +69,20 @@ func (m *jsonHandler) Scanner(store cloudstorage.StoreReader, fr *FileReader) (s
 	}
 	return js, nil
 }
+func (m *jsonHandler) Writer(w io.Writer, tbl *schema.Table) (FileRowWriter, error) {
+	return &jsonWriter{enc: json.NewEncoder(w), cols: tbl.Columns()}, nil
+}
 func (m *jsonHandlerTables) Tables() []string {
 	return m.tables
 }
+
+func (m *jsonWriter) Write(row []driver.Value) error {
+	obj := make(map[string]driver.Value, len(m.cols))
+	for i, col := range m.cols {
+		if i < len(row) {
+			obj[col] = row[i]
+		}
+	}
+	return m.enc.Encode(obj)
+}
+func (m *jsonWriter) Flush() error { return nil }
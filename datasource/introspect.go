@@ -17,6 +17,11 @@ var (
 	IntrospectCount = 20
 )
 
+const (
+	// field context key for fields whose only values so far were nil
+	introspectUntyped = "introspect_untyped"
+)
+
 // IntrospectSchema discover schema from contents of row introspection.
 func IntrospectSchema(s *schema.Schema, name string, iter schema.Iterator) error {
 	tbl, err := s.Table(name)
@@ -32,8 +37,22 @@ func IntrospectSchema(s *schema.Schema, name string, iter schema.Iterator) error
 // to create a schema.  Generally used for CSV, Json files to
 // create strongly typed schemas.
 func IntrospectTable(tbl *schema.Table, iter schema.Iterator) error {
+	return IntrospectTableCount(tbl, iter, IntrospectCount)
+}
+
+// IntrospectTableCount introspect @count rows of iterator, if count is
+// negative every row is read (full-scan).  May be called multiple times
+// for the same table (ie, once per file) and the schema is extended:
+//
+// - columns not seen before are added to end of table (and are nullable).
+// - column types are widened to fit all values seen, see value.UnifyTypes.
+// - columns with nil, empty, or missing values are marked nullable.
+func IntrospectTableCount(tbl *schema.Table, iter schema.Iterator, count int) error {
+
+	// tables that already have fields are being extended, new columns
+	// found now were missing from earlier rows so are nullable
+	hadFields := len(tbl.Fields) > 0
 
-	needsCols := len(tbl.Columns()) == 0
 	nameIndex := make(map[int]string, len(tbl.Columns()))
 	for i, colName := range tbl.Columns() {
 		nameIndex[i] = colName
@@ -41,108 +60,122 @@ func IntrospectTable(tbl *schema.Table, iter schema.Iterator) error {
 	//u.Infof("s:%s INTROSPECT SCHEMA name %q", s, name)
 	ct := 0
 	for {
+		if count >= 0 && ct >= count {
+			break
+		}
 		msg := iter.Next()
 		//u.Debugf("msg %#v", msg)
-		if msg == nil || ct > IntrospectCount {
+		if msg == nil {
 			break
 		}
+		seen := make(map[string]bool)
 		switch mt := msg.Body().(type) {
 		case []driver.Value:
 			for i, v := range mt {
-
 				k := nameIndex[i]
-				_, exists := tbl.FieldMap[k]
-
-				//u.Debugf("i:%v k:%s  v: %T %v", i, k, v, v)
-				switch val := v.(type) {
-				case int, int64, int16, int32, uint16, uint64, uint32:
-					tbl.AddFieldType(k, value.IntType)
-				case time.Time, *time.Time:
-					tbl.AddFieldType(k, value.TimeType)
-				case bool:
-					tbl.AddFieldType(k, value.BoolType)
-				case float32, float64:
-					tbl.AddFieldType(k, value.NumberType)
-				case string:
-					valType := value.ValueTypeFromStringAll(val)
-					if !exists {
-						tbl.AddFieldType(k, valType)
-						//fld := tbl.FieldMap[k]
-						//u.Debugf("add field? %+v", fld)
-						//u.Debugf("%s = %v   type: %T   vt:%s new? %v", k, val, val, valType, !exists)
-					}
-				case map[string]interface{}:
-					tbl.AddFieldType(k, value.JsonType)
-				default:
-					u.Debugf("not implemented: %T", val)
+				if k == "" {
+					continue
 				}
+				seen[k] = true
+				introspectValue(tbl, k, v, hadFields || ct > 0)
 			}
 		case *SqlDriverMessageMap:
-			if needsCols {
-				nameIndex = make(map[int]string, len(mt.ColIndex))
-				for k2, ki := range mt.ColIndex {
-					nameIndex[ki] = k2
+			// Each message may have different columns, and positions
+			keys := make([]string, len(mt.Vals))
+			for k, i := range mt.ColIndex {
+				if i < len(keys) {
+					keys[i] = k
 				}
 			}
-			for i, v := range mt.Vals {
-
-				k := nameIndex[i]
-				// if k == "" {
-				// 	for k2, ki := range mt.ColIndex {
-				// 		if ki == i {
-				// 			k = k2
-				// 			break
-				// 		}
-				// 	}
-				// }
-
-				_, exists := tbl.FieldMap[k]
-
-				//u.Debugf("i:%v k:%s  v: %T %v", i, k, v, v)
-				switch val := v.(type) {
-				case int, int64, int16, int32, uint16, uint64, uint32:
-					tbl.AddFieldType(k, value.IntType)
-				case time.Time, *time.Time:
-					tbl.AddFieldType(k, value.TimeType)
-				case bool:
-					tbl.AddFieldType(k, value.BoolType)
-				case float32, float64, json.Number:
-					tbl.AddFieldType(k, value.NumberType)
-				case string:
-					valType := value.ValueTypeFromStringAll(val)
-					if !exists {
-						tbl.AddFieldType(k, valType)
-						//fld := tbl.FieldMap[k]
-						//u.Debugf("add field? %+v", fld)
-						//u.Debugf("%s = %v   type: %T   vt:%s new? %v", k, val, val, valType, !exists)
-					}
-				case map[string]interface{}:
-					tbl.AddFieldType(k, value.JsonType)
-				case []interface{}:
-					tbl.AddFieldType(k, value.JsonType)
-				case nil:
-					// hm.....
-					tbl.AddFieldType(k, value.JsonType)
-				default:
-					tbl.AddFieldType(k, value.JsonType)
-					u.LogThrottle(u.WARN, 10, "not implemented: k:%v  %T", k, val)
+			for i, k := range keys {
+				if k == "" {
+					continue
 				}
+				seen[k] = true
+				introspectValue(tbl, k, mt.Vals[i], hadFields || ct > 0)
 			}
 		default:
 			u.Warnf("not implemented: %T", mt)
+			continue
+		}
+
+		// Any columns missing from this row are nullable
+		for _, f := range tbl.Fields {
+			if !seen[f.Name] {
+				f.NoNulls = false
+			}
 		}
 
 		ct++
 	}
-	if needsCols {
-		cols := make([]string, len(tbl.Fields))
-		for i, f := range tbl.Fields {
-			//u.Debugf("%+v", f)
-			cols[i] = f.Name
+
+	// Ensure that any new fields are appended to columns
+	cols := tbl.Columns()
+	if len(cols) < len(tbl.Fields) {
+		newCols := make([]string, 0, len(tbl.Fields))
+		newCols = append(newCols, cols...)
+		for _, f := range tbl.Fields {
+			if _, exists := tbl.FieldPositions[f.Name]; !exists {
+				newCols = append(newCols, f.Name)
+			}
 		}
-		tbl.SetColumns(cols)
+		tbl.SetColumns(newCols)
 	}
 
 	//u.Debugf("%s: %v", tbl.Name, tbl.Columns())
 	return nil
 }
+
+// introspectValue add or widen field @k for a single value
+func introspectValue(tbl *schema.Table, k string, v driver.Value, nullable bool) {
+
+	var vt value.ValueType
+	switch val := v.(type) {
+	case int, int64, int16, int32, uint16, uint64, uint32:
+		vt = value.IntType
+	case time.Time, *time.Time:
+		vt = value.TimeType
+	case bool:
+		vt = value.BoolType
+	case float32, float64, json.Number:
+		vt = value.NumberType
+	case string:
+		if val != "" {
+			vt = value.ValueTypeFromStringAll(val)
+		}
+	case map[string]interface{}, []interface{}:
+		vt = value.JsonType
+	case nil:
+		// unknown type
+	default:
+		vt = value.JsonType
+		u.LogThrottle(u.WARN, 10, "not implemented: k:%v  %T", k, val)
+	}
+
+	isNull := vt == value.NilType
+	fld, exists := tbl.FieldMap[k]
+	if !exists {
+		if isNull {
+			// we don't know type yet, json is most permissive until we
+			// see a value
+			tbl.AddFieldType(k, value.JsonType)
+			fld = tbl.FieldMap[k]
+			fld.AddContext(introspectUntyped, true)
+			return
+		}
+		tbl.AddFieldType(k, vt)
+		fld = tbl.FieldMap[k]
+		fld.NoNulls = !nullable
+		return
+	}
+	if isNull {
+		fld.NoNulls = false
+		return
+	}
+	if _, untyped := fld.Context[introspectUntyped]; untyped {
+		delete(fld.Context, introspectUntyped)
+		fld.Type = uint32(vt)
+		return
+	}
+	fld.Type = uint32(value.UnifyTypes(fld.ValueType(), vt))
+}
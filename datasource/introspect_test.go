@@ -1,6 +1,7 @@
 package datasource_test
 
 import (
+	"database/sql/driver"
 	"os"
 	"testing"
 
@@ -48,3 +49,68 @@ func TestIntrospectedCsvSchema(t *testing.T) {
 	jd := tbl.FieldMap["json_data"]
 	assert.Equal(t, int(value.JsonType), int(jd.Type), "wanted json got %s", jd.Type)
 }
+
+type msgIter struct {
+	msgs []schema.Message
+}
+
+func (m *msgIter) Next() schema.Message {
+	if len(m.msgs) == 0 {
+		return nil
+	}
+	msg := m.msgs[0]
+	m.msgs = m.msgs[1:]
+	return msg
+}
+
+func newMsgIter(cols []string, rows ...[]driver.Value) *msgIter {
+	it := &msgIter{}
+	for i, row := range rows {
+		it.msgs = append(it.msgs, datasource.NewSqlDriverMessageMapVals(uint64(i), row, cols))
+	}
+	return it
+}
+
+func TestIntrospectWidenExtend(t *testing.T) {
+	tbl := schema.NewTable("events")
+
+	// first file
+	err := datasource.IntrospectTableCount(tbl, newMsgIter([]string{"id", "score", "code"},
+		[]driver.Value{"1", int64(10), "5"},
+		[]driver.Value{"2", float64(1.5), "abc"},
+	), -1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "score", "code"}, tbl.Columns())
+	assert.Equal(t, value.NumberType, tbl.FieldMap["score"].ValueType())
+	assert.Equal(t, value.StringType, tbl.FieldMap["code"].ValueType())
+	assert.Equal(t, true, tbl.FieldMap["score"].NoNulls)
+
+	// second file has new column, missing column, and nil values
+	err = datasource.IntrospectTableCount(tbl, newMsgIter([]string{"id", "code", "referrer"},
+		[]driver.Value{"3", nil, nil},
+		[]driver.Value{"4", "x", int64(7)},
+	), -1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"id", "score", "code", "referrer"}, tbl.Columns())
+	assert.Equal(t, false, tbl.FieldMap["score"].NoNulls)
+	assert.Equal(t, false, tbl.FieldMap["code"].NoNulls)
+	assert.Equal(t, true, tbl.FieldMap["id"].NoNulls)
+	assert.Equal(t, value.IntType, tbl.FieldMap["referrer"].ValueType())
+	assert.Equal(t, false, tbl.FieldMap["referrer"].NoNulls)
+
+	// count limits the rows introspected
+	tbl = schema.NewTable("limited")
+	err = datasource.IntrospectTableCount(tbl, newMsgIter([]string{"a"},
+		[]driver.Value{int64(1)},
+		[]driver.Value{"hello"},
+	), 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, value.IntType, tbl.FieldMap["a"].ValueType())
+
+	tbl = schema.NewTable("none")
+	err = datasource.IntrospectTableCount(tbl, newMsgIter([]string{"a"},
+		[]driver.Value{int64(1)},
+	), 0)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(tbl.Fields))
+}
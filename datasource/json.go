@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
 	u "github.com/araddon/gou"
@@ -187,3 +188,54 @@ func (m *JsonSource) jsonDefaultLine(line []byte) (schema.Message, error) {
 	u.Debugf("json data: %#v \n%#v", keys, vals)
 	return NewSqlDriverMessageMap(m.rowct, vals, keys), nil
 }
+
+// FlattenMessage flattens nested json objects of a SqlDriverMessageMap into
+// dotted column names, ie {"user":{"name":"bob"}} is column "user.name".
+// Columns are sorted by name.  Other message types are returned unchanged.
+func FlattenMessage(msg schema.Message) schema.Message {
+	sdm, ok := msg.(*SqlDriverMessageMap)
+	if !ok {
+		return msg
+	}
+	// columns are sorted so the flattened message has consistent
+	// column ordering across rows
+	cols := make([]string, 0, len(sdm.ColIndex))
+	for k, i := range sdm.ColIndex {
+		if i < len(sdm.Vals) {
+			cols = append(cols, k)
+		}
+	}
+	sort.Strings(cols)
+	vals := make([]driver.Value, 0, len(sdm.Vals))
+	keys := make(map[string]int, len(sdm.Vals))
+	for _, k := range cols {
+		vals = flattenValue(k, sdm.Vals[sdm.ColIndex[k]], vals, keys)
+	}
+	return NewSqlDriverMessageMap(sdm.IdVal, vals, keys)
+}
+
+func flattenValue(k string, v driver.Value, vals []driver.Value, keys map[string]int) []driver.Value {
+	if obj, isMap := jsonObject(v); isMap && len(obj) > 0 {
+		names := make([]string, 0, len(obj))
+		for k2 := range obj {
+			names = append(names, k2)
+		}
+		sort.Strings(names)
+		for _, k2 := range names {
+			vals = flattenValue(k+"."+k2, obj[k2], vals, keys)
+		}
+		return vals
+	}
+	keys[k] = len(vals)
+	return append(vals, v)
+}
+
+func jsonObject(v driver.Value) (map[string]interface{}, bool) {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		return vt, true
+	case u.JsonHelper:
+		return map[string]interface{}(vt), true
+	}
+	return nil, false
+}
@@ -5,6 +5,8 @@ package membtree
 import (
 	"database/sql/driver"
 	"fmt"
+	"math"
+	"strings"
 
 	u "github.com/araddon/gou"
 	"github.com/dchest/siphash"
@@ -25,13 +27,14 @@ const (
 
 var (
 	// Different Features of this Static Data Source
-	_ schema.Source       = (*StaticDataSource)(nil)
-	_ schema.Conn         = (*StaticDataSource)(nil)
-	_ schema.ConnColumns  = (*StaticDataSource)(nil)
-	_ schema.ConnScanner  = (*StaticDataSource)(nil)
-	_ schema.ConnSeeker   = (*StaticDataSource)(nil)
-	_ schema.ConnUpsert   = (*StaticDataSource)(nil)
-	_ schema.ConnDeletion = (*StaticDataSource)(nil)
+	_ schema.Source          = (*StaticDataSource)(nil)
+	_ schema.Conn            = (*StaticDataSource)(nil)
+	_ schema.ConnColumns     = (*StaticDataSource)(nil)
+	_ schema.ConnScanner     = (*StaticDataSource)(nil)
+	_ schema.ConnSeeker      = (*StaticDataSource)(nil)
+	_ schema.ConnIndexSeeker = (*StaticDataSource)(nil)
+	_ schema.ConnUpsert      = (*StaticDataSource)(nil)
+	_ schema.ConnDeletion    = (*StaticDataSource)(nil)
 )
 
 // Key implements Key and Sort interfaces.
@@ -111,6 +114,7 @@ func makeId(dv driver.Value) uint64 {
 //
 // Features
 // - only a single column may (and must) be identified as the "Indexed" column
+// - index seeks for =, IN on indexed column, and ranges if integer
 // - NOT threadsafe
 // - each StaticDataSource = a single Table
 type StaticDataSource struct {
@@ -121,6 +125,9 @@ type StaticDataSource struct {
 	cursor   btree.Item // cursor position for paging
 	bt       *btree.BTree
 	max      int
+	seek     []*DriverItem // rows found by SeekIndex
+	seekPos  int
+	seeking  bool
 }
 
 func NewStaticDataSource(name string, indexedCol int, data [][]driver.Value, cols []string) *StaticDataSource {
@@ -132,6 +139,11 @@ func NewStaticDataSource(name string, indexedCol int, data [][]driver.Value, col
 	m.tbl = tbl
 	m.bt = btree.New(32)
 	m.tbl.SetColumns(cols)
+	if indexedCol < len(cols) {
+		m.tbl.Indexes = []*schema.Index{
+			{Name: "id", Fields: []string{cols[indexedCol]}, PrimaryKey: true},
+		}
+	}
 	for _, row := range data {
 		m.Put(nil, nil, row)
 	}
@@ -170,6 +182,15 @@ func (m *StaticDataSource) Next() schema.Message {
 	case <-m.exit:
 		return nil
 	default:
+		if m.seeking {
+			if m.seekPos >= len(m.seek) {
+				m.seeking, m.seek, m.seekPos = false, nil, 0
+				return nil
+			}
+			item := m.seek[m.seekPos]
+			m.seekPos++
+			return item.SqlDriverMessageMap.Copy()
+		}
 		for {
 			var item btree.Item
 
@@ -213,6 +234,79 @@ func (m *StaticDataSource) Next() schema.Message {
 	}
 }
 
+// SeekIndex read only the rows matching seek on the indexed column.  Point
+// lookups (=, IN) are by key, ranges only if the column is integer as the
+// btree is ordered by key which is the integer itself.
+func (m *StaticDataSource) SeekIndex(s *schema.IndexSeek) bool {
+	cols := m.tbl.Columns()
+	if m.indexCol >= len(cols) || !strings.EqualFold(cols[m.indexCol], s.Field) {
+		return false
+	}
+	vt, _ := m.tbl.Column(cols[m.indexCol])
+	items := make([]*DriverItem, 0)
+	if !s.IsRange() {
+		for _, v := range s.Values {
+			if !seekValueType(vt, v) {
+				return false
+			}
+			if item := m.bt.Get(NewKey(makeId(v))); item != nil {
+				items = append(items, item.(*DriverItem))
+			}
+		}
+	} else {
+		if vt != value.IntType {
+			return false
+		}
+		lower, upper := int64(math.MinInt64), int64(math.MaxInt64)
+		if s.Lower != nil {
+			iv, ok := s.Lower.(int64)
+			if !ok {
+				return false
+			}
+			if lower = iv; !s.LowerInclusive {
+				lower++
+			}
+		}
+		if s.Upper != nil {
+			iv, ok := s.Upper.(int64)
+			if !ok {
+				return false
+			}
+			if upper = iv; !s.UpperInclusive {
+				upper--
+			}
+		}
+		if upper < 0 {
+			// negative keys are stored after positive
+			return false
+		}
+		if lower < 0 {
+			// include negatives, where clause will filter
+			m.bt.AscendGreaterOrEqual(NewKey(uint64(math.MaxInt64)+1), func(a btree.Item) bool {
+				items = append(items, a.(*DriverItem))
+				return true
+			})
+			lower = 0
+		}
+		m.bt.AscendRange(NewKey(uint64(lower)), NewKey(uint64(upper)+1), func(a btree.Item) bool {
+			items = append(items, a.(*DriverItem))
+			return true
+		})
+	}
+	m.seek, m.seekPos, m.seeking = items, 0, true
+	return true
+}
+
+func seekValueType(vt value.ValueType, v driver.Value) bool {
+	switch v.(type) {
+	case int64:
+		return vt == value.IntType
+	case string:
+		return vt == value.StringType
+	}
+	return false
+}
+
 // interface for Upsert.Put()
 func (m *StaticDataSource) Put(ctx context.Context, key schema.Key, row interface{}) (schema.Key, error) {
 
@@ -2,6 +2,7 @@ package membtree_test
 
 import (
 	"database/sql/driver"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -155,3 +156,35 @@ func TestStaticDataSource(t *testing.T) {
 	assert.Equal(t, nil, err)
 	assert.Equal(t, curSize, delCt, "Should have deleted all records")
 }
+
+func TestStaticSeekIndex(t *testing.T) {
+
+	rows := make([][]driver.Value, 0)
+	for i := -2; i < 10; i++ {
+		rows = append(rows, []driver.Value{i, fmt.Sprintf("user%d", i)})
+	}
+	static := membtree.NewStaticDataSource("seekusers", 0, rows, []string{"user_id", "name"})
+	tbl, _ := static.Table("seekusers")
+	idx := tbl.Indexes[0]
+
+	seekIds := func(s *schema.IndexSeek) []int {
+		assert.True(t, static.SeekIndex(s), "should seek %#v", s)
+		ids := make([]int, 0)
+		for msg := static.Next(); msg != nil; msg = static.Next() {
+			ids = append(ids, msg.Body().(*datasource.SqlDriverMessageMap).Values()[0].(int))
+		}
+		return ids
+	}
+	assert.Equal(t, []int{3, 7}, seekIds(&schema.IndexSeek{Index: idx, Field: "user_id", Values: []driver.Value{int64(3), int64(7), int64(99)}}))
+	assert.Equal(t, []int{3, 4, 5}, seekIds(&schema.IndexSeek{Index: idx, Field: "user_id", Lower: int64(2), Upper: int64(5), UpperInclusive: true}))
+	// negatives are returned for where clause to filter
+	assert.Equal(t, []int{-2, -1, 0, 1}, seekIds(&schema.IndexSeek{Index: idx, Field: "user_id", Upper: int64(2)}))
+	// string values don't match int index
+	assert.Equal(t, false, static.SeekIndex(&schema.IndexSeek{Index: idx, Field: "user_id", Values: []driver.Value{"3"}}))
+	// after seek, full scan
+	ct := 0
+	for msg := static.Next(); msg != nil; msg = static.Next() {
+		ct++
+	}
+	assert.Equal(t, 12, ct)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/go-memdb"
 	"golang.org/x/net/context"
 
+	"github.com/araddon/qlbridge/arrow"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/schema"
@@ -27,12 +28,14 @@ var (
 	_ schema.Source = (*MemDb)(nil)
 
 	// Ensure our dbConn implements variety of Connection interfaces.
-	_ schema.Conn         = (*dbConn)(nil)
-	_ schema.ConnColumns  = (*dbConn)(nil)
-	_ schema.ConnScanner  = (*dbConn)(nil)
-	_ schema.ConnUpsert   = (*dbConn)(nil)
-	_ schema.ConnDeletion = (*dbConn)(nil)
-	_ schema.ConnSeeker   = (*dbConn)(nil)
+	_ schema.Conn             = (*dbConn)(nil)
+	_ schema.ConnColumns      = (*dbConn)(nil)
+	_ schema.ConnScanner      = (*dbConn)(nil)
+	_ schema.ConnBatchScanner = (*dbConn)(nil)
+	_ schema.ConnUpsert       = (*dbConn)(nil)
+	_ schema.ConnDeletion     = (*dbConn)(nil)
+	_ schema.ConnSeeker       = (*dbConn)(nil)
+	_ schema.ConnIndexSeeker  = (*dbConn)(nil)
 )
 
 // MemDb implements qlbridge `Source` to allow in-memory native go data
@@ -51,6 +54,7 @@ type dbConn struct {
 	db     *memdb.MemDB
 	txn    *memdb.Txn
 	result memdb.ResultIterator
+	batch  *arrow.RecordBuilder
 }
 
 // NewMemDbData creates a MemDb with given indexes, columns, and values
@@ -80,18 +84,21 @@ func NewMemDb(name string, cols []string) (*MemDb, error) {
 	return NewMemDbForSchema(name, cols)
 }
 
-// NewMemDbForSchema creates a MemDb with given indexes, columns
-func NewMemDbForSchema(name string, cols []string) (*MemDb, error) {
+// NewMemDbForSchema creates a MemDb with given indexes, columns.  If no
+// indexes are provided the first column is the primary index.  Indexes
+// are single column, and used for seeks from where clauses.
+func NewMemDbForSchema(name string, cols []string, indexes ...*schema.Index) (*MemDb, error) {
 	if len(cols) < 1 {
 		return nil, fmt.Errorf("must have columns provided")
 	}
 
-	m := &MemDb{}
+	m := &MemDb{indexes: indexes}
 	m.exit = make(chan bool, 1)
 	var err error
 	m.tbl = schema.NewTable(name)
 	m.tbl.SetColumns(cols)
 	m.buildDefaultIndexes()
+	m.tbl.Indexes = m.indexes
 	mdbSchema := makeMemDbSchema(m)
 	m.db, err = memdb.NewMemDB(mdbSchema)
 	return m, err
@@ -150,8 +157,103 @@ func newDbConn(mdb *MemDb) *dbConn {
 	return c
 }
 func (m *dbConn) Columns() []string { return m.md.tbl.Columns() }
-func (m *dbConn) Close() error      { return nil }
+
+// SeekIndex read only the rows matching seek from the index.  Point
+// lookups (=, IN) are supported on all indexes, ranges only on string
+// columns as the radix tree is ordered by the string form of values.
+func (m *dbConn) SeekIndex(s *schema.IndexSeek) bool {
+	pos, ok := m.md.tbl.FieldPositions[s.Field]
+	if !ok {
+		return false
+	}
+	vt, _ := m.md.tbl.Column(s.Field)
+	if m.txn == nil {
+		m.txn = m.db.Txn(false)
+	}
+	rows := make([]interface{}, 0)
+	if !s.IsRange() {
+		for _, v := range s.Values {
+			if !seekValueType(vt, v) {
+				return false
+			}
+			iter, err := m.txn.Get(m.md.tbl.Name, s.Index.Name, v)
+			if err != nil {
+				u.Warnf("could not seek %v err=%v", v, err)
+				return false
+			}
+			for raw := iter.Next(); raw != nil; raw = iter.Next() {
+				rows = append(rows, raw)
+			}
+		}
+		m.result = &seekResult{rows: rows}
+		return true
+	}
+
+	if vt != value.StringType {
+		return false
+	}
+	lower, lowerOk := s.Lower.(string)
+	upper, upperOk := s.Upper.(string)
+	if (s.Lower != nil && !lowerOk) || (s.Upper != nil && !upperOk) {
+		return false
+	}
+	iter, err := m.txn.LowerBound(m.md.tbl.Name, s.Index.Name, lower)
+	if err != nil {
+		u.Warnf("could not seek %v err=%v", lower, err)
+		return false
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		msg, ok := raw.(*datasource.SqlDriverMessage)
+		if !ok {
+			continue
+		}
+		val := fmt.Sprintf("%v", msg.Vals[pos])
+		if s.Lower != nil && !s.LowerInclusive && val == lower {
+			continue
+		}
+		if s.Upper != nil && (val > upper || (!s.UpperInclusive && val == upper)) {
+			break
+		}
+		rows = append(rows, raw)
+	}
+	m.result = &seekResult{rows: rows}
+	return true
+}
+
+func seekValueType(vt value.ValueType, v driver.Value) bool {
+	switch v.(type) {
+	case int64:
+		return vt == value.IntType || vt == value.UnknownType
+	case string:
+		return vt == value.StringType || vt == value.UnknownType
+	}
+	return false
+}
+
+// seekResult is a memdb.ResultIterator of rows found by SeekIndex
+type seekResult struct {
+	rows []interface{}
+}
+
+func (m *seekResult) WatchCh() <-chan struct{} { return nil }
+func (m *seekResult) Next() interface{} {
+	if len(m.rows) == 0 {
+		return nil
+	}
+	row := m.rows[0]
+	m.rows = m.rows[1:]
+	return row
+}
+func (m *dbConn) Close() error { return nil }
 func (m *dbConn) Next() schema.Message {
+	if msg := m.nextRow(); msg != nil {
+		return msg.ToMsgMap(m.md.tbl.FieldPositions)
+	}
+	return nil
+}
+
+// nextRow the next stored row of the scan (or seek), nil at end
+func (m *dbConn) nextRow() *datasource.SqlDriverMessage {
 
 	if m.txn == nil {
 		m.txn = m.db.Txn(false)
@@ -160,26 +262,55 @@ func (m *dbConn) Next() schema.Message {
 	case <-m.md.exit:
 		return nil
 	default:
-		for {
-			if m.result == nil {
-				result, err := m.txn.Get(m.md.tbl.Name, m.md.primaryIndex)
-				if err != nil {
-					u.Errorf("error %v", err)
-					return nil
-				}
-				m.result = result
-			}
-			raw := m.result.Next()
-			if raw == nil {
+		if m.result == nil {
+			result, err := m.txn.Get(m.md.tbl.Name, m.md.primaryIndex)
+			if err != nil {
+				u.Errorf("error %v", err)
 				return nil
 			}
-			if msg, ok := raw.(*datasource.SqlDriverMessage); ok {
-				return msg.ToMsgMap(m.md.tbl.FieldPositions)
-			}
-			u.Warnf("error, not correct type: %#v", raw)
+			m.result = result
+		}
+		raw := m.result.Next()
+		if raw == nil {
 			return nil
 		}
+		if msg, ok := raw.(*datasource.SqlDriverMessage); ok {
+			return msg
+		}
+		u.Warnf("error, not correct type: %#v", raw)
+		return nil
+	}
+}
+
+// NextBatch the next batch of up to size rows as columns, implements
+// schema.ConnBatchScanner.  Columns of fields without a known type are
+// typed from the first row.
+func (m *dbConn) NextBatch(size int) *arrow.Record {
+	msg := m.nextRow()
+	if msg == nil {
+		return nil
+	}
+	if m.batch == nil {
+		cols := m.md.tbl.Columns()
+		s := arrow.NewSchema()
+		for i, col := range cols {
+			t := arrow.String
+			if f, ok := m.md.tbl.FieldMap[col]; ok && f.ValueType() != value.UnknownType {
+				t = arrow.FromValueType(f.ValueType())
+			} else if i < len(msg.Vals) {
+				t = arrow.TypeOf(msg.Vals[i])
+			}
+			s.Fields = append(s.Fields, arrow.Field{Name: col, Type: t, Nullable: true})
+		}
+		m.batch = arrow.NewRecordBuilder(s)
+	}
+	for ; msg != nil; msg = m.nextRow() {
+		m.batch.Append(msg.Vals)
+		if m.batch.Len() >= size {
+			break
+		}
 	}
+	return m.batch.NewRecord()
 }
 
 // Put interface for allowing this to accept writes via ConnUpsert.Put()
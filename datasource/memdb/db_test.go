@@ -115,3 +115,46 @@ func TestMemDb(t *testing.T) {
 	}
 	assert.Equal(t, 0, ct)
 }
+
+func TestMemDbSeekIndex(t *testing.T) {
+
+	cols := []string{"user_id", "name", "email"}
+	db, err := NewMemDbForSchema("seekusers", cols,
+		&schema.Index{Name: "id", Fields: []string{"user_id"}, PrimaryKey: true},
+		&schema.Index{Name: "name", Fields: []string{"name"}},
+	)
+	assert.Equal(t, nil, err)
+	c, _ := db.Open("seekusers")
+	dc := c.(*dbConn)
+	for i, name := range []string{"aaron", "bob", "bob", "carl", "dana"} {
+		_, err = dc.Put(nil, nil, []driver.Value{i, name, name + "@email.com"})
+		assert.Equal(t, nil, err)
+	}
+	err = datasource.IntrospectTable(db.tbl, newDbConn(db))
+	assert.Equal(t, nil, err)
+	idx := db.tbl.Indexes[1]
+
+	seekNames := func(s *schema.IndexSeek) []string {
+		dc := newDbConn(db)
+		assert.True(t, dc.SeekIndex(s), "should seek %#v", s)
+		names := make([]string, 0)
+		for msg := dc.Next(); msg != nil; msg = dc.Next() {
+			names = append(names, msg.Body().(*datasource.SqlDriverMessageMap).Values()[2].(string))
+		}
+		return names
+	}
+	assert.Equal(t, []string{"bob@email.com", "bob@email.com", "dana@email.com"},
+		seekNames(&schema.IndexSeek{Index: idx, Field: "name", Values: []driver.Value{"bob", "dana", "zed"}}))
+	assert.Equal(t, []string{"bob@email.com", "bob@email.com", "carl@email.com"},
+		seekNames(&schema.IndexSeek{Index: idx, Field: "name", Lower: "aaron", Upper: "carl", UpperInclusive: true}))
+
+	// planner finds seek from where clause
+	err = schema.RegisterSourceAsSchema("memseek", db)
+	assert.Equal(t, nil, err)
+	testutil.TestSqlSelect(t, "memseek", `SELECT user_id FROM seekusers WHERE name = "carl"`,
+		[][]driver.Value{{int64(3)}},
+	)
+	testutil.TestSqlSelect(t, "memseek", `SELECT user_id FROM seekusers WHERE name IN ("bob", "dana") AND user_id > 1`,
+		[][]driver.Value{{int64(2)}, {int64(4)}},
+	)
+}
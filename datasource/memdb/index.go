@@ -44,11 +44,12 @@ type indexWrapper struct {
 func (s *indexWrapper) FromObject(obj interface{}) (bool, []byte, error) {
 	switch row := obj.(type) {
 	case *datasource.SqlDriverMessage:
-		if len(row.Vals) < 0 {
+		pos := s.pos()
+		if len(row.Vals) <= pos {
 			return false, nil, u.LogErrorf("No values in row?")
 		}
 		// Add the null character as a terminator
-		val := fmt.Sprintf("%v", row.Vals[0])
+		val := fmt.Sprintf("%v", row.Vals[pos])
 		val += "\x00"
 		return true, []byte(val), nil
 	case int, uint64, int64, string:
@@ -60,6 +61,16 @@ func (s *indexWrapper) FromObject(obj interface{}) (bool, []byte, error) {
 	}
 }
 
+// pos the column position of the indexed field
+func (s *indexWrapper) pos() int {
+	if s.t != nil && len(s.Fields) > 0 {
+		if pos, ok := s.t.FieldPositions[s.Fields[0]]; ok {
+			return pos
+		}
+	}
+	return 0
+}
+
 func (s *indexWrapper) FromArgs(args ...interface{}) ([]byte, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("must provide only a single argument")
@@ -77,7 +88,7 @@ func makeMemDbSchema(m *MemDb) *memdb.DBSchema {
 	for _, idx := range m.indexes {
 		sidx := &memdb.IndexSchema{
 			Name:    idx.Name,
-			Indexer: &indexWrapper{Index: idx},
+			Indexer: &indexWrapper{t: m.tbl, Index: idx},
 		}
 		if idx.PrimaryKey {
 			sidx.Unique = true
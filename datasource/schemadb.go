@@ -435,6 +435,12 @@ func mysqlWriteField(w *bytes.Buffer, fld *schema.Field) {
 		fmt.Fprintf(w, "varchar(%d) DEFAULT NULL", deflen)
 	case value.NumberType:
 		fmt.Fprint(w, "float DEFAULT NULL")
+	case value.DecimalType:
+		if fld.Precision > 0 {
+			fmt.Fprintf(w, "decimal(%d,%d) DEFAULT NULL", fld.Precision, fld.Scale)
+		} else {
+			fmt.Fprint(w, "decimal(10,0) DEFAULT NULL")
+		}
 	case value.TimeType:
 		fmt.Fprint(w, "datetime DEFAULT NULL")
 	case value.JsonType:
@@ -458,6 +464,8 @@ func MysqlValueString(t value.ValueType) string {
 		return "text"
 	case value.NumberType:
 		return "float"
+	case value.DecimalType:
+		return "decimal"
 	case value.IntType:
 		return "long"
 	case value.BoolType:
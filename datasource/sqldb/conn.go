@@ -0,0 +1,390 @@
+package sqldb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	u "github.com/araddon/gou"
+	"golang.org/x/net/context"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// ensure our conn implements connection features
+	_ schema.ConnAll        = (*qryconn)(nil)
+	_ schema.ConnMutation   = (*qryconn)(nil)
+	_ schema.ConnPatchWhere = (*qryconn)(nil)
+	_ schema.ConnScanErr    = (*qryconn)(nil)
+	_ plan.SourcePlanner    = (*qryconn)(nil)
+)
+
+// qryconn is a single-query connection to a table, either a
+// select (scanner) or a mutation.
+type qryconn struct {
+	*exec.TaskBase
+	source *Source
+	tbl    *schema.Table
+	name   string // native table name
+	rw     *Rewriter
+	rows   *sql.Rows
+	ct     uint64
+	cols   []string
+	colidx map[string]int
+	insert []string // native column names for INSERT
+	err    error
+}
+
+func newQueryConn(tbl *schema.Table, source *Source) *qryconn {
+	m := &qryconn{
+		tbl:    tbl,
+		source: source,
+		name:   source.native[tbl.Name],
+		rw:     NewRewriter(source.d, tbl),
+		cols:   tbl.Columns(),
+		colidx: tbl.FieldPositions,
+	}
+	if m.name == "" {
+		m.name = tbl.Name
+	}
+	return m
+}
+
+// Close the qryconn, closing any open rows.
+func (m *qryconn) Close() error {
+	if m.rows != nil {
+		rows := m.rows
+		m.rows = nil
+		return rows.Close()
+	}
+	return nil
+}
+
+// CreateIterator creates an iterator to page through each row in this query resultset.
+func (m *qryconn) CreateIterator() schema.Iterator { return m }
+
+// Columns gets the columns used in this query.
+func (m *qryconn) Columns() []string { return m.cols }
+
+// CreateMutator part of Mutator interface to allow this connection to have access
+// to the full plan context to map insert columns.
+func (m *qryconn) CreateMutator(pc interface{}) (schema.ConnMutator, error) {
+	if ctx, ok := pc.(*plan.Context); ok && ctx != nil {
+		m.TaskBase = exec.NewTaskBase(ctx)
+		if ins, isInsert := ctx.Stmt.(*rel.SqlInsert); isInsert && len(ins.Columns) > 0 {
+			m.insert = make([]string, len(ins.Columns))
+			for i, col := range ins.Columns {
+				m.insert[i] = col.Key()
+			}
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("Expected *plan.Context but got %T", pc)
+}
+
+// WalkSourceSelect An interface implemented by this connection allowing the planner
+// to push down as much sql logic as possible to the database.  The planner
+// splits the statement per our Capabilities, for sources in a join we push
+// down the where clauses that can be expressed natively, the rest are
+// poly-filled by qlbridge.
+func (m *qryconn) WalkSourceSelect(planner plan.Planner, p *plan.Source) (plan.Task, error) {
+
+	var sqlSelect *rel.SqlSelect
+	if p.Pushdown != nil {
+		sqlSelect = p.Pushdown.Stmt
+	} else {
+		parent := p.Stmt.Source
+		p.Stmt.Source = nil
+		p.Stmt.Rewrite(parent)
+		sqlSelect = p.Stmt.Source
+		sqlSelect.RewriteAsRawSelect()
+		AddWhereColumns(parent, sqlSelect, m.tbl)
+	}
+
+	sqlString, complete, err := m.rw.Select(sqlSelect)
+	if err != nil {
+		return nil, err
+	}
+	if !complete && p.Pushdown != nil {
+		// the dialect capabilities accepted where terms the rewriter can't
+		// write, split again without them so they are the residual where.
+		caps := *m.source.Capabilities()
+		accept := caps.Accept
+		caps.Accept = func(n expr.Node) bool {
+			return (accept == nil || accept(n)) && m.rw.Supports(n)
+		}
+		p.Pushdown = plan.NewPushdown(&caps, p.Stmt.Source, m.tbl)
+		sqlSelect = p.Pushdown.Stmt
+		if sqlString, _, err = m.rw.Select(sqlSelect); err != nil {
+			return nil, err
+		}
+	}
+	u.Debugf("pushdown sql complete=%v: %s", complete, sqlString)
+
+	if err = m.query(sqlString); err != nil {
+		return nil, err
+	}
+	if p.Pushdown != nil && !sqlSelect.Star {
+		// result columns are positional to the statement columns
+		for i, col := range sqlSelect.Columns {
+			if i < len(m.cols) {
+				m.cols[i] = col.As
+				m.colidx[col.As] = i
+			}
+		}
+	}
+	m.TaskBase = exec.NewTaskBase(p.Context())
+	p.SourceExec = true
+	return nil, nil
+}
+
+func (m *qryconn) query(sqlString string, args ...interface{}) error {
+	rows, err := m.source.db.Query(sqlString, args...)
+	if err != nil {
+		u.Errorf("could not query %q err=%v", sqlString, err)
+		return err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+	m.rows = rows
+	m.err = nil
+	m.cols = make([]string, len(cols))
+	m.colidx = make(map[string]int, len(cols))
+	for i, col := range cols {
+		m.cols[i] = strings.ToLower(col)
+		m.colidx[m.cols[i]] = i
+	}
+	return nil
+}
+
+// Next the next row of current query.
+func (m *qryconn) Next() schema.Message {
+	if m.rows == nil {
+		// full table scan
+		if err := m.query(fmt.Sprintf("SELECT * FROM %s", m.table())); err != nil {
+			m.err = err
+			return nil
+		}
+	}
+	if !m.rows.Next() {
+		m.err = m.rows.Err()
+		return nil
+	}
+	readCols := make([]interface{}, len(m.cols))
+	vals := make([]driver.Value, len(m.cols))
+	for i := range vals {
+		readCols[i] = &vals[i]
+	}
+	if m.err = m.rows.Scan(readCols...); m.err != nil {
+		u.Warnf("could not scan row %d of %s err=%v", m.ct, m.name, m.err)
+		return nil
+	}
+	for i, v := range vals {
+		if by, isBytes := v.([]byte); isBytes {
+			v = string(by)
+			vals[i] = v
+		}
+		if vt, ok := m.tbl.Column(m.cols[i]); ok && vt == value.DecimalType && v != nil {
+			// exact decimals, drivers return them as strings or floats
+			if dv, ok := value.ValueToDecimal(value.NewValue(v)); ok {
+				vals[i] = dv
+			}
+		}
+	}
+	msg := datasource.NewSqlDriverMessageMap(m.ct, vals, m.colidx)
+	m.ct++
+	return msg
+}
+
+// Err the query, scan or row error that ended Next, nil at end of rows.
+func (m *qryconn) Err() error { return m.err }
+
+func (m *qryconn) table() string {
+	w := m.source.d.Writer()
+	w.WriteIdentityQuote(m.name, m.source.d.IdentityQuote)
+	return w.String()
+}
+
+// Put interface for Upsert.Put() to do single row insert.
+func (m *qryconn) Put(ctx context.Context, key schema.Key, row interface{}) (schema.Key, error) {
+	switch rowVals := row.(type) {
+	case []driver.Value:
+		cols := m.insert
+		if len(cols) == 0 {
+			cols = m.tbl.Columns()
+		}
+		if len(rowVals) != len(cols) {
+			return nil, fmt.Errorf("Wrong number of columns, got %v expected %v", len(rowVals), len(cols))
+		}
+		if _, err := m.exec(m.insertSql(cols), rowVals); err != nil {
+			return nil, err
+		}
+		return datasource.NewKeyCol(cols[0], rowVals[0]), nil
+	case map[string]driver.Value:
+		if key == nil {
+			return nil, fmt.Errorf("Put of map values requires key")
+		}
+		keyCol, ok := key.(datasource.KeyCol)
+		if !ok {
+			return nil, fmt.Errorf("Put of map values requires KeyCol but got %T", key)
+		}
+		ct, err := m.update(fmt.Sprintf("%s = %s", m.ident(keyCol.Name), m.source.d.Placeholder(len(rowVals))), []driver.Value{keyCol.Val}, rowVals)
+		if err != nil {
+			return nil, err
+		}
+		if ct == 0 {
+			return nil, schema.ErrNotFound
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("Expected []driver.Value but got %T", row)
+	}
+}
+
+// PutMulti many rows, expects [][]driver.Value
+func (m *qryconn) PutMulti(ctx context.Context, keys []schema.Key, src interface{}) ([]schema.Key, error) {
+	switch rows := src.(type) {
+	case [][]driver.Value:
+		keys := make([]schema.Key, 0, len(rows))
+		for _, row := range rows {
+			key, err := m.Put(ctx, nil, row)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+	return nil, fmt.Errorf("unrecognized put object type: %T", src)
+}
+
+// PatchWhere update rows matching where expression, the where must
+// be expressible natively.
+func (m *qryconn) PatchWhere(ctx context.Context, where expr.Node, patch interface{}) (int64, error) {
+	vals, ok := patch.(map[string]driver.Value)
+	if !ok {
+		return 0, fmt.Errorf("Expected map[string]driver.Value but got %T", patch)
+	}
+	whereSql, complete := m.rw.Where(where)
+	if !complete {
+		return 0, fmt.Errorf("Could not push down where clause %s", where)
+	}
+	return m.update(whereSql, nil, vals)
+}
+
+// Get a single row by key, the first column of table.
+func (m *qryconn) Get(key driver.Value) (schema.Message, error) {
+	sqlString := fmt.Sprintf("SELECT * FROM %s WHERE %s = %s", m.table(), m.ident(m.tbl.Columns()[0]), m.source.d.Placeholder(0))
+	if err := m.query(sqlString, key); err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	msg := m.Next()
+	if msg == nil {
+		if m.err != nil {
+			return nil, m.err
+		}
+		return nil, schema.ErrNotFound
+	}
+	return msg, nil
+}
+
+// Delete deletes a single row by key, the first column of table.
+func (m *qryconn) Delete(key driver.Value) (int, error) {
+	if kc, ok := key.(datasource.KeyCol); ok {
+		key = kc.Val
+	}
+	sqlString := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", m.table(), m.ident(m.tbl.Columns()[0]), m.source.d.Placeholder(0))
+	ct, err := m.exec(sqlString, []driver.Value{key})
+	return int(ct), err
+}
+
+// DeleteExpression Delete using a Where Expression, the where must
+// be expressible natively.
+func (m *qryconn) DeleteExpression(p interface{}, where expr.Node) (int, error) {
+	whereSql, complete := m.rw.Where(where)
+	if !complete {
+		return 0, fmt.Errorf("Could not push down where clause %s", where)
+	}
+	ct, err := m.exec(fmt.Sprintf("DELETE FROM %s WHERE %s", m.table(), whereSql), nil)
+	return int(ct), err
+}
+
+func (m *qryconn) ident(col string) string {
+	w := m.source.d.Writer()
+	if !m.rw.writeIdentity(w, col) {
+		w.WriteIdentityQuote(col, m.source.d.IdentityQuote)
+	}
+	return w.String()
+}
+
+func (m *qryconn) insertSql(cols []string) string {
+	w := m.source.d.Writer()
+	io.WriteString(w, "INSERT INTO ")
+	io.WriteString(w, m.table())
+	io.WriteString(w, " (")
+	for i, col := range cols {
+		if i > 0 {
+			io.WriteString(w, ", ")
+		}
+		io.WriteString(w, m.ident(col))
+	}
+	io.WriteString(w, ") VALUES (")
+	io.WriteString(w, m.source.d.Placeholders(len(cols)))
+	io.WriteString(w, ")")
+	return w.String()
+}
+
+// update set values, where the whereSql may have bind-parameters
+// that come after the set values.
+func (m *qryconn) update(whereSql string, whereArgs []driver.Value, vals map[string]driver.Value) (int64, error) {
+	cols := make([]string, 0, len(vals))
+	for col := range vals {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	w := m.source.d.Writer()
+	io.WriteString(w, "UPDATE ")
+	io.WriteString(w, m.table())
+	io.WriteString(w, " SET ")
+	args := make([]driver.Value, 0, len(cols)+len(whereArgs))
+	for i, col := range cols {
+		if i > 0 {
+			io.WriteString(w, ", ")
+		}
+		io.WriteString(w, m.ident(col))
+		io.WriteString(w, " = ")
+		io.WriteString(w, m.source.d.Placeholder(i))
+		args = append(args, vals[col])
+	}
+	io.WriteString(w, " WHERE ")
+	io.WriteString(w, whereSql)
+	args = append(args, whereArgs...)
+	return m.exec(w.String(), args)
+}
+
+func (m *qryconn) exec(sqlString string, vals []driver.Value) (int64, error) {
+	args := make([]interface{}, len(vals))
+	for i, v := range vals {
+		args[i] = v
+	}
+	res, err := m.source.db.Exec(sqlString, args...)
+	if err != nil {
+		u.Warnf("could not exec %q err=%v", sqlString, err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,276 @@
+package sqldb
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/schema"
+)
+
+var (
+	// Postgres dialect, literals='  identity="  placeholders=$1
+	Postgres = &Dialect{
+		Name:          "postgres",
+		LiteralQuote:  '\'',
+		IdentityQuote: '"',
+		Placeholder:   func(i int) string { return fmt.Sprintf("$%d", i+1) },
+		TablesQuery:   "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()",
+		Funcs: map[string]string{
+			"tolower":          "lower",
+			"string.lowercase": "lower",
+			"string.uppercase": "upper",
+			"char_length":      "char_length",
+			"abs":              "abs",
+			"sign":             "sign",
+			"ceil":             "ceil",
+			"ceiling":          "ceiling",
+			"floor":            "floor",
+			"exp":              "exp",
+			"ln":               "ln",
+			"log10":            "log",
+			"sin":              "sin",
+			"cos":              "cos",
+			"tan":              "tan",
+			"asin":             "asin",
+			"acos":             "acos",
+			"atan":             "atan",
+			"atan2":            "atan2",
+			"pi":               "pi",
+			"degrees":          "degrees",
+			"radians":          "radians",
+		},
+		Regexp:  "~",
+		RegexpI: "~*",
+		Bitwise: true,
+	}
+	// MySQL dialect, literals='  identity=`  placeholders=?
+	MySQL = &Dialect{
+		Name:          "mysql",
+		LiteralQuote:  '\'',
+		IdentityQuote: '`',
+		Placeholder:   func(i int) string { return "?" },
+		TablesQuery:   "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()",
+		Funcs: map[string]string{
+			"tolower":          "lower",
+			"string.lowercase": "lower",
+			"string.uppercase": "upper",
+			"char_length":      "char_length",
+			"abs":              "abs",
+			"sign":             "sign",
+			"round":            "round",
+			"ceil":             "ceil",
+			"ceiling":          "ceiling",
+			"floor":            "floor",
+			"mod":              "mod",
+			"exp":              "exp",
+			"ln":               "ln",
+			"log10":            "log10",
+			"sin":              "sin",
+			"cos":              "cos",
+			"tan":              "tan",
+			"asin":             "asin",
+			"acos":             "acos",
+			"atan":             "atan",
+			"atan2":            "atan2",
+			"pi":               "pi",
+			"degrees":          "degrees",
+			"radians":          "radians",
+		},
+		BackslashEscape: true,
+	}
+	// Sqlite dialect, literals='  identity="  placeholders=?
+	Sqlite = &Dialect{
+		Name:          "sqlite",
+		LiteralQuote:  '\'',
+		IdentityQuote: '"',
+		Placeholder:   func(i int) string { return "?" },
+		TablesQuery:   "SELECT name FROM sqlite_master WHERE type='table'",
+		Funcs: map[string]string{
+			"tolower":          "lower",
+			"string.lowercase": "lower",
+			"string.uppercase": "upper",
+			"char_length":      "length",
+			"abs":              "abs",
+		},
+		Bitwise: true,
+	}
+
+	// standard sql aggregate functions, qlbridge name to native
+	aggregates = map[string]string{
+		"count": "COUNT",
+		"sum":   "SUM",
+		"avg":   "AVG",
+		"min":   "MIN",
+		"max":   "MAX",
+	}
+	// operators the Rewriter can write natively
+	operators = []lex.TokenType{
+		lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE,
+		lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE,
+		lex.TokenPlus, lex.TokenMinus, lex.TokenMultiply, lex.TokenStar,
+		lex.TokenDivide, lex.TokenModulus,
+		lex.TokenLogicAnd, lex.TokenAnd, lex.TokenLogicOr, lex.TokenOr,
+		lex.TokenLike, lex.TokenIN, lex.TokenBetween, lex.TokenNegate, lex.TokenExists,
+		lex.TokenCase, lex.TokenRLike, lex.TokenRLikeI,
+		lex.TokenBitAnd, lex.TokenBitOr, lex.TokenShiftLeft, lex.TokenShiftRight,
+	}
+
+	dialectMu sync.Mutex
+	dialects  = map[string]*Dialect{
+		"postgres": Postgres,
+		"mysql":    MySQL,
+		"sqlite":   Sqlite,
+	}
+	// database/sql driver names to dialect name
+	driverDialects = map[string]string{
+		"postgres": "postgres",
+		"pgx":      "postgres",
+		"mysql":    "mysql",
+		"sqlite3":  "sqlite",
+	}
+)
+
+// Dialect describes the differences between native sql databases that
+// are needed to rewrite qlbridge statements into native sql, and to
+// discover schema.
+type Dialect struct {
+	Name          string
+	LiteralQuote  byte
+	IdentityQuote byte
+	// Placeholder the bind-parameter for the i'th (0 based) argument
+	Placeholder func(i int) string
+	// TablesQuery sql returning single column of table names
+	TablesQuery string
+	// Funcs qlbridge function name (lower-case) to native function name
+	// for functions that may be pushed down.
+	Funcs map[string]string
+	// Regexp native regular expression match operator for RLIKE, ~  empty
+	// if regular expressions are not pushed down.  RegexpI the native
+	// case-insensitive operator for ~*, if empty the pattern is prefixed
+	// with the (?i) flag.
+	Regexp  string
+	RegexpI string
+	// Bitwise the native & | << >> operators are on signed 64 bit integers
+	// as qlbridge's are, xor (^) is never pushed down as it isn't standard.
+	Bitwise bool
+	// BackslashEscape backslash is an escape character in string literals
+	// so must itself be escaped.
+	BackslashEscape bool
+}
+
+// RegisterDialect make a dialect available by name, for use by
+// "dialect" setting of sqldb sources.
+func RegisterDialect(d *Dialect) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	dialects[strings.ToLower(d.Name)] = d
+}
+
+// DialectGet find a dialect by name, or database/sql driver name.
+func DialectGet(name string) (*Dialect, bool) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	name = strings.ToLower(name)
+	if d, ok := dialects[name]; ok {
+		return d, true
+	}
+	if dn, ok := driverDialects[name]; ok {
+		d, ok := dialects[dn]
+		return d, ok
+	}
+	return nil, false
+}
+
+// Writer create a dialect writer with this dialects escaping rules.
+func (m *Dialect) Writer() expr.DialectWriter {
+	return &dialectWriter{DialectWriter: expr.NewDialectWriter(m.LiteralQuote, m.IdentityQuote), d: m}
+}
+
+// dialectWriter always escapes literals, the expr writer passes through
+// literals that look to be already quoted ('x' OR '1'='1').
+type dialectWriter struct {
+	expr.DialectWriter
+	d *Dialect
+}
+
+// WriteLiteral write the quoted literal, quotes doubled.
+func (w *dialectWriter) WriteLiteral(l string) {
+	if w.d.BackslashEscape {
+		l = strings.Replace(l, `\`, `\\`, -1)
+	}
+	q := string(w.d.LiteralQuote)
+	io.WriteString(w, q+strings.Replace(l, q, q+q, -1)+q)
+}
+
+// Placeholders a comma delimited list of n bind-parameters.
+func (m *Dialect) Placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = m.Placeholder(i)
+	}
+	return strings.Join(ph, ", ")
+}
+
+// Capabilities the parts of a select statement this dialect can execute,
+// used by the planner to split statements into pushed down and residual.
+func (m *Dialect) Capabilities() *schema.Capabilities {
+	caps := schema.NewCapabilities()
+	for name := range m.Funcs {
+		caps.Funcs[name] = true
+	}
+	caps.Funcs["exists"] = true
+	for name := range aggregates {
+		caps.Aggregates[name] = true
+	}
+	for _, t := range operators {
+		caps.Operators[t] = true
+	}
+	caps.GroupBy = true
+	caps.OrderBy = true
+	caps.Limit = true
+	caps.Distinct = true
+	// like patterns, in lists have rules beyond operator
+	rw := NewRewriter(m, nil)
+	caps.Accept = func(n expr.Node) bool {
+		return !hasNullCheck(n) && rw.writeNode(m.Writer(), n)
+	}
+	return caps
+}
+
+// hasNullCheck does expression check for null (x = NULL, exists(x)), which
+// are not pushed down as qlbridge also considers empty values to be null.
+func hasNullCheck(node expr.Node) bool {
+	switch n := node.(type) {
+	case *expr.NullNode:
+		return true
+	case *expr.FuncNode:
+		if strings.ToLower(n.Name) == "exists" {
+			return true
+		}
+		return anyNullCheck(n.Args)
+	case *expr.BinaryNode:
+		return anyNullCheck(n.Args)
+	case *expr.BooleanNode:
+		return anyNullCheck(n.Args)
+	case *expr.TriNode:
+		return anyNullCheck(n.Args)
+	case *expr.CaseNode:
+		return anyNullCheck(n.ChildrenArgs())
+	case *expr.UnaryNode:
+		return n.Operator.T == lex.TokenExists || hasNullCheck(n.Arg)
+	}
+	return false
+}
+
+func anyNullCheck(args []expr.Node) bool {
+	for _, arg := range args {
+		if hasNullCheck(arg) {
+			return true
+		}
+	}
+	return false
+}
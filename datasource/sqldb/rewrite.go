@@ -0,0 +1,528 @@
+package sqldb
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+)
+
+// Rewriter rewrites qlbridge statements into native sql for a Dialect.
+//
+// Expressions are written natively if possible, any where clause (AND)
+// terms that can't be expressed natively are dropped from the native
+// query and reported as incomplete so qlbridge can poly-fill them.
+type Rewriter struct {
+	d    *Dialect
+	tbl  *schema.Table
+	cols map[string]string // lower-case column name to native name
+}
+
+// NewRewriter creates a rewriter for dialect, the table is optional
+// and is used to find the native name (case) of columns.
+func NewRewriter(d *Dialect, tbl *schema.Table) *Rewriter {
+	m := &Rewriter{d: d, tbl: tbl}
+	if tbl != nil {
+		m.cols = make(map[string]string, len(tbl.Fields))
+		for _, f := range tbl.Fields {
+			m.cols[strings.ToLower(f.Name)] = f.Name
+		}
+	}
+	return m
+}
+
+// Select rewrites a single table select statement into native sql.  The
+// returned bool is true if the where clause was completely pushed down.
+// Columns, group by, having must be expressible natively (see
+// Dialect.Capabilities) but where terms and ordering that can't be are
+// dropped.
+//
+//	SELECT a, b FROM tbl WHERE a = "x" AND emaildomain(b) = "y" ORDER BY a
+//	SELECT "a", "b" FROM "tbl" WHERE "a" = 'x' ORDER BY "a"
+func (m *Rewriter) Select(sel *rel.SqlSelect) (string, bool, error) {
+
+	if len(sel.From) != 1 {
+		return "", false, fmt.Errorf("Only single table selects supported: %s", sel.String())
+	}
+
+	w := m.d.Writer()
+	io.WriteString(w, "SELECT ")
+	if sel.Distinct {
+		io.WriteString(w, "DISTINCT ")
+	}
+	for i, col := range sel.Columns {
+		if i > 0 {
+			io.WriteString(w, ", ")
+		}
+		if err := m.writeColumn(w, col); err != nil {
+			return "", false, err
+		}
+	}
+	io.WriteString(w, " FROM ")
+	m.writeTable(w, sel.From[0].SourceName())
+
+	complete := true
+	if sel.Where != nil && sel.Where.Expr != nil {
+		where, whereComplete := m.Where(sel.Where.Expr)
+		complete = whereComplete
+		if where != "" {
+			io.WriteString(w, " WHERE ")
+			io.WriteString(w, where)
+		}
+	}
+
+	if len(sel.GroupBy) > 0 {
+		io.WriteString(w, " GROUP BY ")
+		for i, col := range sel.GroupBy {
+			if i > 0 {
+				io.WriteString(w, ", ")
+			}
+			if !m.writeNode(w, col.Expr) {
+				return "", false, fmt.Errorf("Could not push down group by %s", col.Expr)
+			}
+		}
+	}
+	if sel.Having != nil {
+		io.WriteString(w, " HAVING ")
+		if !m.writeNode(w, sel.Having) {
+			return "", false, fmt.Errorf("Could not push down having %s", sel.Having)
+		}
+	}
+
+	if len(sel.OrderBy) > 0 {
+		// ordering is only pushed down if it can be completely
+		// as the planner will sort anyway.
+		ow := m.d.Writer()
+		for i, col := range sel.OrderBy {
+			if i > 0 {
+				io.WriteString(ow, ", ")
+			}
+			if !m.writeOrderNode(ow, sel, col.Expr) {
+				ow = nil
+				break
+			}
+			if col.Order != "" {
+				io.WriteString(ow, " ")
+				io.WriteString(ow, strings.ToUpper(col.Order))
+			}
+		}
+		if ow != nil {
+			io.WriteString(w, " ORDER BY ")
+			io.WriteString(w, ow.String())
+		}
+	}
+	if sel.Limit > 0 {
+		fmt.Fprintf(w, " LIMIT %d", sel.Limit)
+	}
+	if sel.Offset > 0 {
+		fmt.Fprintf(w, " OFFSET %d", sel.Offset)
+	}
+	return w.String(), complete, nil
+}
+
+// writeColumn write a column, with alias if it differs from the
+// native column name.
+func (m *Rewriter) writeColumn(w expr.DialectWriter, col *rel.Column) error {
+	if col.Star {
+		io.WriteString(w, "*")
+		return nil
+	}
+	if in, isIdent := col.Expr.(*expr.IdentityNode); col.Expr == nil || isIdent && !in.IsBooleanIdentity() {
+		if !m.writeIdentity(w, col.SourceField) {
+			return fmt.Errorf("Could not find column %q", col.SourceField)
+		}
+		if col.As == "" || strings.EqualFold(col.As, col.SourceField) {
+			return nil
+		}
+	} else if !m.writeNode(w, col.Expr) {
+		return fmt.Errorf("Could not push down column %s", col.Expr)
+	}
+	io.WriteString(w, " AS ")
+	w.WriteIdentityQuote(col.As, m.d.IdentityQuote)
+	return nil
+}
+
+// writeOrderNode order by may refer to aliases of columns.
+func (m *Rewriter) writeOrderNode(w expr.DialectWriter, sel *rel.SqlSelect, node expr.Node) bool {
+	if in, isIdent := node.(*expr.IdentityNode); isIdent {
+		_, right, _ := in.LeftRight()
+		for _, col := range sel.Columns {
+			if col.As == right && !strings.EqualFold(col.As, col.SourceField) {
+				w.WriteIdentityQuote(col.As, m.d.IdentityQuote)
+				return true
+			}
+		}
+	}
+	return m.writeNode(w, node)
+}
+
+// AddWhereColumns adds columns used in the where clause of the parent
+// statement to the source select.  The source rewrite drops where terms
+// it can't express (functions), but the columns they use are still
+// needed by qlbridge to poly-fill them.
+func AddWhereColumns(parent, sel *rel.SqlSelect, tbl *schema.Table) {
+	if sel.Star || len(parent.From) != 1 || parent.Where == nil || parent.Where.Expr == nil {
+		return
+	}
+	existing := make(map[string]bool, len(sel.Columns))
+	for _, col := range sel.Columns {
+		existing[strings.ToLower(col.SourceField)] = true
+	}
+	for _, col := range expr.FindAllIdentityField(parent.Where.Expr) {
+		_, right, _ := expr.LeftRight(col)
+		right = strings.ToLower(right)
+		if existing[right] {
+			continue
+		}
+		if _, inTable := tbl.FieldMap[right]; tbl != nil && !inTable {
+			continue
+		}
+		existing[right] = true
+		sel.AddColumn(*rel.NewColumn(right))
+	}
+}
+
+// Where rewrites a where expression into native sql, returning the native
+// expression and a bool of was complete expression written.  Top level AND
+// terms that can't be written are dropped.
+func (m *Rewriter) Where(node expr.Node) (string, bool) {
+	terms := make([]string, 0)
+	complete := true
+	for _, n := range expr.AndTerms(node) {
+		w := m.d.Writer()
+		if m.writeNode(w, n) {
+			terms = append(terms, w.String())
+		} else {
+			u.Debugf("not pushing down %s", n)
+			complete = false
+		}
+	}
+	return strings.Join(terms, " AND "), complete
+}
+
+// Supports can the expression be written as native sql.
+func (m *Rewriter) Supports(node expr.Node) bool {
+	return m.writeNode(m.d.Writer(), node)
+}
+
+func isAnd(t lex.TokenType) bool { return t == lex.TokenLogicAnd || t == lex.TokenAnd }
+func isOr(t lex.TokenType) bool  { return t == lex.TokenLogicOr || t == lex.TokenOr }
+
+func (m *Rewriter) writeTable(w expr.DialectWriter, name string) {
+	w.WriteIdentityQuote(name, m.d.IdentityQuote)
+}
+
+// writeIdentity write the native, quoted column name.  If we have a table
+// schema the column must exist.
+func (m *Rewriter) writeIdentity(w expr.DialectWriter, name string) bool {
+	if m.cols != nil {
+		native, ok := m.cols[strings.ToLower(name)]
+		if !ok {
+			return false
+		}
+		name = native
+	}
+	w.WriteIdentityQuote(name, m.d.IdentityQuote)
+	return true
+}
+
+func (m *Rewriter) writeIdentityNode(w expr.DialectWriter, in *expr.IdentityNode) bool {
+	if in.IsBooleanIdentity() {
+		if in.Bool() {
+			io.WriteString(w, "TRUE")
+		} else {
+			io.WriteString(w, "FALSE")
+		}
+		return true
+	}
+	_, right, _ := in.LeftRight()
+	return m.writeIdentity(w, right)
+}
+
+// writeNode write node natively, returns false if it could not be
+// expressed in native sql.
+func (m *Rewriter) writeNode(w expr.DialectWriter, node expr.Node) bool {
+	switch n := node.(type) {
+	case *expr.IdentityNode:
+		return m.writeIdentityNode(w, n)
+	case *expr.StringNode:
+		w.WriteLiteral(n.Text)
+	case *expr.NumberNode:
+		w.WriteNumber(n.Text)
+	case *expr.NullNode:
+		io.WriteString(w, "NULL")
+	case *expr.BinaryNode:
+		return m.writeBinary(w, n)
+	case *expr.BooleanNode:
+		return m.writeBoolean(w, n)
+	case *expr.TriNode:
+		return m.writeTri(w, n)
+	case *expr.UnaryNode:
+		return m.writeUnary(w, n)
+	case *expr.FuncNode:
+		return m.writeFunc(w, n)
+	case *expr.CaseNode:
+		return m.writeCase(w, n)
+	default:
+		return false
+	}
+	return true
+}
+
+// Binary Node:   operations for >, >=, <, <=, =, !=, AND, OR, Like, IN, RLIKE, & | << >>
+//
+//	x = y             =>   x = y
+//	x RLIKE "^a"      =>   x ~ '^a'   (postgres)
+//	x != NULL         =>   x IS NOT NULL
+//	x IN ("a","b")    =>   x IN ('a', 'b')
+func (m *Rewriter) writeBinary(w expr.DialectWriter, n *expr.BinaryNode) bool {
+
+	var op string
+	switch n.Operator.T {
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE:
+		if _, isNull := n.Args[1].(*expr.NullNode); isNull {
+			eq := n.Operator.T != lex.TokenNE
+			if !m.writeNode(w, n.Args[0]) {
+				return false
+			}
+			if eq {
+				io.WriteString(w, " IS NULL")
+			} else {
+				io.WriteString(w, " IS NOT NULL")
+			}
+			return true
+		}
+		op = "="
+		if n.Operator.T == lex.TokenNE {
+			op = "!="
+		}
+	case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE,
+		lex.TokenPlus, lex.TokenMinus, lex.TokenMultiply, lex.TokenStar,
+		lex.TokenDivide, lex.TokenModulus:
+		op = n.Operator.T.String()
+	case lex.TokenBitAnd, lex.TokenBitOr, lex.TokenShiftLeft, lex.TokenShiftRight:
+		if !m.d.Bitwise {
+			return false
+		}
+		op = n.Operator.V
+	case lex.TokenLogicAnd, lex.TokenAnd:
+		op = "AND"
+	case lex.TokenLogicOr, lex.TokenOr:
+		op = "OR"
+	case lex.TokenLike:
+		// qlbridge like is glob matching with * wildcards, only push
+		// down patterns that mean the same thing natively
+		pattern, isString := n.Args[1].(*expr.StringNode)
+		if !isString || strings.ContainsAny(pattern.Text, "*?[") {
+			return false
+		}
+		op = "LIKE"
+	case lex.TokenRLike, lex.TokenRLikeI:
+		// only literal patterns, to dialects with regular expressions
+		pattern, isString := n.Args[1].(*expr.StringNode)
+		if !isString || m.d.Regexp == "" {
+			return false
+		}
+		op = m.d.Regexp
+		if n.Operator.T == lex.TokenRLikeI {
+			if m.d.RegexpI != "" {
+				op = m.d.RegexpI
+			} else {
+				pattern = expr.NewStringNode("(?i)" + pattern.Text)
+			}
+		}
+		io.WriteString(w, "(")
+		if !m.writeNode(w, n.Args[0]) {
+			return false
+		}
+		fmt.Fprintf(w, " %s ", op)
+		w.WriteLiteral(pattern.Text)
+		io.WriteString(w, ")")
+		return true
+	case lex.TokenIN:
+		arr, isArray := n.Args[1].(*expr.ArrayNode)
+		if !isArray {
+			return false
+		}
+		io.WriteString(w, "(")
+		if !m.writeNode(w, n.Args[0]) {
+			return false
+		}
+		io.WriteString(w, " IN (")
+		for i, arg := range arr.Args {
+			if i > 0 {
+				io.WriteString(w, ", ")
+			}
+			if !m.writeNode(w, arg) {
+				return false
+			}
+		}
+		io.WriteString(w, "))")
+		return true
+	default:
+		return false
+	}
+
+	io.WriteString(w, "(")
+	if !m.writeNode(w, n.Args[0]) {
+		return false
+	}
+	io.WriteString(w, " ")
+	io.WriteString(w, op)
+	io.WriteString(w, " ")
+	if !m.writeNode(w, n.Args[1]) {
+		return false
+	}
+	io.WriteString(w, ")")
+	return true
+}
+
+func (m *Rewriter) writeBoolean(w expr.DialectWriter, n *expr.BooleanNode) bool {
+	op := " AND "
+	if isOr(n.Operator.T) {
+		op = " OR "
+	} else if !isAnd(n.Operator.T) {
+		return false
+	}
+	if n.Negated() {
+		io.WriteString(w, "NOT ")
+	}
+	io.WriteString(w, "(")
+	for i, arg := range n.Args {
+		if i > 0 {
+			io.WriteString(w, op)
+		}
+		if !m.writeNode(w, arg) {
+			return false
+		}
+	}
+	io.WriteString(w, ")")
+	return true
+}
+
+// Tri Nodes expressions, qlbridge BETWEEN is exclusive where sql is
+// inclusive so is written as comparisons:
+//
+//	x BETWEEN lo AND hi   =>   (x > lo AND x < hi)
+func (m *Rewriter) writeTri(w expr.DialectWriter, n *expr.TriNode) bool {
+	if n.Operator.T != lex.TokenBetween || n.Negated() {
+		return false
+	}
+	io.WriteString(w, "(")
+	if !m.writeNode(w, n.Args[0]) {
+		return false
+	}
+	io.WriteString(w, " > ")
+	if !m.writeNode(w, n.Args[1]) {
+		return false
+	}
+	io.WriteString(w, " AND ")
+	if !m.writeNode(w, n.Args[0]) {
+		return false
+	}
+	io.WriteString(w, " < ")
+	if !m.writeNode(w, n.Args[2]) {
+		return false
+	}
+	io.WriteString(w, ")")
+	return true
+}
+
+// Case expressions, simple and searched:
+//
+//	CASE [<expression>] WHEN <expression> THEN <expression> [ELSE <expression>] END
+func (m *Rewriter) writeCase(w expr.DialectWriter, n *expr.CaseNode) bool {
+	io.WriteString(w, "CASE")
+	if n.Arg != nil {
+		io.WriteString(w, " ")
+		if !m.writeNode(w, n.Arg) {
+			return false
+		}
+	}
+	for i, when := range n.Whens {
+		io.WriteString(w, " WHEN ")
+		if !m.writeNode(w, when) {
+			return false
+		}
+		io.WriteString(w, " THEN ")
+		if !m.writeNode(w, n.Thens[i]) {
+			return false
+		}
+	}
+	if n.Else != nil {
+		io.WriteString(w, " ELSE ")
+		if !m.writeNode(w, n.Else) {
+			return false
+		}
+	}
+	io.WriteString(w, " END")
+	return true
+}
+
+func (m *Rewriter) writeUnary(w expr.DialectWriter, n *expr.UnaryNode) bool {
+	switch n.Operator.T {
+	case lex.TokenNegate:
+		io.WriteString(w, "NOT (")
+		if !m.writeNode(w, n.Arg) {
+			return false
+		}
+		io.WriteString(w, ")")
+		return true
+	case lex.TokenExists:
+		if !m.writeNode(w, n.Arg) {
+			return false
+		}
+		io.WriteString(w, " IS NOT NULL")
+		return true
+	}
+	return false
+}
+
+// Functions are only pushed down if the dialect has a native equivalent,
+// or are standard sql aggregates.
+//
+//	exists(fieldname)   =>  fieldname IS NOT NULL
+//	tolower(fieldname)  =>  lower(fieldname)
+//	count(*)            =>  COUNT(*)
+func (m *Rewriter) writeFunc(w expr.DialectWriter, n *expr.FuncNode) bool {
+	name := strings.ToLower(n.Name)
+	if name == "exists" && len(n.Args) == 1 {
+		if _, isIdent := n.Args[0].(*expr.IdentityNode); !isIdent {
+			return false
+		}
+		if !m.writeNode(w, n.Args[0]) {
+			return false
+		}
+		io.WriteString(w, " IS NOT NULL")
+		return true
+	}
+	native, ok := m.d.Funcs[name]
+	if !ok {
+		native, ok = aggregates[name]
+		if !ok {
+			return false
+		}
+	}
+	io.WriteString(w, native)
+	io.WriteString(w, "(")
+	for i, arg := range n.Args {
+		if i > 0 {
+			io.WriteString(w, ", ")
+		}
+		if in, isIdent := arg.(*expr.IdentityNode); isIdent && in.Text == "*" {
+			io.WriteString(w, "*")
+			continue
+		}
+		if !m.writeNode(w, arg) {
+			return false
+		}
+	}
+	io.WriteString(w, ")")
+	return true
+}
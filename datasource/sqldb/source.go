@@ -0,0 +1,255 @@
+// Package sqldb implements a Qlbridge Datasource around any database/sql
+// driver (postgres, mysql, etc) with predicate push down, so that existing
+// relational stores can be queried (and joined) with other sources.
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+const (
+	// SourceType "sqldb" is the registered Source name in the qlbridge source registry
+	SourceType = "sqldb"
+)
+
+func init() {
+	// We need to register our DataSource provider here
+	schema.RegisterSourceType(SourceType, NewSource())
+}
+
+var (
+	// Ensure our source implements Source interface
+	_ schema.Source             = (*Source)(nil)
+	_ schema.SourceCapabilities = (*Source)(nil)
+)
+
+// Source implements qlbridge DataSource for a database/sql database.
+//
+// Settings:
+//
+//	"driver"   database/sql driver name, must be imported ie "postgres"
+//	"dsn"      data source name passed to sql.Open
+//	"dialect"  optional dialect name (postgres, mysql, sqlite), defaults
+//	           to the dialect for driver.
+//	"tables"   optional list of tables to expose, if not provided the
+//	           tables are discovered using dialect TablesQuery.
+//
+// Features
+// - Push down of where, aggregation, ordering, limit per dialect Capabilities.
+// - INSERT, UPDATE, DELETE pass through.
+type Source struct {
+	schema    *schema.Schema
+	db        *sql.DB
+	d         *Dialect
+	mu        sync.Mutex
+	tables    map[string]*schema.Table
+	tableList []string
+	native    map[string]string // lower-case table name to native name
+}
+
+// NewSource create an un-configured sqldb source.
+func NewSource() *Source {
+	return &Source{
+		tables:    make(map[string]*schema.Table),
+		tableList: make([]string, 0),
+		native:    make(map[string]string),
+	}
+}
+
+// NewSourceDB create a source from an existing sql.DB and dialect.
+func NewSourceDB(db *sql.DB, d *Dialect) *Source {
+	m := NewSource()
+	m.db = db
+	m.d = d
+	return m
+}
+
+// Init the source
+func (m *Source) Init() {}
+
+// Setup this source with schema from parent, opening database and
+// loading table schemas.
+func (m *Source) Setup(s *schema.Schema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.schema = s
+	var conf = s.Conf.Settings
+	if m.db == nil {
+		driver := conf.String("driver")
+		if driver == "" {
+			return fmt.Errorf("sqldb source %q requires driver setting", s.Name)
+		}
+		db, err := sql.Open(driver, conf.String("dsn"))
+		if err != nil {
+			u.Errorf("could not open %q err=%v", driver, err)
+			return err
+		}
+		if err = db.Ping(); err != nil {
+			u.Errorf("could not ping %q err=%v", driver, err)
+			return err
+		}
+		m.db = db
+		if m.d == nil {
+			m.d, _ = DialectGet(driver)
+		}
+	}
+	if dn := conf.String("dialect"); dn != "" {
+		d, ok := DialectGet(dn)
+		if !ok {
+			return fmt.Errorf("sqldb dialect %q not found", dn)
+		}
+		m.d = d
+	}
+	if m.d == nil {
+		return fmt.Errorf("sqldb source %q requires dialect setting", s.Name)
+	}
+
+	tables := conf.Strings("tables")
+	if len(tables) == 0 {
+		var err error
+		tables, err = m.findTables()
+		if err != nil {
+			return err
+		}
+	}
+	for _, table := range tables {
+		if err := m.loadTable(table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Source) findTables() ([]string, error) {
+	rows, err := m.db.Query(m.d.TablesQuery)
+	if err != nil {
+		u.Errorf("could not list tables err=%v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// loadTable introspect the column names, types of a table by
+// running a query for no rows.
+func (m *Source) loadTable(name string) error {
+	w := m.d.Writer()
+	w.WriteIdentityQuote(name, m.d.IdentityQuote)
+	rows, err := m.db.Query(fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", w.String()))
+	if err != nil {
+		u.Errorf("could not read table %q err=%v", name, err)
+		return err
+	}
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	tbl := schema.NewTable(strings.ToLower(name))
+	for _, ct := range colTypes {
+		size := 255
+		if l, ok := ct.Length(); ok && l > 0 && l < 1<<31 {
+			size = int(l)
+		}
+		fld := schema.NewFieldBase(ct.Name(), TypeFromString(ct.DatabaseTypeName()), size, "")
+		if precision, scale, ok := ct.DecimalSize(); ok && fld.Type == uint32(value.DecimalType) {
+			fld = schema.NewFieldDecimal(ct.Name(), int(precision), int(scale), "")
+		}
+		if nullable, ok := ct.Nullable(); ok {
+			fld.NoNulls = !nullable
+		}
+		tbl.AddField(fld)
+	}
+	tbl.SetColumnsFromFields()
+
+	m.tables[tbl.Name] = tbl
+	m.native[tbl.Name] = name
+	m.tableList = append(m.tableList, tbl.Name)
+	return nil
+}
+
+// Open a connection to table.
+func (m *Source) Open(table string) (schema.Conn, error) {
+	m.mu.Lock()
+	t, ok := m.tables[strings.ToLower(table)]
+	m.mu.Unlock()
+	if !ok {
+		return nil, schema.ErrNotFound
+	}
+	return newQueryConn(t, m), nil
+}
+
+// Table gets table schema for given table
+func (m *Source) Table(table string) (*schema.Table, error) {
+	m.mu.Lock()
+	t, ok := m.tables[strings.ToLower(table)]
+	m.mu.Unlock()
+	if !ok {
+		return nil, schema.ErrNotFound
+	}
+	return t, nil
+}
+
+// Tables gets list of tables
+func (m *Source) Tables() []string { return m.tableList }
+
+// Capabilities of the dialect, which parts of a select are pushed down.
+func (m *Source) Capabilities() *schema.Capabilities { return m.d.Capabilities() }
+
+// DB the underlying database
+func (m *Source) DB() *sql.DB { return m.db }
+
+// Close this source, closing the underlying db
+func (m *Source) Close() error {
+	if m.db != nil {
+		err := m.db.Close()
+		if err != nil {
+			return err
+		}
+		m.db = nil
+	}
+	return nil
+}
+
+// TypeFromString given a native database type name, return data type
+func TypeFromString(t string) value.ValueType {
+	t = strings.ToLower(t)
+	if idx := strings.Index(t, "("); idx > 0 {
+		t = t[:idx]
+	}
+	switch t {
+	case "int", "integer", "int2", "int4", "int8", "smallint", "bigint", "tinyint",
+		"mediumint", "serial", "bigserial", "smallserial":
+		return value.IntType
+	case "real", "float", "float4", "float8", "double", "double precision":
+		return value.NumberType
+	case "numeric", "decimal":
+		return value.DecimalType
+	case "bool", "boolean":
+		return value.BoolType
+	case "date", "datetime", "time", "timestamp", "timestamptz",
+		"timestamp with time zone", "timestamp without time zone":
+		return value.TimeType
+	case "json", "jsonb":
+		return value.JsonType
+	default:
+		return value.StringType
+	}
+}
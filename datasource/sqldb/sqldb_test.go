@@ -0,0 +1,368 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
+
+	// Ensure we import sqlite driver, we test in postgres compatibility mode
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/araddon/qlbridge/datasource"
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/datasource/sqldb"
+	"github.com/araddon/qlbridge/datasource/sqlite"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/testutil"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	testDir  string
+	sch      *schema.Schema
+	loadData sync.Once
+)
+
+func exitIfErr(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Setup() // will call flag.Parse()
+
+	dir, err := ioutil.TempDir("", "qlbridge_sqldb")
+	exitIfErr(err)
+	testDir = dir
+
+	rc := m.Run()
+	os.RemoveAll(testDir)
+	os.Exit(rc)
+}
+
+func loadTestDataOnce(t *testing.T) {
+	loadData.Do(func() {
+		// load our mock data sources "users", "orders"
+		td.LoadTestDataOnce()
+
+		dbFile := filepath.Join(testDir, "test.db")
+		db, err := sql.Open("sqlite3", dbFile)
+		exitIfErr(err)
+
+		// Create db schema
+		for _, tablename := range td.MockSchema.Tables() {
+			tbl, _ := td.MockSchema.Table(tablename)
+			if tbl == nil {
+				panic("missing table " + tablename)
+			}
+			_, err := db.Exec(sqlite.TableToString(tbl))
+			assert.Equal(t, nil, err)
+		}
+		db.Close()
+
+		by := []byte(`{
+			"name": "sqldb_test",
+			"type": "sqldb",
+			"settings" : {
+			  "driver" : "sqlite3",
+			  "dialect" : "postgres",
+			  "tables" : ["users", "orders"]
+			}
+		}`)
+		conf := &schema.ConfigSource{}
+		exitIfErr(json.Unmarshal(by, conf))
+		conf.Settings["dsn"] = dbFile
+
+		reg := schema.DefaultRegistry()
+		exitIfErr(reg.SchemaAddFromConfig(conf))
+		s, ok := reg.Schema("sqldb_test")
+		assert.Equal(t, true, ok)
+		sch = s
+
+		// Copy, populate db using our Put
+		for _, tablename := range []string{"users", "orders"} {
+			baseConn, err := td.MockSchema.OpenConn(tablename)
+			exitIfErr(err)
+
+			dbConn, err := s.OpenConn(tablename)
+			exitIfErr(err)
+			upsertConn := dbConn.(schema.ConnUpsert)
+
+			conn := baseConn.(schema.ConnScanner)
+			for {
+				msg := conn.Next()
+				if msg == nil {
+					break
+				}
+				sm := msg.(*datasource.SqlDriverMessageMap)
+				if _, err := upsertConn.Put(context.Background(), nil, sm.Vals); err != nil {
+					u.Errorf("could not insert %v  %#v", err, sm.Vals)
+				}
+			}
+			assert.Equal(t, nil, dbConn.Close())
+		}
+	})
+}
+
+func planContext(query string) *plan.Context {
+	ctx := plan.NewContext(query)
+	ctx.DisableRecover = true
+	ctx.Schema = sch
+	ctx.Session = datasource.NewMySqlSessionVars()
+	return ctx
+}
+
+func TestSuite(t *testing.T) {
+	loadTestDataOnce(t)
+	defer func() {
+		td.SetContextToMockCsv()
+	}()
+	td.TestContext = planContext
+	testutil.RunSimpleSuite(t)
+
+	// emaildomain() can't be pushed down, is poly-filled
+	testutil.TestSelect(t, `SELECT user_id FROM users WHERE emaildomain(email) = "email.com" AND user_id = "hT2impsOPUREcVPc"`,
+		[][]driver.Value{{"hT2impsOPUREcVPc"}},
+	)
+	// the table has no nosuchcol, where term the rewriter can't write is
+	// evaluated by qlbridge not dropped
+	testutil.TestSelect(t, `SELECT user_id FROM users WHERE nosuchcol = "x" AND user_id = "hT2impsOPUREcVPc"`,
+		[][]driver.Value{},
+	)
+	testutil.TestSelect(t, `SELECT count(*) AS ct FROM orders WHERE price > 30`,
+		[][]driver.Value{{int64(1)}},
+	)
+	// aggregation, ordering, limit pushed down
+	testutil.TestSelect(t, `SELECT user_id, count(*) AS ct FROM orders GROUP BY user_id ORDER BY ct DESC LIMIT 1`,
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM", int64(2)}},
+	)
+	// order by source column pushed down, projection poly-filled
+	testutil.TestSelect(t, `SELECT emaildomain(email) AS domain FROM users WHERE user_id != "hT2impsabc345c" ORDER BY user_id LIMIT 1`,
+		[][]driver.Value{{"email.com"}},
+	)
+	testutil.TestSelect(t, `SELECT CASE WHEN price > 30 THEN "high" ELSE "low" END AS band FROM orders ORDER BY price DESC LIMIT 2`,
+		[][]driver.Value{{"high"}, {"low"}},
+	)
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := sqldb.Postgres.Capabilities()
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{`tolower(email) = "a"`, true},
+		{`email LIKE "a%"`, true},
+		{`email LIKE "a*"`, false},
+		{`email RLIKE "^a.*@"`, true},
+		{`email ~ user_id`, false},
+		{`abs(ct) > 2 AND ct & 4 = 4`, true},
+		{`ct ^ 4 = 0`, false},
+		{`round(ct, 2) > 1`, false},
+		{`email != NULL`, false},
+		{`emaildomain(email) = "x.com"`, false},
+		{`count(*)`, true},
+		{`CASE WHEN ct > 5 THEN "big" ELSE "small" END`, true},
+		{`CASE WHEN emaildomain(email) = "x.com" THEN 1 END`, false},
+	}
+	for _, tt := range tests {
+		node, err := expr.ParseExpression(tt.expr)
+		assert.Equal(t, nil, err, tt.expr)
+		assert.Equal(t, tt.ok, caps.SupportsExpr(node), tt.expr)
+	}
+}
+
+func TestMutations(t *testing.T) {
+	loadTestDataOnce(t)
+
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "sqldb_test",
+		Exec:        `INSERT INTO orders (order_id, user_id, item_id, price) VALUES ("9", "zed", "x", 5.5);`,
+		ExpectRowCt: 1,
+	})
+	testutil.TestSqlSelect(t, "sqldb_test", `SELECT item_id FROM orders WHERE user_id = "zed";`,
+		[][]driver.Value{{"x"}},
+	)
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "sqldb_test",
+		Exec:        `UPDATE orders SET item_id = "y" WHERE user_id = "zed";`,
+		ExpectRowCt: 1,
+	})
+	testutil.TestSqlSelect(t, "sqldb_test", `SELECT item_id FROM orders WHERE user_id = "zed";`,
+		[][]driver.Value{{"y"}},
+	)
+	testutil.ExecSqlSpec(t, &testutil.QuerySpec{
+		Source:      "sqldb_test",
+		Exec:        `DELETE FROM orders WHERE user_id = "zed";`,
+		ExpectRowCt: 1,
+	})
+	testutil.TestSqlSelect(t, "sqldb_test", `SELECT item_id FROM orders WHERE user_id = "zed";`,
+		[][]driver.Value{},
+	)
+}
+
+func TestRewrite(t *testing.T) {
+	tbl := schema.NewTable("users")
+	tbl.AddField(schema.NewFieldBase("user_id", 0, 255, ""))
+	tbl.AddField(schema.NewFieldBase("Email", 0, 255, ""))
+	tbl.AddField(schema.NewFieldBase("ct", 0, 255, ""))
+	tbl.SetColumnsFromFields()
+
+	tests := []struct {
+		sql      string
+		postgres string
+		mysql    string
+		complete bool
+	}{
+		{
+			`SELECT user_id FROM users WHERE email = "a'b" AND ct > 5`,
+			`SELECT "user_id" FROM "users" WHERE ("Email" = 'a''b') AND ("ct" > 5)`,
+			"SELECT `user_id` FROM `users` WHERE (`Email` = 'a''b') AND (`ct` > 5)",
+			true,
+		},
+		{
+			`SELECT user_id FROM users WHERE email != NULL AND emaildomain(email) = "x.com" ORDER BY ct DESC`,
+			`SELECT "user_id" FROM "users" WHERE "Email" IS NOT NULL ORDER BY "ct" DESC`,
+			"SELECT `user_id` FROM `users` WHERE `Email` IS NOT NULL ORDER BY `ct` DESC",
+			false,
+		},
+		{
+			`SELECT user_id FROM users WHERE ct BETWEEN 1 AND 5 OR tolower(email) IN ("a", "b")`,
+			`SELECT "user_id" FROM "users" WHERE (("ct" > 1 AND "ct" < 5) OR (lower("Email") IN ('a', 'b')))`,
+			"SELECT `user_id` FROM `users` WHERE ((`ct` > 1 AND `ct` < 5) OR (lower(`Email`) IN ('a', 'b')))",
+			true,
+		},
+		{
+			`SELECT CASE WHEN ct > 5 THEN "big" ELSE "small" END AS sz FROM users WHERE CASE email WHEN "a" THEN ct END = 1`,
+			`SELECT CASE WHEN ("ct" > 5) THEN 'big' ELSE 'small' END AS "sz" FROM "users" WHERE (CASE "Email" WHEN 'a' THEN "ct" END = 1)`,
+			"SELECT CASE WHEN (`ct` > 5) THEN 'big' ELSE 'small' END AS `sz` FROM `users` WHERE (CASE `Email` WHEN 'a' THEN `ct` END = 1)",
+			true,
+		},
+		{
+			`SELECT user_id FROM users WHERE email ~* "^aaron" AND ct > 5`,
+			`SELECT "user_id" FROM "users" WHERE ("Email" ~* '^aaron') AND ("ct" > 5)`,
+			"SELECT `user_id` FROM `users` WHERE (`ct` > 5)",
+			true,
+		},
+		{
+			`SELECT user_id FROM users WHERE ct & 4 = 4 AND log10(ct) > 1 AND mod(ct, 2) = 0`,
+			`SELECT "user_id" FROM "users" WHERE (("ct" & 4) = 4) AND (log("ct") > 1)`,
+			"SELECT `user_id` FROM `users` WHERE (log10(`ct`) > 1) AND (mod(`ct`, 2) = 0)",
+			false,
+		},
+		{
+			`SELECT user_id FROM users WHERE email LIKE "%aaron*"`,
+			`SELECT "user_id" FROM "users"`,
+			"SELECT `user_id` FROM `users`",
+			false,
+		},
+		{
+			// literals that look quoted are still escaped
+			`SELECT user_id FROM users WHERE email = "'x' OR '1'='1'"`,
+			`SELECT "user_id" FROM "users" WHERE ("Email" = '''x'' OR ''1''=''1''')`,
+			"SELECT `user_id` FROM `users` WHERE (`Email` = '''x'' OR ''1''=''1''')",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := rel.ParseSqlSelect(tt.sql)
+		assert.Equal(t, nil, err, tt.sql)
+
+		sqlString, complete, err := sqldb.NewRewriter(sqldb.Postgres, tbl).Select(stmt)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.postgres, sqlString)
+		assert.Equal(t, tt.complete, complete)
+
+		sqlString, _, err = sqldb.NewRewriter(sqldb.MySQL, tbl).Select(stmt)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.mysql, sqlString)
+	}
+
+	// without a table, identities are passed through
+	where, err := expr.ParseExpression(`a = 1 AND b = "x"`)
+	assert.Equal(t, nil, err)
+	whereSql, complete := sqldb.NewRewriter(sqldb.Postgres, nil).Where(where)
+	assert.Equal(t, true, complete)
+	assert.Equal(t, `("a" = 1) AND ("b" = 'x')`, whereSql)
+
+	// backslash is an escape character in mysql literals only
+	where = expr.NewBinaryNode(lex.Token{T: lex.TokenEqual, V: "="},
+		expr.NewIdentityNodeVal("a"), expr.NewStringNode(`x\' OR 1=1 -- `))
+	whereSql, _ = sqldb.NewRewriter(sqldb.Postgres, nil).Where(where)
+	assert.Equal(t, `("a" = 'x\'' OR 1=1 -- ')`, whereSql)
+	whereSql, _ = sqldb.NewRewriter(sqldb.MySQL, nil).Where(where)
+	assert.Equal(t, "(`a` = 'x\\\\'' OR 1=1 -- ')", whereSql)
+}
+
+func TestTypeFromString(t *testing.T) {
+	tests := map[string]value.ValueType{
+		"INTEGER":          value.IntType,
+		"double precision": value.NumberType,
+		"DECIMAL(10,2)":    value.DecimalType,
+		"numeric":          value.DecimalType,
+		"varchar(255)":     value.StringType,
+	}
+	for typ, vt := range tests {
+		assert.Equal(t, vt, sqldb.TypeFromString(typ), typ)
+	}
+}
+
+func TestScanError(t *testing.T) {
+	loadTestDataOnce(t)
+
+	// the registered sqldb source keeps its db, so add to the test db
+	dbFile := filepath.Join(testDir, "test.db")
+	db, err := sql.Open("sqlite3", dbFile)
+	assert.Equal(t, nil, err)
+	// abs() of the min int64 is an overflow error on the second row
+	for _, s := range []string{
+		`CREATE TABLE nums (id INTEGER)`,
+		`INSERT INTO nums VALUES (1), (-9223372036854775808)`,
+		`CREATE VIEW absnums AS SELECT abs(id) AS id FROM nums`,
+	} {
+		_, err = db.Exec(s)
+		assert.Equal(t, nil, err, s)
+	}
+	db.Close()
+
+	conf := &schema.ConfigSource{}
+	assert.Equal(t, nil, json.Unmarshal([]byte(`{
+		"name": "sqldb_scanerr",
+		"type": "sqldb",
+		"settings" : {"driver" : "sqlite3", "dialect" : "postgres", "tables" : ["absnums"]}
+	}`), conf))
+	conf.Settings["dsn"] = dbFile
+	reg := schema.DefaultRegistry()
+	assert.Equal(t, nil, reg.SchemaAddFromConfig(conf))
+	s, ok := reg.Schema("sqldb_scanerr")
+	assert.Equal(t, true, ok)
+
+	ctx := plan.NewContext(`SELECT id FROM absnums`)
+	ctx.DisableRecover = true
+	ctx.Schema = s
+	ctx.Session = datasource.NewMySqlSessionVars()
+	job, err := exec.BuildSqlJob(ctx)
+	assert.Equal(t, nil, err)
+	var msgs []schema.Message
+	job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+	assert.Equal(t, nil, job.Setup())
+	err = job.Run()
+	job.Close()
+	assert.NotEqual(t, nil, err, "scan error should fail the job")
+	assert.Equal(t, 1, len(msgs))
+}
@@ -16,11 +16,13 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/datasource/sqldb"
 	"github.com/araddon/qlbridge/exec"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/plan"
 	"github.com/araddon/qlbridge/rel"
 	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
 )
 
 var (
@@ -149,6 +151,12 @@ func (m *qryconn) Next() schema.Message {
 				case []uint8:
 					writeCols[i] = driver.Value(string(val))
 				}
+				if vt, ok := m.tbl.Column(m.cols[i]); ok && vt == value.DecimalType && col != nil {
+					// sqlite stores decimals as numeric, real
+					if dv, ok := value.ValueToDecimal(value.NewValue(writeCols[i])); ok {
+						writeCols[i] = dv
+					}
+				}
 			}
 			msg := datasource.NewSqlDriverMessageMap(m.ct, writeCols, m.colidx)
 
@@ -232,19 +240,28 @@ func (m *qryconn) Delete(key driver.Value) (int, error) {
 // to push down as much sql logic down to sqlite.
 func (m *qryconn) WalkSourceSelect(planner plan.Planner, p *plan.Source) (plan.Task, error) {
 
-	sqlSelect := p.Stmt.Source
-	u.Infof("original %s", sqlSelect.String())
-	p.Stmt.Source = nil
-	p.Stmt.Rewrite(sqlSelect)
-	sqlSelect = p.Stmt.Source
-	u.Infof("original after From(source) rewrite %s", sqlSelect.String())
-	sqlSelect.RewriteAsRawSelect()
+	var sqlSelect *rel.SqlSelect
+	if p.Pushdown != nil {
+		// planner has split statement per our capabilities
+		sqlSelect = p.Pushdown.Stmt
+	} else {
+		parent := p.Stmt.Source
+		u.Infof("original %s", parent.String())
+		p.Stmt.Source = nil
+		p.Stmt.Rewrite(parent)
+		sqlSelect = p.Stmt.Source
+		u.Infof("original after From(source) rewrite %s", sqlSelect.String())
+		sqlSelect.RewriteAsRawSelect()
+		sqldb.AddWhereColumns(parent, sqlSelect, m.tbl)
+	}
 
 	m.cols = sqlSelect.Columns.UnAliasedFieldNames()
 	m.colidx = sqlSelect.ColIndexes()
-	sqlString, _ := newRewriter(sqlSelect).rewrite()
+	sqlString, _, err := sqldb.NewRewriter(dialect, m.tbl).Select(sqlSelect)
+	if err != nil {
+		return nil, err
+	}
 
-	u.Infof("after sqlite-rewrite %s", sqlSelect.String())
 	u.Infof("pushdown sql: %s", sqlString)
 
 	rows, err := m.source.db.Query(sqlString)
@@ -252,6 +269,26 @@ func (m *qryconn) WalkSourceSelect(planner plan.Planner, p *plan.Source) (plan.T
 		u.Errorf("could not open master err=%v", err)
 		return nil, err
 	}
+	if p.Pushdown != nil {
+		// result columns are positional to the statement columns
+		if sqlSelect.Star {
+			cols, err := rows.Columns()
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			m.cols = cols
+		} else {
+			m.cols = make([]string, len(sqlSelect.Columns))
+			for i, col := range sqlSelect.Columns {
+				m.cols[i] = col.As
+			}
+		}
+		m.colidx = make(map[string]int, len(m.cols))
+		for i, col := range m.cols {
+			m.colidx[col] = i
+		}
+	}
 	m.rows = rows
 	m.TaskBase = exec.NewTaskBase(p.Context())
 	p.SourceExec = true
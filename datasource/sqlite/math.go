@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"math"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/araddon/qlbridge/datasource/sqldb"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/expr/builtins"
+	"github.com/araddon/qlbridge/value"
+)
+
+// mathFuncs qlbridge math builtins registered as sqlite functions so they
+// may be pushed down, sqlite only has abs() and round() natively and its
+// round() always returns a float.  rand() is not pushed down.
+var mathFuncs = map[string]expr.CustomFunc{
+	"sign":    &builtins.Sign{},
+	"round":   &builtins.Round{},
+	"trunc":   &builtins.Trunc{},
+	"ceil":    &builtins.Ceil{},
+	"ceiling": &builtins.Ceil{},
+	"floor":   &builtins.Floor{},
+	"mod":     &builtins.Mod{},
+	"pow":     &builtins.Pow{},
+	"sqrt":    &builtins.Sqrt{},
+	"atan2":   &builtins.Atan2{},
+	"pi":      &builtins.Pi{},
+	"exp":     builtins.NewMathFunc("exp", math.Exp),
+	"ln":      builtins.NewMathFunc("ln", math.Log),
+	"log10":   builtins.NewMathFunc("log10", math.Log10),
+	"sin":     builtins.NewMathFunc("sin", math.Sin),
+	"cos":     builtins.NewMathFunc("cos", math.Cos),
+	"tan":     builtins.NewMathFunc("tan", math.Tan),
+	"asin":    builtins.NewMathFunc("asin", math.Asin),
+	"acos":    builtins.NewMathFunc("acos", math.Acos),
+	"atan":    builtins.NewMathFunc("atan", math.Atan),
+}
+
+// dialect sqlite with regular expressions and math functions pushed down,
+// they are evaluated by go so have the same semantics as qlbridge.
+var dialect = func() *sqldb.Dialect {
+	d := *sqldb.Sqlite
+	d.Regexp = "REGEXP"
+	d.Funcs = make(map[string]string, len(sqldb.Sqlite.Funcs)+len(mathFuncs))
+	for name, native := range sqldb.Sqlite.Funcs {
+		d.Funcs[name] = native
+	}
+	for name := range mathFuncs {
+		d.Funcs[name] = name
+	}
+	return &d
+}()
+
+func registerMathFuncs(conn *sqlite3.SQLiteConn) error {
+	for name, fn := range mathFuncs {
+		if err := conn.RegisterFunc(name, sqliteFunc(fn), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteFunc adapt a qlbridge func to a variadic sqlite function.  The
+// sqlite3 driver only returns concrete types so results are float64, with
+// NaN (which sqlite stores as NULL) for results that can't be evaluated.
+func sqliteFunc(fn expr.CustomFunc) func(args ...interface{}) (float64, error) {
+	return func(args ...interface{}) (float64, error) {
+		n := &expr.FuncNode{Args: make([]expr.Node, len(args))}
+		for i := range n.Args {
+			n.Args[i] = &expr.NullNode{}
+		}
+		eval, err := fn.Validate(n)
+		if err != nil {
+			return 0, err
+		}
+		vals := make([]value.Value, len(args))
+		for i, arg := range args {
+			if b, isBytes := arg.([]byte); isBytes {
+				arg = string(b)
+			}
+			vals[i] = value.NewValue(arg)
+		}
+		v, ok := eval(nil, vals)
+		if !ok {
+			return math.NaN(), nil
+		}
+		fv, ok := value.ValueToFloat64(v)
+		if !ok {
+			return math.NaN(), nil
+		}
+		return fv, nil
+	}
+}
@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/araddon/qlbridge/vm"
+)
+
+// driverName the sqlite3 driver with a regexp() function, which sqlite
+// requires for its  X REGEXP Y  operator, and the math functions.
+const driverName = "sqlite3_qlbridge"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("regexp", regexpMatch, true); err != nil {
+				return err
+			}
+			return registerMathFuncs(conn)
+		},
+	})
+}
+
+// regexpMatch  value REGEXP pattern  is called as regexp(pattern, value)
+func regexpMatch(pattern string, val interface{}) (bool, error) {
+	var s string
+	switch v := val.(type) {
+	case nil:
+		return false, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	re, err := vm.CompileRegex(pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
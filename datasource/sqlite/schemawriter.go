@@ -81,6 +81,12 @@ func WriteField(w *bytes.Buffer, fld *schema.Field) {
 		fmt.Fprintf(w, "text")
 	case value.NumberType:
 		fmt.Fprint(w, "REAL")
+	case value.DecimalType:
+		if fld.Precision > 0 {
+			fmt.Fprintf(w, "DECIMAL(%d,%d)", fld.Precision, fld.Scale)
+		} else {
+			fmt.Fprint(w, "DECIMAL")
+		}
 	case value.TimeType:
 		fmt.Fprint(w, "text")
 	case value.JsonType:
@@ -102,6 +108,9 @@ func TypeFromString(t string) value.ValueType {
 	case "real":
 		return value.NumberType
 	default:
+		if _, _, ok := value.ParseDecimalType(t); ok {
+			return value.DecimalType
+		}
 		return value.StringType
 	}
 }
@@ -119,6 +128,8 @@ func ValueString(t value.ValueType) string {
 		return "text"
 	case value.NumberType:
 		return "real"
+	case value.DecimalType:
+		return "numeric"
 	case value.IntType:
 		return "integer"
 	case value.BoolType:
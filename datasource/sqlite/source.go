@@ -27,7 +27,8 @@ func init() {
 
 var (
 	// Ensure our source implements Source interface
-	_ schema.Source = (*Source)(nil)
+	_ schema.Source             = (*Source)(nil)
+	_ schema.SourceCapabilities = (*Source)(nil)
 	// ensure our Source implements connection features
 	_ schema.Conn = (*Source)(nil)
 )
@@ -77,7 +78,7 @@ func (m *Source) Setup(s *schema.Schema) error {
 
 	// It will be created if it doesn't exist.
 	//   "./source.enriched.db"
-	db, err := sql.Open("sqlite3", m.file)
+	db, err := sql.Open(driverName, m.file)
 	if err != nil {
 		u.Errorf("could not open %q err=%v", m.file, err)
 		return err
@@ -146,6 +147,9 @@ func (m *Source) Table(table string) (*schema.Table, error) {
 // Tables gets list of tables
 func (m *Source) Tables() []string { return m.tableList }
 
+// Capabilities parts of select statements sqlite can execute.
+func (m *Source) Capabilities() *schema.Capabilities { return dialect.Capabilities() }
+
 // Close this source, closing the underlying sqlite db file
 func (m *Source) Close() error {
 	if m.db != nil {
@@ -3,6 +3,7 @@ package sqlite_test
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"os"
 	"sync"
@@ -152,3 +153,37 @@ func TestSuite(t *testing.T) {
 	LoadTestDataOnce(t)
 	testutil.RunSimpleSuite(t)
 }
+
+func TestRegexp(t *testing.T) {
+	defer func() {
+		td.SetContextToMockCsv()
+	}()
+	LoadTestDataOnce(t)
+	td.TestContext = planContext
+	testutil.TestSelect(t, `SELECT user_id FROM users WHERE email RLIKE "^[a-z]+@"`,
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM"}, {"hT2impsOPUREcVPc"}},
+	)
+	testutil.TestSelect(t, `SELECT user_id FROM users WHERE email ~* "^BOB@"`,
+		[][]driver.Value{{"hT2impsOPUREcVPc"}},
+	)
+	testutil.TestSelect(t, `SELECT user_id FROM users WHERE user_id REGEXP "abc"`,
+		[][]driver.Value{{"hT2impsabc345c"}},
+	)
+}
+
+func TestMath(t *testing.T) {
+	defer func() {
+		td.SetContextToMockCsv()
+	}()
+	LoadTestDataOnce(t)
+	td.TestContext = planContext
+	testutil.TestSelect(t, `SELECT user_id FROM orders WHERE floor(price) = 37`,
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM"}},
+	)
+	testutil.TestSelect(t, `SELECT user_id, round(price) AS p FROM orders WHERE mod(item_id, 2) = 0`,
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM", float64(38)}},
+	)
+	testutil.TestSelect(t, `SELECT user_id FROM orders WHERE item_count & 2 = 2 AND ln(price) > 3.5`,
+		[][]driver.Value{{"9Ip1aKbeZe2njCDM"}},
+	)
+}
@@ -0,0 +1,627 @@
+package exec
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/arrow"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+const (
+	// ItemDefaultBatchSize default rows per record batch of batch tasks
+	ItemDefaultBatchSize = 1024
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*BatchSource)(nil)
+	_ TaskRunner = (*BatchWhere)(nil)
+	_ TaskRunner = (*BatchProjection)(nil)
+	_ TaskRunner = (*BatchGroupBy)(nil)
+
+	// BatchExecutor is an Executor
+	_ Executor = (*BatchExecutor)(nil)
+)
+
+// BatchMessage a columnar record batch of rows, the message passed between
+// batch tasks instead of a message per row.
+type BatchMessage struct {
+	id     uint64
+	Record *arrow.Record
+}
+
+// NewBatchMessage a message of a record batch
+func NewBatchMessage(id uint64, rec *arrow.Record) *BatchMessage {
+	return &BatchMessage{id: id, Record: rec}
+}
+
+func (m *BatchMessage) Id() uint64        { return m.id }
+func (m *BatchMessage) Body() interface{} { return m.Record }
+
+// BatchExecutor is a JobExecutor which runs selects as vectorized tasks
+// passing record batches: a single table scanned by a schema.ConnScanner,
+// with where, group by (count, sum, avg) and projection.  Any other select
+// (joins, order by, having, sources that execute their own plans) runs as
+// the row at a time tasks of JobExecutor.
+type BatchExecutor struct {
+	*JobExecutor
+}
+
+// NewBatchExecutor creates a new batch Job Executor.
+func NewBatchExecutor(ctx *plan.Context, planner plan.Planner) *BatchExecutor {
+	e := &BatchExecutor{NewExecutor(ctx, planner)}
+	e.Executor = e
+	return e
+}
+
+// BuildSqlBatchJob given a plan context (query statement, +context) create
+// a job of batch tasks where the statement allows, see BatchExecutor.  Use
+// ResultBatchWriter for the results as arrow record batches.
+func BuildSqlBatchJob(ctx *plan.Context) (*JobExecutor, error) {
+	job := NewBatchExecutor(ctx, plan.NewPlanner(ctx))
+	task, err := BuildSqlJobPlanned(job.Planner, job.Executor, ctx)
+	if err != nil {
+		return nil, err
+	}
+	taskRunner, ok := task.(TaskRunner)
+	if !ok {
+		return nil, fmt.Errorf("Expected TaskRunner but was %T", task)
+	}
+	job.RootTask = taskRunner
+	return job.JobExecutor, nil
+}
+
+// WalkSelect create dag of batch tasks for the plan Select if it can be
+// run as batches, otherwise of row tasks.
+func (m *BatchExecutor) WalkSelect(p *plan.Select) (Task, error) {
+	if !m.batchable(p) {
+		return m.JobExecutor.WalkSelect(p)
+	}
+	root := m.NewTask(p)
+	for _, t := range p.Children() {
+		var et Task
+		switch t := t.(type) {
+		case *plan.Source:
+			src, err := NewBatchSource(m.Ctx, t)
+			if err != nil {
+				return nil, err
+			}
+			if err := root.Add(src); err != nil {
+				return nil, err
+			}
+			// the where of the source (single table) select
+			for _, c := range t.Children() {
+				if err := root.Add(NewBatchWhere(m.Ctx, c.(*plan.Where))); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		case *plan.Where:
+			et = NewBatchWhere(m.Ctx, t)
+		case *plan.GroupBy:
+			et = NewBatchGroupBy(m.Ctx, t)
+		case *plan.Projection:
+			et = NewBatchProjection(m.Ctx, t)
+		}
+		if err := root.Add(et); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// batchable can the select run as batch tasks
+func (m *BatchExecutor) batchable(p *plan.Select) bool {
+	if len(p.From) != 1 || p.Stmt.Distinct {
+		return false
+	}
+	for i, t := range p.Children() {
+		switch t := t.(type) {
+		case *plan.Source:
+			if i != 0 || len(t.Static) > 0 || t.Pushdown != nil {
+				return false
+			}
+			for _, c := range t.Children() {
+				if _, ok := c.(*plan.Where); !ok {
+					return false
+				}
+			}
+			if t.Conn == nil {
+				if t.DataSource == nil {
+					return false
+				}
+				conn, err := t.DataSource.Open(t.Stmt.SourceName())
+				if err != nil {
+					return false
+				}
+				t.Conn = conn
+			}
+			if _, ok := t.Conn.(ExecutorSource); ok {
+				return false
+			}
+			if _, ok := t.Conn.(schema.ConnScanner); !ok {
+				return false
+			}
+		case *plan.Where:
+		case *plan.GroupBy:
+			if t.Partial {
+				return false
+			}
+			if _, err := buildAggs(t); err != nil {
+				return false
+			}
+		case *plan.Projection:
+			for _, col := range t.Stmt.Columns {
+				if col.Guard != nil {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// BatchSource scans a source as record batches, from the batches of a
+// schema.ConnBatchScanner or the rows of a schema.ConnScanner.
+type BatchSource struct {
+	*Source
+	size   int
+	schema *arrow.Schema
+	rb     *arrow.RecordBuilder
+	done   bool
+}
+
+// NewBatchSource create a scanner to read record batches from data source
+func NewBatchSource(ctx *plan.Context, p *plan.Source) (*BatchSource, error) {
+	s, err := NewSource(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if s.Scanner == nil {
+		return nil, fmt.Errorf("%T Must Implement Scanner for %q", p.Conn, p.Stmt.String())
+	}
+	return &BatchSource{Source: s, size: ItemDefaultBatchSize}, nil
+}
+
+// nextBatch the next record batch of the source, nil at end
+func (m *BatchSource) nextBatch() *arrow.Record {
+	if bs, ok := m.Scanner.(schema.ConnBatchScanner); ok {
+		return bs.NextBatch(m.size)
+	}
+	// some scanners start over after the end, only read to it once
+	for !m.done {
+		msg := m.Scanner.Next()
+		if msg == nil {
+			m.done = true
+			break
+		}
+		rdr, ok := msg.(expr.ContextReader)
+		if !ok {
+			u.Errorf("could not convert to message reader: %T", msg)
+			continue
+		}
+		if m.rb == nil {
+			m.schema = sourceSchema(m.p.Tbl, rdr)
+			m.rb = arrow.NewRecordBuilder(m.schema)
+		}
+		row := make([]driver.Value, len(m.schema.Fields))
+		for i, f := range m.schema.Fields {
+			if v, ok := rdr.Get(f.Name); ok {
+				row[i] = v
+			}
+		}
+		m.rb.Append(row)
+		if m.rb.Len() >= m.size {
+			break
+		}
+	}
+	if m.rb == nil || m.rb.Len() == 0 {
+		return nil
+	}
+	return m.rb.NewRecord()
+}
+
+// sourceSchema the schema of the columns of a table, typed by the table
+// fields or else the values of the first row.
+func sourceSchema(tbl *schema.Table, first expr.ContextReader) *arrow.Schema {
+	s := arrow.NewSchema()
+	var cols []string
+	if tbl != nil {
+		cols = tbl.Columns()
+	}
+	if len(cols) == 0 {
+		for k := range first.Row() {
+			cols = append(cols, k)
+		}
+	}
+	for _, col := range cols {
+		t := arrow.String
+		var fld *schema.Field
+		if tbl != nil {
+			fld = tbl.FieldMap[col]
+		}
+		if fld != nil && fld.ValueType() != value.UnknownType {
+			t = arrow.FromValueType(fld.ValueType())
+		} else if v, ok := first.Get(col); ok && v != nil && !v.Nil() {
+			t = arrow.FromValueType(v.Type())
+		}
+		s.Fields = append(s.Fields, arrow.Field{Name: col, Type: t, Nullable: true})
+	}
+	return s
+}
+
+func (m *BatchSource) Run() error {
+	defer m.Ctx.Recover()
+	defer close(m.msgOutCh)
+
+	sigChan := m.SigChan()
+	id := uint64(0)
+	for rec := m.nextBatch(); rec != nil; rec = m.nextBatch() {
+		select {
+		case <-sigChan:
+			return nil
+		case m.msgOutCh <- NewBatchMessage(id, rec):
+			id++
+		}
+	}
+	return nil
+}
+
+// BatchWhere filters record batches by the where clause
+type BatchWhere struct {
+	*Where
+}
+
+// NewBatchWhere create new batch Where Clause
+func NewBatchWhere(ctx *plan.Context, p *plan.Where) *BatchWhere {
+	s := &BatchWhere{&Where{
+		TaskBase: NewTaskBase(ctx),
+		filter:   p.Stmt.Where.Expr,
+		sel:      p.Stmt,
+	}}
+	s.Handler = batchFilter(s.filter, s)
+	return s
+}
+
+func batchFilter(filter expr.Node, task TaskRunner) MessageHandler {
+	out := task.MessageOut()
+	eval := newBatchExpr(filter)
+	return func(ctx *plan.Context, msg schema.Message) bool {
+		bm, ok := msg.(*BatchMessage)
+		if !ok {
+			u.Errorf("could not filter msg, expected batch: %T", msg)
+			return false
+		}
+		rec := bm.Record
+		v := eval.Eval(ctx, rec)
+		sel := make([]int, 0, rec.Len)
+		for i := 0; i < rec.Len; i++ {
+			// as in whereFilter rows are dropped if null or false
+			if v.isNull(i) || (v.t == arrow.Bool && !v.bool(i)) {
+				continue
+			}
+			sel = append(sel, i)
+		}
+		if len(sel) == 0 {
+			return true
+		}
+		if len(sel) < rec.Len {
+			rec = rec.Take(sel)
+		}
+		select {
+		case out <- NewBatchMessage(bm.id, rec):
+			return true
+		case <-task.SigChan():
+			return false
+		}
+	}
+}
+
+// BatchProjection projects the columns of record batches
+type BatchProjection struct {
+	*Projection
+}
+
+// NewBatchProjection create a projection of record batches
+func NewBatchProjection(ctx *plan.Context, p *plan.Projection) *BatchProjection {
+	s := &BatchProjection{&Projection{
+		TaskBase: NewTaskBase(ctx),
+		p:        p,
+	}}
+	s.Handler = s.batchEvaluator(p.Final)
+	return s
+}
+
+func (m *BatchProjection) batchEvaluator(isFinal bool) MessageHandler {
+
+	out := m.MessageOut()
+	columns := m.p.Stmt.Columns
+	limit := m.p.Stmt.Limit
+	if limit == 0 {
+		limit = math.MaxInt32
+	}
+	exprs := make([]*batchExpr, len(columns))
+	for i, col := range columns {
+		if col.Expr != nil && !col.Star {
+			exprs[i] = newBatchExpr(col.Expr)
+		}
+	}
+
+	rowCt := 0
+	return func(ctx *plan.Context, msg schema.Message) bool {
+
+		select {
+		case <-m.SigChan():
+			return false
+		default:
+		}
+
+		bm, ok := msg.(*BatchMessage)
+		if !ok {
+			u.Errorf("could not project msg, expected batch: %T", msg)
+			return false
+		}
+		rec := bm.Record
+		if rowCt+rec.Len > limit {
+			sel := make([]int, limit-rowCt)
+			for i := range sel {
+				sel[i] = i
+			}
+			rec = rec.Take(sel)
+		}
+
+		s := arrow.NewSchema()
+		cols := make([]*arrow.Column, 0, len(columns))
+		for i, col := range columns {
+			if isFinal && col.ParentIndex < 0 {
+				continue
+			}
+			if col.Star {
+				s.Fields = append(s.Fields, rec.Schema.Fields...)
+				cols = append(cols, rec.Columns...)
+				continue
+			}
+			if exprs[i] == nil {
+				u.Warnf("wat?   nil col expr? %#v", col)
+				continue
+			}
+			v := exprs[i].Eval(ctx, rec)
+			s.Fields = append(s.Fields, arrow.Field{Name: col.As, Type: v.t, Nullable: true})
+			cols = append(cols, v.column(rec.Len))
+		}
+		outRec := arrow.NewRecord(s, cols)
+		outRec.Len = rec.Len
+		rowCt += rec.Len
+
+		select {
+		case out <- NewBatchMessage(bm.id, outRec):
+		case <-m.SigChan():
+			return false
+		}
+		if rowCt >= limit {
+			out <- nil // Sending nil message is a message to downstream to shutdown
+			m.Quit()
+			return false
+		}
+		return true
+	}
+}
+
+// BatchGroupBy a Sql Group By over record batches, the key and aggregated
+// columns are evaluated a batch at a time.  As GroupBy it holds all the
+// groups in memory, but only the aggregates not the rows.
+type BatchGroupBy struct {
+	*GroupBy
+}
+
+// NewBatchGroupBy create a group by of record batches
+func NewBatchGroupBy(ctx *plan.Context, p *plan.GroupBy) *BatchGroupBy {
+	return &BatchGroupBy{NewGroupBy(ctx, p)}
+}
+
+// batchAgg aggregates a column of a select for each group
+type batchAgg struct {
+	fn   string // count, sum, avg or "" for the group by value
+	eval *batchExpr
+	t    arrow.Type
+	// per group state
+	vals []driver.Value
+	cts  []int64
+	sums []float64
+}
+
+func (m *batchAgg) addGroup() {
+	m.vals = append(m.vals, nil)
+	m.cts = append(m.cts, 0)
+	m.sums = append(m.sums, 0)
+}
+
+func (m *batchAgg) do(g int, v *vector, i int, first bool) {
+	if v.isNull(i) {
+		return
+	}
+	switch m.fn {
+	case "":
+		if first {
+			m.vals[g] = v.value(i)
+		}
+	case "count":
+		m.cts[g]++
+	default:
+		switch v.t {
+		case arrow.Int64, arrow.Float64:
+			m.sums[g] += v.float64(i)
+		default:
+			f, ok := value.ValueToFloat64(value.NewValue(v.value(i)))
+			if !ok {
+				return
+			}
+			m.sums[g] += f
+		}
+		m.cts[g]++
+	}
+}
+
+func (m *batchAgg) result(g int) driver.Value {
+	switch m.fn {
+	case "":
+		return m.vals[g]
+	case "count":
+		return m.cts[g]
+	case "avg":
+		if m.cts[g] == 0 {
+			return nil
+		}
+		return m.sums[g] / float64(m.cts[g])
+	}
+	return m.sums[g]
+}
+
+func newBatchAggs(p *plan.GroupBy) []*batchAgg {
+	aggs := make([]*batchAgg, len(p.Stmt.Columns))
+colLoop:
+	for i, col := range p.Stmt.Columns {
+		for _, gb := range p.Stmt.GroupBy {
+			if gb.As == col.As || (col.Expr != nil && col.Expr.Equal(gb.Expr)) {
+				aggs[i] = &batchAgg{eval: newBatchExpr(col.Expr)}
+				continue colLoop
+			}
+		}
+		// buildAggs has checked these are count, sum or avg funcs
+		fn := col.Expr.(*expr.FuncNode)
+		agg := &batchAgg{fn: strings.ToLower(fn.Name), t: arrow.Float64}
+		if agg.fn == "count" {
+			agg.t = arrow.Int64
+		}
+		if len(fn.Args) > 0 {
+			agg.eval = newBatchExpr(fn.Args[0])
+		}
+		aggs[i] = agg
+	}
+	return aggs
+}
+
+// appendKey append the text of row i of a key vector to a group key
+func appendKey(key []byte, v *vector, i int) []byte {
+	if v.isNull(i) {
+		return append(key, 0)
+	}
+	switch v.t {
+	case arrow.Int64, arrow.Timestamp:
+		key = strconv.AppendInt(key, v.int64(i), 10)
+	case arrow.Float64:
+		key = strconv.AppendFloat(key, v.float64(i), 'g', -1, 64)
+	case arrow.Bool:
+		key = strconv.AppendBool(key, v.bool(i))
+	default:
+		if v.col == nil {
+			key = append(key, fmt.Sprint(v.c)...)
+		} else {
+			key = append(key, v.col.Bytes(i)...)
+		}
+	}
+	return append(key, ',')
+}
+
+// Run the batch group by, standard task interface.
+func (m *BatchGroupBy) Run() error {
+	defer m.Ctx.Recover()
+	defer close(m.msgOutCh)
+
+	outCh := m.MessageOut()
+	inCh := m.MessageIn()
+
+	keys := make([]*batchExpr, len(m.p.Stmt.GroupBy))
+	for i, col := range m.p.Stmt.GroupBy {
+		keys[i] = newBatchExpr(col.Expr)
+	}
+	aggs := newBatchAggs(m.p)
+
+	groups := make(map[string]int)
+	keyVecs := make([]*vector, len(keys))
+	aggVecs := make([]*vector, len(aggs))
+	var key []byte
+
+msgReadLoop:
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-inCh:
+			if !ok {
+				break msgReadLoop
+			}
+			bm, isBatch := msg.(*BatchMessage)
+			if !isBatch {
+				u.Errorf("unrecognized msg %T", msg)
+				close(m.TaskBase.sigCh)
+				return fmt.Errorf("To use BatchGroupBy must use BatchMessage but got %T", msg)
+			}
+			rec := bm.Record
+			for i, k := range keys {
+				keyVecs[i] = k.Eval(m.Ctx, rec)
+			}
+			for i, agg := range aggs {
+				aggVecs[i] = nil
+				if agg.eval != nil {
+					aggVecs[i] = agg.eval.Eval(m.Ctx, rec)
+				}
+			}
+			for row := 0; row < rec.Len; row++ {
+				key = key[:0]
+				for _, kv := range keyVecs {
+					key = appendKey(key, kv, row)
+				}
+				g, found := groups[string(key)]
+				if !found {
+					g = len(groups)
+					groups[string(key)] = g
+					for _, agg := range aggs {
+						agg.addGroup()
+					}
+				}
+				for i, agg := range aggs {
+					if aggVecs[i] == nil {
+						// count()
+						agg.cts[g]++
+						continue
+					}
+					if agg.fn == "" && aggVecs[i].t != agg.t {
+						agg.t = aggVecs[i].t
+					}
+					agg.do(g, aggVecs[i], row, !found)
+				}
+			}
+		}
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+	s := arrow.NewSchema()
+	cols := make([]*arrow.Column, len(aggs))
+	for i, agg := range aggs {
+		s.Fields = append(s.Fields, arrow.Field{Name: m.p.Stmt.Columns[i].As, Type: agg.t, Nullable: true})
+		b := arrow.NewBuilder(agg.t)
+		for g := 0; g < len(groups); g++ {
+			b.Append(agg.result(g))
+		}
+		cols[i] = b.Finish()
+	}
+	select {
+	case outCh <- NewBatchMessage(0, arrow.NewRecord(s, cols)):
+	case <-m.SigChan():
+	}
+	return nil
+}
@@ -0,0 +1,493 @@
+package exec
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/arrow"
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/value"
+)
+
+// vector the result of evaluating an expression against a record batch,
+// either a column or a constant for every row (col nil, c the constant
+// with nil a null).
+type vector struct {
+	t   arrow.Type
+	col *arrow.Column
+	c   driver.Value
+}
+
+func (m *vector) isNull(i int) bool {
+	if m.col == nil {
+		return m.c == nil
+	}
+	return m.col.IsNull(i)
+}
+
+func (m *vector) hasNulls() bool {
+	if m.col == nil {
+		return m.c == nil
+	}
+	return m.col.Nulls > 0
+}
+
+func (m *vector) int64(i int) int64 {
+	if m.col == nil {
+		return m.c.(int64)
+	}
+	return m.col.Int64s[i]
+}
+
+func (m *vector) float64(i int) float64 {
+	if m.col == nil {
+		switch c := m.c.(type) {
+		case int64:
+			return float64(c)
+		case float64:
+			return c
+		}
+		return 0
+	}
+	if m.t == arrow.Int64 {
+		return float64(m.col.Int64s[i])
+	}
+	return m.col.Float64s[i]
+}
+
+func (m *vector) bool(i int) bool {
+	if m.col == nil {
+		return m.c.(bool)
+	}
+	return m.col.Bool(i)
+}
+
+func (m *vector) str(i int) string {
+	if m.col == nil {
+		return m.c.(string)
+	}
+	return m.col.String(i)
+}
+
+// value of row i, nil if null
+func (m *vector) value(i int) driver.Value {
+	if m.col == nil {
+		return m.c
+	}
+	return m.col.Value(i)
+}
+
+// column the vector as a column of n rows
+func (m *vector) column(n int) *arrow.Column {
+	if m.col != nil {
+		return m.col
+	}
+	b := arrow.NewBuilder(m.t)
+	for i := 0; i < n; i++ {
+		b.Append(m.c)
+	}
+	return b.Finish()
+}
+
+// batchEval evaluates an expression against each row of a record batch
+type batchEval func(ctx *plan.Context, rec *arrow.Record) *vector
+
+// batchExpr an expression compiled for the record batches of a schema,
+// recompiled if the schema of the batches changes.
+type batchExpr struct {
+	node   expr.Node
+	schema *arrow.Schema
+	eval   batchEval
+}
+
+func newBatchExpr(node expr.Node) *batchExpr {
+	return &batchExpr{node: node}
+}
+
+func (m *batchExpr) Eval(ctx *plan.Context, rec *arrow.Record) *vector {
+	if m.eval == nil || m.schema != rec.Schema {
+		m.schema = rec.Schema
+		m.eval = compileBatch(m.node, rec.Schema)
+	}
+	return m.eval(ctx, rec)
+}
+
+// batchFieldIndex the column of an identity in a schema, by its full name
+// or the name without the table qualifier, -1 if there is none.
+func batchFieldIndex(s *arrow.Schema, key string) int {
+	if i := s.FieldIndex(key); i >= 0 {
+		return i
+	}
+	if dot := strings.LastIndex(key, "."); dot > 0 {
+		return s.FieldIndex(key[dot+1:])
+	}
+	return -1
+}
+
+// compileBatch the batch evaluator of a node.  Column references,
+// literals, comparisons, arithmetic and logical operators on columns of
+// matching types are evaluated a column at a time, anything else is
+// evaluated by the vm a row at a time.
+func compileBatch(node expr.Node, s *arrow.Schema) batchEval {
+	if eval, _, ok := compileVector(node, s); ok {
+		return eval
+	}
+	return compileRowFallback(node)
+}
+
+// compileVector the vectorized evaluator of a node and its result type,
+// false if it can't be vectorized.
+func compileVector(node expr.Node, s *arrow.Schema) (batchEval, arrow.Type, bool) {
+	switch n := node.(type) {
+	case *expr.IdentityNode:
+		i := batchFieldIndex(s, n.Text)
+		if i < 0 {
+			return nil, 0, false
+		}
+		t := s.Fields[i].Type
+		return func(ctx *plan.Context, rec *arrow.Record) *vector {
+			return &vector{t: t, col: rec.Columns[i]}
+		}, t, true
+	case *expr.NumberNode:
+		if n.IsInt {
+			return constVector(arrow.Int64, n.Int64)
+		}
+		return constVector(arrow.Float64, n.Float64)
+	case *expr.StringNode:
+		return constVector(arrow.String, n.Text)
+	case *expr.ValueNode:
+		switch n.Value.(type) {
+		case value.BoolValue, value.IntValue, value.NumberValue, value.StringValue:
+			t := arrow.FromValueType(n.Value.Type())
+			return constVector(t, arrow.Coerce(t, n.Value))
+		}
+	case *expr.BinaryNode:
+		if len(n.Args) != 2 {
+			return nil, 0, false
+		}
+		return compileBinary(n, n.Operator.T, n.Args, s)
+	case *expr.BooleanNode:
+		if n.Negated() || len(n.Args) < 2 {
+			return nil, 0, false
+		}
+		return compileBinary(n, n.Operator.T, n.Args, s)
+	case *expr.UnaryNode:
+		if n.Operator.T != lex.TokenNegate {
+			return nil, 0, false
+		}
+		arg, t, ok := compileVector(n.Arg, s)
+		if !ok || t != arrow.Bool {
+			return nil, 0, false
+		}
+		fallback := compileRowFallback(n)
+		return func(ctx *plan.Context, rec *arrow.Record) *vector {
+			a := arg(ctx, rec)
+			if a.hasNulls() {
+				// NOT of a missing value is decided by the vm rules
+				return fallback(ctx, rec)
+			}
+			c := arrow.NewColumn(arrow.Bool, rec.Len)
+			for i := 0; i < rec.Len; i++ {
+				if a.isNull(i) {
+					c.SetNull(i)
+				} else {
+					c.SetBool(i, !a.bool(i))
+				}
+			}
+			return &vector{t: arrow.Bool, col: c}
+		}, arrow.Bool, true
+	}
+	return nil, 0, false
+}
+
+func constVector(t arrow.Type, c driver.Value) (batchEval, arrow.Type, bool) {
+	v := &vector{t: t, c: c}
+	return func(ctx *plan.Context, rec *arrow.Record) *vector { return v }, t, true
+}
+
+func isNumeric(t arrow.Type) bool { return t == arrow.Int64 || t == arrow.Float64 }
+
+// compileBinary the vectorized evaluator of a binary (or n-ary logical)
+// operator node, the operand types decide if and how it can be vectorized.
+func compileBinary(node expr.Node, op lex.TokenType, args []expr.Node, s *arrow.Schema) (batchEval, arrow.Type, bool) {
+	evals := make([]batchEval, len(args))
+	types := make([]arrow.Type, len(args))
+	for i, arg := range args {
+		eval, t, ok := compileVector(arg, s)
+		if !ok {
+			return nil, 0, false
+		}
+		evals[i], types[i] = eval, t
+	}
+
+	switch op {
+	case lex.TokenLogicAnd, lex.TokenAnd, lex.TokenLogicOr, lex.TokenOr:
+		for _, t := range types {
+			if t != arrow.Bool {
+				return nil, 0, false
+			}
+		}
+		and := op == lex.TokenLogicAnd || op == lex.TokenAnd
+		return func(ctx *plan.Context, rec *arrow.Record) *vector {
+			v := evals[0](ctx, rec)
+			for _, eval := range evals[1:] {
+				v = logicVectors(and, v, eval(ctx, rec), rec.Len)
+			}
+			return v
+		}, arrow.Bool, true
+	}
+
+	if len(args) != 2 {
+		return nil, 0, false
+	}
+	l, r := evals[0], evals[1]
+	lt, rt := types[0], types[1]
+	switch op {
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE:
+		// strings and bools only compare for equality
+		if lt == rt && (lt == arrow.String || lt == arrow.Bool) {
+			return compareEval(op, l, r, lt, compileRowFallback(node)), arrow.Bool, true
+		}
+		fallthrough
+	case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE:
+		if !isNumeric(lt) || !isNumeric(rt) {
+			return nil, 0, false
+		}
+		t := arrow.Float64
+		if lt == arrow.Int64 && rt == arrow.Int64 {
+			t = arrow.Int64
+		}
+		return compareEval(op, l, r, t, compileRowFallback(node)), arrow.Bool, true
+	case lex.TokenPlus, lex.TokenMinus, lex.TokenStar, lex.TokenMultiply, lex.TokenDivide, lex.TokenModulus:
+		if !isNumeric(lt) || !isNumeric(rt) {
+			return nil, 0, false
+		}
+		t := arrow.Float64
+		if lt == arrow.Int64 && rt == arrow.Int64 {
+			t = arrow.Int64
+		}
+		return arithEval(op, l, r, t), t, true
+	}
+	return nil, 0, false
+}
+
+// nullVectors a column of n rows of type t, null where either of a or b
+// are null.
+func nullVectors(t arrow.Type, a, b *vector, n int) *arrow.Column {
+	c := arrow.NewColumn(t, n)
+	if a.hasNulls() || b.hasNulls() {
+		for i := 0; i < n; i++ {
+			if a.isNull(i) || b.isNull(i) {
+				c.SetNull(i)
+			}
+		}
+	}
+	return c
+}
+
+// logicVectors a AND b or a OR b with sql (three valued) null logic
+func logicVectors(and bool, a, b *vector, n int) *vector {
+	c := arrow.NewColumn(arrow.Bool, n)
+	for i := 0; i < n; i++ {
+		an, bn := a.isNull(i), b.isNull(i)
+		av, bv := !an && a.bool(i), !bn && b.bool(i)
+		switch {
+		case and && ((!an && !av) || (!bn && !bv)):
+			// false
+		case !and && (av || bv):
+			c.SetBool(i, true)
+		case an || bn:
+			c.SetNull(i)
+		default:
+			c.SetBool(i, and)
+		}
+	}
+	return &vector{t: arrow.Bool, col: c}
+}
+
+// compareEval comparison of operands without nulls, with nulls the vm
+// rules (a missing value is not equal to anything) are not sql null
+// logic so the rows are evaluated by fallback.
+func compareEval(op lex.TokenType, l, r batchEval, t arrow.Type, fallback batchEval) batchEval {
+	return func(ctx *plan.Context, rec *arrow.Record) *vector {
+		a, b := l(ctx, rec), r(ctx, rec)
+		if a.hasNulls() || b.hasNulls() {
+			return fallback(ctx, rec)
+		}
+		c := arrow.NewColumn(arrow.Bool, rec.Len)
+		for i := 0; i < rec.Len; i++ {
+			var cmp int
+			switch t {
+			case arrow.Int64:
+				x, y := a.int64(i), b.int64(i)
+				cmp = compareOrdered(x < y, x > y)
+			case arrow.Float64:
+				x, y := a.float64(i), b.float64(i)
+				cmp = compareOrdered(x < y, x > y)
+			case arrow.Bool:
+				cmp = compareOrdered(false, a.bool(i) != b.bool(i))
+			default:
+				x, y := a.str(i), b.str(i)
+				cmp = compareOrdered(x < y, x > y)
+			}
+			c.SetBool(i, compareResult(op, cmp))
+		}
+		return &vector{t: arrow.Bool, col: c}
+	}
+}
+
+func compareOrdered(lt, gt bool) int {
+	switch {
+	case lt:
+		return -1
+	case gt:
+		return 1
+	}
+	return 0
+}
+
+func compareResult(op lex.TokenType, cmp int) bool {
+	switch op {
+	case lex.TokenEqual, lex.TokenEqualEqual:
+		return cmp == 0
+	case lex.TokenNE:
+		return cmp != 0
+	case lex.TokenGT:
+		return cmp > 0
+	case lex.TokenGE:
+		return cmp >= 0
+	case lex.TokenLT:
+		return cmp < 0
+	case lex.TokenLE:
+		return cmp <= 0
+	}
+	return false
+}
+
+// arithEval numeric operators, integer operands have integer results as
+// in the vm.  Integer division (or modulus) by zero is null.
+func arithEval(op lex.TokenType, l, r batchEval, t arrow.Type) batchEval {
+	return func(ctx *plan.Context, rec *arrow.Record) *vector {
+		a, b := l(ctx, rec), r(ctx, rec)
+		c := nullVectors(t, a, b, rec.Len)
+		for i := 0; i < rec.Len; i++ {
+			if c.Nulls > 0 && c.IsNull(i) {
+				continue
+			}
+			if t == arrow.Int64 {
+				x, y := a.int64(i), b.int64(i)
+				switch op {
+				case lex.TokenPlus:
+					c.Int64s[i] = x + y
+				case lex.TokenMinus:
+					c.Int64s[i] = x - y
+				case lex.TokenStar, lex.TokenMultiply:
+					c.Int64s[i] = x * y
+				case lex.TokenDivide, lex.TokenModulus:
+					if y == 0 {
+						c.SetNull(i)
+					} else if op == lex.TokenDivide {
+						c.Int64s[i] = x / y
+					} else {
+						c.Int64s[i] = x % y
+					}
+				}
+				continue
+			}
+			x, y := a.float64(i), b.float64(i)
+			switch op {
+			case lex.TokenPlus:
+				c.Float64s[i] = x + y
+			case lex.TokenMinus:
+				c.Float64s[i] = x - y
+			case lex.TokenStar, lex.TokenMultiply:
+				c.Float64s[i] = x * y
+			case lex.TokenDivide:
+				c.Float64s[i] = x / y
+			case lex.TokenModulus:
+				if int64(y) == 0 {
+					c.SetNull(i)
+				} else {
+					c.Float64s[i] = float64(int64(x) % int64(y))
+				}
+			}
+		}
+		return &vector{t: t, col: c}
+	}
+}
+
+// compileRowFallback evaluate a node with the vm a row at a time, the
+// column type is that of the first non-null value.
+func compileRowFallback(node expr.Node) batchEval {
+	eval := compileExpr(node)
+	return func(ctx *plan.Context, rec *arrow.Record) *vector {
+		row := &batchRow{rec: rec}
+		var rdr expr.ContextReader = row
+		if ctx.Session != nil {
+			rdr = datasource.NewNestedContextReader([]expr.ContextReader{row, ctx.Session}, time.Time{})
+		}
+		evalCtx := evalContext(ctx, rdr)
+		vals := make([]value.Value, rec.Len)
+		t, typed := arrow.String, false
+		for i := range vals {
+			row.i = i
+			v, ok := eval(evalCtx)
+			if !ok || v == nil || v.Nil() {
+				continue
+			}
+			if _, isErr := v.(value.ErrorValue); isErr {
+				u.Debugf("could not evaluate %s: %v", node, v)
+				continue
+			}
+			vals[i] = v
+			if !typed {
+				t, typed = arrow.FromValueType(v.Type()), true
+			}
+		}
+		b := arrow.NewBuilder(t)
+		for _, v := range vals {
+			if v == nil {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+		return &vector{t: t, col: b.Finish()}
+	}
+}
+
+// batchRow a row of a record batch as a context reader
+type batchRow struct {
+	rec *arrow.Record
+	i   int
+}
+
+func (m *batchRow) Get(key string) (value.Value, bool) {
+	idx := batchFieldIndex(m.rec.Schema, key)
+	if idx < 0 {
+		return nil, false
+	}
+	v := m.rec.Columns[idx].Value(m.i)
+	if v == nil {
+		return value.NewNilValue(), true
+	}
+	return value.NewValue(v), true
+}
+
+func (m *batchRow) Row() map[string]value.Value {
+	row := make(map[string]value.Value, len(m.rec.Columns))
+	for i, f := range m.rec.Schema.Fields {
+		if v := m.rec.Columns[i].Value(m.i); v != nil {
+			row[f.Name] = value.NewValue(v)
+		}
+	}
+	return row
+}
+
+func (m *batchRow) Ts() time.Time { return time.Time{} }
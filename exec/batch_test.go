@@ -0,0 +1,171 @@
+package exec_test
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/arrow"
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/datasource/memdb"
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/schema"
+)
+
+const batchRows = 2500
+
+var loadBatchOnce sync.Once
+
+// loadBatchData a memdb table of batchRows rows, more than a couple of
+// record batches.
+func loadBatchData(t *testing.T) {
+	loadBatchOnce.Do(func() {
+		cities := []string{"sf", "nyc", "la"}
+		rows := make([][]driver.Value, batchRows)
+		for i := range rows {
+			var score driver.Value = float64(i%7) / 2
+			if i%11 == 0 {
+				score = nil
+			}
+			var tag driver.Value = []string{"a", "b"}[i%2]
+			if i%13 == 0 {
+				tag = nil
+			}
+			rows[i] = []driver.Value{int64(i), fmt.Sprintf("user%d", i), int64(18 + i%50), score, cities[i%3], tag}
+		}
+		db, err := memdb.NewMemDbData("batchusers", rows, []string{"id", "name", "age", "score", "city", "tag"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, schema.RegisterSourceAsSchema("batchtest", db))
+	})
+}
+
+func batchContext(t *testing.T, sql string) *plan.Context {
+	ctx := plan.NewContext(sql)
+	s, ok := schema.DefaultRegistry().Schema("batchtest")
+	assert.True(t, ok)
+	ctx.Schema = s
+	ctx.Session = datasource.NewMySqlSessionVars()
+	return ctx
+}
+
+// runBatchWriter run a job writing its results as record batches
+func runBatchWriter(t *testing.T, ctx *plan.Context, job *exec.JobExecutor) *exec.ResultBatchWriter {
+	w := exec.NewResultBatchWriter(ctx)
+	job.RootTask.Add(w)
+	assert.Equal(t, nil, job.Setup())
+	assert.Equal(t, nil, job.Run())
+	job.Close()
+	return w
+}
+
+// recordRows the rows of record batches as strings, sorted
+func recordRows(recs []*arrow.Record) []string {
+	rows := make([]string, 0)
+	for _, rec := range recs {
+		for i := 0; i < rec.Len; i++ {
+			rows = append(rows, fmt.Sprintf("%v", rec.Row(i)))
+		}
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func TestBatchSelect(t *testing.T) {
+	loadBatchData(t)
+
+	tests := []struct {
+		sql  string
+		rows int
+	}{
+		{`SELECT id, name, age * 2 AS dbl FROM batchusers WHERE age > 40 AND city = "sf"`, 450},
+		{`SELECT * FROM batchusers WHERE score >= 2.5 OR id < 3`, 652},
+		{`SELECT id, score / 2 AS half, age % 7 AS m FROM batchusers WHERE NOT (age <= 60)`, 350},
+		{`SELECT id, tolower(city) AS c FROM batchusers WHERE contains(name, "99")`, 43},
+		{`SELECT city, count(*) AS ct, sum(age) AS total, avg(age) AS av FROM batchusers GROUP BY city`, 3},
+		{`SELECT count(score) AS ct FROM batchusers WHERE city != "la"`, 1},
+		// nullable columns, a missing value is not equal to anything
+		{`SELECT id FROM batchusers WHERE score != 1`, 2175},
+		{`SELECT id FROM batchusers WHERE tag != "a"`, 1347},
+		{`SELECT id FROM batchusers WHERE NOT (score > 2)`, 1623},
+		{`SELECT id FROM batchusers WHERE NOT (tag = "a") AND age > 30`, 1019},
+		{`SELECT id FROM batchusers WHERE score / 0 != 1 OR tag != "b"`, 2500},
+	}
+	for _, tt := range tests {
+		ctx := batchContext(t, tt.sql)
+		job, err := exec.BuildSqlBatchJob(ctx)
+		assert.Equal(t, nil, err, tt.sql)
+		_, isBatch := job.RootTask.Children()[0].(*exec.BatchSource)
+		assert.True(t, isBatch, "should run as batches: %s", tt.sql)
+		w := runBatchWriter(t, ctx, job)
+		got := recordRows(w.Records())
+		assert.Equal(t, tt.rows, len(got), tt.sql)
+
+		// same rows as the row at a time tasks
+		ctx = batchContext(t, tt.sql)
+		job, err = exec.BuildSqlJob(ctx)
+		assert.Equal(t, nil, err, tt.sql)
+		expected := recordRows(runBatchWriter(t, ctx, job).Records())
+		assert.Equal(t, expected, got, tt.sql)
+	}
+
+	ctx := batchContext(t, `SELECT city, count(*) AS ct, sum(age) AS total FROM batchusers WHERE id < 9 GROUP BY city`)
+	job, err := exec.BuildSqlBatchJob(ctx)
+	assert.Equal(t, nil, err)
+	w := runBatchWriter(t, ctx, job)
+	assert.Equal(t, []string{"[la 3 69]", "[nyc 3 66]", "[sf 3 63]"}, recordRows(w.Records()))
+	assert.Equal(t, []arrow.Type{arrow.String, arrow.Int64, arrow.Float64},
+		[]arrow.Type{w.Schema().Fields[0].Type, w.Schema().Fields[1].Type, w.Schema().Fields[2].Type})
+
+	// limit ends the scan
+	ctx = batchContext(t, `SELECT id FROM batchusers WHERE age > 20 LIMIT 1500`)
+	job, err = exec.BuildSqlBatchJob(ctx)
+	assert.Equal(t, nil, err)
+	w = runBatchWriter(t, ctx, job)
+	assert.Equal(t, 1500, len(recordRows(w.Records())))
+}
+
+func TestBatchFallback(t *testing.T) {
+	loadBatchData(t)
+
+	// order by isn't run as batches, the rows are written as batches
+	ctx := batchContext(t, `SELECT id, name FROM batchusers WHERE id < 5 ORDER BY id ASC`)
+	job, err := exec.BuildSqlBatchJob(ctx)
+	assert.Equal(t, nil, err)
+	_, isBatch := job.RootTask.Children()[0].(*exec.BatchSource)
+	assert.False(t, isBatch)
+	w := runBatchWriter(t, ctx, job)
+	recs := w.Records()
+	assert.Equal(t, 1, len(recs))
+	assert.Equal(t, []driver.Value{int64(0), "user0"}, recs[0].Row(0))
+	assert.Equal(t, []driver.Value{int64(4), "user4"}, recs[0].Row(4))
+}
+
+func TestResultBatchWriter(t *testing.T) {
+	ctx := td.TestContext(`SELECT user_id, email FROM users WHERE user_id = "9Ip1aKbeZe2njCDM"`)
+	job, err := exec.BuildSqlBatchJob(ctx)
+	assert.Equal(t, nil, err)
+	w := exec.NewResultBatchWriter(ctx)
+	job.RootTask.Add(w)
+	assert.Equal(t, nil, job.Setup())
+	assert.Equal(t, nil, job.Run())
+	job.Close()
+
+	var buf bytes.Buffer
+	assert.Equal(t, nil, w.WriteStream(&buf))
+	rdr, err := arrow.NewReader(&buf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"user_id", "email"}, rdr.Schema().Names())
+	rec, err := rdr.Next()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []driver.Value{"9Ip1aKbeZe2njCDM", "aaron@email.com"}, rec.Row(0))
+	_, err = rdr.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/araddon/qlbridge/lex"
 	"github.com/araddon/qlbridge/plan"
 	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/vm"
 )
 
 var (
@@ -90,6 +91,22 @@ func (m *Create) Run() error {
 		reg := schema.DefaultRegistry()
 
 		return reg.SchemaAddFromConfig(sourceConf)
+	case lex.TokenFunction:
+
+		// CREATE [OR REPLACE] FUNCTION discount(price number) RETURNS number AS 'price * 0.9'
+		s := m.Ctx.Schema
+		if s == nil {
+			return fmt.Errorf("must have schema")
+		}
+		fn, err := vm.NewSqlFunc(cs)
+		if err != nil {
+			return err
+		}
+		if _, exists := s.Funcs().FuncGet(fn.Name); exists && !cs.OrReplace {
+			return fmt.Errorf("function %q already exists", fn.Name)
+		}
+		s.Funcs().Add(fn.Name, fn)
+		return nil
 	default:
 		u.Warnf("unrecognized create/alter: kw=%v   stmt:%s", cs.Tok, m.p.Stmt)
 	}
@@ -37,6 +37,11 @@ type (
 	MessageChan chan schema.Message
 	// MessageHandler Handle/Forward a message for this Task
 	MessageHandler func(ctx *plan.Context, msg schema.Message) bool
+	// MessageBatch a slice of messages sent as one message between tasks, see
+	// plan.Context.MessageBatchSize
+	MessageBatch []schema.Message
+	// MessageBatchHandler Handle/Forward a batch of messages for this Task
+	MessageBatchHandler func(ctx *plan.Context, msgs []schema.Message) bool
 )
 
 type (
@@ -74,6 +79,11 @@ type (
 		SigChan() SigChan
 		Quit()
 	}
+	// BatchReceiver is a task that accepts a MessageBatch on its input
+	// channel, the task sending to it may batch its messages.
+	BatchReceiver interface {
+		ReceivesBatches() bool
+	}
 	// TaskPrinter a debug printer for dag-shape.
 	TaskPrinter interface {
 		PrintDag(depth int)
@@ -106,6 +116,7 @@ type (
 		WalkGroupBy(p *plan.GroupBy) (Task, error)
 		WalkOrder(p *plan.Order) (Task, error)
 		WalkProjection(p *plan.Projection) (Task, error)
+		WalkInto(p *plan.Into) (Task, error)
 		// Other Statements
 		WalkCommand(p *plan.Command) (Task, error)
 		WalkPreparedStatement(p *plan.PreparedStatement) (Task, error)
@@ -0,0 +1,86 @@
+package exec_test
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/datasource/memdb"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/schema"
+)
+
+/*
+
+go test -bench="ExecScan" -run="XXX"
+
+Benchmark a 1M row memdb scan, with and without message batches
+between the tasks.
+
+BenchmarkExecScan        	       3	3145221560 ns/op
+BenchmarkExecScanBatch   	       3	1916482052 ns/op
+
+*/
+
+const benchScanRows = 1000000
+
+var loadBenchOnce sync.Once
+
+func loadBenchData(b *testing.B) {
+	loadBenchOnce.Do(func() {
+		rows := make([][]driver.Value, benchScanRows)
+		for i := range rows {
+			rows[i] = []driver.Value{int64(i), fmt.Sprintf("user%d", i), int64(18 + i%50)}
+		}
+		db, err := memdb.NewMemDbData("benchscan", rows, []string{"id", "name", "age"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = schema.RegisterSourceAsSchema("benchscan", db); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func benchScan(b *testing.B, batchSize int) {
+	loadBenchData(b)
+	s, ok := schema.DefaultRegistry().Schema("benchscan")
+	if !ok {
+		b.Fatal("no benchscan schema")
+	}
+	msgs := make([]schema.Message, 0, benchScanRows)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx := plan.NewContext(`SELECT id, name FROM benchscan WHERE age > 20`)
+		ctx.Schema = s
+		ctx.Session = datasource.NewMySqlSessionVars()
+		ctx.MessageBatchSize = batchSize
+		job, err := exec.BuildSqlJob(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		msgs = msgs[:0]
+		job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+		if err = job.Setup(); err != nil {
+			b.Fatal(err)
+		}
+		if err = job.Run(); err != nil {
+			b.Fatal(err)
+		}
+		job.Close()
+		if len(msgs) != benchScanRows/50*47 {
+			b.Fatalf("expected %d rows got %d", benchScanRows/50*47, len(msgs))
+		}
+	}
+}
+
+func BenchmarkExecScan(b *testing.B) {
+	benchScan(b, 0)
+}
+
+func BenchmarkExecScanBatch(b *testing.B) {
+	benchScan(b, exec.ItemDefaultBatchSize)
+}
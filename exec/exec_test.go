@@ -14,8 +14,12 @@ import (
 	"github.com/araddon/qlbridge/datasource/mockcsv"
 	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
 	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
 	"github.com/araddon/qlbridge/schema"
 	"github.com/araddon/qlbridge/testutil"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
 )
 
 func TestMain(m *testing.M) {
@@ -137,6 +141,143 @@ func TestExecSelectWhere(t *testing.T) {
 	assert.True(t, row[4] == true)
 }
 
+func TestExecNullLogic(t *testing.T) {
+	runWhere := func(nullLogic bool, sqlText string) int {
+		ctx := td.TestContext(sqlText)
+		ctx.NullLogic = nullLogic
+		job, err := exec.BuildSqlJob(ctx)
+		assert.Equal(t, nil, err)
+
+		msgs := make([]schema.Message, 0)
+		job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+		assert.Equal(t, nil, job.Setup())
+		assert.Equal(t, nil, job.Run())
+		time.Sleep(time.Millisecond * 10)
+		return len(msgs)
+	}
+	// missing fields are not-equal by default, NULL with null logic
+	assert.Equal(t, 3, runWhere(false, `SELECT user_id FROM users WHERE not_a_field != "x"`))
+	assert.Equal(t, 0, runWhere(true, `SELECT user_id FROM users WHERE not_a_field != "x"`))
+	assert.Equal(t, 3, runWhere(true, `SELECT user_id FROM users WHERE not_a_field IS NULL`))
+	assert.Equal(t, 0, runWhere(true, `SELECT user_id FROM users WHERE not_a_field = NULL`))
+	assert.Equal(t, 1, runWhere(true, `SELECT user_id FROM users WHERE not_a_field = "x" OR user_id = "9Ip1aKbeZe2njCDM"`))
+}
+
+type panicFunc struct{}
+
+func (m *panicFunc) Type() value.ValueType { return value.UnknownType }
+func (m *panicFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		panic("panicfunc")
+	}, nil
+}
+
+// rowReaderFunc is true if evaluated against a vm.RowReader, which
+// compiled expressions read columns from by position
+type rowReaderFunc struct{}
+
+func (m *rowReaderFunc) Type() value.ValueType { return value.BoolType }
+func (m *rowReaderFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		_, isRow := ctx.(vm.RowReader)
+		return value.NewBoolValue(isRow), true
+	}, nil
+}
+
+func TestExecProjectionCompiled(t *testing.T) {
+	expr.FuncAdd("isrowreader", &rowReaderFunc{})
+
+	for _, hasSession := range []bool{true, false} {
+		ctx := td.TestContext(`SELECT user_id, isrowreader() FROM users WHERE user_id = "9Ip1aKbeZe2njCDM"`)
+		if !hasSession {
+			ctx.Session = nil
+		}
+		job, err := exec.BuildSqlJob(ctx)
+		assert.Equal(t, nil, err)
+
+		msgs := make([]schema.Message, 0)
+		job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+		assert.Equal(t, nil, job.Setup())
+		assert.Equal(t, nil, job.Run())
+		job.Close()
+		assert.Equal(t, 1, len(msgs))
+		if len(msgs) == 1 {
+			row := msgs[0].(*datasource.SqlDriverMessageMap).Values()
+			assert.Equal(t, "9Ip1aKbeZe2njCDM", row[0])
+			assert.Equal(t, true, row[1], "session=%v", hasSession)
+		}
+	}
+}
+
+func TestExecRecoverPanic(t *testing.T) {
+	expr.FuncAdd("panicfunc", &panicFunc{})
+
+	ctx := td.TestContext(`SELECT user_id, panicfunc() FROM users`)
+	ctx.DisableRecover = false
+	job, err := exec.BuildSqlJob(ctx)
+	assert.Equal(t, nil, err)
+
+	msgs := make([]schema.Message, 0)
+	job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+	assert.Equal(t, nil, job.Setup())
+	err = job.Run()
+	assert.NotEqual(t, nil, err)
+	assert.Contains(t, err.Error(), "panicfunc")
+	job.Close()
+}
+
+func TestExecMessageBatch(t *testing.T) {
+	loadBatchData(t)
+
+	run := func(batchSize int, sqlText string) []schema.Message {
+		ctx := batchContext(t, sqlText)
+		ctx.MessageBatchSize = batchSize
+		job, err := exec.BuildSqlJob(ctx)
+		assert.Equal(t, nil, err)
+
+		msgs := make([]schema.Message, 0)
+		job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+		assert.Equal(t, nil, job.Setup())
+		assert.Equal(t, nil, job.Run())
+		job.Close()
+		return msgs
+	}
+	for _, sqlText := range []string{
+		`SELECT id, name FROM batchusers WHERE age > 40 AND city = "sf"`,
+		`SELECT id, age * 2 AS dbl FROM batchusers`,
+		`SELECT id FROM batchusers WHERE age > 20 LIMIT 1000`,
+		`SELECT count(*) AS ct, sum(age) AS total FROM batchusers WHERE city = "sf"`,
+		`SELECT id FROM batchusers WHERE id < 300 ORDER BY id DESC`,
+	} {
+		expected := run(0, sqlText)
+		msgs := run(100, sqlText)
+		assert.Equal(t, len(expected), len(msgs), sqlText)
+		for i, msg := range msgs {
+			_, isBatch := msg.(exec.MessageBatch)
+			assert.False(t, isBatch, sqlText)
+			if i < len(expected) && msg != nil && expected[i] != nil {
+				assert.Equal(t, expected[i].Body(), msg.Body(), sqlText)
+			}
+		}
+	}
+
+	// handlers see each message of a batch
+	ct := 0
+	h := exec.UnbatchHandler(func(ctx *plan.Context, msg schema.Message) bool {
+		ct++
+		return msg.Id() != 2
+	})
+	batch := exec.MessageBatch{
+		datasource.NewSqlDriverMessageMap(1, nil, nil),
+		datasource.NewSqlDriverMessageMap(2, nil, nil),
+		datasource.NewSqlDriverMessageMap(3, nil, nil),
+	}
+	assert.False(t, h(nil, batch))
+	assert.Equal(t, 3, ct)
+	assert.Equal(t, uint64(1), batch.Id())
+	assert.True(t, h(nil, batch[0]))
+}
+
 func TestExecGroupBy(t *testing.T) {
 
 	sqlText := `
@@ -493,6 +634,50 @@ func TestExecDelete(t *testing.T) {
 	assert.True(t, delCt == 3, "should have deleted 3 but was %v", delCt)
 }
 
+func TestExecCreateFunction(t *testing.T) {
+	run := func(sqlText string) ([]schema.Message, error) {
+		ctx := td.TestContext(sqlText)
+		job, err := exec.BuildSqlJob(ctx)
+		if err != nil {
+			return nil, err
+		}
+		msgs := make([]schema.Message, 0)
+		job.RootTask.Add(exec.NewResultBuffer(ctx, &msgs))
+		if err = job.Setup(); err != nil {
+			return nil, err
+		}
+		err = job.Run()
+		time.Sleep(time.Millisecond * 10)
+		return msgs, err
+	}
+
+	_, err := run(`CREATE FUNCTION double_refs(n int) RETURNS int AS 'n * 2'`)
+	assert.Equal(t, nil, err)
+
+	msgs, err := run(`SELECT user_id, double_refs(referral_count) AS refs
+		FROM users WHERE double_refs(referral_count) > 100`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(msgs))
+	row := msgs[0].(*datasource.SqlDriverMessageMap).Values()
+	assert.Equal(t, "9Ip1aKbeZe2njCDM", row[0])
+	assert.Equal(t, int64(164), row[1])
+
+	// already exists
+	_, err = run(`CREATE FUNCTION double_refs(n int) RETURNS int AS 'n * 3'`)
+	assert.NotEqual(t, nil, err)
+
+	_, err = run(`CREATE OR REPLACE FUNCTION double_refs(n int) RETURNS int AS 'n * 3'`)
+	assert.Equal(t, nil, err)
+	msgs, err = run(`SELECT double_refs(referral_count) FROM users WHERE user_id = "9Ip1aKbeZe2njCDM"`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(msgs))
+	assert.Equal(t, int64(246), msgs[0].(*datasource.SqlDriverMessageMap).Values()[0])
+
+	// wrong number of args
+	_, err = run(`SELECT double_refs(referral_count, 2) FROM users`)
+	assert.NotEqual(t, nil, err)
+}
+
 // sub-select not implemented in exec yet
 func testSubselect(t *testing.T) {
 	sqlText := `
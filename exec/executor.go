@@ -6,6 +6,7 @@ import (
 	u "github.com/araddon/gou"
 
 	"github.com/araddon/qlbridge/datasource/membtree"
+	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/plan"
 	"github.com/araddon/qlbridge/rel"
 )
@@ -65,7 +66,12 @@ func BuildSqlJobPlanned(planner plan.Planner, executor Executor, ctx *plan.Conte
 	if ctx.Raw == "" {
 		return nil, fmt.Errorf("no sql provided")
 	}
-	stmt, err := rel.ParseSql(ctx.Raw)
+	fr := ctx.Funcs
+	if fr == nil && ctx.Schema != nil {
+		// functions from CREATE FUNCTION
+		fr = expr.LenientFuncs(ctx.Schema.Funcs())
+	}
+	stmt, err := rel.ParseSqlResolver(ctx.Raw, fr)
 	if err != nil {
 		u.Debugf("could not parse sql : %v", err)
 		return nil, err
@@ -233,6 +239,9 @@ func (m *JobExecutor) WalkOrder(p *plan.Order) (Task, error) {
 func (m *JobExecutor) WalkProjection(p *plan.Projection) (Task, error) {
 	return NewProjection(m.Ctx, p), nil
 }
+func (m *JobExecutor) WalkInto(p *plan.Into) (Task, error) {
+	return NewInto(m.Ctx, p), nil
+}
 func (m *JobExecutor) WalkJoin(p *plan.JoinMerge) (Task, error) {
 	execTask := NewTaskParallel(m.Ctx)
 	//u.Debugf("join.Left: %#v    \nright:%#v", p.Left, p.Right)
@@ -302,6 +311,8 @@ func (m *JobExecutor) WalkPlanTask(p plan.Task) (Task, error) {
 		return m.Executor.WalkJoin(p)
 	case *plan.JoinKey:
 		return m.Executor.WalkJoinKey(p)
+	case *plan.Into:
+		return m.Executor.WalkInto(p)
 	}
 	panic(fmt.Sprintf("Task plan-exec Not implemented for %T", p))
 }
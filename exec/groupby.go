@@ -351,6 +351,11 @@ type sum struct {
 	partial bool
 	ct      int64
 	n       float64
+	// exact sum of decimals (and ints), the result if there are decimals
+	// but no floats.
+	dec    value.DecimalValue
+	hasDec bool
+	hasNum bool
 }
 
 func (m *sum) Do(v value.Value) {
@@ -358,12 +363,28 @@ func (m *sum) Do(v value.Value) {
 	switch vt := v.(type) {
 	case value.IntValue:
 		m.n += vt.Float()
+		m.addDecimal(value.NewDecimalValue(vt.Val(), 0))
 	case value.NumberValue:
 		m.n += vt.Val()
+		m.hasNum = true
+	case value.DecimalValue:
+		m.n += vt.Float()
+		m.addDecimal(vt)
+		m.hasDec = true
+	}
+}
+func (m *sum) addDecimal(d value.DecimalValue) {
+	if m.dec.Nil() {
+		m.dec = d
+		return
 	}
+	m.dec = m.dec.Add(d)
 }
 func (m *sum) Result() interface{} {
 	if !m.partial {
+		if m.hasDec && !m.hasNum {
+			return m.dec.Value()
+		}
 		return m.n
 	}
 	return &AggPartial{
@@ -371,10 +392,15 @@ func (m *sum) Result() interface{} {
 		m.n,
 	}
 }
-func (m *sum) Reset() { m.n = 0 }
+func (m *sum) Reset() {
+	m.n = 0
+	m.dec = value.NewDecimalNil()
+	m.hasDec, m.hasNum = false, false
+}
 func (m *sum) Merge(a *AggPartial) {
 	m.ct += a.Ct
 	m.n += a.N
+	m.hasNum = true
 }
 func NewSum(col *rel.Column, partial bool) Aggregator {
 	return &sum{partial: partial}
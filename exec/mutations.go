@@ -17,6 +17,7 @@ var (
 	_ = u.EMPTY
 
 	_ TaskRunner = (*Upsert)(nil)
+	_ TaskRunner = (*Into)(nil)
 	_ TaskRunner = (*DeletionTask)(nil)
 	_ TaskRunner = (*DeletionScanner)(nil)
 )
@@ -32,6 +33,13 @@ type (
 		db      schema.ConnUpsert
 		dbpatch schema.ConnPatchWhere
 	}
+	// Into task for SELECT INTO, writes the select results to source
+	Into struct {
+		*TaskBase
+		closed bool
+		p      *plan.Into
+		db     schema.ConnUpsert
+	}
 	// Delete task for sources that natively support delete
 	DeletionTask struct {
 		*TaskBase
@@ -73,6 +81,16 @@ func NewUpsert(ctx *plan.Context, p *plan.Upsert) *Upsert {
 	return m
 }
 
+// NewInto create a task to write messages from select into data source.
+func NewInto(ctx *plan.Context, p *plan.Into) *Into {
+	m := &Into{
+		TaskBase: NewTaskBase(ctx),
+		db:       p.Source,
+		p:        p,
+	}
+	return m
+}
+
 // An inserter to write to data source
 func NewDelete(ctx *plan.Context, p *plan.Delete) *DeletionTask {
 	m := &DeletionTask{
@@ -113,6 +131,9 @@ func (m *Upsert) Run() error {
 	default:
 		u.Warnf("unknown mutation op?  %v", m)
 	}
+	if err == nil {
+		err = flushMutator(m.db)
+	}
 
 	vals := make([]driver.Value, 2)
 	if err != nil {
@@ -213,6 +234,74 @@ func (m *Upsert) insertRows(rows [][]*rel.ValueColumn) (int64, error) {
 	return int64(len(rows)), nil
 }
 
+// flushMutator flushes any buffered writes for mutators that buffer.
+func flushMutator(db interface{}) error {
+	if flusher, ok := db.(schema.ConnFlusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (m *Into) Close() error {
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.Unlock()
+	return m.TaskBase.Close()
+}
+
+// Run the Into task, reading all messages and writing them to
+// the underlying source, then output a single affected-count message.
+func (m *Into) Run() error {
+	defer m.Ctx.Recover()
+	defer close(m.msgOutCh)
+
+	var err error
+	var affectedCt int64
+	inCh := m.MessageIn()
+
+msgReadLoop:
+	for {
+		select {
+		case <-m.SigChan():
+			return nil
+		case msg, ok := <-inCh:
+			if !ok {
+				break msgReadLoop
+			}
+			var vals []driver.Value
+			switch mt := msg.(type) {
+			case *datasource.SqlDriverMessageMap:
+				vals = mt.Values()
+			case *datasource.SqlDriverMessage:
+				vals = mt.Vals
+			default:
+				err = fmt.Errorf("Into requires SqlDriverMessageMap but got %T", msg)
+				break msgReadLoop
+			}
+			if _, err = m.db.Put(m.Ctx.Context, nil, vals); err != nil {
+				u.Errorf("Could not put values: fordb T:%T  %v", m.db, err)
+				break msgReadLoop
+			}
+			affectedCt++
+		}
+	}
+	if err == nil {
+		err = flushMutator(m.db)
+	}
+	if err != nil {
+		u.Warnf("errored, should not complete %v", err)
+		return err
+	}
+
+	vals := []driver.Value{int64(0), affectedCt}
+	m.msgOutCh <- &datasource.SqlDriverMessage{Vals: vals, IdVal: 1}
+	return nil
+}
+
 func (m *DeletionTask) Close() error {
 	m.Lock()
 	if m.closed {
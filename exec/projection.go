@@ -14,6 +14,12 @@ import (
 	"github.com/araddon/qlbridge/vm"
 )
 
+var (
+	// compiled column expressions read the row being projected by position
+	_ vm.RowReader = (*datasource.SqlDriverMessageMap)(nil)
+	_ vm.RowReader = (*datasource.NestedContextReader)(nil)
+)
+
 // Projection Execution Task
 type Projection struct {
 	*TaskBase
@@ -109,10 +115,12 @@ func (m *Projection) CloseFinal() error {
 	return m.TaskBase.Close()
 }
 
+// ReceivesBatches projection runs its handler per message of a MessageBatch
+func (m *Projection) ReceivesBatches() bool { return true }
+
 // Create handler function for evaluation (ie, field selection from tuples)
 func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 
-	out := m.MessageOut()
 	columns := m.p.Stmt.Columns
 	colIndex := m.p.Stmt.ColIndexes()
 	limit := m.p.Stmt.Limit
@@ -125,6 +133,19 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 		colCt = len(m.p.Proj.Columns)
 	}
 
+	// compile column expressions once, not per message
+	type colEval func(ctx expr.EvalContext) (value.Value, bool)
+	exprs := make([]colEval, len(columns))
+	guards := make([]colEval, len(columns))
+	for i, col := range columns {
+		if col.Expr != nil && !col.Star {
+			exprs[i] = compileExpr(col.Expr)
+		}
+		if col.Guard != nil {
+			guards[i] = compileExpr(col.Guard)
+		}
+	}
+
 	rowCt := 0
 	return func(ctx *plan.Context, msg schema.Message) bool {
 
@@ -141,13 +162,17 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 		case *datasource.SqlDriverMessageMap:
 			// use our custom write context for example purposes
 			row := make([]driver.Value, colCt)
-			rdr := datasource.NewNestedContextReader([]expr.ContextReader{
-				mt,
-				ctx.Session,
-			}, mt.Ts())
+			var rdr expr.ContextReader = mt
+			if ctx.Session != nil {
+				rdr = datasource.NewNestedContextReader([]expr.ContextReader{
+					mt,
+					ctx.Session,
+				}, mt.Ts())
+			}
+			evalCtx := evalContext(ctx, rdr)
 			//u.Debugf("about to project: %#v", mt)
 			colIdx := -1
-			for _, col := range columns {
+			for i, col := range columns {
 				colIdx += 1
 				//u.Debugf("%d  colidx:%v sidx: %v pidx:%v key:%q Expr:%v", colIdx, col.Index, col.SourceIndex, col.ParentIndex, col.Key(), col.Expr)
 
@@ -156,7 +181,7 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 				}
 
 				if col.Guard != nil {
-					ifColValue, ok := vm.Eval(rdr, col.Guard)
+					ifColValue, ok := guards[i](evalCtx)
 					if !ok {
 						// Most likely scenario here is Missing Columns.
 						// Unlikely traditional sql, we are going to operate in both strict-schema mode
@@ -203,7 +228,7 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 				} else if col.Expr == nil {
 					u.Warnf("wat?   nil col expr? %#v", col)
 				} else {
-					v, ok := vm.Eval(rdr, col.Expr)
+					v, ok := exprs[i](evalCtx)
 					if !ok {
 						u.Warnf("failed eval key=%q  val=%#v expr:%q  expr:%#v mt:%#v", col.Key(), v, col.Expr, col.Expr, mt)
 						// for k, v := range ctx.Session.Row() {
@@ -230,6 +255,7 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 		case expr.ContextReader:
 			//u.Warnf("nice, got context reader? %T", mt)
 			row := make([]driver.Value, len(columns))
+			evalCtx := evalContext(ctx, mt)
 			//u.Debugf("about to project: %#v", mt)
 			colIdx := 0
 			for i, col := range columns {
@@ -240,7 +266,7 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 				}
 
 				if col.Guard != nil {
-					ifColValue, ok := vm.Eval(mt, col.Guard)
+					ifColValue, ok := guards[i](evalCtx)
 					if !ok {
 						u.Errorf("Could not evaluate if:   %v", col.Guard.String())
 						//return fmt.Errorf("Could not evaluate if clause: %v", col.Guard.String())
@@ -269,7 +295,7 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 				} else if col.Expr == nil {
 					u.Warnf("wat?   nil col expr? %#v", col)
 				} else {
-					v, ok := vm.Eval(mt, col.Expr)
+					v, ok := exprs[i](evalCtx)
 					if !ok {
 						//u.Warnf("failed eval key=%v  val=%#v expr:%s   mt:%#v", col.Key(), v, col.Expr, mt.Row())
 					} else if v == nil {
@@ -295,26 +321,20 @@ func (m *Projection) projectionEvaluator(isFinal bool) MessageHandler {
 
 		if rowCt >= limit {
 			//u.Debugf("%p Projection reaching Limit!!! rowct:%v  limit:%v", m, rowCt, limit)
-			out <- nil // Sending nil message is a message to downstream to shutdown
-			m.Quit()   // should close rest of dag as well
+			m.Emit(nil) // Sending nil message is a message to downstream to shutdown
+			m.Quit()    // should close rest of dag as well
 			return false
 		}
 		rowCt++
 
 		//u.Debugf("row:%d  completed projection for: %p %#v", rowCt, out, outMsg)
-		select {
-		case out <- outMsg:
-			return true
-		case <-m.SigChan():
-			return false
-		}
+		return m.Emit(outMsg)
 	}
 }
 
 // Limit only evaluator
 func (m *Projection) limitEvaluator() MessageHandler {
 
-	out := m.MessageOut()
 	limit := m.p.Stmt.Limit
 	if limit == 0 {
 		limit = math.MaxInt32
@@ -333,7 +353,7 @@ func (m *Projection) limitEvaluator() MessageHandler {
 		if rowCt >= limit {
 			if rowCt == limit {
 				//u.Debugf("%p Projection reaching Limit!!! rowct:%v  limit:%v", m, rowCt, limit)
-				out <- nil // Sending nil message is a message to downstream to shutdown
+				m.Emit(nil) // Sending nil message is a message to downstream to shutdown
 				//m.Close()
 				m.Quit()
 			}
@@ -343,11 +363,6 @@ func (m *Projection) limitEvaluator() MessageHandler {
 		}
 		rowCt++
 
-		select {
-		case out <- msg:
-			return true
-		case <-m.SigChan():
-			return false
-		}
+		return m.Emit(msg)
 	}
 }
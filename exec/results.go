@@ -6,9 +6,13 @@ import (
 
 	u "github.com/araddon/gou"
 
+	"github.com/araddon/qlbridge/arrow"
 	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
 	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
 )
 
 const (
@@ -26,6 +30,7 @@ var (
 	_ TaskRunner = (*ResultExecWriter)(nil)
 	_ TaskRunner = (*ResultWriter)(nil)
 	_ TaskRunner = (*ResultBuffer)(nil)
+	_ TaskRunner = (*ResultBatchWriter)(nil)
 )
 
 type (
@@ -49,8 +54,23 @@ type (
 		closed bool
 		cols   []string
 	}
+	// ResultBatchWriter for writing tasks results as arrow record batches
+	ResultBatchWriter struct {
+		*TaskBase
+		closed    bool
+		BatchSize int
+		schema    *arrow.Schema
+		rb        *arrow.RecordBuilder
+		records   []*arrow.Record
+	}
 )
 
+// ReceivesBatches result writers run their handler per message of a
+// MessageBatch
+func (m *ResultExecWriter) ReceivesBatches() bool  { return true }
+func (m *ResultBuffer) ReceivesBatches() bool      { return true }
+func (m *ResultBatchWriter) ReceivesBatches() bool { return true }
+
 // NewResultExecWriter a result writer for exect task
 func NewResultExecWriter(ctx *plan.Context) *ResultExecWriter {
 	m := &ResultExecWriter{
@@ -109,6 +129,34 @@ func NewResultBuffer(ctx *plan.Context, writeTo *[]schema.Message) *ResultBuffer
 	return m
 }
 
+// NewResultBatchWriter a result writer collecting the results as arrow
+// record batches.  The record batches of batch tasks (see BuildSqlBatchJob)
+// are kept as is, rows of row tasks are built into batches of BatchSize.
+func NewResultBatchWriter(ctx *plan.Context) *ResultBatchWriter {
+	m := &ResultBatchWriter{
+		TaskBase:  NewTaskBase(ctx),
+		BatchSize: ItemDefaultBatchSize,
+	}
+	m.Handler = func(ctx *plan.Context, msg schema.Message) bool {
+		switch mt := msg.(type) {
+		case *BatchMessage:
+			m.writeRecord(mt.Record)
+		case nil:
+			// end of rows, ie a limit
+		default:
+			if m.rb == nil {
+				m.rb = arrow.NewRecordBuilder(m.Schema())
+			}
+			m.rb.Append(MessageValues(msg, len(m.schema.Fields)))
+			if m.rb.Len() >= m.BatchSize {
+				m.flush()
+			}
+		}
+		return true
+	}
+	return m
+}
+
 // Result of exec task
 func (m *ResultExecWriter) Result() driver.Result {
 	return &qlbResult{m.lastInsertID, m.rowsAffected, m.err}
@@ -162,6 +210,138 @@ func (m *ResultBuffer) Close() error {
 	return m.TaskBase.Close()
 }
 
+// Copy the result batch writer
+func (m *ResultBatchWriter) Copy() *ResultBatchWriter { return NewResultBatchWriter(m.Ctx) }
+
+// Close the ResultBatchWriter
+func (m *ResultBatchWriter) Close() error {
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.Unlock()
+	return m.TaskBase.Close()
+}
+
+// Run write the results until the input is closed
+func (m *ResultBatchWriter) Run() error {
+	err := m.TaskBase.Run()
+	m.flush()
+	return err
+}
+
+// Schema the schema of the record batches, of the first batch or if
+// there are none the projection of the statement.
+func (m *ResultBatchWriter) Schema() *arrow.Schema {
+	if m.schema == nil {
+		m.schema = ResultSchema(m.Ctx)
+	}
+	return m.schema
+}
+
+// Records the record batches of the results, once the job has run
+func (m *ResultBatchWriter) Records() []*arrow.Record {
+	return m.records
+}
+
+// WriteStream write the record batches in the arrow IPC stream format
+func (m *ResultBatchWriter) WriteStream(w io.Writer) error {
+	aw := arrow.NewWriter(w, m.Schema())
+	for _, rec := range m.records {
+		if err := aw.Write(rec); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+// writeRecord add a record batch, its columns converted to the types of
+// the schema of the first.
+func (m *ResultBatchWriter) writeRecord(rec *arrow.Record) {
+	if rec.Len == 0 {
+		return
+	}
+	if m.schema == nil {
+		m.schema = arrow.NewSchema(rec.Schema.Fields...)
+	}
+	if len(rec.Columns) != len(m.schema.Fields) {
+		u.Warnf("record has %d columns expected %d", len(rec.Columns), len(m.schema.Fields))
+		return
+	}
+	m.flush()
+	cols := make([]*arrow.Column, len(rec.Columns))
+	for i, c := range rec.Columns {
+		cols[i] = c.Cast(m.schema.Fields[i].Type)
+	}
+	out := arrow.NewRecord(m.schema, cols)
+	out.Len = rec.Len
+	m.records = append(m.records, out)
+}
+
+// flush the rows appended into a record batch
+func (m *ResultBatchWriter) flush() {
+	if m.rb != nil && m.rb.Len() > 0 {
+		m.records = append(m.records, m.rb.NewRecord())
+	}
+}
+
+// ResultSchema the arrow schema of the columns of a select, columns that
+// are a field of the (single) table selected from are typed from that
+// field and function columns by the function.
+func ResultSchema(ctx *plan.Context) *arrow.Schema {
+	s := arrow.NewSchema()
+	if ctx.Projection == nil || ctx.Projection.Proj == nil {
+		return s
+	}
+	var tbl *schema.Table
+	if sel, ok := ctx.Stmt.(*rel.SqlSelect); ok && len(sel.From) == 1 && ctx.Schema != nil {
+		tbl, _ = ctx.Schema.Table(sel.From[0].Name)
+	}
+	for _, rc := range ctx.Projection.Proj.Columns {
+		f := arrow.Field{Name: rc.As, Type: arrow.FromValueType(rc.Type), Nullable: true}
+		if f.Name == "" {
+			f.Name = rc.Name
+		}
+		if tbl != nil {
+			if fld, ok := tbl.FieldMap[rc.SourceName()]; ok && (rc.Col == nil || rc.Col.Expr == nil || isIdentity(rc.Col.Expr)) {
+				f.Type = arrow.FromValueType(fld.ValueType())
+			}
+		}
+		if rc.Col != nil {
+			if fn, ok := rc.Col.Expr.(*expr.FuncNode); ok && fn.F.CustomFunc != nil && fn.F.Type() != value.UnknownType {
+				f.Type = arrow.FromValueType(fn.F.Type())
+			}
+		}
+		s.Fields = append(s.Fields, f)
+	}
+	return s
+}
+
+func isIdentity(n expr.Node) bool {
+	_, ok := n.(*expr.IdentityNode)
+	return ok
+}
+
+// MessageValues the positional values of a row message, padded with nil
+// (or truncated) to n columns.
+func MessageValues(msg schema.Message, n int) []driver.Value {
+	var vals []driver.Value
+	switch mt := msg.Body().(type) {
+	case *datasource.SqlDriverMessageMap:
+		vals = mt.Values()
+	case []driver.Value:
+		vals = mt
+	default:
+		u.Warnf("unknown message type: %T", mt)
+	}
+	for len(vals) < n {
+		vals = append(vals, nil)
+	}
+	return vals[:n]
+}
+
 // Next his is implementation of the sql/driver Rows() Next() interface
 func (m *ResultWriter) Next(dest []driver.Value) error {
 	select {
@@ -75,6 +75,12 @@ func NewSource(ctx *plan.Context, p *plan.Source) (*Source, error) {
 		u.Warnf("source %T does not implement datasource.Scanner", p.Conn)
 		return nil, fmt.Errorf("%T Must Implement Scanner for %q", p.Conn, p.Stmt.String())
 	}
+	// Read only the rows of index seek found by planner
+	if seeker, canSeek := p.Conn.(schema.ConnIndexSeeker); canSeek && p.Seek != nil {
+		if !seeker.SeekIndex(p.Seek) {
+			u.Debugf("could not seek %s on index %q, scanning", p.Seek.Field, p.Seek.Index.Name)
+		}
+	}
 	s := &Source{
 		TaskBase: NewTaskBase(ctx),
 		Scanner:  scanner,
@@ -129,17 +135,16 @@ func (m *Source) Run() error {
 		return fmt.Errorf("No datasource found")
 	}
 
-	sigChan := m.SigChan()
-
 	for item := m.Scanner.Next(); item != nil; item = m.Scanner.Next() {
-
-		select {
-		case <-sigChan:
+		if !m.Emit(item) {
 			return nil
-		case m.msgOutCh <- item:
-			// continue
 		}
-
 	}
+	if se, ok := m.Scanner.(schema.ConnScanErr); ok {
+		if err := se.Err(); err != nil {
+			return err
+		}
+	}
+	m.Flush()
 	return nil
 }
@@ -32,6 +32,8 @@ type TaskBase struct {
 	errCh    ErrChan
 	sigCh    SigChan // notify of quit/stop
 	errors   []error
+	batchSz  int          // messages per MessageBatch sent by Emit
+	batch    MessageBatch // pending messages of Emit
 }
 
 func NewTaskBase(ctx *plan.Context) *TaskBase {
@@ -97,7 +99,95 @@ func (m *TaskBase) Close() error {
 }
 func (m *TaskBase) CloseFinal() error { return nil }
 
+// SetBatchSize the number of messages Emit sends as one MessageBatch, set
+// when the downstream task is a BatchReceiver.  n <= 1 sends each message.
+func (m *TaskBase) SetBatchSize(n int) { m.batchSz = n }
+
+// Emit send a message to the output channel, buffered into a MessageBatch
+// if there is a batch size.  Returns false if the task has quit.  The
+// pending batch is sent on Flush, which TaskBase.Run does on exit.
+func (m *TaskBase) Emit(msg schema.Message) bool {
+	if msg == nil || m.batchSz <= 1 {
+		// nil is the shutdown message, send whats pending ahead of it
+		return m.Flush() && m.send(msg)
+	}
+	if m.batch == nil {
+		m.batch = make(MessageBatch, 0, m.batchSz)
+	}
+	m.batch = append(m.batch, msg)
+	if len(m.batch) < m.batchSz {
+		return true
+	}
+	return m.Flush()
+}
+
+// Flush send the pending batch of Emit
+func (m *TaskBase) Flush() bool {
+	if len(m.batch) == 0 {
+		return true
+	}
+	batch := m.batch
+	m.batch = make(MessageBatch, 0, m.batchSz)
+	return m.send(batch)
+}
+
+func (m *TaskBase) send(msg schema.Message) bool {
+	select {
+	case m.msgOutCh <- msg:
+		return true
+	case <-m.sigCh:
+		return false
+	}
+}
+
+// Id of the first message of the batch
+func (m MessageBatch) Id() uint64 {
+	if len(m) == 0 {
+		return 0
+	}
+	return m[0].Id()
+}
+func (m MessageBatch) Body() interface{} { return []schema.Message(m) }
+
+// BatchHandler adapt a MessageHandler to batches of messages, each message
+// is handled in order, returns false if any of them did.
+func BatchHandler(h MessageHandler) MessageBatchHandler {
+	return func(ctx *plan.Context, msgs []schema.Message) bool {
+		ok := true
+		for _, msg := range msgs {
+			if !h(ctx, msg) {
+				ok = false
+			}
+		}
+		return ok
+	}
+}
+
+// UnbatchHandler adapt a MessageHandler to an input that may have a
+// MessageBatch, a batch is handled a message at a time.
+func UnbatchHandler(h MessageHandler) MessageHandler {
+	bh := BatchHandler(h)
+	return func(ctx *plan.Context, msg schema.Message) bool {
+		if mb, isBatch := msg.(MessageBatch); isBatch {
+			return bh(ctx, mb)
+		}
+		return h(ctx, msg)
+	}
+}
+
+// messageEmitter a task that sends its messages with Emit
+type messageEmitter interface {
+	Emit(msg schema.Message) bool
+}
+
+// MakeHandler a handler forwarding messages to the output of task, batched
+// by Emit for tasks that have it (embed TaskBase).
 func MakeHandler(task TaskRunner) MessageHandler {
+	if e, ok := task.(messageEmitter); ok {
+		return func(ctx *plan.Context, msg schema.Message) bool {
+			return e.Emit(msg)
+		}
+	}
 	out := task.MessageOut()
 	return func(ctx *plan.Context, msg schema.Message) bool {
 		select {
@@ -112,6 +202,7 @@ func MakeHandler(task TaskRunner) MessageHandler {
 func (m *TaskBase) Run() error {
 	defer m.Ctx.Recover() // Our context can recover panics, save error msg
 	defer func() {
+		m.Flush()
 		close(m.msgOutCh) // closing output channels is the signal to stop
 		//u.Debugf("close taskbase: ch:%p    %v", m.msgOutCh, m.Type())
 	}()
@@ -121,6 +212,7 @@ func (m *TaskBase) Run() error {
 		u.Warnf("returning, no handler %T", m)
 		return fmt.Errorf("Must have a handler to run base runner")
 	}
+	handler := UnbatchHandler(m.Handler)
 	ok := true
 	var err error
 	var msg schema.Message
@@ -144,7 +236,7 @@ msgLoop:
 		case msg, ok = <-m.msgInCh:
 			if ok {
 				//u.Debugf("sending to handler: %T  %+v", msg, msg)
-				m.Handler(m.Ctx, msg)
+				handler(m.Ctx, msg)
 			} else {
 				//u.Debugf("msg in closed shutting down")
 				break msgLoop
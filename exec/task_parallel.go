@@ -102,7 +102,7 @@ func (m *TaskParallel) Add(task Task) error {
 
 func (m *TaskParallel) Children() []Task { return m.tasks }
 
-func (m *TaskParallel) Run() error {
+func (m *TaskParallel) Run() (err error) {
 	defer m.Ctx.Recover() // Our context can recover panics, save error msg
 	defer func() {
 		// TODO:  find the culprit
@@ -127,6 +127,7 @@ func (m *TaskParallel) Run() error {
 	}
 
 	var wg sync.WaitGroup
+	var errMu sync.Mutex
 
 	// start tasks in reverse order, so that by time
 	// source starts up all downstreams have started
@@ -135,9 +136,13 @@ func (m *TaskParallel) Run() error {
 		go func(taskId int) {
 			task := m.runners[taskId]
 			//u.Infof("starting task %d-%d %T in:%p  out:%p", m.depth, taskId, task, task.MessageIn(), task.MessageOut())
-			if err := task.Run(); err != nil {
-				u.Errorf("%T.Run() errored %v", task, err)
-				// TODO:  what do we do with this error?   send to error channel?
+			if taskErr := runTask(m.Ctx, task); taskErr != nil {
+				u.Errorf("%T.Run() errored %v", task, taskErr)
+				errMu.Lock()
+				if err == nil {
+					err = taskErr
+				}
+				errMu.Unlock()
 			}
 			//u.Debugf("exiting taskId: %v %T", taskId, task)
 			wg.Done()
@@ -146,5 +151,5 @@ func (m *TaskParallel) Run() error {
 
 	wg.Wait()
 
-	return nil
+	return
 }
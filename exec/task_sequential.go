@@ -2,6 +2,7 @@ package exec
 
 import (
 	"fmt"
+	"runtime/debug"
 	"sync"
 
 	u "github.com/araddon/gou"
@@ -16,6 +17,25 @@ var (
 	_ Task = (*TaskSequential)(nil)
 )
 
+// runTask runs a single task, a panic while running is returned as
+// an error for the statement unless the context disables recover.
+func runTask(ctx *plan.Context, task TaskRunner) (err error) {
+	if ctx == nil || !ctx.DisableRecover {
+		defer func() {
+			if r := recover(); r != nil {
+				u.Errorf("%T.Run() panic %v\n%s", task, r, debug.Stack())
+				err = fmt.Errorf("%T panic: %v", task, r)
+			}
+		}()
+	}
+	return task.Run()
+}
+
+// batchSender a task that can send its output as MessageBatch
+type batchSender interface {
+	SetBatchSize(n int)
+}
+
 type TaskSequential struct {
 	*TaskBase
 	closed  bool
@@ -96,6 +116,18 @@ func (m *TaskSequential) Setup(depth int) error {
 		m.TaskBase.MessageOutSet(m.runners[len(m.tasks)-1].MessageOut())
 		m.runners[0].MessageInSet(m.TaskBase.MessageIn())
 	}
+	// tasks send batches of messages to the tasks that accept them
+	if m.Ctx != nil && m.Ctx.MessageBatchSize > 1 {
+		for i := 1; i < len(m.runners); i++ {
+			br, ok := m.runners[i].(BatchReceiver)
+			if !ok || !br.ReceivesBatches() {
+				continue
+			}
+			if bs, ok := m.runners[i-1].(batchSender); ok {
+				bs.SetBatchSize(m.Ctx.MessageBatchSize)
+			}
+		}
+	}
 	//u.Debugf("setup() %T in:%p  out:%p", m, m.msgInCh, m.msgOutCh)
 	return nil
 }
@@ -148,7 +180,7 @@ func (m *TaskSequential) Run() (err error) {
 		go func(taskId int) {
 			task := m.runners[taskId]
 			//u.Infof("starting task %d-%d %T in:%p  out:%p", m.depth, taskId, task, task.MessageIn(), task.MessageOut())
-			if taskErr := task.Run(); taskErr != nil {
+			if taskErr := runTask(m.Ctx, task); taskErr != nil {
 				u.Errorf("%T.Run() errored %v", task, taskErr)
 				// TODO:  what do we do with this error?   send to error channel?
 				err = taskErr
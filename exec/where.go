@@ -84,10 +84,14 @@ func NewHaving(ctx *plan.Context, p *plan.Having) *Where {
 	return s
 }
 
+// ReceivesBatches where runs its handler per message of a MessageBatch
+func (m *Where) ReceivesBatches() bool { return true }
+
 func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) MessageHandler {
-	out := task.MessageOut()
+	out := MakeHandler(task)
 
 	//u.Debugf("prepare filter %s", filter)
+	eval := compileExpr(filter)
 	return func(ctx *plan.Context, msg schema.Message) bool {
 
 		var filterValue value.Value
@@ -98,9 +102,9 @@ func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) Message
 			//u.Debugf("WHERE:  T:%T  vals:%#v", msg, mt.Vals)
 			//u.Debugf("cols:  %#v", cols)
 			msgReader := mt.ToMsgMap(cols)
-			filterValue, ok = vm.Eval(msgReader, filter)
+			filterValue, ok = eval(evalContext(ctx, msgReader))
 		case *datasource.SqlDriverMessageMap:
-			filterValue, ok = vm.Eval(mt, filter)
+			filterValue, ok = eval(evalContext(ctx, mt))
 			if !ok {
 				u.Warnf("wtf %s    %#v", filter, mt)
 			}
@@ -108,7 +112,7 @@ func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) Message
 			//u.Debugf("cols:  %#v", cols)
 		default:
 			if msgReader, isContextReader := msg.(expr.ContextReader); isContextReader {
-				filterValue, ok = vm.Eval(msgReader, filter)
+				filterValue, ok = eval(evalContext(ctx, msgReader))
 				if !ok {
 					u.Warnf("wat? %v  filterval:%#v expr: %s", filter.String(), filterValue, filter)
 				}
@@ -137,11 +141,28 @@ func whereFilter(filter expr.Node, task TaskRunner, cols map[string]int) Message
 		}
 
 		//u.Debugf("about to send from where to forward: %#v", msg)
-		select {
-		case out <- msg:
-			return true
-		case <-task.SigChan():
-			return false
+		return out(ctx, msg)
+	}
+}
+
+// evalContext the context to evaluate expressions against, wrapped for
+// sql three-valued NULL logic if the plan context asks for it.
+func evalContext(ctx *plan.Context, rdr expr.ContextReader) expr.EvalContext {
+	if ctx != nil && ctx.NullLogic {
+		return vm.NewNullLogicContext(rdr)
+	}
+	return rdr
+}
+
+// compileExpr compile the expression once for evaluating against each
+// message, falls back to interpreting it if it can't be compiled.
+func compileExpr(node expr.Node) func(ctx expr.EvalContext) (value.Value, bool) {
+	compiled, err := vm.Compile(node)
+	if err != nil {
+		u.Warnf("could not compile, interpreting %q err=%v", node, err)
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			return vm.Eval(ctx, node)
 		}
 	}
+	return compiled.Eval
 }
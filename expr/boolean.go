@@ -0,0 +1,178 @@
+package expr
+
+import (
+	"github.com/araddon/qlbridge/lex"
+)
+
+var (
+	tokenAnd    = lex.Token{T: lex.TokenLogicAnd, V: "AND"}
+	tokenOr     = lex.Token{T: lex.TokenLogicOr, V: "OR"}
+	tokenNegate = lex.Token{T: lex.TokenNegate, V: "NOT"}
+)
+
+// isLogical is this an AND/OR operator
+func isLogical(t lex.TokenType) bool {
+	return t == lex.TokenLogicAnd || t == lex.TokenLogicOr
+}
+
+// logicalToken the canonical AND/OR token for t
+func logicalToken(t lex.TokenType) lex.Token {
+	if t == lex.TokenLogicOr {
+		return tokenOr
+	}
+	return tokenAnd
+}
+
+// AndTerms the terms of n AND'd together, from either the binary or
+// boolean list form, a node that isn't an AND is the only term.
+//
+//    a AND (b AND c) AND (d OR e)  =>  [a, b, c, d OR e]
+func AndTerms(n Node) []Node {
+	return andTerms(n, nil)
+}
+
+func andTerms(node Node, terms []Node) []Node {
+	switch n := node.(type) {
+	case *BinaryNode:
+		if n.Operator.T == lex.TokenLogicAnd || n.Operator.T == lex.TokenAnd {
+			terms = andTerms(n.Args[0], terms)
+			return andTerms(n.Args[1], terms)
+		}
+	case *BooleanNode:
+		if !n.Negated() && (n.Operator.T == lex.TokenLogicAnd || n.Operator.T == lex.TokenAnd) {
+			for _, arg := range n.Args {
+				terms = andTerms(arg, terms)
+			}
+			return terms
+		}
+	}
+	return append(terms, node)
+}
+
+// BooleanToBinary rewrite the FilterQL BooleanNode lists of an expression
+// into the sql binary form, == is written as =
+//
+//    AND ( a, OR ( b, c ), NOT AND ( d, e ) )  =>  a AND (b OR c) AND NOT (d AND e)
+//
+// The nodes of n are not changed, rewritten nodes are new.
+func BooleanToBinary(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	n = booleanToBinary(n)
+	if bn, ok := n.(*BinaryNode); ok && isLogical(bn.Operator.T) && bn.Paren {
+		// the outermost doesn't need parens
+		return &BinaryNode{Operator: bn.Operator, Args: bn.Args, negated: bn.negated}
+	}
+	return n
+}
+
+func booleanToBinary(arg Node) Node {
+	switch n := arg.(type) {
+	case *BooleanNode:
+		if len(n.Args) == 0 {
+			return n
+		}
+		var bn Node
+		for _, a := range n.Args {
+			a = parenLogical(booleanToBinary(a))
+			if bn == nil {
+				bn = a
+				continue
+			}
+			bn = NewBinaryNode(logicalToken(n.Operator.T), bn, a)
+		}
+		if b, ok := bn.(*BinaryNode); ok && len(n.Args) > 1 {
+			b.Paren = true
+		}
+		if n.Negated() {
+			if len(n.Args) == 1 {
+				return NewUnary(tokenNegate, bn)
+			}
+			return &UnaryNode{Operator: tokenNegate, Arg: bn}
+		}
+		return bn
+	case *BinaryNode:
+		args := make([]Node, len(n.Args))
+		for i, a := range n.Args {
+			args[i] = booleanToBinary(a)
+			if isLogical(n.Operator.T) {
+				args[i] = parenLogical(args[i])
+			}
+		}
+		op := n.Operator
+		if op.T == lex.TokenEqualEqual {
+			op = lex.Token{T: lex.TokenEqual, V: "="}
+		}
+		return &BinaryNode{Operator: op, Paren: n.Paren, Args: args, negated: n.negated}
+	case *UnaryNode:
+		return &UnaryNode{Operator: n.Operator, Arg: booleanToBinary(n.Arg)}
+	default:
+		return arg
+	}
+}
+
+// parenLogical an AND/OR binary as an arg of another is parenthesized
+func parenLogical(n Node) Node {
+	if bn, ok := n.(*BinaryNode); ok && isLogical(bn.Operator.T) && !bn.Paren {
+		return &BinaryNode{Operator: bn.Operator, Paren: true, Args: bn.Args, negated: bn.negated}
+	}
+	return n
+}
+
+// BinaryToBoolean rewrite the sql AND/OR binary expressions of n into the
+// FilterQL BooleanNode list form, nested lists of the same operator are
+// flattened
+//
+//    a AND (b OR c) AND NOT (d AND e)  =>  AND ( a, OR ( b, c ), NOT AND ( d, e ) )
+//
+// The nodes of n are not changed, rewritten nodes are new.
+func BinaryToBoolean(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	return binaryToBoolean(n)
+}
+
+func binaryToBoolean(arg Node) Node {
+	switch n := arg.(type) {
+	case *BinaryNode:
+		if !isLogical(n.Operator.T) {
+			args := make([]Node, len(n.Args))
+			for i, a := range n.Args {
+				args[i] = binaryToBoolean(a)
+			}
+			return &BinaryNode{Operator: n.Operator, Paren: n.Paren, Args: args, negated: n.negated}
+		}
+		bn := NewBooleanNode(logicalToken(n.Operator.T))
+		for _, a := range n.Args {
+			bn.Args = appendBoolean(bn, binaryToBoolean(a))
+		}
+		return bn
+	case *BooleanNode:
+		bn := NewBooleanNode(logicalToken(n.Operator.T))
+		bn.negated = n.negated
+		for _, a := range n.Args {
+			bn.Args = appendBoolean(bn, binaryToBoolean(a))
+		}
+		return bn.Collapse()
+	case *UnaryNode:
+		a := binaryToBoolean(n.Arg)
+		if bn, ok := a.(*BooleanNode); ok && n.Operator.T == lex.TokenNegate {
+			bn.negated = !bn.negated
+			return bn
+		}
+		return &UnaryNode{Operator: n.Operator, Arg: a}
+	default:
+		return arg
+	}
+}
+
+// appendBoolean append arg to the args of list bn, a list of the same
+// operator is flattened into it
+func appendBoolean(bn *BooleanNode, arg Node) []Node {
+	if an, ok := arg.(*BooleanNode); ok && !an.negated && an.Operator.T == bn.Operator.T {
+		return append(bn.Args, an.Args...)
+	}
+	return append(bn.Args, arg)
+}
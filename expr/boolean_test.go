@@ -0,0 +1,65 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+)
+
+func TestBooleanToBinary(t *testing.T) {
+	tests := []struct {
+		filter string
+		sql    string
+	}{
+		{`FILTER AND ( x = 1, y == 2 )`, `x = 1 AND y = 2`},
+		{`FILTER AND ( x = 1, OR ( y = 2, z > 3 ), NOT AND ( a = 1, b = 2 ) )`,
+			`x = 1 AND (y = 2 OR z > 3) AND NOT (a = 1 AND b = 2)`},
+		{`FILTER OR ( AND ( x = 1, y = 2 ), z IN ("a", "b") )`, `(x = 1 AND y = 2) OR z IN ("a", "b")`},
+		{`FILTER NOT OR ( x IN (1, 2) )`, `NOT (x IN (1, 2))`},
+		{`FILTER x BETWEEN 1 AND 5`, `x BETWEEN 1 AND 5`},
+	}
+	for _, tt := range tests {
+		fs := rel.MustParseFilter(tt.filter)
+		before := fs.Filter.String()
+		n := expr.BooleanToBinary(fs.Filter)
+		assert.Equal(t, tt.sql, n.String(), tt.filter)
+		// original is not changed
+		assert.Equal(t, before, fs.Filter.String())
+
+		// and back to lists
+		sqlNode, err := expr.ParseExpression(tt.sql)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, expr.BinaryToBoolean(n).String(), expr.BinaryToBoolean(sqlNode).String(), tt.sql)
+	}
+
+	// nested lists of the same operator are flattened
+	n, err := expr.ParseExpression(`a = 1 AND (b = 2 AND (c = 3 OR d = 4 OR e = 5)) AND NOT (f = 6 OR g = 7)`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `AND ( a = 1, b = 2, OR ( c = 3, d = 4, e = 5 ), NOT OR ( f = 6, g = 7 ) )`,
+		expr.BinaryToBoolean(n).String())
+	fs := rel.MustParseFilter(`FILTER AND ( a = 1, AND ( b = 2, c = 3 ), OR ( d = 4 ) )`)
+	assert.Equal(t, `AND ( a = 1, b = 2, c = 3, d = 4 )`, expr.BinaryToBoolean(fs.Filter).String())
+}
+
+func TestAndTerms(t *testing.T) {
+	terms := func(n expr.Node) []string {
+		var out []string
+		for _, term := range expr.AndTerms(n) {
+			out = append(out, term.String())
+		}
+		return out
+	}
+	n, err := expr.ParseExpression(`a = 1 AND (b = 2 AND c = 3) AND (d = 4 OR e = 5)`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a = 1", "b = 2", "c = 3", "(d = 4 OR e = 5)"}, terms(n))
+
+	fs := rel.MustParseFilter(`FILTER AND ( a = 1, AND ( b = 2, c = 3 ), NOT AND ( d = 4, e = 5 ) )`)
+	assert.Equal(t, []string{"a = 1", "b = 2", "c = 3", "NOT AND ( d = 4, e = 5 )"}, terms(fs.Filter))
+
+	n, err = expr.ParseExpression(`a = 1 OR b = 2`)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a = 1 OR b = 2"}, terms(n))
+}
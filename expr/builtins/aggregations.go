@@ -26,6 +26,9 @@ func (m *Avg) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
 }
 func (m *Avg) IsAgg() bool { return true }
 
+// ArgTypes all args are numbers
+func (m *Avg) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
 func avgEval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
 	avg := float64(0)
 	ct := 0
@@ -79,6 +82,9 @@ func (m *Sum) Type() value.ValueType { return value.NumberType }
 // IsAgg yes sum is an agg.
 func (m *Sum) IsAgg() bool { return true }
 
+// ArgTypes all args are numbers
+func (m *Sum) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
 func (m *Sum) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
 	if len(n.Args) < 1 {
 		return nil, fmt.Errorf("Expected 1 or more args for Sum(arg, arg, ...) but got %s", n)
@@ -89,6 +95,8 @@ func (m *Sum) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
 func sumEval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
 
 	sumval := float64(0)
+	// decimals are summed exactly
+	var decsum value.DecimalValue
 	for _, val := range vals {
 		if val == nil || val.Nil() || val.Err() {
 			// we don't need to evaluate if nil or error
@@ -112,6 +120,12 @@ func sumEval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
 						return value.NumberNaNValue, false
 					}
 				}
+			case value.DecimalValue:
+				if decsum.Nil() {
+					decsum = v
+				} else {
+					decsum = decsum.Add(v)
+				}
 			case value.NumericValue:
 				fv := v.Float()
 				if !math.IsNaN(fv) {
@@ -123,6 +137,12 @@ func sumEval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
 			}
 		}
 	}
+	if !decsum.Nil() {
+		if sumval != 0 {
+			decsum = decsum.Add(value.NewDecimalFromFloat(sumval))
+		}
+		return decsum, true
+	}
 	if sumval == float64(0) {
 		return value.NumberNaNValue, false
 	}
@@ -4,6 +4,7 @@ package builtins
 
 import (
 	"fmt"
+	"math"
 	"sync"
 
 	u "github.com/araddon/gou"
@@ -24,6 +25,28 @@ func LoadAllBuiltins() {
 		// math
 		expr.FuncAdd("sqrt", &Sqrt{})
 		expr.FuncAdd("pow", &Pow{})
+		expr.FuncAdd("abs", &Abs{})
+		expr.FuncAdd("sign", &Sign{})
+		expr.FuncAdd("round", &Round{})
+		expr.FuncAdd("trunc", &Trunc{})
+		expr.FuncAdd("ceil", &Ceil{})
+		expr.FuncAdd("ceiling", &Ceil{})
+		expr.FuncAdd("floor", &Floor{})
+		expr.FuncAdd("mod", &Mod{})
+		expr.FuncAdd("exp", NewMathFunc("exp", math.Exp))
+		expr.FuncAdd("ln", NewMathFunc("ln", math.Log))
+		expr.FuncAdd("log10", NewMathFunc("log10", math.Log10))
+		expr.FuncAdd("sin", NewMathFunc("sin", math.Sin))
+		expr.FuncAdd("cos", NewMathFunc("cos", math.Cos))
+		expr.FuncAdd("tan", NewMathFunc("tan", math.Tan))
+		expr.FuncAdd("asin", NewMathFunc("asin", math.Asin))
+		expr.FuncAdd("acos", NewMathFunc("acos", math.Acos))
+		expr.FuncAdd("atan", NewMathFunc("atan", math.Atan))
+		expr.FuncAdd("atan2", &Atan2{})
+		expr.FuncAdd("degrees", NewMathFunc("degrees", degrees))
+		expr.FuncAdd("radians", NewMathFunc("radians", radians))
+		expr.FuncAdd("pi", &Pi{})
+		expr.FuncAdd("rand", &Rand{})
 
 		// aggregate ops
 		expr.FuncAdd("count", &Count{})
@@ -42,6 +65,15 @@ func LoadAllBuiltins() {
 		expr.FuncAdd("any", &Any{})
 		expr.FuncAdd("all", &All{})
 
+		// null handling, conditional
+		expr.FuncAdd("coalesce", &Coalesce{})
+		expr.FuncAdd("ifnull", &IfNull{})
+		expr.FuncAdd("nvl", &IfNull{})
+		expr.FuncAdd("nullif", &NullIf{})
+		expr.FuncAdd("if", &If{})
+		expr.FuncAdd("greatest", &Greatest{})
+		expr.FuncAdd("least", &Least{})
+
 		// Map
 		expr.FuncAdd("map", &MapFunc{})
 
@@ -62,6 +94,10 @@ func LoadAllBuiltins() {
 		expr.FuncAdd("extract", &StrFromTime{})
 		expr.FuncAdd("strftime", &StrFromTime{})
 		expr.FuncAdd("unixtrunc", &TimeTrunc{})
+		expr.FuncAdd("date_trunc", &DateTrunc{})
+		expr.FuncAdd("date_add", &DateAdd{})
+		expr.FuncAdd("date_diff", &DateDiff{})
+		expr.FuncAdd("age", &Age{})
 
 		// Casting and Type Coercion
 		expr.FuncAdd("tostring", &ToString{})
@@ -83,6 +119,10 @@ func LoadAllBuiltins() {
 		expr.FuncAdd("split", &Split{})
 		expr.FuncAdd("strip", &Strip{})
 		expr.FuncAdd("replace", &Replace{})
+		expr.FuncAdd("regexp_extract", &RegexpExtract{})
+		expr.FuncAdd("regexp_replace", &RegexpReplace{})
+		expr.FuncAdd("regexp_match_all", &RegexpMatchAll{})
+		expr.FuncAdd("regexp_split", &RegexpSplit{})
 		expr.FuncAdd("join", &Join{})
 		expr.FuncAdd("hassuffix", &HasSuffix{})
 		expr.FuncAdd("hasprefix", &HasPrefix{})
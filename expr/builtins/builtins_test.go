@@ -205,6 +205,29 @@ var builtinTests = []testBuiltins{
 	{`exists(Created)`, value.BoolValueTrue},
 	{`exists(Updated)`, value.BoolValueTrue},
 
+	{`coalesce(not_a_field, event)`, value.NewStringValue("hello")},
+	{`coalesce(not_a_field, toint(not_a_field2), 42)`, value.NewIntValue(42)},
+	{`coalesce(event, "x")`, value.NewStringValue("hello")},
+	{`coalesce(not_a_field)`, nil},
+	{`coalesce(not_a_field, 5) > 3`, value.BoolValueTrue},
+	{`ifnull(not_a_field, "default")`, value.NewStringValue("default")},
+	{`nvl(tag_name, "default")`, value.NewStringValue("bob")},
+	{`nullif(tag_name, "bob")`, nil},
+	{`nullif(tag_name, "alice")`, value.NewStringValue("bob")},
+	{`nullif(tag_name, not_a_field)`, value.NewStringValue("bob")},
+	{`nullif(not_a_field, "bob")`, nil},
+	{`if(score_amount > 20, "big", "small")`, value.NewStringValue("big")},
+	{`if(not_a_field, 1, 2)`, value.NewIntValue(2)},
+	{`if(true, event, not_a_field)`, value.NewStringValue("hello")},
+	{`if(false, "x", not_a_field)`, nil},
+	{`greatest(1, 5, "3")`, value.NewIntValue(5)},
+	{`greatest(not_a_field, "a", "b")`, value.NewStringValue("b")},
+	{`greatest(toint(score_amount), 10)`, value.NewIntValue(22)},
+	{`least(1, 5, "3")`, value.NewIntValue(1)},
+	{`least(not_a_field, "b", "a")`, value.NewStringValue("a")},
+	{`least(not_a_field)`, nil},
+	{`greatest(todate("2014-01-01"), todate("2015-01-01"))`, value.NewTimeValue(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC))},
+
 	/*
 		Logical Bool evaluation of List/Array types
 	*/
@@ -428,6 +451,21 @@ var builtinTests = []testBuiltins{
 	{`replace("M20:30","M","")`, value.NewStringValue("20:30")},
 	{`replace("M20:30","M","Hour ")`, value.NewStringValue("Hour 20:30")},
 
+	{`regexp_extract(email, "@(.*)$")`, value.NewStringValue("email.com")},
+	{`regexp_extract("order-1234-x", "[0-9]+")`, value.NewStringValue("1234")},
+	{`regexp_extract("2017-05-17", "([0-9]+)-([0-9]+)", 2)`, value.NewStringValue("05")},
+	{`regexp_extract("2017-05-17", "([0-9]+)-([0-9]+)", 0)`, value.NewStringValue("2017-05")},
+	{`regexp_extract("2017-05-17", "([0-9]+)", 3)`, value.ErrValue},
+	{`regexp_extract("abc", "[0-9]+")`, value.ErrValue},
+	{`regexp_extract(email, tag_name)`, value.ErrValue},
+	{`regexp_replace("/blog/2017/index.html", "[0-9]+", "x")`, value.NewStringValue("/blog/x/index.html")},
+	{`regexp_replace("bob smith", "([a-z]+) ([a-z]+)", "$2, $1")`, value.NewStringValue("smith, bob")},
+	{`regexp_replace(sval, "event", "e")`, value.NewStringValue("e43,e4=63.00,e228")},
+	{`regexp_match_all("a1b22c333", "[0-9]+")`, value.NewStringsValue([]string{"1", "22", "333"})},
+	{`regexp_match_all("abc", "[0-9]+")`, value.ErrValue},
+	{`regexp_split("a, b ,c", " *, *")`, value.NewStringsValue([]string{"a", "b", "c"})},
+	{`regexp_split(sval, "[,=]")`, value.NewStringsValue([]string{"event43", "event4", "63.00", "event228"})},
+
 	// len is also a list operation above
 	{`len("abc")`, value.NewIntValue(3)},
 	{`len(not_a_field)`, nil},
@@ -606,6 +644,11 @@ var builtinTests = []testBuiltins{
 	{`CAST(score_amount AS int))`, value.NewIntValue(22)},
 	{`CAST(score_amount AS string))`, value.NewStringValue("22")},
 	{`CAST(score_amount AS char))`, value.NewByteSliceValue([]byte("22"))},
+	{`CAST(score_amount AS DECIMAL(10,2))`, value.NewDecimalValue(2200, 2)},
+	{`cast(score_amount, "decimal(4,1)")`, value.NewDecimalValue(220, 1)},
+	{`cast("1.005" AS numeric(5,2))`, value.NewDecimalValue(101, 2)},
+	{`cast(score_amount AS DECIMAL(1,0))`, value.ErrValue},
+	{`cast(event AS decimal)`, value.ErrValue},
 	{`cast(ZeroTime as time)`, value.ErrValue},
 	{`cast(Created as notreal)`, value.ErrValue},
 	{`cast(Address AS int)`, value.ErrValue},
@@ -756,6 +799,21 @@ var builtinTests = []testBuiltins{
 	{`unixtrunc(reg_date,Address)`, value.ErrValue},
 	{`unixtrunc(reg_date,"not-valid")`, value.ErrValue},
 
+	{`date_diff("day", "2017-01-01T23:00:00Z", "2017-01-02T01:00:00Z")`, value.NewIntValue(1)},
+	{`date_diff("days", "2017-01-02T01:00:00Z", "2017-01-01T23:00:00Z")`, value.NewIntValue(-1)},
+	{`date_diff("month", "2017-01-31", "2017-03-01")`, value.NewIntValue(2)},
+	{`date_diff("quarter", "2017-03-31", "2017-04-01")`, value.NewIntValue(1)},
+	{`date_diff("year", "2016-12-31", "2017-01-01")`, value.NewIntValue(1)},
+	{`date_diff("week", "2017-05-14", "2017-05-15")`, value.NewIntValue(1)},
+	{`date_diff("hour", "2017-05-14T10:59:00Z", "2017-05-14T11:01:00Z")`, value.NewIntValue(1)},
+	{`date_diff("day", "2017-01-02T06:00:00Z", "2017-01-02T09:00:00Z", "America/Los_Angeles")`, value.NewIntValue(1)},
+	{`date_diff("day", "hello", reg_date)`, value.ErrValue},
+	{`age("2017-04-10", "1957-06-13")`, value.NewIntervalValue(717, 27, 0)},
+	{`age("2004-03-01", "2004-01-31")`, value.NewIntervalValue(1, 1, 0)},
+	{`age("2017-01-01T10:00:00Z", "2017-01-02T12:30:00Z")`, value.NewIntervalValue(0, -1, -2*time.Hour-30*time.Minute)},
+	{`age(reg_date)`, value.NewIntervalValue(-6, -5, -7*time.Hour-time.Minute-5*time.Second)},
+	{`age("hello")`, value.ErrValue},
+
 	// Math
 	{`pow(5,2)`, value.NewNumberValue(25)},
 	{`pow(2,2)`, value.NewNumberValue(4)},
@@ -768,8 +826,56 @@ var builtinTests = []testBuiltins{
 	{`sqrt(NotAField)`, value.ErrValue},
 	{`sqrt("hello")`, value.ErrValue},
 
+	{`abs(toint("-5"))`, value.NewIntValue(5)},
+	{`abs(-5.5)`, value.NewNumberValue(5.5)},
+	{`abs("-2.5")`, value.NewNumberValue(2.5)},
+	{`abs(cast("-1.10" AS decimal))`, value.NewDecimalValue(110, 2)},
+	{`abs(NotAField)`, value.ErrValue},
+	{`sign(toint("-5"))`, value.NewIntValue(-1)},
+	{`sign(2.5)`, value.NewNumberValue(1)},
+	{`sign(0)`, value.NewIntValue(0)},
+	{`round(2.5)`, value.NewNumberValue(3)},
+	{`round(-2.5)`, value.NewNumberValue(-3)},
+	{`round(1.2345, 2)`, value.NewNumberValue(1.23)},
+	{`round(1234, -2)`, value.NewIntValue(1200)},
+	{`round(cast("1.235" AS decimal), 2)`, value.NewDecimalValue(124, 2)},
+	{`round("hello")`, value.ErrValue},
+	{`trunc(-2.7)`, value.NewNumberValue(-2)},
+	{`trunc(1.239, 2)`, value.NewNumberValue(1.23)},
+	{`trunc(1299, -2)`, value.NewIntValue(1200)},
+	{`ceil(1.2)`, value.NewNumberValue(2)},
+	{`ceil(-1.2)`, value.NewNumberValue(-1)},
+	{`ceil(4)`, value.NewIntValue(4)},
+	{`floor(1.8)`, value.NewNumberValue(1)},
+	{`floor(-1.2)`, value.NewNumberValue(-2)},
+	{`floor(NotAField)`, value.ErrValue},
+	{`mod(7, 3)`, value.NewIntValue(1)},
+	{`mod(toint("-7"), 3)`, value.NewIntValue(-1)},
+	{`mod(-7.5, 2)`, value.NewNumberValue(-1.5)},
+	{`mod(cast("7.5" AS decimal), 2)`, value.NewDecimalValue(15, 1)},
+	{`mod(7, 0)`, value.ErrValue},
+	{`exp(0)`, value.NewNumberValue(1)},
+	{`ln(1)`, value.NewNumberValue(0)},
+	{`ln(0)`, value.ErrValue},
+	{`ln(-1)`, value.ErrValue},
+	{`log10(1000)`, value.NewNumberValue(3)},
+	{`sin(0)`, value.NewNumberValue(0)},
+	{`cos(0)`, value.NewNumberValue(1)},
+	{`tan(0)`, value.NewNumberValue(0)},
+	{`asin(1) * 2 == pi()`, value.BoolValueTrue},
+	{`asin(2)`, value.ErrValue},
+	{`acos(1)`, value.NewNumberValue(0)},
+	{`atan(0)`, value.NewNumberValue(0)},
+	{`atan2(0, 1)`, value.NewNumberValue(0)},
+	{`atan2(NotAField, 1)`, value.ErrValue},
+	{`degrees(pi())`, value.NewNumberValue(180)},
+	{`radians(180) == pi()`, value.BoolValueTrue},
+	{`rand() >= 0 AND rand() < 1`, value.BoolValueTrue},
+
 	// Aggregation functions
 	{`sum(1,2)`, value.NewNumberValue(3)},
+	{`sum(cast("0.1" AS decimal), cast("0.2" AS decimal))`, value.NewDecimalValue(3, 1)},
+	{`sum(cast("1.10" AS decimal), 2)`, value.NewDecimalValue(310, 2)},
 	{`sum(1,[2,3])`, value.NewNumberValue(6)},
 	{`sum(1,"2")`, value.NewNumberValue(3)},
 	{`sum(split("1,2", ","))`, value.NewNumberValue(3)},
@@ -814,8 +920,11 @@ var testValidation = []string{
 	`lt()`, `lt(a)`, `lt(a,b,c)`, // must be 2
 	`le()`, `le(a)`, `le(a,b,c)`, // must be 2
 	`exists()`, `exists(a,b)`, // must be 1
-	`any()`, // must be 1 or greater
-	`all()`, // must be 1 or greater
+	`any()`,                               // must be 1 or greater
+	`all()`,                               // must be 1 or greater
+	`coalesce()`, `greatest()`, `least()`, // must be 1 or greater
+	`ifnull(a)`, `nvl(a,b,c)`, `nullif(a)`, // must be 2
+	`if(a,b)`, `if(a,b,c,d)`, // must be 3
 
 	// cast
 	`cast()`, `cast(field,fake,2,"string")`, // must be cast(a,)
@@ -839,6 +948,13 @@ var testValidation = []string{
 	`seconds()`, `seconds(a,b)`, // must be 1
 	`unixtrunc()`, `unixtrunc(a,b,c)`, // must be 2
 	`strftime()`, `strftime(now())`, // must be 2
+	`regexp_extract(a)`, `regexp_extract(a, "(")`, // must be 2 or 3, valid pattern
+	`regexp_replace(a, "x")`, `regexp_match_all(a)`, `regexp_split(a, "[")`,
+	`date_trunc(now())`, `date_trunc("fortnight", now())`, // must be 2 or 3, valid unit
+	`date_trunc("day", now(), "hello")`,                 // unknown location
+	`date_add(now())`, `date_add(now(), "1 day", a, b)`, // must be 2 or 3
+	`date_diff("day", now())`, `date_diff("eon", a, b)`, // must be 3 or 4, valid unit
+	`age()`, `age(a,b,c)`, // must be 1 or 2
 
 	// slice & maps
 	`len()`, `len(a,b)`, // must be 1
@@ -873,10 +989,15 @@ var testValidation = []string{
 	`useragent.map()`, `useragent.map(a,b)`, // must be 1
 
 	// math
-	`sqrt()`,    // must have 1 args
-	`sqrt(1,2)`, // must have 1 args
-	`pow()`,     // must have 2 args
-	`pow(1)`,    // must have 2 args
+	`sqrt()`,                                              // must have 1 args
+	`sqrt(1,2)`,                                           // must have 1 args
+	`pow()`,                                               // must have 2 args
+	`pow(1)`,                                              // must have 2 args
+	`abs()`, `abs(1,2)`, `sign()`, `ceil()`, `floor(1,2)`, // must have 1 arg
+	`round()`, `round(1,2,3)`, `trunc()`, `trunc(1,2,3)`, // must have 1 or 2 args
+	`mod(1)`, `mod(1,2,3)`, `atan2(1)`, // must have 2 args
+	`exp()`, `ln(1,2)`, `log10()`, `sin()`, `cos(1,2)`, // must have 1 arg
+	`pi(1)`, `rand(1)`, // must have 0 args
 	// aggs
 	`avg()`,                   // must have 1 args
 	`sum()`,                   // must have 1 args
@@ -973,6 +1094,33 @@ func TestOneOffs(t *testing.T) {
 	assert.Equal(t, node.(*expr.FuncNode).F.CustomFunc.Type(), value.StringType)
 }
 
+func TestDateFuncs(t *testing.T) {
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{`date_trunc("month", "2017-05-17T15:04:05Z")`, time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{`date_trunc("week", "2017-05-17T15:04:05Z")`, time.Date(2017, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{`date_trunc("quarter", "2017-05-17T15:04:05Z")`, time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{`date_trunc("hours", "2017-05-17T15:04:05Z")`, time.Date(2017, 5, 17, 15, 0, 0, 0, time.UTC)},
+		{`date_trunc("day", "2017-05-17T03:04:05Z", "America/Los_Angeles")`, time.Date(2017, 5, 16, 0, 0, 0, 0, pst)},
+		{`date_add("2017-01-31T10:00:00Z", "1 month")`, time.Date(2017, 3, 3, 10, 0, 0, 0, time.UTC)},
+		{`date_add("2017-05-17T15:04:05Z", INTERVAL 3 DAY)`, time.Date(2017, 5, 20, 15, 4, 5, 0, time.UTC)},
+		// a calendar day across daylight savings is 23 hours
+		{`date_add("2017-03-12T07:00:00Z", "1 day", "America/Los_Angeles")`, time.Date(2017, 3, 13, 6, 0, 0, 0, time.UTC)},
+		{`date_add("2017-03-12T07:00:00Z", "24h")`, time.Date(2017, 3, 13, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		node, err := expr.ParseExpression(tt.expr)
+		assert.Equal(t, nil, err, tt.expr)
+		val, ok := vm.Eval(readContext, node)
+		assert.True(t, ok, tt.expr)
+		tv, isTime := val.(value.TimeValue)
+		assert.True(t, isTime, tt.expr)
+		assert.True(t, tt.want.Equal(tv.Val()), "%s want %v got %v", tt.expr, tt.want, tv.Val())
+	}
+}
+
 func TestValidation(t *testing.T) {
 	for _, exprText := range testValidation {
 		_, err := expr.ParseExpression(exprText)
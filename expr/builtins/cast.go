@@ -37,7 +37,7 @@ func toStringEval(ctx expr.EvalContext, args []value.Value) (value.Value, bool)
 //    cast(identity AS <type>) => 5.0
 //    cast(reg_date AS string) => "2014/01/12"
 //
-// Types:  [char, string, int, float]
+// Types:  [char, string, int, float, decimal, decimal(p,s)]
 //
 type Cast struct{}
 
@@ -64,7 +64,7 @@ func castEvalNoAs(ctx expr.EvalContext, vals []value.Value) (value.Value, bool)
 		case "char":
 			vt = value.ByteSliceType
 		default:
-			return nil, false
+			return castDecimal(vals[1].ToString(), vals[0])
 		}
 	}
 	val, err := value.Cast(vt, vals[0])
@@ -92,7 +92,7 @@ func castEval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
 		case "char":
 			vt = value.ByteSliceType
 		default:
-			return nil, false
+			return castDecimal(vals[2].ToString(), vals[0])
 		}
 	}
 	val, err := value.Cast(vt, vals[0])
@@ -102,6 +102,27 @@ func castEval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
 	return val, true
 }
 
+// castDecimal cast to a sql decimal type  DECIMAL(p,s), rounded to scale
+// s, not ok if it needs more than p digits.
+func castDecimal(typ string, val value.Value) (value.Value, bool) {
+	precision, scale, ok := value.ParseDecimalType(typ)
+	if !ok {
+		return nil, false
+	}
+	dv, ok := value.ValueToDecimal(val)
+	if !ok {
+		return nil, false
+	}
+	if precision == 0 {
+		return dv, true
+	}
+	dv = dv.Rescale(int32(scale))
+	if dv.Precision() > precision {
+		return nil, false
+	}
+	return dv, true
+}
+
 // ToBool cast as boolean
 //
 type ToBool struct{}
@@ -0,0 +1,297 @@
+package builtins
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+// The null handling and conditional functions are expr.LazyFunc's, they
+// evaluate their own args so accept args that can't be evaluated (missing
+// fields) and treat them as null.
+
+// valueArgs adapt a lazy evaluator to args already evaluated to values,
+// nil values are treated as not evaluated.
+func valueArgs(fn expr.LazyEvaluatorFunc) expr.EvaluatorFunc {
+	return func(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
+		args := make([]expr.ArgEvaluator, len(vals))
+		for i, v := range vals {
+			val := v
+			args[i] = func(expr.EvalContext) (value.Value, bool) {
+				return val, !isNull(val)
+			}
+		}
+		return fn(ctx, args)
+	}
+}
+
+func isNull(v value.Value) bool {
+	return v == nil || v.Nil() || v.Err()
+}
+
+// argValue evaluate arg, false if it could not be evaluated or is null.
+func argValue(ctx expr.EvalContext, arg expr.ArgEvaluator) (value.Value, bool) {
+	v, ok := arg(ctx)
+	if !ok || isNull(v) {
+		return nil, false
+	}
+	return v, true
+}
+
+// Coalesce the first argument that evaluates to a non-null value, missing
+// fields are skipped.
+//
+//     // given context {"name":"wil", "empty": null}
+//
+//     coalesce(not_a_field, name)       => "wil"
+//     coalesce(empty, not_a_field, 42)  => 42
+//     coalesce(not_a_field)             => nil, false
+//
+type Coalesce struct{}
+
+// Type unknown, the type of the chosen arg
+func (m *Coalesce) Type() value.ValueType { return value.UnknownType }
+func (m *Coalesce) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 1 {
+		return nil, fmt.Errorf("Expected 1 or more args for coalesce(arg, arg, ...) but got %s", n)
+	}
+	return valueArgs(coalesceEval), nil
+}
+func (m *Coalesce) ValidateLazy(n *expr.FuncNode) (expr.LazyEvaluatorFunc, error) {
+	return coalesceEval, nil
+}
+func coalesceEval(ctx expr.EvalContext, args []expr.ArgEvaluator) (value.Value, bool) {
+	for _, arg := range args {
+		if v, ok := argValue(ctx, arg); ok {
+			return v, true
+		}
+	}
+	return value.NilValueVal, false
+}
+
+// IfNull the first argument, or the second if the first is null or
+// missing.  Also registered as nvl.
+//
+//     ifnull(not_a_field, "default")  => "default"
+//     nvl(name, "default")            => "wil"
+//
+type IfNull struct{}
+
+// Type unknown, the type of the chosen arg
+func (m *IfNull) Type() value.ValueType { return value.UnknownType }
+func (m *IfNull) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("Expected exactly 2 args for ifnull(field, default) but got %s", n)
+	}
+	return valueArgs(coalesceEval), nil
+}
+func (m *IfNull) ValidateLazy(n *expr.FuncNode) (expr.LazyEvaluatorFunc, error) {
+	return coalesceEval, nil
+}
+
+// NullIf null if the two args are equal, else the first arg.
+//
+//     nullif(name, "wil")     => nil, false
+//     nullif(name, "bob")     => "wil"
+//     nullif(name, not_a_field) => "wil"
+//
+type NullIf struct{}
+
+// Type unknown, the type of the first arg
+func (m *NullIf) Type() value.ValueType { return value.UnknownType }
+func (m *NullIf) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("Expected exactly 2 args for nullif(field, value) but got %s", n)
+	}
+	return valueArgs(nullIfEval), nil
+}
+func (m *NullIf) ValidateLazy(n *expr.FuncNode) (expr.LazyEvaluatorFunc, error) {
+	return nullIfEval, nil
+}
+func nullIfEval(ctx expr.EvalContext, args []expr.ArgEvaluator) (value.Value, bool) {
+	a, ok := argValue(ctx, args[0])
+	if !ok {
+		return value.NilValueVal, false
+	}
+	b, ok := argValue(ctx, args[1])
+	if !ok {
+		return a, true
+	}
+	if eq, err := value.Equal(a, b); err == nil && eq {
+		return value.NilValueVal, false
+	}
+	return a, true
+}
+
+// If evaluates the condition, returning the second arg if true otherwise
+// the third.  Only the chosen arg is evaluated, a condition that can't be
+// evaluated is false.
+//
+//     if(int4 > 3, "big", "small")   => "big"
+//     if(not_a_field, 1, 2)          => 2
+//
+type If struct{}
+
+// Type unknown, the type of the chosen arg
+func (m *If) Type() value.ValueType { return value.UnknownType }
+func (m *If) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 3 {
+		return nil, fmt.Errorf("Expected exactly 3 args for if(condition, then, else) but got %s", n)
+	}
+	return valueArgs(ifEval), nil
+}
+func (m *If) ValidateLazy(n *expr.FuncNode) (expr.LazyEvaluatorFunc, error) {
+	return ifEval, nil
+}
+func ifEval(ctx expr.EvalContext, args []expr.ArgEvaluator) (value.Value, bool) {
+	cond := false
+	if v, ok := argValue(ctx, args[0]); ok {
+		cond, _ = value.ValueToBool(v)
+	}
+	if cond {
+		return args[1](ctx)
+	}
+	return args[2](ctx)
+}
+
+// Greatest the largest of the args, ignoring nulls and missing fields.
+// Numbers compare numerically, times by time, otherwise as strings.
+//
+//     greatest(1, 5, "3")             => 5
+//     greatest(not_a_field, "a", "b") => "b"
+//
+type Greatest struct{}
+
+// Type unknown, the type of the chosen arg
+func (m *Greatest) Type() value.ValueType { return value.UnknownType }
+func (m *Greatest) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 1 {
+		return nil, fmt.Errorf("Expected 1 or more args for greatest(arg, arg, ...) but got %s", n)
+	}
+	return valueArgs(greatestEval), nil
+}
+func (m *Greatest) ValidateLazy(n *expr.FuncNode) (expr.LazyEvaluatorFunc, error) {
+	return greatestEval, nil
+}
+func greatestEval(ctx expr.EvalContext, args []expr.ArgEvaluator) (value.Value, bool) {
+	return extremeEval(ctx, args, 1)
+}
+
+// Least the smallest of the args, ignoring nulls and missing fields.
+//
+//     least(1, 5, "3")              => 1
+//     least(not_a_field, "b", "a")  => "a"
+//
+type Least struct{}
+
+// Type unknown, the type of the chosen arg
+func (m *Least) Type() value.ValueType { return value.UnknownType }
+func (m *Least) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 1 {
+		return nil, fmt.Errorf("Expected 1 or more args for least(arg, arg, ...) but got %s", n)
+	}
+	return valueArgs(leastEval), nil
+}
+func (m *Least) ValidateLazy(n *expr.FuncNode) (expr.LazyEvaluatorFunc, error) {
+	return leastEval, nil
+}
+func leastEval(ctx expr.EvalContext, args []expr.ArgEvaluator) (value.Value, bool) {
+	return extremeEval(ctx, args, -1)
+}
+
+// extremeEval the arg that compares as dir (1 greatest, -1 least) to all
+// the others.
+func extremeEval(ctx expr.EvalContext, args []expr.ArgEvaluator, dir int) (value.Value, bool) {
+	var best value.Value
+	for _, arg := range args {
+		v, ok := argValue(ctx, arg)
+		if !ok {
+			continue
+		}
+		if best == nil {
+			best = v
+			continue
+		}
+		c, ok := compareValues(v, best)
+		if !ok {
+			return value.NilValueVal, false
+		}
+		if c == dir {
+			best = v
+		}
+	}
+	if best == nil {
+		return value.NilValueVal, false
+	}
+	return best, true
+}
+
+// compareValues -1, 0, +1 if a <, ==, > b.  Decimals, intervals and times
+// compare as their types, numbers (or strings of numbers compared to a
+// number) numerically, anything else as strings.
+func compareValues(a, b value.Value) (int, bool) {
+	switch av := a.(type) {
+	case value.DecimalValue:
+		if bv, ok := value.ValueToDecimal(b); ok {
+			return av.Cmp(bv), true
+		}
+	case value.DurationValue:
+		if bv, ok := value.ValueToDuration(b); ok {
+			return av.Cmp(bv), true
+		}
+	case value.TimeValue:
+		if bt, ok := value.ValueToTime(b); ok {
+			return compareTimes(av.Val(), bt), true
+		}
+		return 0, false
+	}
+	switch bv := b.(type) {
+	case value.DecimalValue:
+		if av, ok := value.ValueToDecimal(a); ok {
+			return av.Cmp(bv), true
+		}
+	case value.DurationValue:
+		if av, ok := value.ValueToDuration(a); ok {
+			return av.Cmp(bv), true
+		}
+	case value.TimeValue:
+		if at, ok := value.ValueToTime(a); ok {
+			return compareTimes(at, bv.Val()), true
+		}
+		return 0, false
+	}
+	if a.Type().IsNumeric() || b.Type().IsNumeric() {
+		af, aok := value.ValueToFloat64(a)
+		bf, bok := value.ValueToFloat64(b)
+		if !aok || !bok || math.IsNaN(af) || math.IsNaN(bf) {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	as, aok := value.ValueToString(a)
+	bs, bok := value.ValueToString(b)
+	if !aok || !bok {
+		return 0, false
+	}
+	return strings.Compare(as, bs), true
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
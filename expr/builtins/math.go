@@ -3,6 +3,7 @@ package builtins
 import (
 	"fmt"
 	"math"
+	"math/rand"
 
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/value"
@@ -19,6 +20,9 @@ type Sqrt struct{}
 // Type is NumberType
 func (m *Sqrt) Type() value.ValueType { return value.NumberType }
 
+// ArgTypes is a number
+func (m *Sqrt) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
 // Validate Must have 1 arg
 func (m *Sqrt) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
 	if len(n.Args) != 1 {
@@ -53,6 +57,11 @@ type Pow struct{}
 // Type is Number
 func (m *Pow) Type() value.ValueType { return value.NumberType }
 
+// ArgTypes number, power
+func (m *Pow) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.NumberType, value.NumberType}
+}
+
 // Must have 2 arguments, both must be able to be coerced to Number
 func (m *Pow) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
 	if len(n.Args) != 2 {
@@ -77,3 +86,508 @@ func powerEval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
 	fv = math.Pow(fv, pow)
 	return value.NewNumberValue(fv), true
 }
+
+// mathResultType the type of functions that keep the type of their first
+// arg, ints stay ints and decimals stay decimals, anything else is a number.
+func mathResultType(argTypes []value.ValueType) value.ValueType {
+	if len(argTypes) > 0 {
+		switch argTypes[0] {
+		case value.IntType, value.DecimalType:
+			return argTypes[0]
+		}
+	}
+	return value.NumberType
+}
+
+// numberResult a float64 result, NaN and Inf are not numbers.
+func numberResult(fv float64) (value.Value, bool) {
+	if math.IsNaN(fv) || math.IsInf(fv, 0) {
+		return value.NewNumberNil(), false
+	}
+	return value.NewNumberValue(fv), true
+}
+
+// Abs absolute value, of the same type as the arg.
+//
+//    abs(-5)            =>  5, true
+//    abs(-5.5)          =>  5.5, true
+//    abs(not_number)    =>  NilNumber, false
+//
+type Abs struct{}
+
+// Type is NumberType
+func (m *Abs) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes is a number
+func (m *Abs) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
+// ResultType is the type of the arg
+func (m *Abs) ResultType(argTypes []value.ValueType) value.ValueType {
+	return mathResultType(argTypes)
+}
+
+// Validate Must have 1 arg
+func (m *Abs) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("Expected exactly 1 args for abs(arg) but got %s", n)
+	}
+	return absEval, nil
+}
+
+func absEval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+	switch v := args[0].(type) {
+	case value.IntValue:
+		if v.Val() < 0 {
+			return value.NewIntValue(-v.Val()), true
+		}
+		return v, true
+	case value.DecimalValue:
+		if v.Nil() {
+			return value.NewNumberNil(), false
+		}
+		if v.Sign() < 0 {
+			return v.Neg(), true
+		}
+		return v, true
+	}
+	fv, ok := value.ValueToFloat64(args[0])
+	if !ok {
+		return value.NewNumberNil(), false
+	}
+	return numberResult(math.Abs(fv))
+}
+
+// Sign -1, 0, 1 for negative, zero, positive of the same type as the arg.
+//
+//    sign(-5)           =>  -1, true
+//    sign(2.5)          =>  1, true
+//    sign(not_number)   =>  NilNumber, false
+//
+type Sign struct{}
+
+// Type is NumberType
+func (m *Sign) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes is a number
+func (m *Sign) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
+// ResultType is the type of the arg
+func (m *Sign) ResultType(argTypes []value.ValueType) value.ValueType {
+	return mathResultType(argTypes)
+}
+
+// Validate Must have 1 arg
+func (m *Sign) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("Expected exactly 1 args for sign(arg) but got %s", n)
+	}
+	return signEval, nil
+}
+
+func signEval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+	switch v := args[0].(type) {
+	case value.IntValue:
+		switch {
+		case v.Val() < 0:
+			return value.NewIntValue(-1), true
+		case v.Val() > 0:
+			return value.NewIntValue(1), true
+		}
+		return value.NewIntValue(0), true
+	case value.DecimalValue:
+		if v.Nil() {
+			return value.NewNumberNil(), false
+		}
+		return value.NewDecimalValue(int64(v.Sign()), 0), true
+	}
+	fv, ok := value.ValueToFloat64(args[0])
+	if !ok || math.IsNaN(fv) {
+		return value.NewNumberNil(), false
+	}
+	switch {
+	case fv < 0:
+		return value.NewNumberValue(-1), true
+	case fv > 0:
+		return value.NewNumberValue(1), true
+	}
+	return value.NewNumberValue(0), true
+}
+
+// Round to n (default 0) decimal places, halves round away from zero.  Negative
+// places round to the left of the decimal point.  The result is the same
+// type as the arg.
+//
+//    round(2.5)          =>  3, true
+//    round(-1.235, 2)    =>  -1.24, true
+//    round(1234, -2)     =>  1200, true
+//    round(not_number)   =>  NilNumber, false
+//
+type Round struct{}
+
+// Type is NumberType
+func (m *Round) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes number, places
+func (m *Round) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.NumberType, value.IntType}
+}
+
+// ResultType is the type of the first arg
+func (m *Round) ResultType(argTypes []value.ValueType) value.ValueType {
+	return mathResultType(argTypes)
+}
+
+// Validate Must have 1 or 2 args
+func (m *Round) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 1 || len(n.Args) > 2 {
+		return nil, fmt.Errorf("Expected 1 or 2 args for round(number, [places]) but got %s", n)
+	}
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		return roundEval(args, false)
+	}, nil
+}
+
+// Trunc truncate towards zero to n (default 0) decimal places.  The result
+// is the same type as the arg.
+//
+//    trunc(-2.7)          =>  -2, true
+//    trunc(1.239, 2)      =>  1.23, true
+//    trunc(1299, -2)      =>  1200, true
+//    trunc(not_number)    =>  NilNumber, false
+//
+type Trunc struct{}
+
+// Type is NumberType
+func (m *Trunc) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes number, places
+func (m *Trunc) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.NumberType, value.IntType}
+}
+
+// ResultType is the type of the first arg
+func (m *Trunc) ResultType(argTypes []value.ValueType) value.ValueType {
+	return mathResultType(argTypes)
+}
+
+// Validate Must have 1 or 2 args
+func (m *Trunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 1 || len(n.Args) > 2 {
+		return nil, fmt.Errorf("Expected 1 or 2 args for trunc(number, [places]) but got %s", n)
+	}
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		return roundEval(args, true)
+	}, nil
+}
+
+// roundEval round (or truncate) args[0] to args[1] places by scaling by
+// the number of places.  Decimals rounded to positive places are rescaled
+// exactly.
+func roundEval(args []value.Value, truncate bool) (value.Value, bool) {
+	rounder := math.Round
+	if truncate {
+		rounder = math.Trunc
+	}
+	places := int64(0)
+	if len(args) > 1 {
+		p, ok := value.ValueToInt64(args[1])
+		if !ok {
+			return value.NewNumberNil(), false
+		}
+		places = p
+	}
+	if places > 15 {
+		places = 15
+	} else if places < -18 {
+		places = -18
+	}
+	scale := math.Pow(10, float64(places))
+	switch v := args[0].(type) {
+	case value.IntValue:
+		if places >= 0 {
+			return v, true
+		}
+		return value.NewIntValue(int64(rounder(float64(v.Val())*scale) / scale)), true
+	case value.DecimalValue:
+		if v.Nil() {
+			return value.NewNumberNil(), false
+		}
+		if places >= 0 && !truncate {
+			return v.Rescale(int32(places)), true
+		}
+		return value.NewDecimalFromFloat(rounder(v.Float()*scale) / scale), true
+	}
+	fv, ok := value.ValueToFloat64(args[0])
+	if !ok {
+		return value.NewNumberNil(), false
+	}
+	return numberResult(rounder(fv*scale) / scale)
+}
+
+// Ceil smallest whole number greater than or equal to the arg.
+//
+//    ceil(1.2)          =>  2, true
+//    ceil(-1.2)         =>  -1, true
+//    ceil(not_number)   =>  NilNumber, false
+//
+type Ceil struct{}
+
+// Type is NumberType
+func (m *Ceil) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes is a number
+func (m *Ceil) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
+// ResultType int for int arg, else number
+func (m *Ceil) ResultType(argTypes []value.ValueType) value.ValueType {
+	return wholeResultType(argTypes)
+}
+
+// Validate Must have 1 arg
+func (m *Ceil) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("Expected exactly 1 args for ceil(arg) but got %s", n)
+	}
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		return wholeEval(args[0], math.Ceil)
+	}, nil
+}
+
+// Floor largest whole number less than or equal to the arg.
+//
+//    floor(1.8)          =>  1, true
+//    floor(-1.2)         =>  -2, true
+//    floor(not_number)   =>  NilNumber, false
+//
+type Floor struct{}
+
+// Type is NumberType
+func (m *Floor) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes is a number
+func (m *Floor) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
+// ResultType int for int arg, else number
+func (m *Floor) ResultType(argTypes []value.ValueType) value.ValueType {
+	return wholeResultType(argTypes)
+}
+
+// Validate Must have 1 arg
+func (m *Floor) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("Expected exactly 1 args for floor(arg) but got %s", n)
+	}
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		return wholeEval(args[0], math.Floor)
+	}, nil
+}
+
+func wholeResultType(argTypes []value.ValueType) value.ValueType {
+	if len(argTypes) > 0 && argTypes[0] == value.IntType {
+		return value.IntType
+	}
+	return value.NumberType
+}
+
+func wholeEval(arg value.Value, f func(float64) float64) (value.Value, bool) {
+	if iv, isInt := arg.(value.IntValue); isInt {
+		return iv, true
+	}
+	fv, ok := value.ValueToFloat64(arg)
+	if !ok {
+		return value.NewNumberNil(), false
+	}
+	return numberResult(f(fv))
+}
+
+// Mod remainder of x / y with the sign of x.  Int if both args are ints,
+// decimal if either is a decimal, else number.
+//
+//    mod(7, 3)           =>  1, true
+//    mod(-7.5, 2)        =>  -1.5, true
+//    mod(7, 0)           =>  NilNumber, false
+//
+type Mod struct{}
+
+// Type is NumberType
+func (m *Mod) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes x, y
+func (m *Mod) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.NumberType, value.NumberType}
+}
+
+// ResultType int for ints, decimal for decimals, else number
+func (m *Mod) ResultType(argTypes []value.ValueType) value.ValueType {
+	if len(argTypes) != 2 {
+		return value.NumberType
+	}
+	switch {
+	case argTypes[0] == value.IntType && argTypes[1] == value.IntType:
+		return value.IntType
+	case argTypes[0] == value.DecimalType || argTypes[1] == value.DecimalType:
+		return value.DecimalType
+	}
+	return value.NumberType
+}
+
+// Validate Must have 2 args
+func (m *Mod) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("Expected 2 args for mod(x, y) but got %s", n)
+	}
+	return modEval, nil
+}
+
+func modEval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+	if args[0] == nil || args[0].Err() || args[0].Nil() {
+		return value.NewNumberNil(), false
+	}
+	if args[1] == nil || args[1].Err() || args[1].Nil() {
+		return value.NewNumberNil(), false
+	}
+	ai, aInt := args[0].(value.IntValue)
+	bi, bInt := args[1].(value.IntValue)
+	if aInt && bInt {
+		if bi.Val() == 0 {
+			return value.NewNumberNil(), false
+		}
+		return value.NewIntValue(ai.Val() % bi.Val()), true
+	}
+	if args[0].Type() == value.DecimalType || args[1].Type() == value.DecimalType {
+		ad, aok := value.ValueToDecimal(args[0])
+		bd, bok := value.ValueToDecimal(args[1])
+		if !aok || !bok {
+			return value.NewNumberNil(), false
+		}
+		if r, ok := ad.Mod(bd); ok {
+			return r, true
+		}
+		return value.NewNumberNil(), false
+	}
+	af, aok := value.ValueToFloat64(args[0])
+	bf, bok := value.ValueToFloat64(args[1])
+	if !aok || !bok {
+		return value.NewNumberNil(), false
+	}
+	return numberResult(math.Mod(af, bf))
+}
+
+// MathFunc a float64 math function of one number arg, used for
+// exp, ln, log10 and the trig functions.  Results that are not
+// numbers (ln(0), asin(2)) are NilNumber, false.
+//
+//    exp(1)             =>  2.718281828459045, true
+//    ln(exp(2))         =>  2, true
+//    log10(1000)        =>  3, true
+//    sin(pi() / 2)      =>  1, true
+//    degrees(pi())      =>  180, true
+//    ln(0)              =>  NilNumber, false
+//
+type MathFunc struct {
+	Name string
+	F    func(float64) float64
+}
+
+// NewMathFunc create a one arg float64 math function.
+func NewMathFunc(name string, f func(float64) float64) *MathFunc {
+	return &MathFunc{Name: name, F: f}
+}
+
+// Type is NumberType
+func (m *MathFunc) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes is a number
+func (m *MathFunc) ArgTypes() []value.ValueType { return []value.ValueType{value.NumberType} }
+
+// Validate Must have 1 arg
+func (m *MathFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("Expected exactly 1 args for %s(arg) but got %s", m.Name, n)
+	}
+	return m.eval, nil
+}
+
+func (m *MathFunc) eval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+	fv, ok := value.ValueToFloat64(args[0])
+	if !ok {
+		return value.NewNumberNil(), false
+	}
+	return numberResult(m.F(fv))
+}
+
+// Atan2 arc tangent of y/x, using the signs of both to find the quadrant.
+//
+//    atan2(1, 1)          =>  0.7853981633974483, true
+//    atan2(not_number, 1) =>  NilNumber, false
+//
+type Atan2 struct{}
+
+// Type is NumberType
+func (m *Atan2) Type() value.ValueType { return value.NumberType }
+
+// ArgTypes y, x
+func (m *Atan2) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.NumberType, value.NumberType}
+}
+
+// Validate Must have 2 args
+func (m *Atan2) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("Expected 2 args for atan2(y, x) but got %s", n)
+	}
+	return atan2Eval, nil
+}
+
+func atan2Eval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+	y, ok := value.ValueToFloat64(args[0])
+	if !ok {
+		return value.NewNumberNil(), false
+	}
+	x, ok := value.ValueToFloat64(args[1])
+	if !ok {
+		return value.NewNumberNil(), false
+	}
+	return numberResult(math.Atan2(y, x))
+}
+
+// Pi the constant pi
+//
+//    pi()    =>  3.141592653589793, true
+//
+type Pi struct{}
+
+// Type is NumberType
+func (m *Pi) Type() value.ValueType { return value.NumberType }
+
+// Validate Must have 0 args
+func (m *Pi) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 0 {
+		return nil, fmt.Errorf("Expected 0 args for pi() but got %s", n)
+	}
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		return value.NewNumberValue(math.Pi), true
+	}, nil
+}
+
+// Rand random number in [0.0, 1.0)
+//
+//    rand()    =>  0.6046602879796196, true
+//
+type Rand struct{}
+
+// Type is NumberType
+func (m *Rand) Type() value.ValueType { return value.NumberType }
+
+// Validate Must have 0 args
+func (m *Rand) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 0 {
+		return nil, fmt.Errorf("Expected 0 args for rand() but got %s", n)
+	}
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		return value.NewNumberValue(rand.Float64()), true
+	}, nil
+}
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
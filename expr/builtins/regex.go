@@ -0,0 +1,198 @@
+package builtins
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+// regexArg the compiled pattern of a string literal argument, compiled once
+// at Validate.  Nil if the pattern is not a literal, in which case it is
+// compiled (and cached) when evaluated, see regexEval.
+func regexArg(n *expr.FuncNode, i int) (*regexp.Regexp, error) {
+	sn, ok := n.Args[i].(*expr.StringNode)
+	if !ok {
+		return nil, nil
+	}
+	re, err := regexp.Compile(sn.Text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid regular expression %q in %s: %v", sn.Text, n, err)
+	}
+	return re, nil
+}
+
+// regexEval the pattern, re if it was a literal else compiled from arg.
+func regexEval(re *regexp.Regexp, arg value.Value) (*regexp.Regexp, bool) {
+	if re != nil {
+		return re, true
+	}
+	pattern, ok := value.ValueToString(arg)
+	if !ok {
+		return nil, false
+	}
+	re, err := vm.CompileRegex(pattern, false)
+	return re, err == nil
+}
+
+// RegexpExtract the first match of a regular expression in a string, if the
+// pattern has a capture group the first group (or the optional group index).
+//
+//     regexp_extract("bob@email.com", "@(.*)$")          => "email.com"
+//     regexp_extract("order-1234-x", "[0-9]+")           => "1234"
+//     regexp_extract("2017-05-17", "(\d+)-(\d+)", 2)     => "05"
+//
+type RegexpExtract struct{}
+
+// Type string
+func (m *RegexpExtract) Type() value.ValueType { return value.StringType }
+
+// ArgTypes string, pattern, group index
+func (m *RegexpExtract) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.StringType, value.StringType, value.IntType}
+}
+func (m *RegexpExtract) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 2 || len(n.Args) > 3 {
+		return nil, fmt.Errorf("Expected 2 or 3 args for regexp_extract(field, pattern [, group]) but got %s", n)
+	}
+	re, err := regexArg(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		str, ok := value.ValueToString(args[0])
+		if !ok {
+			return value.EmptyStringValue, false
+		}
+		re, ok := regexEval(re, args[1])
+		if !ok {
+			return value.EmptyStringValue, false
+		}
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1
+		}
+		if len(args) == 3 {
+			gi, ok := value.ValueToInt64(args[2])
+			if !ok || gi < 0 || int(gi) > re.NumSubexp() {
+				return value.EmptyStringValue, false
+			}
+			group = int(gi)
+		}
+		match := re.FindStringSubmatch(str)
+		if match == nil {
+			return value.EmptyStringValue, false
+		}
+		return value.NewStringValue(match[group]), true
+	}, nil
+}
+
+// RegexpReplace replace all matches of a regular expression, the replacement
+// may refer to capture groups as $1, ${name}.
+//
+//     regexp_replace("/blog/2017/index.html", "[0-9]+", "x")  => "/blog/x/index.html"
+//     regexp_replace("bob smith", "(\w+) (\w+)", "$2, $1")     => "smith, bob"
+//
+type RegexpReplace struct{}
+
+// Type string
+func (m *RegexpReplace) Type() value.ValueType { return value.StringType }
+
+// ArgTypes string, pattern, replacement
+func (m *RegexpReplace) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.StringType, value.StringType, value.StringType}
+}
+func (m *RegexpReplace) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 3 {
+		return nil, fmt.Errorf("Expected 3 args for regexp_replace(field, pattern, replacement) but got %s", n)
+	}
+	re, err := regexArg(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		str, ok := value.ValueToString(args[0])
+		if !ok {
+			return value.EmptyStringValue, false
+		}
+		re, ok := regexEval(re, args[1])
+		if !ok {
+			return value.EmptyStringValue, false
+		}
+		return value.NewStringValue(re.ReplaceAllString(str, args[2].ToString())), true
+	}, nil
+}
+
+// RegexpMatchAll all of the matches of a regular expression in a string.
+//
+//     regexp_match_all("a1b22c333", "[0-9]+")   => ["1","22","333"]
+//
+type RegexpMatchAll struct{}
+
+// Type strings
+func (m *RegexpMatchAll) Type() value.ValueType { return value.StringsType }
+
+// ArgTypes string, pattern
+func (m *RegexpMatchAll) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.StringType, value.StringType}
+}
+func (m *RegexpMatchAll) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("Expected 2 args for regexp_match_all(field, pattern) but got %s", n)
+	}
+	re, err := regexArg(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		str, ok := value.ValueToString(args[0])
+		if !ok {
+			return value.NewStringsValue(nil), false
+		}
+		re, ok := regexEval(re, args[1])
+		if !ok {
+			return value.NewStringsValue(nil), false
+		}
+		matches := re.FindAllString(str, -1)
+		if len(matches) == 0 {
+			return value.NewStringsValue(nil), false
+		}
+		return value.NewStringsValue(matches), true
+	}, nil
+}
+
+// RegexpSplit split a string on a regular expression.
+//
+//     regexp_split("a, b ,c", "\s*,\s*")   => ["a","b","c"]
+//
+type RegexpSplit struct{}
+
+// Type strings
+func (m *RegexpSplit) Type() value.ValueType { return value.StringsType }
+
+// ArgTypes string, pattern
+func (m *RegexpSplit) ArgTypes() []value.ValueType {
+	return []value.ValueType{value.StringType, value.StringType}
+}
+func (m *RegexpSplit) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("Expected 2 args for regexp_split(field, pattern) but got %s", n)
+	}
+	re, err := regexArg(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		str, ok := value.ValueToString(args[0])
+		if !ok || str == "" {
+			return value.NewStringsValue(nil), false
+		}
+		re, ok := regexEval(re, args[1])
+		if !ok {
+			return value.NewStringsValue(nil), false
+		}
+		return value.NewStringsValue(re.Split(str, -1)), true
+	}, nil
+}
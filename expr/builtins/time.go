@@ -615,3 +615,282 @@ func strFromTimeEval(ctx expr.EvalContext, args []value.Value) (value.Value, boo
 	formatted := timeutil.Strftime(&t, formatStr)
 	return value.NewStringValue(formatted), true
 }
+
+// dateUnit normalize a date unit name, "Days" => "day"
+func dateUnit(unit string) (string, bool) {
+	u := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(unit)), "s")
+	switch u {
+	case "second", "minute", "hour", "day", "week", "month", "quarter", "year":
+		return u, true
+	}
+	return "", false
+}
+
+// dateUnitArg validate a string literal unit argument.
+func dateUnitArg(n *expr.FuncNode, i int) error {
+	if sn, ok := n.Args[i].(*expr.StringNode); ok {
+		if _, ok := dateUnit(sn.Text); !ok {
+			return fmt.Errorf("Invalid date unit %q in %s", sn.Text, n)
+		}
+	}
+	return nil
+}
+
+// locationArg the time zone of an optional trailing string literal argument,
+// nil if there is none.
+func locationArg(n *expr.FuncNode, i int) (*time.Location, error) {
+	if len(n.Args) <= i {
+		return nil, nil
+	}
+	sn, ok := n.Args[i].(*expr.StringNode)
+	if !ok {
+		return nil, fmt.Errorf("Expected a string literal value for location like America/Los_Angeles")
+	}
+	return time.LoadLocation(sn.Text)
+}
+
+// timeArg convert the arg to time, in loc if not nil.
+func timeArg(arg value.Value, loc *time.Location) (time.Time, bool) {
+	t, ok := value.ValueToTime(arg)
+	if !ok || t.IsZero() {
+		return t, false
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t, true
+}
+
+// truncTime truncate t to the start of the unit in the location of t,
+// weeks start on monday.
+func truncTime(t time.Time, unit string) time.Time {
+	y, mon, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case "second":
+		return time.Date(y, mon, d, t.Hour(), t.Minute(), t.Second(), 0, loc)
+	case "minute":
+		return time.Date(y, mon, d, t.Hour(), t.Minute(), 0, 0, loc)
+	case "hour":
+		return time.Date(y, mon, d, t.Hour(), 0, 0, 0, loc)
+	case "day":
+		return time.Date(y, mon, d, 0, 0, 0, 0, loc)
+	case "week":
+		return time.Date(y, mon, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case "month":
+		return time.Date(y, mon, 1, 0, 0, 0, 0, loc)
+	case "quarter":
+		return time.Date(y, mon-(mon-1)%3, 1, 0, 0, 0, 0, loc)
+	case "year":
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+	}
+	return t
+}
+
+// civilDays the days since the epoch of the calendar date of t.
+func civilDays(t time.Time) int64 {
+	y, mon, d := t.Date()
+	return time.Date(y, mon, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+// DateTrunc truncate a time to the start of the second, minute, hour, day,
+// week (monday), month, quarter or year in an optional time zone.
+//
+//    date_trunc("month", "2017-05-17T15:04:05Z")  =>  2017-05-01T00:00:00Z
+//    date_trunc("day", ts, "America/Los_Angeles")  =>  midnight los angeles time
+//
+type DateTrunc struct{}
+
+// Type time
+func (m *DateTrunc) Type() value.ValueType { return value.TimeType }
+func (m *DateTrunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 2 || len(n.Args) > 3 {
+		return nil, fmt.Errorf("Expected 2 or 3 args for date_trunc(unit, field [, location]) but got %s", n)
+	}
+	if err := dateUnitArg(n, 0); err != nil {
+		return nil, err
+	}
+	loc, err := locationArg(n, 2)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		unitStr, ok := value.ValueToString(args[0])
+		if !ok {
+			return value.TimeZeroValue, false
+		}
+		unit, ok := dateUnit(unitStr)
+		if !ok {
+			return value.TimeZeroValue, false
+		}
+		t, ok := timeArg(args[1], loc)
+		if !ok {
+			return value.TimeZeroValue, false
+		}
+		return value.NewTimeValue(truncTime(t, unit)), true
+	}, nil
+}
+
+// DateAdd add an interval to a time, the months, days of the interval are
+// added in the optional time zone so days are calendar days across daylight
+// savings changes.
+//
+//    date_add(ts, INTERVAL 3 DAY)
+//    date_add(ts, "1 month", "America/Los_Angeles")
+//
+type DateAdd struct{}
+
+// Type time
+func (m *DateAdd) Type() value.ValueType { return value.TimeType }
+func (m *DateAdd) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 2 || len(n.Args) > 3 {
+		return nil, fmt.Errorf("Expected 2 or 3 args for date_add(field, interval [, location]) but got %s", n)
+	}
+	loc, err := locationArg(n, 2)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		t, ok := timeArg(args[0], loc)
+		if !ok {
+			return value.TimeZeroValue, false
+		}
+		d, ok := value.ValueToDuration(args[1])
+		if !ok {
+			return value.TimeZeroValue, false
+		}
+		return value.NewTimeValue(d.AddTo(t)), true
+	}, nil
+}
+
+// DateDiff the number of unit boundaries crossed between start and end,
+// in an optional time zone.
+//
+//    date_diff("day", "2017-01-01T23:00:00Z", "2017-01-02T01:00:00Z")  =>  1
+//    date_diff("month", "2017-01-31", "2017-03-01")                    =>  2
+//
+type DateDiff struct{}
+
+// Type int
+func (m *DateDiff) Type() value.ValueType { return value.IntType }
+func (m *DateDiff) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 3 || len(n.Args) > 4 {
+		return nil, fmt.Errorf("Expected 3 or 4 args for date_diff(unit, start, end [, location]) but got %s", n)
+	}
+	if err := dateUnitArg(n, 0); err != nil {
+		return nil, err
+	}
+	loc, err := locationArg(n, 3)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ expr.EvalContext, args []value.Value) (value.Value, bool) {
+		unitStr, ok := value.ValueToString(args[0])
+		if !ok {
+			return value.NewIntNil(), false
+		}
+		unit, ok := dateUnit(unitStr)
+		if !ok {
+			return value.NewIntNil(), false
+		}
+		start, ok := timeArg(args[1], loc)
+		if !ok {
+			return value.NewIntNil(), false
+		}
+		end, ok := timeArg(args[2], loc)
+		if !ok {
+			return value.NewIntNil(), false
+		}
+		if loc == nil {
+			end = end.In(start.Location())
+		}
+		return value.NewIntValue(dateDiff(unit, start, end)), true
+	}, nil
+}
+
+func dateDiff(unit string, start, end time.Time) int64 {
+	start, end = truncTime(start, unit), truncTime(end, unit)
+	months := func(t time.Time) int64 { return int64(t.Year())*12 + int64(t.Month()) - 1 }
+	switch unit {
+	case "year":
+		return int64(end.Year() - start.Year())
+	case "quarter":
+		return months(end)/3 - months(start)/3
+	case "month":
+		return months(end) - months(start)
+	case "week":
+		return (civilDays(end) - civilDays(start)) / 7
+	case "day":
+		return civilDays(end) - civilDays(start)
+	case "hour":
+		return int64(end.Sub(start) / time.Hour)
+	case "minute":
+		return int64(end.Sub(start) / time.Minute)
+	}
+	return int64(end.Sub(start) / time.Second)
+}
+
+// Age the symbolic interval of years, months, days and time between two
+// times, as postgres age().  With one argument the age is from the time to
+// the message time stamp (or now).
+//
+//    age("2017-04-10", "1957-06-13")  =>  "717 months 27 days"  (59 years 9 months 27 days)
+//    age(birthday)
+//
+type Age struct{}
+
+// Type duration
+func (m *Age) Type() value.ValueType { return value.DurationType }
+func (m *Age) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) < 1 || len(n.Args) > 2 {
+		return nil, fmt.Errorf("Expected 1 or 2 args for age([end,] start) but got %s", n)
+	}
+	return ageEval, nil
+}
+
+func ageEval(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+	var end time.Time
+	startArg := args[0]
+	if len(args) == 2 {
+		var ok bool
+		if end, ok = timeArg(args[0], nil); !ok {
+			return value.NewDurationNil(), false
+		}
+		startArg = args[1]
+	} else if ctx != nil && !ctx.Ts().IsZero() {
+		end = ctx.Ts()
+	} else {
+		end = time.Now().In(time.UTC)
+	}
+	start, ok := timeArg(startArg, nil)
+	if !ok {
+		return value.NewDurationNil(), false
+	}
+	return age(end.In(start.Location()), start), true
+}
+
+// age the interval end - start, borrowing days from the month of start.
+func age(end, start time.Time) value.DurationValue {
+	if end.Before(start) {
+		return age(start, end).Neg()
+	}
+	clock := func(t time.Time) time.Duration {
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+			time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	}
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := end.Date()
+	dur := clock(end) - clock(start)
+	if dur < 0 {
+		dur += 24 * time.Hour
+		d2--
+	}
+	months := int64(y2-y1)*12 + int64(m2-m1)
+	days := int64(d2 - d1)
+	if days < 0 {
+		months--
+		// days in the month of start
+		days += int64(time.Date(y1, m1+1, 0, 0, 0, 0, 0, time.UTC).Day())
+	}
+	return value.NewIntervalValue(months, days, dur)
+}
@@ -24,10 +24,37 @@ type (
 		// function.
 		Validate(n *FuncNode) (EvaluatorFunc, error)
 	}
+	// ArgEvaluator evaluates one argument of a function when called, returns
+	// false if it could not be evaluated (missing field, type error).
+	ArgEvaluator func(ctx EvalContext) (value.Value, bool)
+	// LazyEvaluatorFunc is the evaluator func of a LazyFunc, it is passed
+	// un-evaluated args.
+	LazyEvaluatorFunc func(ctx EvalContext, args []ArgEvaluator) (value.Value, bool)
+	// LazyFunc is an optional interface for CustomFuncs that evaluate their own
+	// arguments.  The vm normally evaluates args first, passing nil for ones
+	// that can't be evaluated.  A LazyFunc sees which args failed, so may
+	// accept them (coalesce) and only evaluates the args it needs (if).
+	// The EvaluatorFunc from Validate is still used where the args are
+	// already values.
+	LazyFunc interface {
+		ValidateLazy(n *FuncNode) (LazyEvaluatorFunc, error)
+	}
 	// AggFunc allows custom functions to specify if they provide aggregation
 	AggFunc interface {
 		IsAgg() bool
 	}
+	// FuncArgTypes is an optional interface for CustomFuncs to declare the
+	// types of their arguments, used when type checking expressions.  The
+	// last type applies to any remaining (variadic) args.
+	FuncArgTypes interface {
+		ArgTypes() []value.ValueType
+	}
+	// FuncResultType is an optional interface for CustomFuncs whose return type
+	// follows the types of their args, ie abs(int) is an int, abs(number) a
+	// number.  Type() is the return type when arg types are unknown.
+	FuncResultType interface {
+		ResultType(argTypes []value.ValueType) value.ValueType
+	}
 	// FuncResolver is a function resolution interface that allows
 	// local/namespaced function resolution.
 	FuncResolver interface {
@@ -47,6 +74,17 @@ func EmptyEvalFunc(ctx EvalContext, args []value.Value) (value.Value, bool) {
 	return value.NilValueVal, false
 }
 
+// lenientFuncs a FuncResolver whose unknown functions are not a parse error.
+type lenientFuncs struct {
+	FuncResolver
+}
+
+// LenientFuncs resolve functions with fr before the global registry, but
+// unlike other resolvers a function that doesn't exist is not a parse error.
+func LenientFuncs(fr FuncResolver) FuncResolver {
+	return lenientFuncs{fr}
+}
+
 // NewFuncRegistry create a new function registry. By default their is a
 // global one, but you can have local function registries as well.
 func NewFuncRegistry() *FuncRegistry {
@@ -40,6 +40,34 @@ func inlineIncludesDepth(ctx Includer, arg Node, depth int) (Node, error) {
 	}
 
 	switch n := arg.(type) {
+	case *CaseNode:
+		// ChildrenArgs is a copy, so replace on the case itself
+		inline := func(narg Node) (Node, error) {
+			if narg == nil {
+				return nil, nil
+			}
+			newNode, err := inlineIncludesDepth(ctx, narg, depth+1)
+			if err != nil || newNode == nil {
+				return narg, err
+			}
+			return newNode, nil
+		}
+		var err error
+		if n.Arg, err = inline(n.Arg); err != nil {
+			return nil, err
+		}
+		for i := range n.Whens {
+			if n.Whens[i], err = inline(n.Whens[i]); err != nil {
+				return nil, err
+			}
+			if n.Thens[i], err = inline(n.Thens[i]); err != nil {
+				return nil, err
+			}
+		}
+		if n.Else, err = inline(n.Else); err != nil {
+			return nil, err
+		}
+		return arg, nil
 	// FuncNode, BinaryNode, BooleanNode, TriNode, UnaryNode, ArrayNode
 	case NodeArgs:
 		args := n.ChildrenArgs()
@@ -118,6 +146,10 @@ func findAllIncludes(node Node, current []string) []string {
 		for _, arg := range n.Args {
 			current = findAllIncludes(arg, current)
 		}
+	case *CaseNode:
+		for _, arg := range n.ChildrenArgs() {
+			current = findAllIncludes(arg, current)
+		}
 	}
 	return current
 }
@@ -57,6 +57,7 @@ var (
 	_ NodeArgs = (*FuncNode)(nil)
 	_ NodeArgs = (*UnaryNode)(nil)
 	_ NodeArgs = (*ArrayNode)(nil)
+	_ NodeArgs = (*CaseNode)(nil)
 )
 
 type (
@@ -136,6 +137,12 @@ type (
 		ContextReader
 		Includer
 	}
+	// EvalNullLogicContext is an optional EvalContext interface, if NullLogic()
+	// is true the vm evaluates expressions using sql three-valued NULL logic.
+	EvalNullLogicContext interface {
+		ContextReader
+		NullLogic() bool
+	}
 
 	// ContextReader is a key-value interface to read the context of message/row
 	// using a  Get("key") interface.  Used by vm to evaluate messages
@@ -200,11 +207,13 @@ type (
 	// FuncNode holds a Func, which desribes a go Function as
 	// well as fulfilling the Pos, String() etc for a Node
 	FuncNode struct {
-		Name    string        // Name of func
-		F       Func          // The actual function that this AST maps to
-		Eval    EvaluatorFunc // the evaluator function
-		Missing bool
-		Args    []Node // Arguments are them-selves nodes
+		Name     string            // Name of func
+		F        Func              // The actual function that this AST maps to
+		Eval     EvaluatorFunc     // the evaluator function
+		EvalLazy LazyEvaluatorFunc // the evaluator of a LazyFunc, evaluates its own args
+		Missing  bool
+		Args     []Node    // Arguments are them-selves nodes
+		Token    lex.Token // Name token, position in statement if parsed
 	}
 
 	// IdentityNode will look up a value out of a env bag also identities of
@@ -309,6 +318,21 @@ type (
 		wraptype string //  (   or [
 		Args     []Node
 	}
+
+	// CaseNode conditional expression, Whens are evaluated in order and the
+	// Then of the first match is the result.  A simple case has an Arg which
+	// is compared for equality to each When, a searched case has no Arg and
+	// each When is a boolean expression.
+	//
+	//    CASE WHEN <expr> THEN <expr> [WHEN <expr> THEN <expr>] [ELSE <expr>] END
+	//    CASE <expr> WHEN <expr> THEN <expr> [WHEN <expr> THEN <expr>] [ELSE <expr>] END
+	//
+	CaseNode struct {
+		Arg   Node   // optional, operand of simple case
+		Whens []Node // conditions, or values compared to Arg
+		Thens []Node // results, one per When
+		Else  Node   // optional, result if no When matches
+	}
 )
 
 // Includer defines an interface used for resolving INCLUDE clauses into a
@@ -395,6 +419,10 @@ func findIdentities(node Node, l IdentityNodes) IdentityNodes {
 		for _, arg := range n.Args {
 			l = findIdentities(arg, l)
 		}
+	case *CaseNode:
+		for _, arg := range n.ChildrenArgs() {
+			l = findIdentities(arg, l)
+		}
 	}
 	return l
 }
@@ -489,6 +517,13 @@ func ValueTypeFromNode(n Node) value.ValueType {
 		if nt.F.CustomFunc == nil {
 			return value.UnknownType
 		}
+		if frt, ok := nt.F.CustomFunc.(FuncResultType); ok {
+			argTypes := make([]value.ValueType, len(nt.Args))
+			for i, arg := range nt.Args {
+				argTypes[i] = ValueTypeFromNode(arg)
+			}
+			return frt.ResultType(argTypes)
+		}
 		return nt.F.Type()
 	case *StringNode:
 		return value.StringType
@@ -506,7 +541,8 @@ func ValueTypeFromNode(n Node) value.ValueType {
 			return value.BoolType
 		case lex.TokenMultiply, lex.TokenMinus, lex.TokenAdd, lex.TokenDivide:
 			return value.NumberType
-		case lex.TokenModulus:
+		case lex.TokenModulus, lex.TokenBitAnd, lex.TokenBitOr, lex.TokenBitXor,
+			lex.TokenShiftLeft, lex.TokenShiftRight:
 			return value.IntType
 		case lex.TokenLT, lex.TokenLE, lex.TokenGT, lex.TokenGE:
 			return value.BoolType
@@ -550,6 +586,11 @@ func (m *FuncNode) Validate() error {
 		}
 
 		m.Eval = ev
+		if lf, ok := m.F.CustomFunc.(LazyFunc); ok {
+			if m.EvalLazy, err = lf.ValidateLazy(m); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
@@ -638,6 +679,9 @@ func (m *FuncNode) FromExpr(e *Expr) error {
 	if m.Eval == nil {
 		m.Eval = fn.Eval
 	}
+	if m.EvalLazy == nil {
+		m.EvalLazy = fn.EvalLazy
+	}
 	return nil
 }
 func (m *FuncNode) Equal(n Node) bool {
@@ -875,6 +919,8 @@ func (m *ValueNode) String() string {
 			vals[i] = fmt.Sprintf("%q", v.ToString())
 		}
 		return fmt.Sprintf("[%s]", strings.Join(vals, ", "))
+	case value.DurationValue:
+		return fmt.Sprintf("INTERVAL %q", vt.ToString())
 	}
 	return m.Value.ToString()
 }
@@ -904,6 +950,11 @@ func (m *ValueNode) WriteDialect(w DialectWriter) {
 		w.WriteNumber(vt.ToString())
 	case value.NumberValue:
 		w.WriteNumber(vt.ToString())
+	case value.DecimalValue:
+		w.WriteNumber(vt.ToString())
+	case value.DurationValue:
+		io.WriteString(w, "INTERVAL ")
+		w.WriteLiteral(vt.ToString())
 	case value.BoolValue:
 		w.WriteLiteral(vt.ToString())
 	default:
@@ -913,10 +964,41 @@ func (m *ValueNode) WriteDialect(w DialectWriter) {
 }
 func (m *ValueNode) Validate() error { return nil }
 func (m *ValueNode) NodePb() *NodePb {
+	switch vt := m.Value.(type) {
+	case value.DecimalValue, value.DurationValue, value.StringValue, value.IntValue, value.NumberValue,
+		value.BoolValue:
+		n := &ValueNodePb{Valuetype: int32(vt.Type()), Value: []byte(vt.ToString())}
+		return &NodePb{Vn: n}
+	}
 	u.Errorf("Not implemented %#v", m)
 	return nil
 }
 func (m *ValueNode) FromPB(n *NodePb) Node {
+	s := string(n.Vn.Value)
+	switch value.ValueType(n.Vn.Valuetype) {
+	case value.DecimalType:
+		if dv, err := value.NewDecimalFromString(s); err == nil {
+			return NewValueNode(dv)
+		}
+	case value.DurationType:
+		if dv, err := value.ParseDuration(s); err == nil {
+			return NewValueNode(dv)
+		}
+	case value.StringType:
+		return NewValueNode(value.NewStringValue(s))
+	case value.IntType:
+		if iv, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return NewValueNode(value.NewIntValue(iv))
+		}
+	case value.NumberType:
+		if fv, err := strconv.ParseFloat(s, 64); err == nil {
+			return NewValueNode(value.NewNumberValue(fv))
+		}
+	case value.BoolType:
+		if bv, err := strconv.ParseBool(s); err == nil {
+			return NewValueNode(value.NewBoolValue(bv))
+		}
+	}
 	u.Errorf("Not implemented %#v", n)
 	return &ValueNode{}
 }
@@ -1211,6 +1293,39 @@ func NewBinaryNode(operator lex.Token, lhArg, rhArg Node) *BinaryNode {
 	//u.Debugf("NewBinaryNode: %v %v %v", lhArg, operator, rhArg)
 	return &BinaryNode{Args: []Node{lhArg, rhArg}, Operator: operator}
 }
+
+// operators of x IS NULL, x IS NOT NULL which are the = and != tokens
+const (
+	opIs    = "IS"
+	opIsNot = "IS NOT"
+)
+
+// isNullToken the IS or IS NOT operator of an = or != token.
+func isNullToken(t lex.TokenType) lex.Token {
+	if t == lex.TokenNE {
+		return lex.Token{T: t, V: opIsNot}
+	}
+	return lex.Token{T: t, V: opIs}
+}
+
+// IsNullTest the node is x IS NULL or x IS NOT NULL.  They are the same as
+// x = NULL, x != NULL under the vm default rules, but under sql null logic
+// test for NULL where a comparison with NULL is NULL.
+func (m *BinaryNode) IsNullTest() bool {
+	if len(m.Args) != 2 {
+		return false
+	}
+	if _, isNull := m.Args[1].(*NullNode); !isNull {
+		return false
+	}
+	switch strings.ToUpper(m.Operator.V) {
+	case opIs:
+		return m.Operator.T == lex.TokenEqual
+	case opIsNot:
+		return m.Operator.T == lex.TokenNE
+	}
+	return false
+}
 func (m *BinaryNode) NodeType() string { return "Binary" }
 func (m *BinaryNode) String() string {
 	w := NewDefaultWriter()
@@ -1231,7 +1346,13 @@ func (m *BinaryNode) writeToString(w DialectWriter, negate string) {
 	}
 	m.Args[0].WriteDialect(w)
 	io.WriteString(w, " ")
-	if len(negate) > 0 {
+	if len(negate) > 0 && m.IsNullTest() {
+		if m.Operator.T == lex.TokenEqual {
+			io.WriteString(w, opIsNot)
+		} else {
+			io.WriteString(w, opIs)
+		}
+	} else if len(negate) > 0 {
 		switch m.Operator.T {
 		case lex.TokenEqual, lex.TokenEqualEqual:
 			io.WriteString(w, lex.TokenNE.String())
@@ -1332,12 +1453,20 @@ func (m *BinaryNode) NodePb() *NodePb {
 	n := &BinaryNodePb{}
 	n.Paren = m.Paren
 	n.Op = int32(m.Operator.T)
+	if m.IsNullTest() {
+		// IS NULL, IS NOT NULL are the negative of their token
+		n.Op = -n.Op
+	}
 	n.Args = []NodePb{*m.Args[0].NodePb(), *m.Args[1].NodePb()}
 	return &NodePb{Bn: n}
 }
 func (m *BinaryNode) FromPB(n *NodePb) Node {
+	op := tokenFromInt(n.Bn.Op)
+	if n.Bn.Op < 0 {
+		op = isNullToken(lex.TokenType(-n.Bn.Op))
+	}
 	return &BinaryNode{
-		Operator: tokenFromInt(n.Bn.Op),
+		Operator: op,
 		Paren:    n.Bn.Paren,
 		Args:     NodesFromNodesPb(n.Bn.Args),
 	}
@@ -1356,7 +1485,14 @@ func (m *BinaryNode) FromExpr(e *Expr) error {
 	if e.Op == "" {
 		return fmt.Errorf("unrecognized BinaryNode")
 	}
-	m.Operator = lex.TokenFromOp(e.Op)
+	switch strings.ToUpper(e.Op) {
+	case opIs:
+		m.Operator = isNullToken(lex.TokenEqual)
+	case opIsNot:
+		m.Operator = isNullToken(lex.TokenNE)
+	default:
+		m.Operator = lex.TokenFromOp(e.Op)
+	}
 	if len(e.Args) == 0 {
 		return fmt.Errorf("Invalid BinaryNode, expected args %+v", e)
 	}
@@ -1573,7 +1709,7 @@ func (m *TriNode) WriteNegate(w DialectWriter) {
 	m.writeToString(w, true)
 }
 func (m *TriNode) WriteDialect(w DialectWriter) {
-	m.writeToString(w, false)
+	m.writeToString(w, m.negated)
 }
 func (m *TriNode) writeToString(w DialectWriter, negate bool) {
 	m.Args[0].WriteDialect(w)
@@ -2018,6 +2154,175 @@ func (m *ArrayNode) Equal(n Node) bool {
 	return false
 }
 
+// NewCaseNode create a case node, arg is nil for a searched case.
+func NewCaseNode(arg Node) *CaseNode {
+	return &CaseNode{Arg: arg}
+}
+func (m *CaseNode) NodeType() string { return "Case" }
+
+// When append a WHEN <when> THEN <then> pair.
+func (m *CaseNode) When(when, then Node) {
+	m.Whens = append(m.Whens, when)
+	m.Thens = append(m.Thens, then)
+}
+func (m *CaseNode) String() string {
+	w := NewDefaultWriter()
+	m.WriteDialect(w)
+	return w.String()
+}
+func (m *CaseNode) WriteDialect(w DialectWriter) {
+	io.WriteString(w, "CASE")
+	if m.Arg != nil {
+		io.WriteString(w, " ")
+		m.Arg.WriteDialect(w)
+	}
+	for i, when := range m.Whens {
+		io.WriteString(w, " WHEN ")
+		when.WriteDialect(w)
+		io.WriteString(w, " THEN ")
+		m.Thens[i].WriteDialect(w)
+	}
+	if m.Else != nil {
+		io.WriteString(w, " ELSE ")
+		m.Else.WriteDialect(w)
+	}
+	io.WriteString(w, " END")
+}
+func (m *CaseNode) Validate() error {
+	if len(m.Whens) == 0 {
+		return fmt.Errorf("CASE requires at least one WHEN")
+	}
+	if len(m.Whens) != len(m.Thens) {
+		return fmt.Errorf("CASE expected a THEN for each WHEN")
+	}
+	for _, n := range m.ChildrenArgs() {
+		if err := n.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChildrenArgs all child nodes, Arg, When/Then pairs, Else.
+func (m *CaseNode) ChildrenArgs() []Node {
+	args := make([]Node, 0, len(m.Whens)*2+2)
+	if m.Arg != nil {
+		args = append(args, m.Arg)
+	}
+	for i, when := range m.Whens {
+		args = append(args, when, m.Thens[i])
+	}
+	if m.Else != nil {
+		args = append(args, m.Else)
+	}
+	return args
+}
+func (m *CaseNode) NodePb() *NodePb {
+	n := &CaseNodePb{
+		Whens: make([]NodePb, len(m.Whens)),
+		Thens: make([]NodePb, len(m.Thens)),
+	}
+	if m.Arg != nil {
+		n.Arg = m.Arg.NodePb()
+	}
+	for i, when := range m.Whens {
+		n.Whens[i] = *when.NodePb()
+	}
+	for i, then := range m.Thens {
+		n.Thens[i] = *then.NodePb()
+	}
+	if m.Else != nil {
+		n.Else = m.Else.NodePb()
+	}
+	return &NodePb{Cn: n}
+}
+func (m *CaseNode) FromPB(n *NodePb) Node {
+	return &CaseNode{
+		Arg:   NodeFromNodePb(n.Cn.Arg),
+		Whens: NodesFromNodesPb(n.Cn.Whens),
+		Thens: NodesFromNodesPb(n.Cn.Thens),
+		Else:  NodeFromNodePb(n.Cn.Else),
+	}
+}
+
+// Expr json representation of case, the Arg, When/Then pairs
+// and Else are each wrapped in an expression of their own.
+//
+//    {"op":"case","args":[{"op":"when","args":[<when>,<then>]},{"op":"else","args":[<else>]}]}
+func (m *CaseNode) Expr() *Expr {
+	fe := &Expr{Op: "case"}
+	if m.Arg != nil {
+		fe.Args = append(fe.Args, &Expr{Op: "arg", Args: []*Expr{m.Arg.Expr()}})
+	}
+	for i, when := range m.Whens {
+		fe.Args = append(fe.Args, &Expr{Op: "when", Args: []*Expr{when.Expr(), m.Thens[i].Expr()}})
+	}
+	if m.Else != nil {
+		fe.Args = append(fe.Args, &Expr{Op: "else", Args: []*Expr{m.Else.Expr()}})
+	}
+	return fe
+}
+func (m *CaseNode) FromExpr(e *Expr) error {
+	if strings.ToLower(e.Op) != "case" {
+		return fmt.Errorf("Invalid CaseNode op %q", e.Op)
+	}
+	for _, arg := range e.Args {
+		args, err := NodesFromExprs(arg.Args)
+		if err != nil {
+			return err
+		}
+		switch {
+		case arg.Op == "arg" && len(args) == 1:
+			m.Arg = args[0]
+		case arg.Op == "when" && len(args) == 2:
+			m.When(args[0], args[1])
+		case arg.Op == "else" && len(args) == 1:
+			m.Else = args[0]
+		default:
+			return fmt.Errorf("Invalid CaseNode arg %+v", arg)
+		}
+	}
+	if len(m.Whens) == 0 {
+		return fmt.Errorf("Invalid CaseNode, expected when %+v", e)
+	}
+	return nil
+}
+func (m *CaseNode) Equal(n Node) bool {
+	if m == nil && n == nil {
+		return true
+	}
+	if m == nil && n != nil {
+		return false
+	}
+	if m != nil && n == nil {
+		return false
+	}
+	nt, ok := n.(*CaseNode)
+	if !ok {
+		return false
+	}
+	if len(m.Whens) != len(nt.Whens) {
+		return false
+	}
+	if !equalOptional(m.Arg, nt.Arg) || !equalOptional(m.Else, nt.Else) {
+		return false
+	}
+	for i, when := range nt.Whens {
+		if !when.Equal(m.Whens[i]) || !nt.Thens[i].Equal(m.Thens[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalOptional compare two optional (possibly nil) nodes.
+func equalOptional(n1, n2 Node) bool {
+	if n1 == nil || n2 == nil {
+		return n1 == nil && n2 == nil
+	}
+	return n1.Equal(n2)
+}
+
 // Node serialization helpers
 func tokenFromInt(iv int32) lex.Token {
 	t, ok := lex.TokenNameMap[lex.TokenType(iv)]
@@ -2075,6 +2380,9 @@ func NodeFromNodePb(n *NodePb) Node {
 	case n.Incn != nil:
 		var in *IncludeNode
 		return in.FromPB(n)
+	case n.Cn != nil:
+		var cn *CaseNode
+		return cn.FromPB(n)
 	case n.Niln != nil:
 		return &NullNode{}
 	}
@@ -2201,8 +2509,11 @@ func NodeFromExpr(e *Expr) (Node, error) {
 			n = &UnaryNode{}
 		case "BETWEEN":
 			n = &TriNode{}
+		case "CASE":
+			n = &CaseNode{}
 		case "=", "-", "+", "++", "+=", "/", "%", "==", "<=", "!=", ">=", ">", "<", "*",
-			"LIKE", "CONTAINS", "INTERSECTS", "IN":
+			"LIKE", "CONTAINS", "INTERSECTS", "IN", "IS DISTINCT FROM", "IS NOT DISTINCT FROM",
+			"RLIKE", "REGEXP", "~", "~*", "&", "|", "^", "<<", ">>", opIs, opIsNot:
 
 			// very weird special case for FILTER * where the * is an ident not op
 			if e.Op == "*" && len(e.Args) == 0 {
@@ -3,6 +3,7 @@ package expr_test
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	u "github.com/araddon/gou"
 	"github.com/gogo/protobuf/proto"
@@ -22,6 +23,13 @@ var pbTests = []string{
 	`AND ( EXISTS x, INCLUDE ref_name )`,
 	`company = "Toys R"" Us"`,
 	`providers.id != NULL`,
+	`x IS NULL AND y IS NOT NULL`,
+	`CASE WHEN x > 5 THEN "big" WHEN x > 1 THEN "medium" ELSE "small" END`,
+	`CASE tolower(x) WHEN "a" THEN 1 END`,
+	`x IS NOT DISTINCT FROM y`,
+	`email RLIKE "^bob" OR name ~* "x"`,
+	`cast(price AS DECIMAL(10,2)) * 2`,
+	`created > now() - INTERVAL 3 DAY`,
 }
 
 func TestNodePb(t *testing.T) {
@@ -40,6 +48,27 @@ func TestNodePb(t *testing.T) {
 	}
 }
 
+func TestValueNodePb(t *testing.T) {
+	t.Parallel()
+	vals := []value.Value{
+		value.NewDecimalValue(-123456, 3),
+		value.NewStringValue("hello"),
+		value.NewIntValue(42),
+		value.NewNumberValue(3.25),
+		value.NewBoolValue(true),
+		value.NewIntervalValue(1, 3, 2*time.Hour),
+	}
+	for _, v := range vals {
+		vn := expr.NewValueNode(v)
+		pbBytes, err := proto.Marshal(vn.NodePb())
+		assert.Equal(t, nil, err)
+		n2, err := expr.NodeFromPb(pbBytes)
+		assert.Equal(t, nil, err)
+		assert.True(t, vn.Equal(n2), "Expected Equal but got %v for %v", n2, vn)
+		assert.Equal(t, v.Type(), n2.(*expr.ValueNode).Value.Type())
+	}
+}
+
 func TestExprRoundTrip(t *testing.T) {
 	t.Parallel()
 	for _, et := range exprTests {
@@ -0,0 +1,691 @@
+package expr
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/araddon/qlbridge/lex"
+)
+
+// maxNormalizePasses bounds the rewrite passes of Normalize, each pass may
+// expose more to simplify (factoring a list makes new lists).
+const maxNormalizePasses = 10
+
+var (
+	tokenEqual = lex.Token{T: lex.TokenEqual, V: "="}
+	tokenIn    = lex.Token{T: lex.TokenIN, V: "IN"}
+
+	// flippedOps the comparison with its args swapped, 5 < x => x > 5
+	flippedOps = map[lex.TokenType]lex.Token{
+		lex.TokenEqual: tokenEqual,
+		lex.TokenNE:    {T: lex.TokenNE, V: "!="},
+		lex.TokenGT:    {T: lex.TokenLT, V: "<"},
+		lex.TokenGE:    {T: lex.TokenLE, V: "<="},
+		lex.TokenLT:    {T: lex.TokenGT, V: ">"},
+		lex.TokenLE:    {T: lex.TokenGE, V: ">="},
+	}
+)
+
+// Normalize rewrite a boolean expression into a canonical form, so that
+// equivalent filters are written, and fingerprinted, the same.
+//
+//   - AND/OR are BooleanNode lists, nested lists of the same operator are
+//     flattened, args de-duplicated and sorted
+//   - NOT NOT x => x, otherwise negated expressions are left as written as
+//     under the vm default rules a missing or nil field is neither true
+//     nor false, NOT (x > 5) is not x <= 5.  See NormalizeNullLogic.
+//   - literal arithmetic and comparisons are folded, true/false args of
+//     lists are removed or decide the list
+//   - comparisons of an identity to numbers are merged into a range,
+//     x > 5 AND x > 3 => x > 5, an empty range is false, BETWEEN is
+//     exclusive as in the vm.  Equalities OR'd are an IN list, IN lists
+//     are de-duplicated and sorted
+//   - common args are absorbed, a OR (a AND b) => a, and factored out
+//     OR ( AND (a, b), AND (a, c) ) => AND ( a, OR (b, c) )
+//
+// The nodes of n are not changed, rewritten nodes are new.
+func Normalize(n Node) Node {
+	return normalizeNot(n, false)
+}
+
+// NormalizeNullLogic normalize an expression that is evaluated with sql
+// NULL logic (see vm.NewNullLogicContext), as Normalize but NOT is also
+// pushed down through AND/OR lists (De Morgan) to the comparisons.
+//
+//	NOT AND ( x = 1, y > 2 ) => OR ( NOT (x = 1), NOT (y > 2) )
+func NormalizeNullLogic(n Node) Node {
+	return normalizeNot(n, true)
+}
+
+func normalizeNot(n Node, nullLogic bool) Node {
+	if n == nil {
+		return nil
+	}
+	n = BinaryToBoolean(n)
+	prev := ""
+	for i := 0; i < maxNormalizePasses; i++ {
+		n = normalize(pushNot(n, false, nullLogic))
+		s := n.String()
+		if s == prev {
+			break
+		}
+		prev = s
+	}
+	return n
+}
+
+func boolIdentity(v bool) *IdentityNode {
+	if v {
+		return NewIdentityNodeVal("true")
+	}
+	return NewIdentityNodeVal("false")
+}
+
+// isBool is n the identity true/false
+func isBool(n Node) (bool, bool) {
+	if in, ok := n.(*IdentityNode); ok && in.IsBooleanIdentity() {
+		return in.Bool(), true
+	}
+	return false, false
+}
+
+// pushNot push negation down, neg is whether n is negated.  Only double
+// negation is removed unless nullLogic, where lists are negated by De
+// Morgan.  Negated comparisons and NegateableNodes are wrapped in NOT, as
+// the vm evaluates their own negation differently for missing fields.
+func pushNot(n Node, neg, nullLogic bool) Node {
+	switch t := n.(type) {
+	case *BooleanNode:
+		neg = neg != t.negated
+		if neg && !nullLogic {
+			if t.negated {
+				return t
+			}
+			bn := NewBooleanNode(logicalToken(t.Operator.T), t.Args...)
+			bn.negated = true
+			return bn
+		}
+		op := t.Operator.T
+		if neg {
+			op = lex.TokenLogicAnd
+			if t.Operator.T == lex.TokenLogicAnd {
+				op = lex.TokenLogicOr
+			}
+		}
+		bn := NewBooleanNode(logicalToken(op))
+		for _, a := range t.Args {
+			bn.Args = append(bn.Args, pushNot(a, neg, nullLogic))
+		}
+		return bn
+	case *UnaryNode:
+		if t.Operator.T == lex.TokenNegate {
+			return pushNot(t.Arg, !neg, nullLogic)
+		}
+		un := &UnaryNode{Operator: t.Operator, Arg: pushNot(t.Arg, false, nullLogic)}
+		if neg {
+			return &UnaryNode{Operator: tokenNegate, Arg: un}
+		}
+		return un
+	case *IdentityNode:
+		if v, ok := isBool(t); ok && neg {
+			return boolIdentity(!v)
+		}
+	}
+	if neg {
+		return &UnaryNode{Operator: tokenNegate, Arg: n}
+	}
+	return n
+}
+
+// normalize simplify n bottom up, negated expressions are not changed
+func normalize(n Node) Node {
+	switch t := n.(type) {
+	case *BooleanNode:
+		if t.negated {
+			return t
+		}
+		args := make([]Node, 0, len(t.Args))
+		for _, a := range t.Args {
+			a = normalize(a)
+			if an, ok := a.(*BooleanNode); ok && !an.negated && an.Operator.T == t.Operator.T {
+				args = append(args, an.Args...)
+				continue
+			}
+			args = append(args, a)
+		}
+		return simplifyList(t.Operator.T == lex.TokenLogicAnd, args)
+	case *BinaryNode:
+		args := make([]Node, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = normalize(a)
+		}
+		return foldBinary(&BinaryNode{Operator: t.Operator, Paren: t.Paren, Args: args, negated: t.negated})
+	case *UnaryNode:
+		if t.Operator.T != lex.TokenNegate {
+			return &UnaryNode{Operator: t.Operator, Arg: normalize(t.Arg)}
+		}
+		arg := t.Arg
+		if v, ok := isBool(arg); ok {
+			return boolIdentity(!v)
+		}
+		if bn, ok := arg.(*BinaryNode); ok && !bn.Paren {
+			arg = &BinaryNode{Operator: bn.Operator, Paren: true, Args: bn.Args, negated: bn.negated}
+		}
+		return &UnaryNode{Operator: t.Operator, Arg: arg}
+	case *TriNode:
+		args := make([]Node, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = normalize(a)
+		}
+		return &TriNode{Operator: t.Operator, Args: args, negated: t.negated}
+	}
+	return n
+}
+
+// isComparison operators of a comparison not arithmetic
+func isComparison(t lex.TokenType) bool {
+	switch t {
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
+		lex.TokenLT, lex.TokenLE, lex.TokenIN, lex.TokenLike, lex.TokenContains,
+		lex.TokenIntersects:
+		return true
+	}
+	return false
+}
+
+// isLiteral number and string nodes
+func isLiteral(n Node) bool {
+	switch n.(type) {
+	case *NumberNode, *StringNode:
+		return true
+	}
+	return false
+}
+
+// foldBinary fold the literals of a binary, and write comparisons as
+// identity op literal
+func foldBinary(bn *BinaryNode) Node {
+	if bn.Operator.T == lex.TokenEqualEqual {
+		bn.Operator = tokenEqual
+	}
+	if isComparison(bn.Operator.T) {
+		bn.Paren = false
+	}
+	left, right := bn.Args[0], bn.Args[1]
+	if isLiteral(left) && isLiteral(right) {
+		if folded := foldLiterals(bn.Operator.T, left, right); folded != nil {
+			return folded
+		}
+	}
+	if op, ok := flippedOps[bn.Operator.T]; ok && isLiteral(left) && !isLiteral(right) {
+		return &BinaryNode{Operator: op, Args: []Node{right, left}}
+	}
+	if bn.Operator.T == lex.TokenIN && !bn.negated {
+		if an, ok := right.(*ArrayNode); ok {
+			vals, ok := literalSet(nil, an.Args)
+			switch {
+			case !ok:
+			case len(vals) == 0:
+				return boolIdentity(false)
+			case len(vals) == 1:
+				return &BinaryNode{Operator: tokenEqual, Args: []Node{left, vals[0]}}
+			default:
+				return &BinaryNode{Operator: bn.Operator, Args: []Node{left, NewArrayNodeArgs(vals)}}
+			}
+		}
+	}
+	return bn
+}
+
+// foldLiterals the result of an operator on two literals, nil if it can't
+func foldLiterals(op lex.TokenType, left, right Node) Node {
+	ln, lok := left.(*NumberNode)
+	rn, rok := right.(*NumberNode)
+	if lok && rok {
+		if ln.IsInt && rn.IsInt {
+			l, r := ln.Int64, rn.Int64
+			switch op {
+			case lex.TokenPlus:
+				return intNode(l + r)
+			case lex.TokenMinus:
+				return intNode(l - r)
+			case lex.TokenMultiply, lex.TokenStar:
+				return intNode(l * r)
+			case lex.TokenDivide:
+				if r != 0 {
+					return intNode(l / r)
+				}
+				return nil
+			case lex.TokenModulus:
+				if r != 0 {
+					return intNode(l % r)
+				}
+				return nil
+			}
+		}
+		l, r := ln.Float64, rn.Float64
+		switch op {
+		case lex.TokenPlus:
+			return floatNode(l + r)
+		case lex.TokenMinus:
+			return floatNode(l - r)
+		case lex.TokenMultiply, lex.TokenStar:
+			return floatNode(l * r)
+		case lex.TokenDivide:
+			if r != 0 {
+				return floatNode(l / r)
+			}
+			return nil
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			return boolIdentity(l == r)
+		case lex.TokenNE:
+			return boolIdentity(l != r)
+		case lex.TokenGT:
+			return boolIdentity(l > r)
+		case lex.TokenGE:
+			return boolIdentity(l >= r)
+		case lex.TokenLT:
+			return boolIdentity(l < r)
+		case lex.TokenLE:
+			return boolIdentity(l <= r)
+		}
+		return nil
+	}
+	ls, lok := left.(*StringNode)
+	rs, rok := right.(*StringNode)
+	if lok && rok {
+		switch op {
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			return boolIdentity(ls.Text == rs.Text)
+		case lex.TokenNE:
+			return boolIdentity(ls.Text != rs.Text)
+		}
+	}
+	return nil
+}
+
+func intNode(v int64) Node {
+	nn, err := NewNumberStr(strconv.FormatInt(v, 10))
+	if err != nil {
+		return nil
+	}
+	return nn
+}
+
+func floatNode(v float64) Node {
+	nn, err := NewNumber(v)
+	if err != nil {
+		return nil
+	}
+	return nn
+}
+
+// literalSet add the literal args to vals, de-duplicated and sorted, false
+// if an arg is not a literal
+func literalSet(vals []Node, args []Node) ([]Node, bool) {
+	seen := make(map[string]bool, len(vals)+len(args))
+	out := make([]Node, 0, len(vals)+len(args))
+	for _, a := range append(append([]Node{}, vals...), args...) {
+		if !isLiteral(a) {
+			return nil, false
+		}
+		if k := a.String(); !seen[k] {
+			seen[k] = true
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return literalLess(out[i], out[j]) })
+	return out, true
+}
+
+// literalLess order numbers by value ahead of strings
+func literalLess(a, b Node) bool {
+	an, aNum := a.(*NumberNode)
+	bn, bNum := b.(*NumberNode)
+	switch {
+	case aNum && bNum:
+		return an.Float64 < bn.Float64
+	case aNum != bNum:
+		return aNum
+	}
+	return a.String() < b.String()
+}
+
+// simplifyList simplify the args of an AND (isAnd) or OR list
+func simplifyList(isAnd bool, args []Node) Node {
+	// true/false, AND (false, ..) => false, AND (true, x) => x
+	out := make([]Node, 0, len(args))
+	for _, a := range args {
+		if v, ok := isBool(a); ok {
+			if v != isAnd {
+				return boolIdentity(v)
+			}
+			continue
+		}
+		out = append(out, a)
+	}
+
+	out, decided := mergeComparisons(isAnd, out)
+	if decided != nil {
+		return decided
+	}
+	out = dedupe(out)
+	out = absorb(isAnd, out)
+	if factored := factor(isAnd, out); factored != nil {
+		return factored
+	}
+
+	switch len(out) {
+	case 0:
+		return boolIdentity(isAnd)
+	case 1:
+		return out[0]
+	}
+	op := tokenOr
+	if isAnd {
+		op = tokenAnd
+	}
+	return NewBooleanNode(op, out...)
+}
+
+// dedupe remove duplicate args and sort them
+func dedupe(args []Node) []Node {
+	seen := make(map[string]bool, len(args))
+	out := args[:0]
+	for _, a := range args {
+		if k := a.String(); !seen[k] {
+			seen[k] = true
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].String() < out[j].String() })
+	return out
+}
+
+// listArgs the args of a list of the operator that isn't isAnd (the
+// lists of an AND are ORs), or the node itself as a list of one
+func listArgs(isAnd bool, n Node) []Node {
+	if bn, ok := n.(*BooleanNode); ok && (bn.Operator.T == lex.TokenLogicAnd) != isAnd {
+		return bn.Args
+	}
+	return []Node{n}
+}
+
+// absorb drop args that are a list containing another arg
+//
+//	AND ( a, OR ( a, b ) ) => a
+func absorb(isAnd bool, args []Node) []Node {
+	keys := make(map[string]bool, len(args))
+	for _, a := range args {
+		keys[a.String()] = true
+	}
+	out := make([]Node, 0, len(args))
+	for _, a := range args {
+		absorbed := false
+		if inner := listArgs(isAnd, a); len(inner) > 1 {
+			for _, ia := range inner {
+				if keys[ia.String()] {
+					absorbed = true
+					break
+				}
+			}
+		}
+		if !absorbed {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// factor the args common to each of the lists of a list
+//
+//	OR ( AND (a, b), AND (a, c) ) => AND ( a, OR (b, c) )
+func factor(isAnd bool, args []Node) Node {
+	if len(args) < 2 {
+		return nil
+	}
+	counts := make(map[string]int)
+	common := make(map[string]Node)
+	for _, a := range args {
+		for _, ia := range listArgs(isAnd, a) {
+			k := ia.String()
+			counts[k]++
+			common[k] = ia
+		}
+	}
+	shared := make([]Node, 0)
+	for k, ct := range counts {
+		if ct == len(args) {
+			shared = append(shared, common[k])
+		}
+	}
+	if len(shared) == 0 {
+		return nil
+	}
+	isShared := make(map[string]bool, len(shared))
+	for _, s := range shared {
+		isShared[s.String()] = true
+	}
+	inner := tokenAnd
+	if isAnd {
+		inner = tokenOr
+	}
+	rest := make([]Node, 0, len(args))
+	for _, a := range args {
+		remain := make([]Node, 0)
+		for _, ia := range listArgs(isAnd, a) {
+			if !isShared[ia.String()] {
+				remain = append(remain, ia)
+			}
+		}
+		switch len(remain) {
+		case 0:
+			// this arg is only the shared, which absorbs the rest
+			return NewBooleanNode(inner, shared...)
+		case 1:
+			rest = append(rest, remain[0])
+		default:
+			rest = append(rest, NewBooleanNode(inner, remain...))
+		}
+	}
+	outer := tokenOr
+	if isAnd {
+		outer = tokenAnd
+	}
+	return NewBooleanNode(inner, append(shared, NewBooleanNode(outer, rest...))...)
+}
+
+// bound one end of a range of numbers
+type bound struct {
+	val *NumberNode
+	inc bool // inclusive
+}
+
+// numRange the numbers an identity is compared to in a list
+type numRange struct {
+	id     *IdentityNode
+	lo, hi *bound
+	eqs    []Node // OR'd equalities, IN
+	ct     int
+}
+
+// rangeOf the identity and bound of a comparison of an identity to
+// numbers (or an OR'd equality/IN of literals), nil if it isn't one
+func rangeOf(n Node, isAnd bool) (id *IdentityNode, lo, hi *bound, eqs []Node) {
+	switch t := n.(type) {
+	case *BinaryNode:
+		in, ok := t.Args[0].(*IdentityNode)
+		if !ok || in.IsBooleanIdentity() {
+			return nil, nil, nil, nil
+		}
+		if t.Operator.T == lex.TokenIN {
+			if an, ok := t.Args[1].(*ArrayNode); ok && !isAnd {
+				if vals, ok := literalSet(nil, an.Args); ok {
+					return in, nil, nil, vals
+				}
+			}
+			return nil, nil, nil, nil
+		}
+		if t.Operator.T == lex.TokenEqual && !isAnd && isLiteral(t.Args[1]) {
+			return in, nil, nil, []Node{t.Args[1]}
+		}
+		num, ok := t.Args[1].(*NumberNode)
+		if !ok {
+			return nil, nil, nil, nil
+		}
+		switch t.Operator.T {
+		case lex.TokenEqual:
+			return in, &bound{num, true}, &bound{num, true}, nil
+		case lex.TokenGT:
+			return in, &bound{num, false}, nil, nil
+		case lex.TokenGE:
+			return in, &bound{num, true}, nil, nil
+		case lex.TokenLT:
+			return in, nil, &bound{num, false}, nil
+		case lex.TokenLE:
+			return in, nil, &bound{num, true}, nil
+		}
+	case *TriNode:
+		if t.negated || !isAnd || t.Operator.T != lex.TokenBetween {
+			return nil, nil, nil, nil
+		}
+		in, ok := t.Args[0].(*IdentityNode)
+		lo, lok := t.Args[1].(*NumberNode)
+		hi, hok := t.Args[2].(*NumberNode)
+		if ok && lok && hok {
+			return in, &bound{lo, false}, &bound{hi, false}, nil
+		}
+	}
+	return nil, nil, nil, nil
+}
+
+// tighter is a a tighter lower (or upper if !lower) bound than b
+func tighter(a, b *bound, lower bool) bool {
+	if b == nil {
+		return true
+	}
+	if a.val.Float64 == b.val.Float64 {
+		return !a.inc && b.inc
+	}
+	return (a.val.Float64 > b.val.Float64) == lower
+}
+
+// mergeComparisons merge the comparisons of each identity to numbers.  In
+// an AND the ranges are intersected, an empty range decides the list is
+// false.  In an OR half ranges of the same direction are unioned and
+// equalities are IN lists.
+func mergeComparisons(isAnd bool, args []Node) ([]Node, Node) {
+	ranges := make(map[string]*numRange)
+	out := make([]Node, 0, len(args))
+	for _, a := range args {
+		id, lo, hi, eqs := rangeOf(a, isAnd)
+		if id == nil || (!isAnd && lo != nil && hi != nil) {
+			out = append(out, a)
+			continue
+		}
+		r := ranges[id.String()]
+		if r == nil {
+			r = &numRange{id: id}
+			ranges[id.String()] = r
+			// placeholder for the merged comparisons
+			out = append(out, r.id)
+		}
+		r.ct++
+		if eqs != nil {
+			r.eqs = append(r.eqs, eqs...)
+			continue
+		}
+		if isAnd {
+			if lo != nil && tighter(lo, r.lo, true) {
+				r.lo = lo
+			}
+			if hi != nil && tighter(hi, r.hi, false) {
+				r.hi = hi
+			}
+			continue
+		}
+		// OR, the union is the looser bound
+		if lo != nil && (r.lo == nil || tighter(r.lo, lo, true)) {
+			r.lo = lo
+		}
+		if hi != nil && (r.hi == nil || tighter(r.hi, hi, false)) {
+			r.hi = hi
+		}
+	}
+
+	merged := make([]Node, 0, len(out))
+	for _, a := range out {
+		in, ok := a.(*IdentityNode)
+		r := ranges[a.String()]
+		if !ok || r == nil || r.id != in {
+			merged = append(merged, a)
+			continue
+		}
+		nodes, decided := r.nodes(isAnd)
+		if decided != nil {
+			return nil, decided
+		}
+		merged = append(merged, nodes...)
+	}
+	return merged, nil
+}
+
+// nodes the comparisons of a merged range
+func (r *numRange) nodes(isAnd bool) ([]Node, Node) {
+	out := make([]Node, 0, 2)
+	if isAnd {
+		if r.lo != nil && r.hi != nil {
+			lo, hi := r.lo.val.Float64, r.hi.val.Float64
+			switch {
+			case lo > hi, lo == hi && (!r.lo.inc || !r.hi.inc):
+				return nil, boolIdentity(false)
+			case lo == hi:
+				return append(out, &BinaryNode{Operator: tokenEqual, Args: []Node{r.id, r.lo.val}}), nil
+			}
+		}
+	} else if len(r.eqs) > 0 {
+		eqs, _ := literalSet(nil, r.eqs)
+		// equalities inside the half ranges are redundant
+		remain := eqs[:0]
+		for _, eq := range eqs {
+			if num, ok := eq.(*NumberNode); ok && (r.lo.contains(num, true) || r.hi.contains(num, false)) {
+				continue
+			}
+			remain = append(remain, eq)
+		}
+		switch len(remain) {
+		case 0:
+		case 1:
+			out = append(out, &BinaryNode{Operator: tokenEqual, Args: []Node{r.id, remain[0]}})
+		default:
+			out = append(out, &BinaryNode{Operator: tokenIn, Args: []Node{r.id, NewArrayNodeArgs(remain)}})
+		}
+	}
+	if r.lo != nil {
+		op := lex.Token{T: lex.TokenGT, V: ">"}
+		if r.lo.inc {
+			op = lex.Token{T: lex.TokenGE, V: ">="}
+		}
+		out = append(out, &BinaryNode{Operator: op, Args: []Node{r.id, r.lo.val}})
+	}
+	if r.hi != nil {
+		op := lex.Token{T: lex.TokenLT, V: "<"}
+		if r.hi.inc {
+			op = lex.Token{T: lex.TokenLE, V: "<="}
+		}
+		out = append(out, &BinaryNode{Operator: op, Args: []Node{r.id, r.hi.val}})
+	}
+	return out, nil
+}
+
+// contains is num within the lower (or upper) half range of b
+func (b *bound) contains(num *NumberNode, lower bool) bool {
+	if b == nil {
+		return false
+	}
+	v, bv := num.Float64, b.val.Float64
+	if v == bv {
+		return b.inc
+	}
+	return (v > bv) == lower
+}
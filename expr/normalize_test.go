@@ -0,0 +1,131 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		// ranges
+		{`AND ( x > 5, x > 3 )`, `x > 5`},
+		{`AND ( x > 5, x < 3 )`, `false`},
+		{`AND ( x >= 1, x <= 5, y = "a" )`, `AND ( x <= 5, x >= 1, y = "a" )`},
+		{`AND ( x >= 5, x <= 5 )`, `x = 5`},
+		{`AND ( x > 5, x <= 5 )`, `false`},
+		{`AND ( x BETWEEN 1 AND 10, x > 4 )`, `AND ( x < 10, x > 4 )`},
+		{`AND ( x BETWEEN 1 AND 10, x >= 1 )`, `AND ( x < 10, x > 1 )`},
+		{`OR ( x > 5, x > 3 )`, `x > 3`},
+		{`OR ( x = 4, x > 3 )`, `x > 3`},
+		// equalities and IN
+		{`OR ( x = 1, x = 2, x IN (2, 3, 3), y = "b" )`, `OR ( x IN (1, 2, 3), y = "b" )`},
+		{`x IN (5)`, `x = 5`},
+		{`z IN ("b", "a", "b")`, `z IN ("a", "b")`},
+		// not
+		{`NOT NOT x = 1`, `x = 1`},
+		{`NOT ( x == "a" )`, `NOT (x == "a")`},
+		{`NOT AND ( x = 1, y > 2 )`, `NOT AND ( x = 1, y > 2 )`},
+		{`OR ( NOT NOT AND ( x = 1, y > 2 ), z = 1 )`, `OR ( AND ( x = 1, y > 2 ), z = 1 )`},
+		{`NOT OR ( x IN (1,2), EXISTS y, z BETWEEN 1 AND 2 )`,
+			`NOT OR ( x IN (1, 2), EXISTS y, z BETWEEN 1 AND 2 )`},
+		{`AND ( INCLUDE foo, NOT INCLUDE bar )`, `AND ( INCLUDE foo, NOT INCLUDE bar )`},
+		// constants
+		{`AND ( 1 + 2 > x, 3 = 3 )`, `x < 3`},
+		{`AND ( 1 = 2, y = 3 )`, `false`},
+		{`OR ( "a" = "a", y = 3 )`, `true`},
+		{`AND ( x > 5, true )`, `x > 5`},
+		// lists, absorption and factoring
+		{`AND ( b = 1, a = 1, AND ( c = 1, a = 1 ) )`, `AND ( a = 1, b = 1, c = 1 )`},
+		{`AND ( a = 1, OR ( a = 1, b = 2 ) )`, `a = 1`},
+		{`OR ( AND ( a = 1, b = 2 ), AND ( a = 1, c = 3 ) )`, `AND ( OR ( b = 2, c = 3 ), a = 1 )`},
+		{`AND ( OR ( a = 1, b = 2 ), OR ( c = 3, a = 1 ) )`, `OR ( AND ( b = 2, c = 3 ), a = 1 )`},
+		{`*`, `*`},
+	}
+	for _, tt := range tests {
+		fs := rel.MustParseFilter("FILTER " + tt.in)
+		before := fs.Filter.String()
+		n := expr.Normalize(fs.Filter)
+		assert.Equal(t, tt.out, n.String(), tt.in)
+		assert.Equal(t, before, fs.Filter.String(), "should not change %s", tt.in)
+		// the canonical form is already normal and parses
+		assert.Equal(t, n.String(), expr.Normalize(n).String(), tt.in)
+		_, err := rel.ParseFilterQL("FILTER " + n.String())
+		assert.Equal(t, nil, err, tt.out)
+	}
+
+	// with sql null logic NOT is pushed down to the comparisons
+	for in, out := range map[string]string{
+		`NOT ( x == "a" )`:                                   `NOT (x == "a")`,
+		`NOT AND ( x = 1, y > 2 )`:                           `OR ( NOT (x = 1), NOT (y > 2) )`,
+		`NOT OR ( x IN (1,2), EXISTS y, z BETWEEN 1 AND 2 )`: `AND ( NOT (x IN (1, 2)), NOT EXISTS y, z NOT BETWEEN 1 AND 2 )`,
+	} {
+		fs := rel.MustParseFilter("FILTER " + in)
+		assert.Equal(t, out, expr.NormalizeNullLogic(fs.Filter).String(), in)
+	}
+}
+
+func TestNormalizeEval(t *testing.T) {
+	filters := []string{
+		`NOT x > 5`,
+		`NOT ( x == "a" )`,
+		`NOT AND ( x > 5, y == "a" )`,
+		`NOT OR ( x <= 5, y != "a" )`,
+		`NOT x BETWEEN 3 AND 5`,
+		`NOT NOT x < 4`,
+		`AND ( x >= 3, x <= 5 )`,
+		`AND ( x > 3, x < 5 )`,
+		`AND ( x >= 5, x <= 5 )`,
+		`AND ( x BETWEEN 3 AND 5, x > 4 )`,
+		`AND ( x BETWEEN 3 AND 5, x >= 4 )`,
+		`AND ( x BETWEEN 3 AND 5, y = "a", x > 4 )`,
+		`AND ( x > 5, x > 3 )`,
+		`OR ( x > 5, x > 3 )`,
+		`OR ( x = 4, x > 3 )`,
+		`OR ( x = 3, x = 5, y = "a" )`,
+		`AND ( 1 + 2 > x, 3 = 3 )`,
+	}
+	msgs := []map[string]interface{}{
+		{},
+		{"y": "a"},
+		{"x": 2},
+		{"x": 3},
+		{"x": 4},
+		{"x": 4.5, "y": "a"},
+		{"x": 5},
+		{"x": 6, "y": "a"},
+		{"x": "abc", "y": "b"},
+		{"x": "5"},
+		{"x": nil},
+	}
+	for _, f := range filters {
+		fs := rel.MustParseFilter("FILTER " + f)
+		n := expr.Normalize(fs.Filter)
+		for _, msg := range msgs {
+			cr := datasource.NewContextSimpleNative(msg)
+			assert.Equal(t, evalBool(cr, fs.Filter), evalBool(cr, n), "%s => %s  %v", f, n, msg)
+			nc := vm.NewNullLogicContext(cr)
+			assert.Equal(t, evalBool(nc, fs.Filter), evalBool(nc, n), "null logic %s => %s  %v", f, n, msg)
+			nn := expr.NormalizeNullLogic(fs.Filter)
+			assert.Equal(t, evalBool(nc, fs.Filter), evalBool(nc, nn), "null logic %s => %s  %v", f, nn, msg)
+		}
+	}
+}
+
+func evalBool(ctx expr.EvalContext, n expr.Node) bool {
+	v, ok := vm.Eval(ctx, n)
+	if !ok {
+		return false
+	}
+	bv, isBool := v.(value.BoolValue)
+	return isBool && bv.Val()
+}
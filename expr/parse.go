@@ -163,7 +163,8 @@ func newTree(pager TokenPager) *tree {
 	return &t
 }
 func newTreeFuncs(pager TokenPager, fr FuncResolver) *tree {
-	t := tree{TokenPager: pager, fr: fr, funcCheck: fr != nil}
+	_, lenient := fr.(lenientFuncs)
+	t := tree{TokenPager: pager, fr: fr, funcCheck: fr != nil && !lenient}
 	return &t
 }
 
@@ -291,7 +292,8 @@ TODO:
 --------------------------------------
 O -> A {( "||" | OR  ) A}
 A -> C {( "&&" | AND ) C}
-C -> P {( "==" | "!=" | ">" | ">=" | "<" | "<=" | "LIKE" | "IN" | "CONTAINS" | "INTERSECTS") P}
+C -> B {( "==" | "!=" | ">" | ">=" | "<" | "<=" | "LIKE" | "IN" | "CONTAINS" | "INTERSECTS") B}
+B -> P {( "&" | "|" | "^" | "<<" | ">>" ) P}
 P -> M {( "+" | "-" ) M}
 M -> F {( "*" | "/" ) F}
 F -> v | "(" O ")" | "!" v | "-" O | "NOT" C | "EXISTS" v | "IS" O | "AND (" O ")" | "OR (" O ")"
@@ -308,11 +310,12 @@ Recursion:  We recurse so the LAST to evaluate is the highest (parent, then or)
 1	Unary + - arithmetic operators
 2	* / arithmetic operators
 3	Binary + - arithmetic operators, || character operators
-4	All comparison operators
-5	NOT logical operator
-6	AND logical operator
-7	OR logical operator
-8	Paren's
+4	& | ^ << >> bitwise operators
+5	All comparison operators
+6	NOT logical operator
+7	AND logical operator
+8	OR logical operator
+9	Paren's
 
 
 */
@@ -365,7 +368,7 @@ func (t *tree) A(depth int) Node {
 
 func (t *tree) C(depth int) Node {
 	debugf(depth, "C  pre: %v", t.Cur())
-	n := t.P(depth)
+	n := t.B(depth)
 	for {
 		debugf(depth, "C post: %v peek=%v n=%v", t.Cur(), t.Peek(), n)
 		switch cur := t.Cur(); cur.T {
@@ -375,10 +378,19 @@ func (t *tree) C(depth int) Node {
 			return NewUnary(cur, t.cInner(n, depth+1))
 		case lex.TokenIs:
 			t.Next()
+			// x IS NULL is x = NULL, the IS operator lets null logic tell
+			// it from a comparison with NULL, see BinaryNode.IsNullTest
 			if t.Cur().T == lex.TokenNegate {
 				cur = t.Next()
 				ne := lex.Token{T: lex.TokenNE, V: "!="}
-				return NewBinaryNode(ne, n, t.P(depth+1))
+				if t.Cur().T == lex.TokenNull {
+					ne.V = opIsNot
+				}
+				return NewBinaryNode(ne, n, t.B(depth+1))
+			}
+			if t.Cur().T == lex.TokenNull {
+				eq := lex.Token{T: lex.TokenEqual, V: opIs}
+				return NewBinaryNode(eq, n, t.B(depth+1))
 			}
 			u.Warnf("TokenIS?  is this supported?")
 			return NewUnary(cur, t.cInner(n, depth+1))
@@ -393,16 +405,20 @@ func (t *tree) cInner(n Node, depth int) Node {
 		debugf(depth, "cInner:  tok:  cur=%v peek=%v n=%v", t.Cur(), t.Peek(), n)
 		switch cur := t.Cur(); cur.T {
 		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
-			lex.TokenLE, lex.TokenLT, lex.TokenLike, lex.TokenContains:
+			lex.TokenLE, lex.TokenLT, lex.TokenLike, lex.TokenContains, lex.TokenRLike, lex.TokenRLikeI:
 			t.Next()
-			n = NewBinaryNode(cur, n, t.P(depth+1))
+			n = NewBinaryNode(cur, n, t.B(depth+1))
+		case lex.TokenDistinctFrom, lex.TokenNotDistinctFrom:
+			t.Next()
+			op := lex.Token{T: cur.T, V: strings.ToUpper(cur.T.String())}
+			n = NewBinaryNode(op, n, t.B(depth+1))
 		case lex.TokenBetween:
 			// weird syntax:    BETWEEN x AND y     AND is ignored essentially
 			t.Next()
-			n2 := t.P(depth)
+			n2 := t.B(depth)
 			t.expect(lex.TokenLogicAnd, "input")
 			t.Next()
-			n = NewTriNode(cur, n, n2, t.P(depth+1))
+			n = NewTriNode(cur, n, n2, t.B(depth+1))
 		case lex.TokenIN:
 			t.Next()
 			switch t.Cur().T {
@@ -461,6 +477,21 @@ func (t *tree) cInner(n Node, depth int) Node {
 	}
 }
 
+// B bitwise operators, below + - as postgres
+func (t *tree) B(depth int) Node {
+	debugf(depth, "B pre : %v", t.Cur())
+	n := t.P(depth)
+	for {
+		switch cur := t.Cur(); cur.T {
+		case lex.TokenBitAnd, lex.TokenBitOr, lex.TokenBitXor, lex.TokenShiftLeft, lex.TokenShiftRight:
+			t.Next()
+			n = NewBinaryNode(cur, n, t.P(depth+1))
+		default:
+			return n
+		}
+	}
+}
+
 func (t *tree) P(depth int) Node {
 	debugf(depth, "P pre : %v", t.Cur())
 	n := t.M(depth)
@@ -506,7 +537,7 @@ func (t *tree) F(depth int) Node {
 
 		switch t.Peek().T {
 		case lex.TokenIN, lex.TokenLike, lex.TokenContains, lex.TokenBetween,
-			lex.TokenIntersects:
+			lex.TokenIntersects, lex.TokenRLike, lex.TokenRLikeI:
 			// TODO:  this is a bug.  An old version of generator was saving these
 			//  NOT news INTERSECTS ("a")    which is invalid it should be
 			//  news NOT INTERSECTS ("a")  OR NOT (news INTERSECTS ("a"))
@@ -609,6 +640,11 @@ func (t *tree) v(depth int) Node {
 		t.Next()
 		return n
 	case lex.TokenIdentity:
+		if strings.ToLower(cur.V) == "interval" {
+			if n := t.interval(); n != nil {
+				return n
+			}
+		}
 		n := NewIdentityNode(&cur)
 		t.Next() // Consume identity
 
@@ -633,6 +669,8 @@ func (t *tree) v(depth int) Node {
 	case lex.TokenUdfExpr:
 		t.Next() // consume Function Name
 		return t.Func(depth, cur)
+	case lex.TokenCase:
+		return t.Case(depth)
 	case lex.TokenLeftParenthesis:
 		t.Next() // Consume  (
 		n := t.O(depth + 1)
@@ -675,6 +713,7 @@ func (t *tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 	}
 	fn = NewFuncNode(funcTok.V, funcImpl)
 	fn.Missing = !ok
+	fn.Token = funcTok
 
 	t.expect(lex.TokenLeftParenthesis, "func")
 	t.Next() // Are we sure we consume?
@@ -701,10 +740,18 @@ func (t *tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 		}
 		// This really isn't correct, we probably need an OperatorNode?
 		fn.append(NewStringNodeToken(t.Next()))
-		if t.Cur().T != lex.TokenIdentity {
-			t.unexpected(t.Cur(), "func AS exected Identity")
+		if t.Cur().T == lex.TokenUdfExpr && t.Peek().T == lex.TokenLeftParenthesis {
+			// a type with arguments  DECIMAL(10,2)
+			fn.append(NewStringNodeToken(castTypeArgs(t)))
+		} else {
+			if t.Cur().T != lex.TokenIdentity {
+				t.unexpected(t.Cur(), "func AS exected Identity")
+			}
+			fn.append(NewStringNodeToken(t.Next()))
+		}
+		if t.Cur().T == lex.TokenRightParenthesis {
+			t.Next()
 		}
-		fn.append(NewStringNodeToken(t.Next()))
 		return fn
 	default:
 		lastComma := false
@@ -772,6 +819,88 @@ func (t *tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 	}
 }
 
+// interval parse an interval literal into a duration value, nil if
+// this is not an interval (ie a field named interval).
+//
+//    INTERVAL 3 DAY
+//    INTERVAL '1' MONTH
+//    INTERVAL "1 year 2 months"
+//
+func (t *tree) interval() Node {
+	switch t.Peek().T {
+	case lex.TokenInteger, lex.TokenFloat, lex.TokenValue:
+	default:
+		return nil
+	}
+	t.Next() // Consume interval
+	num := t.Next()
+	if unit := t.Cur(); unit.T == lex.TokenIdentity {
+		if dv, err := value.ParseDuration(num.V + " " + unit.V); err == nil {
+			t.Next()
+			return NewValueNode(dv)
+		}
+	}
+	dv, err := value.ParseDuration(num.V)
+	if err != nil {
+		t.error(err)
+	}
+	return NewValueNode(dv)
+}
+
+// castTypeArgs consume a cast type with arguments, returning it as a single
+// token  DECIMAL ( 10 , 2 ) => "DECIMAL(10,2)"
+func castTypeArgs(t *tree) lex.Token {
+	typeTok := t.Next()
+	t.Next() // (
+	args := make([]string, 0, 2)
+	for {
+		tok := t.Next()
+		switch tok.T {
+		case lex.TokenRightParenthesis:
+			typeTok.T = lex.TokenIdentity
+			typeTok.V = fmt.Sprintf("%s(%s)", typeTok.V, strings.Join(args, ","))
+			return typeTok
+		case lex.TokenInteger:
+			args = append(args, tok.V)
+		case lex.TokenComma:
+		default:
+			t.unexpected(tok, "cast type arguments")
+		}
+	}
+}
+
+// Case parses simple and searched case expressions.
+//
+//    CASE [<expr>] WHEN <expr> THEN <expr> [WHEN <expr> THEN <expr>] [ELSE <expr>] END
+func (t *tree) Case(depth int) Node {
+	debugf(depth, "Case: cur:%v peek:%v", t.Cur(), t.Peek())
+	t.expect(lex.TokenCase, "case")
+	t.Next() // Consume CASE
+
+	var arg Node
+	if t.Cur().T != lex.TokenWhen {
+		arg = t.O(depth + 1)
+	}
+	cn := NewCaseNode(arg)
+	for t.Cur().T == lex.TokenWhen {
+		t.Next() // Consume WHEN
+		when := t.O(depth + 1)
+		t.expect(lex.TokenThen, "Expected THEN after CASE WHEN <expr>")
+		t.Next() // Consume THEN
+		cn.When(when, t.O(depth+1))
+	}
+	if len(cn.Whens) == 0 {
+		t.unexpected(t.Cur(), "Expected WHEN in CASE")
+	}
+	if t.Cur().T == lex.TokenElse {
+		t.Next() // Consume ELSE
+		cn.Else = t.O(depth + 1)
+	}
+	t.expect(lex.TokenEnd, "Expected END of CASE")
+	t.Next() // Consume END
+	return cn
+}
+
 // get Function from Global function registry.
 func (t *tree) getFunction(name string) (fn Func, ok bool) {
 	if t.fr != nil {
@@ -293,7 +293,57 @@ var exprTests = []exprTest{
 		`version == 4 AND (NOT(exists(@@content_whitelist_domains)) OR len(@@content_whitelist_domains) == 0 OR host(url) IN hosts(@@content_whitelist_domains))`,
 		true,
 	},
+	{
+		`CASE WHEN x > 5 THEN "big" when x > 1 THEN "medium" ELSE "small" END`,
+		`CASE WHEN x > 5 THEN "big" WHEN x > 1 THEN "medium" ELSE "small" END`,
+		true,
+	},
+	{
+		`case tolower(x) when "a" then 1 when "b" then 2 end + 5`,
+		`CASE tolower(x) WHEN "a" THEN 1 WHEN "b" THEN 2 END + 5`,
+		true,
+	},
+	{
+		`tolower(CASE WHEN x IN ("a","b") AND y THEN z ELSE "b" END)`,
+		`tolower(CASE WHEN x IN ("a", "b") AND y THEN z ELSE "b" END)`,
+		true,
+	},
+	{
+		`x IS NULL AND y IS NOT NULL`,
+		`x IS NULL AND y IS NOT NULL`,
+		true,
+	},
+	{
+		`x is  distinct from y OR x IS NOT DISTINCT FROM tolower(z)`,
+		`x IS DISTINCT FROM y OR x IS NOT DISTINCT FROM tolower(z)`,
+		true,
+	},
+	{
+		`email rlike "^bob" AND name ~* 'smith$' OR NOT x REGEXP y`,
+		`email rlike "^bob" AND name ~* "smith$" OR NOT (x REGEXP y)`,
+		true,
+	},
+	{
+		`email NOT ~ "[0-9]+"`,
+		`NOT (email ~ "[0-9]+")`,
+		true,
+	},
+	{
+		`flags & 4 == 4 AND x << 1 + 1 > y | z`,
+		`flags & 4 == 4 AND x << 1 + 1 > y | z`,
+		true,
+	},
 	// Invalid Statements
+	{
+		`CASE ELSE "b" END`, // requires a WHEN
+		"",
+		false,
+	},
+	{
+		`CASE WHEN x > 5 "big" END`, // requires THEN
+		"",
+		false,
+	},
 	{
 		"`fieldname` INTERSECTS \"hello\"", // Right Side only allows (identity|array|func)
 		"",
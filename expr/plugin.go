@@ -0,0 +1,58 @@
+package expr
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+)
+
+// FuncPluginSymbol the symbol a go plugin exports to provide functions, a
+// map of function name to CustomFunc:
+//
+//    // go build -buildmode=plugin -o myfuncs.so
+//    package main
+//
+//    var Funcs = map[string]expr.CustomFunc{
+//        "discount": &Discount{},
+//    }
+//
+const FuncPluginSymbol = "Funcs"
+
+// LoadPlugin open a go plugin (.so) and add its exported Funcs to this
+// registry.  Plugins must be built with the same version of qlbridge.
+func (m *FuncRegistry) LoadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup(FuncPluginSymbol)
+	if err != nil {
+		return err
+	}
+	funcs, ok := sym.(*map[string]CustomFunc)
+	if !ok {
+		return fmt.Errorf("plugin %s %s must be map[string]expr.CustomFunc but was %T", path, FuncPluginSymbol, sym)
+	}
+	for name, fn := range *funcs {
+		m.Add(name, fn)
+	}
+	return nil
+}
+
+// LoadFuncPlugins load all go plugins matching the glob pattern into the
+// global function registry, for use at startup.
+//
+//    expr.LoadFuncPlugins("/usr/lib/qlbridge/*.so")
+//
+func LoadFuncPlugins(pattern string) error {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := funcReg.LoadPlugin(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+// Package httpfe is an http/json front end, an http.Handler that runs sql
+// or FilterQL against qlbridge schemas and streams the results.
+//
+//    h := httpfe.NewHandler(schema.DefaultRegistry(), "mydb")
+//    http.Handle("/qlb/", http.StripPrefix("/qlb", h))
+//
+// Endpoints:
+//
+//    GET|POST /query    run a statement, params (query string, form or a
+//                       json body) sql or filter, schema, args
+//    GET      /schemas  the schema names of the registry
+//    GET      /tables   the tables and fields of ?schema=
+//
+// Statements may have ? placeholders bound from args.  FilterQL
+// statements (FILTER ... FROM table, SELECT cols FROM table FILTER ...)
+// run as a select of the table with the filter as its WHERE.  Rows are
+// streamed as they are produced as NDJSON (default), CSV or the Arrow IPC
+// stream format, chosen by the Accept header or ?format=.  An error after
+// the rows have started is sent in the X-Query-Error trailer.  The job
+// is closed if the request is cancelled (the client goes away).
+package httpfe
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+)
+
+// DefaultBatchSize of arrow record batches, and rows between flushes
+const DefaultBatchSize = 1024
+
+// Handler serves the query api
+type Handler struct {
+	// Registry of schemas queries may use
+	Registry *schema.Registry
+	// Schema default for requests that don't name one
+	Schema string
+	// Includer resolves FilterQL INCLUDE references, optional
+	Includer expr.Includer
+	// BatchSize rows per arrow record batch and between flushes
+	BatchSize int
+
+	mux *http.ServeMux
+}
+
+// NewHandler a handler for schemas of registry, defaultSchema is used by
+// requests that don't name a schema.
+func NewHandler(reg *schema.Registry, defaultSchema string) *Handler {
+	m := &Handler{
+		Registry:  reg,
+		Schema:    defaultSchema,
+		BatchSize: DefaultBatchSize,
+		mux:       http.NewServeMux(),
+	}
+	m.mux.HandleFunc("/query", m.query)
+	m.mux.HandleFunc("/schemas", m.schemas)
+	m.mux.HandleFunc("/tables", m.tables)
+	return m
+}
+
+func (m *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// request the params of a query
+type request struct {
+	SQL    string        `json:"sql"`
+	Filter string        `json:"filter"`
+	Schema string        `json:"schema"`
+	Args   []interface{} `json:"args"`
+	Format string        `json:"format"`
+}
+
+// httpError an error with the status to respond with
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string { return e.msg }
+
+func newError(status int, format string, args ...interface{}) *httpError {
+	return &httpError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if he, ok := err.(*httpError); ok {
+		status = he.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		u.Debugf("could not write response: %v", err)
+	}
+}
+
+// parseRequest the query params of a request, from a json body or the
+// query string and form.
+func parseRequest(r *http.Request) (*request, error) {
+	req := &request{}
+	switch r.Method {
+	case "GET", "POST":
+	default:
+		return nil, newError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+	if r.Method == "POST" && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(req); err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid json request: %v", err)
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid request: %v", err)
+		}
+		req.SQL = r.Form.Get("sql")
+		req.Filter = r.Form.Get("filter")
+		req.Schema = r.Form.Get("schema")
+		req.Format = r.Form.Get("format")
+		for _, arg := range r.Form["arg"] {
+			req.Args = append(req.Args, formArg(arg))
+		}
+	}
+	if f := r.URL.Query().Get("format"); f != "" {
+		req.Format = f
+	}
+	if req.SQL == "" && req.Filter == "" {
+		return nil, newError(http.StatusBadRequest, "sql or filter is required")
+	}
+	if req.SQL != "" && req.Filter != "" {
+		return nil, newError(http.StatusBadRequest, "only one of sql or filter may be given")
+	}
+	return req, nil
+}
+
+func (m *Handler) schema(name string) (*schema.Schema, error) {
+	if name == "" {
+		name = m.Schema
+	}
+	s, ok := m.Registry.Schema(name)
+	if !ok {
+		return nil, newError(http.StatusNotFound, "schema %q not found", name)
+	}
+	return s, nil
+}
+
+func (m *Handler) query(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s, err := m.schema(req.Schema)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sql, q := req.SQL, byte('\'')
+	if sql == "" {
+		sql, q = req.Filter, '"'
+	}
+	if sql, err = interpolate(sql, req.Args, q); err != nil {
+		writeError(w, newError(http.StatusBadRequest, "%v", err))
+		return
+	}
+	if req.Filter != "" {
+		if sql, err = m.filterSQL(sql); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	job, err := m.buildJob(r, s, sql)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, ok := job.Ctx.Stmt.(*rel.SqlSelect); ok {
+		m.streamRows(w, r, req, job)
+		return
+	}
+	m.exec(w, job)
+}
+
+// buildJob plan a statement, a panic while parsing or planning fails the
+// request not the server.
+func (m *Handler) buildJob(r *http.Request, s *schema.Schema, sql string) (job *exec.JobExecutor, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			u.Errorf("panic planning %q: %v", sql, rec)
+			job, err = nil, newError(http.StatusBadRequest, "could not parse or plan statement")
+		}
+	}()
+	ctx := plan.NewContext(sql)
+	ctx.Context = r.Context()
+	ctx.Schema = s
+	ctx.Session = datasource.NewMySqlSessionVars()
+	job, err = exec.BuildSqlJob(ctx)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "%v", err)
+	}
+	return job, nil
+}
+
+// filterSQL the select of a FilterQL statement
+func (m *Handler) filterSQL(filter string) (string, error) {
+	fs, err := rel.ParseFilterSelect(filter)
+	if err != nil {
+		return "", newError(http.StatusBadRequest, "%v", err)
+	}
+	if fs.From == "" {
+		return "", newError(http.StatusBadRequest, "filter requires FROM table")
+	}
+	if fs.Filter == nil && fs.Where == nil {
+		return "", newError(http.StatusBadRequest, "filter has no expression")
+	}
+	if len(fs.Includes()) > 0 && m.Includer == nil {
+		return "", newError(http.StatusBadRequest, "filter has INCLUDE but no includer is configured")
+	}
+	sel, err := rel.FilterSelectToSql(fs, m.Includer)
+	if err != nil {
+		return "", newError(http.StatusBadRequest, "%v", err)
+	}
+	return sel.String(), nil
+}
+
+// exec a statement without rows, the response is the rows affected
+func (m *Handler) exec(w http.ResponseWriter, job *exec.JobExecutor) {
+	rw := exec.NewResultExecWriter(job.Ctx)
+	job.RootTask.Add(rw)
+	if err := job.Setup(); err != nil {
+		job.Close()
+		writeError(w, err)
+		return
+	}
+	err := job.Run()
+	job.Close()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	affected, _ := rw.Result().RowsAffected()
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": affected})
+}
+
+func (m *Handler) schemas(w http.ResponseWriter, r *http.Request) {
+	names := append([]string{}, m.Registry.Schemas()...)
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, names)
+}
+
+// tableInfo the json description of a table
+type tableInfo struct {
+	Name   string      `json:"name"`
+	Fields []fieldInfo `json:"fields"`
+}
+
+type fieldInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+func (m *Handler) tables(w http.ResponseWriter, r *http.Request) {
+	s, err := m.schema(r.URL.Query().Get("schema"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	names := append([]string{}, s.Tables()...)
+	sort.Strings(names)
+	tables := make([]tableInfo, 0, len(names))
+	for _, name := range names {
+		tbl, err := s.Table(name)
+		if err != nil || tbl == nil {
+			continue
+		}
+		if len(tbl.Fields) == 0 && len(tbl.Columns()) > 0 {
+			introspect(s, name)
+		}
+		ti := tableInfo{Name: tbl.Name, Fields: make([]fieldInfo, 0, len(tbl.Fields))}
+		for _, fld := range tbl.Fields {
+			ti.Fields = append(ti.Fields, fieldInfo{Name: fld.Name, Type: fld.ValueType().String(), Nullable: !fld.NoNulls})
+		}
+		tables = append(tables, ti)
+	}
+	writeJSON(w, http.StatusOK, tables)
+}
+
+// introspect the fields of a table from its rows
+func introspect(s *schema.Schema, table string) {
+	conn, err := s.OpenConn(table)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if scanner, ok := conn.(schema.ConnScanner); ok {
+		if err := datasource.IntrospectSchema(s, table, scanner); err != nil {
+			u.Debugf("could not introspect %q: %v", table, err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package httpfe_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/arrow"
+	"github.com/araddon/qlbridge/datasource/mockcsv"
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/frontends/httpfe"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/testutil"
+)
+
+var (
+	handler *httpfe.Handler
+	server  *httptest.Server
+	bigRows = 20000
+)
+
+type includer map[string]string
+
+func (m includer) Include(name string) (expr.Node, error) {
+	f, ok := m[name]
+	if !ok {
+		return nil, expr.ErrNoIncluder
+	}
+	fs, err := rel.ParseFilterQL(f)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Filter, nil
+}
+
+func TestMain(m *testing.M) {
+	testutil.Setup() // will call flag.Parse()
+	td.LoadTestDataOnce()
+
+	var big bytes.Buffer
+	big.WriteString("id,name\n")
+	for i := 0; i < bigRows; i++ {
+		fmt.Fprintf(&big, "%d,name%d\n", i, i)
+	}
+	mockcsv.LoadTable(mockcsv.SchemaName, "httpfe_big", big.String())
+
+	handler = httpfe.NewHandler(schema.DefaultRegistry(), mockcsv.SchemaName)
+	handler.Includer = includer{"is_bob": `FILTER email LIKE "bob*"`}
+	server = httptest.NewServer(handler)
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+func get(t *testing.T, path string, params url.Values, accept string) *http.Response {
+	req, err := http.NewRequest("GET", server.URL+path+"?"+params.Encode(), nil)
+	assert.Equal(t, nil, err)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Equal(t, nil, err)
+	return resp
+}
+
+func ndjson(t *testing.T, resp *http.Response) []map[string]interface{} {
+	defer resp.Body.Close()
+	var rows []map[string]interface{}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		row := make(map[string]interface{})
+		if err := dec.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid ndjson: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestQueryNDJSON(t *testing.T) {
+	resp := get(t, "/query", url.Values{
+		"sql": {"SELECT user_id, referral_count FROM users WHERE referral_count > ? ORDER BY user_id"},
+		"arg": {"20"},
+	}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, httpfe.ContentTypeNDJSON, resp.Header.Get("Content-Type"))
+	rows := ndjson(t, resp)
+	assert.Equal(t, 1, len(rows))
+	if len(rows) == 1 {
+		assert.Equal(t, "9Ip1aKbeZe2njCDM", rows[0]["user_id"])
+		assert.Equal(t, float64(82), rows[0]["referral_count"])
+	}
+	assert.Equal(t, "", resp.Trailer.Get(httpfe.ErrorTrailer))
+
+	// json body, args are typed
+	body := `{"sql": "SELECT user_id FROM users WHERE referral_count = ? AND user_id != ?",
+		"args": [12, "hT2impsabc345c"]}`
+	resp, err := http.Post(server.URL+"/query", "application/json", strings.NewReader(body))
+	assert.Equal(t, nil, err)
+	rows = ndjson(t, resp)
+	assert.Equal(t, 1, len(rows))
+	if len(rows) == 1 {
+		assert.Equal(t, "hT2impsOPUREcVPc", rows[0]["user_id"])
+	}
+}
+
+func TestQueryCSV(t *testing.T) {
+	resp := get(t, "/query", url.Values{"sql": {"SELECT user_id, reg_date FROM users WHERE user_id = '9Ip1aKbeZe2njCDM'"}},
+		"text/csv;q=0.9, */*")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, httpfe.ContentTypeCSV, resp.Header.Get("Content-Type"))
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, [][]string{
+		{"user_id", "reg_date"},
+		{"9Ip1aKbeZe2njCDM", "2012-10-17T17:29:39.738Z"},
+	}, records)
+
+	// format param
+	resp = get(t, "/query", url.Values{"sql": {"SELECT 1 AS one"}, "format": {"csv"}}, "")
+	records, err = csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, [][]string{{"one"}, {"1"}}, records)
+}
+
+func TestQueryArrow(t *testing.T) {
+	handler.BatchSize = 2
+	defer func() { handler.BatchSize = httpfe.DefaultBatchSize }()
+
+	resp := get(t, "/query", url.Values{"sql": {"SELECT user_id, referral_count, reg_date FROM users ORDER BY user_id ASC"}},
+		arrow.ContentType)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, arrow.ContentType, resp.Header.Get("Content-Type"))
+	defer resp.Body.Close()
+	r, err := arrow.NewReader(resp.Body)
+	assert.Equal(t, nil, err)
+	if err != nil {
+		return
+	}
+	assert.Equal(t, []arrow.Field{
+		{Name: "user_id", Type: arrow.String, Nullable: true},
+		{Name: "referral_count", Type: arrow.Int64, Nullable: true},
+		{Name: "reg_date", Type: arrow.Timestamp, Nullable: true},
+	}, r.Schema().Fields)
+	batches, rows := 0, 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, nil, err)
+		if err != nil {
+			return
+		}
+		if batches == 0 {
+			assert.Equal(t, "9Ip1aKbeZe2njCDM", rec.Columns[0].String(0))
+			assert.Equal(t, int64(82), rec.Columns[1].Int64(0))
+			assert.Equal(t, 2012, rec.Columns[2].Time(0).Year())
+		}
+		batches++
+		rows += rec.Len
+	}
+	assert.Equal(t, 2, batches)
+	assert.Equal(t, 3, rows)
+}
+
+func TestQueryFilter(t *testing.T) {
+	resp := get(t, "/query", url.Values{"filter": {`FILTER AND ( referral_count > ?, EXISTS interests ) FROM users`},
+		"arg": {"10"}}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	rows := ndjson(t, resp)
+	assert.Equal(t, 2, len(rows))
+	if len(rows) > 0 {
+		assert.Equal(t, 6, len(rows[0]))
+	}
+
+	resp = get(t, "/query", url.Values{"filter": {`SELECT user_id FROM users FILTER INCLUDE is_bob`}}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	rows = ndjson(t, resp)
+	assert.Equal(t, []map[string]interface{}{{"user_id": "hT2impsOPUREcVPc"}}, rows)
+
+	resp = get(t, "/query", url.Values{"filter": {`FILTER INCLUDE not_a_filter FROM users`}}, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestQueryExec(t *testing.T) {
+	body := `{"sql": "INSERT INTO orders (order_id, user_id, item_id, price, order_date, item_count) VALUES (?, 'httpfe', 5, 1.5, '2016-01-02', 1)",
+		"args": [100]}`
+	resp, err := http.Post(server.URL+"/query", "application/json", strings.NewReader(body))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var res map[string]int64
+	assert.Equal(t, nil, json.NewDecoder(resp.Body).Decode(&res))
+	resp.Body.Close()
+	assert.Equal(t, int64(1), res["rows_affected"])
+
+	resp = get(t, "/query", url.Values{"sql": {"DELETE FROM orders WHERE order_id = ?"}, "arg": {"100"}}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	res = nil
+	assert.Equal(t, nil, json.NewDecoder(resp.Body).Decode(&res))
+	resp.Body.Close()
+	assert.Equal(t, int64(1), res["rows_affected"])
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		params url.Values
+		status int
+	}{
+		{url.Values{}, http.StatusBadRequest},
+		{url.Values{"sql": {"SELECT 1"}, "filter": {"FILTER x > 1"}}, http.StatusBadRequest},
+		{url.Values{"sql": {"SELECT 1"}, "schema": {"not_a_schema"}}, http.StatusNotFound},
+		{url.Values{"sql": {"SELECT FROM WHERE"}}, http.StatusBadRequest},
+		{url.Values{"sql": {"SELECT * FROM not_a_table"}}, http.StatusBadRequest},
+		{url.Values{"sql": {"SELECT * FROM users WHERE user_id = ?"}}, http.StatusBadRequest},
+		{url.Values{"sql": {"SELECT 1"}, "format": {"xml"}}, http.StatusBadRequest},
+		{url.Values{"filter": {"FILTER x > 1"}}, http.StatusBadRequest},
+	} {
+		resp := get(t, "/query", tc.params, "")
+		assert.Equal(t, tc.status, resp.StatusCode, "%v", tc.params)
+		var res map[string]string
+		assert.Equal(t, nil, json.NewDecoder(resp.Body).Decode(&res))
+		resp.Body.Close()
+		assert.NotEqual(t, "", res["error"], "%v", tc.params)
+	}
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/query?sql=SELECT+1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestQueryCancel(t *testing.T) {
+	// a handler that reports when the query is done
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer s.Close()
+	handler.BatchSize = 10
+	defer func() { handler.BatchSize = httpfe.DefaultBatchSize }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", s.URL+"/query?sql=SELECT+id,+name+FROM+httpfe_big", nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	assert.Equal(t, nil, err)
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	assert.Equal(t, nil, err)
+	assert.True(t, strings.HasPrefix(line, `{"id":`), line)
+	cancel()
+	resp.Body.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("query was not cancelled")
+	}
+
+	// the rows are flushed in batches, the handler didn't write them all
+	resp = get(t, "/query", url.Values{"sql": {"SELECT id FROM httpfe_big"}}, "text/csv")
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, nil, err)
+	assert.True(t, bytes.Count(b, []byte("\n")) > bigRows/2)
+}
+
+func TestSchemasTables(t *testing.T) {
+	resp := get(t, "/schemas", nil, "")
+	var names []string
+	assert.Equal(t, nil, json.NewDecoder(resp.Body).Decode(&names))
+	resp.Body.Close()
+	assert.Contains(t, names, mockcsv.SchemaName)
+
+	resp = get(t, "/tables", url.Values{"schema": {mockcsv.SchemaName}}, "")
+	var tables []struct {
+		Name   string
+		Fields []struct {
+			Name, Type string
+		}
+	}
+	assert.Equal(t, nil, json.NewDecoder(resp.Body).Decode(&tables))
+	resp.Body.Close()
+	found := false
+	for _, tbl := range tables {
+		if tbl.Name == "users" {
+			found = true
+			assert.Equal(t, 6, len(tbl.Fields))
+			for _, f := range tbl.Fields {
+				if f.Name == "referral_count" {
+					assert.Equal(t, "int", f.Type)
+				}
+			}
+		}
+	}
+	assert.True(t, found, "%v", tables)
+
+	resp = get(t, "/tables", url.Values{"schema": {"not_a_schema"}}, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
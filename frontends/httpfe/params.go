@@ -0,0 +1,92 @@
+package httpfe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+)
+
+// placeholders positions of ? outside of quotes and comments
+func placeholders(query string) []int {
+	var pos []int
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '?':
+			pos = append(pos, i)
+		}
+	}
+	return pos
+}
+
+// interpolate replace ? placeholders with literals of args, strings are
+// quoted with q (FilterQL strings are double quoted, sql single).
+func interpolate(query string, args []interface{}, q byte) (string, error) {
+	pos := placeholders(query)
+	if len(pos) != len(args) {
+		return "", fmt.Errorf("expected %d args but got %d", len(pos), len(args))
+	}
+	if len(args) == 0 {
+		return query, nil
+	}
+	b := &strings.Builder{}
+	last := 0
+	for i, p := range pos {
+		b.WriteString(query[last:p])
+		b.WriteString(literal(args[i], q))
+		last = p + 1
+	}
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
+
+// formArg an arg from a query string or form, which are all strings,
+// those that are numbers are numeric literals as json numbers are.
+func formArg(s string) interface{} {
+	if _, err := strconv.ParseFloat(s, 64); err == nil && strings.Trim(s, "+-.0123456789eE") == "" {
+		return json.Number(s)
+	}
+	return s
+}
+
+// literal of an arg, json numbers are numeric literals
+func literal(v interface{}, q byte) string {
+	switch vt := v.(type) {
+	case nil:
+		return "NULL"
+	case json.Number:
+		return vt.String()
+	case int64:
+		return strconv.FormatInt(vt, 10)
+	case float64:
+		return strconv.FormatFloat(vt, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vt)
+	case time.Time:
+		return quote(vt.Format(time.RFC3339Nano), q)
+	case string:
+		return quote(vt, q)
+	}
+	return quote(fmt.Sprint(v), q)
+}
+
+func quote(s string, q byte) string {
+	return string(q) + expr.StringEscape(rune(q), s) + string(q)
+}
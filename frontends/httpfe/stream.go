@@ -0,0 +1,289 @@
+package httpfe
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/arrow"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/value"
+)
+
+// Result formats, the media types of the Accept header
+const (
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeCSV    = "text/csv"
+	ContentTypeArrow  = arrow.ContentType
+)
+
+// ErrorTrailer the trailer with the error of a query that failed after
+// its rows started.
+const ErrorTrailer = "X-Query-Error"
+
+// formats the result content type of format names and media types
+var formats = map[string]string{
+	"ndjson":            ContentTypeNDJSON,
+	"json":              ContentTypeNDJSON,
+	"csv":               ContentTypeCSV,
+	"arrow":             ContentTypeArrow,
+	ContentTypeNDJSON:   ContentTypeNDJSON,
+	"application/jsonl": ContentTypeNDJSON,
+	"application/json":  ContentTypeNDJSON,
+	ContentTypeCSV:      ContentTypeCSV,
+	ContentTypeArrow:    ContentTypeArrow,
+}
+
+// contentType the result format of a request, the format param or the
+// first supported media type of Accept, NDJSON by default.
+func contentType(r *http.Request, format string) (string, error) {
+	if format != "" {
+		ct, ok := formats[strings.ToLower(format)]
+		if !ok {
+			return "", newError(http.StatusBadRequest, "unsupported format %q", format)
+		}
+		return ct, nil
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt := strings.ToLower(strings.TrimSpace(strings.SplitN(accept, ";", 2)[0]))
+		if ct, ok := formats[mt]; ok && strings.Contains(mt, "/") {
+			return ct, nil
+		}
+	}
+	return ContentTypeNDJSON, nil
+}
+
+// rowWriter writes rows of a result format
+type rowWriter interface {
+	writeRow(vals []driver.Value) error
+	// flush buffered rows to the response
+	flush() error
+	// close write the end of the result
+	close() error
+}
+
+func newRowWriter(ct string, w io.Writer, s *arrow.Schema, batchSize int) rowWriter {
+	switch ct {
+	case ContentTypeCSV:
+		return newCSVWriter(w, s)
+	case ContentTypeArrow:
+		return &arrowWriter{w: arrow.NewWriter(w, s), rb: arrow.NewRecordBuilder(s), batchSize: batchSize}
+	}
+	return newNDJSONWriter(w, s)
+}
+
+// ndjsonWriter writes a json object per row, keys in column order
+type ndjsonWriter struct {
+	w    io.Writer
+	s    *arrow.Schema
+	keys [][]byte
+	buf  bytes.Buffer
+}
+
+func newNDJSONWriter(w io.Writer, s *arrow.Schema) *ndjsonWriter {
+	m := &ndjsonWriter{w: w, s: s}
+	for _, f := range s.Fields {
+		k, _ := json.Marshal(f.Name)
+		m.keys = append(m.keys, append(k, ':'))
+	}
+	return m
+}
+
+func (m *ndjsonWriter) writeRow(vals []driver.Value) error {
+	m.buf.Reset()
+	m.buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			m.buf.WriteByte(',')
+		}
+		m.buf.Write(k)
+		b, err := json.Marshal(jsonValue(m.s.Fields[i].Type, vals[i]))
+		if err != nil {
+			b = []byte("null")
+		}
+		m.buf.Write(b)
+	}
+	m.buf.WriteString("}\n")
+	_, err := m.w.Write(m.buf.Bytes())
+	return err
+}
+
+func (m *ndjsonWriter) flush() error { return nil }
+func (m *ndjsonWriter) close() error { return nil }
+
+// jsonValue a value converted to its column type, other than strings
+// which may be maps or slices written as json.
+func jsonValue(t arrow.Type, v driver.Value) interface{} {
+	if t != arrow.String {
+		return arrow.Coerce(t, v)
+	}
+	if vv, ok := v.(value.Value); ok {
+		if vv.Nil() {
+			return nil
+		}
+		return vv.Value()
+	}
+	return v
+}
+
+// csvWriter writes a header row then a record per row
+type csvWriter struct {
+	w      *csv.Writer
+	s      *arrow.Schema
+	record []string
+}
+
+func newCSVWriter(w io.Writer, s *arrow.Schema) *csvWriter {
+	m := &csvWriter{w: csv.NewWriter(w), s: s, record: make([]string, len(s.Fields))}
+	m.w.Write(s.Names())
+	return m
+}
+
+func (m *csvWriter) writeRow(vals []driver.Value) error {
+	for i := range m.record {
+		m.record[i] = textValue(jsonValue(m.s.Fields[i].Type, vals[i]))
+	}
+	return m.w.Write(m.record)
+}
+
+func (m *csvWriter) flush() error {
+	m.w.Flush()
+	return m.w.Error()
+}
+
+func (m *csvWriter) close() error { return m.flush() }
+
+// textValue the csv text of a value, empty for null
+func textValue(v interface{}) string {
+	switch vt := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vt
+	case []byte:
+		return string(vt)
+	case time.Time:
+		return vt.Format(time.RFC3339Nano)
+	case bool, int64, float64:
+		return value.NewValue(vt).ToString()
+	default:
+		if b, err := json.Marshal(vt); err == nil {
+			return string(b)
+		}
+		return value.NewValue(vt).ToString()
+	}
+}
+
+// arrowWriter writes record batches of batchSize rows
+type arrowWriter struct {
+	w         *arrow.Writer
+	rb        *arrow.RecordBuilder
+	batchSize int
+}
+
+func (m *arrowWriter) writeRow(vals []driver.Value) error {
+	m.rb.Append(vals)
+	if m.rb.Len() >= m.batchSize {
+		return m.flush()
+	}
+	return nil
+}
+
+func (m *arrowWriter) flush() error {
+	if m.rb.Len() == 0 {
+		return nil
+	}
+	return m.w.Write(m.rb.NewRecord())
+}
+
+func (m *arrowWriter) close() error {
+	if err := m.flush(); err != nil {
+		return err
+	}
+	return m.w.Close()
+}
+
+// streamRows run a select job writing its rows as they are produced, the
+// job is closed if the request is cancelled.
+func (m *Handler) streamRows(w http.ResponseWriter, r *http.Request, req *request, job *exec.JobExecutor) {
+	ct, err := contentType(r, req.Format)
+	if err != nil {
+		job.Close()
+		writeError(w, err)
+		return
+	}
+	s := exec.ResultSchema(job.Ctx)
+	res := exec.NewResultRows(job.Ctx, s.Names())
+	job.RootTask.Add(res)
+	if err := job.Setup(); err != nil {
+		job.Close()
+		writeError(w, err)
+		return
+	}
+	// the result writer doesn't finish until the job is closed, which is
+	// done once all rows are read or the request is done.
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- job.Run()
+	}()
+	closed := false
+	closeJob := func() error {
+		if closed {
+			return nil
+		}
+		closed = true
+		job.Close()
+		return <-runErr
+	}
+	defer closeJob()
+
+	batchSize := m.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Trailer", ErrorTrailer)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	rw := newRowWriter(ct, w, s, batchSize)
+
+	n := 0
+	for {
+		select {
+		case <-r.Context().Done():
+			u.Debugf("query cancelled after %d rows: %v", n, r.Context().Err())
+			return
+		case msg, ok := <-res.MessageIn():
+			if !ok || msg == nil {
+				err := closeJob()
+				if err == nil {
+					err = rw.close()
+				}
+				if err != nil {
+					w.Header().Set(ErrorTrailer, err.Error())
+				}
+				return
+			}
+			if err := rw.writeRow(exec.MessageValues(msg, len(s.Fields))); err != nil {
+				u.Debugf("could not write row: %v", err)
+				return
+			}
+			n++
+			if n%batchSize == 0 {
+				if err := rw.flush(); err != nil {
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+		}
+	}
+}
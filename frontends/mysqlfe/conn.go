@@ -0,0 +1,556 @@
+package mysqlfe
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+)
+
+// client/server capability flags
+const (
+	clientLongPassword     uint32 = 0x00000001
+	clientFoundRows        uint32 = 0x00000002
+	clientLongFlag         uint32 = 0x00000004
+	clientConnectWithDB    uint32 = 0x00000008
+	clientProtocol41       uint32 = 0x00000200
+	clientTransactions     uint32 = 0x00002000
+	clientSecureConn       uint32 = 0x00008000
+	clientMultiResults     uint32 = 0x00020000
+	clientPluginAuth       uint32 = 0x00080000
+	clientConnectAttrs     uint32 = 0x00100000
+	clientPluginAuthLenenc uint32 = 0x00200000
+
+	serverCapabilities = clientLongPassword | clientFoundRows | clientLongFlag |
+		clientConnectWithDB | clientProtocol41 | clientTransactions | clientSecureConn |
+		clientMultiResults | clientPluginAuth | clientConnectAttrs | clientPluginAuthLenenc
+)
+
+// commands
+const (
+	comQuit             byte = 0x01
+	comInitDB           byte = 0x02
+	comQuery            byte = 0x03
+	comFieldList        byte = 0x04
+	comPing             byte = 0x0e
+	comStmtPrepare      byte = 0x16
+	comStmtExecute      byte = 0x17
+	comStmtSendLongData byte = 0x18
+	comStmtClose        byte = 0x19
+	comStmtReset        byte = 0x1a
+	comSetOption        byte = 0x1b
+	comResetConnection  byte = 0x1f
+)
+
+const (
+	statusAutocommit uint16 = 0x0002
+
+	nativePassword = "mysql_native_password"
+)
+
+// Error a mysql error with code and sql state sent to clients
+type Error struct {
+	Code  uint16
+	State string
+	Msg   string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("ERROR %d (%s): %s", e.Code, e.State, e.Msg) }
+
+func newError(code uint16, state, format string, args ...interface{}) *Error {
+	return &Error{Code: code, State: state, Msg: fmt.Sprintf(format, args...)}
+}
+
+// conn a client connection
+type conn struct {
+	*packetConn
+	s        *Server
+	id       uint32
+	caps     uint32
+	user     string
+	scramble []byte
+	schema   *schema.Schema
+	session  expr.ContextReadWriter
+	stmts    map[uint32]*stmt
+	lastStmt uint32
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func (c *conn) close() {
+	c.cancel()
+	c.conn.Close()
+}
+
+// serve the connection, handshake then commands until quit
+func (c *conn) serve() error {
+	if err := c.handshake(); err != nil {
+		if merr, ok := err.(*Error); ok {
+			c.writeError(merr)
+			c.flush()
+		}
+		return err
+	}
+	for {
+		c.seq = 0
+		data, err := c.readPacket()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if data[0] == comQuit {
+			return nil
+		}
+		if err := c.dispatch(data[0], data[1:]); err != nil {
+			return err
+		}
+		if err := c.flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch a command, errors returned are connection errors, statement
+// errors are written to the client.
+func (c *conn) dispatch(cmd byte, data []byte) error {
+	switch cmd {
+	case comQuery:
+		return c.query(string(data), nil)
+	case comPing:
+		return c.writeOK(0, 0)
+	case comInitDB:
+		return c.useSchema(string(data))
+	case comFieldList:
+		return c.fieldList(data)
+	case comStmtPrepare:
+		return c.prepare(string(data))
+	case comStmtExecute:
+		return c.execute(data)
+	case comStmtSendLongData:
+		c.sendLongData(data)
+		return nil
+	case comStmtClose:
+		r := &reader{b: data}
+		delete(c.stmts, r.uint32())
+		return nil
+	case comStmtReset:
+		r := &reader{b: data}
+		st, ok := c.stmts[r.uint32()]
+		if !ok {
+			return c.writeError(errUnknownStmt)
+		}
+		st.longData = nil
+		return c.writeOK(0, 0)
+	case comSetOption:
+		return c.writeEOF()
+	case comResetConnection:
+		c.session = datasource.NewMySqlSessionVars()
+		c.stmts = make(map[uint32]*stmt)
+		return c.writeOK(0, 0)
+	}
+	return c.writeError(newError(1047, "08S01", "Unknown command %d", cmd))
+}
+
+// handshake send the v10 handshake and authenticate the response
+func (c *conn) handshake() error {
+	c.scramble = newScramble()
+	p := packet{10}.
+		nulString(ServerVersion).
+		uint32(c.id).
+		bytes(c.scramble[:8]).
+		byte1(0).
+		uint16(uint16(serverCapabilities & 0xffff)).
+		byte1(charsetUtf8).
+		uint16(statusAutocommit).
+		uint16(uint16(serverCapabilities >> 16)).
+		byte1(byte(len(c.scramble) + 1)).
+		bytes(make([]byte, 10)).
+		bytes(c.scramble[8:]).
+		byte1(0).
+		nulString(nativePassword)
+	if err := c.writePacket(p); err != nil {
+		return err
+	}
+	if err := c.flush(); err != nil {
+		return err
+	}
+
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	r := &reader{b: data}
+	c.caps = r.uint32()
+	if c.caps&clientProtocol41 == 0 {
+		return newError(1043, "08S01", "client must support protocol 4.1")
+	}
+	r.next(4 + 1 + 23) // max packet, charset, filler
+	c.user = r.nulString()
+	var auth []byte
+	switch {
+	case c.caps&clientPluginAuthLenenc != 0:
+		auth = r.lenEncBytes()
+	case c.caps&clientSecureConn != 0:
+		auth = r.next(int(r.byte1()))
+	default:
+		auth = []byte(r.nulString())
+	}
+	dbName := ""
+	if c.caps&clientConnectWithDB != 0 && r.more() {
+		dbName = r.nulString()
+	}
+	plugin := nativePassword
+	if c.caps&clientPluginAuth != 0 && r.more() {
+		plugin = r.nulString()
+	}
+	if r.err != nil {
+		return newError(1043, "08S01", "bad handshake")
+	}
+
+	if plugin != nativePassword {
+		// ask client to switch to our auth method
+		p := packet{0xfe}.nulString(nativePassword).bytes(c.scramble).byte1(0)
+		if err := c.writePacket(p); err != nil {
+			return err
+		}
+		if err := c.flush(); err != nil {
+			return err
+		}
+		if auth, err = c.readPacket(); err != nil {
+			return err
+		}
+	}
+	if err := c.authenticate(auth); err != nil {
+		return err
+	}
+
+	c.session = datasource.NewMySqlSessionVars()
+	if dbName == "" {
+		dbName = c.s.Schema
+	}
+	if dbName != "" {
+		s, ok := c.s.Registry.Schema(dbName)
+		if !ok {
+			return newError(1049, "42000", "Unknown database '%s'", dbName)
+		}
+		c.schema = s
+	}
+	if err := c.writeOK(0, 0); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+func (c *conn) authenticate(auth []byte) error {
+	if len(c.s.Users) == 0 {
+		return nil
+	}
+	password, ok := c.s.Users[c.user]
+	if ok && checkNativePassword(c.scramble, auth, password) {
+		return nil
+	}
+	usingPassword := "NO"
+	if len(auth) > 0 {
+		usingPassword = "YES"
+	}
+	return newError(1045, "28000", "Access denied for user '%s' (using password: %s)", c.user, usingPassword)
+}
+
+// newScramble 20 random printable bytes for the auth challenge
+func newScramble() []byte {
+	b := make([]byte, 20)
+	rand.Read(b)
+	for i := range b {
+		b[i] = b[i]&0x7f | 0x01
+		if b[i] == '$' {
+			b[i]++
+		}
+	}
+	return b
+}
+
+// checkNativePassword response == SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+func checkNativePassword(scramble, response []byte, password string) bool {
+	if password == "" {
+		return len(response) == 0
+	}
+	if len(response) != sha1.Size {
+		return false
+	}
+	h1 := sha1.Sum([]byte(password))
+	h2 := sha1.Sum(h1[:])
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(h2[:])
+	h3 := h.Sum(nil)
+	for i := range h3 {
+		h3[i] ^= h1[i]
+	}
+	return subtle.ConstantTimeCompare(h3, response) == 1
+}
+
+func (c *conn) useSchema(name string) error {
+	name = strings.Trim(strings.TrimSpace(name), "`")
+	s, ok := c.s.Registry.Schema(name)
+	if !ok {
+		return c.writeError(newError(1049, "42000", "Unknown database '%s'", name))
+	}
+	c.schema = s
+	return c.writeOK(0, 0)
+}
+
+// buildJob plan a statement against the connections schema, a panic
+// while parsing or planning fails the statement not the server.
+func (c *conn) buildJob(sql string) (job *exec.JobExecutor, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			u.Errorf("mysql conn %d panic planning %q: %v", c.id, sql, r)
+			job, err = nil, newError(1064, "42000", "could not parse or plan statement")
+		}
+	}()
+	ctx := plan.NewContext(sql)
+	ctx.Context = c.ctx
+	ctx.Schema = c.schema
+	ctx.Session = c.session
+	return exec.BuildSqlJob(ctx)
+}
+
+// query run a statement writing its result, binary result sets for
+// prepared statements if st is not nil.
+func (c *conn) query(sql string, st *stmt) error {
+	if c.schema == nil {
+		return c.writeError(newError(1046, "3D000", "No database selected"))
+	}
+	job, err := c.buildJob(sql)
+	if err != nil {
+		return c.writeError(err)
+	}
+	ctx := job.Ctx
+
+	switch stmt := job.Ctx.Stmt.(type) {
+	case *rel.SqlSelect:
+		return c.writeRows(job, resultColumns(job.Ctx), st != nil)
+	case *rel.SqlCommand:
+		if stmt.Keyword() == lex.TokenUse {
+			job.Close()
+			return c.useSchema(stmt.Identity)
+		}
+	}
+
+	rw := exec.NewResultExecWriter(ctx)
+	job.RootTask.Add(rw)
+	if err := job.Setup(); err != nil {
+		return c.writeError(err)
+	}
+	err = job.Run()
+	job.Close()
+	if err != nil {
+		return c.writeError(err)
+	}
+	result := rw.Result()
+	affected, _ := result.RowsAffected()
+	lastID, _ := result.LastInsertId()
+	return c.writeOK(uint64(affected), uint64(lastID))
+}
+
+// resultColumns the column definitions for a select, columns that are a
+// field of the (single) table selected from are typed from that field.
+func resultColumns(ctx *plan.Context) []*column {
+	if ctx.Projection == nil || ctx.Projection.Proj == nil {
+		return nil
+	}
+	var tbl *schema.Table
+	if sel, ok := ctx.Stmt.(*rel.SqlSelect); ok && len(sel.From) == 1 && ctx.Schema != nil {
+		tbl, _ = ctx.Schema.Table(sel.From[0].Name)
+	}
+	cols := make([]*column, 0, len(ctx.Projection.Proj.Columns))
+	for _, rc := range ctx.Projection.Proj.Columns {
+		name := rc.As
+		if name == "" {
+			name = rc.Name
+		}
+		if tbl != nil && (rc.Col == nil || rc.Col.Expr == nil || isIdentity(rc.Col.Expr)) {
+			if fld, ok := tbl.FieldMap[rc.SourceName()]; ok {
+				c := fieldColumn(tbl, fld)
+				c.Name = name
+				cols = append(cols, c)
+				continue
+			}
+		}
+		cols = append(cols, newColumn(name, rc.Type))
+	}
+	return cols
+}
+
+func isIdentity(n expr.Node) bool {
+	_, ok := n.(*expr.IdentityNode)
+	return ok
+}
+
+// writeRows run a select job streaming rows to the client
+func (c *conn) writeRows(job *exec.JobExecutor, cols []*column, binary bool) error {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.Name
+	}
+	rw := exec.NewResultRows(job.Ctx, names)
+	job.RootTask.Add(rw)
+	if err := job.Setup(); err != nil {
+		return c.writeError(err)
+	}
+	// the result writer doesn't finish until the job is closed, which is
+	// done once all rows are read or the client write fails.
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- job.Run()
+	}()
+	closed := false
+	defer func() {
+		if !closed {
+			job.Close()
+		}
+	}()
+
+	headerSent := false
+	writeHeader := func() error {
+		headerSent = true
+		if err := c.writePacket(packet{}.lenEncInt(uint64(len(cols)))); err != nil {
+			return err
+		}
+		for _, col := range cols {
+			if err := c.writePacket(col.packet(nil)); err != nil {
+				return err
+			}
+		}
+		return c.writeEOF()
+	}
+
+	for msg := range rw.MessageIn() {
+		if msg == nil {
+			break
+		}
+		if !headerSent {
+			if err := writeHeader(); err != nil {
+				return err
+			}
+		}
+		vals := exec.MessageValues(msg, len(cols))
+		var p packet
+		if binary {
+			p = binaryRow(cols, vals)
+		} else {
+			p = textRow(cols, vals)
+		}
+		if err := c.writePacket(p); err != nil {
+			return err
+		}
+	}
+	closed = true
+	job.Close()
+	if err := <-runErr; err != nil {
+		// an error packet may end a result set
+		return c.writeError(err)
+	}
+	if !headerSent {
+		if err := writeHeader(); err != nil {
+			return err
+		}
+	}
+	return c.writeEOF()
+}
+
+func textRow(cols []*column, vals []driver.Value) packet {
+	var p packet
+	for i, v := range vals {
+		if v == nil {
+			p = p.byte1(0xfb)
+			continue
+		}
+		p = p.lenEncBytes(textColumnValue(cols[i].Type, v))
+	}
+	return p
+}
+
+// binaryRow 0x00 header, null bitmap (offset 2) then non-null values
+func binaryRow(cols []*column, vals []driver.Value) packet {
+	nullMap := make([]byte, (len(cols)+7+2)/8)
+	var body packet
+	for i, v := range vals {
+		ok := false
+		if v != nil {
+			body, ok = appendBinaryValue(body, cols[i].Type, v)
+		}
+		if !ok {
+			pos := i + 2
+			nullMap[pos/8] |= 1 << uint(pos%8)
+		}
+	}
+	return packet{0}.bytes(nullMap).bytes(body)
+}
+
+// fieldList COM_FIELD_LIST the column definitions of a table
+func (c *conn) fieldList(data []byte) error {
+	if c.schema == nil {
+		return c.writeError(newError(1046, "3D000", "No database selected"))
+	}
+	r := &reader{b: data}
+	name := r.nulString()
+	tbl, err := c.schema.Table(name)
+	if err != nil || tbl == nil {
+		return c.writeError(newError(1146, "42S02", "Table '%s.%s' doesn't exist", c.schema.Name, name))
+	}
+	for _, fld := range tbl.Fields {
+		// field list column definitions have a trailing default value
+		p := fieldColumn(tbl, fld).packet(nil).lenEncInt(0)
+		if err := c.writePacket(p); err != nil {
+			return err
+		}
+	}
+	return c.writeEOF()
+}
+
+func (c *conn) writeOK(affected, lastID uint64) error {
+	p := packet{0}.
+		lenEncInt(affected).
+		lenEncInt(lastID).
+		uint16(statusAutocommit).
+		uint16(0)
+	return c.writePacket(p)
+}
+
+func (c *conn) writeEOF() error {
+	return c.writePacket(packet{0xfe}.uint16(0).uint16(statusAutocommit))
+}
+
+// writeError send ERR packet, non mysql errors are ER_UNKNOWN_ERROR
+func (c *conn) writeError(err error) error {
+	merr, ok := err.(*Error)
+	if !ok {
+		merr = newError(1105, "HY000", "%v", err)
+	}
+	p := packet{0xff}.
+		uint16(merr.Code).
+		byte1('#').
+		bytes([]byte(merr.State)).
+		bytes([]byte(merr.Msg))
+	return c.writePacket(p)
+}
@@ -0,0 +1,214 @@
+package mysqlfe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+const (
+	maxPacketSize = 1<<24 - 1
+	// maxPayloadLen is the largest (multi-part) payload a client may send,
+	// the mysql server default max_allowed_packet.
+	maxPayloadLen = 64 << 20
+)
+
+// packetConn reads and writes mysql protocol packets, 3 byte length and
+// 1 byte sequence header, payloads of 16MB or more are split.
+type packetConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	seq  uint8
+}
+
+func newPacketConn(conn net.Conn) *packetConn {
+	return &packetConn{
+		conn: conn,
+		r:    bufio.NewReaderSize(conn, 16*1024),
+		w:    bufio.NewWriterSize(conn, 16*1024),
+	}
+}
+
+// readPacket read a full (possibly multi-part) payload
+func (m *packetConn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(m.r, header[:]); err != nil {
+			return nil, err
+		}
+		size := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		if header[3] != m.seq {
+			return nil, fmt.Errorf("packet out of order, got sequence %d expected %d", header[3], m.seq)
+		}
+		m.seq++
+		if len(payload)+size > maxPayloadLen {
+			return nil, fmt.Errorf("packet too large, larger than %d bytes", maxPayloadLen)
+		}
+		start := len(payload)
+		payload = append(payload, make([]byte, size)...)
+		if _, err := io.ReadFull(m.r, payload[start:]); err != nil {
+			return nil, err
+		}
+		if size < maxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+// writePacket buffer a payload, call flush to send
+func (m *packetConn) writePacket(payload []byte) error {
+	for {
+		size := len(payload)
+		if size > maxPacketSize {
+			size = maxPacketSize
+		}
+		header := [4]byte{byte(size), byte(size >> 8), byte(size >> 16), m.seq}
+		m.seq++
+		if _, err := m.w.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := m.w.Write(payload[:size]); err != nil {
+			return err
+		}
+		payload = payload[size:]
+		if size < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+func (m *packetConn) flush() error { return m.w.Flush() }
+
+// packet builder for a payload
+type packet []byte
+
+func (p packet) byte1(b byte) packet { return append(p, b) }
+func (p packet) uint16(v uint16) packet {
+	return append(p, byte(v), byte(v>>8))
+}
+func (p packet) uint32(v uint32) packet {
+	return append(p, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+func (p packet) uint64(v uint64) packet {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(p, b[:]...)
+}
+func (p packet) bytes(b []byte) packet { return append(p, b...) }
+
+// nulString null terminated string
+func (p packet) nulString(s string) packet {
+	return append(append(p, s...), 0)
+}
+
+// lenEncInt length encoded integer
+func (p packet) lenEncInt(v uint64) packet {
+	switch {
+	case v < 251:
+		return append(p, byte(v))
+	case v < 1<<16:
+		return append(p, 0xfc, byte(v), byte(v>>8))
+	case v < 1<<24:
+		return append(p, 0xfd, byte(v), byte(v>>8), byte(v>>16))
+	}
+	return packet(append(p, 0xfe)).uint64(v)
+}
+
+// lenEncString length encoded string
+func (p packet) lenEncString(s string) packet {
+	return append(p.lenEncInt(uint64(len(s))), s...)
+}
+func (p packet) lenEncBytes(b []byte) packet {
+	return append(p.lenEncInt(uint64(len(b))), b...)
+}
+
+// reader of a received payload, reads past the end set err
+type reader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (m *reader) next(n int) []byte {
+	if m.err != nil {
+		return nil
+	}
+	if n < 0 || m.pos+n > len(m.b) {
+		m.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := m.b[m.pos : m.pos+n]
+	m.pos += n
+	return b
+}
+func (m *reader) byte1() byte {
+	if b := m.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+func (m *reader) uint16() uint16 {
+	if b := m.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+func (m *reader) uint32() uint32 {
+	if b := m.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+func (m *reader) uint64() uint64 {
+	if b := m.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+func (m *reader) nulString() string {
+	if m.err != nil {
+		return ""
+	}
+	for i := m.pos; i < len(m.b); i++ {
+		if m.b[i] == 0 {
+			s := string(m.b[m.pos:i])
+			m.pos = i + 1
+			return s
+		}
+	}
+	// unterminated, rest of packet
+	s := string(m.b[m.pos:])
+	m.pos = len(m.b)
+	return s
+}
+func (m *reader) lenEncInt() uint64 {
+	switch b := m.byte1(); b {
+	case 0xfc:
+		return uint64(m.uint16())
+	case 0xfd:
+		if b := m.next(3); b != nil {
+			return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16
+		}
+		return 0
+	case 0xfe:
+		return m.uint64()
+	default:
+		return uint64(b)
+	}
+}
+func (m *reader) lenEncBytes() []byte {
+	return m.next(int(m.lenEncInt()))
+}
+func (m *reader) rest() []byte {
+	if m.err != nil {
+		return nil
+	}
+	b := m.b[m.pos:]
+	m.pos = len(m.b)
+	return b
+}
+func (m *reader) more() bool { return m.err == nil && m.pos < len(m.b) }
@@ -0,0 +1,43 @@
+package mysqlfe
+
+import (
+	"bufio"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// continuations an endless stream of full size packets, each requiring
+// another continuation packet.
+type continuations struct {
+	seq  uint8
+	left int
+}
+
+func (m *continuations) Read(p []byte) (int, error) {
+	if m.left == 0 {
+		if len(p) < 4 {
+			return 0, nil
+		}
+		p[0], p[1], p[2], p[3] = 0xff, 0xff, 0xff, m.seq
+		m.seq++
+		m.left = maxPacketSize
+		return 4, nil
+	}
+	n := len(p)
+	if n > m.left {
+		n = m.left
+	}
+	for i := range p[:n] {
+		p[i] = 0
+	}
+	m.left -= n
+	return n, nil
+}
+
+func TestReadPacketTooLarge(t *testing.T) {
+	pc := &packetConn{r: bufio.NewReader(&continuations{})}
+	payload, err := pc.readPacket()
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, 0, len(payload))
+}
@@ -0,0 +1,157 @@
+// Package mysqlfe is a mysql client/server protocol front end, so mysql
+// clients (the mysql cli, BI tools, drivers) can query qlbridge schemas.
+//
+//    s := mysqlfe.NewServer(schema.DefaultRegistry(), "mydb")
+//    s.Users = map[string]string{"root": "secret"}
+//    log.Fatal(s.ListenAndServe(":3306"))
+//
+// Statements are run with exec.BuildSqlJob against the connections
+// schema, selected by the handshake database, USE or COM_INIT_DB.  Text
+// (COM_QUERY) and binary (COM_STMT_PREPARE/EXECUTE) result sets are typed
+// from the statement projection, which for table columns comes from their
+// schema.Field.  There is no TLS, compression or multi-statement support.
+package mysqlfe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/schema"
+)
+
+// ServerVersion reported to clients in the handshake
+const ServerVersion = "5.7.99-qlbridge"
+
+// ErrServerClosed returned by Serve after Close
+var ErrServerClosed = errors.New("mysqlfe: Server closed")
+
+// Server accepts mysql protocol connections.
+type Server struct {
+	// Registry of schemas clients may use
+	Registry *schema.Registry
+	// Schema default for connections that don't name a database, optional
+	Schema string
+	// Users name to password for mysql_native_password authentication, if
+	// empty any user and password is accepted.
+	Users map[string]string
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*conn]struct{}
+	lastID   uint32
+	closed   bool
+}
+
+// NewServer a server for schemas of registry, defaultSchema is used by
+// connections that don't name a database.
+func NewServer(reg *schema.Registry, defaultSchema string) *Server {
+	return &Server{
+		Registry: reg,
+		Schema:   defaultSchema,
+		conns:    make(map[*conn]struct{}),
+	}
+}
+
+// ListenAndServe listen on tcp address and serve connections until Close.
+func (m *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return m.Serve(l)
+}
+
+// Serve accept connections from listener until Close.
+func (m *Server) Serve(l net.Listener) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	m.listener = l
+	m.mu.Unlock()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			m.mu.Lock()
+			closed := m.closed
+			m.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		c := m.newConn(nc)
+		if c == nil {
+			nc.Close()
+			continue
+		}
+		go func() {
+			defer m.removeConn(c)
+			if err := c.serve(); err != nil {
+				u.Debugf("mysql conn %d closed: %v", c.id, err)
+			}
+		}()
+	}
+}
+
+// Addr the address the server is listening on, nil if not listening.
+func (m *Server) Addr() net.Addr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Addr()
+}
+
+// Close the listener and all connections, cancelling running statements.
+func (m *Server) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	var err error
+	if m.listener != nil {
+		err = m.listener.Close()
+	}
+	for c := range m.conns {
+		c.close()
+	}
+	return err
+}
+
+func (m *Server) newConn(nc net.Conn) *conn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.lastID++
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &conn{
+		packetConn: newPacketConn(nc),
+		s:          m,
+		id:         m.lastID,
+		ctx:        ctx,
+		cancel:     cancel,
+		stmts:      make(map[uint32]*stmt),
+	}
+	m.conns[c] = struct{}{}
+	return c
+}
+
+func (m *Server) removeConn(c *conn) {
+	c.close()
+	m.mu.Lock()
+	delete(m.conns, c)
+	m.mu.Unlock()
+}
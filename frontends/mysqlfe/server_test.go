@@ -0,0 +1,221 @@
+package mysqlfe_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource/mockcsv"
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/frontends/mysqlfe"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/testutil"
+	"github.com/araddon/qlbridge/value"
+)
+
+var addr string
+
+// panicFunc a function that panics when evaluated
+type panicFunc struct{}
+
+func (m *panicFunc) Type() value.ValueType { return value.UnknownType }
+func (m *panicFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		panic("panicfunc")
+	}, nil
+}
+
+func TestMain(m *testing.M) {
+	testutil.Setup() // will call flag.Parse()
+	td.LoadTestDataOnce()
+	expr.FuncAdd("panicfunc", &panicFunc{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s := mysqlfe.NewServer(schema.DefaultRegistry(), mockcsv.SchemaName)
+	s.Users = map[string]string{"root": "secret", "nopass": ""}
+	go s.Serve(l)
+	addr = l.Addr().String()
+
+	code := m.Run()
+	s.Close()
+	os.Exit(code)
+}
+
+func open(t *testing.T, user, password, db string) *sql.DB {
+	conn, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", user, password, addr, db))
+	assert.Equal(t, nil, err)
+	return conn
+}
+
+func TestAuth(t *testing.T) {
+	db := open(t, "root", "secret", "")
+	defer db.Close()
+	assert.Equal(t, nil, db.Ping())
+
+	db2 := open(t, "nopass", "", mockcsv.SchemaName)
+	defer db2.Close()
+	assert.Equal(t, nil, db2.Ping())
+
+	for _, bad := range [][3]string{
+		{"root", "wrong", ""},
+		{"root", "", ""},
+		{"nobody", "secret", ""},
+		{"root", "secret", "not_a_db"},
+	} {
+		db := open(t, bad[0], bad[1], bad[2])
+		err := db.Ping()
+		assert.NotEqual(t, nil, err, "%v", bad)
+		if err != nil {
+			assert.Contains(t, err.Error(), "Error 10", "%v", bad)
+		}
+		db.Close()
+	}
+}
+
+func TestQuery(t *testing.T) {
+	db := open(t, "root", "secret", "")
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT user_id, referral_count, reg_date, referral_count * 2 AS rc2
+		FROM users WHERE user_id = "9Ip1aKbeZe2njCDM"`)
+	assert.Equal(t, nil, err)
+	types, err := rows.ColumnTypes()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "VARCHAR", types[0].DatabaseTypeName())
+	assert.Equal(t, "BIGINT", types[1].DatabaseTypeName())
+	assert.Equal(t, "DATETIME", types[2].DatabaseTypeName())
+	assert.Equal(t, "BIGINT", types[3].DatabaseTypeName())
+
+	ct := 0
+	for rows.Next() {
+		var userID string
+		var refs, refs2 int64
+		var regDate time.Time
+		assert.Equal(t, nil, rows.Scan(&userID, &refs, &regDate, &refs2))
+		assert.Equal(t, "9Ip1aKbeZe2njCDM", userID)
+		assert.Equal(t, int64(82), refs)
+		assert.Equal(t, int64(164), refs2)
+		assert.Equal(t, 2012, regDate.Year())
+		ct++
+	}
+	assert.Equal(t, nil, rows.Err())
+	assert.Equal(t, 1, ct)
+
+	var tables []string
+	rows, err = db.Query("SHOW TABLES")
+	assert.Equal(t, nil, err)
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		tables = append(tables, name)
+	}
+	assert.Contains(t, tables, "users")
+
+	var version string
+	assert.Equal(t, nil, db.QueryRow("SELECT @@version_comment LIMIT 1").Scan(&version))
+	assert.NotEqual(t, "", version)
+
+	// no rows still has columns
+	rows, err = db.Query(`SELECT user_id FROM users WHERE user_id = "none"`)
+	assert.Equal(t, nil, err)
+	cols, _ := rows.Columns()
+	assert.Equal(t, []string{"user_id"}, cols)
+	assert.Equal(t, false, rows.Next())
+
+	_, err = db.Query("SELECT user_id FROM not_a_table")
+	assert.NotEqual(t, nil, err)
+	_, err = db.Query("SELECT FROM WHERE")
+	assert.NotEqual(t, nil, err)
+	// a panic while running is a statement error
+	_, err = db.Query("SELECT user_id, panicfunc() FROM users")
+	assert.NotEqual(t, nil, err)
+
+	// connection still usable after errors
+	assert.Equal(t, nil, db.Ping())
+}
+
+func TestPrepared(t *testing.T) {
+	db := open(t, "root", "secret", "")
+	defer db.Close()
+
+	stmt, err := db.Prepare(`SELECT user_id, referral_count, reg_date FROM users WHERE user_id = ?`)
+	assert.Equal(t, nil, err)
+	defer stmt.Close()
+
+	var userID string
+	var refs int64
+	var regDate time.Time
+	err = stmt.QueryRow("9Ip1aKbeZe2njCDM").Scan(&userID, &refs, &regDate)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "9Ip1aKbeZe2njCDM", userID)
+	assert.Equal(t, int64(82), refs)
+	assert.Equal(t, 2012, regDate.Year())
+
+	// quotes in params are escaped
+	err = stmt.QueryRow(`x' OR 'a' = 'a`).Scan(&userID, &refs, &regDate)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	var ct int64
+	err = db.QueryRow(`SELECT count(*) FROM users WHERE referral_count > ?`, 10).Scan(&ct)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(3), ct)
+}
+
+func TestExec(t *testing.T) {
+	mockcsv.LoadTable(mockcsv.SchemaName, "mysqlfe_events", "id,user_id,event\n1,abcd,signup")
+
+	db := open(t, "root", "secret", mockcsv.SchemaName)
+	defer db.Close()
+
+	result, err := db.Exec(`INSERT INTO mysqlfe_events (id, user_id, event)
+		VALUES (2, "abcd", "logon"), (3, "efgh", "logon")`)
+	assert.Equal(t, nil, err)
+	affected, err := result.RowsAffected()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), affected)
+
+	result, err = db.Exec(`DELETE FROM mysqlfe_events WHERE user_id = ?`, "abcd")
+	assert.Equal(t, nil, err)
+	affected, _ = result.RowsAffected()
+	assert.Equal(t, int64(2), affected)
+
+	// USE on a single connection
+	conn, err := db.Conn(context.Background())
+	assert.Equal(t, nil, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(context.Background(), "USE not_a_db")
+	assert.NotEqual(t, nil, err)
+	_, err = conn.ExecContext(context.Background(), "USE "+mockcsv.SchemaName)
+	assert.Equal(t, nil, err)
+}
+
+func TestServeClosed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	s := mysqlfe.NewServer(schema.DefaultRegistry(), mockcsv.SchemaName)
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+	for s.Addr() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, nil, s.Close())
+	select {
+	case err := <-served:
+		assert.Equal(t, mysqlfe.ErrServerClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+}
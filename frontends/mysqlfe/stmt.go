@@ -0,0 +1,278 @@
+package mysqlfe
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+)
+
+var errUnknownStmt = newError(1243, "HY000", "Unknown prepared statement handler")
+
+// stmt a prepared statement, ? placeholders are replaced by literals of
+// the bound values when executed.
+type stmt struct {
+	id         uint32
+	query      string
+	params     int
+	paramTypes []byte // type, unsigned flag pairs from the last execute
+	longData   map[int][]byte
+}
+
+// prepare COM_STMT_PREPARE, the result columns of selects are found by
+// planning the statement with null params.
+func (c *conn) prepare(query string) error {
+	c.lastStmt++
+	st := &stmt{id: c.lastStmt, query: query, params: len(placeholders(query))}
+
+	var cols []*column
+	if c.schema != nil {
+		args := make([]interface{}, st.params)
+		if sql, err := interpolate(query, args); err == nil {
+			job, err := c.buildJob(sql)
+			if err != nil {
+				return c.writeError(err)
+			}
+			if _, ok := job.Ctx.Stmt.(*rel.SqlSelect); ok {
+				cols = resultColumns(job.Ctx)
+			}
+			job.Close()
+		}
+	}
+	c.stmts[st.id] = st
+
+	p := packet{0}.
+		uint32(st.id).
+		uint16(uint16(len(cols))).
+		uint16(uint16(st.params)).
+		byte1(0).
+		uint16(0)
+	if err := c.writePacket(p); err != nil {
+		return err
+	}
+	if st.params > 0 {
+		for i := 0; i < st.params; i++ {
+			if err := c.writePacket(newColumn("?", 0).packet(nil)); err != nil {
+				return err
+			}
+		}
+		if err := c.writeEOF(); err != nil {
+			return err
+		}
+	}
+	if len(cols) > 0 {
+		for _, col := range cols {
+			if err := c.writePacket(col.packet(nil)); err != nil {
+				return err
+			}
+		}
+		if err := c.writeEOF(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendLongData COM_STMT_SEND_LONG_DATA append data for a param, there is
+// no response.
+func (c *conn) sendLongData(data []byte) {
+	r := &reader{b: data}
+	st, ok := c.stmts[r.uint32()]
+	param := int(r.uint16())
+	if !ok || r.err != nil {
+		return
+	}
+	if st.longData == nil {
+		st.longData = make(map[int][]byte)
+	}
+	st.longData[param] = append(st.longData[param], r.rest()...)
+}
+
+// execute COM_STMT_EXECUTE bind the params and run with binary results
+func (c *conn) execute(data []byte) error {
+	r := &reader{b: data}
+	st, ok := c.stmts[r.uint32()]
+	if !ok {
+		return c.writeError(errUnknownStmt)
+	}
+	r.next(1 + 4) // flags, iteration count
+	args, err := st.bind(r)
+	st.longData = nil
+	if err != nil {
+		return c.writeError(err)
+	}
+	sql, err := interpolate(st.query, args)
+	if err != nil {
+		return c.writeError(err)
+	}
+	return c.query(sql, st)
+}
+
+// bind read the param values of an execute
+func (m *stmt) bind(r *reader) ([]interface{}, error) {
+	args := make([]interface{}, m.params)
+	if m.params == 0 {
+		return args, nil
+	}
+	nullMap := r.next((m.params + 7) / 8)
+	if r.byte1() == 1 {
+		m.paramTypes = r.next(2 * m.params)
+	}
+	if r.err != nil || len(m.paramTypes) != 2*m.params {
+		return nil, newError(1210, "HY000", "Incorrect arguments to mysqld_stmt_execute")
+	}
+	for i := range args {
+		if nullMap[i/8]&(1<<uint(i%8)) != 0 {
+			continue
+		}
+		if ld, ok := m.longData[i]; ok {
+			args[i] = string(ld)
+			continue
+		}
+		args[i] = readParam(r, m.paramTypes[2*i], m.paramTypes[2*i+1]&0x80 != 0)
+	}
+	if r.err != nil {
+		return nil, newError(1210, "HY000", "Incorrect arguments to mysqld_stmt_execute")
+	}
+	return args, nil
+}
+
+// readParam a binary protocol param value
+func readParam(r *reader, typ byte, unsigned bool) interface{} {
+	switch typ {
+	case typeNull:
+		return nil
+	case typeTiny:
+		if unsigned {
+			return int64(r.byte1())
+		}
+		return int64(int8(r.byte1()))
+	case typeShort, typeYear:
+		if unsigned {
+			return int64(r.uint16())
+		}
+		return int64(int16(r.uint16()))
+	case typeLong, typeInt24:
+		if unsigned {
+			return int64(r.uint32())
+		}
+		return int64(int32(r.uint32()))
+	case typeLongLong:
+		v := r.uint64()
+		if unsigned && v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case typeFloat:
+		return float64(math.Float32frombits(r.uint32()))
+	case typeDouble:
+		return math.Float64frombits(r.uint64())
+	case typeDate, typeDateTime, typeTimestamp:
+		var year, month, day, hour, min, sec, micro int
+		switch r.byte1() {
+		case 11:
+			year, month, day = int(r.uint16()), int(r.byte1()), int(r.byte1())
+			hour, min, sec = int(r.byte1()), int(r.byte1()), int(r.byte1())
+			micro = int(r.uint32())
+		case 7:
+			year, month, day = int(r.uint16()), int(r.byte1()), int(r.byte1())
+			hour, min, sec = int(r.byte1()), int(r.byte1()), int(r.byte1())
+		case 4:
+			year, month, day = int(r.uint16()), int(r.byte1()), int(r.byte1())
+		default:
+			return time.Time{}
+		}
+		return time.Date(year, time.Month(month), day, hour, min, sec, micro*1000, time.UTC)
+	case typeTime:
+		n := r.byte1()
+		if n == 0 {
+			return "00:00:00"
+		}
+		neg, days := r.byte1(), r.uint32()
+		hour, min, sec := uint32(r.byte1()), r.byte1(), r.byte1()
+		if n == 12 {
+			r.uint32()
+		}
+		sign := ""
+		if neg == 1 {
+			sign = "-"
+		}
+		return fmt.Sprintf("%s%02d:%02d:%02d", sign, days*24+hour, min, sec)
+	}
+	return string(r.lenEncBytes())
+}
+
+// placeholders positions of ? outside of quotes and comments
+func placeholders(query string) []int {
+	var pos []int
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '?':
+			pos = append(pos, i)
+		}
+	}
+	return pos
+}
+
+// interpolate replace ? placeholders with sql literals of args
+func interpolate(query string, args []interface{}) (string, error) {
+	pos := placeholders(query)
+	if len(pos) != len(args) {
+		return "", newError(1210, "HY000", "expected %d params but got %d", len(pos), len(args))
+	}
+	if len(args) == 0 {
+		return query, nil
+	}
+	b := &strings.Builder{}
+	last := 0
+	for i, p := range pos {
+		b.WriteString(query[last:p])
+		b.WriteString(literal(args[i]))
+		last = p + 1
+	}
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
+
+// literal sql literal of a param value
+func literal(v interface{}) string {
+	switch vt := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(vt, 10)
+	case float64:
+		return strconv.FormatFloat(vt, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(vt)
+	case time.Time:
+		return quote(vt.Format(mysqlTimeFormat))
+	case []byte:
+		return quote(string(vt))
+	case string:
+		return quote(vt)
+	}
+	return quote(fmt.Sprint(v))
+}
+
+func quote(s string) string {
+	return "'" + expr.StringEscape('\'', s) + "'"
+}
@@ -0,0 +1,36 @@
+package mysqlfe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		sql  string
+		args []interface{}
+		out  string
+	}{
+		{"SELECT a FROM t", nil, "SELECT a FROM t"},
+		{"SELECT a FROM t WHERE a = ? AND b > ?", []interface{}{"x", int64(2)},
+			"SELECT a FROM t WHERE a = 'x' AND b > 2"},
+		{"SELECT '?', \"?\", `?` FROM t WHERE a = ?", []interface{}{nil},
+			"SELECT '?', \"?\", `?` FROM t WHERE a = NULL"},
+		{"SELECT a FROM t -- why?\nWHERE a = ?", []interface{}{1.5},
+			"SELECT a FROM t -- why?\nWHERE a = 1.5"},
+		{"SELECT 'it\\'s ?' FROM t WHERE a = ?", []interface{}{"it's"},
+			"SELECT 'it\\'s ?' FROM t WHERE a = 'it''s'"},
+		{"SELECT a FROM t WHERE d = ?", []interface{}{time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)},
+			"SELECT a FROM t WHERE d = '2018-01-02 03:04:05'"},
+	}
+	for _, tc := range tests {
+		out, err := interpolate(tc.sql, tc.args)
+		assert.Equal(t, nil, err, tc.sql)
+		assert.Equal(t, tc.out, out)
+	}
+
+	_, err := interpolate("SELECT a FROM t WHERE a = ?", nil)
+	assert.NotEqual(t, nil, err)
+}
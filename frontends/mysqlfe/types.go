@@ -0,0 +1,227 @@
+package mysqlfe
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+// mysql column types
+const (
+	typeDecimal    byte = 0x00
+	typeTiny       byte = 0x01
+	typeShort      byte = 0x02
+	typeLong       byte = 0x03
+	typeFloat      byte = 0x04
+	typeDouble     byte = 0x05
+	typeNull       byte = 0x06
+	typeTimestamp  byte = 0x07
+	typeLongLong   byte = 0x08
+	typeInt24      byte = 0x09
+	typeDate       byte = 0x0a
+	typeTime       byte = 0x0b
+	typeDateTime   byte = 0x0c
+	typeYear       byte = 0x0d
+	typeVarChar    byte = 0x0f
+	typeBit        byte = 0x10
+	typeJSON       byte = 0xf5
+	typeNewDecimal byte = 0xf6
+	typeBlob       byte = 0xfc
+	typeVarString  byte = 0xfd
+	typeString     byte = 0xfe
+)
+
+// column definition flags
+const (
+	flagNotNull  uint16 = 0x0001
+	flagBinary   uint16 = 0x0080
+	flagUnsigned uint16 = 0x0020
+)
+
+const (
+	charsetUtf8   = 33
+	charsetBinary = 63
+
+	mysqlTimeFormat = "2006-01-02 15:04:05.999999"
+)
+
+// column a result set column definition
+type column struct {
+	Schema   string
+	Table    string
+	OrgTable string
+	Name     string
+	OrgName  string
+	Length   uint32
+	Type     byte
+	Flags    uint16
+	Decimals byte
+}
+
+// newColumn a column of value type, mysql types are chosen to round trip
+// through database/sql drivers as the qlbridge driver returns them.
+func newColumn(name string, vt value.ValueType) *column {
+	c := &column{Name: name, OrgName: name}
+	switch vt {
+	case value.IntType:
+		c.Type, c.Length, c.Flags = typeLongLong, 20, flagBinary
+	case value.NumberType:
+		c.Type, c.Length, c.Flags, c.Decimals = typeDouble, 22, flagBinary, 31
+	case value.BoolType:
+		c.Type, c.Length, c.Flags = typeTiny, 1, flagBinary
+	case value.TimeType:
+		c.Type, c.Length, c.Flags, c.Decimals = typeDateTime, 26, flagBinary, 6
+	case value.DecimalType:
+		c.Type, c.Length, c.Decimals = typeNewDecimal, 65, 30
+	case value.JsonType, value.MapValueType, value.MapStringType, value.MapIntType,
+		value.MapNumberType, value.MapBoolType, value.MapTimeType, value.SliceValueType,
+		value.StringsType:
+		c.Type, c.Length = typeJSON, 1<<32-1
+	case value.ByteSliceType:
+		c.Type, c.Length, c.Flags = typeBlob, 65535, flagBinary
+	default:
+		c.Type, c.Length = typeVarString, 255*3
+	}
+	return c
+}
+
+// fieldColumn a column typed from a schema field
+func fieldColumn(tbl *schema.Table, fld *schema.Field) *column {
+	c := newColumn(fld.Name, fld.ValueType())
+	c.Table, c.OrgTable = tbl.Name, tbl.Name
+	if tbl.Schema != nil {
+		c.Schema = tbl.Schema.Name
+	}
+	switch {
+	case c.Type == typeVarString && fld.Length > 0:
+		c.Length = fld.Length * 3
+	case c.Type == typeNewDecimal && fld.Precision > 0:
+		c.Length, c.Decimals = fld.Precision+2, byte(fld.Scale)
+	}
+	if fld.NoNulls {
+		c.Flags |= flagNotNull
+	}
+	return c
+}
+
+// write column definition payload
+func (c *column) packet(p packet) packet {
+	p = p.lenEncString("def").
+		lenEncString(c.Schema).
+		lenEncString(c.Table).
+		lenEncString(c.OrgTable).
+		lenEncString(c.Name).
+		lenEncString(c.OrgName).
+		lenEncInt(0x0c)
+	charset := uint16(charsetUtf8)
+	if c.Flags&flagBinary != 0 {
+		charset = charsetBinary
+	}
+	return p.uint16(charset).
+		uint32(c.Length).
+		byte1(c.Type).
+		uint16(c.Flags).
+		byte1(c.Decimals).
+		uint16(0)
+}
+
+// textValue the text protocol representation of a value
+func textValue(v interface{}) []byte {
+	switch vt := v.(type) {
+	case []byte:
+		return vt
+	case string:
+		return []byte(vt)
+	case int64:
+		return strconv.AppendInt(nil, vt, 10)
+	case int:
+		return strconv.AppendInt(nil, int64(vt), 10)
+	case float64:
+		return strconv.AppendFloat(nil, vt, 'g', -1, 64)
+	case bool:
+		if vt {
+			return []byte{'1'}
+		}
+		return []byte{'0'}
+	case time.Time:
+		return []byte(vt.Format(mysqlTimeFormat))
+	case value.Value:
+		return textValue(vt.Value())
+	case map[string]interface{}, []interface{}, []string, map[string]string,
+		map[string]int64, map[string]float64, map[string]bool, json.RawMessage:
+		by, err := json.Marshal(vt)
+		if err == nil {
+			return by
+		}
+	}
+	return []byte(fmt.Sprint(v))
+}
+
+// textColumnValue the text protocol representation of a value for column
+// type, sources such as csv hold times and bools as strings which clients
+// can't parse as the column type.
+func textColumnValue(typ byte, v interface{}) []byte {
+	switch typ {
+	case typeDateTime:
+		if t, ok := value.ValueToTime(value.NewValue(v)); ok {
+			return []byte(t.Format(mysqlTimeFormat))
+		}
+	case typeTiny:
+		if b, ok := value.ValueToBool(value.NewValue(v)); ok {
+			return textValue(b)
+		}
+	}
+	return textValue(v)
+}
+
+// appendBinaryValue append the binary protocol representation of a value
+// for column type, false if value is null or can't be converted.
+func appendBinaryValue(p packet, typ byte, v interface{}) (packet, bool) {
+	switch typ {
+	case typeLongLong:
+		i, ok := value.ValueToInt64(value.NewValue(v))
+		if !ok {
+			return p, false
+		}
+		return p.uint64(uint64(i)), true
+	case typeDouble:
+		f, ok := value.ValueToFloat64(value.NewValue(v))
+		if !ok || math.IsNaN(f) {
+			return p, false
+		}
+		return p.uint64(math.Float64bits(f)), true
+	case typeTiny:
+		b, ok := value.ValueToBool(value.NewValue(v))
+		if !ok {
+			return p, false
+		}
+		if b {
+			return p.byte1(1), true
+		}
+		return p.byte1(0), true
+	case typeDateTime:
+		t, ok := v.(time.Time)
+		if !ok {
+			if t, ok = value.ValueToTime(value.NewValue(v)); !ok {
+				return p, false
+			}
+		}
+		if t.IsZero() {
+			return p.byte1(0), true
+		}
+		return p.byte1(11).
+			uint16(uint16(t.Year())).
+			byte1(byte(t.Month())).
+			byte1(byte(t.Day())).
+			byte1(byte(t.Hour())).
+			byte1(byte(t.Minute())).
+			byte1(byte(t.Second())).
+			uint32(uint32(t.Nanosecond() / 1000)), true
+	}
+	return p.lenEncBytes(textValue(v)), true
+}
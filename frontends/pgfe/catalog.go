@@ -0,0 +1,433 @@
+package pgfe
+
+import (
+	"database/sql/driver"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+// namespace oids, the qlbridge schemas tables are all in public
+const (
+	nspCatalog    int32 = 11
+	nspPublic     int32 = 2200
+	nspInfoSchema int32 = 13000
+
+	ownerOid int32 = 10
+)
+
+var (
+	// Ensure catalog implements schema source interfaces
+	_ schema.Source      = (*catalog)(nil)
+	_ schema.ConnScanner = (*catalogConn)(nil)
+	_ schema.ConnColumns = (*catalogConn)(nil)
+)
+
+// tableOid a stable oid for a table name, the same in pg_class and the
+// table oids of RowDescription.
+func tableOid(name string) int32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 16384 + int32(h.Sum32()%(1<<30))
+}
+
+// catalogTable a pg_catalog or information_schema table whose rows are
+// derived from the schema when opened.
+type catalogTable struct {
+	name   string
+	nsp    int32
+	fields []*schema.Field
+}
+
+func field(name string, vt value.ValueType) *schema.Field {
+	return schema.NewFieldBase(name, vt, 0, "")
+}
+
+var catalogTables = []*catalogTable{
+	{name: "pg_namespace", nsp: nspCatalog, fields: []*schema.Field{
+		field("oid", value.IntType),
+		field("nspname", value.StringType),
+		field("nspowner", value.IntType),
+	}},
+	{name: "pg_class", nsp: nspCatalog, fields: []*schema.Field{
+		field("oid", value.IntType),
+		field("relname", value.StringType),
+		field("relnamespace", value.IntType),
+		field("reltype", value.IntType),
+		field("relowner", value.IntType),
+		field("relkind", value.StringType),
+		field("relnatts", value.IntType),
+		field("relhasindex", value.BoolType),
+		field("relpersistence", value.StringType),
+		field("relispartition", value.BoolType),
+	}},
+	{name: "pg_attribute", nsp: nspCatalog, fields: []*schema.Field{
+		field("attrelid", value.IntType),
+		field("attname", value.StringType),
+		field("atttypid", value.IntType),
+		field("attlen", value.IntType),
+		field("attnum", value.IntType),
+		field("atttypmod", value.IntType),
+		field("attnotnull", value.BoolType),
+		field("atthasdef", value.BoolType),
+		field("attisdropped", value.BoolType),
+	}},
+	{name: "pg_type", nsp: nspCatalog, fields: []*schema.Field{
+		field("oid", value.IntType),
+		field("typname", value.StringType),
+		field("typnamespace", value.IntType),
+		field("typowner", value.IntType),
+		field("typlen", value.IntType),
+		field("typtype", value.StringType),
+		field("typcategory", value.StringType),
+		field("typelem", value.IntType),
+		field("typarray", value.IntType),
+		field("typrelid", value.IntType),
+		field("typbasetype", value.IntType),
+		field("typtypmod", value.IntType),
+		field("typnotnull", value.BoolType),
+	}},
+	{name: "pg_tables", nsp: nspCatalog, fields: []*schema.Field{
+		field("schemaname", value.StringType),
+		field("tablename", value.StringType),
+		field("tableowner", value.StringType),
+		field("tablespace", value.StringType),
+		field("hasindexes", value.BoolType),
+		field("hasrules", value.BoolType),
+		field("hastriggers", value.BoolType),
+		field("rowsecurity", value.BoolType),
+	}},
+	{name: "pg_database", nsp: nspCatalog, fields: []*schema.Field{
+		field("oid", value.IntType),
+		field("datname", value.StringType),
+		field("datdba", value.IntType),
+		field("encoding", value.IntType),
+		field("datcollate", value.StringType),
+		field("datctype", value.StringType),
+		field("datistemplate", value.BoolType),
+		field("datallowconn", value.BoolType),
+	}},
+	{name: "schemata", nsp: nspInfoSchema, fields: []*schema.Field{
+		field("catalog_name", value.StringType),
+		field("schema_name", value.StringType),
+		field("schema_owner", value.StringType),
+	}},
+	{name: "tables", nsp: nspInfoSchema, fields: []*schema.Field{
+		field("table_catalog", value.StringType),
+		field("table_schema", value.StringType),
+		field("table_name", value.StringType),
+		field("table_type", value.StringType),
+	}},
+	{name: "columns", nsp: nspInfoSchema, fields: []*schema.Field{
+		field("table_catalog", value.StringType),
+		field("table_schema", value.StringType),
+		field("table_name", value.StringType),
+		field("column_name", value.StringType),
+		field("ordinal_position", value.IntType),
+		field("column_default", value.StringType),
+		field("is_nullable", value.StringType),
+		field("data_type", value.StringType),
+		field("character_maximum_length", value.IntType),
+		field("numeric_precision", value.IntType),
+		field("numeric_scale", value.IntType),
+		field("udt_name", value.StringType),
+	}},
+}
+
+// catalog is a source of the pg_catalog and information_schema tables
+// postgres clients introspect, derived from a qlbridge schema.
+type catalog struct {
+	s      *schema.Schema   // the schema described
+	reg    *schema.Registry // for pg_database
+	tables map[string]*schema.Table
+	names  []string
+}
+
+// newCatalogSchema a schema of the catalog tables describing s
+func newCatalogSchema(s *schema.Schema, reg *schema.Registry) (*schema.Schema, error) {
+	c := &catalog{s: s, reg: reg, tables: make(map[string]*schema.Table)}
+	for _, ct := range catalogTables {
+		t := schema.NewTable(ct.name)
+		for _, f := range ct.fields {
+			t.AddField(f)
+		}
+		t.SetColumnsFromFields()
+		c.tables[ct.name] = t
+		c.names = append(c.names, ct.name)
+	}
+
+	// catalog schemas are private to the front end, not in reg
+	applyer := schema.NewApplyer(datasource.SchemaDBStoreProvider)
+	creg := schema.NewRegistry(applyer)
+	applyer.Init(creg)
+	cs := schema.NewSchemaSource("pg_catalog", c)
+	if err := creg.SchemaAdd(cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Init the catalog source
+func (m *catalog) Init() {}
+
+// Setup the catalog source
+func (m *catalog) Setup(*schema.Schema) error { return nil }
+
+// Close the catalog source
+func (m *catalog) Close() error { return nil }
+
+// Tables list of catalog table names
+func (m *catalog) Tables() []string { return m.names }
+
+// Table get a catalog table
+func (m *catalog) Table(table string) (*schema.Table, error) {
+	if t, ok := m.tables[strings.ToLower(table)]; ok {
+		return t, nil
+	}
+	return nil, schema.ErrNotFound
+}
+
+// Open a catalog table, its rows are read from the schema now.
+func (m *catalog) Open(table string) (schema.Conn, error) {
+	table = strings.ToLower(table)
+	t, ok := m.tables[table]
+	if !ok {
+		return nil, schema.ErrNotFound
+	}
+	return &catalogConn{tbl: t, rows: m.rows(table)}, nil
+}
+
+// rows of a catalog table
+func (m *catalog) rows(table string) [][]driver.Value {
+	switch table {
+	case "pg_namespace":
+		return m.namespaceRows()
+	case "pg_class":
+		return m.classRows()
+	case "pg_attribute":
+		return m.attributeRows()
+	case "pg_type":
+		return m.typeRows()
+	case "pg_tables":
+		return m.pgTablesRows()
+	case "pg_database":
+		return m.databaseRows()
+	case "schemata":
+		return m.schemataRows()
+	case "tables":
+		return m.tablesRows()
+	case "columns":
+		return m.columnsRows()
+	}
+	return nil
+}
+
+// userTables the tables of the schema with their fields
+func (m *catalog) userTables() []*schema.Table {
+	names := append([]string{}, m.s.Tables()...)
+	sort.Strings(names)
+	tbls := make([]*schema.Table, 0, len(names))
+	for _, name := range names {
+		tbl, err := m.s.Table(name)
+		if err != nil || tbl == nil {
+			continue
+		}
+		if len(tbl.Fields) == 0 && len(tbl.Columns()) > 0 {
+			m.introspect(name)
+		}
+		tbls = append(tbls, tbl)
+	}
+	return tbls
+}
+
+func (m *catalog) introspect(table string) {
+	conn, err := m.s.OpenConn(table)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if scanner, ok := conn.(schema.ConnScanner); ok {
+		if err := datasource.IntrospectSchema(m.s, table, scanner); err != nil {
+			u.Debugf("could not introspect %q: %v", table, err)
+		}
+	}
+}
+
+func (m *catalog) namespaceRows() [][]driver.Value {
+	return [][]driver.Value{
+		{int64(nspCatalog), "pg_catalog", int64(ownerOid)},
+		{int64(nspPublic), "public", int64(ownerOid)},
+		{int64(nspInfoSchema), "information_schema", int64(ownerOid)},
+	}
+}
+
+func (m *catalog) classRows() [][]driver.Value {
+	var rows [][]driver.Value
+	for _, tbl := range m.userTables() {
+		rows = append(rows, []driver.Value{int64(tableOid(tbl.Name)), tbl.Name, int64(nspPublic),
+			int64(0), int64(ownerOid), "r", int64(len(tbl.Fields)), false, "p", false})
+	}
+	for _, ct := range catalogTables {
+		rows = append(rows, []driver.Value{int64(tableOid(ct.name)), ct.name, int64(ct.nsp),
+			int64(0), int64(ownerOid), "r", int64(len(ct.fields)), false, "p", false})
+	}
+	return rows
+}
+
+func (m *catalog) attributeRows() [][]driver.Value {
+	var rows [][]driver.Value
+	add := func(tbl string, fields []*schema.Field) {
+		for i, fld := range fields {
+			c := newColumn(fld.Name, fld.ValueType())
+			rows = append(rows, []driver.Value{int64(tableOid(tbl)), fld.Name, int64(c.TypeOid),
+				int64(c.TypeSize), int64(i + 1), int64(fieldTypeMod(c.TypeOid, fld)), fld.NoNulls,
+				hasDefault(fld), false})
+		}
+	}
+	for _, tbl := range m.userTables() {
+		add(tbl.Name, tbl.Fields)
+	}
+	for _, ct := range catalogTables {
+		add(ct.name, ct.fields)
+	}
+	return rows
+}
+
+func (m *catalog) typeRows() [][]driver.Value {
+	rows := make([][]driver.Value, 0, len(pgTypes))
+	for _, t := range pgTypes {
+		typtype := "b"
+		rows = append(rows, []driver.Value{int64(t.oid), t.name, int64(nspCatalog), int64(ownerOid),
+			int64(t.size), typtype, t.category, int64(t.elem), int64(t.array), int64(0), int64(0),
+			int64(-1), false})
+	}
+	return rows
+}
+
+func (m *catalog) pgTablesRows() [][]driver.Value {
+	var rows [][]driver.Value
+	for _, tbl := range m.userTables() {
+		rows = append(rows, []driver.Value{"public", tbl.Name, "qlbridge", nil, false, false, false, false})
+	}
+	return rows
+}
+
+func (m *catalog) databaseRows() [][]driver.Value {
+	names := append([]string{}, m.reg.Schemas()...)
+	sort.Strings(names)
+	rows := make([][]driver.Value, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []driver.Value{int64(tableOid(name)), name, int64(ownerOid), int64(6),
+			"C", "C", false, true})
+	}
+	return rows
+}
+
+func (m *catalog) schemataRows() [][]driver.Value {
+	return [][]driver.Value{
+		{m.s.Name, "pg_catalog", "qlbridge"},
+		{m.s.Name, "public", "qlbridge"},
+		{m.s.Name, "information_schema", "qlbridge"},
+	}
+}
+
+func (m *catalog) tablesRows() [][]driver.Value {
+	var rows [][]driver.Value
+	for _, tbl := range m.userTables() {
+		rows = append(rows, []driver.Value{m.s.Name, "public", tbl.Name, "BASE TABLE"})
+	}
+	return rows
+}
+
+func (m *catalog) columnsRows() [][]driver.Value {
+	var rows [][]driver.Value
+	for _, tbl := range m.userTables() {
+		for i, fld := range tbl.Fields {
+			oid := typeOid(fld.ValueType())
+			nullable := "YES"
+			if fld.NoNulls {
+				nullable = "NO"
+			}
+			var def, length, precision, scale driver.Value
+			if hasDefault(fld) {
+				def = string(fld.DefVal)
+			}
+			switch {
+			case oid == oidVarchar && fld.Length > 0:
+				length = int64(fld.Length)
+			case oid == oidNumeric && fld.Precision > 0:
+				precision, scale = int64(fld.Precision), int64(fld.Scale)
+			}
+			udt := "text"
+			if t, ok := typeByOid(oid); ok {
+				udt = t.name
+			}
+			rows = append(rows, []driver.Value{m.s.Name, "public", tbl.Name, fld.Name, int64(i + 1),
+				def, nullable, typeName(oid), length, precision, scale, udt})
+		}
+	}
+	return rows
+}
+
+// hasDefault does the field have a default, DefVal is json of the value
+func hasDefault(fld *schema.Field) bool {
+	return len(fld.DefVal) > 0 && string(fld.DefVal) != "null"
+}
+
+// catalogConn scans the rows of a catalog table
+type catalogConn struct {
+	tbl    *schema.Table
+	rows   [][]driver.Value
+	cursor int
+}
+
+func (m *catalogConn) Close() error      { return nil }
+func (m *catalogConn) Columns() []string { return m.tbl.Columns() }
+func (m *catalogConn) Next() schema.Message {
+	if m.cursor >= len(m.rows) {
+		return nil
+	}
+	msg := datasource.NewSqlDriverMessageMap(uint64(m.cursor), m.rows[m.cursor], m.tbl.FieldPositions)
+	m.cursor++
+	return msg
+}
+
+// usesCatalog does the statement select from catalog tables, which are
+// qualified by pg_catalog or information_schema, or unqualified names of
+// catalog tables the schema doesn't have.
+func usesCatalog(stmt rel.SqlStatement, s *schema.Schema) bool {
+	sel, ok := stmt.(*rel.SqlSelect)
+	if !ok {
+		return false
+	}
+	for _, src := range sel.From {
+		if src.SubQuery != nil {
+			if usesCatalog(src.SubQuery, s) {
+				return true
+			}
+			continue
+		}
+		nsp, name := src.Schema, src.Name
+		if left, right, ok := expr.LeftRight(name); ok {
+			nsp, name = left, right
+		}
+		switch strings.ToLower(nsp) {
+		case "pg_catalog", "information_schema":
+			return true
+		}
+		if strings.HasPrefix(strings.ToLower(name), "pg_") {
+			if _, err := s.Table(name); err != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
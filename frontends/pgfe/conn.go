@@ -0,0 +1,569 @@
+package pgfe
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+// Error a postgres error with sqlstate code sent to clients
+type Error struct {
+	Severity string // ERROR or FATAL
+	Code     string
+	Msg      string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Msg, e.Code) }
+
+func newError(code, format string, args ...interface{}) *Error {
+	return &Error{Severity: "ERROR", Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+func newFatal(code, format string, args ...interface{}) *Error {
+	return &Error{Severity: "FATAL", Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// statementError a statement error for err, errors planning statements
+// are syntax or access errors, others internal.
+func statementError(code string, err error) *Error {
+	if perr, ok := err.(*Error); ok {
+		return perr
+	}
+	return newError(code, "%v", err)
+}
+
+// statusParams the parameters reported to clients with ParameterStatus,
+// by their case insensitive name.
+var statusParams = []string{"server_version", "server_encoding", "client_encoding", "DateStyle",
+	"TimeZone", "integer_datetimes", "standard_conforming_strings", "IntervalStyle", "is_superuser",
+	"session_authorization", "application_name"}
+
+// conn a client connection
+type conn struct {
+	*msgConn
+	s       *Server
+	pid     int32
+	secret  int32
+	user    string
+	params  map[string]string // session parameters by lower case name, SET/SHOW
+	schema  *schema.Schema
+	funcs   *expr.FuncRegistry
+	session expr.ContextReadWriter
+	stmts   map[string]*stmt
+	portals map[string]*portal
+	failed  bool // an extended query message failed, skip until Sync
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu          sync.Mutex
+	queryCancel context.CancelFunc
+}
+
+func (c *conn) close() {
+	c.cancel()
+	c.conn.Close()
+}
+
+// cancelQuery cancel the running statement, if any
+func (c *conn) cancelQuery() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.queryCancel != nil {
+		c.queryCancel()
+	}
+}
+
+// queryContext a context for a running statement, cancelled by a cancel
+// request, the returned func must be called when it finishes.
+func (c *conn) queryContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.mu.Lock()
+	c.queryCancel = cancel
+	c.mu.Unlock()
+	return ctx, func() {
+		c.mu.Lock()
+		c.queryCancel = nil
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// serve the connection, startup then messages until terminate
+func (c *conn) serve() error {
+	ok, err := c.startup()
+	if err != nil {
+		if perr, isPg := err.(*Error); isPg {
+			c.writeError(perr)
+			c.flush()
+		}
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	defer c.closePortals()
+	for {
+		typ, data, err := c.readMessage()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if typ == 'X' {
+			return nil
+		}
+		if err := c.dispatch(typ, data); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch a message, errors returned are connection errors, statement
+// errors are written to the client.
+func (c *conn) dispatch(typ byte, data []byte) error {
+	var err error
+	switch typ {
+	case 'Q':
+		r := &reader{b: data}
+		err = c.simpleQuery(r.string())
+		if err == nil {
+			err = c.readyForQuery()
+		}
+		if err == nil {
+			err = c.flush()
+		}
+		return err
+	case 'S':
+		c.closePortals()
+		c.failed = false
+		if err = c.readyForQuery(); err != nil {
+			return err
+		}
+		return c.flush()
+	case 'H':
+		return c.flush()
+	case 'P', 'B', 'D', 'E', 'C':
+		if c.failed {
+			return nil
+		}
+		switch typ {
+		case 'P':
+			err = c.parse(data)
+		case 'B':
+			err = c.bind(data)
+		case 'D':
+			err = c.describe(data)
+		case 'E':
+			err = c.execute(data)
+		case 'C':
+			err = c.closeMsg(data)
+		}
+	default:
+		err = newError("08P01", "unsupported frontend message type %q", typ)
+	}
+	if perr, ok := err.(*Error); ok {
+		c.failed = true
+		return c.writeError(perr)
+	}
+	return err
+}
+
+// startup read the startup message, authenticate and select the schema,
+// false if the connection was a cancel request.
+func (c *conn) startup() (bool, error) {
+	var params map[string]string
+	for params == nil {
+		data, err := c.readStartup()
+		if err != nil {
+			return false, err
+		}
+		r := &reader{b: data}
+		switch code := r.int32(); code {
+		case sslRequestCode, gssRequestCode:
+			if err := c.writeByte('N'); err != nil {
+				return false, err
+			}
+			if err := c.flush(); err != nil {
+				return false, err
+			}
+		case cancelRequestCode:
+			pid, secret := r.int32(), r.int32()
+			c.s.cancelQuery(pid, secret)
+			return false, nil
+		case protocolVersion3:
+			params = make(map[string]string)
+			for {
+				k := r.string()
+				if k == "" || r.err != nil {
+					break
+				}
+				params[k] = r.string()
+			}
+		default:
+			return false, newFatal("0A000", "unsupported frontend protocol %d.%d", code>>16, code&0xffff)
+		}
+	}
+
+	c.user = params["user"]
+	if c.user == "" {
+		return false, newFatal("28000", "no PostgreSQL user name specified in startup packet")
+	}
+	if err := c.authenticate(); err != nil {
+		return false, err
+	}
+
+	dbName := params["database"]
+	if dbName == "" {
+		dbName = c.s.Schema
+	}
+	s, ok := c.s.Registry.Schema(strings.ToLower(dbName))
+	if !ok {
+		return false, newFatal("3D000", "database %q does not exist", dbName)
+	}
+	c.schema = s
+	c.funcs = c.newFuncs()
+	c.session = datasource.NewMySqlSessionVars()
+
+	for k, v := range map[string]string{
+		"server_version":              ServerVersion,
+		"server_encoding":             "UTF8",
+		"client_encoding":             "UTF8",
+		"DateStyle":                   "ISO, MDY",
+		"TimeZone":                    "UTC",
+		"integer_datetimes":           "on",
+		"standard_conforming_strings": "on",
+		"IntervalStyle":               "postgres",
+		"is_superuser":                "off",
+		"session_authorization":       c.user,
+		"application_name":            params["application_name"],
+		"search_path":                 "public",
+		"transaction_isolation":       "read committed",
+		"max_identifier_length":       "63",
+	} {
+		c.params[strings.ToLower(k)] = v
+	}
+
+	if err := c.writeMessage('R', message{}.int32(0)); err != nil {
+		return false, err
+	}
+	for _, name := range statusParams {
+		if err := c.parameterStatus(name); err != nil {
+			return false, err
+		}
+	}
+	if err := c.writeMessage('K', message{}.int32(c.pid).int32(c.secret)); err != nil {
+		return false, err
+	}
+	if err := c.readyForQuery(); err != nil {
+		return false, err
+	}
+	return true, c.flush()
+}
+
+// authenticate md5 password authentication if the server has users
+func (c *conn) authenticate() error {
+	if len(c.s.Users) == 0 {
+		return nil
+	}
+	var salt [4]byte
+	rand.Read(salt[:])
+	if err := c.writeMessage('R', message{}.int32(5).byte1(salt[0]).byte1(salt[1]).byte1(salt[2]).byte1(salt[3])); err != nil {
+		return err
+	}
+	if err := c.flush(); err != nil {
+		return err
+	}
+	typ, data, err := c.readMessage()
+	if err != nil {
+		return err
+	}
+	if typ != 'p' {
+		return newFatal("08P01", "expected password response, got message type %q", typ)
+	}
+	r := &reader{b: data}
+	response := r.string()
+	password, ok := c.s.Users[c.user]
+	if !ok || response != md5Password(c.user, password, salt[:]) {
+		return newFatal("28P01", "password authentication failed for user %q", c.user)
+	}
+	return nil
+}
+
+// md5Password the md5 password response, "md5" + md5(md5(password + user) + salt)
+func md5Password(user, password string, salt []byte) string {
+	h := md5.Sum([]byte(password + user))
+	h = md5.Sum(append([]byte(hex.EncodeToString(h[:])), salt...))
+	return "md5" + hex.EncodeToString(h[:])
+}
+
+func (c *conn) parameterStatus(name string) error {
+	return c.writeMessage('S', message{}.string(name).string(c.params[strings.ToLower(name)]))
+}
+
+func (c *conn) readyForQuery() error {
+	return c.writeMessage('Z', message{}.byte1('I'))
+}
+
+// writeError write an ErrorResponse
+func (c *conn) writeError(err *Error) error {
+	m := message{}.
+		byte1('S').string(err.Severity).
+		byte1('V').string(err.Severity).
+		byte1('C').string(err.Code).
+		byte1('M').string(err.Msg).
+		byte1(0)
+	return c.writeMessage('E', m)
+}
+
+// simpleQuery run the statements of a Query message, the first error
+// ends the query.
+func (c *conn) simpleQuery(query string) error {
+	c.closePortal("")
+	stmts := splitStatements(translate(query))
+	if len(stmts) == 0 {
+		return c.writeMessage('I', nil)
+	}
+	for _, sql := range stmts {
+		p, err := c.newPortal("", sql)
+		if err == nil {
+			if len(p.cols) > 0 {
+				err = c.writeMessage('T', rowDescription(p.cols))
+			}
+			if err == nil {
+				err = c.executePortal(p, 0)
+			}
+			p.close()
+		}
+		if perr, ok := err.(*Error); ok {
+			return c.writeError(perr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPortal plan a statement ready to execute, statements about the
+// session (SET, SHOW, BEGIN) are handled here not by qlbridge.
+func (c *conn) newPortal(name, sql string) (*portal, error) {
+	p := &portal{name: name, sql: sql}
+	if c.sessionStatement(p) {
+		return p, nil
+	}
+
+	job, err := c.buildJob(sql)
+	if err != nil {
+		return nil, statementError("42601", err)
+	}
+	p.job = job
+	if _, ok := job.Ctx.Stmt.(*rel.SqlSelect); ok {
+		p.isSelect = true
+		p.cols = resultColumns(job.Ctx)
+	}
+	return p, nil
+}
+
+// buildJob plan a statement against the connections schema, or the
+// catalog for selects from pg_catalog tables.  A panic while parsing or
+// planning fails the statement not the server.
+func (c *conn) buildJob(sql string) (job *exec.JobExecutor, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			u.Errorf("postgres conn %d panic planning %q: %v", c.pid, sql, r)
+			job, err = nil, newError("42601", "could not parse or plan statement")
+		}
+	}()
+	s := c.schema
+	if stmt, err := rel.ParseSql(sql); err == nil && usesCatalog(stmt, s) {
+		if s, err = c.s.catalog(s); err != nil {
+			return nil, err
+		}
+	}
+	ctx := plan.NewContext(sql)
+	ctx.Context = c.ctx
+	ctx.Schema = s
+	ctx.Session = c.session
+	ctx.Funcs = expr.LenientFuncs(funcChain{c.funcs, s.Funcs()})
+	return exec.BuildSqlJob(ctx)
+}
+
+// sessionStatement handle transaction and session parameter statements,
+// false if the statement is for qlbridge.
+func (c *conn) sessionStatement(p *portal) bool {
+	words := strings.Fields(strings.ToLower(strings.TrimRight(p.sql, "; \t\n")))
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "begin", "start":
+		p.tag = "BEGIN"
+		if words[0] == "start" {
+			p.tag = "START TRANSACTION"
+		}
+	case "commit", "end":
+		p.tag = "COMMIT"
+	case "rollback", "abort":
+		p.tag = "ROLLBACK"
+	case "discard", "reset":
+		p.tag = strings.ToUpper(strings.Join(words, " "))
+	case "deallocate":
+		if name := strings.Trim(words[len(words)-1], `"`); name != "all" {
+			delete(c.stmts, name)
+		} else {
+			c.stmts = make(map[string]*stmt)
+		}
+		p.tag = "DEALLOCATE"
+	case "set":
+		name, val, ok := parseSet(p.sql)
+		if !ok {
+			return false
+		}
+		c.params[strings.ToLower(name)] = val
+		p.tag = "SET"
+		for _, sp := range statusParams {
+			if strings.EqualFold(sp, name) {
+				p.status = sp
+			}
+		}
+	case "show":
+		if len(words) != 2 {
+			return false
+		}
+		name := strings.Trim(words[1], "`")
+		val, ok := c.params[name]
+		if !ok {
+			return false
+		}
+		p.tag = "SHOW"
+		p.cols = []*column{newColumn(name, value.StringType)}
+		p.rows = [][]driver.Value{{val}}
+	default:
+		return false
+	}
+	return true
+}
+
+// parseSet the name and value of SET [SESSION|LOCAL] name {=|TO} value
+func parseSet(sql string) (string, string, bool) {
+	s := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(sql), ";"))
+	s = strings.TrimSpace(s[len("set"):])
+	for _, scope := range []string{"session ", "local "} {
+		if strings.HasPrefix(strings.ToLower(s), scope) {
+			s = strings.TrimSpace(s[len(scope):])
+		}
+	}
+	if strings.HasPrefix(s, "@") {
+		// qlbridge session variables
+		return "", "", false
+	}
+	var name, val string
+	if i := strings.IndexByte(s, '='); i > 0 {
+		name, val = s[:i], s[i+1:]
+	} else if f := strings.Fields(s); len(f) >= 3 && strings.EqualFold(f[1], "to") {
+		name, val = f[0], strings.Join(f[2:], " ")
+	} else {
+		return "", "", false
+	}
+	name = strings.Trim(strings.TrimSpace(name), "`")
+	val = strings.TrimSpace(val)
+	if len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'' {
+		val = strings.Replace(val[1:len(val)-1], "''", "'", -1)
+	}
+	return name, val, name != ""
+}
+
+// resultColumns the columns of a select, columns that are a field of the
+// (single) table selected from are typed from that field.
+func resultColumns(ctx *plan.Context) []*column {
+	if ctx.Projection == nil || ctx.Projection.Proj == nil {
+		return nil
+	}
+	var tbl *schema.Table
+	if sel, ok := ctx.Stmt.(*rel.SqlSelect); ok && len(sel.From) == 1 && ctx.Schema != nil {
+		tbl, _ = ctx.Schema.Table(sel.From[0].Name)
+	}
+	cols := make([]*column, 0, len(ctx.Projection.Proj.Columns))
+	for _, rc := range ctx.Projection.Proj.Columns {
+		name := rc.As
+		if name == "" {
+			name = rc.Name
+		}
+		if tbl != nil && (rc.Col == nil || rc.Col.Expr == nil || isIdentity(rc.Col.Expr)) {
+			if fld, ok := tbl.FieldMap[rc.SourceName()]; ok {
+				c := fieldColumn(tbl, fld)
+				c.Name = name
+				cols = append(cols, c)
+				continue
+			}
+		}
+		cols = append(cols, newColumn(name, rc.Type))
+	}
+	return cols
+}
+
+func isIdentity(n expr.Node) bool {
+	_, ok := n.(*expr.IdentityNode)
+	return ok
+}
+
+// commandTag the CommandComplete tag of a statement
+func commandTag(p *portal, rows int64) string {
+	switch p.job.Ctx.Stmt.(type) {
+	case *rel.SqlSelect:
+		return fmt.Sprintf("SELECT %d", rows)
+	case *rel.SqlInsert, *rel.SqlUpsert:
+		return fmt.Sprintf("INSERT 0 %d", rows)
+	case *rel.SqlUpdate:
+		return fmt.Sprintf("UPDATE %d", rows)
+	case *rel.SqlDelete:
+		return fmt.Sprintf("DELETE %d", rows)
+	}
+	words := strings.Fields(strings.ToUpper(p.sql))
+	switch {
+	case len(words) == 0:
+		return ""
+	case len(words) >= 4 && words[1] == "OR" && words[2] == "REPLACE":
+		return words[0] + " " + words[3]
+	case len(words) >= 2 && (words[0] == "CREATE" || words[0] == "DROP" || words[0] == "ALTER"):
+		return words[0] + " " + words[1]
+	}
+	return words[0]
+}
+
+// closePortals close all portals, at Sync as there are no transactions
+func (c *conn) closePortals() {
+	names := make([]string, 0, len(c.portals))
+	for name := range c.portals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.closePortal(name)
+	}
+}
+
+func (c *conn) closePortal(name string) {
+	if p, ok := c.portals[name]; ok {
+		p.close()
+		delete(c.portals, name)
+	}
+}
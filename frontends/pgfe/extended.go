@@ -0,0 +1,345 @@
+package pgfe
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/araddon/qlbridge/exec"
+	"github.com/araddon/qlbridge/schema"
+)
+
+// stmt a prepared statement of the extended query protocol
+type stmt struct {
+	name      string
+	query     string  // translated to the qlbridge dialect, with $n params
+	paramOids []int32 // as given in Parse, 0 (unspecified) are bound as text
+}
+
+// portal a statement ready to execute, with bound params
+type portal struct {
+	name     string
+	sql      string
+	job      *exec.JobExecutor // nil for session statements
+	isSelect bool
+	cols     []*column
+	tag      string           // command tag of session statements
+	status   string           // parameter to report after a SET
+	rows     [][]driver.Value // rows of session statements (SHOW)
+	stream   *rowStream       // running select, between Execute messages
+	count    int64            // rows sent
+	done     bool
+}
+
+func (p *portal) close() {
+	if p.stream != nil {
+		p.stream.close()
+		p.stream = nil
+	} else if p.job != nil && !p.done {
+		p.job.Close()
+	}
+	p.done = true
+}
+
+// rowStream the rows of a running select job
+type rowStream struct {
+	job    *exec.JobExecutor
+	msgs   <-chan schema.Message
+	runErr chan error
+	ctx    context.Context
+	finish func()
+	closed bool
+}
+
+// startRows setup and run a select job, the job runs until all its rows
+// are read or the stream is closed.
+func (c *conn) startRows(p *portal) (*rowStream, error) {
+	names := make([]string, len(p.cols))
+	for i, col := range p.cols {
+		names[i] = col.Name
+	}
+	rw := exec.NewResultRows(p.job.Ctx, names)
+	p.job.RootTask.Add(rw)
+	if err := p.job.Setup(); err != nil {
+		p.job.Close()
+		return nil, newError("XX000", "%v", err)
+	}
+	ctx, finish := c.queryContext()
+	rs := &rowStream{job: p.job, msgs: rw.MessageIn(), runErr: make(chan error, 1), ctx: ctx, finish: finish}
+	go func() {
+		rs.runErr <- p.job.Run()
+	}()
+	return rs, nil
+}
+
+// next row, nil at the end of the rows
+func (rs *rowStream) next() (schema.Message, error) {
+	select {
+	case <-rs.ctx.Done():
+		rs.close()
+		return nil, newError("57014", "canceling statement due to user request")
+	case msg, ok := <-rs.msgs:
+		if !ok || msg == nil {
+			return nil, rs.close()
+		}
+		return msg, nil
+	}
+}
+
+// close the job, the result writer doesn't finish until it is closed
+func (rs *rowStream) close() error {
+	if rs.closed {
+		return nil
+	}
+	rs.closed = true
+	rs.job.Close()
+	err := <-rs.runErr
+	rs.finish()
+	if err != nil {
+		return newError("XX000", "%v", err)
+	}
+	return nil
+}
+
+// parse a Parse message, statement name, query and param type oids
+func (c *conn) parse(data []byte) error {
+	r := &reader{b: data}
+	st := &stmt{name: r.string(), query: translate(r.string())}
+	n := int(r.int16())
+	for i := 0; i < n; i++ {
+		st.paramOids = append(st.paramOids, r.int32())
+	}
+	if r.err != nil {
+		return newError("08P01", "invalid Parse message")
+	}
+	stmts := splitStatements(st.query)
+	if len(stmts) > 1 {
+		return newError("42601", "cannot insert multiple commands into a prepared statement")
+	}
+	if len(stmts) == 1 {
+		st.query = stmts[0]
+	}
+	if st.name != "" {
+		if _, exists := c.stmts[st.name]; exists {
+			return newError("42P05", "prepared statement %q already exists", st.name)
+		}
+	}
+	for len(st.paramOids) < paramCount(st.query) {
+		st.paramOids = append(st.paramOids, 0)
+	}
+	c.stmts[st.name] = st
+	return c.writeMessage('1', nil)
+}
+
+// bind a Bind message, creating a portal of a statement with params
+func (c *conn) bind(data []byte) error {
+	r := &reader{b: data}
+	portalName, stmtName := r.string(), r.string()
+	paramFormats := make([]int16, r.int16())
+	for i := range paramFormats {
+		paramFormats[i] = r.int16()
+	}
+	params := make([][]byte, r.int16())
+	for i := range params {
+		params[i] = r.bytes()
+	}
+	resultFormats := make([]int16, r.int16())
+	for i := range resultFormats {
+		resultFormats[i] = r.int16()
+	}
+	if r.err != nil {
+		return newError("08P01", "invalid Bind message")
+	}
+	st, ok := c.stmts[stmtName]
+	if !ok {
+		return newError("26000", "prepared statement %q does not exist", stmtName)
+	}
+	if len(params) != len(st.paramOids) {
+		return newError("08P01", "bind message supplies %d parameters, but prepared statement %q requires %d",
+			len(params), stmtName, len(st.paramOids))
+	}
+
+	args := make([]interface{}, len(params))
+	for i, b := range params {
+		format := formatText
+		switch {
+		case len(paramFormats) == 1:
+			format = paramFormats[0]
+		case len(paramFormats) > i:
+			format = paramFormats[i]
+		}
+		v, err := paramValue(st.paramOids[i], format, b)
+		if err != nil {
+			return newError("22P02", "%v", err)
+		}
+		args[i] = v
+	}
+	sql, err := interpolate(st.query, args)
+	if err != nil {
+		return newError("08P01", "%v", err)
+	}
+
+	c.closePortal(portalName)
+	p, err := c.newPortal(portalName, sql)
+	if err != nil {
+		return err
+	}
+	for i, col := range p.cols {
+		switch {
+		case len(resultFormats) == 1:
+			col.Format = resultFormats[0]
+		case len(resultFormats) > i:
+			col.Format = resultFormats[i]
+		}
+	}
+	c.portals[portalName] = p
+	return c.writeMessage('2', nil)
+}
+
+// describe a Describe message of a statement ('S') or portal ('P')
+func (c *conn) describe(data []byte) error {
+	r := &reader{b: data}
+	kind, name := r.byte1(), r.string()
+	if r.err != nil {
+		return newError("08P01", "invalid Describe message")
+	}
+	var cols []*column
+	switch kind {
+	case 'S':
+		st, ok := c.stmts[name]
+		if !ok {
+			return newError("26000", "prepared statement %q does not exist", name)
+		}
+		m := message{}.int16(int16(len(st.paramOids)))
+		for _, oid := range st.paramOids {
+			if oid == 0 {
+				oid = oidText
+			}
+			m = m.int32(oid)
+		}
+		if err := c.writeMessage('t', m); err != nil {
+			return err
+		}
+		// plan with NULL params for the result columns
+		sql, err := interpolate(st.query, make([]interface{}, len(st.paramOids)))
+		if err != nil {
+			return newError("08P01", "%v", err)
+		}
+		p, err := c.newPortal("", sql)
+		if err != nil {
+			return err
+		}
+		cols = p.cols
+		p.close()
+	case 'P':
+		p, ok := c.portals[name]
+		if !ok {
+			return newError("34000", "portal %q does not exist", name)
+		}
+		cols = p.cols
+	default:
+		return newError("08P01", "invalid Describe message subtype %q", kind)
+	}
+	if len(cols) == 0 {
+		return c.writeMessage('n', nil)
+	}
+	return c.writeMessage('T', rowDescription(cols))
+}
+
+// execute an Execute message, a portal and max rows (0 for all)
+func (c *conn) execute(data []byte) error {
+	r := &reader{b: data}
+	name, maxRows := r.string(), r.int32()
+	if r.err != nil {
+		return newError("08P01", "invalid Execute message")
+	}
+	p, ok := c.portals[name]
+	if !ok {
+		return newError("34000", "portal %q does not exist", name)
+	}
+	return c.executePortal(p, int64(maxRows))
+}
+
+// executePortal run a portal writing its rows and CommandComplete, or
+// PortalSuspended after maxRows rows if maxRows > 0.
+func (c *conn) executePortal(p *portal, maxRows int64) error {
+	if p.done {
+		return newError("55000", "portal %q cannot be run", p.name)
+	}
+	if p.job == nil {
+		for _, row := range p.rows {
+			if err := c.writeMessage('D', dataRow(p.cols, row)); err != nil {
+				return err
+			}
+		}
+		p.done = true
+		if p.status != "" {
+			if err := c.parameterStatus(p.status); err != nil {
+				return err
+			}
+		}
+		return c.writeMessage('C', message{}.string(p.tag))
+	}
+
+	if !p.isSelect {
+		p.done = true
+		ctx, finish := c.queryContext()
+		defer finish()
+		p.job.Ctx.Context = ctx
+		rw := exec.NewResultExecWriter(p.job.Ctx)
+		p.job.RootTask.Add(rw)
+		if err := p.job.Setup(); err != nil {
+			p.job.Close()
+			return newError("XX000", "%v", err)
+		}
+		err := p.job.Run()
+		p.job.Close()
+		if err != nil {
+			return newError("XX000", "%v", err)
+		}
+		affected, _ := rw.Result().RowsAffected()
+		return c.writeMessage('C', message{}.string(commandTag(p, affected)))
+	}
+
+	if p.stream == nil {
+		rs, err := c.startRows(p)
+		if err != nil {
+			p.done = true
+			return err
+		}
+		p.stream = rs
+	}
+	for sent := int64(0); maxRows <= 0 || sent < maxRows; sent++ {
+		msg, err := p.stream.next()
+		if err != nil || msg == nil {
+			p.stream = nil
+			p.done = true
+			if err != nil {
+				return err
+			}
+			return c.writeMessage('C', message{}.string(commandTag(p, p.count)))
+		}
+		if err := c.writeMessage('D', dataRow(p.cols, exec.MessageValues(msg, len(p.cols)))); err != nil {
+			return err
+		}
+		p.count++
+	}
+	return c.writeMessage('s', nil)
+}
+
+// closeMsg a Close message of a statement ('S') or portal ('P')
+func (c *conn) closeMsg(data []byte) error {
+	r := &reader{b: data}
+	kind, name := r.byte1(), r.string()
+	if r.err != nil {
+		return newError("08P01", "invalid Close message")
+	}
+	switch kind {
+	case 'S':
+		delete(c.stmts, name)
+	case 'P':
+		c.closePortal(name)
+	default:
+		return newError("08P01", "invalid Close message subtype %q", kind)
+	}
+	return c.writeMessage('3', nil)
+}
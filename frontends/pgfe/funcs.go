@@ -0,0 +1,80 @@
+package pgfe
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+// pgFunc a postgres builtin clients call when introspecting
+type pgFunc struct {
+	name string
+	args []int // allowed arg counts
+	typ  value.ValueType
+	eval expr.EvaluatorFunc
+}
+
+// Type of the function result
+func (m *pgFunc) Type() value.ValueType { return m.typ }
+
+// Validate the arg count
+func (m *pgFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	for _, ct := range m.args {
+		if len(n.Args) == ct {
+			return m.eval, nil
+		}
+	}
+	return nil, fmt.Errorf("wrong number of args for %s() got %s", m.name, n)
+}
+
+// funcChain resolves functions from the first resolver that has them
+type funcChain []expr.FuncResolver
+
+func (m funcChain) FuncGet(name string) (expr.Func, bool) {
+	for _, fr := range m {
+		if fr == nil {
+			continue
+		}
+		if fn, ok := fr.FuncGet(name); ok {
+			return fn, true
+		}
+	}
+	return expr.Func{}, false
+}
+
+// newFuncs the postgres builtins of a connection
+func (c *conn) newFuncs() *expr.FuncRegistry {
+	fr := expr.NewFuncRegistry()
+	add := func(f *pgFunc) { fr.Add(f.name, f) }
+	str := func(s func() string) expr.EvaluatorFunc {
+		return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+			return value.NewStringValue(s()), true
+		}
+	}
+
+	add(&pgFunc{name: "version", typ: value.StringType,
+		eval: str(func() string { return "PostgreSQL " + ServerVersion }), args: []int{0}})
+	add(&pgFunc{name: "current_database", typ: value.StringType,
+		eval: str(func() string { return c.schema.Name }), args: []int{0}})
+	add(&pgFunc{name: "current_schema", typ: value.StringType,
+		eval: str(func() string { return "public" }), args: []int{0}})
+	add(&pgFunc{name: "current_user", typ: value.StringType,
+		eval: str(func() string { return c.user }), args: []int{0}})
+	add(&pgFunc{name: "pg_table_is_visible", typ: value.BoolType, args: []int{1},
+		eval: func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+			return value.NewBoolValue(true), true
+		}})
+	add(&pgFunc{name: "format_type", typ: value.StringType, args: []int{1, 2},
+		eval: func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+			if len(args) == 0 || args[0] == nil || args[0].Nil() {
+				return value.NilValueVal, false
+			}
+			oid, ok := value.ValueToInt64(args[0])
+			if !ok {
+				return value.NilValueVal, false
+			}
+			return value.NewStringValue(typeName(int32(oid))), true
+		}})
+	return fr
+}
@@ -0,0 +1,196 @@
+package pgfe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// startup packet protocol codes, in place of the protocol version
+const (
+	protocolVersion3  = 196608   // 3.0
+	sslRequestCode    = 80877103 // 1234.5679
+	gssRequestCode    = 80877104 // 1234.5680
+	cancelRequestCode = 80877102 // 1234.5678
+
+	// maxMessageLen is the largest message a client may send, there is no
+	// limit in the protocol but a length this large is a protocol error.
+	maxMessageLen = 1 << 30
+)
+
+// msgConn reads and writes postgres protocol messages, a type byte then
+// int32 length (including itself) and body.  Writes are buffered until
+// flush.
+type msgConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func newMsgConn(nc net.Conn) *msgConn {
+	return &msgConn{
+		conn: nc,
+		r:    bufio.NewReaderSize(nc, 16*1024),
+		w:    bufio.NewWriterSize(nc, 16*1024),
+	}
+}
+
+// readStartup read the untyped startup, ssl or cancel request
+func (m *msgConn) readStartup() ([]byte, error) {
+	return m.readBody()
+}
+
+// readMessage read a typed message
+func (m *msgConn) readMessage() (byte, []byte, error) {
+	typ, err := m.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := m.readBody()
+	return typ, data, err
+}
+
+func (m *msgConn) readBody() ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(m.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(hdr[:]))
+	if n < 4 || n > maxMessageLen {
+		return nil, fmt.Errorf("invalid message length %d", n)
+	}
+	data := make([]byte, n-4)
+	if _, err := io.ReadFull(m.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeMessage write a typed message, buffered until flush
+func (m *msgConn) writeMessage(typ byte, body message) error {
+	var hdr [5]byte
+	hdr[0] = typ
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(body)+4))
+	if _, err := m.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := m.w.Write(body)
+	return err
+}
+
+// writeByte write a single untyped byte, the ssl request response
+func (m *msgConn) writeByte(b byte) error {
+	return m.w.WriteByte(b)
+}
+
+func (m *msgConn) flush() error {
+	return m.w.Flush()
+}
+
+// message a message body builder
+type message []byte
+
+func (m message) byte1(b byte) message {
+	return append(m, b)
+}
+
+func (m message) int16(v int16) message {
+	return append(m, byte(v>>8), byte(v))
+}
+
+func (m message) int32(v int32) message {
+	return append(m, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (m message) int64(v int64) message {
+	return m.int32(int32(v >> 32)).int32(int32(v))
+}
+
+// string a null terminated string
+func (m message) string(s string) message {
+	return append(append(m, s...), 0)
+}
+
+// bytes a length prefixed value, nil is sql NULL
+func (m message) bytes(b []byte) message {
+	if b == nil {
+		return m.int32(-1)
+	}
+	return append(m.int32(int32(len(b))), b...)
+}
+
+// reader reads a message body, after the first error reads return zero
+// values and err is set.
+type reader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.b) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) byte1() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) int16() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *reader) int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+// string a null terminated string
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i := r.pos; i < len(r.b); i++ {
+		if r.b[i] == 0 {
+			s := string(r.b[r.pos:i])
+			r.pos = i + 1
+			return s
+		}
+	}
+	r.err = io.ErrUnexpectedEOF
+	return ""
+}
+
+// bytes a length prefixed value, nil for sql NULL (-1 length)
+func (r *reader) bytes() []byte {
+	n := r.int32()
+	if n == -1 || r.err != nil {
+		return nil
+	}
+	b := r.next(int(n))
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
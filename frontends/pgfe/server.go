@@ -0,0 +1,194 @@
+// Package pgfe is a postgres frontend/backend protocol (v3) front end, so
+// postgres clients (psql, drivers, BI tools) can query qlbridge schemas.
+//
+//    s := pgfe.NewServer(schema.DefaultRegistry(), "mydb")
+//    s.Users = map[string]string{"postgres": "secret"}
+//    log.Fatal(s.ListenAndServe(":5432"))
+//
+// The startup database selects the schema statements run against with
+// exec.BuildSqlJob.  Both the simple and extended (Parse, Bind, Describe,
+// Execute) query protocols are supported, RowDescription type oids are
+// mapped from the value.ValueType of the projection, which for table
+// columns comes from their schema.Field.
+//
+// Postgres sql is translated to the qlbridge dialect: "quoted" identifiers
+// and ::type casts, $n params are bound as literals.  Selects from
+// pg_catalog (pg_namespace, pg_class, pg_attribute, pg_type, pg_tables,
+// pg_database) and information_schema (schemata, tables, columns) run
+// against tables derived from the schema, so clients can introspect it.
+// There is no TLS (ssl requests are refused) or transaction support,
+// BEGIN/COMMIT/ROLLBACK are accepted and ignored.
+package pgfe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+
+	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/schema"
+)
+
+// ServerVersion reported to clients in the server_version parameter
+const ServerVersion = "10.0-qlbridge"
+
+var ErrServerClosed = errors.New("pgfe: Server closed")
+
+// Server accepts postgres protocol connections.
+type Server struct {
+	// Registry of schemas clients may use as databases
+	Registry *schema.Registry
+	// Schema default for connections that don't name a database, optional
+	Schema string
+	// Users name to password for md5 password authentication, if empty any
+	// user is accepted without a password.
+	Users map[string]string
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[int32]*conn // by process id, for cancel requests
+	lastPid  int32
+	catalogs map[*schema.Schema]*schema.Schema
+	closed   bool
+}
+
+func NewServer(reg *schema.Registry, defaultSchema string) *Server {
+	return &Server{
+		Registry: reg,
+		Schema:   defaultSchema,
+		conns:    make(map[int32]*conn),
+		catalogs: make(map[*schema.Schema]*schema.Schema),
+	}
+}
+
+func (m *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return m.Serve(l)
+}
+
+func (m *Server) Serve(l net.Listener) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	m.listener = l
+	m.mu.Unlock()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			m.mu.Lock()
+			closed := m.closed
+			m.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		c := m.newConn(nc)
+		if c == nil {
+			nc.Close()
+			continue
+		}
+		go func() {
+			defer m.removeConn(c)
+			if err := c.serve(); err != nil {
+				u.Debugf("postgres conn %d closed: %v", c.pid, err)
+			}
+		}()
+	}
+}
+
+func (m *Server) Addr() net.Addr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Addr()
+}
+
+func (m *Server) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	var err error
+	if m.listener != nil {
+		err = m.listener.Close()
+	}
+	for _, c := range m.conns {
+		c.close()
+	}
+	return err
+}
+
+func (m *Server) newConn(nc net.Conn) *conn {
+	var key [4]byte
+	rand.Read(key[:])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.lastPid++
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &conn{
+		msgConn: newMsgConn(nc),
+		s:       m,
+		pid:     m.lastPid,
+		secret:  int32(binary.BigEndian.Uint32(key[:])),
+		ctx:     ctx,
+		cancel:  cancel,
+		params:  make(map[string]string),
+		stmts:   make(map[string]*stmt),
+		portals: make(map[string]*portal),
+	}
+	m.conns[c.pid] = c
+	return c
+}
+
+func (m *Server) removeConn(c *conn) {
+	c.close()
+	m.mu.Lock()
+	delete(m.conns, c.pid)
+	m.mu.Unlock()
+}
+
+// cancelQuery a CancelRequest, the running statement of the connection
+// with process id and secret key is cancelled.
+func (m *Server) cancelQuery(pid, secret int32) {
+	m.mu.Lock()
+	c, ok := m.conns[pid]
+	m.mu.Unlock()
+	if ok && c.secret == secret {
+		c.cancelQuery()
+	}
+}
+
+// catalog the pg_catalog schema describing s
+func (m *Server) catalog(s *schema.Schema) (*schema.Schema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cs, ok := m.catalogs[s]; ok {
+		return cs, nil
+	}
+	cs, err := newCatalogSchema(s, m.Registry)
+	if err != nil {
+		return nil, err
+	}
+	m.catalogs[s] = cs
+	return cs, nil
+}
@@ -0,0 +1,460 @@
+package pgfe
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource/mockcsv"
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/testutil"
+	"github.com/araddon/qlbridge/value"
+)
+
+var addr string
+
+// panicFunc a function that panics when evaluated
+type panicFunc struct{}
+
+func (m *panicFunc) Type() value.ValueType { return value.UnknownType }
+func (m *panicFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	return func(ctx expr.EvalContext, args []value.Value) (value.Value, bool) {
+		panic("panicfunc")
+	}, nil
+}
+
+func TestMain(m *testing.M) {
+	testutil.Setup() // will call flag.Parse()
+	td.LoadTestDataOnce()
+	expr.FuncAdd("panicfunc", &panicFunc{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s := NewServer(schema.DefaultRegistry(), mockcsv.SchemaName)
+	s.Users = map[string]string{"postgres": "secret"}
+	go s.Serve(l)
+	addr = l.Addr().String()
+
+	code := m.Run()
+	s.Close()
+	os.Exit(code)
+}
+
+// client a minimal postgres protocol client
+type client struct {
+	*msgConn
+	pid, secret int32
+	params      map[string]string
+}
+
+// result of the messages up to ReadyForQuery
+type result struct {
+	cols   []*column
+	params []int32 // ParameterDescription
+	rows   [][]*string
+	tags   []string
+	types  []byte
+	err    *Error
+}
+
+func dial(user, password, db string) (*client, error) {
+	nc, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	nc.SetDeadline(time.Now().Add(10 * time.Second))
+	c := &client{msgConn: newMsgConn(nc), params: make(map[string]string)}
+
+	// ssl is refused
+	if err := c.startup(message{}.int32(sslRequestCode)); err != nil {
+		return nil, err
+	}
+	if b, err := c.r.ReadByte(); err != nil || b != 'N' {
+		return nil, fmt.Errorf("ssl response %q %v", b, err)
+	}
+
+	m := message{}.int32(protocolVersion3).string("user").string(user)
+	if db != "" {
+		m = m.string("database").string(db)
+	}
+	if err := c.startup(m.byte1(0)); err != nil {
+		return nil, err
+	}
+	for {
+		typ, data, err := c.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		r := &reader{b: data}
+		switch typ {
+		case 'R':
+			switch r.int32() {
+			case 5:
+				salt := r.next(4)
+				c.writeMessage('p', message{}.string(md5Password(user, password, salt)))
+				c.flush()
+			case 0:
+			default:
+				return nil, fmt.Errorf("unexpected auth request")
+			}
+		case 'S':
+			k := r.string()
+			c.params[k] = r.string()
+		case 'K':
+			c.pid, c.secret = r.int32(), r.int32()
+		case 'E':
+			return nil, readError(r)
+		case 'Z':
+			return c, nil
+		}
+	}
+}
+
+func (c *client) startup(m message) error {
+	b := message{}.int32(int32(len(m) + 4))
+	if _, err := c.w.Write(append(b, m...)); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+func readError(r *reader) *Error {
+	e := &Error{}
+	for {
+		switch r.byte1() {
+		case 0:
+			return e
+		case 'S':
+			e.Severity = r.string()
+		case 'C':
+			e.Code = r.string()
+		case 'M':
+			e.Msg = r.string()
+		default:
+			r.string()
+		}
+	}
+}
+
+// results read messages until ReadyForQuery
+func (c *client) results(t *testing.T) *result {
+	res := &result{}
+	for {
+		typ, data, err := c.readMessage()
+		assert.Equal(t, nil, err)
+		if err != nil {
+			return res
+		}
+		res.types = append(res.types, typ)
+		r := &reader{b: data}
+		switch typ {
+		case 'T':
+			res.cols = nil
+			for i := r.int16(); i > 0; i-- {
+				res.cols = append(res.cols, &column{Name: r.string(), TableOid: r.int32(), AttNum: r.int16(),
+					TypeOid: r.int32(), TypeSize: r.int16(), TypeMod: r.int32(), Format: r.int16()})
+			}
+		case 't':
+			for i := r.int16(); i > 0; i-- {
+				res.params = append(res.params, r.int32())
+			}
+		case 'D':
+			var row []*string
+			for i := r.int16(); i > 0; i-- {
+				b := r.bytes()
+				if b == nil {
+					row = append(row, nil)
+					continue
+				}
+				s := string(b)
+				row = append(row, &s)
+			}
+			res.rows = append(res.rows, row)
+		case 'C':
+			res.tags = append(res.tags, r.string())
+		case 'E':
+			res.err = readError(r)
+		case 'S':
+			k := r.string()
+			c.params[k] = r.string()
+		case 'Z':
+			return res
+		}
+	}
+}
+
+func (c *client) query(t *testing.T, sql string) *result {
+	assert.Equal(t, nil, c.writeMessage('Q', message{}.string(sql)))
+	assert.Equal(t, nil, c.flush())
+	return c.results(t)
+}
+
+func (c *client) close() {
+	c.writeMessage('X', nil)
+	c.flush()
+	c.conn.Close()
+}
+
+func connect(t *testing.T) *client {
+	c, err := dial("postgres", "secret", "")
+	assert.Equal(t, nil, err)
+	if err != nil {
+		t.FailNow()
+	}
+	return c
+}
+
+func str(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
+
+func TestStartup(t *testing.T) {
+	c := connect(t)
+	defer c.close()
+	assert.Equal(t, ServerVersion, c.params["server_version"])
+	assert.Equal(t, "UTF8", c.params["client_encoding"])
+	assert.Equal(t, "postgres", c.params["session_authorization"])
+	assert.NotEqual(t, int32(0), c.pid)
+
+	for _, bad := range [][3]string{
+		{"postgres", "wrong", ""},
+		{"nobody", "secret", ""},
+		{"postgres", "secret", "not_a_db"},
+	} {
+		_, err := dial(bad[0], bad[1], bad[2])
+		assert.NotEqual(t, nil, err, "%v", bad)
+		if perr, ok := err.(*Error); ok {
+			assert.Equal(t, "FATAL", perr.Severity)
+		} else {
+			t.Errorf("expected postgres error %v got %v", bad, err)
+		}
+	}
+	_, err := dial("postgres", "wrong", "")
+	assert.Equal(t, "28P01", err.(*Error).Code)
+	_, err = dial("postgres", "secret", "not_a_db")
+	assert.Equal(t, "3D000", err.(*Error).Code)
+}
+
+func TestSimpleQuery(t *testing.T) {
+	c := connect(t)
+	defer c.close()
+
+	res := c.query(t, `SELECT "user_id", referral_count, reg_date, referral_count * 2 AS rc2
+		FROM users WHERE user_id = '9Ip1aKbeZe2njCDM'`)
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 4, len(res.cols))
+	assert.Equal(t, "user_id", res.cols[0].Name)
+	assert.Equal(t, int32(oidVarchar), res.cols[0].TypeOid)
+	assert.Equal(t, tableOid("users"), res.cols[0].TableOid)
+	assert.Equal(t, int32(oidInt8), res.cols[1].TypeOid)
+	assert.Equal(t, int32(oidTimestamp), res.cols[2].TypeOid)
+	assert.Equal(t, "rc2", res.cols[3].Name)
+	assert.Equal(t, int32(oidInt8), res.cols[3].TypeOid)
+	assert.Equal(t, 1, len(res.rows))
+	if len(res.rows) == 1 {
+		row := res.rows[0]
+		assert.Equal(t, "9Ip1aKbeZe2njCDM", str(row[0]))
+		assert.Equal(t, "82", str(row[1]))
+		assert.Equal(t, "2012-10-17 17:29:39.738", str(row[2]))
+		assert.Equal(t, "164", str(row[3]))
+	}
+	assert.Equal(t, []string{"SELECT 1"}, res.tags)
+
+	// multiple statements, casts stripped
+	res = c.query(t, `SELECT user_id FROM users WHERE referral_count > '10'::int; SELECT 1 AS one;`)
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, []string{"SELECT 3", "SELECT 1"}, res.tags)
+
+	// empty query
+	res = c.query(t, ";")
+	assert.Equal(t, []byte{'I', 'Z'}, res.types)
+
+	// errors end the query, the connection is still usable
+	res = c.query(t, "SELECT FROM WHERE; SELECT 1")
+	assert.True(t, res.err != nil)
+	assert.Equal(t, 0, len(res.tags))
+	res = c.query(t, "SELECT * FROM not_a_table")
+	assert.True(t, res.err != nil)
+	// a panic while running is a statement error
+	res = c.query(t, "SELECT user_id, panicfunc() FROM users")
+	assert.True(t, res.err != nil)
+	res = c.query(t, "SELECT 1 AS one")
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 1, len(res.rows))
+}
+
+func TestSessionStatements(t *testing.T) {
+	c := connect(t)
+	defer c.close()
+
+	res := c.query(t, "BEGIN; COMMIT; ROLLBACK")
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, []string{"BEGIN", "COMMIT", "ROLLBACK"}, res.tags)
+
+	res = c.query(t, "SET application_name = 'pgtest'")
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, []string{"SET"}, res.tags)
+	assert.Equal(t, "pgtest", c.params["application_name"])
+
+	res = c.query(t, "SET extra_float_digits TO 3; SHOW extra_float_digits")
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 1, len(res.rows))
+	if len(res.rows) == 1 {
+		assert.Equal(t, "3", str(res.rows[0][0]))
+	}
+
+	res = c.query(t, "SELECT version(), current_database(), current_user()")
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 1, len(res.rows))
+	if len(res.rows) == 1 {
+		assert.Equal(t, "PostgreSQL "+ServerVersion, str(res.rows[0][0]))
+		assert.Equal(t, mockcsv.SchemaName, str(res.rows[0][1]))
+		assert.Equal(t, "postgres", str(res.rows[0][2]))
+	}
+}
+
+func TestExtendedQuery(t *testing.T) {
+	c := connect(t)
+	defer c.close()
+
+	sql := "SELECT user_id, referral_count FROM users WHERE referral_count > $1 AND user_id != $2"
+	c.writeMessage('P', message{}.string("q1").string(sql).int16(1).int32(oidInt8))
+	c.writeMessage('D', message{}.byte1('S').string("q1"))
+	c.writeMessage('S', nil)
+	c.flush()
+	res := c.results(t)
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, []byte{'1', 't', 'T', 'Z'}, res.types)
+	assert.Equal(t, []int32{oidInt8, oidText}, res.params)
+	assert.Equal(t, 2, len(res.cols))
+
+	// bind, text param and a binary result column, fetched in 2 batches
+	bind := message{}.string("p1").string("q1").
+		int16(0).
+		int16(2).bytes([]byte("10")).bytes([]byte("hT2impsabc345c")).
+		int16(2).int16(formatText).int16(formatBinary)
+	c.writeMessage('B', bind)
+	c.writeMessage('D', message{}.byte1('P').string("p1"))
+	c.writeMessage('E', message{}.string("p1").int32(1))
+	c.writeMessage('E', message{}.string("p1").int32(0))
+	c.writeMessage('S', nil)
+	c.flush()
+	res = c.results(t)
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, []byte{'2', 'T', 'D', 's', 'D', 'C', 'Z'}, res.types)
+	assert.Equal(t, int16(formatBinary), res.cols[1].Format)
+	assert.Equal(t, []string{"SELECT 2"}, res.tags)
+	assert.Equal(t, 2, len(res.rows))
+	if len(res.rows) == 2 {
+		assert.Equal(t, 8, len(str(res.rows[0][1])))
+	}
+
+	// a bad param fails until sync
+	c.writeMessage('B', message{}.string("").string("q1").int16(0).int16(2).
+		bytes([]byte("abc")).bytes(nil).int16(0))
+	c.writeMessage('E', message{}.string("").int32(0))
+	c.writeMessage('S', nil)
+	c.flush()
+	res = c.results(t)
+	assert.True(t, res.err != nil)
+	assert.Equal(t, "22P02", res.err.Code)
+	assert.Equal(t, []byte{'E', 'Z'}, res.types)
+
+	// unnamed statement, insert then delete
+	for _, tc := range []struct{ sql, tag string }{
+		{"INSERT INTO orders (order_id, user_id, item_id, price, order_date, item_count) VALUES ($1, 'pgfe', 5, 1.5, '2016-01-02', 1)", "INSERT 0 1"},
+		{"DELETE FROM orders WHERE order_id = $1", "DELETE 1"},
+	} {
+		c.writeMessage('P', message{}.string("").string(tc.sql).int16(1).int32(oidInt4))
+		c.writeMessage('B', message{}.string("").string("").int16(1).int16(formatBinary).
+			int16(1).bytes(message{}.int32(99)).int16(0))
+		c.writeMessage('D', message{}.byte1('P').string(""))
+		c.writeMessage('E', message{}.string("").int32(0))
+		c.writeMessage('S', nil)
+		c.flush()
+		res = c.results(t)
+		assert.True(t, res.err == nil, "%s %v", tc.sql, res.err)
+		assert.Equal(t, []string{tc.tag}, res.tags)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	c := connect(t)
+	defer c.close()
+
+	res := c.query(t, `SELECT c.relname FROM pg_catalog.pg_class c
+		WHERE c.relkind = 'r' AND c.relname = 'users'`)
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 1, len(res.rows))
+
+	res = c.query(t, `SELECT table_name FROM information_schema.tables WHERE table_schema = 'public'`)
+	assert.True(t, res.err == nil, "%v", res.err)
+	names := make(map[string]bool)
+	for _, row := range res.rows {
+		names[str(row[0])] = true
+	}
+	assert.True(t, names["users"] && names["orders"], "%v", names)
+
+	res = c.query(t, `SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_name = 'users' AND column_name = 'referral_count'`)
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 1, len(res.rows))
+	if len(res.rows) == 1 {
+		assert.Equal(t, "bigint", str(res.rows[0][1]))
+	}
+
+	res = c.query(t, fmt.Sprintf(`SELECT attname, format_type(atttypid, atttypmod) FROM pg_attribute
+		WHERE attrelid = %d AND attnum > 0`, tableOid("users")))
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 6, len(res.rows))
+
+	res = c.query(t, "SELECT nspname FROM pg_namespace WHERE nspname = 'public'")
+	assert.True(t, res.err == nil, "%v", res.err)
+	assert.Equal(t, 1, len(res.rows))
+}
+
+func TestCancel(t *testing.T) {
+	c := connect(t)
+	defer c.close()
+
+	// a cancel request with the wrong key is ignored
+	nc, err := net.Dial("tcp", addr)
+	assert.Equal(t, nil, err)
+	cc := &client{msgConn: newMsgConn(nc)}
+	cc.startup(message{}.int32(cancelRequestCode).int32(c.pid).int32(c.secret + 1))
+	nc.Close()
+
+	res := c.query(t, "SELECT 1 AS one")
+	assert.True(t, res.err == nil, "%v", res.err)
+}
+
+func TestServeClosed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	s := NewServer(schema.DefaultRegistry(), mockcsv.SchemaName)
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+	for s.Addr() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, nil, s.Close())
+	select {
+	case err := <-served:
+		assert.Equal(t, ErrServerClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+}
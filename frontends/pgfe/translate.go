@@ -0,0 +1,269 @@
+package pgfe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+)
+
+// multi word type names that may follow a :: cast
+var castTypeWords = map[string][]string{
+	"character": {"varying"},
+	"double":    {"precision"},
+	"timestamp": {"with", "without", "time", "zone"},
+	"time":      {"with", "without", "time", "zone"},
+}
+
+// translate postgres sql into the qlbridge dialect:
+//
+//    "quoted identifiers"  =>  `quoted identifiers`
+//    expr::type casts      =>  expr
+//
+// string literals and comments are unchanged.
+func translate(sql string) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'':
+			end := quotedEnd(sql, i, '\'')
+			b.WriteString(sql[i:end])
+			i = end - 1
+		case ch == '"':
+			end := quotedEnd(sql, i, '"')
+			ident := sql[i+1 : end]
+			if end-1 > i && sql[end-1] == '"' {
+				ident = sql[i+1 : end-1]
+			}
+			ident = strings.Replace(ident, `""`, `"`, -1)
+			b.WriteByte('`')
+			b.WriteString(strings.Replace(ident, "`", "``", -1))
+			b.WriteByte('`')
+			i = end - 1
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteString(sql[i : i+end])
+			i += end - 1
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(sql[i:])
+				i = len(sql)
+				continue
+			}
+			b.WriteString(sql[i : i+end+4])
+			i += end + 3
+		case ch == ':' && strings.HasPrefix(sql[i:], "::"):
+			i = skipCastType(sql, i+2) - 1
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// quotedEnd the position after the closing quote of a quoted string or
+// identifier starting at i, doubled quotes are part of the value.
+func quotedEnd(sql string, i int, quote byte) int {
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] == quote {
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// skipCastType the position after the type name of a cast starting at i,
+// such as regclass, pg_catalog.text, varchar(10), text[], double precision.
+func skipCastType(sql string, i int) int {
+	word := func(i int) (string, int) {
+		for i < len(sql) && sql[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(sql) && (isIdentChar(sql[i]) || sql[i] == '.' || sql[i] == '"') {
+			i++
+		}
+		return strings.ToLower(sql[start:i]), i
+	}
+	name, end := word(i)
+	if name == "" {
+		return i
+	}
+	if next, ok := castTypeWords[name]; ok {
+		for {
+			w, e := word(end)
+			if !containsWord(next, w) {
+				break
+			}
+			end = e
+		}
+	}
+	// (precision, scale) and array brackets
+	if end < len(sql) && sql[end] == '(' {
+		if close := strings.IndexByte(sql[end:], ')'); close > 0 {
+			end += close + 1
+		}
+	}
+	for strings.HasPrefix(sql[end:], "[]") {
+		end += 2
+	}
+	return end
+}
+
+func containsWord(words []string, w string) bool {
+	for _, x := range words {
+		if x == w {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+// placeholder a $n param reference in a statement
+type placeholder struct {
+	pos, end int // position of $ and after the last digit
+	n        int // 1 based param number
+}
+
+// placeholders the $n params outside of quotes and comments
+func placeholders(sql string) []placeholder {
+	var ps []placeholder
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = quotedEnd(sql, i, ch) - 1
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return ps
+			}
+			i += end + 3
+		case ch == '$':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				n, err := strconv.Atoi(sql[i+1 : j])
+				if err == nil && n > 0 {
+					ps = append(ps, placeholder{pos: i, end: j, n: n})
+				}
+			}
+			i = j - 1
+		}
+	}
+	return ps
+}
+
+// paramCount the number of params of a statement, the highest $n
+func paramCount(sql string) int {
+	n := 0
+	for _, p := range placeholders(sql) {
+		if p.n > n {
+			n = p.n
+		}
+	}
+	return n
+}
+
+// interpolate replace $n params with sql literals of args
+func interpolate(sql string, args []interface{}) (string, error) {
+	ps := placeholders(sql)
+	if len(ps) == 0 {
+		return sql, nil
+	}
+	b := &strings.Builder{}
+	last := 0
+	for _, p := range ps {
+		if p.n > len(args) {
+			return "", fmt.Errorf("there is no parameter $%d", p.n)
+		}
+		b.WriteString(sql[last:p.pos])
+		b.WriteString(literal(args[p.n-1]))
+		last = p.end
+	}
+	b.WriteString(sql[last:])
+	return b.String(), nil
+}
+
+// literal sql literal of a param value
+func literal(v interface{}) string {
+	switch vt := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(vt, 10)
+	case float64:
+		return strconv.FormatFloat(vt, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vt)
+	case time.Time:
+		return quote(vt.Format(pgTimeFormat))
+	case []byte:
+		return quote(string(vt))
+	case string:
+		return quote(vt)
+	}
+	return quote(fmt.Sprint(v))
+}
+
+func quote(s string) string {
+	return "'" + expr.StringEscape('\'', s) + "'"
+}
+
+// splitStatements split a query string on ; outside of quotes and
+// comments, empty statements are dropped.
+func splitStatements(sql string) []string {
+	var stmts []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	start := 0
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = quotedEnd(sql, i, ch) - 1
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+				continue
+			}
+			i += end + 3
+		case ch == ';':
+			add(sql[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(sql) {
+		add(sql[start:])
+	}
+	return stmts
+}
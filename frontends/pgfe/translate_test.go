@@ -0,0 +1,60 @@
+package pgfe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		sql string
+		out string
+	}{
+		{"SELECT a FROM t", "SELECT a FROM t"},
+		{`SELECT "User Id" FROM "users"`, "SELECT `User Id` FROM `users`"},
+		{`SELECT "a""b" FROM t`, "SELECT `a\"b` FROM t"},
+		{"SELECT 'it''s \"x\"::int' FROM t", "SELECT 'it''s \"x\"::int' FROM t"},
+		{"SELECT a::text, b::character varying(10), c::text[] FROM t", "SELECT a, b, c FROM t"},
+		{"SELECT 'users'::regclass, d::timestamp with time zone FROM t", "SELECT 'users', d FROM t"},
+		{"SELECT a FROM t -- \"x\"::int\nWHERE b = 1::bigint", "SELECT a FROM t -- \"x\"::int\nWHERE b = 1"},
+		{"SELECT a /* \"x\" */ FROM t", "SELECT a /* \"x\" */ FROM t"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.out, translate(tc.sql), tc.sql)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		sql  string
+		args []interface{}
+		out  string
+	}{
+		{"SELECT a FROM t", nil, "SELECT a FROM t"},
+		{"SELECT a FROM t WHERE a = $1 AND b > $2 AND c != $1", []interface{}{"x", int64(2)},
+			"SELECT a FROM t WHERE a = 'x' AND b > 2 AND c != 'x'"},
+		{"SELECT '$1', `$1` FROM t WHERE a = $1", []interface{}{nil},
+			"SELECT '$1', `$1` FROM t WHERE a = NULL"},
+		{"SELECT a FROM t -- $2\nWHERE a = $1", []interface{}{1.5},
+			"SELECT a FROM t -- $2\nWHERE a = 1.5"},
+		{"SELECT a FROM t WHERE a = $1", []interface{}{"it's"},
+			"SELECT a FROM t WHERE a = 'it''s'"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, paramCount(tc.sql), len(tc.args), tc.sql)
+		out, err := interpolate(tc.sql, tc.args)
+		assert.Equal(t, nil, err, tc.sql)
+		assert.Equal(t, tc.out, out)
+	}
+
+	_, err := interpolate("SELECT a FROM t WHERE a = $2", []interface{}{1})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	assert.Equal(t, []string(nil), splitStatements(" ; ;"))
+	assert.Equal(t, []string{"SELECT 1", "SELECT ';'", "SELECT `a;b` FROM t"},
+		splitStatements("SELECT 1; SELECT ';';\nSELECT `a;b` FROM t;"))
+	assert.Equal(t, []string{"SELECT 1 -- a; b"}, splitStatements("SELECT 1 -- a; b\n;"))
+}
@@ -0,0 +1,593 @@
+package pgfe
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+// postgres type oids
+const (
+	oidBool        int32 = 16
+	oidBytea       int32 = 17
+	oidName        int32 = 19
+	oidInt8        int32 = 20
+	oidInt2        int32 = 21
+	oidInt4        int32 = 23
+	oidText        int32 = 25
+	oidOid         int32 = 26
+	oidJSON        int32 = 114
+	oidFloat4      int32 = 700
+	oidFloat8      int32 = 701
+	oidUnknown     int32 = 705
+	oidBpchar      int32 = 1042
+	oidVarchar     int32 = 1043
+	oidDate        int32 = 1082
+	oidTimestamp   int32 = 1114
+	oidTimestampTz int32 = 1184
+	oidNumeric     int32 = 1700
+	oidTextArray   int32 = 1009
+)
+
+// format codes of params and results
+const (
+	formatText   int16 = 0
+	formatBinary int16 = 1
+)
+
+const pgTimeFormat = "2006-01-02 15:04:05.999999"
+
+// pgType a postgres type as reported in pg_type
+type pgType struct {
+	oid      int32
+	name     string
+	size     int16 // -1 for variable length
+	category string
+	array    int32 // oid of the array type of this type
+	elem     int32 // oid of the element type of an array type
+}
+
+// pgTypes the types used for qlbridge values, in pg_type
+var pgTypes = []pgType{
+	{oidBool, "bool", 1, "B", 1000, 0},
+	{oidBytea, "bytea", -1, "U", 1001, 0},
+	{oidName, "name", 64, "S", 1003, 0},
+	{oidInt8, "int8", 8, "N", 1016, 0},
+	{oidInt2, "int2", 2, "N", 1005, 0},
+	{oidInt4, "int4", 4, "N", 1007, 0},
+	{oidText, "text", -1, "S", oidTextArray, 0},
+	{oidOid, "oid", 4, "N", 1028, 0},
+	{oidJSON, "json", -1, "U", 199, 0},
+	{oidFloat4, "float4", 4, "N", 1021, 0},
+	{oidFloat8, "float8", 8, "N", 1022, 0},
+	{oidBpchar, "bpchar", -1, "S", 1014, 0},
+	{oidVarchar, "varchar", -1, "S", 1015, 0},
+	{oidDate, "date", 4, "D", 1182, 0},
+	{oidTimestamp, "timestamp", 8, "D", 1115, 0},
+	{oidTimestampTz, "timestamptz", 8, "D", 1185, 0},
+	{oidNumeric, "numeric", -1, "N", 1231, 0},
+	{oidTextArray, "_text", -1, "A", 0, oidText},
+}
+
+// typeByOid find a pg type by oid
+func typeByOid(oid int32) (pgType, bool) {
+	for _, t := range pgTypes {
+		if t.oid == oid {
+			return t, true
+		}
+	}
+	return pgType{}, false
+}
+
+// typeOid the postgres type of a qlbridge value type
+func typeOid(vt value.ValueType) int32 {
+	switch vt {
+	case value.IntType:
+		return oidInt8
+	case value.NumberType:
+		return oidFloat8
+	case value.BoolType:
+		return oidBool
+	case value.TimeType:
+		return oidTimestamp
+	case value.DecimalType:
+		return oidNumeric
+	case value.ByteSliceType:
+		return oidBytea
+	case value.StringsType:
+		return oidTextArray
+	case value.JsonType, value.MapValueType, value.MapStringType, value.MapIntType,
+		value.MapNumberType, value.MapBoolType, value.MapTimeType, value.SliceValueType:
+		return oidJSON
+	case value.StringType:
+		return oidVarchar
+	}
+	return oidText
+}
+
+// typeName the sql name of a postgres type as information_schema and
+// format_type describe it.
+func typeName(oid int32) string {
+	switch oid {
+	case oidBool:
+		return "boolean"
+	case oidInt8:
+		return "bigint"
+	case oidInt4:
+		return "integer"
+	case oidInt2:
+		return "smallint"
+	case oidFloat8:
+		return "double precision"
+	case oidFloat4:
+		return "real"
+	case oidVarchar:
+		return "character varying"
+	case oidTimestamp:
+		return "timestamp without time zone"
+	case oidTimestampTz:
+		return "timestamp with time zone"
+	case oidTextArray:
+		return "text[]"
+	}
+	if t, ok := typeByOid(oid); ok {
+		return t.name
+	}
+	return "text"
+}
+
+// column a RowDescription field
+type column struct {
+	Name     string
+	TableOid int32 // 0 if not a table column
+	AttNum   int16 // 0 if not a table column
+	TypeOid  int32
+	TypeSize int16
+	TypeMod  int32
+	Format   int16
+}
+
+// newColumn a column of value type
+func newColumn(name string, vt value.ValueType) *column {
+	oid := typeOid(vt)
+	c := &column{Name: name, TypeOid: oid, TypeSize: -1, TypeMod: -1}
+	if t, ok := typeByOid(oid); ok {
+		c.TypeSize = t.size
+	}
+	return c
+}
+
+// fieldColumn a column typed from the field of a table
+func fieldColumn(tbl *schema.Table, fld *schema.Field) *column {
+	c := newColumn(fld.Name, fld.ValueType())
+	c.TableOid = tableOid(tbl.Name)
+	for i, f := range tbl.Fields {
+		if f == fld {
+			c.AttNum = int16(i + 1)
+			break
+		}
+	}
+	c.TypeMod = fieldTypeMod(c.TypeOid, fld)
+	return c
+}
+
+// fieldTypeMod the atttypmod of a field, length of varchar, precision and
+// scale of numeric.
+func fieldTypeMod(oid int32, fld *schema.Field) int32 {
+	switch {
+	case oid == oidVarchar && fld.Length > 0:
+		return int32(fld.Length) + 4
+	case oid == oidNumeric && fld.Precision > 0:
+		return int32(fld.Precision<<16|fld.Scale) + 4
+	}
+	return -1
+}
+
+func (c *column) field(m message) message {
+	return m.string(c.Name).
+		int32(c.TableOid).
+		int16(c.AttNum).
+		int32(c.TypeOid).
+		int16(c.TypeSize).
+		int32(c.TypeMod).
+		int16(c.Format)
+}
+
+// rowDescription the RowDescription message body of columns
+func rowDescription(cols []*column) message {
+	m := message{}.int16(int16(len(cols)))
+	for _, c := range cols {
+		m = c.field(m)
+	}
+	return m
+}
+
+// dataRow the DataRow message body of values
+func dataRow(cols []*column, vals []driver.Value) message {
+	m := message{}.int16(int16(len(cols)))
+	for i, c := range cols {
+		var v driver.Value
+		if i < len(vals) {
+			v = vals[i]
+		}
+		if v == nil {
+			m = m.int32(-1)
+			continue
+		}
+		var b []byte
+		var ok bool
+		if c.Format == formatBinary {
+			b, ok = binaryValue(c.TypeOid, v)
+		} else {
+			b, ok = textValue(c.TypeOid, v)
+		}
+		if !ok {
+			m = m.int32(-1)
+			continue
+		}
+		m = m.bytes(b)
+	}
+	return m
+}
+
+// textValue the text format of a value for a type oid, false if the
+// value can't be converted to it (written as NULL).
+func textValue(oid int32, v interface{}) ([]byte, bool) {
+	if vv, ok := v.(value.Value); ok {
+		if vv.Nil() {
+			return nil, false
+		}
+		v = vv.Value()
+	}
+	switch oid {
+	case oidBool:
+		b, ok := value.ValueToBool(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		if b {
+			return []byte{'t'}, true
+		}
+		return []byte{'f'}, true
+	case oidInt8, oidInt4, oidInt2, oidOid:
+		i, ok := value.ValueToInt64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return strconv.AppendInt(nil, i, 10), true
+	case oidFloat8, oidFloat4:
+		f, ok := value.ValueToFloat64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return formatFloat(f), true
+	case oidTimestamp, oidTimestampTz:
+		t, ok := v.(time.Time)
+		if !ok {
+			if t, ok = value.ValueToTime(value.NewValue(v)); !ok {
+				return nil, false
+			}
+		}
+		return []byte(t.Format(pgTimeFormat)), true
+	case oidBytea:
+		var b []byte
+		switch vt := v.(type) {
+		case []byte:
+			b = vt
+		case string:
+			b = []byte(vt)
+		default:
+			b = []byte(fmt.Sprint(v))
+		}
+		return append([]byte(`\x`), hex.EncodeToString(b)...), true
+	case oidTextArray:
+		strs, ok := value.ValueToStrings(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return textArray(strs), true
+	case oidNumeric:
+		switch vt := v.(type) {
+		case float64:
+			return strconv.AppendFloat(nil, vt, 'f', -1, 64), true
+		case float32:
+			return strconv.AppendFloat(nil, float64(vt), 'f', -1, 32), true
+		}
+	case oidJSON:
+		switch vt := v.(type) {
+		case string:
+			return []byte(vt), true
+		case []byte:
+			return vt, true
+		}
+		by, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		return by, true
+	}
+	switch vt := v.(type) {
+	case string:
+		return []byte(vt), true
+	case []byte:
+		return vt, true
+	case int64:
+		return strconv.AppendInt(nil, vt, 10), true
+	case float64:
+		return formatFloat(vt), true
+	case bool:
+		if vt {
+			return []byte{'t'}, true
+		}
+		return []byte{'f'}, true
+	case time.Time:
+		return []byte(vt.Format(pgTimeFormat)), true
+	case map[string]interface{}, []interface{}, map[string]string, map[string]int64,
+		map[string]float64, map[string]bool, json.RawMessage:
+		if by, err := json.Marshal(vt); err == nil {
+			return by, true
+		}
+	}
+	return []byte(fmt.Sprint(v)), true
+}
+
+func formatFloat(f float64) []byte {
+	switch {
+	case math.IsNaN(f):
+		return []byte("NaN")
+	case math.IsInf(f, 1):
+		return []byte("Infinity")
+	case math.IsInf(f, -1):
+		return []byte("-Infinity")
+	}
+	return strconv.AppendFloat(nil, f, 'g', -1, 64)
+}
+
+// textArray the text format of a text[], all elements are quoted
+func textArray(strs []string) []byte {
+	b := []byte{'{'}
+	for i, s := range strs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '"')
+		for j := 0; j < len(s); j++ {
+			if s[j] == '"' || s[j] == '\\' {
+				b = append(b, '\\')
+			}
+			b = append(b, s[j])
+		}
+		b = append(b, '"')
+	}
+	return append(b, '}')
+}
+
+// pgEpoch the zero of binary timestamps
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// binaryValue the binary format of a value for a type oid, types without
+// a binary encoding here (arrays) use their text format.
+func binaryValue(oid int32, v interface{}) ([]byte, bool) {
+	if vv, ok := v.(value.Value); ok {
+		if vv.Nil() {
+			return nil, false
+		}
+		v = vv.Value()
+	}
+	switch oid {
+	case oidBool:
+		b, ok := value.ValueToBool(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		if b {
+			return []byte{1}, true
+		}
+		return []byte{0}, true
+	case oidInt8:
+		i, ok := value.ValueToInt64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return message{}.int64(i), true
+	case oidInt4, oidOid:
+		i, ok := value.ValueToInt64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return message{}.int32(int32(i)), true
+	case oidInt2:
+		i, ok := value.ValueToInt64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return message{}.int16(int16(i)), true
+	case oidFloat8:
+		f, ok := value.ValueToFloat64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return message{}.int64(int64(math.Float64bits(f))), true
+	case oidFloat4:
+		f, ok := value.ValueToFloat64(value.NewValue(v))
+		if !ok {
+			return nil, false
+		}
+		return message{}.int32(int32(math.Float32bits(float32(f)))), true
+	case oidTimestamp, oidTimestampTz:
+		t, ok := v.(time.Time)
+		if !ok {
+			if t, ok = value.ValueToTime(value.NewValue(v)); !ok {
+				return nil, false
+			}
+		}
+		return message{}.int64(t.Sub(pgEpoch).Nanoseconds() / 1000), true
+	case oidBytea:
+		switch vt := v.(type) {
+		case []byte:
+			return vt, true
+		case string:
+			return []byte(vt), true
+		}
+		return []byte(fmt.Sprint(v)), true
+	case oidNumeric:
+		b, ok := textValue(oid, v)
+		if !ok {
+			return nil, false
+		}
+		return binaryNumeric(string(b))
+	}
+	return textValue(oid, v)
+}
+
+// binaryNumeric the binary numeric format of a decimal string, base 10000
+// digits with a weight (exponent of the first digit) and display scale.
+func binaryNumeric(s string) ([]byte, bool) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, false
+	}
+	neg := r.Sign() < 0
+	if neg {
+		r.Neg(r)
+	}
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s[i+1:]) - len(strings.TrimLeft(s[i+1:], "0123456789"))
+	}
+	// integer digits, then fractional digits padded to groups of 4
+	digits := r.FloatString(scale)
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	for len(intPart)%4 != 0 {
+		intPart = "0" + intPart
+	}
+	for len(fracPart)%4 != 0 {
+		fracPart += "0"
+	}
+	var groups []int16
+	for i := 0; i < len(intPart); i += 4 {
+		g, _ := strconv.Atoi(intPart[i : i+4])
+		groups = append(groups, int16(g))
+	}
+	weight := len(groups) - 1
+	for i := 0; i < len(fracPart); i += 4 {
+		g, _ := strconv.Atoi(fracPart[i : i+4])
+		groups = append(groups, int16(g))
+	}
+	// strip leading and trailing zero groups
+	for len(groups) > 0 && groups[0] == 0 {
+		groups = groups[1:]
+		weight--
+	}
+	for len(groups) > 0 && groups[len(groups)-1] == 0 {
+		groups = groups[:len(groups)-1]
+	}
+	if len(groups) == 0 {
+		weight = 0
+	}
+	sign := int16(0)
+	if neg && len(groups) > 0 {
+		sign = 0x4000
+	}
+	m := message{}.int16(int16(len(groups))).int16(int16(weight)).int16(sign).int16(int16(scale))
+	for _, g := range groups {
+		m = m.int16(g)
+	}
+	return m, true
+}
+
+// paramValue decode a bound param of type oid and format, the result is
+// converted to a sql literal.
+func paramValue(oid int32, format int16, b []byte) (interface{}, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if format == formatText {
+		switch oid {
+		case oidBool:
+			switch strings.ToLower(string(b)) {
+			case "t", "true", "1", "yes", "on", "y":
+				return true, nil
+			case "f", "false", "0", "no", "off", "n":
+				return false, nil
+			}
+			return nil, fmt.Errorf("invalid input syntax for type boolean: %q", b)
+		case oidInt8, oidInt4, oidInt2, oidOid:
+			i, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid input syntax for type %s: %q", typeName(oid), b)
+			}
+			return i, nil
+		case oidFloat8, oidFloat4, oidNumeric:
+			f, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid input syntax for type %s: %q", typeName(oid), b)
+			}
+			return f, nil
+		case oidBytea:
+			if len(b) >= 2 && b[0] == '\\' && b[1] == 'x' {
+				return hex.DecodeString(string(b[2:]))
+			}
+		}
+		return string(b), nil
+	}
+	switch oid {
+	case oidBool:
+		if len(b) != 1 {
+			break
+		}
+		return b[0] != 0, nil
+	case oidInt8:
+		if len(b) != 8 {
+			break
+		}
+		return int64(binary.BigEndian.Uint64(b)), nil
+	case oidInt4, oidOid:
+		if len(b) != 4 {
+			break
+		}
+		return int64(int32(binary.BigEndian.Uint32(b))), nil
+	case oidInt2:
+		if len(b) != 2 {
+			break
+		}
+		return int64(int16(binary.BigEndian.Uint16(b))), nil
+	case oidFloat8:
+		if len(b) != 8 {
+			break
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case oidFloat4:
+		if len(b) != 4 {
+			break
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case oidTimestamp, oidTimestampTz:
+		if len(b) != 8 {
+			break
+		}
+		us := int64(binary.BigEndian.Uint64(b))
+		return pgEpoch.Add(time.Duration(us) * time.Microsecond), nil
+	case oidBytea:
+		return b, nil
+	case oidText, oidVarchar, oidBpchar, oidName, oidJSON, oidUnknown, 0:
+		return string(b), nil
+	default:
+		return nil, fmt.Errorf("unsupported binary format for param type %d", oid)
+	}
+	return nil, fmt.Errorf("invalid binary length %d for param type %s", len(b), typeName(oid))
+}
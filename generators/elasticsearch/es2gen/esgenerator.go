@@ -93,6 +93,15 @@ func (fg *FilterGenerator) walkExpr(node expr.Node, depth int) (interface{}, err
 		filter, err = fg.walkExpr(n.ExprNode, depth+1)
 	case *expr.FuncNode:
 		filter, err = fg.funcExpr(n, depth+1)
+	case *expr.CaseNode:
+		cf, cerr := gentypes.CaseFilter(n)
+		if cerr != nil {
+			return nil, cerr
+		}
+		if cf == nil {
+			return MatchNone, nil
+		}
+		filter, err = fg.walkExpr(cf, depth+1)
 	default:
 		gou.Warnf("not handled %v", node)
 		return nil, fmt.Errorf("qlindex: unsupported node in expression: %T (%s)", node, node)
@@ -249,6 +258,17 @@ func (fg *FilterGenerator) binaryExpr(node *expr.BinaryNode, depth int) (interfa
 		}
 		return makeWildcard(lhs, rhsstr)
 
+	case lex.TokenRLike: // ident RLIKE literal
+		rhs, ok := node.Args[1].(*expr.StringNode)
+		if !ok {
+			return nil, fmt.Errorf("qlindex: unsupported non-string argument for RLIKE pattern: %T", node.Args[1])
+		}
+		pattern, ok := gentypes.LuceneRegexp(rhs.Text)
+		if !ok {
+			return nil, fmt.Errorf("qlindex: unsupported regular expression for RLIKE: %q", rhs.Text)
+		}
+		return makeRegexp(lhs, pattern)
+
 	case lex.TokenIN, lex.TokenIntersects:
 		// Build up list of arguments
 		array, ok := node.Args[1].(*expr.ArrayNode)
@@ -151,6 +151,10 @@ type wildcardquery struct {
 	Query wildcard `json:"query"`
 }
 
+type regexpq struct {
+	Regexp map[string]string `json:"regexp"`
+}
+
 func wcFunc(val string) string {
 	if len(val) < 1 {
 		return val
@@ -182,3 +186,11 @@ func wcFunc(val string) string {
 func Wildcard(field, value string) *wildcardquery {
 	return &wildcardquery{Query: wildcard{Wildcard: map[string]string{field: wcFunc(value)}}}
 }
+
+// Regexp creates a new Elasticsearch regexp filter, the pattern must
+// already be lucene syntax (see gentypes.LuceneRegexp)
+//
+//   {"regexp": {field: pattern}}
+func Regexp(field, pattern string) *regexpq {
+	return &regexpq{Regexp: map[string]string{field: pattern}}
+}
@@ -228,6 +228,24 @@ func makeWildcard(lhs *gentypes.FieldType, value string) (interface{}, error) {
 	return &wc, nil
 }
 
+// makeRegexp returns a regexp query, pattern is lucene syntax
+//   {"regexp": {field: pattern}}
+func makeRegexp(lhs *gentypes.FieldType, pattern string) (interface{}, error) {
+	fieldName := lhs.Field
+	if lhs.Nested() {
+		fieldName = lhs.PathAndPrefix(pattern)
+	}
+	rq := Regexp(fieldName, pattern)
+	if lhs.Nested() {
+		fl := []interface{}{rq, Term(fmt.Sprintf("%s.k", lhs.Path), lhs.Field)}
+		return &nested{&NestedQuery{
+			Query: &boolean{must{fl}},
+			Path:  lhs.Path,
+		}}, nil
+	}
+	return rq, nil
+}
+
 // makeTimeWindowQuery maps the provided threshold and window arguments to the indexed time buckets
 func makeTimeWindowQuery(lhs *gentypes.FieldType, threshold, window, ts int64) (interface{}, error) {
 	/*
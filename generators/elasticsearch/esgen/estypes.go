@@ -176,6 +176,10 @@ type wildcard struct {
 	Wildcard map[string]string `json:"wildcard"`
 }
 
+type regexpq struct {
+	Regexp map[string]string `json:"regexp"`
+}
+
 func wcFunc(val string) string {
 	if len(val) < 1 {
 		return val
@@ -207,3 +211,11 @@ func wcFunc(val string) string {
 func Wildcard(field, value string) *wildcard {
 	return &wildcard{Wildcard: map[string]string{field: wcFunc(value)}}
 }
+
+// Regexp creates a new Elasticsearch regexp query, the pattern must
+// already be lucene syntax (see gentypes.LuceneRegexp)
+//
+//    {"regexp": {field: pattern}}
+func Regexp(field, pattern string) *regexpq {
+	return &regexpq{Regexp: map[string]string{field: pattern}}
+}
@@ -55,6 +55,12 @@ func (m *TypeValidator) walkNode(node expr.Node) error {
 		return nil
 	case *expr.FuncNode:
 		return m.funcExpr(n)
+	case *expr.CaseNode:
+		cf, err := gentypes.CaseFilter(n)
+		if err != nil || cf == nil {
+			return err
+		}
+		return m.walkNode(cf)
 	default:
 		u.Warnf("not handled type validation %v %T", node, node)
 		return fmt.Errorf("esgen: unsupported node in expression: %T (%s)", node, node)
@@ -123,6 +129,8 @@ func (m *TypeValidator) binaryNode(node *expr.BinaryNode) error {
 		// ident CONTAINS literal
 	case lex.TokenLike:
 		// ident LIKE literal
+	case lex.TokenRLike:
+		// ident RLIKE literal, converted to lucene regexp
 	case lex.TokenIN, lex.TokenIntersects:
 		// Build up list of arguments
 	}
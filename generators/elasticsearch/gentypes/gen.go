@@ -1,13 +1,17 @@
 package gentypes
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/araddon/dateparse"
 	u "github.com/araddon/gou"
 
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
 	"github.com/araddon/qlbridge/rel"
 	"github.com/araddon/qlbridge/schema"
 	"github.com/araddon/qlbridge/value"
@@ -143,3 +147,136 @@ func ValueAndPrefix(val interface{}) (interface{}, string) {
 	// Default to strings
 	return val, "s"
 }
+
+// CaseFilter rewrites a CASE used as a filter into the equivalent boolean
+// expression of AND/OR terms, as filters have no conditional.  Each THEN,
+// ELSE must be a boolean expression.  Returns nil node if the case can
+// never be true.
+//
+//    CASE WHEN a THEN x WHEN b THEN y ELSE z END
+//    =>  OR ( AND (a, x), AND (NOT a, b, y), AND (NOT a, NOT b, z) )
+func CaseFilter(n *expr.CaseNode) (expr.Node, error) {
+	and := lex.Token{T: lex.TokenLogicAnd, V: "AND"}
+	or := lex.Token{T: lex.TokenLogicOr, V: "OR"}
+	not := lex.Token{T: lex.TokenNegate, V: "NOT"}
+	eq := lex.Token{T: lex.TokenEqual, V: "="}
+
+	var terms []expr.Node
+	var prior []expr.Node
+	addTerm := func(conds []expr.Node, result expr.Node) error {
+		switch rt := result.(type) {
+		case *expr.IdentityNode:
+			if rt.IsBooleanIdentity() {
+				if !rt.Bool() {
+					return nil
+				}
+				result = nil
+			}
+		case *expr.StringNode, *expr.NumberNode, *expr.NullNode, *expr.ValueNode:
+			return fmt.Errorf("unsupported non-boolean CASE result in filter: %s", result)
+		}
+		if result != nil {
+			conds = append(conds, result)
+		}
+		switch len(conds) {
+		case 0:
+			terms = append(terms, expr.NewIdentityNodeVal("match_all"))
+		case 1:
+			terms = append(terms, conds[0])
+		default:
+			terms = append(terms, expr.NewBooleanNode(and, conds...))
+		}
+		return nil
+	}
+	for i, when := range n.Whens {
+		if n.Arg != nil {
+			when = expr.NewBinaryNode(eq, n.Arg, when)
+		}
+		conds := append(append([]expr.Node{}, prior...), when)
+		if err := addTerm(conds, n.Thens[i]); err != nil {
+			return nil, err
+		}
+		// un-matched whens negated for subsequent terms, the when
+		// nodes are not mutated as NewUnary would
+		prior = append(prior, &expr.UnaryNode{Operator: not, Arg: when})
+	}
+	if n.Else != nil {
+		if err := addTerm(prior, n.Else); err != nil {
+			return nil, err
+		}
+	}
+	switch len(terms) {
+	case 0:
+		return nil, nil
+	case 1:
+		return terms[0], nil
+	}
+	return expr.NewBooleanNode(or, terms...), nil
+}
+
+// LuceneRegexp convert a go regular expression into the lucene syntax of
+// elasticsearch regexp queries.  Lucene patterns match the whole value so
+// are wrapped in .* unless anchored with ^, $.  Returns false if the
+// pattern uses syntax lucene does not have (\d, \w, \b, (?i) flags,
+// anchors within the pattern).
+//
+//    ^bob@       =>  bob\@.*
+//    [0-9]+ x#   =>  .*[0-9]+ x\#.*
+func LuceneRegexp(pattern string) (string, bool) {
+	p := pattern
+	start := strings.HasPrefix(p, "^")
+	if start {
+		p = p[1:]
+	}
+	end := strings.HasSuffix(p, "$") && !strings.HasSuffix(p, `\$`)
+	if end {
+		p = p[:len(p)-1]
+	}
+	var buf bytes.Buffer
+	if !start {
+		buf.WriteString(".*")
+	}
+	inClass := false
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '\\':
+			// only escaped punctuation means the same thing
+			if i+1 >= len(p) || isAlphaNum(p[i+1]) {
+				return "", false
+			}
+			buf.WriteByte(c)
+			buf.WriteByte(p[i+1])
+			i++
+			continue
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '^':
+			if !inClass || p[i-1] != '[' {
+				return "", false
+			}
+		case '$':
+			if !inClass {
+				return "", false
+			}
+		case '(':
+			if !inClass && i+1 < len(p) && p[i+1] == '?' {
+				return "", false
+			}
+		case '#', '@', '&', '~', '<', '>', '"':
+			// lucene operators, literals in go
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(c)
+	}
+	if !end {
+		buf.WriteString(".*")
+	}
+	return buf.String(), true
+}
+
+func isAlphaNum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
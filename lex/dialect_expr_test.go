@@ -106,6 +106,42 @@ func TestLexLogicalDialect(t *testing.T) {
 			tv(TokenRightParenthesis, ")"),
 		})
 
+	verifyExpr2Tokens(t, `email RLIKE "^bob" AND name ~* 'smith$' OR x REGEXP y ~ "z"`,
+		[]Token{
+			tv(TokenIdentity, "email"),
+			tv(TokenRLike, "RLIKE"),
+			tv(TokenValue, "^bob"),
+			tv(TokenLogicAnd, "AND"),
+			tv(TokenIdentity, "name"),
+			tv(TokenRLikeI, "~*"),
+			tv(TokenValue, "smith$"),
+			tv(TokenLogicOr, "OR"),
+			tv(TokenIdentity, "x"),
+			tv(TokenRLike, "REGEXP"),
+			tv(TokenIdentity, "y"),
+			tv(TokenRLike, "~"),
+			tv(TokenValue, "z"),
+		})
+
+	verifyExpr2Tokens(t, `a & 3 | b ^ 1 << 2 >> c || d && e`,
+		[]Token{
+			tv(TokenIdentity, "a"),
+			tv(TokenBitAnd, "&"),
+			tv(TokenInteger, "3"),
+			tv(TokenBitOr, "|"),
+			tv(TokenIdentity, "b"),
+			tv(TokenBitXor, "^"),
+			tv(TokenInteger, "1"),
+			tv(TokenShiftLeft, "<<"),
+			tv(TokenInteger, "2"),
+			tv(TokenShiftRight, ">>"),
+			tv(TokenIdentity, "c"),
+			tv(TokenOr, "||"),
+			tv(TokenIdentity, "d"),
+			tv(TokenAnd, "&&"),
+			tv(TokenIdentity, "e"),
+		})
+
 	verifyExpr2Tokens(t, `(4 + 5)/2`,
 		[]Token{
 			tv(TokenLeftParenthesis, "("),
@@ -392,6 +392,7 @@ func LexLimit(l *Lexer) StateFn {
 //    CREATE {SCHEMA|DATABASE|SOURCE} [IF NOT EXISTS] <identity>  <WITH>
 //    CREATE {TABLE} <identity> [IF NOT EXISTS] <table_spec> [WITH]
 //    CREATE [OR REPLACE] {VIEW|CONTINUOUSVIEW} <identity> AS <select_statement> [WITH]
+//    CREATE [OR REPLACE] FUNCTION <identity> ([<arg> <type>, ...]) RETURNS <type> AS '<expression>'
 //
 func LexCreate(l *Lexer) StateFn {
 
@@ -439,6 +440,11 @@ func LexCreate(l *Lexer) StateFn {
 		l.Emit(TokenContinuousView)
 		l.Push("lexAs", lexAs)
 		return LexIdentifier
+	case "function":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenFunction)
+		l.Push("lexFunctionArgs", lexFunctionArgs)
+		return LexIdentifier
 	case "if":
 		l.Push("LexCreate", LexCreate)
 		return lexNotExists
@@ -457,6 +463,56 @@ func lexAs(l *Lexer) StateFn {
 	}
 	return nil
 }
+
+// lexFunctionArgs the args, return type and body of CREATE FUNCTION
+//
+//    (a int, b number) RETURNS number AS 'a * b'
+//
+func lexFunctionArgs(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	r := l.Next()
+	switch r {
+	case '(':
+		l.Emit(TokenLeftParenthesis)
+		return lexFunctionArgs
+	case ',':
+		l.Emit(TokenComma)
+		return lexFunctionArgs
+	case ')':
+		l.Emit(TokenRightParenthesis)
+		return lexFunctionReturns
+	case eof:
+		return l.errorToken("expected ) for function args")
+	}
+	l.backup()
+	// <arg> <type>
+	l.Push("lexFunctionArgs", lexFunctionArgs)
+	l.Push("LexIdentifier", LexIdentifier)
+	return LexIdentifier
+}
+func lexFunctionReturns(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	keyWord := strings.ToLower(l.PeekWord())
+	switch keyWord {
+	case "returns":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenReturns)
+		l.Push("lexFunctionBody", lexFunctionBody)
+		return LexIdentifier
+	}
+	return l.errorToken("expected RETURNS <type> for function")
+}
+func lexFunctionBody(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	keyWord := strings.ToLower(l.PeekWord())
+	switch keyWord {
+	case "as":
+		l.ConsumeWord(keyWord)
+		l.Emit(TokenAs)
+		return LexValue
+	}
+	return l.errorToken("expected AS '<expression>' for function")
+}
 func lexNotExists(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 	keyWord := strings.ToLower(l.PeekWord())
@@ -728,6 +784,15 @@ func LexDdlAlterColumn(l *Lexer) StateFn {
 		l.Push("LexDdlAlterColumn", l.clauseState())
 		l.Push("LexParenRight", LexParenRight)
 		return LexListOfArgs
+	case "decimal", "numeric":
+		l.ConsumeWord(word)
+		l.Emit(TokenTypeDecimal)
+		if l.Peek() == '(' {
+			l.Push("LexDdlAlterColumn", l.clauseState())
+			l.Push("LexParenRight", LexParenRight)
+			return LexListOfArgs
+		}
+		return l.clauseState()
 
 	default:
 		r = l.Peek()
@@ -910,6 +975,16 @@ func LexDdlTableColumn(l *Lexer) StateFn {
 		l.Push("LexDdlTableColumn", LexDdlTableColumn)
 		l.Push("LexParenRight", LexParenRight)
 		return LexListOfArgs
+	case "decimal", "numeric":
+		l.ConsumeWord(word)
+		l.Emit(TokenTypeDecimal)
+		p := l.Peek()
+		if p == '(' {
+			l.Push("LexDdlTableColumn", LexDdlTableColumn)
+			l.Push("LexParenRight", LexParenRight)
+			return LexListOfArgs
+		}
+		return LexDdlTableColumn
 	case "char":
 		l.ConsumeWord(word)
 		l.Emit(TokenTypeChar)
@@ -236,6 +236,27 @@ func TestLexSqlCreate(t *testing.T) {
 			tv(TokenEqual, "="),
 			tv(TokenValue, "hello"),
 		})
+
+	verifyTokens(t, `CREATE OR REPLACE FUNCTION discount(price number, pct int)
+		RETURNS number AS 'price * (100 - pct) / 100';`,
+		[]Token{
+			tv(TokenCreate, "CREATE"),
+			tv(TokenOr, "OR"),
+			tv(TokenReplace, "REPLACE"),
+			tv(TokenFunction, "FUNCTION"),
+			tv(TokenIdentity, "discount"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenIdentity, "price"),
+			tv(TokenIdentity, "number"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "pct"),
+			tv(TokenIdentity, "int"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenReturns, "RETURNS"),
+			tv(TokenIdentity, "number"),
+			tv(TokenAs, "AS"),
+			tv(TokenValue, "price * (100 - pct) / 100"),
+		})
 }
 func TestLexSqlDrop(t *testing.T) {
 	// DROP {DATABASE | SCHEMA | SOURCE | TABLE} [IF EXISTS] db_name
@@ -105,6 +105,7 @@ type Lexer struct {
 	peekedWordPos int
 	peekedWord    string
 	lastQuoteMark byte
+	caseDepth     int // nesting depth of CASE ... END expressions
 
 	// Due to nested Expressions and evaluation this allows us to descend/ascend
 	// during lex, using push/pop to add and remove states needing evaluation
@@ -567,6 +568,31 @@ func (l *Lexer) match(matchTo string, skip int) bool {
 	return true
 }
 
+// peekWords the length of input matching the sequence of (lower case) words
+// separated by whitespace, without consuming.  Returns 0 if not matched.
+func (l *Lexer) peekWords(words ...string) int {
+	pos := l.pos
+	for i, word := range words {
+		if i > 0 {
+			start := pos
+			for pos < len(l.input) && isWhiteSpace(rune(l.input[pos])) {
+				pos++
+			}
+			if pos == start {
+				return 0
+			}
+		}
+		if len(l.input)-pos < len(word) || strings.ToLower(l.input[pos:pos+len(word)]) != word {
+			return 0
+		}
+		pos += len(word)
+	}
+	if pos < len(l.input) && IsIdentifierRune(rune(l.input[pos])) {
+		return 0
+	}
+	return pos - l.pos
+}
+
 // Scans input and tries to match the expected string.
 // Returns true if the expected string was matched.
 // Does not advance the input if the string was not matched.
@@ -745,6 +771,10 @@ func (l *Lexer) lexIfMatch(tok TokenType, matchState StateFn) StateFn {
 
 // current clause state function, used for repeated clauses
 func (l *Lexer) clauseState() StateFn {
+	if l.caseDepth > 0 {
+		// inside of CASE ... END the clause continues until END
+		return LexExpression
+	}
 	if l.curClause != nil {
 		if len(l.curClause.Clauses) > 0 {
 			return l.curClause.Clauses[0].Lexer
@@ -754,6 +784,16 @@ func (l *Lexer) clauseState() StateFn {
 	return emptyLexFn
 }
 
+// lexCase consume the CASE keyword, the expression is lexed until the
+// matching END after which lexing continues with next.
+func (l *Lexer) lexCase(next StateFn) StateFn {
+	l.ConsumeWord("case")
+	l.Emit(TokenCase)
+	l.Push("LexCase", next)
+	l.caseDepth++
+	return LexExpression
+}
+
 var emptyLexFn = func(*Lexer) StateFn { u.Debugf("empty statefun"); return nil }
 
 // LexMatchClosure matches expected tokentype emitting the token on success
@@ -1341,7 +1381,7 @@ func LexListOfArgs(l *Lexer) StateFn {
 			l.backup()
 			return LexExpression
 		}
-	case '!', '=', '>', '<', '-', '+', '%', '&', '/', '|':
+	case '!', '=', '>', '<', '-', '+', '%', '&', '/', '|', '^':
 		l.backup()
 		return LexExpression
 	case ';':
@@ -1365,6 +1405,9 @@ func LexListOfArgs(l *Lexer) StateFn {
 			//u.Warnf("found keyword while looking for arg? %v", string(r))
 			return nil
 		}
+		if peekWord == "case" {
+			return l.lexCase(LexListOfArgs)
+		}
 
 		//u.Debugf("LexListOfArgs sending LexExpressionOrIdentity: %v", string(peekWord))
 		l.Push("LexListOfArgs", LexListOfArgs)
@@ -2371,7 +2414,16 @@ func LexExpression(l *Lexer) StateFn {
 		//l.Emit(TokenRightParenthesis)
 		l.backup() // don't consume )
 		return nil
-	case '!', '=', '>', '<', ',', ';', '-', '*', '+', '%', '&', '/', '|':
+	case '~':
+		// regular expression match  ~  and case-insensitive ~*
+		if l.Peek() == '*' {
+			l.Next()
+			l.Emit(TokenRLikeI)
+		} else {
+			l.Emit(TokenRLike)
+		}
+		return LexExpression
+	case '!', '=', '>', '<', ',', ';', '-', '*', '+', '%', '&', '/', '|', '^':
 		foundLogical := false
 		foundOperator := false
 		switch r {
@@ -2413,18 +2465,28 @@ func LexExpression(l *Lexer) StateFn {
 			if r2 := l.Peek(); r2 == '|' {
 				l.Next()
 				l.Emit(TokenOr)
-				foundOperator = true
+			} else {
+				l.Emit(TokenBitOr)
 			}
+			foundOperator = true
 		case '&':
 			if r2 := l.Peek(); r2 == '&' {
 				l.Next()
 				l.Emit(TokenAnd)
-				foundOperator = true
+			} else {
+				l.Emit(TokenBitAnd)
 			}
+			foundOperator = true
+		case '^':
+			l.Emit(TokenBitXor)
+			foundOperator = true
 		case '>':
 			if r2 := l.Peek(); r2 == '=' {
 				l.Next()
 				l.Emit(TokenGE)
+			} else if r2 == '>' { //   >>
+				l.Next()
+				l.Emit(TokenShiftRight)
 			} else {
 				l.Emit(TokenGT)
 			}
@@ -2438,6 +2500,10 @@ func LexExpression(l *Lexer) StateFn {
 				l.Next()
 				l.Emit(TokenNE)
 				foundOperator = true
+			} else if r2 == '<' { //   <<
+				l.Next()
+				l.Emit(TokenShiftLeft)
+				foundOperator = true
 			} else {
 				l.Emit(TokenLT)
 				foundOperator = true
@@ -2479,7 +2545,7 @@ func LexExpression(l *Lexer) StateFn {
 	switch word {
 	case "as":
 		return nil
-	case "in", "intersects", "like", "between", "contains": // what is complete list here?
+	case "in", "intersects", "like", "rlike", "regexp", "between", "contains": // what is complete list here?
 		switch word {
 		case "in":
 			l.ConsumeWord(word)
@@ -2493,6 +2559,9 @@ func LexExpression(l *Lexer) StateFn {
 				if word == "select" {
 					return nil
 				}
+				if l.caseDepth > 0 {
+					l.Push("LexExpression", LexExpression)
+				}
 				l.Push("LexParenRight", LexParenRight)
 				return LexListOfArgs
 			}
@@ -2502,6 +2571,9 @@ func LexExpression(l *Lexer) StateFn {
 			l.Emit(TokenIntersects)
 			l.SkipWhiteSpaces()
 			if l.PeekX(1) == "(" {
+				if l.caseDepth > 0 {
+					l.Push("LexExpression", LexExpression)
+				}
 				l.Push("LexParenRight", LexParenRight)
 				return LexListOfArgs
 			}
@@ -2510,6 +2582,10 @@ func LexExpression(l *Lexer) StateFn {
 			l.ConsumeWord(word)
 			l.Emit(TokenLike)
 			return LexExpressionOrIdentity
+		case "rlike", "regexp":
+			l.ConsumeWord(word)
+			l.Emit(TokenRLike)
+			return LexExpressionOrIdentity
 		case "contains":
 			l.ConsumeWord(word)
 			if l.Peek() == '(' {
@@ -2544,9 +2620,44 @@ func LexExpression(l *Lexer) StateFn {
 		l.Emit(TokenExists)
 		return LexExpression
 	case "is":
+		//  x IS [NOT] DISTINCT FROM y
+		if n := l.peekWords("is", "distinct", "from"); n > 0 {
+			l.skipX(n)
+			l.Emit(TokenDistinctFrom)
+			return LexExpression
+		}
+		if n := l.peekWords("is", "not", "distinct", "from"); n > 0 {
+			l.skipX(n)
+			l.Emit(TokenNotDistinctFrom)
+			return LexExpression
+		}
 		l.ConsumeWord(word)
 		l.Emit(TokenIs)
 		return LexExpression
+	case "case", "when", "then", "else", "end":
+		//  CASE [<expr>] WHEN <expr> THEN <expr> [ELSE <expr>] END
+		switch word {
+		case "case":
+			return l.lexCase(l.clauseState())
+		case "when":
+			l.ConsumeWord(word)
+			l.Emit(TokenWhen)
+		case "then":
+			l.ConsumeWord(word)
+			l.Emit(TokenThen)
+		case "else":
+			l.ConsumeWord(word)
+			l.Emit(TokenElse)
+		case "end":
+			l.ConsumeWord(word)
+			l.Emit(TokenEnd)
+			if l.caseDepth > 0 {
+				l.caseDepth--
+				return nil // ascend to state after CASE
+			}
+			return l.clauseState()
+		}
+		return LexExpression
 	case "null":
 		l.ConsumeWord(word)
 		l.Emit(TokenNull)
@@ -2683,6 +2794,8 @@ func LexOrderByColumn(l *Lexer) StateFn {
 		l.ConsumeWord(word)
 		l.Emit(TokenDesc)
 		return LexOrderByColumn
+	case "case":
+		return l.lexCase(LexOrderByColumn)
 	default:
 		if len(l.stack) < 2 {
 			l.Push("LexOrderByColumn", LexOrderByColumn)
@@ -123,6 +123,22 @@ const (
 	TokenNull             TokenType = 88 // NULL
 	TokenContains         TokenType = 89 // CONTAINS
 	TokenIntersects       TokenType = 90 // INTERSECTS
+	TokenCase             TokenType = 91 // CASE
+	TokenWhen             TokenType = 92 // WHEN
+	TokenThen             TokenType = 93 // THEN
+	TokenElse             TokenType = 94 // ELSE
+	TokenEnd              TokenType = 95 // END
+	TokenDistinctFrom     TokenType = 96 // IS DISTINCT FROM
+	TokenNotDistinctFrom  TokenType = 97 // IS NOT DISTINCT FROM
+	TokenRLike            TokenType = 98 // RLIKE, REGEXP, ~
+	TokenRLikeI           TokenType = 99 // ~*
+
+	// bitwise operators
+	TokenBitAnd     TokenType = 100 // &
+	TokenBitOr      TokenType = 101 // |
+	TokenBitXor     TokenType = 102 // ^
+	TokenShiftLeft  TokenType = 103 // <<
+	TokenShiftRight TokenType = 104 // >>
 
 	// ql top-level keywords, these first keywords determine parser
 	TokenPrepare   TokenType = 200
@@ -180,6 +196,7 @@ const (
 	TokenView           TokenType = 404 // VIEW
 	TokenContinuousView TokenType = 405 // CONTINUOUSVIEW
 	TokenTemp           TokenType = 406 // TEMP or TEMPORARY
+	TokenFunction       TokenType = 407 // FUNCTION
 
 	// ddl other
 	TokenChange       TokenType = 410 // change
@@ -195,6 +212,7 @@ const (
 	TokenForeign      TokenType = 420 // foreign
 	TokenReferences   TokenType = 421 // references
 	TokenEngine       TokenType = 422 // engine
+	TokenReturns      TokenType = 423 // returns
 
 	// Other QL keywords
 	TokenSet  TokenType = 500 // set
@@ -225,6 +243,7 @@ const (
 	TokenTypeTime    TokenType = 991
 	TokenTypeText    TokenType = 990
 	TokenTypeJson    TokenType = 989
+	TokenTypeDecimal TokenType = 988
 
 	// Value types
 	TokenValueType TokenType = 1000 // A generic Identifier of value type
@@ -304,6 +323,22 @@ var (
 		TokenNull:       {Kw: "null", Description: "NULL"},
 		TokenContains:   {Kw: "contains", Description: "contains"},
 		TokenIntersects: {Kw: "intersects", Description: "intersects"},
+		TokenCase:       {Kw: "case", Description: "CASE"},
+		TokenWhen:       {Kw: "when", Description: "WHEN"},
+		TokenThen:       {Kw: "then", Description: "THEN"},
+		TokenElse:       {Kw: "else", Description: "ELSE"},
+		TokenEnd:        {Kw: "end", Description: "END"},
+
+		TokenDistinctFrom:    {Kw: "is distinct from", Description: "IS DISTINCT FROM"},
+		TokenNotDistinctFrom: {Kw: "is not distinct from", Description: "IS NOT DISTINCT FROM"},
+		TokenRLike:           {Kw: "rlike", Description: "RLIKE"},
+		TokenRLikeI:          {Kw: "~*", Description: "~*"},
+
+		TokenBitAnd:     {Kw: "&", Description: "Bitwise And &"},
+		TokenBitOr:      {Kw: "|", Description: "Bitwise Or |"},
+		TokenBitXor:     {Kw: "^", Description: "Bitwise Xor ^"},
+		TokenShiftLeft:  {Kw: "<<", Description: "Shift Left <<"},
+		TokenShiftRight: {Kw: ">>", Description: "Shift Right >>"},
 
 		// Identity ish bools
 		TokenTrue:  {Kw: "true", Description: "True"},
@@ -373,6 +408,7 @@ var (
 		TokenView:           {Description: "view"},
 		TokenContinuousView: {Description: "continuousview"},
 		TokenTemp:           {Description: "temp"},
+		TokenFunction:       {Description: "function"},
 		// ddl other
 		TokenChange:       {Description: "change"},
 		TokenCharacterSet: {Description: "character set"},
@@ -387,6 +423,7 @@ var (
 		TokenForeign:      {Description: "foreign"},
 		TokenReferences:   {Description: "references"},
 		TokenEngine:       {Description: "engine"},
+		TokenReturns:      {Description: "returns"},
 
 		// QL Keywords, all lower-case
 		TokenSet:  {Description: "set"},
@@ -414,6 +451,7 @@ var (
 		TokenTypeTime:    {Description: "TimeType"},
 		TokenTypeText:    {Description: "TextType"},
 		TokenTypeJson:    {Description: "JsonType"},
+		TokenTypeDecimal: {Description: "DecimalType"},
 
 		// VALUE TYPES:  ie literal values
 		TokenBool:    {Description: "BoolVal"},
@@ -453,6 +491,9 @@ func LoadTokenInfo() {
 			ti.HasSpaces = true
 		}
 	}
+	// operators with more than one keyword
+	TokenToOp["regexp"] = TokenRLike
+	TokenToOp["~"] = TokenRLike
 }
 
 // TokenFromOp get token from operation string
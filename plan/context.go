@@ -53,6 +53,13 @@ type Context struct {
 
 	// From configuration
 	DisableRecover bool
+	// NullLogic evaluate expressions with sql three-valued NULL logic, see
+	// vm.NewNullLogicContext.  Default is missing values make comparisons false.
+	NullLogic bool
+	// MessageBatchSize messages exec tasks send per channel send, as one
+	// exec.MessageBatch, to tasks that accept batches.  0 or 1 sends each
+	// message on its own.
+	MessageBatchSize int
 
 	// Local State
 	Errors     []error
@@ -0,0 +1,145 @@
+package plan
+
+import (
+	"database/sql/driver"
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/schema"
+)
+
+// FindIndexSeek find an index seek for the where clause on any single
+// column index of table.  Only top level AND terms are considered as each
+// narrows the rows, and point lookups (=, IN) are preferred over ranges
+// (>, >=, <, <=, BETWEEN).  Returns nil if no index can be used.
+func FindIndexSeek(tbl *schema.Table, where expr.Node) *schema.IndexSeek {
+	if tbl == nil || where == nil || len(tbl.Indexes) == 0 {
+		return nil
+	}
+	terms := expr.AndTerms(where)
+
+	var rangeSeek *schema.IndexSeek
+	for _, idx := range tbl.Indexes {
+		if len(idx.Fields) != 1 {
+			continue
+		}
+		seek := &schema.IndexSeek{Index: idx, Field: idx.Fields[0]}
+		isRange := false
+		for _, term := range terms {
+			switch seekTerm(seek, term) {
+			case seekPoint:
+				return seek
+			case seekRange:
+				isRange = true
+			}
+		}
+		if isRange && rangeSeek == nil {
+			rangeSeek = seek
+		}
+	}
+	return rangeSeek
+}
+
+type seekType int
+
+const (
+	seekNone seekType = iota
+	seekPoint
+	seekRange
+)
+
+// seekTerm apply the where term to seek if it is a predicate on
+// the seek field.
+func seekTerm(seek *schema.IndexSeek, term expr.Node) seekType {
+	switch n := term.(type) {
+	case *expr.BinaryNode:
+		op := n.Operator.T
+		left, right := n.Args[0], n.Args[1]
+		if !isSeekField(seek.Field, left) {
+			if !isSeekField(seek.Field, right) {
+				return seekNone
+			}
+			// 5 < col  =>  col > 5
+			left, right = right, left
+			switch op {
+			case lex.TokenGT:
+				op = lex.TokenLT
+			case lex.TokenGE:
+				op = lex.TokenLE
+			case lex.TokenLT:
+				op = lex.TokenGT
+			case lex.TokenLE:
+				op = lex.TokenGE
+			case lex.TokenIN:
+				return seekNone
+			}
+		}
+		switch op {
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			if v, ok := literalValue(right); ok {
+				seek.Values = []driver.Value{v}
+				return seekPoint
+			}
+		case lex.TokenIN:
+			arr, isArray := right.(*expr.ArrayNode)
+			if !isArray || len(arr.Args) == 0 {
+				return seekNone
+			}
+			vals := make([]driver.Value, 0, len(arr.Args))
+			for _, arg := range arr.Args {
+				v, ok := literalValue(arg)
+				if !ok {
+					return seekNone
+				}
+				vals = append(vals, v)
+			}
+			seek.Values = vals
+			return seekPoint
+		case lex.TokenGT, lex.TokenGE:
+			if v, ok := literalValue(right); ok {
+				seek.Lower, seek.LowerInclusive = v, op == lex.TokenGE
+				return seekRange
+			}
+		case lex.TokenLT, lex.TokenLE:
+			if v, ok := literalValue(right); ok {
+				seek.Upper, seek.UpperInclusive = v, op == lex.TokenLE
+				return seekRange
+			}
+		}
+	case *expr.TriNode:
+		if n.Operator.T != lex.TokenBetween || n.Negated() || !isSeekField(seek.Field, n.Args[0]) {
+			return seekNone
+		}
+		lower, lok := literalValue(n.Args[1])
+		upper, uok := literalValue(n.Args[2])
+		if lok && uok {
+			seek.Lower, seek.LowerInclusive = lower, true
+			seek.Upper, seek.UpperInclusive = upper, true
+			return seekRange
+		}
+	}
+	return seekNone
+}
+
+func isSeekField(field string, node expr.Node) bool {
+	in, isIdent := node.(*expr.IdentityNode)
+	if !isIdent || in.IsBooleanIdentity() {
+		return false
+	}
+	_, right, _ := in.LeftRight()
+	return strings.EqualFold(right, field)
+}
+
+func literalValue(node expr.Node) (driver.Value, bool) {
+	switch n := node.(type) {
+	case *expr.StringNode:
+		return n.Text, true
+	case *expr.NumberNode:
+		if n.IsInt {
+			return n.Int64, true
+		}
+		return n.Float64, true
+	}
+	return nil, false
+}
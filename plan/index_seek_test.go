@@ -0,0 +1,39 @@
+package plan_test
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/schema"
+)
+
+func TestFindIndexSeek(t *testing.T) {
+	tbl := schema.NewTable("users")
+	tbl.SetColumns([]string{"user_id", "email", "ct"})
+	tbl.Indexes = []*schema.Index{
+		{Name: "id", Fields: []string{"user_id"}, PrimaryKey: true},
+		{Name: "email", Fields: []string{"email"}},
+	}
+
+	tests := []struct {
+		where string
+		seek  *schema.IndexSeek
+	}{
+		{`user_id = 5`, &schema.IndexSeek{Index: tbl.Indexes[0], Field: "user_id", Values: []driver.Value{int64(5)}}},
+		{`ct > 1 AND email IN ("a", "b")`, &schema.IndexSeek{Index: tbl.Indexes[1], Field: "email", Values: []driver.Value{"a", "b"}}},
+		{`user_id > 5 AND 10 >= user_id`, &schema.IndexSeek{Index: tbl.Indexes[0], Field: "user_id",
+			Lower: int64(5), Upper: int64(10), UpperInclusive: true}},
+		{`user_id BETWEEN 1 AND 3 AND email = "x"`, &schema.IndexSeek{Index: tbl.Indexes[1], Field: "email", Values: []driver.Value{"x"}}},
+		{`u.user_id = 5 OR email = "x"`, nil},
+		{`ct = 5`, nil},
+		{`user_id = ct`, nil},
+	}
+	for _, tt := range tests {
+		seek := plan.FindIndexSeek(tbl, expr.MustParse(tt.where))
+		assert.Equal(t, tt.seek, seek, tt.where)
+	}
+}
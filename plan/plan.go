@@ -202,11 +202,14 @@ type (
 		Tbl        *schema.Table  // Table schema for this From
 		Static     []driver.Value // this is static data source
 		Cols       []string
+		Pushdown   *Pushdown         // split of statement for sources declaring schema.Capabilities
+		Seek       *schema.IndexSeek // index seek found from where, for schema.ConnIndexSeeker
 	}
 	// Into Select INTO table
 	Into struct {
 		*PlanBase
-		Stmt *rel.SqlInto
+		Stmt   *rel.SqlInto
+		Source schema.ConnUpsert
 	}
 	// GroupBy clause plan
 	GroupBy struct {
@@ -410,6 +413,7 @@ func (m *Update) Walk(p Planner) error            { return p.WalkUpdate(m) }
 func (m *Delete) Walk(p Planner) error            { return p.WalkDelete(m) }
 func (m *Command) Walk(p Planner) error           { return p.WalkCommand(m) }
 func (m *Source) Walk(p Planner) error            { return p.WalkSourceSelect(m) }
+func (m *Into) Walk(p Planner) error              { return p.WalkInto(m) }
 func (m *Create) Walk(p Planner) error            { return p.WalkCreate(m) }
 func (m *Drop) Walk(p Planner) error              { return p.WalkDrop(m) }
 func (m *Alter) Walk(p Planner) error             { return p.WalkAlter(m) }
@@ -891,6 +895,11 @@ func NewWhereFinal(stmt *rel.SqlSelect) *Where {
 	return &Where{Stmt: stmt, Final: true, PlanBase: NewPlanBase(false)}
 }
 
+// NewInto from SqlSelect INTO statement.
+func NewInto(stmt *rel.SqlInto) *Into {
+	return &Into{Stmt: stmt, PlanBase: NewPlanBase(false)}
+}
+
 // NewHaving from SqlSelect statement.
 func NewHaving(stmt *rel.SqlSelect) *Having {
 	return &Having{Stmt: stmt, PlanBase: NewPlanBase(false)}
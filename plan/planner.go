@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	u "github.com/araddon/gou"
+
+	"github.com/araddon/qlbridge/lex"
 )
 
 var (
@@ -54,6 +56,9 @@ func (m *PlannerDefault) WalkDrop(p *Drop) error {
 // WalkCreate walk a Create Plan to create the dag of tasks for Create.
 func (m *PlannerDefault) WalkCreate(p *Create) error {
 	u.Debugf("WalkCreate %#v", p)
+	if p.Stmt.Tok.T == lex.TokenFunction {
+		return nil
+	}
 	if len(p.Stmt.With) == 0 {
 		return fmt.Errorf("CREATE {SCHEMA|SOURCE|DATABASE}")
 	}
@@ -12,9 +12,15 @@ var (
 	_ = u.EMPTY
 )
 
+// WalkInto find the upsert source for the SELECT INTO table.
 func (m *PlannerDefault) WalkInto(p *Into) error {
 	u.Debugf("VisitInto %+v", p.Stmt)
-	return ErrNotImplemented
+	src, err := upsertSource(m.Ctx, p.Stmt.Table)
+	if err != nil {
+		return err
+	}
+	p.Source = src
+	return nil
 }
 
 func upsertSource(ctx *Context, table string) (schema.ConnUpsert, error) {
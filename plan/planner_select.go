@@ -2,6 +2,7 @@ package plan
 
 import (
 	"fmt"
+	"strings"
 
 	u "github.com/araddon/gou"
 
@@ -42,6 +43,9 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 		if err != nil {
 			return err
 		}
+		if err := TypeCheckSelect(srcPlan.Tbl, p.Stmt); err != nil {
+			return err
+		}
 		p.From = append(p.From, srcPlan)
 		p.Add(srcPlan)
 
@@ -50,6 +54,15 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 			return err
 		}
 
+		if srcPlan.Pushdown != nil {
+			if !srcPlan.Pushdown.Complete {
+				if err := m.walkSelectResidual(p, srcPlan.Pushdown); err != nil {
+					return err
+				}
+			}
+			goto finalProjection
+		}
+
 		if srcPlan.Complete && !needsFinalProjection(p.Stmt) {
 			goto finalProjection
 		}
@@ -58,6 +71,7 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 
 		var prevSource *Source
 		var prevTask Task
+		tbls := make(map[string]*schema.Table, len(p.Stmt.From))
 
 		for i, from := range p.Stmt.From {
 
@@ -85,6 +99,15 @@ func (m *PlannerDefault) WalkSelect(p *Select) error {
 			}
 			prevSource = srcPlan
 			//u.Debugf("got task: %T", lastSource)
+			if srcPlan.Tbl != nil {
+				tbls[strings.ToLower(from.Name)] = srcPlan.Tbl
+				if from.Alias != "" {
+					tbls[strings.ToLower(from.Alias)] = srcPlan.Tbl
+				}
+			}
+		}
+		if err := TypeCheckJoin(tbls, p.Stmt); err != nil {
+			return err
 		}
 		p.Add(prevTask)
 
@@ -137,6 +160,42 @@ finalProjection:
 		//u.Debugf("m.Ctx: %p m.Ctx.Projection:    %T:%p", m.Ctx, m.Ctx.Projection, m.Ctx.Projection)
 	}
 
+	if p.Stmt.Into != nil {
+		into := NewInto(p.Stmt.Into)
+		if err := into.Walk(m.Planner); err != nil {
+			return err
+		}
+		p.Add(into)
+	}
+
+	return nil
+}
+
+// walkSelectResidual add the tasks for the parts of the statement that
+// were not pushed down to the source.
+func (m *PlannerDefault) walkSelectResidual(p *Select, pd *Pushdown) error {
+
+	needsFinalProject := true
+
+	if pd.Where != nil {
+		p.Add(NewWhere(pd.WhereStmt(p.Stmt)))
+	}
+
+	if p.Stmt.IsAggQuery() && !pd.GroupBy {
+		p.Add(NewGroupBy(p.Stmt))
+		needsFinalProject = false
+		if p.Stmt.Having != nil {
+			p.Add(NewHaving(p.Stmt))
+		}
+	}
+
+	if len(p.Stmt.OrderBy) > 0 && !pd.OrderBy {
+		p.Add(NewOrder(p.Stmt))
+	}
+
+	if needsFinalProject {
+		return m.WalkProjectionFinal(p)
+	}
 	return nil
 }
 
@@ -206,6 +265,12 @@ func (m *PlannerDefault) WalkSourceSelect(p *Source) error {
 	}
 
 	if sourcePlanner, hasSourcePlanner := p.Conn.(SourcePlanner); hasSourcePlanner {
+		// Sources declaring capabilities get the statement split into
+		// the pushed down part, and the residual we poly-fill.
+		if caps, hasCaps := p.DataSource.(schema.SourceCapabilities); hasCaps && p.Final &&
+			p.Stmt.Source != nil && p.Stmt.SubQuery == nil {
+			p.Pushdown = NewPushdown(caps.Capabilities(), p.Stmt.Source, p.Tbl)
+		}
 		// Can do our own planning
 		t, err := sourcePlanner.WalkSourceSelect(m.Planner, p)
 		if err != nil {
@@ -225,6 +290,11 @@ func (m *PlannerDefault) WalkSourceSelect(p *Source) error {
 			return fmt.Errorf("%q Didn't implement schema.ConnColumns: %T", p.Stmt.SourceName(), p.Conn)
 		}
 
+		if _, canSeek := p.Conn.(schema.ConnIndexSeeker); canSeek && p.Stmt.Source != nil &&
+			p.Stmt.Source.Where != nil && p.Stmt.Source.Where.Expr != nil {
+			p.Seek = FindIndexSeek(p.Tbl, p.Stmt.Source.Where.Expr)
+		}
+
 		if p.Stmt.Source != nil && p.Stmt.Source.Where != nil {
 			switch {
 			case p.Stmt.Source.Where.Expr != nil:
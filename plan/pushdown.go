@@ -0,0 +1,216 @@
+package plan
+
+import (
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+)
+
+// Pushdown is the split of a single source select statement into the part
+// a source executes natively (per its schema.Capabilities) and the residual
+// that is run by qlbridge Where, GroupBy, Order, Projection tasks.
+//
+//	SELECT user_id, emaildomain(email) AS domain FROM users
+//	WHERE user_id > 5 AND emaildomain(email) = "x.com" ORDER BY user_id
+//
+//	pushed:    SELECT user_id, email FROM users WHERE user_id > 5 ORDER BY user_id
+//	residual:  WHERE emaildomain(email) = "x.com", projection
+type Pushdown struct {
+	// Stmt the statement the source should execute.  If Complete it is the
+	// original statement, otherwise a raw (no aliases, functions, aggregates)
+	// select of the columns needed to evaluate the residual.
+	Stmt *rel.SqlSelect
+	// Where residual where expression qlbridge must evaluate, nil if
+	// the where was completely pushed down.
+	Where expr.Node
+	// GroupBy aggregation (group by, having) was pushed down.
+	GroupBy bool
+	// OrderBy ordering was pushed down.
+	OrderBy bool
+	// Limit limit, offset was pushed down.
+	Limit bool
+	// Complete the entire statement was pushed down, rows returned by the
+	// source are the final result rows.
+	Complete bool
+}
+
+// NewPushdown split the select statement into pushed down and residual parts
+// using the capabilities of the source.  The table is optional, if provided
+// only columns that exist in table are requested from source.
+func NewPushdown(caps *schema.Capabilities, stmt *rel.SqlSelect, tbl *schema.Table) *Pushdown {
+
+	pd := &Pushdown{}
+
+	var pushed, residual []expr.Node
+	if stmt.Where != nil && stmt.Where.Expr != nil {
+		for _, n := range expr.AndTerms(stmt.Where.Expr) {
+			if caps.SupportsExpr(n) {
+				pushed = append(pushed, n)
+			} else {
+				residual = append(residual, n)
+			}
+		}
+	}
+	pd.Where = joinAnd(residual)
+	whereDone := len(residual) == 0
+
+	colsOk := caps.Distinct || !stmt.Distinct
+	for _, col := range stmt.Columns {
+		if col.Star {
+			continue
+		}
+		if col.Expr == nil || !caps.SupportsExpr(col.Expr) {
+			colsOk = false
+			break
+		}
+	}
+	hasOrder := len(stmt.OrderBy) > 0
+	orderOk := caps.OrderBy && columnsSupported(caps, stmt.OrderBy)
+	hasLimit := stmt.Limit > 0 || stmt.Offset > 0
+
+	if stmt.IsAggQuery() {
+		// aggregation is only pushed down along with everything else, as
+		// the rows returned are then already final.
+		if whereDone && colsOk && caps.GroupBy && columnsSupported(caps, stmt.GroupBy) &&
+			(stmt.Having == nil || caps.SupportsExpr(stmt.Having)) &&
+			(!hasOrder || orderOk) && (!hasLimit || caps.Limit) {
+			pd.Stmt = stmt
+			pd.GroupBy = true
+			pd.OrderBy = hasOrder
+			pd.Limit = hasLimit
+			pd.Complete = true
+			return pd
+		}
+	} else {
+		// filtering (residual where) and projection preserve order, so
+		// ordering by source columns may be pushed down even if they
+		// are not.
+		pd.OrderBy = hasOrder && orderOk && ((whereDone && colsOk) || orderBySourceColumns(stmt))
+		pd.Limit = hasLimit && caps.Limit && whereDone && !stmt.Distinct && (!hasOrder || pd.OrderBy)
+		if whereDone && colsOk && (!hasOrder || pd.OrderBy) && (!hasLimit || caps.Limit) {
+			pd.Stmt = stmt
+			pd.Limit = hasLimit
+			pd.Complete = true
+			return pd
+		}
+	}
+
+	pd.Stmt = rawSelect(stmt, tbl)
+	if where := joinAnd(pushed); where != nil {
+		pd.Stmt.Where = &rel.SqlWhere{Expr: where}
+	}
+	if pd.OrderBy {
+		pd.Stmt.OrderBy = stmt.OrderBy
+	}
+	if pd.Limit {
+		pd.Stmt.Limit = stmt.Limit
+		pd.Stmt.Offset = stmt.Offset
+	}
+	return pd
+}
+
+// WhereStmt the statement to use for residual where filtering, ie the
+// statement with its where replaced by the residual where.
+func (m *Pushdown) WhereStmt(stmt *rel.SqlSelect) *rel.SqlSelect {
+	sel := *stmt
+	sel.Where = &rel.SqlWhere{Expr: m.Where}
+	return &sel
+}
+
+func columnsSupported(caps *schema.Capabilities, cols rel.Columns) bool {
+	for _, col := range cols {
+		if col.Expr == nil || !caps.SupportsExpr(col.Expr) {
+			return false
+		}
+	}
+	return true
+}
+
+// orderBySourceColumns is the order by made up of only identities that
+// refer to source columns (not aliases of projected expressions).
+func orderBySourceColumns(stmt *rel.SqlSelect) bool {
+	aliases := make(map[string]bool)
+	for _, col := range stmt.Columns {
+		if in, isIdent := col.Expr.(*expr.IdentityNode); isIdent {
+			if _, right, _ := in.LeftRight(); right == col.As {
+				continue
+			}
+		}
+		aliases[col.As] = true
+	}
+	for _, col := range stmt.OrderBy {
+		in, isIdent := col.Expr.(*expr.IdentityNode)
+		if !isIdent {
+			return false
+		}
+		if _, right, _ := in.LeftRight(); aliases[right] {
+			return false
+		}
+	}
+	return true
+}
+
+// rawSelect create a select of the un-aliased source columns needed
+// to evaluate all expressions of the statement.
+func rawSelect(stmt *rel.SqlSelect, tbl *schema.Table) *rel.SqlSelect {
+	sel := &rel.SqlSelect{}
+	sel.From = append(sel.From, &rel.SqlSource{Name: stmt.From[0].Name})
+	if stmt.Star {
+		sel.AddColumn(rel.Column{Star: true})
+		return sel
+	}
+
+	aliases := make(map[string]bool)
+	for _, col := range stmt.Columns {
+		aliases[col.As] = true
+	}
+	added := make(map[string]bool)
+	addIdentities := func(node expr.Node, isColumn bool) {
+		for _, in := range expr.FindAllIdentities(node) {
+			if in.Text == "*" || in.IsBooleanIdentity() {
+				continue
+			}
+			_, right, _ := in.LeftRight()
+			if added[right] || (!isColumn && aliases[right]) {
+				continue
+			}
+			if tbl != nil {
+				if _, inTable := tbl.Column(right); !inTable {
+					continue
+				}
+			}
+			added[right] = true
+			sel.AddColumn(*rel.NewColumn(right))
+		}
+	}
+	for _, col := range stmt.Columns {
+		addIdentities(col.Expr, true)
+	}
+	if stmt.Where != nil && stmt.Where.Expr != nil {
+		addIdentities(stmt.Where.Expr, false)
+	}
+	for _, col := range stmt.GroupBy {
+		addIdentities(col.Expr, false)
+	}
+	if stmt.Having != nil {
+		addIdentities(stmt.Having, false)
+	}
+	for _, col := range stmt.OrderBy {
+		addIdentities(col.Expr, false)
+	}
+	return sel
+}
+
+// joinAnd join terms into a single AND expression, nil if no terms.
+func joinAnd(terms []expr.Node) expr.Node {
+	var node expr.Node
+	for _, term := range terms {
+		if node == nil {
+			node = term
+			continue
+		}
+		node = expr.NewBinaryNode(lex.Token{T: lex.TokenLogicAnd, V: "AND"}, node, term)
+	}
+	return node
+}
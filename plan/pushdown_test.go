@@ -0,0 +1,76 @@
+package plan_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+)
+
+func TestPushdown(t *testing.T) {
+	caps := schema.NewCapabilities()
+	caps.Funcs["tolower"] = true
+	caps.Aggregates["count"] = true
+	for _, op := range []lex.TokenType{lex.TokenEqual, lex.TokenGT, lex.TokenLogicAnd} {
+		caps.Operators[op] = true
+	}
+	caps.GroupBy = true
+	caps.OrderBy = true
+	caps.Limit = true
+
+	tests := []struct {
+		sql      string
+		pushed   string
+		residual string
+		complete bool
+		orderBy  bool
+		limit    bool
+	}{
+		{
+			sql:      `SELECT user_id, tolower(email) AS e FROM users WHERE ct > 5 ORDER BY user_id LIMIT 10`,
+			pushed:   `SELECT user_id, tolower(email) AS e FROM users WHERE ct > 5 ORDER BY user_id LIMIT 10`,
+			complete: true, orderBy: true, limit: true,
+		},
+		{
+			sql:      `SELECT user_id, count(*) AS ct FROM users WHERE ct > 5 GROUP BY user_id`,
+			pushed:   `SELECT user_id, count(*) AS ct FROM users WHERE ct > 5 GROUP BY user_id`,
+			complete: true,
+		},
+		{
+			// residual where, so only raw columns and order by source column pushed
+			sql:      `SELECT user_id FROM users WHERE ct > 5 AND emaildomain(email) = "x.com" ORDER BY user_id LIMIT 10`,
+			pushed:   `SELECT user_id, ct, email FROM users WHERE ct > 5 ORDER BY user_id`,
+			residual: `emaildomain(email) = "x.com"`,
+			orderBy:  true,
+		},
+		{
+			// un-supported aggregate, qlbridge groups
+			sql:    `SELECT user_id, sum(ct) AS total FROM users WHERE ct > 5 GROUP BY user_id HAVING total > 1`,
+			pushed: `SELECT user_id, ct FROM users WHERE ct > 5`,
+		},
+		{
+			// un-supported projection, ordering by alias isn't pushed down
+			sql:    `SELECT emaildomain(email) AS domain FROM users ORDER BY domain`,
+			pushed: `SELECT email FROM users`,
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := rel.ParseSqlSelect(tt.sql)
+		assert.Equal(t, nil, err, tt.sql)
+		pd := plan.NewPushdown(caps, stmt, nil)
+		assert.Equal(t, tt.pushed, pd.Stmt.String(), tt.sql)
+		assert.Equal(t, tt.complete, pd.Complete, tt.sql)
+		assert.Equal(t, tt.complete && stmt.IsAggQuery(), pd.GroupBy, tt.sql)
+		assert.Equal(t, tt.orderBy, pd.OrderBy, tt.sql)
+		assert.Equal(t, tt.limit, pd.Limit, tt.sql)
+		if tt.residual == "" {
+			assert.Equal(t, nil, pd.Where, tt.sql)
+		} else {
+			assert.Equal(t, tt.residual, pd.Where.String(), tt.sql)
+		}
+	}
+}
@@ -203,7 +203,8 @@ func RewriteShowAsSelect(stmt *rel.SqlShow, ctx *Context) (*rel.SqlSelect, error
 		u.Warnf("unhandled sql rewrite statement %s", raw)
 		return nil, fmt.Errorf("Unrecognized:   %s", raw)
 	}
-	sel, err := rel.ParseSqlSelectResolver(sqlStatement, ctx.Funcs)
+	// parse with our typewriter, ctx.Funcs may be the schemas functions
+	sel, err := rel.ParseSqlSelectResolver(sqlStatement, fr)
 	if err != nil {
 		u.Errorf("could not reparse %s  err=%v", sqlStatement, err)
 		return nil, err
@@ -0,0 +1,471 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+type (
+	// TypeError an expression whose operands, function arguments are of
+	// incompatible types.  Token is the operator nearest the error, for its
+	// line, column position in the statement.
+	TypeError struct {
+		Token lex.Token
+		Node  expr.Node
+		Msg   string
+	}
+	// TypeErrors all of the type errors found in a statement.
+	TypeErrors []*TypeError
+
+	// typeChecker resolves identities against a table to check expressions,
+	// or for joins qualified identities against the table of each source.
+	typeChecker struct {
+		tbl  *schema.Table
+		tbls map[string]*schema.Table // lower-case source alias to table
+		errs TypeErrors
+	}
+)
+
+func (m *TypeError) Error() string {
+	if m.Token.Line == 0 {
+		return m.Msg
+	}
+	return fmt.Sprintf("line %d column %d: %s", m.Token.Line, m.Token.Column, m.Msg)
+}
+
+func (m TypeErrors) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// type classes, types within a class are compatible
+type typeClass int
+
+const (
+	classAny typeClass = iota
+	classNumber
+	classString
+	classBool
+	classTime
+)
+
+func classOf(vt value.ValueType) typeClass {
+	switch vt {
+	case value.IntType, value.NumberType, value.DecimalType:
+		return classNumber
+	case value.StringType, value.ByteSliceType:
+		return classString
+	case value.BoolType:
+		return classBool
+	case value.TimeType:
+		return classTime
+	}
+	return classAny
+}
+
+func (c typeClass) String() string {
+	switch c {
+	case classNumber:
+		return "number"
+	case classString:
+		return "string"
+	case classBool:
+		return "bool"
+	case classTime:
+		return "time"
+	}
+	return "any"
+}
+
+// TypeCheck infer the type of the expression with identities resolved to the
+// fields of tbl, checking operators and function arguments are of compatible
+// types.  Strings compare to numbers as the vm coerces them, numeric string
+// literals may be used as numbers.  The node is not modified.  Identities not found in tbl (ie sparse, no-sql sources) are of unknown
+// type and not checked.  Returns TypeErrors if any are found.
+func TypeCheck(tbl *schema.Table, node expr.Node) (value.ValueType, error) {
+	tc := &typeChecker{tbl: tbl}
+	vt := tc.check(nodeToken(node), node)
+	if len(tc.errs) > 0 {
+		return vt, tc.errs
+	}
+	return vt, nil
+}
+
+// TypeCheckSelect type check the expressions (columns, where, group by,
+// having, order by) of a single table select statement.
+func TypeCheckSelect(tbl *schema.Table, stmt *rel.SqlSelect) error {
+	if tbl == nil || stmt == nil {
+		return nil
+	}
+	tc := &typeChecker{tbl: tbl}
+	tc.checkSelect(stmt)
+	if len(tc.errs) > 0 {
+		return tc.errs
+	}
+	return nil
+}
+
+// TypeCheckJoin type check the expressions of a multi-source (join) select
+// statement including the join expressions.  The tables of the sources are
+// keyed by lower-case alias, only qualified identities (alias.column) are
+// resolved as un-qualified ones may be ambiguous.
+func TypeCheckJoin(tbls map[string]*schema.Table, stmt *rel.SqlSelect) error {
+	if len(tbls) == 0 || stmt == nil {
+		return nil
+	}
+	tc := &typeChecker{tbls: tbls}
+	for _, from := range stmt.From {
+		if from.JoinExpr != nil {
+			tc.checkBool(nodeToken(from.JoinExpr), from.JoinExpr)
+		}
+	}
+	tc.checkSelect(stmt)
+	if len(tc.errs) > 0 {
+		return tc.errs
+	}
+	return nil
+}
+
+func (m *typeChecker) checkSelect(stmt *rel.SqlSelect) {
+	for _, col := range stmt.Columns {
+		if col.Expr != nil && !col.Star {
+			m.check(nodeToken(col.Expr), col.Expr)
+		}
+		if col.Guard != nil {
+			m.checkBool(nodeToken(col.Guard), col.Guard)
+		}
+	}
+	if stmt.Where != nil && stmt.Where.Expr != nil {
+		m.checkBool(nodeToken(stmt.Where.Expr), stmt.Where.Expr)
+	}
+	for _, col := range stmt.GroupBy {
+		if col.Expr != nil {
+			m.check(nodeToken(col.Expr), col.Expr)
+		}
+	}
+	if stmt.Having != nil {
+		m.checkBool(nodeToken(stmt.Having), stmt.Having)
+	}
+	for _, col := range stmt.OrderBy {
+		if col.Expr != nil {
+			m.check(nodeToken(col.Expr), col.Expr)
+		}
+	}
+}
+
+// nodeToken the token of a node, for the position of errors in top level
+// expressions which have no enclosing operator.
+func nodeToken(node expr.Node) lex.Token {
+	switch n := node.(type) {
+	case *expr.BinaryNode:
+		return n.Operator
+	case *expr.BooleanNode:
+		return n.Operator
+	case *expr.TriNode:
+		return n.Operator
+	case *expr.UnaryNode:
+		return n.Operator
+	case *expr.FuncNode:
+		return n.Token
+	}
+	return lex.Token{}
+}
+
+func (m *typeChecker) errorf(tok lex.Token, n expr.Node, format string, args ...interface{}) {
+	m.errs = append(m.errs, &TypeError{Token: tok, Node: n, Msg: fmt.Sprintf(format, args...)})
+}
+
+// check infer the type of node, tok is the nearest enclosing operator.
+func (m *typeChecker) check(tok lex.Token, node expr.Node) value.ValueType {
+	switch n := node.(type) {
+	case *expr.NumberNode:
+		if n.IsInt {
+			return value.IntType
+		}
+		return value.NumberType
+	case *expr.StringNode:
+		return value.StringType
+	case *expr.NullNode:
+		return value.NilType
+	case *expr.ValueNode:
+		if n.Value == nil {
+			return value.UnknownType
+		}
+		return n.Value.Type()
+	case *expr.IdentityNode:
+		return m.identityType(n)
+	case *expr.ArrayNode:
+		for i := range n.Args {
+			m.check(tok, n.Args[i])
+		}
+		return value.SliceValueType
+	case *expr.FuncNode:
+		if n.Token.Line > 0 {
+			tok = n.Token
+		}
+		return m.checkFunc(tok, n)
+	case *expr.UnaryNode:
+		switch n.Operator.T {
+		case lex.TokenNegate:
+			m.checkBool(n.Operator, n.Arg)
+			return value.BoolType
+		case lex.TokenMinus:
+			vt := m.check(n.Operator, n.Arg)
+			m.want(n.Operator, n.Arg, vt, classNumber)
+			return value.NumberType
+		}
+		m.check(n.Operator, n.Arg)
+		return value.BoolType
+	case *expr.BooleanNode:
+		for i := range n.Args {
+			m.checkBool(n.Operator, n.Args[i])
+		}
+		return value.BoolType
+	case *expr.TriNode:
+		vt := m.check(n.Operator, n.Args[0])
+		for i := 1; i < len(n.Args); i++ {
+			m.compare(n.Operator, n.Args[0], n.Args[i], vt, m.check(n.Operator, n.Args[i]))
+		}
+		return value.BoolType
+	case *expr.BinaryNode:
+		return m.checkBinary(n)
+	case *expr.CaseNode:
+		return m.checkCase(tok, n)
+	}
+	return value.UnknownType
+}
+
+func (m *typeChecker) identityType(n *expr.IdentityNode) value.ValueType {
+	if n.IsBooleanIdentity() {
+		return value.BoolType
+	}
+	tbl := m.tbl
+	left, right, hasLeft := n.LeftRight()
+	if hasLeft && m.tbls != nil {
+		tbl = m.tbls[strings.ToLower(left)]
+	}
+	if tbl == nil {
+		return value.UnknownType
+	}
+	if vt, ok := tbl.Column(n.Text); ok {
+		return vt
+	}
+	if hasLeft {
+		if vt, ok := tbl.Column(right); ok {
+			return vt
+		}
+	}
+	return value.UnknownType
+}
+
+// checkBool check the node is a boolean expression.
+func (m *typeChecker) checkBool(tok lex.Token, node expr.Node) {
+	vt := m.check(tok, node)
+	if c := classOf(vt); c != classAny && c != classBool {
+		m.errorf(tok, node, "expected bool but %s is %s", node, c)
+	}
+}
+
+func (m *typeChecker) checkBinary(n *expr.BinaryNode) value.ValueType {
+	op := n.Operator
+	at := m.check(op, n.Args[0])
+	bt := m.check(op, n.Args[1])
+
+	switch op.T {
+	case lex.TokenLogicAnd, lex.TokenLogicOr, lex.TokenAnd, lex.TokenOr:
+		for i, vt := range []value.ValueType{at, bt} {
+			if c := classOf(vt); c != classAny && c != classBool {
+				m.errorf(op, n.Args[i], "expected bool but %s is %s", n.Args[i], c)
+			}
+		}
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
+		lex.TokenLT, lex.TokenLE:
+		m.compare(op, n.Args[0], n.Args[1], at, bt)
+	case lex.TokenIN:
+		if arr, isArray := n.Args[1].(*expr.ArrayNode); isArray {
+			for i := range arr.Args {
+				m.compare(op, n.Args[0], arr.Args[i], at, m.check(op, arr.Args[i]))
+			}
+		}
+	case lex.TokenLike, lex.TokenRLike, lex.TokenRLikeI:
+		for i, vt := range []value.ValueType{at, bt} {
+			if c := classOf(vt); c == classBool || c == classNumber {
+				m.errorf(op, n.Args[i], "%s requires string but %s is %s", op.V, n.Args[i], c)
+			}
+		}
+	case lex.TokenBitAnd, lex.TokenBitOr, lex.TokenBitXor, lex.TokenShiftLeft, lex.TokenShiftRight:
+		m.want(op, n.Args[0], at, classNumber)
+		m.want(op, n.Args[1], bt, classNumber)
+		return value.IntType
+	case lex.TokenPlus, lex.TokenMinus, lex.TokenMultiply, lex.TokenDivide, lex.TokenModulus:
+		if vt, isDate := dateMathType(op.T, at, bt); isDate {
+			return vt
+		}
+		m.want(op, n.Args[0], at, classNumber)
+		m.want(op, n.Args[1], bt, classNumber)
+		switch {
+		case op.T == lex.TokenModulus:
+			return value.IntType
+		case at == value.IntType && bt == value.IntType && op.T != lex.TokenDivide:
+			return value.IntType
+		case at == value.DecimalType || bt == value.DecimalType:
+			return value.DecimalType
+		}
+		return value.NumberType
+	default:
+		return expr.ValueTypeFromNode(n)
+	}
+	return value.BoolType
+}
+
+// dateMathType the type of time, interval arithmetic:  time +- interval
+// is a time, time - time and interval +- interval are intervals.
+func dateMathType(op lex.TokenType, at, bt value.ValueType) (value.ValueType, bool) {
+	switch {
+	case at == value.TimeType && bt == value.DurationType && (op == lex.TokenPlus || op == lex.TokenMinus):
+		return value.TimeType, true
+	case at == value.DurationType && bt == value.TimeType && op == lex.TokenPlus:
+		return value.TimeType, true
+	case at == value.TimeType && bt == value.TimeType && op == lex.TokenMinus:
+		return value.DurationType, true
+	case at == value.DurationType && bt == value.DurationType && (op == lex.TokenPlus || op == lex.TokenMinus):
+		return value.DurationType, true
+	case at == value.DurationType && classOf(bt) == classNumber && op == lex.TokenMultiply,
+		classOf(at) == classNumber && bt == value.DurationType && op == lex.TokenMultiply:
+		return value.DurationType, true
+	}
+	return value.UnknownType, false
+}
+
+// compare check the two operands of a comparison are of compatible types.
+func (m *typeChecker) compare(tok lex.Token, a, b expr.Node, at, bt value.ValueType) {
+	ac, bc := classOf(at), classOf(bt)
+	switch {
+	case ac == classAny || bc == classAny || ac == bc:
+		return
+	case ac == classTime || bc == classTime:
+		// times compare to date strings, unix timestamps
+		if ac != classBool && bc != classBool {
+			return
+		}
+	case ac == classNumber && bc == classString, ac == classString && bc == classNumber:
+		// the vm coerces strings to numbers to compare
+		return
+	}
+	m.errorf(tok, a, "cannot compare %s %s to %s %s", ac, a, bc, b)
+}
+
+// want check the node is of the wanted type class, numeric string literals
+// are numbers.
+func (m *typeChecker) want(tok lex.Token, node expr.Node, vt value.ValueType, want typeClass) {
+	c := classOf(vt)
+	if want == classAny || c == classAny || c == want {
+		return
+	}
+	switch want {
+	case classNumber:
+		if c == classString && isNumberString(node) {
+			return
+		}
+	case classString:
+		// numbers, times, bools are written as strings
+		return
+	case classTime:
+		if c == classString || c == classNumber {
+			return
+		}
+	}
+	m.errorf(tok, node, "expected %s but %s is %s", want, node, c)
+}
+
+// isNumberString is the node a string literal which parses as a number.
+func isNumberString(node expr.Node) bool {
+	sn, isString := node.(*expr.StringNode)
+	if !isString {
+		return false
+	}
+	_, err := expr.NewNumberStr(strings.TrimSpace(sn.Text))
+	return err == nil
+}
+
+func (m *typeChecker) checkFunc(tok lex.Token, n *expr.FuncNode) value.ValueType {
+	var argTypes []value.ValueType
+	if n.F.CustomFunc != nil {
+		if fat, ok := n.F.CustomFunc.(expr.FuncArgTypes); ok {
+			argTypes = fat.ArgTypes()
+		}
+	}
+	types := make([]value.ValueType, len(n.Args))
+	for i := range n.Args {
+		vt := m.check(tok, n.Args[i])
+		types[i] = vt
+		if len(argTypes) == 0 {
+			continue
+		}
+		want := argTypes[len(argTypes)-1]
+		if i < len(argTypes) {
+			want = argTypes[i]
+		}
+		if c := classOf(vt); c != classAny && classOf(want) != classAny && c != classOf(want) {
+			if classOf(want) == classNumber && c == classString && isNumberString(n.Args[i]) {
+				types[i] = value.NumberType
+				continue
+			}
+			m.errorf(tok, n.Args[i], "%s() expected %s argument but %s is %s", n.Name, classOf(want), n.Args[i], c)
+		}
+	}
+	if n.F.CustomFunc == nil {
+		return value.UnknownType
+	}
+	if frt, ok := n.F.CustomFunc.(expr.FuncResultType); ok {
+		return frt.ResultType(types)
+	}
+	return n.F.Type()
+}
+
+func (m *typeChecker) checkCase(tok lex.Token, n *expr.CaseNode) value.ValueType {
+	var at value.ValueType
+	if n.Arg != nil {
+		at = m.check(tok, n.Arg)
+	}
+	for i := range n.Whens {
+		if n.Arg != nil {
+			m.compare(tok, n.Arg, n.Whens[i], at, m.check(tok, n.Whens[i]))
+		} else {
+			m.checkBool(tok, n.Whens[i])
+		}
+	}
+	// the result type if all Then, Else agree
+	var vt value.ValueType
+	result := func(node expr.Node) {
+		rt := m.check(tok, node)
+		switch {
+		case rt == value.NilType || rt == vt:
+		case vt == value.NilType:
+			vt = rt
+		case classOf(rt) == classNumber && classOf(vt) == classNumber:
+			vt = value.NumberType
+		default:
+			vt = value.UnknownType
+		}
+	}
+	for i := range n.Thens {
+		result(n.Thens[i])
+	}
+	if n.Else != nil {
+		result(n.Else)
+	}
+	if vt == value.NilType {
+		return value.UnknownType
+	}
+	return vt
+}
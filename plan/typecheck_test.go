@@ -0,0 +1,145 @@
+package plan_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	td "github.com/araddon/qlbridge/datasource/mockcsvtestdata"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/plan"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/schema"
+	"github.com/araddon/qlbridge/value"
+)
+
+func TestTypeCheck(t *testing.T) {
+	tbl := schema.NewTable("users")
+	tbl.AddFieldType("email", value.StringType)
+	tbl.AddFieldType("ct", value.IntType)
+	tbl.AddFieldType("price", value.NumberType)
+	tbl.AddFieldType("created", value.TimeType)
+	tbl.AddFieldType("active", value.BoolType)
+
+	tests := []struct {
+		expr string
+		vt   value.ValueType
+		err  string
+	}{
+		{expr: `email = "a" AND ct > 5`, vt: value.BoolType},
+		{expr: `ct * 2`, vt: value.IntType},
+		{expr: `ct * price`, vt: value.NumberType},
+		{expr: `users.ct + 1`, vt: value.IntType},
+		{expr: `ct > "5"`, vt: value.BoolType},
+		{expr: `ct IN ("1", 2)`, vt: value.BoolType},
+		{expr: `sqrt("4")`, vt: value.NumberType},
+		{expr: `email = 94107`, vt: value.BoolType},
+		{expr: `ct = "abc"`, vt: value.BoolType},
+		{expr: `abs(ct)`, vt: value.IntType},
+		{expr: `round(price, 2)`, vt: value.NumberType},
+		{expr: `mod(ct, 3)`, vt: value.IntType},
+		{expr: `floor(price) + 1`, vt: value.NumberType},
+		{expr: `ct & 4`, vt: value.IntType},
+		{expr: `created > "2016-01-01"`, vt: value.BoolType},
+		{expr: `missing > 5 AND missing2 = "x"`, vt: value.BoolType},
+		{expr: `tolower(email) LIKE "a%"`, vt: value.BoolType},
+		{expr: `CASE WHEN ct > 5 THEN 1 ELSE 2.5 END`, vt: value.NumberType},
+		{expr: `created + INTERVAL 3 DAY`, vt: value.TimeType},
+		{expr: `now() - created`, vt: value.DurationType},
+		{expr: `created > now() - INTERVAL "1 month"`, vt: value.BoolType},
+		{expr: `active > 5`, err: `line 1 column 8: cannot compare bool active to number 5`},
+		{expr: `created = true`, err: `cannot compare time created to bool true`},
+		{expr: `email + 1`, err: `expected number but email is string`},
+		{expr: `ct AND active`, err: `expected bool but ct is number`},
+		{expr: `NOT email`, err: `expected bool but email is string`},
+		{expr: `sum(email)`, err: `sum() expected number argument but email is string`},
+		{expr: `CASE WHEN email THEN 1 END`, err: `expected bool but email is string`},
+		{expr: `ct LIKE "5%"`, err: `LIKE requires string but ct is number`},
+		{expr: `email << 2`, err: `expected number but email is string`},
+		{expr: `ln(email)`, err: `ln() expected number argument but email is string`},
+	}
+	for _, tt := range tests {
+		node, err := expr.ParseExpression(tt.expr)
+		assert.Equal(t, nil, err, tt.expr)
+		before := node.String()
+		vt, err := plan.TypeCheck(tbl, node)
+		// checking does not modify the expression
+		assert.Equal(t, before, node.String(), tt.expr)
+		if tt.err != "" {
+			assert.NotEqual(t, nil, err, tt.expr)
+			if err != nil {
+				assert.Contains(t, err.Error(), tt.err, tt.expr)
+			}
+			continue
+		}
+		assert.Equal(t, nil, err, tt.expr)
+		assert.Equal(t, tt.vt.String(), vt.String(), tt.expr)
+	}
+
+	stmt, err := rel.ParseSqlSelect("SELECT email\nFROM users\nWHERE active > 5 AND ct > \"3\"")
+	assert.Equal(t, nil, err)
+	err = plan.TypeCheckSelect(tbl, stmt)
+	assert.NotEqual(t, nil, err)
+	terrs, ok := err.(plan.TypeErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(terrs))
+	assert.Equal(t, 3, terrs[0].Token.Line)
+	assert.Equal(t, `active > 5 AND ct > "3"`, stmt.Where.Expr.String())
+
+	// top level function, group by, having, order by carry their position
+	for sql, line := range map[string]int{
+		"SELECT email\nFROM users\nWHERE sqrt(email)":                    3,
+		"SELECT email FROM users\nGROUP BY email + 1":                    2,
+		"SELECT email FROM users GROUP BY email\nHAVING count(*) = true": 2,
+		"SELECT email FROM users\nORDER BY sqrt(email)":                  2,
+	} {
+		stmt, err := rel.ParseSqlSelect(sql)
+		assert.Equal(t, nil, err, sql)
+		err = plan.TypeCheckSelect(tbl, stmt)
+		assert.NotEqual(t, nil, err, sql)
+		terrs, ok := err.(plan.TypeErrors)
+		assert.True(t, ok, sql)
+		if ok && len(terrs) > 0 {
+			assert.Equal(t, line, terrs[0].Token.Line, sql)
+			assert.NotEqual(t, 0, terrs[0].Token.Column, sql)
+		}
+	}
+
+	// joins resolve alias qualified identities to the table of the source
+	orders := schema.NewTable("orders")
+	orders.AddFieldType("price", value.NumberType)
+	orders.AddFieldType("email", value.StringType)
+	tbls := map[string]*schema.Table{"u": tbl, "users": tbl, "o": orders, "orders": orders}
+	for sql, ok := range map[string]bool{
+		`SELECT u.email FROM users AS u INNER JOIN orders AS o ON u.email = o.email WHERE o.price > 5`:      true,
+		`SELECT u.email FROM users AS u INNER JOIN orders AS o ON u.email = o.email WHERE email > 5`:        true,
+		`SELECT u.email FROM users AS u INNER JOIN orders AS o ON u.active = o.price`:                       false,
+		`SELECT u.email FROM users AS u INNER JOIN orders AS o ON u.email = o.email WHERE o.email`:          false,
+		`SELECT u.email FROM users AS u INNER JOIN orders AS o ON u.email = o.email GROUP BY sqrt(o.email)`: false,
+	} {
+		stmt, err := rel.ParseSqlSelect(sql)
+		assert.Equal(t, nil, err, sql)
+		err = plan.TypeCheckJoin(tbls, stmt)
+		assert.Equal(t, ok, err == nil, "%s: %v", sql, err)
+	}
+}
+
+func TestTypeCheckPlan(t *testing.T) {
+	ctx := td.TestContext(`SELECT user_id FROM users WHERE referral_count = true`)
+	stmt, err := rel.ParseSql(ctx.Raw)
+	assert.Equal(t, nil, err)
+	ctx.Stmt = stmt
+	_, err = plan.WalkStmt(ctx, stmt, plan.NewPlanner(ctx))
+	assert.NotEqual(t, nil, err)
+	_, isTypeErr := err.(plan.TypeErrors)
+	assert.True(t, isTypeErr, "%v", err)
+
+	ctx = td.TestContext(`SELECT u.user_id FROM users AS u INNER JOIN orders AS o ON u.user_id = o.user_id WHERE o.price AND o.item_count > 1`)
+	stmt, err = rel.ParseSql(ctx.Raw)
+	assert.Equal(t, nil, err)
+	ctx.Stmt = stmt
+	_, err = plan.WalkStmt(ctx, stmt, plan.NewPlanner(ctx))
+	assert.NotEqual(t, nil, err)
+	_, isTypeErr = err.(plan.TypeErrors)
+	assert.True(t, isTypeErr, "%v", err)
+}
@@ -101,6 +101,18 @@ func (m *FilterStatement) FingerPrintID() int64 {
 	return int64(h.Sum64())
 }
 
+// Normalize a copy of this statement with its filter rewritten to the
+// canonical form of expr.Normalize, equivalent filters have the same
+// String and FingerPrintID.
+func (m *FilterStatement) Normalize() *FilterStatement {
+	fs := *m
+	fs.Filter = expr.Normalize(m.Filter)
+	fs.Where = expr.Normalize(m.Where)
+	fs.checkedIncludes = false
+	fs.includes = nil
+	return &fs
+}
+
 // Includes Recurse this statement and find all includes
 func (m *FilterStatement) Includes() []string {
 	if !m.checkedIncludes {
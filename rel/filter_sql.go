@@ -0,0 +1,128 @@
+package rel
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+)
+
+// FilterToSql convert a FilterQL statement to a sql select * of its FROM
+// table.  Includes are inlined with inc, which may be nil if the filter
+// has none, and the BooleanNode lists are rewritten as sql AND/OR.  FILTER *
+// and FILTER match_all have no WHERE.  Write the select in the dialect of
+// a database with SqlSelect.WriteDialect.
+func FilterToSql(fs *FilterStatement, inc expr.Includer) (*SqlSelect, error) {
+	return filterToSql(fs, nil, inc)
+}
+
+// FilterSelectToSql convert a FilterQL select to a sql select of its
+// columns, see FilterToSql.
+func FilterSelectToSql(fs *FilterSelect, inc expr.Includer) (*SqlSelect, error) {
+	if fs == nil {
+		return nil, fmt.Errorf("nil filter select")
+	}
+	return filterToSql(fs.FilterStatement, fs.Columns, inc)
+}
+
+func filterToSql(fs *FilterStatement, cols Columns, inc expr.Includer) (*SqlSelect, error) {
+	if fs == nil {
+		return nil, fmt.Errorf("nil filter statement")
+	}
+	if fs.From == "" {
+		return nil, fmt.Errorf("filter requires FROM <table> to convert to sql: %s", fs)
+	}
+	node := fs.Filter
+	if node == nil {
+		node = fs.Where
+	}
+	if isMatchAll(node) {
+		node = nil
+	}
+	if node != nil && len(expr.FindIncludes(node)) > 0 {
+		if inc == nil {
+			return nil, fmt.Errorf("filter has INCLUDE but no includer: %s", fs)
+		}
+		var err error
+		if node, err = expr.InlineIncludes(inc, node); err != nil {
+			return nil, err
+		}
+	}
+
+	w := expr.NewDefaultWriter()
+	io.WriteString(w, "SELECT ")
+	if len(cols) > 0 {
+		cols.WriteDialect(w)
+	} else {
+		io.WriteString(w, "*")
+	}
+	io.WriteString(w, " FROM ")
+	w.WriteIdentity(fs.From)
+	if node != nil {
+		io.WriteString(w, " WHERE ")
+		expr.BooleanToBinary(node).WriteDialect(w)
+	}
+	if len(fs.OrderBy) > 0 {
+		io.WriteString(w, " ORDER BY ")
+		fs.OrderBy.WriteDialect(w)
+	}
+	if fs.Limit > 0 {
+		io.WriteString(w, fmt.Sprintf(" LIMIT %d", fs.Limit))
+	}
+	if len(fs.With) > 0 {
+		io.WriteString(w, " WITH ")
+		HelperString(w, fs.With)
+	}
+	if fs.Alias != "" {
+		io.WriteString(w, " ALIAS ")
+		w.WriteIdentity(fs.Alias)
+	}
+	sel, err := ParseSqlSelect(w.String())
+	if err != nil {
+		return nil, fmt.Errorf("could not convert filter to sql %q: %v", w.String(), err)
+	}
+	return sel, nil
+}
+
+// isMatchAll FILTER * and FILTER match_all
+func isMatchAll(n expr.Node) bool {
+	in, ok := n.(*expr.IdentityNode)
+	return ok && (in.Text == "*" || strings.ToLower(in.Text) == "match_all")
+}
+
+// SqlToFilter convert a sql select of one table to a FilterQL select, the
+// WHERE is rewritten as BooleanNode lists (FILTER * if there is none).
+// Joins, sub-queries, DISTINCT, GROUP BY, HAVING, ORDER BY and OFFSET have
+// no FilterQL form and are an error.  Its FilterStatement is the FILTER
+// form for SELECT * statements.
+func SqlToFilter(sel *SqlSelect) (*FilterSelect, error) {
+	if sel == nil {
+		return nil, fmt.Errorf("nil select")
+	}
+	switch {
+	case len(sel.From) != 1 || sel.From[0].Source != nil || sel.From[0].Name == "":
+		return nil, fmt.Errorf("filter must select from one table: %s", sel)
+	case sel.Distinct, len(sel.GroupBy) > 0, sel.Having != nil, len(sel.OrderBy) > 0, sel.Offset > 0:
+		return nil, fmt.Errorf("select has clauses with no FilterQL form: %s", sel)
+	case sel.Where != nil && sel.Where.Source != nil:
+		return nil, fmt.Errorf("filter can't have a sub-query: %s", sel)
+	}
+
+	fs := &FilterSelect{FilterStatement: &FilterStatement{
+		From:  sel.From[0].Name,
+		Limit: sel.Limit,
+		Alias: sel.Alias,
+		With:  sel.With,
+	}}
+	if sel.Where != nil && sel.Where.Expr != nil {
+		fs.Filter = expr.BinaryToBoolean(sel.Where.Expr)
+	} else {
+		fs.Filter = expr.NewIdentityNodeVal("*")
+	}
+	for _, col := range sel.Columns {
+		fs.AddColumn(*col)
+	}
+	fs.Raw = fs.String()
+	return fs, nil
+}
@@ -0,0 +1,101 @@
+package rel_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+)
+
+type filterIncluder map[string]string
+
+func (m filterIncluder) Include(name string) (expr.Node, error) {
+	fs, err := rel.ParseFilterQL(m[name])
+	if err != nil {
+		return nil, err
+	}
+	return fs.Filter, nil
+}
+
+func TestFilterToSql(t *testing.T) {
+	inc := filterIncluder{
+		"adults": `FILTER age >= 18 ALIAS adults`,
+		"west":   `FILTER OR ( state = "CA", state = "NV" ) ALIAS west`,
+	}
+	tests := []struct {
+		filter string
+		sql    string
+		sqlite string
+	}{
+		{`FILTER AND ( x = 1, OR ( y = 2, z > 3 ), NOT AND ( a = 1, b = "b" ) ) FROM users LIMIT 10`,
+			`SELECT * FROM users WHERE x = 1 AND (y = 2 OR z > 3) AND NOT (a = 1 AND b = "b") LIMIT 10`,
+			`SELECT * FROM users WHERE x = 1 AND (y = 2 OR z > 3) AND NOT (a = 1 AND b = 'b') LIMIT 10`},
+		{`FILTER AND ( INCLUDE adults, NOT INCLUDE west, name LIKE "b%" ) FROM users ALIAS seg1`,
+			`SELECT * FROM users WHERE age >= 18 AND NOT (state = "CA" OR state = "NV") AND name LIKE "b%"`,
+			`SELECT * FROM users WHERE age >= 18 AND NOT (state = 'CA' OR state = 'NV') AND name LIKE 'b%'`},
+		{`FILTER * FROM users`, `SELECT * FROM users`, `SELECT * FROM users`},
+		{`FILTER match_all FROM users`, `SELECT * FROM users`, `SELECT * FROM users`},
+		{`SELECT name, age FROM users FILTER AND ( age > 5, name == "x" )`,
+			`SELECT name, age FROM users WHERE age > 5 AND name = "x"`,
+			`SELECT name, age FROM users WHERE age > 5 AND name = 'x'`},
+	}
+	for _, tt := range tests {
+		fs, err := rel.ParseFilterSelect(tt.filter)
+		if err != nil {
+			stmt, err := rel.ParseFilterQL(tt.filter)
+			assert.Equal(t, nil, err, tt.filter)
+			fs = &rel.FilterSelect{FilterStatement: stmt}
+		}
+		sel, err := rel.FilterSelectToSql(fs, inc)
+		assert.Equal(t, nil, err, tt.filter)
+		assert.Equal(t, tt.sql, sel.String(), tt.filter)
+		w := expr.NewDialectWriter('\'', '"')
+		sel.WriteDialect(w)
+		assert.Equal(t, tt.sqlite, w.String(), tt.filter)
+
+		// and back, to the same filter includes inlined
+		back, err := rel.SqlToFilter(sel)
+		assert.Equal(t, nil, err, tt.filter)
+		assert.Equal(t, fs.Alias, back.Alias)
+		again, err := rel.FilterSelectToSql(back, nil)
+		assert.Equal(t, nil, err, tt.filter)
+		assert.Equal(t, tt.sql, again.String(), tt.filter)
+	}
+
+	_, err := rel.FilterToSql(rel.MustParseFilter(`FILTER x = 1`), nil)
+	assert.NotEqual(t, nil, err, "requires FROM")
+	_, err = rel.FilterToSql(rel.MustParseFilter(`FILTER INCLUDE adults FROM users`), nil)
+	assert.NotEqual(t, nil, err, "requires includer")
+}
+
+func TestSqlToFilter(t *testing.T) {
+	sel, err := rel.ParseSqlSelect(`SELECT * FROM users WHERE a = 1 AND (b = 2 AND (c = 3 OR d = 4)) LIMIT 5 ALIAS seg`)
+	assert.Equal(t, nil, err)
+	fs, err := rel.SqlToFilter(sel)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `FILTER AND ( a = 1, b = 2, OR ( c = 3, d = 4 ) ) FROM users LIMIT 5 ALIAS seg`, fs.FilterStatement.String())
+	// the filter statement parses to the same
+	stmt, err := rel.ParseFilterQL(fs.FilterStatement.String())
+	assert.Equal(t, nil, err)
+	assert.True(t, stmt.Equal(fs.FilterStatement))
+
+	sel, err = rel.ParseSqlSelect(`SELECT id, name FROM users`)
+	assert.Equal(t, nil, err)
+	fs, err = rel.SqlToFilter(sel)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "SELECT id, name FROM users FILTER *", fs.String())
+
+	for _, sql := range []string{
+		`SELECT a FROM users WHERE x = 1 ORDER BY a`,
+		`SELECT count(*) FROM users GROUP BY a`,
+		`SELECT u.a FROM users AS u INNER JOIN orders AS o ON u.id = o.uid`,
+		`SELECT DISTINCT a FROM users`,
+	} {
+		sel, err := rel.ParseSqlSelect(sql)
+		assert.Equal(t, nil, err, sql)
+		_, err = rel.SqlToFilter(sel)
+		assert.NotEqual(t, nil, err, sql)
+	}
+}
@@ -92,3 +92,35 @@ func TestFilterEquality(t *testing.T) {
 		assert.True(t, !fts1.Equal(fts2))
 	}
 }
+
+func TestFilterNormalize(t *testing.T) {
+	equivalent := [][]string{
+		{
+			`FILTER AND ( x > 5, y = "a" ) FROM users`,
+			`FILTER AND ( y == "a", x > 3, x > 5, AND ( y = "a" ) ) FROM users`,
+			`FILTER AND ( NOT NOT y = "a", x > 5 ) FROM users`,
+		},
+		{
+			`FILTER x IN (1, 2, 3)`,
+			`FILTER OR ( x = 3, x IN (2, 1, 2) )`,
+		},
+		{
+			`FILTER AND ( a = 1, OR ( b = 2, c = 3 ) )`,
+			`FILTER OR ( AND ( a = 1, b = 2 ), AND ( c = 3, a = 1 ) )`,
+		},
+	}
+	for _, filters := range equivalent {
+		first := rel.MustParseFilter(filters[0]).Normalize()
+		for _, f := range filters[1:] {
+			fs := rel.MustParseFilter(f).Normalize()
+			assert.Equal(t, first.String(), fs.String(), f)
+			assert.Equal(t, first.FingerPrintID(), fs.FingerPrintID(), f)
+		}
+	}
+	a := rel.MustParseFilter(`FILTER AND ( x > 5, y = "a" )`).Normalize()
+	b := rel.MustParseFilter(`FILTER OR ( x > 5, y = "a" )`).Normalize()
+	assert.NotEqual(t, a.FingerPrintID(), b.FingerPrintID())
+	// with x missing y = "a" matches NOT OR ( y != "a", x <= 5 ) but not a
+	c := rel.MustParseFilter(`FILTER NOT OR ( y != "a", x <= 5 )`).Normalize()
+	assert.NotEqual(t, a.FingerPrintID(), c.FingerPrintID())
+}
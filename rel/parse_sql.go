@@ -29,6 +29,12 @@ type ParseError struct {
 func ParseSql(sqlQuery string) (SqlStatement, error) {
 	return parseSqlResolver(sqlQuery, nil)
 }
+
+// ParseSqlResolver parse a sql statement using function resolver for
+// functions not in the global registry.
+func ParseSqlResolver(sqlQuery string, fr expr.FuncResolver) (SqlStatement, error) {
+	return parseSqlResolver(sqlQuery, fr)
+}
 func parseSqlResolver(sqlQuery string, fr expr.FuncResolver) (SqlStatement, error) {
 	l := lex.NewSqlLexer(sqlQuery)
 	m := Sqlbridge{l: l, SqlTokenPager: NewSqlTokenPager(l), funcs: fr}
@@ -743,8 +749,14 @@ func (m *Sqlbridge) parseCreate() (*SqlCreate, error) {
 		}
 		req.Select = sel
 		return req, nil
+	case lex.TokenFunction:
+		req.Tok = m.Next()
+		if err := m.parseCreateFunction(req); err != nil {
+			return nil, err
+		}
+		return req, nil
 	default:
-		return nil, m.ErrMsg("Expected view, table, source, schema, database, continuousview for CREATE got")
+		return nil, m.ErrMsg("Expected view, table, source, schema, database, continuousview, function for CREATE got")
 	}
 
 	// [IF NOT EXISTS]
@@ -811,6 +823,60 @@ func (m *Sqlbridge) parseCreate() (*SqlCreate, error) {
 	return req, nil
 }
 
+// parseCreateFunction the signature and body of a sql function, the body
+// is parsed with this parsers functions so may call other functions.
+//
+//    CREATE [OR REPLACE] FUNCTION <identity> ([<arg> <type>, ...]) RETURNS <type> AS '<expression>'
+func (m *Sqlbridge) parseCreateFunction(req *SqlCreate) error {
+	const usage = "Expected CREATE [OR REPLACE] FUNCTION <identity> ([<arg> <type>, ...]) RETURNS <type> AS '<expression>'"
+	if m.Cur().T != lex.TokenIdentity {
+		return m.ErrMsg(usage)
+	}
+	req.Identity = m.Next().V
+	if m.Next().T != lex.TokenLeftParenthesis {
+		return m.ErrMsg(usage)
+	}
+	for m.Cur().T != lex.TokenRightParenthesis {
+		name, typ := m.Next(), m.Next()
+		if name.T != lex.TokenIdentity || typ.T != lex.TokenIdentity {
+			return m.ErrMsg(usage)
+		}
+		if value.ValueFromString(strings.ToLower(typ.V)) == value.UnknownType {
+			return m.ErrMsg(fmt.Sprintf("Unknown type %q for function arg %s", typ.V, name.V))
+		}
+		req.FuncArgs = append(req.FuncArgs, &DdlColumn{Kw: lex.TokenIdentity, Name: name.V, DataType: strings.ToLower(typ.V)})
+		if m.Cur().T == lex.TokenComma {
+			m.Next()
+		}
+	}
+	m.Next() // Consume )
+	if m.Next().T != lex.TokenReturns || m.Cur().T != lex.TokenIdentity {
+		return m.ErrMsg(usage)
+	}
+	req.Returns = strings.ToLower(m.Next().V)
+	if value.ValueFromString(req.Returns) == value.UnknownType {
+		return m.ErrMsg(fmt.Sprintf("Unknown return type %q for function", req.Returns))
+	}
+	if m.Next().T != lex.TokenAs {
+		return m.ErrMsg(usage)
+	}
+	body := m.Next()
+	switch body.T {
+	case lex.TokenValue:
+	case lex.TokenValueEscaped:
+		body.V, _ = expr.StringUnEscape(rune(body.Quote), body.V)
+	default:
+		return m.ErrMsg(usage)
+	}
+	l := lex.NewLexer(body.V, lex.LogicalExpressionDialect)
+	node, err := expr.ParseExprWithFuncs(expr.NewLexTokenPager(l), m.funcs)
+	if err != nil {
+		return err
+	}
+	req.Body = node
+	return nil
+}
+
 // First keyword was DROP
 func (m *Sqlbridge) parseDrop() (*SqlDrop, error) {
 
@@ -953,6 +1019,18 @@ func parseColumns(m expr.TokenPager, fr expr.FuncResolver, stmt ColumnsStatement
 				return err
 			}
 			col.Expr = exprNode
+		case lex.TokenCase:
+			// CASE WHEN ... END, un-aliased it is named by its expression
+			col = NewColumnValue(m.Cur())
+			exprNode, err := expr.ParseExprWithFuncs(m, fr)
+			if err != nil {
+				return err
+			}
+			col.Expr = exprNode
+			col.SourceField = expr.FindFirstIdentity(col.Expr)
+			if m.Cur().T != lex.TokenAs {
+				col.As = exprNode.String()
+			}
 		}
 		//u.Debugf("after colstart?:   %v  ", m.Cur())
 		comment += readComment(m)
@@ -1096,6 +1174,7 @@ func (m *Sqlbridge) parseValueList() ([][]*ValueColumn, error) {
 			row = make([]*ValueColumn, 0)
 		case lex.TokenRightParenthesis:
 			values = append(values, row)
+			row = nil
 		case lex.TokenFrom, lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF:
 			if len(row) > 0 {
 				values = append(values, row)
@@ -1254,9 +1333,13 @@ func (m *Sqlbridge) parseSourceTable(req *SqlSelect) error {
 	src := SqlSource{}
 	req.From = append(req.From, &src)
 	src.Schema, src.Name, _ = expr.LeftRight(m.Next().V)
-	if m.Cur().T == lex.TokenAs {
+	switch m.Cur().T {
+	case lex.TokenAs:
 		m.Next() // Skip over "AS", we don't need it
 		src.Alias = m.Next().V
+	case lex.TokenIdentity:
+		// SELECT ... FROM users u
+		src.Alias = m.Next().V
 	}
 	return nil
 }
@@ -1466,6 +1549,14 @@ func (m *Sqlbridge) parseGroupBy(req *SqlSelect) (err error) {
 				return err
 			}
 			col.Expr = exprNode
+		case lex.TokenCase:
+			col = NewColumnValue(m.Cur())
+			exprNode, err := expr.ParseExprWithFuncs(m, m.funcs)
+			if err != nil {
+				return err
+			}
+			col.Expr = exprNode
+			col.As = exprNode.String()
 		}
 		//u.Debugf("GroupBy after colstart?:   %v  ", m.Cur())
 
@@ -1577,6 +1668,14 @@ func (m *Sqlbridge) parseOrderBy(req *SqlSelect) (err error) {
 				return err
 			}
 			col.Expr = exprNode
+		case lex.TokenCase:
+			col = NewColumnValue(m.Cur())
+			exprNode, err := expr.ParseExprWithFuncs(m, m.funcs)
+			if err != nil {
+				return err
+			}
+			col.Expr = exprNode
+			col.As = exprNode.String()
 		}
 		//u.Debugf("OrderBy after colstart?:   %v  ", m.Cur())
 
@@ -1772,6 +1871,10 @@ func (m *Sqlbridge) parseDdlConstraint(col *DdlColumn) error {
 		lex.TokenTypeText, lex.TokenTypeJson:
 
 		col.DataType = m.Next().V
+	case lex.TokenTypeDecimal:
+		if err := m.parseDdlDecimal(col); err != nil {
+			return err
+		}
 	case lex.TokenTypeFloat, lex.TokenTypeInteger, lex.TokenTypeString,
 		lex.TokenTypeVarChar, lex.TokenTypeChar, lex.TokenTypeBigInt:
 		col.DataType = m.Next().V
@@ -1891,6 +1994,10 @@ func (m *Sqlbridge) parseDdlColumn(col *DdlColumn) error {
 		lex.TokenTypeText, lex.TokenTypeJson:
 
 		col.DataType = m.Next().V
+	case lex.TokenTypeDecimal:
+		if err := m.parseDdlDecimal(col); err != nil {
+			return err
+		}
 	case lex.TokenTypeFloat, lex.TokenTypeInteger, lex.TokenTypeString,
 		lex.TokenTypeVarChar, lex.TokenTypeChar, lex.TokenTypeBigInt:
 		col.DataType = m.Next().V
@@ -1974,6 +2081,42 @@ func convertIdentityToValue(n expr.Node) {
 	}
 }
 
+// parseDdlDecimal decimal data type with optional precision, scale
+//
+//	DECIMAL | DECIMAL(precision) | DECIMAL(precision, scale)
+//
+// DataTypeSize is the precision, DataTypeArgs the precision, scale.
+func (m *Sqlbridge) parseDdlDecimal(col *DdlColumn) error {
+	col.DataType = m.Next().V
+	if m.Cur().T != lex.TokenLeftParenthesis {
+		return nil
+	}
+	m.Next()
+	for {
+		if m.Cur().T != lex.TokenInteger {
+			return m.ErrMsg("expected 'decimal(precision, scale)'")
+		}
+		nn, err := expr.NewNumberStr(m.Next().V)
+		if err != nil {
+			return m.ErrMsg("Expected integer")
+		}
+		col.DataTypeArgs = append(col.DataTypeArgs, nn)
+		switch m.Next().T {
+		case lex.TokenComma:
+			if len(col.DataTypeArgs) == 2 {
+				m.Backup()
+				return m.ErrMsg("expected 'decimal(precision, scale)'")
+			}
+			continue
+		case lex.TokenRightParenthesis:
+			col.DataTypeSize = int(col.DataTypeArgs[0].(*expr.NumberNode).Int64)
+			return nil
+		}
+		m.Backup()
+		return m.ErrMsg("expected 'decimal(precision, scale)'")
+	}
+}
+
 func (m *Sqlbridge) parseLimit(req *SqlSelect) error {
 	if m.Cur().T != lex.TokenLimit {
 		return nil
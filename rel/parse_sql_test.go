@@ -520,6 +520,17 @@ func TestSqlParseFromTypes(t *testing.T) {
 	assert.True(t, len(sel.From) == 3, "has 3 from: %v", sel.From)
 	//assert.True(t, len(sel.OrderBy) == 1, "want 1 orderby but has %v", len(sel.OrderBy))
 	u.Info(sel.String())
+
+	// alias without AS
+	sql = `SELECT c.relname FROM pg_catalog.pg_class c WHERE c.relkind = 'r'`
+	req, err = rel.ParseSql(sql)
+	assert.True(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel = req.(*rel.SqlSelect)
+	assert.Equal(t, 1, len(sel.From))
+	assert.Equal(t, "pg_catalog", sel.From[0].Schema)
+	assert.Equal(t, "pg_class", sel.From[0].Name)
+	assert.Equal(t, "c", sel.From[0].Alias)
+	assert.True(t, sel.Where != nil, "has where")
 }
 
 func TestSqlShowAst(t *testing.T) {
@@ -659,6 +670,71 @@ func TestSqlCreate(t *testing.T) {
 	assert.Equal(t, 150, c2.DataTypeSize, "%+v", c2)
 }
 
+func TestSqlCreateDecimal(t *testing.T) {
+	t.Parallel()
+	sql := `CREATE TABLE orders (
+		  ID int(11) NOT NULL,
+		  price DECIMAL(10,2) NOT NULL,
+		  tax numeric(5),
+		  total decimal
+		) ENGINE=InnoDB`
+	req, err := rel.ParseSql(sql)
+	assert.Equal(t, nil, err)
+	cs, ok := req.(*rel.SqlCreate)
+	assert.True(t, ok, "wanted SqlCreate got %T", req)
+	assert.Equal(t, 4, len(cs.Cols))
+
+	price := cs.Cols[1]
+	assert.Equal(t, "DECIMAL", price.DataType)
+	assert.Equal(t, 10, price.DataTypeSize)
+	assert.Equal(t, 2, len(price.DataTypeArgs))
+	assert.Equal(t, "2", price.DataTypeArgs[1].String())
+	assert.Equal(t, false, price.Null)
+
+	tax := cs.Cols[2]
+	assert.Equal(t, 5, tax.DataTypeSize)
+	assert.Equal(t, 1, len(tax.DataTypeArgs))
+	assert.Equal(t, 0, len(cs.Cols[3].DataTypeArgs))
+
+	_, err = rel.ParseSql(`CREATE TABLE orders (price DECIMAL(10,2,1))`)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSqlCreateFunction(t *testing.T) {
+	t.Parallel()
+	sql := `CREATE OR REPLACE FUNCTION discount(price number, pct INT)
+		RETURNS number AS 'price * (100 - pct) / 100'`
+	req, err := rel.ParseSql(sql)
+	assert.Equal(t, nil, err)
+	cs, ok := req.(*rel.SqlCreate)
+	assert.True(t, ok, "wanted SqlCreate got %T", req)
+	assert.Equal(t, lex.TokenFunction, cs.Tok.T)
+	assert.Equal(t, "discount", cs.Identity)
+	assert.Equal(t, true, cs.OrReplace)
+	assert.Equal(t, 2, len(cs.FuncArgs))
+	assert.Equal(t, "price", cs.FuncArgs[0].Name)
+	assert.Equal(t, "number", cs.FuncArgs[0].DataType)
+	assert.Equal(t, "int", cs.FuncArgs[1].DataType)
+	assert.Equal(t, "number", cs.Returns)
+	assert.Equal(t, "price * (100 - pct) / 100", cs.Body.String())
+
+	req, err = rel.ParseSql(`CREATE FUNCTION greeting() RETURNS string AS 'concat("hello", " world")'`)
+	assert.Equal(t, nil, err)
+	cs = req.(*rel.SqlCreate)
+	assert.Equal(t, 0, len(cs.FuncArgs))
+	assert.Equal(t, "string", cs.Returns)
+
+	for _, sql := range []string{
+		`CREATE FUNCTION f(a notatype) RETURNS int AS 'a'`,
+		`CREATE FUNCTION f(a int) RETURNS notatype AS 'a'`,
+		`CREATE FUNCTION f(a int) AS 'a'`,
+		`CREATE FUNCTION f(a int) RETURNS int AS 'a +'`,
+	} {
+		_, err = rel.ParseSql(sql)
+		assert.NotEqual(t, nil, err, sql)
+	}
+}
+
 func TestSqlDrop(t *testing.T) {
 	t.Parallel()
 	sql := `DROP TABLE articles;`
@@ -211,13 +211,16 @@ type (
 	SqlCreate struct {
 		Raw         string       // full original raw statement
 		Identity    string       // identity of table, view, etc
-		Tok         lex.Token    // CREATE [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER] etc
+		Tok         lex.Token    // CREATE [TABLE,VIEW,CONTINUOUSVIEW,TRIGGER,FUNCTION] etc
 		OrReplace   bool         // OR REPLACE
 		IfNotExists bool         // IF NOT EXISTS
 		Cols        []*DdlColumn // columns
 		Engine      map[string]interface{}
 		With        u.JsonHelper
 		Select      *SqlSelect
+		FuncArgs    []*DdlColumn // CREATE FUNCTION args, name and data type
+		Returns     string       // CREATE FUNCTION return data type
+		Body        expr.Node    // CREATE FUNCTION body expression
 	}
 	// SqlDrop SQL DROP statement
 	SqlDrop struct {
@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+)
+
+// Capabilities declares the parts of a select statement a Source can
+// execute natively.  Anything not declared is poly-filled by qlbridge.
+type Capabilities struct {
+	// Funcs lower-case names of scalar functions the source can evaluate.
+	Funcs map[string]bool
+	// Aggregates lower-case names of aggregate functions (count, sum, ...).
+	Aggregates map[string]bool
+	// Operators binary, boolean, unary and tri operators (=, LIKE, IN, NOT, BETWEEN...),
+	// also CASE expressions.
+	Operators map[lex.TokenType]bool
+	// GroupBy the source can aggregate (GROUP BY, HAVING).
+	GroupBy bool
+	// OrderBy the source can sort.
+	OrderBy bool
+	// Limit the source can apply LIMIT, OFFSET.
+	Limit bool
+	// Distinct the source can apply SELECT DISTINCT.
+	Distinct bool
+	// Accept optional veto called once per expression (where term, column,
+	// group by, order by) for source specific rules that can't be declared
+	// above (ie like patterns).
+	Accept func(n expr.Node) bool
+}
+
+// NewCapabilities create capabilities with empty function, aggregate,
+// operator sets.
+func NewCapabilities() *Capabilities {
+	return &Capabilities{
+		Funcs:      make(map[string]bool),
+		Aggregates: make(map[string]bool),
+		Operators:  make(map[lex.TokenType]bool),
+	}
+}
+
+// Func can the source evaluate the named scalar function.
+func (m *Capabilities) Func(name string) bool {
+	return m.Funcs[strings.ToLower(name)]
+}
+
+// Aggregate can the source evaluate the named aggregate function.
+func (m *Capabilities) Aggregate(name string) bool {
+	return m.Aggregates[strings.ToLower(name)]
+}
+
+// Operator can the source evaluate the operator.
+func (m *Capabilities) Operator(t lex.TokenType) bool {
+	return m.Operators[t]
+}
+
+// SupportsExpr can the source evaluate every node of this expression.
+func (m *Capabilities) SupportsExpr(node expr.Node) bool {
+	if node == nil {
+		return false
+	}
+	if m.Accept != nil && !m.Accept(node) {
+		return false
+	}
+	return m.supports(node)
+}
+
+func (m *Capabilities) supports(node expr.Node) bool {
+	switch n := node.(type) {
+	case *expr.IdentityNode, *expr.StringNode, *expr.NumberNode, *expr.NullNode:
+		return true
+	case *expr.ArrayNode:
+		return m.supportsArgs(n.Args)
+	case *expr.BinaryNode:
+		return m.Operator(n.Operator.T) && m.supportsArgs(n.Args)
+	case *expr.BooleanNode:
+		if n.Negated() && !m.Operator(lex.TokenNegate) {
+			return false
+		}
+		return m.Operator(n.Operator.T) && m.supportsArgs(n.Args)
+	case *expr.TriNode:
+		if n.Negated() && !m.Operator(lex.TokenNegate) {
+			return false
+		}
+		return m.Operator(n.Operator.T) && m.supportsArgs(n.Args)
+	case *expr.UnaryNode:
+		return m.Operator(n.Operator.T) && m.supports(n.Arg)
+	case *expr.CaseNode:
+		return m.Operator(lex.TokenCase) && m.supportsArgs(n.ChildrenArgs())
+	case *expr.FuncNode:
+		if !m.Func(n.Name) && !m.Aggregate(n.Name) {
+			return false
+		}
+		for _, arg := range n.Args {
+			if in, isIdent := arg.(*expr.IdentityNode); isIdent && in.Text == "*" {
+				continue
+			}
+			if !m.supports(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (m *Capabilities) supportsArgs(args []expr.Node) bool {
+	for _, arg := range args {
+		if !m.supports(arg) {
+			return false
+		}
+	}
+	return true
+}
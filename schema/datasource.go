@@ -6,6 +6,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/araddon/qlbridge/arrow"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/value"
 )
@@ -67,6 +68,14 @@ type (
 		// Underlying data type of column
 		Column(col string) (value.ValueType, bool)
 	}
+	// SourceCapabilities is an optional interface a source may implement to
+	// declare which parts of a select statement (functions, operators, aggregates,
+	// ordering, limit) it can execute natively.  The planner uses these to split
+	// a statement into the part pushed down to the source and the residual
+	// which qlbridge poly-fills.
+	SourceCapabilities interface {
+		Capabilities() *Capabilities
+	}
 )
 
 type (
@@ -117,6 +126,12 @@ type (
 		Conn
 		Iterator
 	}
+	// ConnScanErr is an optional interface for a ConnScanner whose scan can
+	// fail part way, a nil from Next is then either the end of data or an
+	// error.  Err is the error that ended the scan, nil at end of data.
+	ConnScanErr interface {
+		Err() error
+	}
 	// Iterator is simple iterator for paging through a datastore Message(rows)
 	// to be used for scanning.  Building block for Tasks that process part of
 	// a DAG of tasks to process data.
@@ -124,11 +139,28 @@ type (
 		// Next returns the next message.  If none remain, returns nil.
 		Next() Message
 	}
+	// ConnBatchScanner is an optional interface for scanners that can read
+	// rows as columnar record batches, used by the batch executor instead
+	// of a Message per row.  Batches are of the table columns.
+	ConnBatchScanner interface {
+		ConnScanner
+		// NextBatch returns the next batch of at most size rows.  If none
+		// remain, returns nil.
+		NextBatch(size int) *arrow.Record
+	}
 	// ConnSeeker is a conn that is Key-Value store, allows relational
 	// implementation to be faster for Seeking row values instead of scanning
 	ConnSeeker interface {
 		Get(key driver.Value) (Message, error)
 	}
+	// ConnIndexSeeker is an optional interface for connections that can read
+	// only the rows matching an IndexSeek (point lookups, ranges on an indexed
+	// column) instead of a full scan.  Returns false if the seek can't be used
+	// (ie ranges on a hash index) in which case rows are scanned.  The where
+	// clause is still evaluated on the rows returned.
+	ConnIndexSeeker interface {
+		SeekIndex(s *IndexSeek) bool
+	}
 	// ConnMutation creates a Mutator connection similar to Open() connection for select
 	// - accepts the plan context used in this upsert/insert/update
 	// - returns a connection which must be closed
@@ -140,6 +172,11 @@ type (
 		ConnUpsert
 		ConnDeletion
 	}
+	// ConnFlusher is an optional interface for Mutators that buffer writes
+	// (ie, files).  Flush is called once a mutation statement has completed.
+	ConnFlusher interface {
+		Flush() error
+	}
 	// ConnUpsert Mutation interface for Put
 	//  - assumes datasource understands key(s?)
 	ConnUpsert interface {
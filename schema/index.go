@@ -0,0 +1,28 @@
+package schema
+
+import (
+	"database/sql/driver"
+)
+
+// IndexSeek describes the rows to read from an index, found by the planner
+// from where clause predicates on the indexed column.
+//
+//	col = x, col IN (x, y)      =>  Values
+//	col > x AND col <= y        =>  Lower, Upper range
+//	col BETWEEN x AND y         =>  Lower, Upper inclusive range
+type IndexSeek struct {
+	Index *Index
+	// Field the indexed column name
+	Field string
+	// Values point lookups, if empty this is a range seek
+	Values []driver.Value
+	// Lower bound of range, nil if un-bounded
+	Lower          driver.Value
+	LowerInclusive bool
+	// Upper bound of range, nil if un-bounded
+	Upper          driver.Value
+	UpperInclusive bool
+}
+
+// IsRange is this a range seek (not point lookups).
+func (m *IndexSeek) IsRange() bool { return len(m.Values) == 0 }
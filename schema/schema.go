@@ -94,6 +94,7 @@ type (
 		tableMap      map[string]*Table  // Tables and their field info, flattened from all child schemas
 		tableNames    []string           // List Table names, flattened all schemas into one list
 		lastRefreshed time.Time          // Last time we refreshed this schema
+		funcs         *expr.FuncRegistry // user defined functions, CREATE FUNCTION
 		mu            sync.RWMutex       // lock for schema mods
 	}
 
@@ -184,11 +185,18 @@ func NewSchemaSource(schemaName string, ds Source) *Schema {
 		tableMap:     make(map[string]*Table),
 		tableSchemas: make(map[string]*Schema),
 		tableNames:   make([]string, 0),
+		funcs:        expr.NewFuncRegistry(),
 		DS:           ds,
 	}
 	return m
 }
 
+// Funcs the user defined functions of this schema, such as from
+// CREATE FUNCTION, which resolve before the global builtins.
+func (m *Schema) Funcs() *expr.FuncRegistry {
+	return m.funcs
+}
+
 // Since Is this schema object been refreshed within time window described by @dur time ago ?
 func (m *Schema) Since(dur time.Duration) bool {
 	if m.lastRefreshed.IsZero() {
@@ -632,6 +640,14 @@ func NewFieldBase(name string, valType value.ValueType, size int, desc string) *
 	}
 	return &Field{FieldPb: f}
 }
+
+// NewFieldDecimal a decimal(precision, scale) field.
+func NewFieldDecimal(name string, precision, scale int, desc string) *Field {
+	f := NewFieldBase(name, value.DecimalType, precision, desc)
+	f.Precision = uint32(precision)
+	f.Scale = uint32(scale)
+	return f
+}
 func NewField(name string, valType value.ValueType, size int, allowNulls bool, defaultVal driver.Value, key, collation, description string) *Field {
 	jb, _ := json.Marshal(defaultVal)
 	f := FieldPb{
@@ -661,6 +677,9 @@ func (m *Field) AsRow() []driver.Value {
 	// []string{"Field", "Type", "Collation", "Null", "Key", "Default", "Extra", "Privileges", "Comment"}
 	m.row[0] = m.Name
 	m.row[1] = value.ValueType(m.Type).String() // should we send this through a dialect-writer?  bc dialect specific?
+	if m.Type == uint32(value.DecimalType) && m.Precision > 0 {
+		m.row[1] = fmt.Sprintf("decimal(%d,%d)", m.Precision, m.Scale)
+	}
 	m.row[2] = m.Collation
 	m.row[3] = ""
 	m.row[4] = ""
@@ -67,6 +67,32 @@ func ValueTypeFromStringAll(val string) ValueType {
 	return StringType
 }
 
+// UnifyTypes find the narrowest type that can represent values of both
+// types, used to widen a column type when introspecting rows.
+// Will unify based on the following rules:
+// - Nil, Unknown are ignored, other type is used
+// - int, decimal widen to decimal
+// - int, number widen to number
+// - json can represent any other type
+// - else string
+func UnifyTypes(a, b ValueType) ValueType {
+	switch {
+	case a == b:
+		return a
+	case a == NilType || a == UnknownType:
+		return b
+	case b == NilType || b == UnknownType:
+		return a
+	case (a == DecimalType || b == DecimalType) && (a == IntType || b == IntType):
+		return DecimalType
+	case a.IsNumeric() && b.IsNumeric():
+		return NumberType
+	case a == JsonType || b == JsonType:
+		return JsonType
+	}
+	return StringType
+}
+
 // Cast a value to given value type
 func Cast(valType ValueType, val Value) (Value, error) {
 	switch valType {
@@ -91,6 +117,16 @@ func Cast(valType ValueType, val Value) (Value, error) {
 			return NewIntValue(iv), nil
 		}
 		return nil, ErrConversion
+	case DecimalType:
+		if dv, ok := ValueToDecimal(val); ok {
+			return dv, nil
+		}
+		return nil, ErrConversion
+	case DurationType:
+		if dv, ok := ValueToDuration(val); ok {
+			return dv, nil
+		}
+		return nil, ErrConversion
 	}
 	return nil, ErrConversionNotSupported
 }
@@ -132,8 +168,17 @@ func Equal(l, r Value) (bool, error) {
 		rhv, _ := ValueToInt64(r)
 		return lt.Val() == rhv, nil
 	case NumberValue:
+		if rd, isDecimal := r.(DecimalValue); isDecimal {
+			return Equal(rd, lt)
+		}
 		rhv, _ := ValueToFloat64(r)
 		return lt.Val() == rhv, nil
+	case DecimalValue:
+		rhv, ok := ValueToDecimal(r)
+		return ok && lt.Cmp(rhv) == 0, nil
+	case DurationValue:
+		rhv, ok := ValueToDuration(r)
+		return ok && lt.Cmp(rhv) == 0, nil
 	case BoolValue:
 		rhv, _ := ValueToBool(r)
 		return lt.Val() == rhv, nil
@@ -127,6 +127,19 @@ func TestValueTypeFromString(t *testing.T) {
 	assert.Equal(t, JsonType, ValueTypeFromStringAll(`["hello","world",1]`))
 }
 
+func TestUnifyTypes(t *testing.T) {
+	assert.Equal(t, IntType, UnifyTypes(IntType, IntType))
+	assert.Equal(t, IntType, UnifyTypes(NilType, IntType))
+	assert.Equal(t, IntType, UnifyTypes(IntType, UnknownType))
+	assert.Equal(t, NumberType, UnifyTypes(IntType, NumberType))
+	assert.Equal(t, NumberType, UnifyTypes(NumberType, IntType))
+	assert.Equal(t, StringType, UnifyTypes(NumberType, StringType))
+	assert.Equal(t, StringType, UnifyTypes(IntType, BoolType))
+	assert.Equal(t, StringType, UnifyTypes(TimeType, StringType))
+	assert.Equal(t, JsonType, UnifyTypes(JsonType, StringType))
+	assert.Equal(t, JsonType, UnifyTypes(IntType, JsonType))
+}
+
 func TestCast(t *testing.T) {
 	good := func(expect interface{}, vt ValueType, v Value) {
 		val, err := Cast(vt, v)
@@ -0,0 +1,378 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// DecimalDivScale is the number of decimal places division adds to the
+	// larger scale of its operands, ie 1.00 / 3 => 0.333333
+	DecimalDivScale int32 = 4
+
+	bigTen = big.NewInt(10)
+
+	_ NumericValue = DecimalValue{}
+)
+
+// DecimalValue an exact, arbitrary precision decimal number, an integer
+// (unscaled) value and a scale, the number of digits after the decimal
+// point:  unscaled * 10^-scale.  Operations return new values, the
+// unscaled value is never modified.
+type DecimalValue struct {
+	v     *big.Int
+	scale int32
+}
+
+// NewDecimalValue create decimal of unscaled * 10^-scale, ie (1234, 2) => 12.34
+func NewDecimalValue(unscaled int64, scale int32) DecimalValue {
+	return newDecimal(big.NewInt(unscaled), scale)
+}
+
+// NewDecimalBig create decimal of unscaled * 10^-scale.
+func NewDecimalBig(unscaled *big.Int, scale int32) DecimalValue {
+	if unscaled == nil {
+		return DecimalValue{}
+	}
+	return newDecimal(new(big.Int).Set(unscaled), scale)
+}
+
+// NewDecimalNil a nil decimal, ie sql NULL
+func NewDecimalNil() DecimalValue { return DecimalValue{} }
+
+// NewDecimalFromFloat the decimal of the shortest representation of f
+// that round trips, ie 0.1 => 0.1 not 0.1000000000000000055511151231257827.
+// NaN, Inf are nil.
+func NewDecimalFromFloat(f float64) DecimalValue {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return DecimalValue{}
+	}
+	d, err := NewDecimalFromString(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return DecimalValue{}
+	}
+	return d
+}
+
+// NewDecimalFromString parse a decimal "-123.4500", "1.5e3".  The scale is
+// the number of digits after the decimal point, so trailing zeros are kept.
+func NewDecimalFromString(s string) (DecimalValue, error) {
+	str := strings.TrimSpace(s)
+	exp := int64(0)
+	if idx := strings.IndexAny(str, "eE"); idx >= 0 {
+		e, err := strconv.ParseInt(str[idx+1:], 10, 32)
+		if err != nil {
+			return DecimalValue{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		exp = e
+		str = str[:idx]
+	}
+	neg := false
+	if len(str) > 0 && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+	intPart, fracPart := str, ""
+	if idx := strings.IndexByte(str, '.'); idx >= 0 {
+		intPart, fracPart = str[:idx], str[idx+1:]
+	}
+	digits := intPart + fracPart
+	if len(digits) == 0 {
+		return DecimalValue{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return DecimalValue{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+	v, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		v.Neg(v)
+	}
+	scale := int64(len(fracPart)) - exp
+	if scale < 0 {
+		v.Mul(v, pow10(int32(-scale)))
+		scale = 0
+	}
+	if scale > math.MaxInt32 {
+		return DecimalValue{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return DecimalValue{v: v, scale: int32(scale)}, nil
+}
+
+func newDecimal(v *big.Int, scale int32) DecimalValue {
+	if scale < 0 {
+		v.Mul(v, pow10(-scale))
+		scale = 0
+	}
+	return DecimalValue{v: v, scale: scale}
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func (m DecimalValue) Nil() bool       { return m.v == nil }
+func (m DecimalValue) Err() bool       { return m.v == nil }
+func (m DecimalValue) Type() ValueType { return DecimalType }
+
+// Value the decimal string, as database/sql drivers represent decimals.
+func (m DecimalValue) Value() interface{} {
+	if m.v == nil {
+		return nil
+	}
+	return m.ToString()
+}
+func (m DecimalValue) MarshalJSON() ([]byte, error) {
+	if m.v == nil {
+		return []byte("null"), nil
+	}
+	return []byte(m.ToString()), nil
+}
+func (m DecimalValue) ToString() string {
+	if m.v == nil {
+		return ""
+	}
+	digits := new(big.Int).Abs(m.v).String()
+	if m.scale > 0 {
+		if pad := int(m.scale) - len(digits) + 1; pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(m.scale)] + "." + digits[len(digits)-int(m.scale):]
+	}
+	if m.v.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Float the nearest float64, may lose precision.
+func (m DecimalValue) Float() float64 {
+	if m.v == nil {
+		return math.NaN()
+	}
+	f, _ := m.Rat().Float64()
+	return f
+}
+
+// Int the integer part, truncated towards zero.
+func (m DecimalValue) Int() int64 {
+	if m.v == nil {
+		return 0
+	}
+	return new(big.Int).Quo(m.v, pow10(m.scale)).Int64()
+}
+
+// Rat the exact rational value of the decimal.
+func (m DecimalValue) Rat() *big.Rat {
+	if m.v == nil {
+		return nil
+	}
+	return new(big.Rat).SetFrac(m.v, pow10(m.scale))
+}
+
+// Unscaled the integer value of the decimal without its decimal point.
+func (m DecimalValue) Unscaled() *big.Int {
+	if m.v == nil {
+		return nil
+	}
+	return new(big.Int).Set(m.v)
+}
+
+// Scale number of digits after the decimal point.
+func (m DecimalValue) Scale() int32 { return m.scale }
+
+// Precision total number of significant digits of the unscaled value.
+func (m DecimalValue) Precision() int {
+	if m.v == nil || m.v.Sign() == 0 {
+		return 1
+	}
+	return len(new(big.Int).Abs(m.v).String())
+}
+
+// Sign -1, 0, +1 for negative, zero, positive.
+func (m DecimalValue) Sign() int {
+	if m.v == nil {
+		return 0
+	}
+	return m.v.Sign()
+}
+
+// Rescale to the given scale, rounding half away from zero if it has fewer
+// digits, ie 1.235 => 1.24, -1.235 => -1.24.
+func (m DecimalValue) Rescale(scale int32) DecimalValue {
+	if m.v == nil {
+		return m
+	}
+	if scale < 0 {
+		scale = 0
+	}
+	if scale >= m.scale {
+		return DecimalValue{v: new(big.Int).Mul(m.v, pow10(scale-m.scale)), scale: scale}
+	}
+	return DecimalValue{v: quoRound(m.v, pow10(m.scale-scale)), scale: scale}
+}
+
+// quoRound a / b rounding half away from zero, b > 0.
+func quoRound(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(b) >= 0 {
+		if a.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// align the unscaled values of a, b to the larger scale.
+func alignDecimals(a, b DecimalValue) (*big.Int, *big.Int, int32) {
+	switch {
+	case a.scale > b.scale:
+		return a.v, new(big.Int).Mul(b.v, pow10(a.scale-b.scale)), a.scale
+	case b.scale > a.scale:
+		return new(big.Int).Mul(a.v, pow10(b.scale-a.scale)), b.v, b.scale
+	}
+	return a.v, b.v, a.scale
+}
+
+// Cmp compare -1, 0, +1 if m <, ==, > b.  Nil decimals are less than
+// any other.
+func (m DecimalValue) Cmp(b DecimalValue) int {
+	switch {
+	case m.v == nil && b.v == nil:
+		return 0
+	case m.v == nil:
+		return -1
+	case b.v == nil:
+		return 1
+	}
+	av, bv, _ := alignDecimals(m, b)
+	return av.Cmp(bv)
+}
+
+// Add m + b, scale of the larger scale.
+func (m DecimalValue) Add(b DecimalValue) DecimalValue {
+	if m.v == nil || b.v == nil {
+		return DecimalValue{}
+	}
+	av, bv, scale := alignDecimals(m, b)
+	return DecimalValue{v: new(big.Int).Add(av, bv), scale: scale}
+}
+
+// Sub m - b, scale of the larger scale.
+func (m DecimalValue) Sub(b DecimalValue) DecimalValue {
+	if m.v == nil || b.v == nil {
+		return DecimalValue{}
+	}
+	av, bv, scale := alignDecimals(m, b)
+	return DecimalValue{v: new(big.Int).Sub(av, bv), scale: scale}
+}
+
+// Mul m * b, scale is the sum of scales.
+func (m DecimalValue) Mul(b DecimalValue) DecimalValue {
+	if m.v == nil || b.v == nil {
+		return DecimalValue{}
+	}
+	return DecimalValue{v: new(big.Int).Mul(m.v, b.v), scale: m.scale + b.scale}
+}
+
+// Div m / b, scale of the larger scale plus DecimalDivScale rounded half
+// away from zero.  False if b is zero.
+func (m DecimalValue) Div(b DecimalValue) (DecimalValue, bool) {
+	if m.v == nil || b.v == nil || b.v.Sign() == 0 {
+		return DecimalValue{}, false
+	}
+	scale := m.scale
+	if b.scale > scale {
+		scale = b.scale
+	}
+	scale += DecimalDivScale
+	// m.v * 10^(scale - m.scale + b.scale) / b.v
+	num := new(big.Int).Mul(m.v, pow10(scale-m.scale+b.scale))
+	den := new(big.Int).Set(b.v)
+	if den.Sign() < 0 {
+		num.Neg(num)
+		den.Neg(den)
+	}
+	return DecimalValue{v: quoRound(num, den), scale: scale}, true
+}
+
+// Mod remainder of m / b truncated, with the sign of m.  False if b is zero.
+func (m DecimalValue) Mod(b DecimalValue) (DecimalValue, bool) {
+	if m.v == nil || b.v == nil || b.v.Sign() == 0 {
+		return DecimalValue{}, false
+	}
+	av, bv, scale := alignDecimals(m, b)
+	return DecimalValue{v: new(big.Int).Rem(av, bv), scale: scale}, true
+}
+
+// Neg -m
+func (m DecimalValue) Neg() DecimalValue {
+	if m.v == nil {
+		return m
+	}
+	return DecimalValue{v: new(big.Int).Neg(m.v), scale: m.scale}
+}
+
+// ValueToDecimal Convert a value type to a decimal if possible, floats
+// use their shortest representation.
+func ValueToDecimal(val Value) (DecimalValue, bool) {
+	if val == nil || val.Nil() || val.Err() {
+		return DecimalValue{}, false
+	}
+	switch v := val.(type) {
+	case DecimalValue:
+		return v, true
+	case IntValue:
+		return NewDecimalValue(v.Val(), 0), true
+	case NumberValue:
+		d := NewDecimalFromFloat(v.Val())
+		return d, !d.Nil()
+	case StringValue:
+		d, err := NewDecimalFromString(v.Val())
+		return d, err == nil
+	case BoolValue:
+		if v.Val() {
+			return NewDecimalValue(1, 0), true
+		}
+		return NewDecimalValue(0, 0), true
+	}
+	return DecimalValue{}, false
+}
+
+// ParseDecimalType parse a sql decimal type "decimal", "DECIMAL(10,2)",
+// "numeric(5)" into its precision, scale.  Precision is 0 if not given.
+func ParseDecimalType(s string) (precision, scale int, ok bool) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	args := ""
+	if idx := strings.IndexByte(t, '('); idx > 0 && strings.HasSuffix(t, ")") {
+		t, args = strings.TrimSpace(t[:idx]), t[idx+1:len(t)-1]
+	}
+	if t != "decimal" && t != "numeric" {
+		return 0, 0, false
+	}
+	if args == "" {
+		return 0, 0, true
+	}
+	parts := strings.Split(args, ",")
+	if len(parts) > 2 {
+		return 0, 0, false
+	}
+	p, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || p < 1 {
+		return 0, 0, false
+	}
+	if len(parts) == 2 {
+		scale, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || scale < 0 || scale > p {
+			return 0, 0, false
+		}
+	}
+	return p, scale, true
+}
@@ -0,0 +1,155 @@
+package value
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDecimal(s string) DecimalValue {
+	d, err := NewDecimalFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestDecimalParse(t *testing.T) {
+	tests := []struct {
+		in    string
+		out   string
+		scale int32
+	}{
+		{"0", "0", 0},
+		{"12.34", "12.34", 2},
+		{"-0.05", "-0.05", 2},
+		{"+7.500", "7.500", 3},
+		{".5", "0.5", 1},
+		{"1.5e3", "1500", 0},
+		{"1.5e-3", "0.0015", 4},
+		{"12345678901234567890.123456789", "12345678901234567890.123456789", 9},
+	}
+	for _, tt := range tests {
+		d, err := NewDecimalFromString(tt.in)
+		assert.Equal(t, nil, err, tt.in)
+		assert.Equal(t, tt.out, d.ToString(), tt.in)
+		assert.Equal(t, tt.scale, d.Scale(), tt.in)
+	}
+	for _, bad := range []string{"", "abc", "1.2.3", "1e", "-"} {
+		_, err := NewDecimalFromString(bad)
+		assert.NotEqual(t, nil, err, bad)
+	}
+
+	assert.Equal(t, "12.34", NewDecimalValue(1234, 2).ToString())
+	assert.Equal(t, "0.1", NewDecimalFromFloat(0.1).ToString())
+	assert.True(t, NewDecimalNil().Nil())
+	assert.Equal(t, DecimalType, NewDecimalValue(1, 0).Type())
+	assert.Equal(t, "1.10", NewDecimalValue(110, 2).Value())
+
+	by, err := json.Marshal(NewDecimalValue(-1005, 3))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "-1.005", string(by))
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a, b := mustDecimal("1.10"), mustDecimal("2.205")
+	assert.Equal(t, "3.305", a.Add(b).ToString())
+	assert.Equal(t, "-1.105", a.Sub(b).ToString())
+	assert.Equal(t, "2.42550", a.Mul(b).ToString())
+	assert.Equal(t, "2.2", b.Neg().Neg().Rescale(1).ToString())
+
+	// float64 would be 0.30000000000000004
+	assert.Equal(t, "0.3", mustDecimal("0.1").Add(mustDecimal("0.2")).ToString())
+
+	q, ok := mustDecimal("1.00").Div(NewDecimalValue(3, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "0.333333", q.ToString())
+	q, ok = mustDecimal("-2").Div(NewDecimalValue(3, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "-0.6667", q.ToString())
+	_, ok = a.Div(NewDecimalValue(0, 0))
+	assert.False(t, ok)
+
+	r, ok := mustDecimal("10.5").Mod(NewDecimalValue(3, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "1.5", r.ToString())
+	_, ok = a.Mod(NewDecimalValue(0, 2))
+	assert.False(t, ok)
+
+	assert.True(t, a.Add(NewDecimalNil()).Nil())
+}
+
+func TestDecimalRescaleCmp(t *testing.T) {
+	assert.Equal(t, "1.24", mustDecimal("1.235").Rescale(2).ToString())
+	assert.Equal(t, "-1.24", mustDecimal("-1.235").Rescale(2).ToString())
+	assert.Equal(t, "1.23", mustDecimal("1.2349").Rescale(2).ToString())
+	assert.Equal(t, "5.000", mustDecimal("5").Rescale(3).ToString())
+	assert.Equal(t, 4, mustDecimal("12.34").Precision())
+
+	assert.Equal(t, 0, mustDecimal("1.10").Cmp(mustDecimal("1.1")))
+	assert.Equal(t, -1, mustDecimal("1.09").Cmp(mustDecimal("1.1")))
+	assert.Equal(t, 1, mustDecimal("-1").Cmp(mustDecimal("-1.5")))
+	assert.Equal(t, -1, NewDecimalNil().Cmp(mustDecimal("0")))
+
+	assert.Equal(t, int64(12), mustDecimal("12.99").Int())
+	assert.Equal(t, int64(-12), mustDecimal("-12.99").Int())
+	assert.True(t, CloseEnuf(12.99, mustDecimal("12.99").Float()))
+}
+
+func TestDecimalCoerce(t *testing.T) {
+	d, ok := ValueToDecimal(NewStringValue(" 3.50 "))
+	assert.True(t, ok)
+	assert.Equal(t, "3.50", d.ToString())
+	d, ok = ValueToDecimal(NewIntValue(42))
+	assert.True(t, ok)
+	assert.Equal(t, "42", d.ToString())
+	d, ok = ValueToDecimal(NewNumberValue(2.5))
+	assert.True(t, ok)
+	assert.Equal(t, "2.5", d.ToString())
+	_, ok = ValueToDecimal(NewStringValue("abc"))
+	assert.False(t, ok)
+	_, ok = ValueToDecimal(NilValueVal)
+	assert.False(t, ok)
+
+	val, err := Cast(DecimalType, NewStringValue("19.99"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "19.99", val.Value())
+	_, err = Cast(DecimalType, NewStringValue("x"))
+	assert.NotEqual(t, nil, err)
+
+	eq, _ := Equal(mustDecimal("2.50"), NewNumberValue(2.5))
+	assert.True(t, eq)
+	eq, _ = Equal(NewNumberValue(2.5), mustDecimal("2.5"))
+	assert.True(t, eq)
+	eq, _ = Equal(mustDecimal("2"), NewIntValue(3))
+	assert.False(t, eq)
+
+	assert.Equal(t, DecimalType, UnifyTypes(IntType, DecimalType))
+	assert.Equal(t, NumberType, UnifyTypes(DecimalType, NumberType))
+	assert.Equal(t, DecimalType, ValueFromString("decimal"))
+	assert.True(t, DecimalType.IsNumeric())
+}
+
+func TestParseDecimalType(t *testing.T) {
+	tests := []struct {
+		in   string
+		p, s int
+		ok   bool
+	}{
+		{"decimal", 0, 0, true},
+		{"DECIMAL(10,2)", 10, 2, true},
+		{"numeric(5)", 5, 0, true},
+		{"decimal( 12 , 4 )", 12, 4, true},
+		{"decimal(2,3)", 0, 0, false},
+		{"decimal(a)", 0, 0, false},
+		{"decimal(1,2,3)", 0, 0, false},
+		{"float", 0, 0, false},
+	}
+	for _, tt := range tests {
+		p, s, ok := ParseDecimalType(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		assert.Equal(t, tt.p, p, tt.in)
+		assert.Equal(t, tt.s, s, tt.in)
+	}
+}
@@ -0,0 +1,275 @@
+package value
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// calendar units of an interval, the number of months, days they add
+// to a time depends on the date and location so are kept separate from
+// the exact duration.
+type calendarUnit struct {
+	months int64
+	days   int64
+	dur    time.Duration
+}
+
+var durationUnits = map[string]calendarUnit{
+	"ns":          {dur: time.Nanosecond},
+	"nanosecond":  {dur: time.Nanosecond},
+	"us":          {dur: time.Microsecond},
+	"µs":          {dur: time.Microsecond},
+	"microsecond": {dur: time.Microsecond},
+	"ms":          {dur: time.Millisecond},
+	"millisecond": {dur: time.Millisecond},
+	"s":           {dur: time.Second},
+	"sec":         {dur: time.Second},
+	"second":      {dur: time.Second},
+	"m":           {dur: time.Minute},
+	"min":         {dur: time.Minute},
+	"minute":      {dur: time.Minute},
+	"h":           {dur: time.Hour},
+	"hr":          {dur: time.Hour},
+	"hour":        {dur: time.Hour},
+	"d":           {days: 1},
+	"day":         {days: 1},
+	"w":           {days: 7},
+	"wk":          {days: 7},
+	"week":        {days: 7},
+	"mon":         {months: 1},
+	"month":       {months: 1},
+	"q":           {months: 3},
+	"quarter":     {months: 3},
+	"y":           {months: 12},
+	"yr":          {months: 12},
+	"year":        {months: 12},
+	"decade":      {months: 120},
+	"century":     {months: 1200},
+}
+
+// DurationValue an interval of time.  Months and days are calendar units
+// whose length depends on the time they are added to (28-31 day months,
+// 23-25 hour days across daylight savings changes) so are kept separate
+// from the exact duration, as sql INTERVAL types do.
+type DurationValue struct {
+	months int64
+	days   int64
+	dur    time.Duration
+	valid  bool
+}
+
+// NewDurationValue an exact duration, ie time.Sub()
+func NewDurationValue(d time.Duration) DurationValue {
+	return DurationValue{dur: d, valid: true}
+}
+
+// NewIntervalValue an interval of months, days and an exact duration.
+func NewIntervalValue(months, days int64, d time.Duration) DurationValue {
+	return DurationValue{months: months, days: days, dur: d, valid: true}
+}
+
+// NewDurationNil a nil duration, ie sql NULL
+func NewDurationNil() DurationValue { return DurationValue{} }
+
+// ParseDuration parse an interval from "3 days", "1 year 2 months",
+// "-2h30m", "14d", "1 month 3 days 2h0m0s".  Units are singular or plural
+// sql units (second, minute, hour, day, week, month, quarter, year), Go
+// duration units (ns, us, ms, s, m, h) or d, w, M (month), y.
+func ParseDuration(s string) (DurationValue, error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return DurationValue{}, fmt.Errorf("invalid duration %q", s)
+	}
+	// a single leading sign negates the whole interval as go durations
+	// "-2h30m", otherwise each unit is signed "-1 month -2 days"
+	neg := false
+	if (str[0] == '-' || str[0] == '+') && !strings.ContainsAny(str[1:], "-+") {
+		neg = str[0] == '-'
+		str = strings.TrimSpace(str[1:])
+	}
+	d := DurationValue{valid: true}
+	for len(str) > 0 {
+		// number
+		i := 0
+		if str[0] == '-' || str[0] == '+' {
+			i++
+		}
+		for i < len(str) && (str[i] >= '0' && str[i] <= '9' || str[i] == '.') {
+			i++
+		}
+		num := str[:i]
+		str = strings.TrimLeft(str[i:], " ")
+		// unit
+		j := strings.IndexFunc(str, func(r rune) bool { return !unicode.IsLetter(r) })
+		if j < 0 {
+			j = len(str)
+		}
+		unit := str[:j]
+		str = strings.TrimLeft(str[j:], " ,")
+
+		cu, ok := lookupDurationUnit(unit)
+		if !ok || num == "" {
+			return DurationValue{}, fmt.Errorf("invalid duration %q", s)
+		}
+		if cu.dur != 0 {
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return DurationValue{}, fmt.Errorf("invalid duration %q", s)
+			}
+			d.dur += time.Duration(f * float64(cu.dur))
+			continue
+		}
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			// fractional months, days are not exact
+			return DurationValue{}, fmt.Errorf("invalid duration %q", s)
+		}
+		d.months += n * cu.months
+		d.days += n * cu.days
+	}
+	if neg {
+		return d.Neg(), nil
+	}
+	return d, nil
+}
+
+func lookupDurationUnit(unit string) (calendarUnit, bool) {
+	if unit == "M" {
+		return durationUnits["month"], true
+	}
+	u := strings.ToLower(unit)
+	if cu, ok := durationUnits[u]; ok {
+		return cu, true
+	}
+	if strings.HasSuffix(u, "s") {
+		cu, ok := durationUnits[u[:len(u)-1]]
+		return cu, ok
+	}
+	return calendarUnit{}, false
+}
+
+func (m DurationValue) Nil() bool       { return !m.valid }
+func (m DurationValue) Err() bool       { return !m.valid }
+func (m DurationValue) Type() ValueType { return DurationType }
+
+// Value the interval string, see ToString.
+func (m DurationValue) Value() interface{} {
+	if !m.valid {
+		return nil
+	}
+	return m.ToString()
+}
+func (m DurationValue) MarshalJSON() ([]byte, error) {
+	if !m.valid {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.Quote(m.ToString())), nil
+}
+
+// ToString the interval as its calendar units and exact duration that
+// ParseDuration reads back  "1 month 3 days 2h0m0s", "-36h0m0s".
+func (m DurationValue) ToString() string {
+	if !m.valid {
+		return ""
+	}
+	parts := make([]string, 0, 3)
+	if m.months != 0 {
+		parts = append(parts, plural(m.months, "month"))
+	}
+	if m.days != 0 {
+		parts = append(parts, plural(m.days, "day"))
+	}
+	if m.dur != 0 || len(parts) == 0 {
+		parts = append(parts, m.dur.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 || n == -1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Val the approximate duration, months of 30 days, days of 24 hours.
+func (m DurationValue) Val() time.Duration {
+	return time.Duration(m.months*30+m.days)*24*time.Hour + m.dur
+}
+
+// Months the calendar months of the interval.
+func (m DurationValue) Months() int64 { return m.months }
+
+// Days the calendar days of the interval.
+func (m DurationValue) Days() int64 { return m.days }
+
+// Duration the exact (hours, minutes...) part of the interval.
+func (m DurationValue) Duration() time.Duration { return m.dur }
+
+// AddTo add the interval to t, months and days in the location of t.
+func (m DurationValue) AddTo(t time.Time) time.Time {
+	if m.months != 0 || m.days != 0 {
+		t = t.AddDate(0, int(m.months), int(m.days))
+	}
+	return t.Add(m.dur)
+}
+
+// Neg the negated interval.
+func (m DurationValue) Neg() DurationValue {
+	if !m.valid {
+		return m
+	}
+	return DurationValue{months: -m.months, days: -m.days, dur: -m.dur, valid: true}
+}
+
+// Add m + b, by units.
+func (m DurationValue) Add(b DurationValue) DurationValue {
+	if !m.valid || !b.valid {
+		return DurationValue{}
+	}
+	return DurationValue{months: m.months + b.months, days: m.days + b.days, dur: m.dur + b.dur, valid: true}
+}
+
+// Sub m - b, by units.
+func (m DurationValue) Sub(b DurationValue) DurationValue {
+	return m.Add(b.Neg())
+}
+
+// Mul multiply each unit of the interval by n.
+func (m DurationValue) Mul(n int64) DurationValue {
+	if !m.valid {
+		return m
+	}
+	return DurationValue{months: m.months * n, days: m.days * n, dur: m.dur * time.Duration(n), valid: true}
+}
+
+// Cmp compare -1, 0, +1 if m <, ==, > b by their approximate durations.
+func (m DurationValue) Cmp(b DurationValue) int {
+	av, bv := m.Val(), b.Val()
+	switch {
+	case av < bv:
+		return -1
+	case av > bv:
+		return 1
+	}
+	return 0
+}
+
+// ValueToDuration Convert a value type to a duration if possible, strings
+// are parsed by ParseDuration.
+func ValueToDuration(val Value) (DurationValue, bool) {
+	if val == nil || val.Nil() || val.Err() {
+		return DurationValue{}, false
+	}
+	switch v := val.(type) {
+	case DurationValue:
+		return v, true
+	case StringValue:
+		d, err := ParseDuration(v.Val())
+		return d, err == nil
+	}
+	return DurationValue{}, false
+}
@@ -0,0 +1,99 @@
+package value
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		months int64
+		days   int64
+		dur    time.Duration
+		out    string
+	}{
+		{"3 days", 0, 3, 0, "3 days"},
+		{"1 day", 0, 1, 0, "1 day"},
+		{"1 year 2 months", 14, 0, 0, "14 months"},
+		{"2 weeks", 0, 14, 0, "14 days"},
+		{"1 quarter", 3, 0, 0, "3 months"},
+		{"-2h30m", 0, 0, -150 * time.Minute, "-2h30m0s"},
+		{"14d", 0, 14, 0, "14 days"},
+		{"3M", 3, 0, 0, "3 months"},
+		{"1.5 hours", 0, 0, 90 * time.Minute, "1h30m0s"},
+		{"1 month 3 days 2h0m0s", 1, 3, 2 * time.Hour, "1 month 3 days 2h0m0s"},
+		{"-1 month, 2 days", -1, -2, 0, "-1 month -2 days"},
+		{"0s", 0, 0, 0, "0s"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDuration(tt.in)
+		assert.Equal(t, nil, err, tt.in)
+		assert.Equal(t, tt.months, d.Months(), tt.in)
+		assert.Equal(t, tt.days, d.Days(), tt.in)
+		assert.Equal(t, tt.dur, d.Duration(), tt.in)
+		assert.Equal(t, tt.out, d.ToString(), tt.in)
+		// ToString reads back
+		d2, err := ParseDuration(d.ToString())
+		assert.Equal(t, nil, err, tt.in)
+		assert.Equal(t, d, d2, tt.in)
+	}
+	for _, bad := range []string{"", "days", "3", "3 fortnights", "1.5 days"} {
+		_, err := ParseDuration(bad)
+		assert.NotEqual(t, nil, err, bad)
+	}
+
+	assert.True(t, NewDurationNil().Nil())
+	assert.Equal(t, DurationType, NewDurationValue(time.Second).Type())
+	assert.Equal(t, "1s", NewDurationValue(time.Second).Value())
+	by, err := json.Marshal(NewIntervalValue(0, 2, time.Hour))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `"2 days 1h0m0s"`, string(by))
+}
+
+func TestDurationArithmetic(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	// day before daylight savings starts
+	ts := time.Date(2017, 3, 11, 12, 0, 0, 0, la)
+
+	day := NewIntervalValue(0, 1, 0)
+	assert.Equal(t, time.Date(2017, 3, 12, 12, 0, 0, 0, la), day.AddTo(ts))
+	assert.Equal(t, 23*time.Hour, day.AddTo(ts).Sub(ts))
+	assert.Equal(t, 24*time.Hour, NewDurationValue(24*time.Hour).AddTo(ts).Sub(ts))
+
+	month := NewIntervalValue(1, 0, 0)
+	assert.Equal(t, time.Date(2017, 3, 3, 0, 0, 0, 0, time.UTC), month.AddTo(time.Date(2017, 1, 31, 0, 0, 0, 0, time.UTC)))
+
+	sum := month.Add(day).Add(NewDurationValue(time.Hour))
+	assert.Equal(t, "1 month 1 day 1h0m0s", sum.ToString())
+	assert.Equal(t, "1 month", sum.Sub(day).Sub(NewDurationValue(time.Hour)).ToString())
+	assert.Equal(t, "-3 months -3 days -3h0m0s", sum.Mul(-3).ToString())
+	assert.True(t, sum.Add(NewDurationNil()).Nil())
+
+	assert.Equal(t, 1, NewDurationValue(36*time.Hour).Cmp(day))
+	assert.Equal(t, 0, NewDurationValue(24*time.Hour).Cmp(day))
+	assert.Equal(t, -1, day.Cmp(month))
+}
+
+func TestDurationCoerce(t *testing.T) {
+	d, ok := ValueToDuration(NewStringValue("2 days"))
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), d.Days())
+	_, ok = ValueToDuration(NewStringValue("hello"))
+	assert.False(t, ok)
+	_, ok = ValueToDuration(NewIntValue(3))
+	assert.False(t, ok)
+
+	val, err := Cast(DurationType, NewStringValue("1 week"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "7 days", val.ToString())
+
+	eq, _ := Equal(NewIntervalValue(0, 1, 0), NewDurationValue(24*time.Hour))
+	assert.True(t, eq)
+
+	assert.Equal(t, DurationType, ValueFromString("interval"))
+	assert.Equal(t, DurationType, NewValue(time.Minute).Type())
+}
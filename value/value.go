@@ -60,6 +60,8 @@ const (
 	BoolType           ValueType = 12
 	TimeType           ValueType = 13
 	ByteSliceType      ValueType = 14
+	DecimalType        ValueType = 15
+	DurationType       ValueType = 16
 	StringType         ValueType = 20
 	StringsType        ValueType = 21
 	MapValueType       ValueType = 30
@@ -93,6 +95,10 @@ func (m ValueType) String() string {
 		return "time"
 	case ByteSliceType:
 		return "[]byte"
+	case DecimalType:
+		return "decimal"
+	case DurationType:
+		return "duration"
 	case StringType:
 		return "string"
 	case StringsType:
@@ -138,7 +144,7 @@ func (m ValueType) IsSlice() bool {
 
 func (m ValueType) IsNumeric() bool {
 	switch m {
-	case NumberType, IntType:
+	case NumberType, IntType, DecimalType:
 		return true
 	}
 	return false
@@ -255,6 +261,10 @@ func ValueFromString(vt string) ValueType {
 		return TimeType
 	case "[]byte":
 		return ByteSliceType
+	case "decimal":
+		return DecimalType
+	case "duration", "interval":
+		return DurationType
 	case "string":
 		return StringType
 	case "[]string":
@@ -292,6 +302,8 @@ func NewValue(goVal interface{}) Value {
 		return NilValueVal
 	case Value:
 		return val
+	case time.Duration:
+		return NewDurationValue(val)
 	case float64:
 		return NewNumberValue(val)
 	case float32:
@@ -0,0 +1,408 @@
+package vm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+type (
+	// RowReader is a ContextReader of a row of values with column positions,
+	// ie datasource.SqlDriverMessageMap.  Compiled expressions read
+	// identities from it by position instead of Get(key).
+	RowReader interface {
+		expr.ContextReader
+		Values() []driver.Value
+		ColumnIndex() map[string]int
+	}
+
+	// CompiledExpr is an expression compiled to a tree of closures, to
+	// evaluate the same expression against many rows without walking the
+	// expr.Node tree for each:
+	//
+	//   - identities are resolved to column positions once per column index
+	//     of a RowReader
+	//   - literal only sub-expressions are evaluated once at compile time
+	//   - comparisons to number, string literals have specialized closures
+	//   - AND, OR short-circuit once the left side decides the result
+	//
+	// A CompiledExpr holds the column positions it is bound to, so it is not
+	// safe for concurrent use, each task should compile its own.
+	CompiledExpr struct {
+		node   expr.Node
+		eval   evalFn
+		idents []*colIdent
+		cols   uintptr // column index the idents are bound to
+	}
+
+	evalFn func(ctx expr.EvalContext) (value.Value, bool)
+
+	// colIdent identity read by column position if bound
+	colIdent struct {
+		key   string
+		right string
+		idx   int
+	}
+)
+
+// Compile the expression into a CompiledExpr.  Returns an error for
+// un-recognized nodes.
+func Compile(node expr.Node) (_ *CompiledExpr, err error) {
+	defer errRecover(&err)
+	c := &CompiledExpr{node: node}
+	c.eval = c.compile(node)
+	return c, nil
+}
+
+// Node the expression this was compiled from.
+func (m *CompiledExpr) Node() expr.Node { return m.node }
+
+// Eval evaluate the compiled expression against the given context, same
+// result as vm.Eval(ctx, node).
+func (m *CompiledExpr) Eval(ctx expr.EvalContext) (value.Value, bool) {
+	if nullLogic(ctx) {
+		return Eval(ctx, m.node)
+	}
+	if len(m.idents) > 0 {
+		if rr, ok := ctx.(RowReader); ok {
+			m.bind(rr.ColumnIndex())
+		} else {
+			m.bind(nil)
+		}
+	}
+	return m.eval(ctx)
+}
+
+// bind resolve the identities to positions in the column index.
+func (m *CompiledExpr) bind(cols map[string]int) {
+	ptr := reflect.ValueOf(cols).Pointer()
+	if ptr == m.cols {
+		return
+	}
+	m.cols = ptr
+	for _, ci := range m.idents {
+		ci.idx = -1
+		if cols == nil {
+			continue
+		}
+		if idx, ok := cols[ci.key]; ok {
+			ci.idx = idx
+		} else if idx, ok := cols[ci.right]; ok && ci.right != "" {
+			ci.idx = idx
+		}
+	}
+}
+
+func (m *CompiledExpr) compile(node expr.Node) evalFn {
+
+	if isLiteral(node) {
+		v, ok := evalDepth(nil, node, 0)
+		return func(expr.EvalContext) (value.Value, bool) { return v, ok }
+	}
+
+	switch n := node.(type) {
+	case *expr.IdentityNode:
+		return m.compileIdentity(n)
+	case *expr.BinaryNode:
+		return m.compileBinary(n)
+	case *expr.BooleanNode:
+		return m.compileBoolean(n)
+	case *expr.UnaryNode:
+		arg := m.compile(n.Arg)
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			a, ok := arg(ctx)
+			return operateUnary(ctx, n, a, ok)
+		}
+	case *expr.TriNode:
+		a, b, c := m.compile(n.Args[0]), m.compile(n.Args[1]), m.compile(n.Args[2])
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			av, aok := a(ctx)
+			bv, bok := b(ctx)
+			cv, cok := c(ctx)
+			return operateTernary(ctx, n, av, aok, bv, bok, cv, cok)
+		}
+	case *expr.ArrayNode:
+		args := m.compileArgs(n.Args)
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			vals := make([]value.Value, len(args))
+			for i, arg := range args {
+				v, ok := arg(ctx)
+				if !ok || v == nil {
+					v = value.NilValueVal
+				}
+				vals[i] = v
+			}
+			return value.NewSliceValues(vals), true
+		}
+	case *expr.FuncNode:
+		if n.F.CustomFunc == nil || n.Eval == nil {
+			return func(expr.EvalContext) (value.Value, bool) { return nil, false }
+		}
+		args := m.compileArgs(n.Args)
+		if n.EvalLazy != nil {
+			lazy := make([]expr.ArgEvaluator, len(args))
+			for i, arg := range args {
+				lazy[i] = expr.ArgEvaluator(arg)
+			}
+			return func(ctx expr.EvalContext) (value.Value, bool) {
+				return n.EvalLazy(ctx, lazy)
+			}
+		}
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			vals := make([]value.Value, len(args))
+			for i, arg := range args {
+				v, ok := arg(ctx)
+				if !ok {
+					v = value.NewNilValue()
+				}
+				vals[i] = v
+			}
+			return n.Eval(ctx, vals)
+		}
+	case *expr.CaseNode, *expr.IncludeNode:
+		// evaluated by the vm
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			return evalDepth(ctx, n, 0)
+		}
+	}
+	panic(fmt.Errorf("%v: %T", ErrUnknownNodeType, node))
+}
+
+func (m *CompiledExpr) compileArgs(nodes []expr.Node) []evalFn {
+	args := make([]evalFn, len(nodes))
+	for i, arg := range nodes {
+		args[i] = m.compile(arg)
+	}
+	return args
+}
+
+func (m *CompiledExpr) compileIdentity(n *expr.IdentityNode) evalFn {
+	key := n.Text
+	if n.HasLeftRight() {
+		key = n.OriginalText()
+	}
+	ci := &colIdent{key: key, idx: -1}
+	if _, right, hasLeft := expr.LeftRight(key); hasLeft {
+		ci.right = right
+	}
+	m.idents = append(m.idents, ci)
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		if ci.idx >= 0 {
+			if vals := ctx.(RowReader).Values(); ci.idx < len(vals) {
+				return value.NewValue(vals[ci.idx]), true
+			}
+		}
+		if ctx == nil {
+			return nil, false
+		}
+		return ctx.Get(ci.key)
+	}
+}
+
+func (m *CompiledExpr) compileBinary(n *expr.BinaryNode) evalFn {
+
+	switch n.Operator.T {
+	case lex.TokenDistinctFrom, lex.TokenNotDistinctFrom:
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			return walkDistinctFrom(ctx, n, 0)
+		}
+	}
+
+	a, b := m.compile(n.Args[0]), m.compile(n.Args[1])
+
+	switch n.Operator.T {
+	case lex.TokenRLike, lex.TokenRLikeI:
+		if fn := compileRegex(n, a); fn != nil {
+			return fn
+		}
+	case lex.TokenLogicAnd, lex.TokenLogicOr:
+		// left side false (AND), true (OR) decides the result
+		decides := n.Operator.T == lex.TokenLogicOr
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			av, aok := a(ctx)
+			if bv, isBool := av.(value.BoolValue); aok && isBool && bv.Val() == decides {
+				return bv, true
+			}
+			bv, bok := b(ctx)
+			return binaryResult(operateBinary(n, av, aok, bv, bok))
+		}
+	case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
+		lex.TokenLT, lex.TokenLE:
+		switch rn := n.Args[1].(type) {
+		case *expr.NumberNode:
+			if fn := compareNumber(n, a, rn); fn != nil {
+				return fn
+			}
+		case *expr.StringNode:
+			if fn := compareString(n, a, rn); fn != nil {
+				return fn
+			}
+		}
+	}
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		av, aok := a(ctx)
+		bv, bok := b(ctx)
+		return binaryResult(operateBinary(n, av, aok, bv, bok))
+	}
+}
+
+func binaryResult(v value.Value, ok bool) (value.Value, bool) {
+	if !ok {
+		return nil, false
+	}
+	return v, true
+}
+
+// compileRegex specialized closure for <expr> (RLIKE|~|~*) <pattern literal>,
+// compiling the pattern once.
+func compileRegex(n *expr.BinaryNode, a evalFn) evalFn {
+	sn, isString := n.Args[1].(*expr.StringNode)
+	if !isString {
+		return nil
+	}
+	pattern := sn.Text
+	if n.Operator.T == lex.TokenRLikeI {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// invalid patterns error when evaluated
+		return nil
+	}
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		av, aok := a(ctx)
+		if !aok {
+			return value.BoolValueFalse, true
+		}
+		return regexMatch(re, av), true
+	}
+}
+
+// compareNumber specialized closure for <expr> (=|!=|>|>=|<|<=) <number>
+func compareNumber(n *expr.BinaryNode, a evalFn, rn *expr.NumberNode) evalFn {
+	rv, ok := numberNodeToValue(rn)
+	if !ok {
+		return nil
+	}
+	op := n.Operator.T
+	ri, rf, isInt := rn.Int64, rn.Float64, rn.IsInt
+	if isInt {
+		rf = float64(ri)
+	}
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		av, aok := a(ctx)
+		if aok {
+			switch at := av.(type) {
+			case value.IntValue:
+				if isInt {
+					return compareInts(op, at.Val(), ri), true
+				}
+				return compareFloats(op, float64(at.Val()), rf), true
+			case value.NumberValue:
+				return compareFloats(op, at.Val(), rf), true
+			}
+		}
+		return binaryResult(operateBinary(n, av, aok, rv, true))
+	}
+}
+
+// compareString specialized closure for <expr> (=|!=) <string>
+func compareString(n *expr.BinaryNode, a evalFn, rn *expr.StringNode) evalFn {
+	op := n.Operator.T
+	if op != lex.TokenEqual && op != lex.TokenEqualEqual && op != lex.TokenNE {
+		return nil
+	}
+	rv := value.NewStringValue(rn.Text)
+	eq := op != lex.TokenNE
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		av, aok := a(ctx)
+		if at, isStr := av.(value.StringValue); aok && isStr {
+			return value.NewBoolValue((at.Val() == rn.Text) == eq), true
+		}
+		return binaryResult(operateBinary(n, av, aok, rv, true))
+	}
+}
+
+func compareInts(op lex.TokenType, a, b int64) value.Value {
+	switch op {
+	case lex.TokenEqual, lex.TokenEqualEqual:
+		return value.NewBoolValue(a == b)
+	case lex.TokenNE:
+		return value.NewBoolValue(a != b)
+	case lex.TokenGT:
+		return value.NewBoolValue(a > b)
+	case lex.TokenGE:
+		return value.NewBoolValue(a >= b)
+	case lex.TokenLT:
+		return value.NewBoolValue(a < b)
+	}
+	return value.NewBoolValue(a <= b)
+}
+
+func compareFloats(op lex.TokenType, a, b float64) value.Value {
+	switch op {
+	case lex.TokenEqual, lex.TokenEqualEqual:
+		return value.NewBoolValue(a == b)
+	case lex.TokenNE:
+		return value.NewBoolValue(a != b)
+	case lex.TokenGT:
+		return value.NewBoolValue(a > b)
+	case lex.TokenGE:
+		return value.NewBoolValue(a >= b)
+	case lex.TokenLT:
+		return value.NewBoolValue(a < b)
+	}
+	return value.NewBoolValue(a <= b)
+}
+
+func (m *CompiledExpr) compileBoolean(n *expr.BooleanNode) evalFn {
+	var and bool
+	switch n.Operator.T {
+	case lex.TokenAnd, lex.TokenLogicAnd:
+		and = true
+	case lex.TokenOr, lex.TokenLogicOr:
+		and = false
+	default:
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			return walkBoolean(ctx, n, 0)
+		}
+	}
+	args := m.compileArgs(n.Args)
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		return operateBoolean(n, and, func(i int) (bool, bool) {
+			v, ok := args[i](ctx)
+			if bv, isBool := v.(value.BoolValue); ok && isBool {
+				return bv.Val(), true
+			}
+			return false, false
+		})
+	}
+}
+
+// isLiteral the node (and its children) has no identities, functions that
+// depend on the context.
+func isLiteral(node expr.Node) bool {
+	switch n := node.(type) {
+	case *expr.NumberNode, *expr.StringNode, *expr.NullNode, *expr.ValueNode:
+		return true
+	case *expr.IdentityNode:
+		return n.IsBooleanIdentity()
+	case *expr.BinaryNode, *expr.BooleanNode, *expr.UnaryNode, *expr.TriNode,
+		*expr.ArrayNode, *expr.CaseNode:
+		for _, arg := range node.(expr.NodeArgs).ChildrenArgs() {
+			if arg == nil {
+				continue
+			}
+			if !isLiteral(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package vm_test
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+func TestCompile(t *testing.T) {
+	for _, test := range vmTests {
+		if !test.parseok || strings.Contains(test.qlText, "12/18/2020") {
+			continue
+		}
+		n, err := expr.ParseExpression(test.qlText)
+		assert.Equal(t, nil, err, test.qlText)
+
+		c, err := vm.Compile(n)
+		assert.Equal(t, nil, err, test.qlText)
+
+		val, ok := vm.Eval(test.context, n)
+		cval, cok := c.Eval(test.context)
+		assert.Equal(t, ok, cok, test.qlText)
+		assert.Equal(t, val == nil, cval == nil, test.qlText)
+		if val != nil && cval != nil {
+			assert.Equal(t, val.Value(), cval.Value(), test.qlText)
+		}
+	}
+}
+
+func TestCompileRow(t *testing.T) {
+	n, err := expr.ParseExpression(`ct > 5 AND users.name == "bob" AND tolower(name) IN ("bob", "jane") AND 2 * 3 = 6`)
+	assert.Equal(t, nil, err)
+	c, err := vm.Compile(n)
+	assert.Equal(t, nil, err)
+
+	cols := map[string]int{"name": 0, "ct": 1}
+	tests := []struct {
+		row    []driver.Value
+		result bool
+	}{
+		{[]driver.Value{"bob", int64(6)}, true},
+		{[]driver.Value{"bob", int64(5)}, false},
+		{[]driver.Value{"jane", 10.5}, false},
+	}
+	for _, tt := range tests {
+		msg := datasource.NewSqlDriverMessageMap(0, tt.row, cols)
+		val, ok := c.Eval(msg)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, tt.result, val.Value(), "%v", tt.row)
+		ival, _ := vm.Eval(msg, n)
+		assert.Equal(t, ival.Value(), val.Value(), "%v", tt.row)
+	}
+
+	// different column positions re-binds
+	msg := datasource.NewSqlDriverMessageMap(0, []driver.Value{int64(7), "bob"}, map[string]int{"name": 1, "ct": 0})
+	val, ok := c.Eval(msg)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, true, val.Value())
+
+	// a context without column positions, or missing columns
+	for _, rdr := range []expr.ContextReader{
+		datasource.NewContextSimpleNative(map[string]interface{}{"name": "jane", "ct": 7}),
+		datasource.NewSqlDriverMessageMap(0, []driver.Value{"bob"}, map[string]int{"name": 0}),
+	} {
+		val, ok = c.Eval(rdr)
+		ival, iok := vm.Eval(rdr, n)
+		assert.Equal(t, iok, ok)
+		assert.Equal(t, ival, val)
+	}
+}
+
+func TestCompileConstant(t *testing.T) {
+	n, err := expr.ParseExpression(`(4 + 2) * 10`)
+	assert.Equal(t, nil, err)
+	c, err := vm.Compile(n)
+	assert.Equal(t, nil, err)
+	val, ok := c.Eval(nil)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, value.NewIntValue(60), val)
+}
@@ -186,6 +186,10 @@ func (d *DateConverter) findDateMath(node expr.Node) {
 		for _, arg := range n.Args {
 			d.findDateMath(arg)
 		}
+	case *expr.CaseNode:
+		for _, arg := range n.ChildrenArgs() {
+			d.findDateMath(arg)
+		}
 	case *expr.IncludeNode:
 		if err := resolveInclude(d.ctx, n, 0); err != nil {
 			d.err = err
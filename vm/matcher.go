@@ -0,0 +1,498 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/value"
+)
+
+type (
+	// Matcher matches a message against many FilterQL statements at once,
+	// the many filter counterpart of Matches.
+	//
+	//   - Each filter is indexed by predicates it can't match without, ie
+	//     AND ( x = 1, y > 5 ) by x = 1.  Per message the indexed identities
+	//     are read once and the filters whose index predicates hold are
+	//     found by hash (=, IN) or binary search (<, <=, >, >=, BETWEEN)
+	//     lookups, only these (and the filters with no index predicate)
+	//     are evaluated.
+	//   - Sub-expressions shared by filters, and identity lookups, are
+	//     evaluated once per message.
+	//
+	// Results are those of MatchesInc with the Matcher includer for each
+	// filter.  Add is not safe for concurrent use, Match is once the
+	// filters are added.
+	Matcher struct {
+		// Includer optional, resolves INCLUDE's
+		Includer expr.Includer
+
+		filters []*matchFilter
+		ids     map[string]int
+		idents  map[string]*identIndex
+		scan    []int             // filters without index predicates
+		shared  map[expr.Node]int // sub-expression -> position in per message cache
+		keys    map[string]int    // sub-expression string -> position
+	}
+
+	matchFilter struct {
+		id   string
+		stmt *rel.FilterStatement
+	}
+
+	// identIndex index predicates of one identity
+	identIndex struct {
+		node   *expr.IdentityNode
+		eq     map[string][]int // literal key -> filters
+		lower  []matchBound     // x > c, x >= c, sorted by c
+		upper  []matchBound     // x < c, x <= c, sorted by c
+		exists []int
+		all    []int // every filter indexed on this identity
+	}
+
+	matchBound struct {
+		v         float64
+		inclusive bool
+		filter    int
+	}
+
+	// matchPred an index predicate, kind is = (IN), <, >, or EXISTS
+	matchPred struct {
+		ident *expr.IdentityNode
+		kind  lex.TokenType
+		keys  []string
+		bound matchBound
+	}
+)
+
+// NewMatcher create a Matcher, inc may be nil if filters have no INCLUDE.
+func NewMatcher(inc expr.Includer) *Matcher {
+	return &Matcher{
+		Includer: inc,
+		ids:      make(map[string]int),
+		idents:   make(map[string]*identIndex),
+		shared:   make(map[expr.Node]int),
+		keys:     make(map[string]int),
+	}
+}
+
+// Len the number of filters.
+func (m *Matcher) Len() int { return len(m.filters) }
+
+// Add a filter under id, the id Match returns for it.
+func (m *Matcher) Add(id string, stmt *rel.FilterStatement) error {
+	if stmt == nil || stmt.Filter == nil {
+		return fmt.Errorf("filter %q has no FILTER expression", id)
+	}
+	if _, exists := m.ids[id]; exists {
+		return fmt.Errorf("filter %q already added", id)
+	}
+	if len(expr.FindIncludes(stmt.Filter)) > 0 {
+		if m.Includer == nil {
+			return fmt.Errorf("filter %q has INCLUDE but no includer", id)
+		}
+		if err := ResolveIncludes(m.Includer, stmt.Filter); err != nil {
+			return err
+		}
+	}
+
+	fi := len(m.filters)
+	m.filters = append(m.filters, &matchFilter{id: id, stmt: stmt})
+	m.ids[id] = fi
+	m.share(stmt.Filter)
+
+	preds, ok := m.indexPreds(stmt.Filter, 0)
+	if !ok {
+		m.scan = append(m.scan, fi)
+		return nil
+	}
+	for _, p := range preds {
+		m.index(fi, p)
+	}
+	return nil
+}
+
+// share register the leaf sub-expressions of n, equal ones share a
+// position in the per message cache
+func (m *Matcher) share(n expr.Node) {
+	if bn, ok := n.(*expr.BooleanNode); ok {
+		for _, arg := range bn.Args {
+			m.share(arg)
+		}
+		return
+	}
+	key := n.String()
+	pos, ok := m.keys[key]
+	if !ok {
+		pos = len(m.keys)
+		m.keys[key] = pos
+	}
+	m.shared[n] = pos
+}
+
+func (m *Matcher) index(fi int, p *matchPred) {
+	key := identKey(p.ident)
+	ix := m.idents[key]
+	if ix == nil {
+		ix = &identIndex{node: p.ident, eq: make(map[string][]int)}
+		m.idents[key] = ix
+	}
+	if n := len(ix.all); n == 0 || ix.all[n-1] != fi {
+		ix.all = append(ix.all, fi)
+	}
+	switch p.kind {
+	case lex.TokenEqual:
+		for _, k := range p.keys {
+			ix.eq[k] = append(ix.eq[k], fi)
+		}
+	case lex.TokenExists:
+		ix.exists = append(ix.exists, fi)
+	case lex.TokenGT:
+		ix.lower = insertBound(ix.lower, p.bound, fi)
+	case lex.TokenLT:
+		ix.upper = insertBound(ix.upper, p.bound, fi)
+	}
+}
+
+func insertBound(bounds []matchBound, b matchBound, fi int) []matchBound {
+	b.filter = fi
+	i := sort.Search(len(bounds), func(i int) bool { return bounds[i].v > b.v })
+	bounds = append(bounds, matchBound{})
+	copy(bounds[i+1:], bounds[i:])
+	bounds[i] = b
+	return bounds
+}
+
+// Match the ids of the filters cr matches, in the order they were added.
+func (m *Matcher) Match(cr expr.ContextReader) []string {
+	ctx := newMatchContext(cr, m.Includer)
+	candidates := make([]bool, len(m.filters))
+	for _, ix := range m.idents {
+		v, ok := walkIdentity(ctx, ix.node)
+		if !ok || v == nil {
+			continue
+		}
+		ix.candidates(v, candidates)
+	}
+	for _, fi := range m.scan {
+		candidates[fi] = true
+	}
+
+	var ids []string
+	eval := &matchEval{m: m, ctx: ctx, cache: make([]int8, len(m.keys))}
+	for fi, isCandidate := range candidates {
+		if !isCandidate {
+			continue
+		}
+		if matched, _ := eval.matches(m.filters[fi].stmt.Filter); matched {
+			ids = append(ids, m.filters[fi].id)
+		}
+	}
+	return ids
+}
+
+// candidates mark the filters whose index predicates on this identity may
+// hold for v
+func (m *identIndex) candidates(v value.Value, candidates []bool) {
+	mark := func(fis []int) {
+		for _, fi := range fis {
+			candidates[fi] = true
+		}
+	}
+	mark(m.exists)
+
+	var (
+		num   float64
+		isNum bool
+	)
+	switch vt := v.(type) {
+	case value.NilValue:
+		return
+	case value.IntValue:
+		num, isNum = float64(vt.Val()), true
+	case value.NumberValue:
+		num, isNum = vt.Val(), true
+	case value.StringValue:
+		mark(m.eq[stringKey(vt.Val())])
+		if f, err := strconv.ParseFloat(vt.Val(), 64); err == nil {
+			num, isNum = f, true
+		} else if len(m.lower) > 0 || len(m.upper) > 0 {
+			// leave non-numeric range comparisons to the vm
+			mark(m.all)
+			return
+		}
+	default:
+		// slices, maps, times etc, the vm compares these its own way
+		mark(m.all)
+		return
+	}
+	if !isNum {
+		return
+	}
+	mark(m.eq[numberKey(num)])
+
+	// lower bounds below num
+	end := sort.Search(len(m.lower), func(i int) bool { return m.lower[i].v > num })
+	for _, b := range m.lower[:end] {
+		if b.v < num || b.inclusive {
+			candidates[b.filter] = true
+		}
+	}
+	// upper bounds above num
+	start := sort.Search(len(m.upper), func(i int) bool { return m.upper[i].v >= num })
+	for _, b := range m.upper[start:] {
+		if b.v > num || b.inclusive {
+			candidates[b.filter] = true
+		}
+	}
+}
+
+// indexPreds predicates n can't match without, if it has any: one of
+// the returned predicates holds for every message n matches
+func (m *Matcher) indexPreds(n expr.Node, depth int) ([]*matchPred, bool) {
+	switch n := n.(type) {
+	case *expr.IncludeNode:
+		if n.Negated() || n.ExprNode == nil || depth > MaxDepth {
+			return nil, false
+		}
+		return m.indexPreds(n.ExprNode, depth+1)
+	case *expr.BooleanNode:
+		if n.Negated() || len(n.Args) == 0 {
+			return nil, false
+		}
+		return m.listPreds(n.Operator.T == lex.TokenLogicAnd, n.Args, depth)
+	case *expr.BinaryNode:
+		switch n.Operator.T {
+		case lex.TokenLogicAnd, lex.TokenLogicOr:
+			return m.listPreds(n.Operator.T == lex.TokenLogicAnd, n.Args, depth)
+		}
+		return comparisonPred(n)
+	case *expr.TriNode:
+		if n.Negated() || n.Operator.T != lex.TokenBetween {
+			return nil, false
+		}
+		id, ok := n.Args[0].(*expr.IdentityNode)
+		lo, isNum := n.Args[1].(*expr.NumberNode)
+		if !ok || !isNum || id.IsBooleanIdentity() {
+			return nil, false
+		}
+		return []*matchPred{{ident: id, kind: lex.TokenGT, bound: matchBound{v: lo.Float64, inclusive: true}}}, true
+	case *expr.UnaryNode:
+		if id, ok := n.Arg.(*expr.IdentityNode); ok && n.Operator.T == lex.TokenExists && !id.IsBooleanIdentity() {
+			return []*matchPred{{ident: id, kind: lex.TokenExists}}, true
+		}
+	}
+	return nil, false
+}
+
+// listPreds an AND needs one of its args, the most selective is used, an
+// OR needs one of the predicates of each
+func (m *Matcher) listPreds(isAnd bool, args []expr.Node, depth int) ([]*matchPred, bool) {
+	var best []*matchPred
+	for _, arg := range args {
+		preds, ok := m.indexPreds(arg, depth+1)
+		switch {
+		case isAnd && ok:
+			if best == nil || predCost(preds) < predCost(best) {
+				best = preds
+			}
+		case !isAnd && !ok:
+			return nil, false
+		case !isAnd:
+			best = append(best, preds...)
+		}
+	}
+	return best, best != nil
+}
+
+// predCost how many messages the predicates are expected to let through,
+// equality being the most selective
+func predCost(preds []*matchPred) int {
+	cost := 0
+	for _, p := range preds {
+		switch p.kind {
+		case lex.TokenEqual:
+			cost += len(p.keys)
+		case lex.TokenExists:
+			cost += 4
+		default:
+			cost += 2
+		}
+	}
+	return cost
+}
+
+func comparisonPred(n *expr.BinaryNode) ([]*matchPred, bool) {
+	if len(n.Args) != 2 {
+		return nil, false
+	}
+	id, ok := n.Args[0].(*expr.IdentityNode)
+	if !ok || id.IsBooleanIdentity() {
+		return nil, false
+	}
+	switch n.Operator.T {
+	case lex.TokenEqual, lex.TokenEqualEqual:
+		keys, ok := literalKeys(n.Args[1])
+		if !ok {
+			return nil, false
+		}
+		return []*matchPred{{ident: id, kind: lex.TokenEqual, keys: keys}}, true
+	case lex.TokenIN:
+		arr, ok := n.Args[1].(*expr.ArrayNode)
+		if !ok || len(arr.Args) == 0 {
+			return nil, false
+		}
+		var keys []string
+		for _, a := range arr.Args {
+			ak, ok := literalKeys(a)
+			if !ok {
+				return nil, false
+			}
+			keys = append(keys, ak...)
+		}
+		return []*matchPred{{ident: id, kind: lex.TokenEqual, keys: keys}}, true
+	case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE:
+		num, ok := n.Args[1].(*expr.NumberNode)
+		if !ok {
+			return nil, false
+		}
+		p := &matchPred{ident: id, kind: lex.TokenGT, bound: matchBound{v: num.Float64}}
+		if n.Operator.T == lex.TokenLT || n.Operator.T == lex.TokenLE {
+			p.kind = lex.TokenLT
+		}
+		p.bound.inclusive = n.Operator.T == lex.TokenGE || n.Operator.T == lex.TokenLE
+		return []*matchPred{p}, true
+	}
+	return nil, false
+}
+
+// literalKeys index keys of a literal, strings that are numbers also
+// match numeric values
+func literalKeys(n expr.Node) ([]string, bool) {
+	switch n := n.(type) {
+	case *expr.NumberNode:
+		return []string{numberKey(n.Float64)}, true
+	case *expr.StringNode:
+		if f, err := strconv.ParseFloat(n.Text, 64); err == nil {
+			return []string{stringKey(n.Text), numberKey(f)}, true
+		}
+		return []string{stringKey(n.Text)}, true
+	}
+	return nil, false
+}
+
+func numberKey(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+func stringKey(s string) string  { return "s:" + s }
+
+// identKey the key the vm reads an identity with
+func identKey(n *expr.IdentityNode) string {
+	if n.HasLeftRight() {
+		return n.OriginalText()
+	}
+	return n.Text
+}
+
+// matchEval evaluates filters for one message, caching shared
+// sub-expression results: 0 not evaluated, 1 true, -1 false, -2 not ok
+type matchEval struct {
+	m     *Matcher
+	ctx   expr.EvalContext
+	cache []int8
+}
+
+func (e *matchEval) matches(n expr.Node) (bool, bool) {
+	if id, ok := n.(*expr.IdentityNode); ok && (id.Text == "*" || id.Text == "match_all") {
+		return true, true
+	}
+	return e.eval(n)
+}
+
+func (e *matchEval) eval(n expr.Node) (bool, bool) {
+	if bn, ok := n.(*expr.BooleanNode); ok {
+		var val value.Value
+		switch bn.Operator.T {
+		case lex.TokenAnd, lex.TokenLogicAnd, lex.TokenOr, lex.TokenLogicOr:
+		default:
+			ok = false
+		}
+		if ok && !nullLogic(e.ctx) {
+			and := bn.Operator.T == lex.TokenAnd || bn.Operator.T == lex.TokenLogicAnd
+			val, ok = operateBoolean(bn, and, func(i int) (bool, bool) {
+				return e.eval(bn.Args[i])
+			})
+		} else {
+			val, ok = walkBoolean(e.ctx, bn, 0)
+		}
+		if bv, isBool := val.(value.BoolValue); ok && isBool {
+			return bv.Val(), true
+		}
+		return false, ok
+	}
+	pos := e.m.shared[n]
+	switch e.cache[pos] {
+	case 1:
+		return true, true
+	case -1:
+		return false, true
+	case -2:
+		return false, false
+	}
+	matched, ok := evalBool(e.ctx, n, 1)
+	switch {
+	case !ok:
+		e.cache[pos] = -2
+	case matched:
+		e.cache[pos] = 1
+	default:
+		e.cache[pos] = -1
+	}
+	return matched, ok
+}
+
+// matchContext caches the values read from a message, INCLUDE's are
+// resolved by the Matcher includer
+type matchContext struct {
+	expr.ContextReader
+	inc       expr.Includer
+	nullLogic bool
+	vals      map[string]matchValue
+}
+
+type matchValue struct {
+	v  value.Value
+	ok bool
+}
+
+func newMatchContext(cr expr.ContextReader, inc expr.Includer) *matchContext {
+	return &matchContext{
+		ContextReader: cr,
+		inc:           inc,
+		nullLogic:     nullLogic(cr),
+		vals:          make(map[string]matchValue),
+	}
+}
+
+// Get read key from the message, once
+func (m *matchContext) Get(key string) (value.Value, bool) {
+	if mv, ok := m.vals[key]; ok {
+		return mv.v, mv.ok
+	}
+	v, ok := m.ContextReader.Get(key)
+	m.vals[key] = matchValue{v, ok}
+	return v, ok
+}
+
+// NullLogic if the message context uses sql three-valued logic.
+func (m *matchContext) NullLogic() bool { return m.nullLogic }
+
+// Include resolve an include with the Matcher includer.
+func (m *matchContext) Include(name string) (expr.Node, error) {
+	if m.inc == nil {
+		return nil, expr.ErrNoIncluder
+	}
+	return m.inc.Include(name)
+}
@@ -0,0 +1,147 @@
+package vm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/vm"
+)
+
+type matchIncluder map[string]string
+
+func (m matchIncluder) Include(name string) (expr.Node, error) {
+	fs, err := rel.ParseFilterQL(m[name])
+	if err != nil {
+		return nil, err
+	}
+	return fs.Filter, nil
+}
+
+var matcherFilters = []string{
+	`*`,
+	`x = 5`,
+	`x == 5`,
+	`x = "5"`,
+	`x IN (1, 5, 9)`,
+	`x > 5`,
+	`x >= 5`,
+	`x < 5`,
+	`x <= 5.5`,
+	`x > 4.5`,
+	`x BETWEEN 3 AND 6`,
+	`NOT x BETWEEN 3 AND 6`,
+	`x != 5`,
+	`x NOT IN (5, 6)`,
+	`NOT x > 5`,
+	`EXISTS x`,
+	`NOT EXISTS x`,
+	`name = "bob"`,
+	`name IN ("bob", "sue")`,
+	`name > 5`,
+	`name LIKE "b*"`,
+	`name CONTAINS "o"`,
+	`roles = "admin"`,
+	`roles IN ("admin", "x")`,
+	`score > 1.5`,
+	`score = 2.5`,
+	`AND ( x > 1, name = "bob" )`,
+	`AND ( x > 1, OR ( name = "bob", score > 2 ) )`,
+	`OR ( x = 1, name = "sue" )`,
+	`OR ( x = 1, NOT name = "sue" )`,
+	`NOT AND ( x > 1, name = "bob" )`,
+	`AND ( x < 9000, INCLUDE big )`,
+	`OR ( INCLUDE big, name = "sue" )`,
+	`AND ( EXISTS name, x IN (5, 7) )`,
+	`flag = true`,
+	`AND ( flag = true, x > 2 )`,
+	`OR ( x > 1, x < -1 )`,
+	`x > 5 AND name = "bob"`,
+	`x = 5 OR name = "sue"`,
+}
+
+var matcherMessages = []map[string]interface{}{
+	{},
+	{"x": 5},
+	{"x": 6, "name": "bob"},
+	{"x": 1, "name": "sue", "score": 2.5},
+	{"x": "5", "name": "bob"},
+	{"x": "5.0"},
+	{"x": "abc", "name": "jo"},
+	{"x": 5.5, "score": 1.0},
+	{"x": -3, "flag": true},
+	{"x": 3, "flag": true, "roles": []string{"admin", "user"}},
+	{"x": 9, "name": "bobby", "roles": []string{"user"}},
+	{"name": "bob", "score": 3},
+	{"x": nil, "name": nil},
+}
+
+func TestMatcher(t *testing.T) {
+	inc := matchIncluder{"big": `FILTER x > 4`}
+
+	m := vm.NewMatcher(inc)
+	stmts := make([]*rel.FilterStatement, len(matcherFilters))
+	for i, f := range matcherFilters {
+		fs, err := rel.ParseFilterQL("FILTER " + f)
+		assert.Equal(t, nil, err, f)
+		stmts[i] = fs
+		assert.Equal(t, nil, m.Add(fmt.Sprintf("f%d", i), fs))
+	}
+	assert.Equal(t, len(matcherFilters), m.Len())
+
+	for _, msg := range matcherMessages {
+		cr := datasource.NewContextSimpleNative(msg)
+		var expected []string
+		for i, fs := range stmts {
+			if matched, _ := vm.MatchesInc(inc, cr, fs); matched {
+				expected = append(expected, fmt.Sprintf("f%d", i))
+			}
+		}
+		got := m.Match(cr)
+		assert.Equal(t, expected, got, "msg %v\nexpected %v\ngot      %v", msg, names(expected), names(got))
+
+		// the same under sql null logic
+		nc := &nullIncluder{vm.NewNullLogicContext(cr), inc}
+		expected = expected[:0]
+		for i, fs := range stmts {
+			if matched, _ := vm.Matches(nc, fs); matched {
+				expected = append(expected, fmt.Sprintf("f%d", i))
+			}
+		}
+		got = m.Match(nc)
+		assert.Equal(t, expected, got, "null logic msg %v\nexpected %v\ngot      %v", msg, names(expected), names(got))
+	}
+}
+
+type nullIncluder struct {
+	expr.EvalContext
+	matchIncluder
+}
+
+func (m *nullIncluder) NullLogic() bool { return true }
+
+func names(ids []string) []string {
+	var fs []string
+	for _, id := range ids {
+		var i int
+		fmt.Sscanf(id, "f%d", &i)
+		fs = append(fs, matcherFilters[i])
+	}
+	return fs
+}
+
+func TestMatcherAdd(t *testing.T) {
+	m := vm.NewMatcher(nil)
+	fs := rel.MustParseFilter(`FILTER x = 5`)
+	assert.Equal(t, nil, m.Add("a", fs))
+	assert.NotEqual(t, nil, m.Add("a", fs), "duplicate id")
+	assert.NotEqual(t, nil, m.Add("b", rel.MustParseFilter(`FILTER INCLUDE big`)), "no includer")
+	assert.Equal(t, 1, m.Len())
+
+	assert.Equal(t, []string{"a"}, m.Match(datasource.NewContextSimpleNative(map[string]interface{}{"x": 5})))
+	assert.Equal(t, 0, len(m.Match(datasource.NewContextSimpleNative(map[string]interface{}{"x": 6}))))
+}
@@ -0,0 +1,142 @@
+package vm
+
+import (
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+var _ expr.EvalNullLogicContext = (*nullLogicContext)(nil)
+
+// nullLogicContext wraps a context reader to select sql three-valued logic.
+type nullLogicContext struct {
+	expr.ContextReader
+}
+
+// NewNullLogicContext wrap a context reader so expressions evaluated against it
+// use sql three-valued NULL logic instead of the default where a missing value
+// makes a comparison false:
+//
+//   - NULL (missing or nil value) propagates through comparisons, arithmetic,
+//     LIKE, IN, BETWEEN and NOT, ie  NULL = 1  => NULL,  NOT NULL => NULL
+//   - AND, OR follow Kleene logic:  false AND NULL => false,  true OR NULL => true
+//   - x = NULL, x != NULL are NULL, use x IS NULL, x IS NOT NULL to test for NULL
+//   - x IN (1, NULL) is NULL if x is not in the list
+//
+// A NULL result is returned as value.NilValue, which a where filter drops.
+func NewNullLogicContext(ctx expr.ContextReader) expr.EvalContext {
+	return &nullLogicContext{ctx}
+}
+
+// NullLogic always true.
+func (m *nullLogicContext) NullLogic() bool { return true }
+
+// Include pass through to the wrapped context if it is an Includer.
+func (m *nullLogicContext) Include(name string) (expr.Node, error) {
+	if inc, ok := m.ContextReader.(expr.Includer); ok {
+		return inc.Include(name)
+	}
+	return nil, expr.ErrNoIncluder
+}
+
+func nullLogic(ctx expr.EvalContext) bool {
+	nc, ok := ctx.(expr.EvalNullLogicContext)
+	return ok && nc.NullLogic()
+}
+
+// isNull the evaluated value is sql NULL, either couldn't be evaluated
+// (missing) or a nil value.
+func isNull(v value.Value, ok bool) bool {
+	if !ok {
+		return true
+	}
+	switch v.(type) {
+	case nil, value.NilValue:
+		return true
+	}
+	return false
+}
+
+// walkBinaryNull binary evaluation for three-valued logic.
+func walkBinaryNull(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.Value, bool) {
+
+	switch node.Operator.T {
+	case lex.TokenLogicAnd, lex.TokenAnd:
+		return walkKleene(ctx, true, node.Args, depth)
+	case lex.TokenLogicOr, lex.TokenOr:
+		return walkKleene(ctx, false, node.Args, depth)
+	}
+	if node.IsNullTest() {
+		v, ok := evalDepth(ctx, node.Args[0], depth+1)
+		return value.NewBoolValue(isNull(v, ok) == (node.Operator.T == lex.TokenEqual)), true
+	}
+
+	ar, aok := evalDepth(ctx, node.Args[0], depth+1)
+	if isNull(ar, aok) {
+		return value.NilValueVal, true
+	}
+	br, bok := evalDepth(ctx, node.Args[1], depth+1)
+	if isNull(br, bok) {
+		return value.NilValueVal, true
+	}
+	val, ok := operateBinary(node, ar, aok, br, bok)
+	if !ok {
+		return nil, false
+	}
+	switch node.Operator.T {
+	case lex.TokenIN, lex.TokenIntersects:
+		// x IN (1, NULL) is NULL rather than false if x was not found
+		if bv, isBool := val.(value.BoolValue); isBool && !bv.Val() {
+			if sv, isSlice := br.(value.SliceValue); isSlice {
+				for _, v := range sv.Val() {
+					if isNull(v, true) {
+						return value.NilValueVal, true
+					}
+				}
+			}
+		}
+	}
+	return val, ok
+}
+
+// walkKleene evaluate args as AND (or OR) of three-valued logic, AND is false
+// if any arg is false, OR is true if any arg is true, otherwise the result
+// is NULL if any arg is NULL (or not a boolean).
+func walkKleene(ctx expr.EvalContext, and bool, args []expr.Node, depth int) (value.Value, bool) {
+	unknown := false
+	for _, arg := range args {
+		v, ok := evalDepth(ctx, arg, depth+1)
+		bv, isBool := v.(value.BoolValue)
+		if !ok || !isBool {
+			unknown = true
+			continue
+		}
+		if bv.Val() != and {
+			// false in AND, true in OR decides the result
+			return value.NewBoolValue(!and), true
+		}
+	}
+	if unknown {
+		return value.NilValueVal, true
+	}
+	return value.NewBoolValue(and), true
+}
+
+// walkDistinctFrom null-safe comparison, never NULL in either logic mode.
+//
+//	x IS DISTINCT FROM y       NULL, NULL => false,  NULL, 1 => true
+//	x IS NOT DISTINCT FROM y   NULL, NULL => true,   NULL, 1 => false
+func walkDistinctFrom(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.Value, bool) {
+	ar, aok := evalDepth(ctx, node.Args[0], depth+1)
+	br, bok := evalDepth(ctx, node.Args[1], depth+1)
+	distinct := node.Operator.T == lex.TokenDistinctFrom
+	aNull, bNull := isNull(ar, aok), isNull(br, bok)
+	if aNull || bNull {
+		return value.NewBoolValue((aNull != bNull) == distinct), true
+	}
+	eq, err := value.Equal(ar, br)
+	if err != nil {
+		return nil, false
+	}
+	return value.NewBoolValue(eq != distinct), true
+}
@@ -0,0 +1,79 @@
+package vm
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// RegexCacheSize the number of compiled regular expressions kept for
+	// patterns that are not literals (ie read from a field).
+	RegexCacheSize = 1000
+
+	regexMu    sync.Mutex
+	regexCache = make(map[string]*regexp.Regexp)
+)
+
+// CompileRegex compile a regular expression, caseInsensitive adds the (?i)
+// flag.  Compiled patterns are kept in a cache bounded by RegexCacheSize.
+func CompileRegex(pattern string, caseInsensitive bool) (*regexp.Regexp, error) {
+	if caseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	regexMu.Lock()
+	re, ok := regexCache[pattern]
+	regexMu.Unlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexMu.Lock()
+	if len(regexCache) >= RegexCacheSize {
+		// evict an arbitrary pattern
+		for k := range regexCache {
+			delete(regexCache, k)
+			break
+		}
+	}
+	regexCache[pattern] = re
+	regexMu.Unlock()
+	return re, nil
+}
+
+// operateRegex a(value) (RLIKE|~|~*) b(pattern), a list of values
+// matches if any of them match.
+func operateRegex(node *expr.BinaryNode, a, b value.Value) (value.Value, bool) {
+	if b == nil || b.Nil() {
+		return value.BoolValueFalse, true
+	}
+	re, err := CompileRegex(b.ToString(), node.Operator.T == lex.TokenRLikeI)
+	if err != nil {
+		return value.NewErrorValuef("invalid regular expression %q: %v", b.ToString(), err), false
+	}
+	return regexMatch(re, a), true
+}
+
+func regexMatch(re *regexp.Regexp, a value.Value) value.BoolValue {
+	switch at := a.(type) {
+	case nil, value.NilValue:
+		return value.BoolValueFalse
+	case value.Slice:
+		for _, v := range at.SliceValue() {
+			if v != nil && re.MatchString(v.ToString()) {
+				return value.BoolValueTrue
+			}
+		}
+		return value.BoolValueFalse
+	}
+	if a.Nil() {
+		return value.BoolValueFalse
+	}
+	return value.NewBoolValue(re.MatchString(a.ToString()))
+}
@@ -0,0 +1,118 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	_ expr.CustomFunc   = (*SqlFunc)(nil)
+	_ expr.FuncArgTypes = (*SqlFunc)(nil)
+)
+
+// SqlFunc a user defined function whose body is a sql expression of its
+// args, from  CREATE FUNCTION.
+//
+//    CREATE FUNCTION discount(price number, pct int) RETURNS number AS 'price * (100 - pct) / 100'
+//
+//    discount(20, 10)   =>  18
+//
+// Args are cast to their declared types, an arg that can't be is missing
+// when evaluating the body, as is a missing (null) arg.  The result is cast
+// to the return type.
+type SqlFunc struct {
+	Name     string
+	Args     []string
+	argTypes []value.ValueType
+	Returns  value.ValueType
+	Body     expr.Node
+}
+
+// NewSqlFunc create a sql function from a CREATE FUNCTION statement.
+func NewSqlFunc(stmt *rel.SqlCreate) (*SqlFunc, error) {
+	if stmt.Body == nil {
+		return nil, fmt.Errorf("CREATE FUNCTION %s requires a body expression", stmt.Identity)
+	}
+	m := &SqlFunc{
+		Name:     strings.ToLower(stmt.Identity),
+		Args:     make([]string, len(stmt.FuncArgs)),
+		argTypes: make([]value.ValueType, len(stmt.FuncArgs)),
+		Returns:  value.ValueFromString(stmt.Returns),
+		Body:     stmt.Body,
+	}
+	for i, arg := range stmt.FuncArgs {
+		m.Args[i] = arg.Name
+		m.argTypes[i] = value.ValueFromString(arg.DataType)
+	}
+	return m, nil
+}
+
+// Type the declared return type
+func (m *SqlFunc) Type() value.ValueType { return m.Returns }
+
+// ArgTypes the declared arg types
+func (m *SqlFunc) ArgTypes() []value.ValueType { return m.argTypes }
+
+// Validate must have the declared number of args
+func (m *SqlFunc) Validate(n *expr.FuncNode) (expr.EvaluatorFunc, error) {
+	if len(n.Args) != len(m.Args) {
+		return nil, fmt.Errorf("Expected %d args for %s(%s) but got %s", len(m.Args), m.Name, strings.Join(m.Args, ", "), n)
+	}
+	return m.eval, nil
+}
+
+func (m *SqlFunc) eval(ctx expr.EvalContext, vals []value.Value) (value.Value, bool) {
+	args := &funcArgsContext{row: make(map[string]value.Value, len(m.Args))}
+	if ctx != nil {
+		args.ts = ctx.Ts()
+	}
+	for i, name := range m.Args {
+		v := vals[i]
+		if v == nil || v.Nil() || v.Err() {
+			continue
+		}
+		if v, ok := castArg(m.argTypes[i], v); ok {
+			args.row[name] = v
+		}
+	}
+	var bodyCtx expr.EvalContext = args
+	if nullLogic(ctx) {
+		bodyCtx = NewNullLogicContext(args)
+	}
+	v, ok := Eval(bodyCtx, m.Body)
+	if !ok || v == nil || v.Nil() {
+		return v, ok
+	}
+	return castArg(m.Returns, v)
+}
+
+// castArg cast to the declared type, value (or unknown) is any type.
+func castArg(vt value.ValueType, v value.Value) (value.Value, bool) {
+	switch vt {
+	case v.Type(), value.ValueInterfaceType, value.UnknownType:
+		return v, true
+	}
+	cv, err := value.Cast(vt, v)
+	if err != nil {
+		return nil, false
+	}
+	return cv, true
+}
+
+// funcArgsContext the args of a SqlFunc call, by name.
+type funcArgsContext struct {
+	row map[string]value.Value
+	ts  time.Time
+}
+
+func (m *funcArgsContext) Get(key string) (value.Value, bool) {
+	v, ok := m.row[key]
+	return v, ok
+}
+func (m *funcArgsContext) Row() map[string]value.Value { return m.row }
+func (m *funcArgsContext) Ts() time.Time               { return m.ts }
@@ -122,6 +122,12 @@ func resolveIncludesDepth(ctx expr.Includer, arg expr.Node, depth int) error {
 				return err
 			}
 		}
+	case *expr.CaseNode:
+		for _, narg := range n.ChildrenArgs() {
+			if err := resolveIncludesDepth(ctx, narg, depth+1); err != nil {
+				return err
+			}
+		}
 	case *expr.NumberNode, *expr.IdentityNode, *expr.StringNode, nil,
 		*expr.ValueNode, *expr.NullNode:
 		return nil
@@ -158,6 +164,8 @@ func evalDepth(ctx expr.EvalContext, arg expr.Node, depth int) (value.Value, boo
 		return walkUnary(ctx, argVal, depth)
 	case *expr.TriNode:
 		return walkTernary(ctx, argVal, depth)
+	case *expr.CaseNode:
+		return walkCase(ctx, argVal, depth)
 	case *expr.ArrayNode:
 		return walkArray(ctx, argVal, depth)
 	case *expr.FuncNode:
@@ -182,6 +190,8 @@ func evalDepth(ctx expr.EvalContext, arg expr.Node, depth int) (value.Value, boo
 			return nil, false
 		case value.SliceValue:
 			return val, true
+		case value.DurationValue, value.DecimalValue:
+			return val, true
 		}
 		u.Errorf("Unknonwn node type:  %#v", argVal.Value)
 		panic(ErrUnknownNodeType)
@@ -265,9 +275,24 @@ func walkBoolean(ctx expr.EvalContext, n *expr.BooleanNode, depth int) (value.Va
 		return value.BoolValueFalse, false
 	}
 
-	for _, bn := range n.Args {
+	if nullLogic(ctx) {
+		val, ok := walkKleene(ctx, and, n.Args, depth)
+		if bv, isBool := val.(value.BoolValue); isBool && n.Negated() {
+			return value.NewBoolValue(!bv.Val()), true
+		}
+		return val, ok
+	}
+	return operateBoolean(n, and, func(i int) (bool, bool) {
+		return evalBool(ctx, n.Args[i], depth+1)
+	})
+}
+
+// operateBoolean the AND (or OR) of the args of n, evaluated in order by
+// evalArg until one decides the result.
+func operateBoolean(n *expr.BooleanNode, and bool, evalArg func(i int) (bool, bool)) (value.Value, bool) {
+	for i := range n.Args {
 
-		matches, ok := evalBool(ctx, bn, depth+1)
+		matches, ok := evalArg(i)
 		if !ok && and {
 			return nil, false
 		} else if !ok {
@@ -306,6 +331,13 @@ func walkBoolean(ctx expr.EvalContext, n *expr.BooleanNode, depth int) (value.Va
 //       x < =
 //
 func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.Value, bool) {
+	switch node.Operator.T {
+	case lex.TokenDistinctFrom, lex.TokenNotDistinctFrom:
+		return walkDistinctFrom(ctx, node, depth)
+	}
+	if nullLogic(ctx) {
+		return walkBinaryNull(ctx, node, depth)
+	}
 	val, ok := evalBinary(ctx, node, depth)
 	if !ok {
 		return nil, ok
@@ -315,6 +347,12 @@ func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.Value, bool) {
 	ar, aok := evalDepth(ctx, node.Args[0], depth+1)
 	br, bok := evalDepth(ctx, node.Args[1], depth+1)
+	return operateBinary(node, ar, aok, br, bok)
+}
+
+// operateBinary apply the binary operator to the evaluated left, right values
+// where aok, bok say if the left, right could be evaluated.
+func operateBinary(node *expr.BinaryNode, ar value.Value, aok bool, br value.Value, bok bool) (value.Value, bool) {
 
 	// If we could not evaluate either we can shortcut
 	if !aok && !bok {
@@ -327,7 +365,8 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 			return value.NewBoolValue(false), true
 		case lex.TokenNE:
 			return value.NewBoolValue(false), true
-		case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE, lex.TokenLike:
+		case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE, lex.TokenLike,
+			lex.TokenRLike, lex.TokenRLikeI:
 			return value.NewBoolValue(false), true
 		}
 		return nil, false
@@ -336,7 +375,8 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 	// Else if we can only evaluate right
 	if !aok {
 		switch node.Operator.T {
-		case lex.TokenIntersects, lex.TokenIN, lex.TokenContains, lex.TokenLike:
+		case lex.TokenIntersects, lex.TokenIN, lex.TokenContains, lex.TokenLike,
+			lex.TokenRLike, lex.TokenRLikeI:
 			return value.NewBoolValue(false), true
 		}
 	}
@@ -353,16 +393,34 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 			return value.NewBoolValue(true), true
 		case lex.TokenIN, lex.TokenIntersects:
 			return value.NewBoolValue(false), true
-		case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE, lex.TokenLike:
+		case lex.TokenGT, lex.TokenGE, lex.TokenLT, lex.TokenLE, lex.TokenLike,
+			lex.TokenRLike, lex.TokenRLikeI:
 			return value.NewBoolValue(false), true
 		}
 	}
 
+	switch node.Operator.T {
+	case lex.TokenRLike, lex.TokenRLikeI:
+		return operateRegex(node, ar, br)
+	case lex.TokenBitAnd, lex.TokenBitOr, lex.TokenBitXor, lex.TokenShiftLeft, lex.TokenShiftRight:
+		return operateBitwise(node.Operator, ar, br)
+	}
+
+	if n, ok, isDecimal := operateDecimalVals(node.Operator, ar, br); isDecimal {
+		return n, ok
+	}
+	if n, ok, isDate := operateDateMath(node.Operator.T, ar, br); isDate {
+		return n, ok
+	}
+
 	switch at := ar.(type) {
 	case value.IntValue:
 		switch bt := br.(type) {
 		case value.IntValue:
-			n := operateInts(node.Operator, at, bt)
+			n, err := operateIntVals(node.Operator, at.Val(), bt.Val())
+			if err != nil {
+				return nil, false
+			}
 			return n, true
 		case value.StringValue:
 			// Try int first
@@ -377,12 +435,10 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 			// Fallback to float
 			bf, err := strconv.ParseFloat(bt.Val(), 64)
 			if err == nil {
-				n := operateNumbers(node.Operator, at.NumberValue(), value.NewNumberValue(bf))
-				return n, true
+				return operateNumbers(node.Operator, at.NumberValue(), value.NewNumberValue(bf))
 			}
 		case value.NumberValue:
-			n := operateNumbers(node.Operator, at.NumberValue(), bt)
-			return n, true
+			return operateNumbers(node.Operator, at.NumberValue(), bt)
 		case value.SliceValue:
 			switch node.Operator.T {
 			case lex.TokenIN, lex.TokenIntersects:
@@ -406,11 +462,9 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 
 		switch bt := br.(type) {
 		case value.IntValue:
-			n := operateNumbers(node.Operator, at, bt.NumberValue())
-			return n, true
+			return operateNumbers(node.Operator, at, bt.NumberValue())
 		case value.NumberValue:
-			n := operateNumbers(node.Operator, at, bt)
-			return n, true
+			return operateNumbers(node.Operator, at, bt)
 		case value.SliceValue:
 			for _, val := range bt.Val() {
 				switch valt := val.(type) {
@@ -434,13 +488,11 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 		case value.StringValue:
 			// Try int first
 			if bf, err := strconv.ParseInt(bt.Val(), 10, 64); err == nil {
-				n := operateNumbers(node.Operator, at, value.NewNumberValue(float64(bf)))
-				return n, true
+				return operateNumbers(node.Operator, at, value.NewNumberValue(float64(bf)))
 			}
 			// Fallback to float
 			if bf, err := strconv.ParseFloat(bt.Val(), 64); err == nil {
-				n := operateNumbers(node.Operator, at, value.NewNumberValue(bf))
-				return n, true
+				return operateNumbers(node.Operator, at, value.NewNumberValue(bf))
 			}
 		case nil, value.NilValue:
 			return nil, false
@@ -562,11 +614,9 @@ func evalBinary(ctx expr.EvalContext, node *expr.BinaryNode, depth int) (value.V
 			}
 
 		case value.IntValue:
-			n := operateNumbers(node.Operator, at.NumberValue(), bt.NumberValue())
-			return n, true
+			return operateNumbers(node.Operator, at.NumberValue(), bt.NumberValue())
 		case value.NumberValue:
-			n := operateNumbers(node.Operator, at.NumberValue(), bt)
-			return n, true
+			return operateNumbers(node.Operator, at.NumberValue(), bt)
 		case value.TimeValue:
 			lht, ok := value.ValueToTime(at)
 			if !ok {
@@ -805,8 +855,15 @@ func walkIdentity(ctx expr.EvalContext, node *expr.IdentityNode) (value.Value, b
 }
 
 func walkUnary(ctx expr.EvalContext, node *expr.UnaryNode, depth int) (value.Value, bool) {
-
 	a, ok := Eval(ctx, node.Arg)
+	return operateUnary(ctx, node, a, ok)
+}
+
+// operateUnary apply the unary operator to the evaluated arg.
+func operateUnary(ctx expr.EvalContext, node *expr.UnaryNode, a value.Value, ok bool) (value.Value, bool) {
+	if node.Operator.T != lex.TokenExists && nullLogic(ctx) && isNull(a, ok) {
+		return value.NilValueVal, true
+	}
 	if !ok {
 		switch node.Operator.T {
 		case lex.TokenExists:
@@ -830,6 +887,12 @@ func walkUnary(ctx expr.EvalContext, node *expr.UnaryNode, depth int) (value.Val
 			return value.NewNilValue(), false
 		}
 	case lex.TokenMinus:
+		if ad, isDecimal := a.(value.DecimalValue); isDecimal {
+			return ad.Neg(), true
+		}
+		if ad, isDuration := a.(value.DurationValue); isDuration {
+			return ad.Neg(), true
+		}
 		if an, aok := a.(value.NumericValue); aok {
 			return value.NewNumberValue(-an.Float()), true
 		}
@@ -858,6 +921,16 @@ func walkTernary(ctx expr.EvalContext, node *expr.TriNode, depth int) (value.Val
 	a, aok := Eval(ctx, node.Args[0])
 	b, bok := Eval(ctx, node.Args[1])
 	c, cok := Eval(ctx, node.Args[2])
+	return operateTernary(ctx, node, a, aok, b, bok, c, cok)
+}
+
+// operateTernary apply the ternary operator to the evaluated args.
+func operateTernary(ctx expr.EvalContext, node *expr.TriNode, a value.Value, aok bool,
+	b value.Value, bok bool, c value.Value, cok bool) (value.Value, bool) {
+
+	if nullLogic(ctx) && (isNull(a, aok) || isNull(b, bok) || isNull(c, cok)) {
+		return value.NilValueVal, true
+	}
 	if !aok {
 		return nil, false
 	}
@@ -903,6 +976,21 @@ func walkTernary(ctx expr.EvalContext, node *expr.TriNode, depth int) (value.Val
 
 			return value.NewBoolValue(false), true
 
+		case value.DecimalValue:
+
+			if at.Nil() {
+				return nil, false
+			}
+			bv, ok := value.ValueToDecimal(b)
+			if !ok {
+				return nil, false
+			}
+			cv, ok := value.ValueToDecimal(c)
+			if !ok {
+				return nil, false
+			}
+			return value.NewBoolValue(at.Cmp(bv) > 0 && at.Cmp(cv) < 0), true
+
 		case value.TimeValue:
 
 			av := at.Val()
@@ -930,6 +1018,39 @@ func walkTernary(ctx expr.EvalContext, node *expr.TriNode, depth int) (value.Val
 	return nil, false
 }
 
+// walkCase evaluate the Then of the first matching When, or the Else.  For
+// a simple case an Arg that can't be evaluated matches no When.
+func walkCase(ctx expr.EvalContext, node *expr.CaseNode, depth int) (value.Value, bool) {
+
+	var arg value.Value
+	argOk := false
+	if node.Arg != nil {
+		arg, argOk = evalDepth(ctx, node.Arg, depth+1)
+		if arg == nil || arg.Nil() {
+			argOk = false
+		}
+	}
+
+	for i, when := range node.Whens {
+		matched := false
+		if node.Arg == nil {
+			matched, _ = evalBool(ctx, when, depth+1)
+		} else if argOk {
+			wv, ok := evalDepth(ctx, when, depth+1)
+			if ok && wv != nil && !wv.Nil() {
+				matched, _ = value.Equal(arg, wv)
+			}
+		}
+		if matched {
+			return evalDepth(ctx, node.Thens[i], depth+1)
+		}
+	}
+	if node.Else != nil {
+		return evalDepth(ctx, node.Else, depth+1)
+	}
+	return nil, false
+}
+
 // walkArray Array evaluator:  evaluate multiple values into an array
 //
 //     (b,c,d)
@@ -939,7 +1060,11 @@ func walkArray(ctx expr.EvalContext, node *expr.ArrayNode, depth int) (value.Val
 	vals := make([]value.Value, len(node.Args))
 
 	for i := 0; i < len(node.Args); i++ {
-		v, _ := Eval(ctx, node.Args[i])
+		v, ok := Eval(ctx, node.Args[i])
+		if !ok || v == nil {
+			// un-evaluateable (missing) values are nil
+			v = value.NilValueVal
+		}
 		vals[i] = v
 	}
 
@@ -958,6 +1083,18 @@ func walkFunc(ctx expr.EvalContext, node *expr.FuncNode, depth int) (value.Value
 		return nil, false
 	}
 
+	if node.EvalLazy != nil {
+		// the func evaluates (or skips) its own args
+		lazy := make([]expr.ArgEvaluator, len(node.Args))
+		for i, a := range node.Args {
+			arg := a
+			lazy[i] = func(ctx expr.EvalContext) (value.Value, bool) {
+				return Eval(ctx, arg)
+			}
+		}
+		return node.EvalLazy(ctx, lazy)
+	}
+
 	args := make([]value.Value, len(node.Args))
 
 	for i, a := range node.Args {
@@ -970,84 +1107,168 @@ func walkFunc(ctx expr.EvalContext, node *expr.FuncNode, depth int) (value.Value
 	return node.Eval(ctx, args)
 }
 
-func operateNumbers(op lex.Token, av, bv value.NumberValue) value.Value {
+func operateNumbers(op lex.Token, av, bv value.NumberValue) (value.Value, bool) {
 	switch op.T {
 	case lex.TokenPlus, lex.TokenStar, lex.TokenMultiply, lex.TokenDivide, lex.TokenMinus,
 		lex.TokenModulus:
 		if math.IsNaN(av.Val()) || math.IsNaN(bv.Val()) {
-			return value.NewNumberValue(math.NaN())
+			return value.NewNumberValue(math.NaN()), true
 		}
 	}
 
 	a, b := av.Val(), bv.Val()
 	switch op.T {
 	case lex.TokenPlus: // +
-		return value.NewNumberValue(a + b)
+		return value.NewNumberValue(a + b), true
 	case lex.TokenStar, lex.TokenMultiply: // *
-		return value.NewNumberValue(a * b)
+		return value.NewNumberValue(a * b), true
 	case lex.TokenMinus: // -
-		return value.NewNumberValue(a - b)
+		return value.NewNumberValue(a - b), true
 	case lex.TokenDivide: //
-		return value.NewNumberValue(a / b)
+		return value.NewNumberValue(a / b), true
 	case lex.TokenModulus: //    %
 		// is this even valid?   modulus on floats?
-		return value.NewNumberValue(float64(int64(a) % int64(b)))
+		if int64(b) == 0 {
+			return nil, false
+		}
+		return value.NewNumberValue(float64(int64(a) % int64(b))), true
 
 	// Below here are Boolean Returns
 	case lex.TokenEqualEqual, lex.TokenEqual: //  ==
 		if a == b {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenGT: //  >
 		if a > b {
 			//r = 1
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
 			//r = 0
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenNE: //  !=    or <>
 		if a != b {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenLT: // <
 		if a < b {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenGE: // >=
 		if a >= b {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenLE: // <=
 		if a <= b {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenLogicOr, lex.TokenOr: //  ||
 		if a != 0 || b != 0 {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	case lex.TokenLogicAnd: //  &&
 		if a != 0 && b != 0 {
-			return value.BoolValueTrue
+			return value.BoolValueTrue, true
 		} else {
-			return value.BoolValueFalse
+			return value.BoolValueFalse, true
 		}
 	}
 	panic(fmt.Errorf("expr: unknown operator %s", op))
 }
 
+// operateDecimalVals if either of a, b is a decimal operate on both as
+// decimals so the result is exact.  isDecimal is false if neither is.
+func operateDecimalVals(op lex.Token, a, b value.Value) (_ value.Value, ok, isDecimal bool) {
+	ad, aIsDec := a.(value.DecimalValue)
+	bd, bIsDec := b.(value.DecimalValue)
+	if !aIsDec && !bIsDec {
+		return nil, false, false
+	}
+	if aIsDec {
+		if bs, isSlice := b.(value.SliceValue); isSlice {
+			switch op.T {
+			case lex.TokenIN, lex.TokenIntersects:
+				for _, val := range bs.Val() {
+					if vd, ok := value.ValueToDecimal(val); ok && ad.Cmp(vd) == 0 {
+						return value.BoolValueTrue, true, true
+					}
+				}
+				return value.BoolValueFalse, true, true
+			}
+			return nil, false, true
+		}
+	}
+	if !aIsDec {
+		if ad, aIsDec = value.ValueToDecimal(a); !aIsDec {
+			return nil, false, false
+		}
+	}
+	if !bIsDec {
+		if bd, bIsDec = value.ValueToDecimal(b); !bIsDec {
+			return nil, false, false
+		}
+	}
+	if ad.Nil() || bd.Nil() {
+		return nil, false, true
+	}
+	n, ok := operateDecimals(op, ad, bd)
+	return n, ok, true
+}
+
+// operateDecimals exact decimal arithmetic, comparison.  Division, modulus
+// by zero are not ok.
+func operateDecimals(op lex.Token, a, b value.DecimalValue) (value.Value, bool) {
+	switch op.T {
+	case lex.TokenPlus: // +
+		return a.Add(b), true
+	case lex.TokenStar, lex.TokenMultiply: // *
+		return a.Mul(b), true
+	case lex.TokenMinus: // -
+		return a.Sub(b), true
+	case lex.TokenDivide: // /
+		n, ok := a.Div(b)
+		if !ok {
+			return nil, false
+		}
+		return n, true
+	case lex.TokenModulus: // %
+		n, ok := a.Mod(b)
+		if !ok {
+			return nil, false
+		}
+		return n, true
+	case lex.TokenEqualEqual, lex.TokenEqual: //  ==
+		return value.NewBoolValue(a.Cmp(b) == 0), true
+	case lex.TokenNE: //  !=    or <>
+		return value.NewBoolValue(a.Cmp(b) != 0), true
+	case lex.TokenGT: //  >
+		return value.NewBoolValue(a.Cmp(b) > 0), true
+	case lex.TokenGE: //  >=
+		return value.NewBoolValue(a.Cmp(b) >= 0), true
+	case lex.TokenLT: //  <
+		return value.NewBoolValue(a.Cmp(b) < 0), true
+	case lex.TokenLE: //  <=
+		return value.NewBoolValue(a.Cmp(b) <= 0), true
+	case lex.TokenLogicOr, lex.TokenOr: //  ||
+		return value.NewBoolValue(a.Sign() != 0 || b.Sign() != 0), true
+	case lex.TokenLogicAnd: //  &&
+		return value.NewBoolValue(a.Sign() != 0 && b.Sign() != 0), true
+	}
+	return nil, false
+}
+
 func operateStrings(op lex.Token, av, bv value.StringValue) value.Value {
 
 	//  Any other ops besides =, ==, !=, contains, like?
@@ -1125,6 +1346,83 @@ func operateTime(op lex.TokenType, lht, rht time.Time) (value.BoolValue, bool) {
 	return value.BoolValueFalse, false
 }
 
+// operateDateMath date arithmetic and interval comparison:
+//
+//    time + interval, interval + time, time - interval  => time
+//    time - time                                        => interval
+//    interval (+|-) interval                            => interval
+//    interval * int                                     => interval
+//    interval (=|!=|>|>=|<|<=) interval                 => bool
+//
+// Calendar units (months, days) are added in the location of the time.
+// isDate is false if neither operand is an interval nor both times.
+func operateDateMath(op lex.TokenType, a, b value.Value) (_ value.Value, ok, isDate bool) {
+	switch at := a.(type) {
+	case value.TimeValue:
+		switch bt := b.(type) {
+		case value.DurationValue:
+			switch op {
+			case lex.TokenPlus:
+				return value.NewTimeValue(bt.AddTo(at.Val())), !at.Nil() && !bt.Nil(), true
+			case lex.TokenMinus:
+				return value.NewTimeValue(bt.Neg().AddTo(at.Val())), !at.Nil() && !bt.Nil(), true
+			}
+			return nil, false, true
+		case value.TimeValue:
+			if op == lex.TokenMinus {
+				if at.Nil() || bt.Nil() {
+					return nil, false, true
+				}
+				return value.NewDurationValue(at.Val().Sub(bt.Val())), true, true
+			}
+		}
+	case value.DurationValue:
+		if at.Nil() {
+			return nil, false, true
+		}
+		switch bt := b.(type) {
+		case value.TimeValue:
+			if op == lex.TokenPlus && !bt.Nil() {
+				return value.NewTimeValue(at.AddTo(bt.Val())), true, true
+			}
+			return nil, false, true
+		case value.IntValue:
+			if op == lex.TokenMultiply || op == lex.TokenStar {
+				return at.Mul(bt.Val()), true, true
+			}
+			return nil, false, true
+		}
+		bd, isDuration := value.ValueToDuration(b)
+		if !isDuration {
+			return nil, false, true
+		}
+		switch op {
+		case lex.TokenPlus:
+			return at.Add(bd), true, true
+		case lex.TokenMinus:
+			return at.Sub(bd), true, true
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			return value.NewBoolValue(at.Cmp(bd) == 0), true, true
+		case lex.TokenNE:
+			return value.NewBoolValue(at.Cmp(bd) != 0), true, true
+		case lex.TokenGT:
+			return value.NewBoolValue(at.Cmp(bd) > 0), true, true
+		case lex.TokenGE:
+			return value.NewBoolValue(at.Cmp(bd) >= 0), true, true
+		case lex.TokenLT:
+			return value.NewBoolValue(at.Cmp(bd) < 0), true, true
+		case lex.TokenLE:
+			return value.NewBoolValue(at.Cmp(bd) <= 0), true, true
+		}
+		return nil, false, true
+	case value.IntValue:
+		if bt, isDuration := b.(value.DurationValue); isDuration && (op == lex.TokenMultiply || op == lex.TokenStar) {
+			return bt.Mul(at.Val()), !bt.Nil(), true
+		}
+	}
+	return nil, false, false
+}
+
 // LikeCompare takes two strings and evaluates them for like equality
 func LikeCompare(a, b string) (value.BoolValue, bool) {
 	// Do we want to always do this replacement?   Or do this at parse time or config?
@@ -1140,11 +1438,47 @@ func LikeCompare(a, b string) (value.BoolValue, bool) {
 	}
 	return value.BoolValueFalse, true
 }
-func operateInts(op lex.Token, av, bv value.IntValue) value.Value {
-	a, b := av.Val(), bv.Val()
-	v, _ := operateIntVals(op, a, b)
-	return v
+
+// operateBitwise & | ^ << >> on integers, numbers and strings only if
+// they are whole numbers.
+func operateBitwise(op lex.Token, a, b value.Value) (value.Value, bool) {
+	ai, ok := bitwiseInt(a)
+	if !ok {
+		return nil, false
+	}
+	bi, ok := bitwiseInt(b)
+	if !ok {
+		return nil, false
+	}
+	v, err := operateIntVals(op, ai, bi)
+	if err != nil {
+		return value.NewErrorValue(err), false
+	}
+	return v, true
 }
+
+func bitwiseInt(v value.Value) (int64, bool) {
+	switch vt := v.(type) {
+	case value.IntValue:
+		return vt.Val(), true
+	case value.NumberValue:
+		f := vt.Val()
+		if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(f), true
+	case value.DecimalValue:
+		if vt.Nil() || vt.Cmp(value.NewDecimalValue(vt.Int(), 0)) != 0 {
+			return 0, false
+		}
+		return vt.Int(), true
+	case value.StringValue:
+		i, err := strconv.ParseInt(strings.TrimSpace(vt.Val()), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
 func operateIntVals(op lex.Token, a, b int64) (value.Value, error) {
 	switch op.T {
 	case lex.TokenPlus: // +
@@ -1163,8 +1497,25 @@ func operateIntVals(op lex.Token, a, b int64) (value.Value, error) {
 		}
 		return value.NewIntValue(a / b), nil
 	case lex.TokenModulus: //    %
-		//r = a / b
+		//r = a % b
+		if b == 0 {
+			return nil, fmt.Errorf("Modulus by Zero error")
+		}
 		return value.NewIntValue(a % b), nil
+	case lex.TokenBitAnd: //    &
+		return value.NewIntValue(a & b), nil
+	case lex.TokenBitOr: //    |
+		return value.NewIntValue(a | b), nil
+	case lex.TokenBitXor: //    ^
+		return value.NewIntValue(a ^ b), nil
+	case lex.TokenShiftLeft, lex.TokenShiftRight: //    << >>
+		if b < 0 || b > 63 {
+			return nil, fmt.Errorf("Invalid shift count %d", b)
+		}
+		if op.T == lex.TokenShiftLeft {
+			return value.NewIntValue(a << uint(b)), nil
+		}
+		return value.NewIntValue(a >> uint(b)), nil
 
 	// Below here are Boolean Returns
 	case lex.TokenEqualEqual, lex.TokenEqual: //  ==, =
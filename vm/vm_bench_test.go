@@ -1,9 +1,13 @@
 package vm_test
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"testing"
 
+	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/rel"
 	"github.com/araddon/qlbridge/value"
 	"github.com/araddon/qlbridge/vm"
 )
@@ -44,3 +48,115 @@ func BenchmarkVmFuncNew(b *testing.B) {
 		}
 	}
 }
+
+/*
+
+go test -bench="VmCompile"
+
+BenchmarkVmCompileEval-8       	 1407343	       947 ns/op
+BenchmarkVmCompileCompiled-8   	 3115485	       379 ns/op
+
+*/
+
+func benchCompileExpr(b *testing.B) (expr.Node, expr.EvalContext) {
+	n, err := expr.ParseExpression(`ct > 5 AND name == "bob" AND tolower(name) IN ("bob", "jane")`)
+	if err != nil {
+		b.Fail()
+	}
+	msg := datasource.NewSqlDriverMessageMap(0, []driver.Value{"bob", int64(6)}, map[string]int{"name": 0, "ct": 1})
+	return n, msg
+}
+
+func BenchmarkVmCompileEval(b *testing.B) {
+	n, msg := benchCompileExpr(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := vm.Eval(msg, n); !ok {
+			b.Fail()
+		}
+	}
+}
+
+func BenchmarkVmCompileCompiled(b *testing.B) {
+	n, msg := benchCompileExpr(b)
+	c, err := vm.Compile(n)
+	if err != nil {
+		b.Fail()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := c.Eval(msg); !ok {
+			b.Fail()
+		}
+	}
+}
+
+/*
+
+go test -bench="VmMatcher" -run="XXX"
+
+Match an event against 5k segment filters, one Matches call per filter
+vs a Matcher of them.
+
+BenchmarkVmMatcherMatches   	     658	   1776201 ns/op
+BenchmarkVmMatcherMatch     	    5751	    195350 ns/op
+
+*/
+
+func benchMatcherFilters(b *testing.B) []*rel.FilterStatement {
+	stmts := make([]*rel.FilterStatement, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		var f string
+		switch i % 4 {
+		case 0:
+			f = fmt.Sprintf(`FILTER AND ( country = "c%d", age > %d )`, i%200, i%60)
+		case 1:
+			f = fmt.Sprintf(`FILTER AND ( plan IN ("p%d", "p%d"), EXISTS email )`, i%100, i%100+1)
+		case 2:
+			f = fmt.Sprintf(`FILTER OR ( visits > %d, AND ( country = "c%d", NOT plan = "free" ) )`, 100+i, i%200)
+		case 3:
+			f = fmt.Sprintf(`FILTER AND ( age BETWEEN %d AND %d, name LIKE "a*" )`, i%60, i%60+5)
+		}
+		fs, err := rel.ParseFilterQL(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		stmts = append(stmts, fs)
+	}
+	return stmts
+}
+
+var benchMatcherEvent = datasource.NewContextSimpleNative(map[string]interface{}{
+	"country": "c7", "age": 31, "plan": "p12", "email": "a@b.c", "visits": 2100, "name": "alice",
+})
+
+func BenchmarkVmMatcherMatches(b *testing.B) {
+	stmts := benchMatcherFilters(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ct := 0
+		for _, fs := range stmts {
+			if matched, _ := vm.Matches(benchMatcherEvent, fs); matched {
+				ct++
+			}
+		}
+		if ct == 0 {
+			b.Fatal("expected matches")
+		}
+	}
+}
+
+func BenchmarkVmMatcherMatch(b *testing.B) {
+	m := vm.NewMatcher(nil)
+	for i, fs := range benchMatcherFilters(b) {
+		if err := m.Add(fmt.Sprintf("f%d", i), fs); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(m.Match(benchMatcherEvent)) == 0 {
+			b.Fatal("expected matches")
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/araddon/dateparse"
 	u "github.com/araddon/gou"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
@@ -60,6 +61,8 @@ var (
 		"urls":    value.NewStringsValue([]string{"abc", "123"}),
 		"hits":    value.NewMapIntValue(map[string]int64{"google.com": 5, "bing.com": 1}),
 		"email":   value.NewStringValue("bob@bob.com"),
+		"price":   value.NewDecimalValue(1999, 2),
+		"ts":      value.NewTimeValue(t0),
 		"mt":      value.NewMapTimeValue(map[string]time.Time{"event0": t0, "event1": t1}),
 	}, true)
 	vmTestsx = []vmTest{
@@ -218,6 +221,70 @@ var (
 		vmtall(`5.5 == ["hello", 3, "5.5"]`, true, parseOk, noError),
 		vmtall(`5.5 == ["5.9", 99, "hello"]`, false, parseOk, noError),
 
+		// Decimal, exact
+		vmt(`price + cast("2.205" AS decimal)`, "22.195", noError),
+		vmt(`cast("1.10" AS DECIMAL(10,2)) + 2`, "3.10", noError),
+		vmt(`0.3 == price - cast("19.69" AS decimal)`, true, noError),
+		vmt(`cast("2.675" AS DECIMAL(5,2))`, "2.68", noError),
+		vmtall(`cast("12345.6" AS DECIMAL(5,2))`, nil, parseOk, evalError),
+		vmt(`price * 3`, "59.97", noError),
+		vmt(`price - "0.99"`, "19.00", noError),
+		vmt(`price / 2`, "9.995000", noError),
+		vmt(`price > 19.98`, true, noError),
+		vmt(`price == "19.990"`, true, noError),
+		vmt(`price IN (5, 19.99)`, true, noError),
+		vmt(`price BETWEEN 19 AND "19.995"`, true, noError),
+		vmt(`price BETWEEN 19.99 AND 20`, false, noError),
+		vmtall(`price / 0`, nil, parseOk, evalError),
+
+		// Regular expressions
+		vmt(`email RLIKE "^bob@"`, true, noError),
+		vmt(`email REGEXP "BOB"`, false, noError),
+		vmt(`email ~* "BOB"`, true, noError),
+		vmt(`email ~ "[0-9]"`, false, noError),
+		vmt(`email NOT RLIKE "^bob@"`, false, noError),
+		vmt(`urls ~ "^[0-9]+$"`, true, noError),
+		vmt(`not_a_field RLIKE "x"`, false, noError),
+		vmt(`email RLIKE user_id`, false, noError),
+		vmtall(`email RLIKE "("`, nil, parseOk, evalError),
+
+		// null handling, args that don't evaluate are null
+		vmt(`coalesce(not_a_field, int5) + 1 == 6`, true, noError),
+		vmt(`ifnull(not_a_field, "x") == "x"`, true, noError),
+		vmt(`if(int5 > 3, "big", not_a_field)`, "big", noError),
+		vmt(`greatest(int5, not_a_field, 2)`, int64(5), noError),
+		vmt(`nullif(int5, 4) == 5`, true, noError),
+
+		// bitwise operators, math
+		vmt(`int5 & 3`, int64(1), noError),
+		vmt(`int5 | 2`, int64(7), noError),
+		vmt(`int5 ^ 1`, int64(4), noError),
+		vmt(`int5 << 2`, int64(20), noError),
+		vmt(`int5 >> 1`, int64(2), noError),
+		vmt(`int5 & 4 == 4`, true, noError),
+		vmt(`1 + 1 << 2`, int64(8), noError),
+		vmtall(`int5 & 1.5`, nil, parseOk, evalError),
+		vmtall(`int5 << 64`, nil, parseOk, evalError),
+		vmt(`abs(int5 - 10)`, int64(5), noError),
+		vmt(`round(int5 * 0.5)`, float64(3), noError),
+
+		// Interval, date arithmetic
+		vmt(`ts + INTERVAL 3 DAY == todate("12/21/2015")`, true, noError),
+		vmt(`ts - INTERVAL "1 month" < todate("11/19/2015")`, true, noError),
+		vmt(`INTERVAL 1 YEAR + ts > todate("12/17/2016")`, true, noError),
+		vmt(`todate("12/21/2015") - ts`, "72h0m0s", noError),
+		vmt(`INTERVAL 3 DAY + INTERVAL "2h"`, "3 days 2h0m0s", noError),
+		vmt(`INTERVAL 2 DAY * 3`, "6 days", noError),
+		vmt(`INTERVAL "36h" > INTERVAL 1 DAY`, true, noError),
+		vmt(`created > now() - INTERVAL 15 DAY`, true, noError),
+
+		// Case
+		vmt(`CASE WHEN int5 > 10 THEN "big" WHEN int5 > 1 THEN "medium" ELSE "small" END`, "medium", noError),
+		vmt(`CASE WHEN int5 > 10 THEN "big" ELSE "small" END`, "small", noError),
+		vmt(`CASE user_id WHEN "xyz" THEN 1 WHEN "abc" THEN 2 END + 5`, int64(7), noError),
+		vmt(`CASE WHEN not_a_field > 1 THEN 1 ELSE 0 END`, int64(0), noError),
+		vmtall(`CASE not_a_field WHEN 1 THEN 1 END`, nil, parseOk, evalError),
+
 		// Numeric Boolean coerce
 		vmt(`"5.5" == 5.5`, true, noError),
 		vmt(`"5.5" > 5`, true, noError),
@@ -241,6 +308,10 @@ var (
 		// Context Based Tests
 		vmt(`int5 + 5`, int64(10), noError),
 		vmt(`int5 * 6`, int64(30), noError),
+		vmt(`int5 % 2`, int64(1), noError),
+		vmtall(`int5 % 0`, nil, parseOk, evalError),
+		vmtall(`int5 / 0`, nil, parseOk, evalError),
+		vmtall(`5.5 % 0.5`, nil, parseOk, evalError),
 		vmt(`toint(str5 * 6)`, int64(30), noError),
 		vmt(`toint(str5 + 6)`, int64(11), noError),
 
@@ -348,6 +419,67 @@ func TestRunExpr(t *testing.T) {
 	}
 }
 
+func TestNullLogic(t *testing.T) {
+	nullCtx := vm.NewNullLogicContext(msgContext)
+	tests := []struct {
+		qlText string
+		result interface{} // nil is NULL
+	}{
+		{`not_a_field = 1`, nil},
+		{`not_a_field != 1`, nil},
+		{`NOT (not_a_field = 1)`, nil},
+		{`not_a_field + 1`, nil},
+		{`not_a_field > 1 AND int5 = 5`, nil},
+		{`not_a_field > 1 AND int5 = 6`, false},
+		{`not_a_field > 1 OR int5 = 5`, true},
+		{`not_a_field > 1 OR int5 = 6`, nil},
+		{`AND (not_a_field > 1, int5 = 6)`, false},
+		{`OR (not_a_field > 1, int5 = 6)`, nil},
+		{`NOT OR (not_a_field > 1, int5 = 5)`, false},
+		{`not_a_field BETWEEN 1 AND 5`, nil},
+		{`user_id IN ("a", NULL)`, nil},
+		{`user_id IN ("abc", NULL)`, true},
+		{`user_id NOT IN ("a", NULL)`, nil},
+		{`not_a_field = NULL`, nil},
+		{`user_id != NULL`, nil},
+		{`NULL = NULL`, nil},
+		{`not_a_field IS NULL`, true},
+		{`not_a_field IS NOT NULL`, false},
+		{`user_id IS NOT NULL`, true},
+		{`not_a_field IS DISTINCT FROM NULL`, false},
+		{`not_a_field IS NOT DISTINCT FROM NULL`, true},
+		{`user_id IS DISTINCT FROM not_a_field`, true},
+		{`int5 IS NOT DISTINCT FROM 5`, true},
+		{`CASE WHEN not_a_field > 1 THEN 1 ELSE 0 END`, int64(0)},
+		{`EXISTS not_a_field`, false},
+		{`coalesce(not_a_field, int5)`, int64(5)},
+		{`nullif(int5, 5) IS NULL`, true},
+		{`if(not_a_field > 1, 1, 0)`, int64(0)},
+		{`int5 + 1`, int64(6)},
+	}
+	for _, tt := range tests {
+		n, err := expr.ParseExpression(tt.qlText)
+		assert.Equal(t, nil, err, tt.qlText)
+		val, ok := vm.Eval(nullCtx, n)
+		assert.True(t, ok, tt.qlText)
+		if tt.result == nil {
+			assert.Equal(t, value.NilType, val.Type(), tt.qlText)
+		} else {
+			assert.Equal(t, tt.result, val.Value(), tt.qlText)
+		}
+	}
+
+	// default logic, a missing value makes comparisons false
+	n := expr.MustParse(`not_a_field != 1`)
+	val, ok := vm.Eval(msgContext, n)
+	assert.True(t, ok)
+	assert.Equal(t, true, val.Value())
+	n = expr.MustParse(`not_a_field IS DISTINCT FROM 1`)
+	val, ok = vm.Eval(msgContext, n)
+	assert.True(t, ok)
+	assert.Equal(t, true, val.Value())
+}
+
 type vmTest struct {
 	qlText  string
 	parseok bool